@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gYonder/drime-shell/internal/ui"
+)
+
+// cliFlag is a flag of the drime binary itself, as offered by the outer
+// shell's completion. Flags with choices complete those as their value.
+type cliFlag struct {
+	name       string
+	help       string
+	takesValue bool
+	choices    []string
+}
+
+var cliFlags = []cliFlag{
+	{name: "version", help: "Print the version and exit"},
+	{name: "color-scheme", help: "Color scheme for this run", takesValue: true,
+		choices: []string{string(ui.ThemeAuto), string(ui.ThemeDark), string(ui.ThemeLight), string(ui.ThemeMono)}},
+	{name: "api-url", help: "API endpoint for this run", takesValue: true},
+	{name: "json", help: "Report command errors as JSON on stderr"},
+}
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// runCompletion prints the completion script for the shell named in args and
+// returns the exit code.
+func runCompletion(stdout, stderr io.Writer, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(stderr, "usage: drime completion %s\n", strings.Join(completionShells, "|"))
+		return 2
+	}
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(stdout)
+	case "zsh":
+		writeZshCompletion(stdout)
+	case "fish":
+		writeFishCompletion(stdout)
+	default:
+		fmt.Fprintf(stderr, "drime completion: unsupported shell %q (want %s)\n", args[0], strings.Join(completionShells, ", "))
+		return 2
+	}
+	return 0
+}
+
+// writeBashCompletion writes a script for ~/.bashrc:
+// source <(drime completion bash)
+func writeBashCompletion(w io.Writer) {
+	var flags []string
+	for _, f := range cliFlags {
+		flags = append(flags, "--"+f.name)
+	}
+
+	fmt.Fprintln(w, "# bash completion for drime")
+	fmt.Fprintln(w, "_drime() {")
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintln(w, `    case "$prev" in`)
+	for _, f := range cliFlags {
+		if !f.takesValue {
+			continue
+		}
+		if len(f.choices) > 0 {
+			fmt.Fprintf(w, "        --%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", f.name, strings.Join(f.choices, " "))
+		} else {
+			fmt.Fprintf(w, "        --%s) COMPREPLY=(); return ;;\n", f.name)
+		}
+	}
+	fmt.Fprintf(w, "        completion) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", strings.Join(completionShells, " "))
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, `    if [[ $COMP_CWORD -eq 1 ]]; then`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", "completion "+strings.Join(flags, " "))
+	fmt.Fprintln(w, "    else")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(flags, " "))
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -F _drime drime")
+}
+
+// writeZshCompletion writes a script to put in $fpath as _drime, or to
+// source from ~/.zshrc after compinit.
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprintln(w, "#compdef drime")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "_drime() {")
+	fmt.Fprintln(w, "    if (( CURRENT == 3 )) && [[ $words[2] == completion ]]; then")
+	fmt.Fprintf(w, "        _values 'shell' %s\n", strings.Join(completionShells, " "))
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "    _arguments \\")
+	for _, f := range cliFlags {
+		spec := fmt.Sprintf("--%s[%s]", f.name, f.help)
+		switch {
+		case f.name == "version":
+			spec = "(- *)" + spec
+		case len(f.choices) > 0:
+			spec += fmt.Sprintf(":%s:(%s)", f.name, strings.Join(f.choices, " "))
+		case f.takesValue:
+			spec += ":" + f.name + ":"
+		}
+		fmt.Fprintf(w, "        '%s' \\\n", spec)
+	}
+	fmt.Fprintln(w, `        '1:command:((completion\:"Print a shell completion script"))'`)
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, `if [ "$funcstack[1]" = "_drime" ]; then`)
+	fmt.Fprintln(w, `    _drime "$@"`)
+	fmt.Fprintln(w, "else")
+	fmt.Fprintln(w, "    compdef _drime drime")
+	fmt.Fprintln(w, "fi")
+}
+
+// writeFishCompletion writes a script for
+// ~/.config/fish/completions/drime.fish.
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# fish completion for drime")
+	fmt.Fprintln(w, "complete -c drime -f")
+	fmt.Fprintln(w, "complete -c drime -n __fish_use_subcommand -a completion -d 'Print a shell completion script'")
+	fmt.Fprintf(w, "complete -c drime -n '__fish_seen_subcommand_from completion' -a '%s'\n", strings.Join(completionShells, " "))
+	for _, f := range cliFlags {
+		line := "complete -c drime -l " + f.name
+		if f.takesValue {
+			line += " -x"
+		}
+		if len(f.choices) > 0 {
+			line += fmt.Sprintf(" -a '%s'", strings.Join(f.choices, " "))
+		}
+		fmt.Fprintf(w, "%s -d '%s'\n", line, f.help)
+	}
+}
@@ -3,24 +3,22 @@ package main
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/build"
+	"github.com/gYonder/drime-shell/internal/commands"
 	"github.com/gYonder/drime-shell/internal/config"
+	"github.com/gYonder/drime-shell/internal/dlcache"
 	"github.com/gYonder/drime-shell/internal/session"
 	"github.com/gYonder/drime-shell/internal/shell"
 	"github.com/gYonder/drime-shell/internal/ui"
 	"golang.org/x/term"
-
-	// Register commands
-	_ "github.com/gYonder/drime-shell/internal/commands"
 )
 
 func main() {
@@ -28,6 +26,9 @@ func main() {
 		fmt.Println(build.Version)
 		os.Exit(0)
 	}
+	if len(os.Args) > 1 && os.Args[1] == "completion" {
+		os.Exit(runCompletion(os.Stdout, os.Stderr, os.Args[2:]))
+	}
 
 	// Show immediate feedback - gets cleared before any prompts or replaced by spinner
 	fmt.Fprint(os.Stderr, "Initializing... ⠋")
@@ -42,11 +43,36 @@ func main() {
 		os.Exit(1)
 	}
 
+	// --color-scheme overrides the theme from the config for this run
+	if scheme, ok := globalFlag("color-scheme"); ok {
+		cfg.Theme = scheme
+	}
+	// --api-url points this run at another Drime instance (staging,
+	// self-hosted) without touching the saved config
+	apiURL := cfg.APIURL
+	if raw, ok := globalFlag("api-url"); ok {
+		apiURL, err = config.ValidateAPIURL(raw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\r\033[KError: --api-url: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := ui.ApplyTheme(ui.Theme(cfg.Theme), cfg.Colors); err != nil {
+		fmt.Fprintf(os.Stderr, "\r\033[KWarning: %v\n", err)
+	}
+
+	// Machine-readable progress for programs driving the shell
+	progressMode := os.Getenv("DRIME_PROGRESS")
+	if _, err := ui.ProgressSinkFor(progressMode, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "\r\033[KWarning: DRIME_PROGRESS: %v\n", err)
+		progressMode = ""
+	}
+
 	// If the token isn't set, we need to ask the user for it
 	if cfg.Token == "" {
 		// Clear the "Starting..." message before prompting
 		fmt.Fprint(os.Stderr, "\r\033[K")
-		token, err := promptForToken()
+		token, err := promptForToken(apiURL)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -64,7 +90,10 @@ func main() {
 	}
 
 	// Set up the API client
-	client := api.NewHTTPClient(cfg.APIURL, cfg.Token)
+	client := api.NewHTTPClient(apiURL, cfg.Token)
+	if dir, err := config.ConfigDir(); err == nil {
+		client.Journal = api.NewMultipartJournal(filepath.Join(dir, "pending-uploads.json"))
+	}
 
 	// check connectivity and initialize shell
 	// We wrap all network activity in a spinner so it looks nice
@@ -72,6 +101,7 @@ func main() {
 		user    *api.User
 		cache   *api.FileCache
 		entries []api.FileEntry
+		skew    time.Duration
 	}
 
 	data, err := ui.WithSpinner(os.Stderr, "Initializing...", true, func() (*initData, error) {
@@ -94,7 +124,13 @@ func main() {
 			entries = []api.FileEntry{}
 		}
 
-		return &initData{user, cache, entries}, nil
+		// 4. Measure the clock skew; without it, times compare as if in sync
+		skew, err := client.MeasureClockSkew(context.Background())
+		if err != nil {
+			skew = 0
+		}
+
+		return &initData{user, cache, entries, skew}, nil
 	})
 
 	if err != nil {
@@ -112,11 +148,52 @@ func main() {
 	sess.Username = user.Name()
 	sess.Token = cfg.Token
 	sess.MaxMemoryBufferMB = cfg.MaxMemoryBufferMB
+	sess.RmConfirmEntries = cfg.RmConfirmEntries
+	sess.TransferJobs = cfg.TransferJobs
+	sess.ExternalEditor = cfg.ExternalEditor
+	sess.NoZipThreshold = cfg.NoZipThreshold
+	sess.DownloadDir = cfg.DownloadDirPath()
+	sess.OnDuplicate = cfg.OnDuplicate
+	sess.ChecksumAlgo = cfg.ChecksumAlgo
+	sess.PartialSuffix = cfg.PartialSuffix
+	sess.ProgressMode = progressMode
+	sess.ClockSkew = data.skew
+	if data.skew >= api.ClockSkewWarnThreshold || data.skew <= -api.ClockSkewWarnThreshold {
+		fmt.Fprintf(os.Stderr, "Warning: local clock is %s off from the server's; update checks (-u) allow for it, but consider syncing your clock\n", data.skew.Abs())
+	}
+	sess.UploadJournal = client.Journal
+	if dir, err := config.ConfigDir(); err == nil && cfg.LocateIndex {
+		sess.IndexDir = filepath.Join(dir, "index")
+		if err := sess.AttachNameIndex(cache, 0); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not load locate index: %v\n", err)
+		}
+	}
+	if dir, err := config.ConfigDir(); err == nil && cfg.DownloadCacheMB > 0 {
+		sess.DownloadCache = dlcache.New(filepath.Join(dir, "cache"), int64(cfg.DownloadCacheMB)<<20)
+	}
+	if dir, err := config.ConfigDir(); err == nil {
+		sess.TransfersDir = filepath.Join(dir, "transfers")
+		go commands.PublishTransfers(context.Background(), sess.TransfersDir)
+	}
+	go commands.ReportTransfersOnSignal(context.Background(), os.Stderr)
+	if path := cfg.AuditLogPath(); path != "" {
+		commands.AddHook(commands.NewAuditHook(path))
+	}
 	if cfg.Aliases != nil {
 		for k, v := range cfg.Aliases {
 			sess.Aliases[k] = v
 		}
 	}
+	if cfg.Home != "" {
+		sess.HomeDir = filepath.Clean("/" + cfg.Home)
+	}
+	if len(cfg.Workspaces) > 0 {
+		sess.WorkspaceProfiles = make(map[string]session.WorkspaceProfile, len(cfg.Workspaces))
+		for name, ws := range cfg.Workspaces {
+			sess.WorkspaceProfiles[name] = session.WorkspaceProfile{Home: ws.Home, Aliases: ws.Aliases}
+		}
+	}
+	sess.ApplyWorkspaceProfile()
 
 	// Apply prefetched entries
 	if len(entries) > 0 {
@@ -134,12 +211,19 @@ func main() {
 		}
 	}
 
+	if !sess.EnterHome() {
+		fmt.Fprintf(os.Stderr, "Warning: home %s not found, starting at /\n", sess.Home())
+	}
+
 	// 6. Start Shell
 	sh, err := shell.New(sess)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to start shell: %v\n", err)
 		os.Exit(1)
 	}
+	// --json (or DRIME_ERRORS=json) reports failed commands as JSON on
+	// stderr for programs driving the shell
+	sh.JSONErrors = globalSwitch("json") || os.Getenv("DRIME_ERRORS") == "json"
 
 	select {
 	case msg := <-updateMsg:
@@ -150,9 +234,36 @@ func main() {
 	}
 
 	sh.Run()
+	_ = sess.SaveNameIndexes()
 }
 
-func promptForToken() (string, error) {
+// globalFlag returns the value of --name, given as --name=value or
+// --name value on the command line.
+func globalFlag(name string) (string, bool) {
+	args := os.Args[1:]
+	for i, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--"+name+"="); ok {
+			return value, true
+		}
+		if arg == "--"+name && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// globalSwitch reports whether the boolean flag --name is on the command
+// line.
+func globalSwitch(name string) bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "--"+name || arg == "--"+name+"=true" {
+			return true
+		}
+	}
+	return false
+}
+
+func promptForToken(apiURL string) (string, error) {
 	fmt.Println("No Drime API token found.")
 	fmt.Println()
 	fmt.Println("Choose authentication method:")
@@ -174,7 +285,7 @@ func promptForToken() (string, error) {
 		case "1":
 			return promptForTokenDirect(reader)
 		case "2":
-			return promptLoginFlow(reader)
+			return promptLoginFlow(reader, apiURL)
 		default:
 			fmt.Println("Please enter 1 or 2")
 		}
@@ -199,7 +310,7 @@ func promptForTokenDirect(reader *bufio.Reader) (string, error) {
 	return token, nil
 }
 
-func promptLoginFlow(reader *bufio.Reader) (string, error) {
+func promptLoginFlow(reader *bufio.Reader, apiURL string) (string, error) {
 	fmt.Println()
 
 	// Get email
@@ -233,8 +344,7 @@ func promptLoginFlow(reader *bufio.Reader) (string, error) {
 	deviceName := fmt.Sprintf("drime-shell@%s", hostname)
 
 	// Need a temporary client to call login
-	cfg, _ := config.Load()
-	tempClient := api.NewHTTPClient(cfg.APIURL, "")
+	tempClient := api.NewHTTPClient(apiURL, "")
 
 	fmt.Print("Logging in... ")
 	user, err := tempClient.Login(context.Background(), email, password, deviceName)
@@ -271,105 +381,13 @@ func checkForUpdates(result chan<- string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.github.com/repos/gYonder/drime-shell/releases/latest", nil)
-	if err != nil {
-		return
-	}
-	req.Header.Set("User-Agent", "drime-shell/"+build.Version)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
+	latest, err := build.LatestUpdate(ctx)
+	if err != nil || latest == "" {
 		return
 	}
-
-	var rel struct {
-		TagName string `json:"tag_name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
-		return
-	}
-
-	latest := strings.TrimPrefix(rel.TagName, "v")
-	current := strings.TrimPrefix(build.Version, "v")
-
-	if latest != "" && semverGreater(latest, current) {
-		msg := fmt.Sprintf("%s %s -> %s\nRun %s to upgrade.\n",
-			ui.SuccessStyle.Render("Update available:"),
-			current,
-			rel.TagName,
-			ui.CommandStyle.Render("update"))
-		result <- msg
-	}
-}
-
-// semverGreater returns true if version a > b using semantic versioning.
-// Handles versions like "1.2.3", "1.2.3-beta", etc.
-// Returns false if either version is invalid or if a <= b.
-func semverGreater(a, b string) bool {
-	// Handle dev/unknown versions - always consider releases newer than dev
-	if b == "dev" || b == "" {
-		return a != "dev" && a != ""
-	}
-	if a == "dev" || a == "" {
-		return false
-	}
-
-	// Split into version and prerelease parts
-	aParts := strings.SplitN(a, "-", 2)
-	bParts := strings.SplitN(b, "-", 2)
-
-	// Parse major.minor.patch
-	aVer := parseVersion(aParts[0])
-	bVer := parseVersion(bParts[0])
-
-	if aVer == nil || bVer == nil {
-		return false
-	}
-
-	// Compare major.minor.patch
-	for i := 0; i < 3; i++ {
-		if aVer[i] > bVer[i] {
-			return true
-		}
-		if aVer[i] < bVer[i] {
-			return false
-		}
-	}
-
-	// Same version numbers - check prerelease
-	// A release (no prerelease) is greater than a prerelease
-	aHasPrerelease := len(aParts) > 1
-	bHasPrerelease := len(bParts) > 1
-
-	if !aHasPrerelease && bHasPrerelease {
-		return true // 1.0.0 > 1.0.0-beta
-	}
-
-	return false // Equal or b is release and a is prerelease
-}
-
-// parseVersion parses "1.2.3" into [1, 2, 3]. Returns nil on error.
-func parseVersion(s string) []int {
-	parts := strings.Split(s, ".")
-	if len(parts) != 3 {
-		return nil
-	}
-
-	result := make([]int, 3)
-	for i, p := range parts {
-		var n int
-		if _, err := fmt.Sscanf(p, "%d", &n); err != nil {
-			return nil
-		}
-		if n < 0 {
-			return nil
-		}
-		result[i] = n
-	}
-	return result
+	result <- fmt.Sprintf("%s %s -> %s\nRun %s to upgrade.\n",
+		ui.SuccessStyle.Render("Update available:"),
+		strings.TrimPrefix(build.Version, "v"),
+		latest,
+		ui.CommandStyle.Render("update"))
 }
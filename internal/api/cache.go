@@ -2,18 +2,25 @@ package api
 
 import (
 	"context"
+	"path"
 	"strings"
 	"sync"
 
 	"github.com/bmatcuk/doublestar/v4"
 )
 
-// FileCache maps paths to their API identifiers and vice-versa
+// FileCache maps paths to their API identifiers and vice-versa. It is safe
+// for concurrent use: directory transfers update it from their workers while
+// the shell reads it. Cached entries are shared with callers, so their
+// metadata is only changed through UpdateMetadata, under the cache's lock.
 type FileCache struct {
-	entries        map[string]*FileEntry // path -> entry
-	byID           map[int64]*FileEntry  // id -> entry
-	pathByID       map[int64]string      // id -> path (best-effort)
-	loadedChildren map[string]bool       // paths whose children have been fetched
+	entries        map[string]*FileEntry      // path -> entry
+	byID           map[int64]*FileEntry       // id -> entry
+	pathByID       map[int64]string           // id -> path (best-effort)
+	loadedChildren map[string]bool            // paths whose children have been fetched
+	childNames     map[string]map[string]bool // parent path -> names of its cached children
+	counts         map[string]int             // folder path -> item count from the server, until its children change
+	index          *NameIndex                 // optional name index kept in step with the cache
 	mu             sync.RWMutex
 }
 
@@ -23,16 +30,92 @@ func NewFileCache() *FileCache {
 		byID:           make(map[int64]*FileEntry),
 		pathByID:       make(map[int64]string),
 		loadedChildren: make(map[string]bool),
+		childNames:     make(map[string]map[string]bool),
+		counts:         make(map[string]int),
 	}
 }
 
+// put caches entry at p. Callers must hold c.mu.
+func (c *FileCache) put(p string, entry *FileEntry) {
+	c.entries[p] = entry
+	c.byID[entry.ID] = entry
+	c.pathByID[entry.ID] = p
+	if p != "/" {
+		parent := path.Dir(p)
+		delete(c.counts, parent)
+		if c.childNames[parent] == nil {
+			c.childNames[parent] = make(map[string]bool)
+		}
+		c.childNames[parent][path.Base(p)] = true
+	}
+}
+
+// drop removes the entry cached at p. Callers must hold c.mu.
+func (c *FileCache) drop(p string, entry *FileEntry) {
+	delete(c.byID, entry.ID)
+	delete(c.pathByID, entry.ID)
+	delete(c.entries, p)
+	delete(c.counts, p)
+	delete(c.counts, path.Dir(p))
+	if names := c.childNames[path.Dir(p)]; names != nil {
+		delete(names, path.Base(p))
+	}
+}
+
+// copyMetadata copies size, hash, MIME type and timestamps from src into dst.
+// With onlyMissing set, fields dst already has are kept.
+func copyMetadata(dst, src *FileEntry, onlyMissing bool) {
+	if !onlyMissing || dst.Size == 0 {
+		dst.Size = src.Size
+	}
+	if !onlyMissing || dst.Hash == "" {
+		dst.Hash = src.Hash
+	}
+	if !onlyMissing || dst.Mime == "" {
+		dst.Mime = src.Mime
+	}
+	if !onlyMissing || dst.UpdatedAt.IsZero() {
+		dst.UpdatedAt = src.UpdatedAt
+	}
+	if !onlyMissing || dst.CreatedAt.IsZero() {
+		dst.CreatedAt = src.CreatedAt
+	}
+}
+
+// keepMetadata fills fields missing from entry with those of the entry it
+// replaces in the cache, so reloading from an endpoint that omits them (such
+// as the folder tree) doesn't lose what a listing already provided.
+// Callers must hold c.mu.
+func (c *FileCache) keepMetadata(entry *FileEntry) {
+	if old, ok := c.byID[entry.ID]; ok && old != entry && !entry.HasMetadata() {
+		copyMetadata(entry, old, true)
+	}
+}
+
+// SetIndex attaches a name index to the cache, seeding it with the entries
+// already cached. Later cache mutations keep it up to date.
+func (c *FileCache) SetIndex(idx *NameIndex) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.index = idx
+	for p, e := range c.entries {
+		idx.put(p, e)
+	}
+}
+
+// Index returns the attached name index, or nil.
+func (c *FileCache) Index() *NameIndex {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.index
+}
+
 // Add inserts an entry into the cache at specific path
 func (c *FileCache) Add(entry *FileEntry, path string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.entries[path] = entry
-	c.byID[entry.ID] = entry
-	c.pathByID[entry.ID] = path
+	c.put(path, entry)
+	c.index.put(path, entry)
 }
 
 // Get retrieves an entry by path
@@ -65,17 +148,21 @@ func (c *FileCache) Remove(path string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if entry, ok := c.entries[path]; ok {
-		delete(c.byID, entry.ID)
-		delete(c.pathByID, entry.ID)
-		delete(c.entries, path)
+		c.drop(path, entry)
 	}
+	c.index.remove(path)
 }
 
 // AddChildren adds child entries under a parent path and marks it as loaded
 func (c *FileCache) AddChildren(parentPath string, children []FileEntry) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.addChildren(parentPath, children)
+}
 
+// addChildren is AddChildren for callers holding c.mu.
+func (c *FileCache) addChildren(parentPath string, children []FileEntry) {
+	names := make(map[string]bool, len(children))
 	for i := range children {
 		child := &children[i]
 		var childPath string
@@ -84,11 +171,70 @@ func (c *FileCache) AddChildren(parentPath string, children []FileEntry) {
 		} else {
 			childPath = parentPath + "/" + child.Name
 		}
-		c.entries[childPath] = child
-		c.byID[child.ID] = child
-		c.pathByID[child.ID] = childPath
+		c.keepMetadata(child)
+		c.put(childPath, child)
+		c.index.put(childPath, child)
+		names[child.Name] = true
 	}
 	c.loadedChildren[parentPath] = true
+	// A listing is complete, so indexed children it lacks are gone
+	c.index.replaceChildren(parentPath, names)
+}
+
+// ReplaceChildren is AddChildren for a fresh listing: cached children of
+// parentPath missing from children (and everything below them) are dropped,
+// since another client must have removed or renamed them.
+func (c *FileCache) ReplaceChildren(parentPath string, children []FileEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keep := make(map[string]bool, len(children))
+	for i := range children {
+		keep[children[i].Name] = true
+	}
+	prefix := strings.TrimSuffix(parentPath, "/") + "/"
+	for p, entry := range c.entries {
+		if p == parentPath || !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		child, _, _ := strings.Cut(p[len(prefix):], "/")
+		if keep[child] {
+			continue
+		}
+		c.drop(p, entry)
+		delete(c.loadedChildren, p)
+	}
+	// Adding under the same lock means no reader sees the folder half-updated
+	c.addChildren(parentPath, children)
+}
+
+// FillMetadata completes entry's size, hash and timestamps from the API when
+// the cached copy lacks them, writing them back into the cached entry so later
+// lookups don't repeat the request. Entries that already have metadata and the
+// synthetic root are left alone.
+func (c *FileCache) FillMetadata(ctx context.Context, client DrimeClient, entry *FileEntry, workspaceID int64) error {
+	if entry == nil || entry.IsRoot() {
+		return nil
+	}
+	c.mu.RLock()
+	complete := entry.HasMetadata()
+	c.mu.RUnlock()
+	if complete {
+		return nil
+	}
+	fresh, err := client.GetEntry(ctx, entry.ID, workspaceID)
+	if err != nil || fresh == nil {
+		return err
+	}
+	c.UpdateMetadata(entry, fresh)
+	return nil
+}
+
+// UpdateMetadata overwrites the metadata of a cached entry with fresh values
+// fetched from the API.
+func (c *FileCache) UpdateMetadata(entry, fresh *FileEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	copyMetadata(entry, fresh, false)
 }
 
 // HasChildren returns true if the children of this path have been fetched
@@ -103,6 +249,24 @@ func (c *FileCache) InvalidateChildren(path string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.loadedChildren, path)
+	delete(c.counts, path)
+}
+
+// SetCount caches n as the number of items in the folder at path, as
+// counted by the server without listing it. The count is forgotten once
+// an entry is added to or removed from the folder.
+func (c *FileCache) SetCount(path string, n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[path] = n
+}
+
+// Count returns the item count cached by SetCount for the folder at path.
+func (c *FileCache) Count(path string) (int, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	n, ok := c.counts[path]
+	return n, ok
 }
 
 // MarkChildrenLoaded marks a path's children as having been loaded
@@ -156,27 +320,26 @@ func (c *FileCache) GetChildren(parentPath string) []FileEntry {
 
 	// Return empty slice (not nil) for loaded but empty directories
 	children := []FileEntry{}
-	prefix := parentPath
-	if prefix != "/" {
-		prefix += "/"
-	}
-
-	for path, entry := range c.entries {
-		if path == parentPath {
-			continue
-		}
-		// Check if this is a direct child (not a grandchild)
-		if strings.HasPrefix(path, prefix) {
-			remainder := path[len(prefix):]
-			// Direct child has no more slashes
-			if !strings.Contains(remainder, "/") {
-				children = append(children, *entry)
-			}
+	for name := range c.childNames[parentPath] {
+		if entry, ok := c.entries[path.Join(parentPath, name)]; ok {
+			children = append(children, *entry)
 		}
 	}
 	return children
 }
 
+// ChildNames returns the names of the entries cached directly under
+// parentPath, whether or not its full listing has been fetched.
+func (c *FileCache) ChildNames(parentPath string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.childNames[parentPath]))
+	for name := range c.childNames[parentPath] {
+		names = append(names, name)
+	}
+	return names
+}
+
 // LoadFolderTree fetches all folders and builds the path map
 func (c *FileCache) LoadFolderTree(ctx context.Context, client DrimeClient, userID int64, username string, workspaceID int64) error {
 	folders, err := client.GetUserFolders(ctx, userID, workspaceID)
@@ -201,23 +364,16 @@ func (c *FileCache) LoadFolderTree(ctx context.Context, client DrimeClient, user
 
 	// Add synthetic root entry for "/"
 	// Root has no ID in Drime API - items at root have parent_id = null
-	c.entries["/"] = &FileEntry{
-		ID:      0, // Synthetic ID for root
-		Name:    "/",
-		Type:    "folder",
-		OwnerID: userID,
-		Users: []FileEntryUser{
-			{ID: userID, DisplayName: username, OwnsEntry: true},
-		},
-	}
-	c.byID[0] = c.entries["/"]
-	c.pathByID[0] = "/"
+	root := NewRootEntry()
+	root.OwnerID = userID
+	root.Users = []FileEntryUser{{ID: userID, DisplayName: username, OwnsEntry: true}}
+	c.put("/", root)
 
 	for _, f := range folders {
 		path := buildPath(&f, tempByID)
-		c.entries[path] = &f
-		c.byID[f.ID] = &f
-		c.pathByID[f.ID] = path
+		c.keepMetadata(&f)
+		c.put(path, &f)
+		c.index.put(path, &f)
 	}
 
 	return nil
@@ -241,23 +397,15 @@ func (c *FileCache) LoadVaultFolderTree(ctx context.Context, client DrimeClient,
 	defer c.mu.Unlock()
 
 	// Add synthetic root entry for "/"
-	c.entries["/"] = &FileEntry{
-		ID:      0, // Synthetic ID for root
-		Name:    "/",
-		Type:    "folder",
-		OwnerID: userID,
-		Users: []FileEntryUser{
-			{ID: userID, DisplayName: username, OwnsEntry: true},
-		},
-	}
-	c.byID[0] = c.entries["/"]
-	c.pathByID[0] = "/"
+	root := NewRootEntry()
+	root.OwnerID = userID
+	root.Users = []FileEntryUser{{ID: userID, DisplayName: username, OwnsEntry: true}}
+	c.put("/", root)
 
 	for _, f := range folders {
 		path := buildPath(&f, tempByID)
-		c.entries[path] = &f
-		c.byID[f.ID] = &f
-		c.pathByID[f.ID] = path
+		c.keepMetadata(&f)
+		c.put(path, &f)
 	}
 
 	return nil
@@ -2,10 +2,15 @@ package api_test
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFileCache_LoadFolderTree(t *testing.T) {
@@ -71,3 +76,222 @@ func TestFileCache_PathResolution(t *testing.T) {
 	// Test case insensitivity or normalization if needed?
 	// For now assume case sensitive as per Linux, but Drime might differ. AGENTS.md implies standard shell.
 }
+
+func TestFileCache_MetadataConsistency(t *testing.T) {
+	updated := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache := api.NewFileCache()
+
+	// A listing provides full metadata
+	cache.AddChildren("/", []api.FileEntry{
+		{ID: 1, Name: "docs", Type: "folder", Hash: "h1", Size: 4096, UpdatedAt: updated},
+		{ID: 2, Name: "notes.txt", Type: "text"},
+	})
+
+	// Reloading from an endpoint without metadata keeps what we already had
+	cache.AddChildren("/", []api.FileEntry{{ID: 1, Name: "docs", Type: "folder"}})
+	docs, ok := cache.Get("/docs")
+	assert.True(t, ok)
+	assert.Equal(t, "h1", docs.Hash)
+	assert.Equal(t, int64(4096), docs.Size)
+	assert.Equal(t, updated, docs.UpdatedAt)
+
+	// Missing metadata is fetched once and written back to the cache
+	calls := 0
+	mockClient := &api.MockDrimeClient{
+		GetEntryFunc: func(ctx context.Context, entryID int64, workspaceID int64) (*api.FileEntry, error) {
+			calls++
+			return &api.FileEntry{ID: entryID, Hash: "h2", Size: 12, UpdatedAt: updated}, nil
+		},
+	}
+	notes, _ := cache.Get("/notes.txt")
+	assert.NoError(t, cache.FillMetadata(context.Background(), mockClient, notes, 0))
+	assert.NoError(t, cache.FillMetadata(context.Background(), mockClient, notes, 0))
+	assert.Equal(t, 1, calls)
+
+	cached, _ := cache.Get("/notes.txt")
+	assert.Equal(t, "h2", cached.Hash)
+	assert.Equal(t, int64(12), cached.Size)
+	assert.Equal(t, updated, cached.UpdatedAt)
+}
+
+func TestFileCache_NameIndexFollowsMutations(t *testing.T) {
+	indexPath := filepath.Join(t.TempDir(), "index", "ws-0.json")
+	idx, err := api.LoadNameIndex(indexPath)
+	require.NoError(t, err)
+
+	cache := api.NewFileCache()
+	cache.Add(&api.FileEntry{ID: 0, Name: "/", Type: "folder"}, "/")
+	cache.Add(&api.FileEntry{ID: 1, Name: "Docs", Type: "folder"}, "/Docs")
+	cache.SetIndex(idx)
+	assert.Equal(t, []string{"/Docs"}, idx.Search("doc", true), "existing entries are seeded")
+
+	cache.AddChildren("/Docs", []api.FileEntry{
+		{ID: 2, Name: "Invoice-2024.pdf", Type: "pdf"},
+		{ID: 3, Name: "notes.txt", Type: "text"},
+	})
+	assert.Equal(t, []string{"/Docs/Invoice-2024.pdf"}, idx.Search("invoice", true))
+	assert.Empty(t, idx.Search("invoice", false))
+
+	// A fresh listing without notes.txt drops it from the index
+	cache.AddChildren("/Docs", []api.FileEntry{{ID: 2, Name: "Invoice-2024.pdf", Type: "pdf"}})
+	assert.Empty(t, idx.Search("notes", false))
+
+	// Removing a folder removes everything below it
+	cache.Remove("/Docs")
+	assert.Equal(t, 0, idx.Len())
+
+	cache.Add(&api.FileEntry{ID: 4, Name: "report.md", Type: "text", Size: 42}, "/report.md")
+	require.NoError(t, idx.Save())
+
+	reloaded, err := api.LoadNameIndex(indexPath)
+	require.NoError(t, err)
+	entry, ok := reloaded.Get("/report.md")
+	require.True(t, ok)
+	assert.Equal(t, api.IndexedEntry{ID: 4, Type: "text", Size: 42}, entry)
+}
+
+func TestFileCache_ReplaceChildrenDropsStaleEntries(t *testing.T) {
+	cache := api.NewFileCache()
+	cache.Add(&api.FileEntry{ID: 0, Name: "/", Type: "folder"}, "/")
+	cache.AddChildren("/", []api.FileEntry{
+		{ID: 1, Name: "Docs", Type: "folder"},
+		{ID: 2, Name: "old.txt", Type: "text"},
+	})
+	cache.AddChildren("/Docs", []api.FileEntry{{ID: 3, Name: "a.txt", Type: "text"}})
+
+	// Another client removed old.txt and Docs, and created new.txt
+	cache.ReplaceChildren("/", []api.FileEntry{{ID: 4, Name: "new.txt", Type: "text"}})
+
+	_, ok := cache.Get("/")
+	assert.True(t, ok, "the parent itself stays")
+	for _, gone := range []string{"/old.txt", "/Docs", "/Docs/a.txt"} {
+		_, ok := cache.Get(gone)
+		assert.False(t, ok, gone)
+	}
+	_, ok = cache.GetByID(3)
+	assert.False(t, ok)
+	assert.False(t, cache.HasChildren("/Docs"))
+
+	children := cache.GetChildren("/")
+	require.Len(t, children, 1)
+	assert.Equal(t, "new.txt", children[0].Name)
+}
+
+func TestFileCache_ChildNames(t *testing.T) {
+	cache := api.NewFileCache()
+	cache.Add(&api.FileEntry{ID: 0, Name: "/", Type: "folder"}, "/")
+	cache.Add(&api.FileEntry{ID: 1, Name: "Docs", Type: "folder"}, "/Docs")
+	cache.Add(&api.FileEntry{ID: 2, Name: "Music", Type: "folder"}, "/Music")
+	cache.Add(&api.FileEntry{ID: 3, Name: "a.txt", Type: "text"}, "/Docs/a.txt")
+
+	assert.ElementsMatch(t, []string{"Docs", "Music"}, cache.ChildNames("/"))
+	assert.Equal(t, []string{"a.txt"}, cache.ChildNames("/Docs"))
+	assert.Nil(t, cache.GetChildren("/"), "names are known, the listing is not")
+
+	cache.Remove("/Music")
+	assert.Equal(t, []string{"Docs"}, cache.ChildNames("/"))
+	cache.ReplaceChildren("/Docs", nil)
+	assert.Empty(t, cache.ChildNames("/Docs"))
+}
+
+func TestFileCache_CountForgottenWhenChildrenChange(t *testing.T) {
+	cache := api.NewFileCache()
+	cache.Add(&api.FileEntry{ID: 1, Name: "Docs", Type: "folder"}, "/Docs")
+	cache.SetCount("/Docs", 40)
+	n, ok := cache.Count("/Docs")
+	require.True(t, ok)
+	assert.Equal(t, 40, n)
+
+	cache.Add(&api.FileEntry{ID: 2, Name: "a.txt", Type: "text"}, "/Docs/a.txt")
+	_, ok = cache.Count("/Docs")
+	assert.False(t, ok, "an added child makes the count stale")
+
+	cache.SetCount("/Docs", 41)
+	cache.Remove("/Docs/a.txt")
+	_, ok = cache.Count("/Docs")
+	assert.False(t, ok, "so does a removed one")
+
+	cache.SetCount("/Docs", 40)
+	cache.InvalidateChildren("/Docs")
+	_, ok = cache.Count("/Docs")
+	assert.False(t, ok)
+}
+
+func TestFileEntry_IsRoot(t *testing.T) {
+	mockClient := &api.MockDrimeClient{
+		GetUserFoldersFunc: func(ctx context.Context, userID int64, workspaceID int64) ([]api.FileEntry, error) {
+			return nil, nil
+		},
+	}
+	cache := api.NewFileCache()
+	require.NoError(t, cache.LoadFolderTree(context.Background(), mockClient, 123, "testuser", 0))
+
+	root, ok := cache.Get("/")
+	require.True(t, ok)
+	assert.True(t, root.IsRoot())
+	assert.True(t, api.NewRootEntry().IsRoot())
+
+	var missing *api.FileEntry
+	assert.False(t, missing.IsRoot())
+	assert.False(t, (&api.FileEntry{ID: api.RootID, Name: "notes.txt", Type: "text"}).IsRoot(), "only a folder can be the root")
+	assert.False(t, (&api.FileEntry{ID: 42, Name: "Photos", Type: "folder"}).IsRoot())
+}
+
+// TestFileCache_ConcurrentUse hammers the cache from many goroutines, as
+// directory transfers and the background prefetch do; run with -race.
+func TestFileCache_ConcurrentUse(t *testing.T) {
+	cache := api.NewFileCache()
+	idx, err := api.LoadNameIndex(filepath.Join(t.TempDir(), "index.json"))
+	require.NoError(t, err)
+	cache.SetIndex(idx)
+	cache.AddChildren("/", []api.FileEntry{{ID: 1, Name: "docs", Type: "folder"}})
+
+	client := &api.MockDrimeClient{GetEntryFunc: func(ctx context.Context, entryID int64, workspaceID int64) (*api.FileEntry, error) {
+		return &api.FileEntry{ID: entryID, Size: 1, Hash: "h", UpdatedAt: time.Now()}, nil
+	}}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				// Workers share names, so they update the same entries
+				id := int64(i%40 + 10)
+				name := fmt.Sprintf("f%d.txt", i%40)
+				p := "/docs/" + name
+				if i%3 == 0 {
+					cache.Add(&api.FileEntry{ID: id, Name: name, Type: "text"}, p)
+				}
+				if e, ok := cache.Get(p); ok {
+					_ = cache.FillMetadata(context.Background(), client, e, 0)
+					cache.UpdateMetadata(e, &api.FileEntry{Size: int64(i), Hash: "h", UpdatedAt: time.Now()})
+				}
+				cache.GetByID(id)
+				cache.PathForID(id)
+				cache.GetChildren("/docs")
+				cache.MatchGlob("/docs", "*.txt")
+				cache.AllPaths()
+				cache.HasChildren("/docs")
+				switch i % 50 {
+				case 0:
+					cache.ReplaceChildren("/docs", []api.FileEntry{{ID: id, Name: name, Type: "text"}})
+				case 10:
+					cache.InvalidateChildren("/docs")
+				case 20:
+					cache.MarkChildrenLoaded("/docs")
+				case 30:
+					cache.Remove(p)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	children := cache.GetChildren("/docs")
+	for _, c := range children {
+		e, ok := cache.GetByID(c.ID)
+		require.True(t, ok)
+		assert.Equal(t, c.Name, e.Name)
+	}
+}
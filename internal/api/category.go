@@ -0,0 +1,123 @@
+package api
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Broad kinds of files, for filtering listings regardless of how files are
+// named.
+const (
+	CategoryImage    = "image"
+	CategoryVideo    = "video"
+	CategoryAudio    = "audio"
+	CategoryDocument = "document"
+	CategoryArchive  = "archive"
+	CategoryCode     = "code"
+)
+
+// Categories lists every category EntryCategory can return.
+var Categories = []string{CategoryImage, CategoryVideo, CategoryAudio, CategoryDocument, CategoryArchive, CategoryCode}
+
+// mimeCategories maps full MIME types that their top-level type doesn't
+// classify.
+var mimeCategories = map[string]string{
+	"application/pdf":               CategoryDocument,
+	"application/msword":            CategoryDocument,
+	"application/rtf":               CategoryDocument,
+	"application/epub+zip":          CategoryDocument,
+	"application/vnd.ms-excel":      CategoryDocument,
+	"application/vnd.ms-powerpoint": CategoryDocument,
+	"text/csv":                      CategoryDocument,
+	"text/markdown":                 CategoryDocument,
+	"application/zip":               CategoryArchive,
+	"application/gzip":              CategoryArchive,
+	"application/x-gzip":            CategoryArchive,
+	"application/x-tar":             CategoryArchive,
+	"application/x-bzip2":           CategoryArchive,
+	"application/x-xz":              CategoryArchive,
+	"application/x-7z-compressed":   CategoryArchive,
+	"application/x-rar-compressed":  CategoryArchive,
+	"application/vnd.rar":           CategoryArchive,
+	"application/zstd":              CategoryArchive,
+	"application/json":              CategoryCode,
+	"application/javascript":        CategoryCode,
+	"application/x-yaml":            CategoryCode,
+	"application/xml":               CategoryCode,
+	"application/x-sh":              CategoryCode,
+	"text/html":                     CategoryCode,
+	"text/css":                      CategoryCode,
+	"text/javascript":               CategoryCode,
+	"text/xml":                      CategoryCode,
+}
+
+// typeCategories maps the server's FileEntry.Type values.
+var typeCategories = map[string]string{
+	FileTypeImage:       CategoryImage,
+	FileTypePhotoshop:   CategoryImage,
+	FileTypeVideo:       CategoryVideo,
+	FileTypeAudio:       CategoryAudio,
+	FileTypePDF:         CategoryDocument,
+	FileTypeWord:        CategoryDocument,
+	FileTypeSpreadsheet: CategoryDocument,
+	FileTypePowerPoint:  CategoryDocument,
+	FileTypeText:        CategoryDocument,
+	FileTypeArchive:     CategoryArchive,
+}
+
+// extCategories classifies by extension when the MIME type says nothing.
+var extCategories = map[string]string{}
+
+func init() {
+	for category, exts := range map[string][]string{
+		CategoryImage:    {"jpg", "jpeg", "png", "gif", "webp", "bmp", "tif", "tiff", "svg", "heic", "heif", "avif", "raw", "cr2", "nef", "arw", "dng", "psd", "ico"},
+		CategoryVideo:    {"mp4", "m4v", "mov", "avi", "mkv", "webm", "wmv", "flv", "mpg", "mpeg", "3gp"},
+		CategoryAudio:    {"mp3", "m4a", "aac", "wav", "flac", "ogg", "opus", "wma", "aiff"},
+		CategoryDocument: {"pdf", "doc", "docx", "odt", "rtf", "txt", "md", "xls", "xlsx", "ods", "csv", "ppt", "pptx", "odp", "epub", "pages", "numbers", "key", "tex"},
+		CategoryArchive:  {"zip", "tar", "gz", "tgz", "bz2", "xz", "zst", "7z", "rar", "iso", "dmg"},
+		CategoryCode:     {"go", "py", "js", "mjs", "ts", "tsx", "jsx", "java", "kt", "c", "h", "cc", "cpp", "hpp", "cs", "rs", "rb", "php", "swift", "scala", "sh", "bash", "zsh", "ps1", "sql", "html", "htm", "css", "scss", "json", "yaml", "yml", "toml", "xml", "ini", "lua", "pl", "r", "dart", "vue", "svelte", "mod", "sum"},
+	} {
+		for _, ext := range exts {
+			extCategories[ext] = category
+		}
+	}
+}
+
+// EntryCategory returns the category of e, or "" for folders and files that
+// fit none. The MIME type decides when it is specific; otherwise, for
+// instance for text/plain or when the listing had none, the extension does,
+// then the server's Type.
+func EntryCategory(e *FileEntry) string {
+	if e.Type == FileTypeFolder {
+		return ""
+	}
+	if c := mimeCategory(e.Mime); c != "" {
+		return c
+	}
+	if c := extCategories[strings.ToLower(strings.TrimPrefix(filepath.Ext(e.Name), "."))]; c != "" {
+		return c
+	}
+	return typeCategories[e.Type]
+}
+
+func mimeCategory(mime string) string {
+	mime, _, _ = strings.Cut(strings.ToLower(mime), ";")
+	mime = strings.TrimSpace(mime)
+	if c, ok := mimeCategories[mime]; ok {
+		return c
+	}
+	switch {
+	case strings.HasPrefix(mime, "image/"):
+		return CategoryImage
+	case strings.HasPrefix(mime, "video/"):
+		return CategoryVideo
+	case strings.HasPrefix(mime, "audio/"):
+		return CategoryAudio
+	case strings.HasPrefix(mime, "text/x-"):
+		return CategoryCode
+	case strings.HasPrefix(mime, "application/vnd.openxmlformats-officedocument."),
+		strings.HasPrefix(mime, "application/vnd.oasis.opendocument."):
+		return CategoryDocument
+	}
+	return ""
+}
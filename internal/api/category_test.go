@@ -0,0 +1,26 @@
+package api
+
+import "testing"
+
+func TestEntryCategory(t *testing.T) {
+	tests := []struct {
+		entry FileEntry
+		want  string
+	}{
+		{FileEntry{Name: "photos", Type: "folder"}, ""},
+		{FileEntry{Name: "IMG_0001", Type: "image", Mime: "image/jpeg"}, CategoryImage},
+		{FileEntry{Name: "clip.bin", Mime: "video/mp4; codecs=avc1"}, CategoryVideo},
+		{FileEntry{Name: "song.FLAC"}, CategoryAudio},
+		{FileEntry{Name: "report", Type: "pdf"}, CategoryDocument},
+		{FileEntry{Name: "slides.pptx", Mime: "application/vnd.openxmlformats-officedocument.presentationml.presentation"}, CategoryDocument},
+		{FileEntry{Name: "main.go", Type: "text", Mime: "text/plain"}, CategoryCode},
+		{FileEntry{Name: "notes", Type: "text", Mime: "text/plain"}, CategoryDocument},
+		{FileEntry{Name: "site.tar.gz", Mime: "application/octet-stream"}, CategoryArchive},
+		{FileEntry{Name: "data.bin", Type: "file", Mime: "application/octet-stream"}, ""},
+	}
+	for _, tt := range tests {
+		if got := EntryCategory(&tt.entry); got != tt.want {
+			t.Errorf("EntryCategory(%s, %q, %q) = %q, want %q", tt.entry.Name, tt.entry.Type, tt.entry.Mime, got, tt.want)
+		}
+	}
+}
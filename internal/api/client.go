@@ -51,12 +51,14 @@ type DrimeClient interface {
 	// Navigation & Listing
 	GetUserFolders(ctx context.Context, userID int64, workspaceID int64) ([]FileEntry, error)
 	GetFolderPath(ctx context.Context, folderHash string, workspaceID int64) ([]FileEntry, error)
+	GetFolderCount(ctx context.Context, folderID int64, workspaceID int64) (int, error)
 	ListByParentID(ctx context.Context, parentID *int64) ([]FileEntry, error)
 	ListByParentIDWithOptions(ctx context.Context, parentID *int64, opts *ListEntriesOptions) ([]FileEntry, error)
 
 	// Starring
 	StarEntries(ctx context.Context, entryIDs []int64, workspaceID int64) error
 	UnstarEntries(ctx context.Context, entryIDs []int64, workspaceID int64) error
+	ListTags(ctx context.Context, query string) ([]Tag, error)
 
 	// Trash
 	RestoreEntries(ctx context.Context, entryIDs []int64, workspaceID int64) error
@@ -66,8 +68,10 @@ type DrimeClient interface {
 
 	// Transfers
 	Upload(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*FileEntry, error)
+	UploadWithOptions(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *UploadOptions) (*FileEntry, error)
 	Download(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*FileEntry, error)
 	DownloadWithOptions(ctx context.Context, hash string, w io.Writer, progress func(int64, int64), opts *DownloadOptions) (*FileEntry, error)
+	AbortMultipart(ctx context.Context, key, uploadID string) error
 
 	// Management
 	CreateFolder(ctx context.Context, name string, parentID *int64, workspaceID int64) (*FileEntry, error)
@@ -76,6 +80,7 @@ type DrimeClient interface {
 	MoveEntries(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) error
 	CopyEntries(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]FileEntry, error)
 	RenameEntry(ctx context.Context, entryID int64, newName string, workspaceID int64) (*FileEntry, error)
+	TouchEntry(ctx context.Context, entryID int64, name string, workspaceID int64) (*FileEntry, error)
 	GetSpaceUsage(ctx context.Context, workspaceID int64) (*SpaceUsage, error)
 	ExtractEntry(ctx context.Context, entryID int64, parentID *int64, workspaceID int64) error
 	GetEntry(ctx context.Context, entryID int64, workspaceID int64) (*FileEntry, error)
@@ -90,6 +95,7 @@ type DrimeClient interface {
 	UpdateShareableLink(ctx context.Context, entryID int64, req ShareableLinkRequest) (*ShareableLink, error)
 	DeleteShareableLink(ctx context.Context, entryID int64) error
 	GetShareableLink(ctx context.Context, entryID int64) (*ShareableLink, error)
+	GetShareableLinkByHash(ctx context.Context, hash string) (*ShareableLink, error)
 	ShareEntry(ctx context.Context, entryID int64, emails []string, permissions []string) error
 
 	// File Requests
@@ -132,21 +138,26 @@ type MockDrimeClient struct {
 
 	GetUserFoldersFunc            func(ctx context.Context, userID int64, workspaceID int64) ([]FileEntry, error)
 	GetFolderPathFunc             func(ctx context.Context, folderHash string, workspaceID int64) ([]FileEntry, error)
+	GetFolderCountFunc            func(ctx context.Context, folderID int64, workspaceID int64) (int, error)
 	ListByParentIDFunc            func(ctx context.Context, parentID *int64) ([]FileEntry, error)
 	ListByParentIDWithOptionsFunc func(ctx context.Context, parentID *int64, opts *ListEntriesOptions) ([]FileEntry, error)
 	StarEntriesFunc               func(ctx context.Context, entryIDs []int64, workspaceID int64) error
 	UnstarEntriesFunc             func(ctx context.Context, entryIDs []int64, workspaceID int64) error
+	ListTagsFunc                  func(ctx context.Context, query string) ([]Tag, error)
 	RestoreEntriesFunc            func(ctx context.Context, entryIDs []int64, workspaceID int64) error
 	EmptyTrashFunc                func(ctx context.Context, workspaceID int64) error
 	UploadFunc                    func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*FileEntry, error)
+	UploadWithOptionsFunc         func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *UploadOptions) (*FileEntry, error)
 	DownloadFunc                  func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*FileEntry, error)
 	DownloadWithOptionsFunc       func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64), opts *DownloadOptions) (*FileEntry, error)
+	AbortMultipartFunc            func(ctx context.Context, key, uploadID string) error
 	CreateFolderFunc              func(ctx context.Context, name string, parentID *int64, workspaceID int64) (*FileEntry, error)
 	DeleteEntriesFunc             func(ctx context.Context, entryIDs []int64, workspaceID int64) error
 	DeleteEntriesForeverFunc      func(ctx context.Context, entryIDs []int64, workspaceID int64) error
 	MoveEntriesFunc               func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) error
 	CopyEntriesFunc               func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]FileEntry, error)
 	RenameEntryFunc               func(ctx context.Context, entryID int64, newName string, workspaceID int64) (*FileEntry, error)
+	TouchEntryFunc                func(ctx context.Context, entryID int64, name string, workspaceID int64) (*FileEntry, error)
 	GetSpaceUsageFunc             func(ctx context.Context, workspaceID int64) (*SpaceUsage, error)
 	ExtractEntryFunc              func(ctx context.Context, entryID int64, parentID *int64, workspaceID int64) error
 	GetEntryFunc                  func(ctx context.Context, entryID int64, workspaceID int64) (*FileEntry, error)
@@ -165,12 +176,13 @@ type MockDrimeClient struct {
 	DownloadEncryptedFunc  func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*FileEntry, error)
 	UploadToVaultFunc      func(ctx context.Context, encryptedContent []byte, name string, parentID *int64, vaultID int64, ivBase64 string) (*FileEntry, error)
 	// Sharing mock functions
-	CreateShareableLinkFunc func(ctx context.Context, entryID int64, req ShareableLinkRequest) (*ShareableLink, error)
-	CreateFileRequestFunc   func(ctx context.Context, entryID int64, title, description string) (*ShareableLink, error)
-	UpdateShareableLinkFunc func(ctx context.Context, entryID int64, req ShareableLinkRequest) (*ShareableLink, error)
-	DeleteShareableLinkFunc func(ctx context.Context, entryID int64) error
-	GetShareableLinkFunc    func(ctx context.Context, entryID int64) (*ShareableLink, error)
-	ShareEntryFunc          func(ctx context.Context, entryID int64, emails []string, permissions []string) error
+	CreateShareableLinkFunc    func(ctx context.Context, entryID int64, req ShareableLinkRequest) (*ShareableLink, error)
+	CreateFileRequestFunc      func(ctx context.Context, entryID int64, title, description string) (*ShareableLink, error)
+	UpdateShareableLinkFunc    func(ctx context.Context, entryID int64, req ShareableLinkRequest) (*ShareableLink, error)
+	DeleteShareableLinkFunc    func(ctx context.Context, entryID int64) error
+	GetShareableLinkFunc       func(ctx context.Context, entryID int64) (*ShareableLink, error)
+	GetShareableLinkByHashFunc func(ctx context.Context, hash string) (*ShareableLink, error)
+	ShareEntryFunc             func(ctx context.Context, entryID int64, emails []string, permissions []string) error
 	// File Requests mock functions
 	ListFileRequestsFunc  func(ctx context.Context) ([]FileRequest, error)
 	DeleteFileRequestFunc func(ctx context.Context, requestID int64) error
@@ -279,6 +291,13 @@ func (m *MockDrimeClient) GetFolderPath(ctx context.Context, folderHash string,
 	return nil, nil
 }
 
+func (m *MockDrimeClient) GetFolderCount(ctx context.Context, folderID int64, workspaceID int64) (int, error) {
+	if m.GetFolderCountFunc != nil {
+		return m.GetFolderCountFunc(ctx, folderID, workspaceID)
+	}
+	return 0, nil
+}
+
 func (m *MockDrimeClient) ListByParentID(ctx context.Context, parentID *int64) ([]FileEntry, error) {
 	return m.ListByParentIDFunc(ctx, parentID)
 }
@@ -304,6 +323,13 @@ func (m *MockDrimeClient) UnstarEntries(ctx context.Context, entryIDs []int64, w
 	return nil
 }
 
+func (m *MockDrimeClient) ListTags(ctx context.Context, query string) ([]Tag, error) {
+	if m.ListTagsFunc != nil {
+		return m.ListTagsFunc(ctx, query)
+	}
+	return nil, nil
+}
+
 func (m *MockDrimeClient) RestoreEntries(ctx context.Context, entryIDs []int64, workspaceID int64) error {
 	if m.RestoreEntriesFunc != nil {
 		return m.RestoreEntriesFunc(ctx, entryIDs, workspaceID)
@@ -322,6 +348,14 @@ func (m *MockDrimeClient) Upload(ctx context.Context, reader io.Reader, name str
 	return m.UploadFunc(ctx, reader, name, parentID, size, workspaceID)
 }
 
+func (m *MockDrimeClient) UploadWithOptions(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *UploadOptions) (*FileEntry, error) {
+	if m.UploadWithOptionsFunc != nil {
+		return m.UploadWithOptionsFunc(ctx, reader, name, parentID, size, workspaceID, opts)
+	}
+	// Fall back to regular upload if not mocked
+	return m.UploadFunc(ctx, reader, name, parentID, size, workspaceID)
+}
+
 func (m *MockDrimeClient) Download(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*FileEntry, error) {
 	return m.DownloadFunc(ctx, hash, w, progress)
 }
@@ -334,6 +368,13 @@ func (m *MockDrimeClient) DownloadWithOptions(ctx context.Context, hash string,
 	return m.DownloadFunc(ctx, hash, w, progress)
 }
 
+func (m *MockDrimeClient) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	if m.AbortMultipartFunc != nil {
+		return m.AbortMultipartFunc(ctx, key, uploadID)
+	}
+	return nil
+}
+
 func (m *MockDrimeClient) CreateFolder(ctx context.Context, name string, parentID *int64, workspaceID int64) (*FileEntry, error) {
 	return m.CreateFolderFunc(ctx, name, parentID, workspaceID)
 }
@@ -361,6 +402,13 @@ func (m *MockDrimeClient) RenameEntry(ctx context.Context, entryID int64, newNam
 	return m.RenameEntryFunc(ctx, entryID, newName, workspaceID)
 }
 
+func (m *MockDrimeClient) TouchEntry(ctx context.Context, entryID int64, name string, workspaceID int64) (*FileEntry, error) {
+	if m.TouchEntryFunc == nil {
+		return nil, nil
+	}
+	return m.TouchEntryFunc(ctx, entryID, name, workspaceID)
+}
+
 func (m *MockDrimeClient) GetSpaceUsage(ctx context.Context, workspaceID int64) (*SpaceUsage, error) {
 	return m.GetSpaceUsageFunc(ctx, workspaceID)
 }
@@ -370,7 +418,10 @@ func (m *MockDrimeClient) ExtractEntry(ctx context.Context, entryID int64, paren
 }
 
 func (m *MockDrimeClient) GetEntry(ctx context.Context, entryID int64, workspaceID int64) (*FileEntry, error) {
-	return m.GetEntryFunc(ctx, entryID, workspaceID)
+	if m.GetEntryFunc != nil {
+		return m.GetEntryFunc(ctx, entryID, workspaceID)
+	}
+	return nil, nil
 }
 
 func (m *MockDrimeClient) Search(ctx context.Context, query string) ([]FileEntry, error) {
@@ -493,6 +544,13 @@ func (m *MockDrimeClient) GetShareableLink(ctx context.Context, entryID int64) (
 	return nil, nil
 }
 
+func (m *MockDrimeClient) GetShareableLinkByHash(ctx context.Context, hash string) (*ShareableLink, error) {
+	if m.GetShareableLinkByHashFunc != nil {
+		return m.GetShareableLinkByHashFunc(ctx, hash)
+	}
+	return nil, nil
+}
+
 func (m *MockDrimeClient) ShareEntry(ctx context.Context, entryID int64, emails []string, permissions []string) error {
 	if m.ShareEntryFunc != nil {
 		return m.ShareEntryFunc(ctx, entryID, emails, permissions)
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ClockSkewWarnThreshold is the skew above which the shell warns that
+// comparisons between local and server times may be off.
+const ClockSkewWarnThreshold = time.Minute
+
+// clockSkewNoise is the skew below which the clocks count as in sync: the
+// Date header only has one-second resolution.
+const clockSkewNoise = 2 * time.Second
+
+// ClockSkewFromDate returns how far the server's clock is ahead of the local
+// one, given a response's Date header and the local times the request was
+// sent and its response received. Skews within the header's resolution are
+// reported as 0.
+func ClockSkewFromDate(date string, sent, received time.Time) (time.Duration, error) {
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Date header %q: %w", date, err)
+	}
+	// The header is truncated to the second, and the server stamped it
+	// somewhere between sending and receiving
+	serverTime = serverTime.Add(500 * time.Millisecond)
+	local := sent.Add(received.Sub(sent) / 2)
+	skew := serverTime.Sub(local)
+	if skew > -clockSkewNoise && skew < clockSkewNoise {
+		return 0, nil
+	}
+	return skew.Round(time.Second), nil
+}
+
+// MeasureClockSkew asks the server for its time and returns how far its
+// clock is ahead of the local one (negative when behind).
+func (c *HTTPClient) MeasureClockSkew(ctx context.Context) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.BaseURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	sent := time.Now()
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	received := time.Now()
+	resp.Body.Close()
+
+	date := resp.Header.Get("Date")
+	if date == "" {
+		return 0, fmt.Errorf("server sent no Date header")
+	}
+	return ClockSkewFromDate(date, sent, received)
+}
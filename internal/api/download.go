@@ -2,15 +2,27 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 )
 
+// ErrRangeNotHonored is returned for a ranged download with RequireRange
+// set when the server doesn't answer with the requested range.
+var ErrRangeNotHonored = errors.New("server did not honor the Range request")
+
 // DownloadOptions configures a download operation
 type DownloadOptions struct {
 	// ResumeFrom specifies the byte offset to resume from (for Range requests)
 	ResumeFrom int64
+	// Length limits the download to that many bytes from ResumeFrom
+	// (0 = to the end of the file)
+	Length int64
+	// RequireRange fails a ranged download with ErrRangeNotHonored unless
+	// the server answers 206 Partial Content starting at ResumeFrom, so a
+	// whole file sent in reply is never taken for the requested bytes
+	RequireRange bool
 }
 
 func (c *HTTPClient) Download(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*FileEntry, error) {
@@ -31,9 +43,14 @@ func (c *HTTPClient) DownloadWithOptions(ctx context.Context, hash string, w io.
 
 	// Add Range header for resumable downloads
 	resumeOffset := int64(0)
-	if opts != nil && opts.ResumeFrom > 0 {
-		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", opts.ResumeFrom))
+	ranged := opts != nil && (opts.ResumeFrom > 0 || opts.Length > 0)
+	if ranged {
 		resumeOffset = opts.ResumeFrom
+		if opts.Length > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", opts.ResumeFrom, opts.ResumeFrom+opts.Length-1))
+		} else {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", opts.ResumeFrom))
+		}
 	}
 
 	resp, err := c.DoWithRetry(req)
@@ -46,6 +63,9 @@ func (c *HTTPClient) DownloadWithOptions(ctx context.Context, hash string, w io.
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
 		return nil, fmt.Errorf("Download failed: %s", resp.Status)
 	}
+	if ranged && opts.RequireRange && !rangeStartsAt(resp, resumeOffset) {
+		return nil, fmt.Errorf("%w (asked for bytes from %d, got %s)", ErrRangeNotHonored, resumeOffset, resp.Status)
+	}
 
 	// Try to get metadata from headers
 	var entry FileEntry
@@ -97,6 +117,19 @@ func (c *HTTPClient) DownloadWithOptions(ctx context.Context, hash string, w io.
 	return &entry, nil
 }
 
+// rangeStartsAt reports whether resp is a 206 whose Content-Range starts at
+// offset.
+func rangeStartsAt(resp *http.Response, offset int64) bool {
+	if resp.StatusCode != http.StatusPartialContent {
+		return false
+	}
+	var start int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start); err != nil {
+		return false
+	}
+	return start == offset
+}
+
 func (c *HTTPClient) CheckResumeSupport(ctx context.Context, hash string) (bool, int64, error) {
 	url := fmt.Sprintf("%s/file-entries/download/%s", c.BaseURL, hash)
 
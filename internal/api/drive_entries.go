@@ -101,11 +101,7 @@ func (c *HTTPClient) fetchDriveFileEntriesPage(ctx context.Context, parentID *in
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		msg := extractAPIError(body)
-		if msg == "" {
-			msg = resp.Status
-		}
-		return nil, fmt.Errorf("API error: %s", msg)
+		return nil, newAPIError("API request", req.URL.Path, resp.StatusCode, body)
 	}
 
 	var page driveFileEntriesPage
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// APIError represents a structured error from the Drime API
+type APIError struct {
+	Message    string `json:"message"`
+	Status     string `json:"status"`
+	RetryAfter int    `json:"retry_after,omitempty"` // From Retry-After header
+
+	// StatusCode is the HTTP status returned by the server.
+	StatusCode int `json:"-"`
+	// Endpoint is the API path that failed (without query string).
+	Endpoint string `json:"-"`
+	// Op names the failed operation and prefixes the message, e.g. "DeleteEntries".
+	Op string `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	if e.Op != "" {
+		return fmt.Sprintf("%s failed: %s", e.Op, e.Message)
+	}
+	return e.Message
+}
+
+// newAPIError builds an APIError from a non-success response body.
+func newAPIError(op, endpoint string, status int, body []byte) *APIError {
+	msg := extractAPIError(body)
+	if msg == "" {
+		msg = fmt.Sprintf("%d %s", status, http.StatusText(status))
+	}
+	return &APIError{
+		Message:    msg,
+		StatusCode: status,
+		Endpoint:   endpoint,
+		Op:         op,
+	}
+}
+
+// statusOf returns the HTTP status of the first APIError in err's chain, or 0.
+func statusOf(err error) (int, *APIError) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode, apiErr
+	}
+	return 0, nil
+}
+
+// IsNotFound reports whether err is an API error for a missing resource.
+func IsNotFound(err error) bool {
+	status, _ := statusOf(err)
+	return status == http.StatusNotFound
+}
+
+// IsPermissionDenied reports whether err is an API error for a forbidden action.
+func IsPermissionDenied(err error) bool {
+	status, _ := statusOf(err)
+	return status == http.StatusForbidden
+}
+
+// IsQuotaExceeded reports whether err means the account is out of storage.
+// The API has no dedicated status for this, so besides 413/507 it falls back
+// to the message of a 4xx validation response. "space" alone is not enough,
+// since it also matches every "workspace" error.
+func IsQuotaExceeded(err error) bool {
+	status, apiErr := statusOf(err)
+	switch {
+	case apiErr == nil:
+		return false
+	case status == http.StatusRequestEntityTooLarge, status == http.StatusInsufficientStorage:
+		return true
+	case status >= 400 && status < 500:
+		msg := strings.ToLower(apiErr.Message)
+		for _, hint := range []string{"quota", "storage space", "storage limit", "exhausted"} {
+			if strings.Contains(msg, hint) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsNetworkError reports whether err means the server could not be reached
+// at all (refused or reset connections, DNS failures, timeouts), as opposed
+// to the server answering with an error. Cancellation by the user and an
+// expired deadline (which net.Error also matches, as a timeout) are not
+// network errors.
+func IsNetworkError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// ErrorCode classifies err for programs reading errors: "token_expired",
+// "canceled", "network", "quota_exceeded", "not_found", "permission_denied",
+// "api_error" for any other API failure, and "error" for the rest.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		return "token_expired"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case IsNetworkError(err):
+		return "network"
+	case IsQuotaExceeded(err):
+		return "quota_exceeded"
+	case IsNotFound(err):
+		return "not_found"
+	case IsPermissionDenied(err):
+		return "permission_denied"
+	}
+	if status, _ := statusOf(err); status != 0 {
+		return "api_error"
+	}
+	return "error"
+}
+
+// ErrorHint returns a short human explanation for well-known API failures
+// ("not found", "permission denied", "out of space"), or "" if none applies.
+// Network failures suggest running 'reconnect'.
+func ErrorHint(err error) string {
+	switch {
+	case IsNetworkError(err):
+		return "connection lost? run 'reconnect'"
+	case IsQuotaExceeded(err):
+		return "out of space"
+	case IsNotFound(err):
+		return "not found"
+	case IsPermissionDenied(err):
+		return "permission denied"
+	}
+	return ""
+}
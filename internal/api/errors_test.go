@@ -0,0 +1,140 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIError_CarriesStatusAndEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Entry not found"}`))
+	}))
+	defer server.Close()
+
+	client := api.NewHTTPClient(server.URL, "token")
+	_, err := client.GetEntry(context.Background(), 42, 0)
+	require.Error(t, err)
+
+	var apiErr *api.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "/file-entries/42", apiErr.Endpoint)
+	assert.Equal(t, "GetEntry failed: Entry not found", err.Error())
+	assert.True(t, api.IsNotFound(err))
+	assert.False(t, api.IsQuotaExceeded(err))
+}
+
+func TestAPIError_FromEveryCallSite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/folders":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": "Not allowed here"}`))
+		case "/file-entries/duplicate":
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := api.NewHTTPClient(server.URL, "token")
+	client.MaxRetries = 0
+	ctx := context.Background()
+
+	_, err := client.CreateFolder(ctx, "docs", nil, 0)
+	assert.True(t, api.IsPermissionDenied(err))
+	assert.Equal(t, "CreateFolder failed: Not allowed here", err.Error())
+
+	_, err = client.CopyEntries(ctx, []int64{1}, nil, 0, nil)
+	assert.True(t, api.IsQuotaExceeded(err))
+	assert.Equal(t, "CopyEntries failed: 413 Request Entity Too Large", err.Error())
+
+	_, err = client.GetSpaceUsage(ctx, 0)
+	assert.Equal(t, "not_found", api.ErrorCode(err))
+
+	_, err = client.Whoami(ctx)
+	var apiErr *api.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "/cli/loggedUser", apiErr.Endpoint)
+	assert.True(t, api.IsNotFound(err))
+}
+
+func TestAPIError_Helpers(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		notFound  bool
+		denied    bool
+		quota     bool
+		errorHint string
+		code      string
+	}{
+		{"plain error", errors.New("boom"), false, false, false, "", "error"},
+		{"not found", &api.APIError{StatusCode: 404, Message: "gone"}, true, false, false, "not found", "not_found"},
+		{"forbidden", &api.APIError{StatusCode: 403, Message: "nope"}, false, true, false, "permission denied", "permission_denied"},
+		{"insufficient storage", &api.APIError{StatusCode: 507, Message: "full"}, false, false, true, "out of space", "quota_exceeded"},
+		{"validation quota message", &api.APIError{StatusCode: 422, Message: "You have exhausted your available space"}, false, false, true, "out of space", "quota_exceeded"},
+		{"workspace message", &api.APIError{StatusCode: 422, Message: "The selected workspace id is invalid"}, false, false, false, "", "api_error"},
+		{"storage space message", &api.APIError{StatusCode: 422, Message: "Not enough storage space"}, false, false, true, "out of space", "quota_exceeded"},
+		{"wrapped", fmt.Errorf("rm: %w", &api.APIError{StatusCode: 404}), true, false, false, "not found", "not_found"},
+		{"server error", &api.APIError{StatusCode: 500, Message: "oops"}, false, false, false, "", "api_error"},
+		{"expired token", fmt.Errorf("ls: %w", api.ErrTokenExpired), false, false, false, "", "token_expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.notFound, api.IsNotFound(tt.err))
+			assert.Equal(t, tt.denied, api.IsPermissionDenied(tt.err))
+			assert.Equal(t, tt.quota, api.IsQuotaExceeded(tt.err))
+			assert.Equal(t, tt.errorHint, api.ErrorHint(tt.err))
+			assert.Equal(t, tt.code, api.ErrorCode(tt.err))
+		})
+	}
+}
+
+func TestIsNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	client := api.NewHTTPClient(url, "token")
+	client.MaxRetries = 0
+	_, err := client.GetEntry(context.Background(), 42, 0)
+	require.Error(t, err)
+	assert.True(t, api.IsNetworkError(err))
+	assert.Equal(t, "connection lost? run 'reconnect'", api.ErrorHint(err))
+
+	assert.False(t, api.IsNetworkError(&api.APIError{StatusCode: 500}))
+	assert.False(t, api.IsNetworkError(fmt.Errorf("ls: %w", context.Canceled)))
+}
+
+func TestIsNetworkError_DeadlineIsNotLostConnection(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := api.NewHTTPClient(server.URL, "token")
+	client.MaxRetries = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.GetEntry(ctx, 42, 0)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, api.IsNetworkError(err))
+	assert.Empty(t, api.ErrorHint(err))
+	assert.NotEqual(t, "network", api.ErrorCode(err))
+}
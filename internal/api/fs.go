@@ -31,27 +31,26 @@ func (c *HTTPClient) CreateFolder(ctx context.Context, name string, parentID *in
 		return nil, err
 	}
 
-	// Check if we got HTML (likely an error page or SPA redirect)
-	if len(respBody) > 0 && respBody[0] == '<' {
-		return nil, fmt.Errorf("CreateFolder failed: got HTML response (status %d) - API may be unavailable or endpoint incorrect", status)
-	}
+	// HTML is likely an error page or SPA redirect
+	isHTML := len(respBody) > 0 && respBody[0] == '<'
+	const htmlHint = "got HTML response - API may be unavailable or endpoint incorrect"
 
 	if status != http.StatusOK && status != http.StatusCreated {
-		// Try to extract error message from JSON response
+		apiErr := newAPIError("CreateFolder", "/folders", status, respBody)
+		// Some failures carry their message under "error"
 		var errResp struct {
 			Message string `json:"message"`
 			Error   string `json:"error"`
 		}
-		if json.Unmarshal(respBody, &errResp) == nil {
-			msg := errResp.Message
-			if msg == "" {
-				msg = errResp.Error
-			}
-			if msg != "" {
-				return nil, fmt.Errorf("CreateFolder failed: %s", msg)
-			}
+		if isHTML {
+			apiErr.Message = fmt.Sprintf("%s (status %d)", htmlHint, status)
+		} else if json.Unmarshal(respBody, &errResp) == nil && errResp.Message == "" && errResp.Error != "" {
+			apiErr.Message = errResp.Error
 		}
-		return nil, fmt.Errorf("CreateFolder failed: %d %s", status, http.StatusText(status))
+		return nil, apiErr
+	}
+	if isHTML {
+		return nil, fmt.Errorf("CreateFolder failed: %s (status %d)", htmlHint, status)
 	}
 
 	var res CreateFolderResponse
@@ -80,11 +79,7 @@ func (c *HTTPClient) DeleteEntries(ctx context.Context, entryIDs []int64, worksp
 		return err
 	}
 	if status != http.StatusOK {
-		msg := extractAPIError(respBody)
-		if msg == "" {
-			msg = fmt.Sprintf("%d %s", status, http.StatusText(status))
-		}
-		return fmt.Errorf("DeleteEntries failed: %s", msg)
+		return newAPIError("DeleteEntries", "/file-entries/delete", status, respBody)
 	}
 	return nil
 }
@@ -119,15 +114,16 @@ func (c *HTTPClient) MoveEntries(ctx context.Context, entryIDs []int64, destinat
 		return err
 	}
 	if status != http.StatusOK {
-		msg := extractAPIError(respBody)
-		if msg == "" {
-			msg = fmt.Sprintf("%d %s", status, http.StatusText(status))
-		}
-		return fmt.Errorf("MoveEntries failed: %s", msg)
+		return newAPIError("MoveEntries", "/file-entries/move", status, respBody)
 	}
 	return nil
 }
 
+// CopyEntries duplicates entries into destinationParentID, in
+// destinationWorkspaceID when set. The call is synchronous: the API answers
+// once the copies exist, listing them in its response, and documents no
+// queued or pending state (see /file-entries/duplicate in
+// drime-openapi.yaml), so callers may cache the returned entries right away.
 func (c *HTTPClient) CopyEntries(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]FileEntry, error) {
 	// For cross-workspace copies, the URL and body workspaceId must be the DESTINATION workspace
 	queryWsID := workspaceID
@@ -153,7 +149,7 @@ func (c *HTTPClient) CopyEntries(ctx context.Context, entryIDs []int64, destinat
 	}
 
 	if status != http.StatusOK {
-		return nil, fmt.Errorf("CopyEntries failed: %d %s - %s", status, http.StatusText(status), string(respBody))
+		return nil, newAPIError("CopyEntries", "/file-entries/duplicate", status, respBody)
 	}
 
 	var result struct {
@@ -188,11 +184,33 @@ func (c *HTTPClient) RenameEntry(ctx context.Context, entryID int64, newName str
 		return nil, err
 	}
 	if status != http.StatusOK {
-		msg := extractAPIError(respBody)
-		if msg == "" {
-			msg = fmt.Sprintf("%d %s", status, http.StatusText(status))
-		}
-		return nil, fmt.Errorf("RenameEntry failed: %s", msg)
+		return nil, newAPIError("RenameEntry", path, status, respBody)
+	}
+
+	var res RenameResponse
+	if err := json.Unmarshal(respBody, &res); err != nil {
+		return nil, err
+	}
+	return &res.FileEntry, nil
+}
+
+// TouchEntry updates an entry in place, keeping its name, so the server
+// bumps its modification time to now. The update endpoint only takes a name
+// and a description, so there is no way to ask for another time.
+func (c *HTTPClient) TouchEntry(ctx context.Context, entryID int64, name string, workspaceID int64) (*FileEntry, error) {
+	reqBody := map[string]interface{}{
+		"name": name,
+	}
+
+	q := url.Values{}
+	q.Set("workspaceId", fmt.Sprintf("%d", workspaceID))
+	path := fmt.Sprintf("/file-entries/%d", entryID)
+	status, respBody, err := c.do(ctx, http.MethodPut, path, q, reqBody, true)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, newAPIError("TouchEntry", path, status, respBody)
 	}
 
 	var res RenameResponse
@@ -221,7 +239,8 @@ func (c *HTTPClient) ExtractEntry(ctx context.Context, entryID int64, parentID *
 		return err
 	}
 	if status != http.StatusOK {
-		return fmt.Errorf("extraction failed: %s", string(respBody))
+		// Unsupported archive types come back as a 4xx with a message
+		return newAPIError("extraction", path, status, respBody)
 	}
 	return nil
 }
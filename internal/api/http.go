@@ -33,6 +33,7 @@ type HTTPClient struct {
 	Token          string
 	BaseRetryDelay time.Duration
 	MaxRetries     int
+	Journal        *MultipartJournal // Records in-progress multipart uploads (optional)
 }
 
 func NewHTTPClient(baseURL, token string) *HTTPClient {
@@ -45,6 +46,35 @@ func NewHTTPClient(baseURL, token string) *HTTPClient {
 	}
 }
 
+// ResetConnections drops every pooled connection so the next request dials
+// the server afresh. After a network change (sleep, VPN, Wi-Fi switch) kept-
+// alive sockets can hang until they time out; a fresh transport avoids that.
+func (c *HTTPClient) ResetConnections() {
+	c.Client.CloseIdleConnections()
+	if t, ok := c.Client.Transport.(*http.Transport); ok {
+		c.Client.Transport = t.Clone()
+	} else if c.Client.Transport == nil {
+		c.Client.Transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+}
+
+// Proxy returns the proxy that requests to BaseURL go through, or nil when
+// they connect directly.
+func (c *HTTPClient) Proxy() (*url.URL, error) {
+	proxy := http.ProxyFromEnvironment
+	if t, ok := c.Client.Transport.(*http.Transport); ok {
+		if t.Proxy == nil {
+			return nil, nil
+		}
+		proxy = t.Proxy
+	}
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return proxy(req)
+}
+
 // DoWithRetry executes a request with exponential backoff and jitter
 // NOTE: For POST/PUT requests with bodies, the body must be a *bytes.Reader or *bytes.Buffer
 // so it can be reset for retries. Otherwise, retries after body consumption will fail.
@@ -113,7 +143,12 @@ func (c *HTTPClient) DoWithRetry(req *http.Request) (*http.Response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("request failed after %d retries: %w", c.MaxRetries, err)
 	}
-	return nil, fmt.Errorf("server returned %d after %d retries", resp.StatusCode, c.MaxRetries)
+	apiErr := &APIError{
+		Message:    fmt.Sprintf("server returned %d after %d retries", resp.StatusCode, c.MaxRetries),
+		StatusCode: resp.StatusCode,
+		Endpoint:   req.URL.Path,
+	}
+	return nil, apiErr
 }
 
 // isSSLError checks if an error is SSL/TLS related
@@ -181,7 +216,7 @@ func (c *HTTPClient) GetSpaceUsage(ctx context.Context, workspaceID int64) (*Spa
 		return nil, err
 	}
 	if status != http.StatusOK {
-		return nil, fmt.Errorf("GetSpaceUsage failed: %d %s", status, http.StatusText(status))
+		return nil, newAPIError("GetSpaceUsage", "/user/space-usage", status, body)
 	}
 
 	var res SpaceUsage
@@ -197,7 +232,7 @@ func (c *HTTPClient) Whoami(ctx context.Context) (*User, error) {
 		return nil, err
 	}
 	if status != http.StatusOK {
-		return nil, fmt.Errorf("API error: %d %s", status, http.StatusText(status))
+		return nil, newAPIError("Whoami", "/cli/loggedUser", status, body)
 	}
 
 	var result struct {
@@ -221,7 +256,7 @@ func (c *HTTPClient) GetUserFolders(ctx context.Context, userID int64, workspace
 		return nil, err
 	}
 	if status != http.StatusOK {
-		return nil, fmt.Errorf("API error: %d %s - %s", status, http.StatusText(status), extractAPIError(body))
+		return nil, newAPIError("GetUserFolders", path, status, body)
 	}
 
 	var result struct {
@@ -245,7 +280,7 @@ func (c *HTTPClient) GetFolderPath(ctx context.Context, folderHash string, works
 		return nil, err
 	}
 	if status != http.StatusOK {
-		return nil, fmt.Errorf("GetFolderPath failed: %d %s - %s", status, http.StatusText(status), extractAPIError(body))
+		return nil, newAPIError("GetFolderPath", path, status, body)
 	}
 
 	var result struct {
@@ -258,6 +293,29 @@ func (c *HTTPClient) GetFolderPath(ctx context.Context, folderHash string, works
 	return result.Path, nil
 }
 
+// GetFolderCount returns the number of items the server counts in a folder,
+// without listing it.
+func (c *HTTPClient) GetFolderCount(ctx context.Context, folderID int64, workspaceID int64) (int, error) {
+	q := url.Values{}
+	q.Set("workspaceId", fmt.Sprintf("%d", workspaceID))
+	path := fmt.Sprintf("/folders/%d/count", folderID)
+	status, body, err := c.do(ctx, http.MethodGet, path, q, nil, true)
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusOK {
+		return 0, newAPIError("GetFolderCount", path, status, body)
+	}
+
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+	return result.Count, nil
+}
+
 func (c *HTTPClient) ListByParentID(ctx context.Context, parentID *int64) ([]FileEntry, error) {
 	return c.ListByParentIDWithOptions(ctx, parentID, ListOptions(0))
 }
@@ -86,11 +86,7 @@ func (c *HTTPClient) doJSONWithHeaders(ctx context.Context, method, path string,
 	}
 
 	if !statusAllowed(status, okStatuses) {
-		msg := extractAPIError(body)
-		if msg == "" {
-			msg = fmt.Sprintf("HTTP %d", status)
-		}
-		return fmt.Errorf("%s %s failed: %s", method, path, msg)
+		return newAPIError(method+" "+path, path, status, body)
 	}
 
 	if out == nil {
@@ -1,15 +1,18 @@
 package api_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHTTPClient_Whoami_Retry(t *testing.T) {
@@ -87,6 +90,21 @@ func TestHTTPClient_GetFolderPath(t *testing.T) {
 	assert.Equal(t, "2024", path[2].Name)
 }
 
+func TestHTTPClient_GetFolderCount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/folders/42/count", r.URL.Path)
+		assert.Equal(t, "7", r.URL.Query().Get("workspaceId"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"count": 16}`))
+	}))
+	defer server.Close()
+
+	client := api.NewHTTPClient(server.URL, "test-token")
+	count, err := client.GetFolderCount(context.Background(), 42, 7)
+	require.NoError(t, err)
+	assert.Equal(t, 16, count)
+}
+
 func TestMaxPerPage_Constant(t *testing.T) {
 	// Verify MaxPerPage is set to the expected value
 	assert.Equal(t, int64(9999999999), api.MaxPerPage)
@@ -133,3 +151,85 @@ func TestExtractAPIError_InvalidJSON(t *testing.T) {
 	result := api.ExtractAPIErrorForTest(body)
 	assert.Equal(t, "not json", result)
 }
+
+func TestHTTPClient_Proxy(t *testing.T) {
+	client := api.NewHTTPClient("https://api.example.com/api/v1", "dummy-token")
+
+	// An explicit transport without a proxy connects directly
+	client.Client.Transport = &http.Transport{}
+	proxy, err := client.Proxy()
+	assert.NoError(t, err)
+	assert.Nil(t, proxy)
+
+	client.Client.Transport = &http.Transport{Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: "proxy:3128"})}
+	proxy, err = client.Proxy()
+	assert.NoError(t, err)
+	assert.Equal(t, "http://proxy:3128", proxy.String())
+}
+
+func TestClockSkewFromDate(t *testing.T) {
+	sent := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	received := sent.Add(200 * time.Millisecond)
+
+	// Server five minutes ahead
+	skew, err := api.ClockSkewFromDate("Sun, 01 Mar 2026 12:05:00 GMT", sent, received)
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, skew)
+
+	// Server behind
+	skew, err = api.ClockSkewFromDate("Sun, 01 Mar 2026 11:58:30 GMT", sent, received)
+	assert.NoError(t, err)
+	assert.Equal(t, -90*time.Second, skew)
+
+	// Within the header's one-second resolution counts as in sync
+	skew, err = api.ClockSkewFromDate("Sun, 01 Mar 2026 12:00:00 GMT", sent, received)
+	assert.NoError(t, err)
+	assert.Zero(t, skew)
+
+	_, err = api.ClockSkewFromDate("yesterday", sent, received)
+	assert.Error(t, err)
+}
+
+func TestHTTPClient_MeasureClockSkew(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-10*time.Minute).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := api.NewHTTPClient(server.URL, "token")
+	skew, err := client.MeasureClockSkew(context.Background())
+	assert.NoError(t, err)
+	assert.InDelta(t, float64(-10*time.Minute), float64(skew), float64(2*time.Second))
+}
+
+func TestHTTPClient_DownloadRequireRange(t *testing.T) {
+	content := "0123456789"
+	honor := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "bytes=5-7", r.Header.Get("Range"))
+		if !honor {
+			w.Write([]byte(content))
+			return
+		}
+		w.Header().Set("Content-Range", "bytes 5-7/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(content[5:8]))
+	}))
+	defer server.Close()
+
+	client := api.NewHTTPClient(server.URL, "test-token")
+	opts := &api.DownloadOptions{ResumeFrom: 5, Length: 3, RequireRange: true}
+
+	var buf bytes.Buffer
+	_, err := client.DownloadWithOptions(context.Background(), "hash", &buf, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "567", buf.String())
+
+	// A server answering with the whole file must not be taken for the range
+	honor = false
+	buf.Reset()
+	_, err = client.DownloadWithOptions(context.Background(), "hash", &buf, nil, opts)
+	require.ErrorIs(t, err, api.ErrRangeNotHonored)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// PendingMultipart is a multipart upload the shell started but has not yet
+// completed or aborted.
+type PendingMultipart struct {
+	Key         string    `json:"key"`
+	UploadID    string    `json:"upload_id"`
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	WorkspaceID int64     `json:"workspace_id"`
+	StartedAt   time.Time `json:"started_at"`
+}
+
+// MultipartJournal persists pending multipart uploads to disk so they can be
+// aborted later, even after the shell crashed mid-upload. The API offers no
+// way to enumerate in-progress uploads, so this is the only record of them.
+// A nil journal is valid and records nothing.
+type MultipartJournal struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewMultipartJournal returns a journal backed by the JSON file at path.
+func NewMultipartJournal(path string) *MultipartJournal {
+	return &MultipartJournal{path: path}
+}
+
+// List returns the recorded uploads, oldest first.
+func (j *MultipartJournal) List() ([]PendingMultipart, error) {
+	if j == nil {
+		return nil, nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.load()
+}
+
+// Record adds an upload to the journal.
+func (j *MultipartJournal) Record(p PendingMultipart) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	pending, err := j.load()
+	if err != nil {
+		return err
+	}
+	return j.save(append(pending, p))
+}
+
+// Forget removes an upload from the journal once it completed or was aborted.
+func (j *MultipartJournal) Forget(uploadID string) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	pending, err := j.load()
+	if err != nil {
+		return err
+	}
+	kept := pending[:0]
+	for _, p := range pending {
+		if p.UploadID != uploadID {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == len(pending) {
+		return nil
+	}
+	return j.save(kept)
+}
+
+func (j *MultipartJournal) load() ([]PendingMultipart, error) {
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pending []PendingMultipart
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+func (j *MultipartJournal) save(pending []PendingMultipart) error {
+	if len(pending) == 0 {
+		if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temp file and rename so a crash never leaves a torn journal
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
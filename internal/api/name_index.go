@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// IndexedEntry is what the name index keeps for each path.
+type IndexedEntry struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// NameIndex maps every known path of a workspace to its entry so names can be
+// searched without API calls. It is attached to a FileCache, which keeps it in
+// step with its own mutations, and persisted as JSON between sessions.
+// A nil index is valid and records nothing.
+type NameIndex struct {
+	path    string
+	entries map[string]IndexedEntry
+	dirty   bool
+	mu      sync.RWMutex
+}
+
+// LoadNameIndex reads the index persisted at path. A missing file yields an
+// empty index that will be written there on Save.
+func LoadNameIndex(path string) (*NameIndex, error) {
+	x := &NameIndex{path: path, entries: make(map[string]IndexedEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return x, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &x.entries); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Len returns the number of indexed paths.
+func (x *NameIndex) Len() int {
+	if x == nil {
+		return 0
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.entries)
+}
+
+// Search returns the indexed paths containing pattern, sorted.
+func (x *NameIndex) Search(pattern string, ignoreCase bool) []string {
+	if x == nil {
+		return nil
+	}
+	if ignoreCase {
+		pattern = strings.ToLower(pattern)
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	var matches []string
+	for p := range x.entries {
+		candidate := p
+		if ignoreCase {
+			candidate = strings.ToLower(p)
+		}
+		if strings.Contains(candidate, pattern) {
+			matches = append(matches, p)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// Get returns the indexed entry for path.
+func (x *NameIndex) Get(path string) (IndexedEntry, bool) {
+	if x == nil {
+		return IndexedEntry{}, false
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	e, ok := x.entries[path]
+	return e, ok
+}
+
+// Reset drops every indexed path, ahead of a full rebuild.
+func (x *NameIndex) Reset() {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.entries = make(map[string]IndexedEntry)
+	x.dirty = true
+}
+
+// Save writes the index to disk if it changed since it was loaded or saved.
+func (x *NameIndex) Save() error {
+	if x == nil {
+		return nil
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.dirty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(x.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(x.entries)
+	if err != nil {
+		return err
+	}
+	// Write to a temp file and rename so a crash never leaves a torn index
+	tmp := x.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, x.path); err != nil {
+		return err
+	}
+	x.dirty = false
+	return nil
+}
+
+// put records entry at path. The root is never indexed.
+func (x *NameIndex) put(path string, entry *FileEntry) {
+	if x == nil || path == "/" {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	indexed := IndexedEntry{ID: entry.ID, Type: entry.Type, Size: entry.Size}
+	if old, ok := x.entries[path]; ok && old == indexed {
+		return
+	}
+	x.entries[path] = indexed
+	x.dirty = true
+}
+
+// remove drops path and everything below it.
+func (x *NameIndex) remove(path string) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for p := range x.entries {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(x.entries, p)
+			x.dirty = true
+		}
+	}
+}
+
+// replaceChildren drops the direct children of parent (and their subtrees)
+// whose names are not in keep, after a fresh listing of parent.
+func (x *NameIndex) replaceChildren(parent string, keep map[string]bool) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	prefix := strings.TrimSuffix(parent, "/") + "/"
+	for p := range x.entries {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		child, _, _ := strings.Cut(p[len(prefix):], "/")
+		if !keep[child] {
+			delete(x.entries, p)
+			x.dirty = true
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy overrides how persistently one command retries its transfers.
+// Retries is the number of tries after the first (negative keeps the
+// default) and Delay the first backoff interval, doubled after each failure
+// (0 keeps the default).
+type RetryPolicy struct {
+	Retries int
+	Delay   time.Duration
+}
+
+type retryPolicyKey struct{}
+
+// WithRetryPolicy returns a context whose transfers retry as p says.
+func WithRetryPolicy(ctx context.Context, p RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, p)
+}
+
+// RetryPolicyFrom returns the policy set with WithRetryPolicy, or one that
+// keeps every default.
+func RetryPolicyFrom(ctx context.Context) RetryPolicy {
+	if p, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return p
+	}
+	return RetryPolicy{Retries: -1}
+}
+
+// RetriesOr returns the number of retries, or def when the policy keeps it.
+func (p RetryPolicy) RetriesOr(def int) int {
+	if p.Retries < 0 {
+		return def
+	}
+	return p.Retries
+}
+
+// DelayOr returns the first backoff interval, or def when the policy keeps
+// it.
+func (p RetryPolicy) DelayOr(def time.Duration) time.Duration {
+	if p.Delay <= 0 {
+		return def
+	}
+	return p.Delay
+}
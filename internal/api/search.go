@@ -22,11 +22,7 @@ func (c *HTTPClient) GetEntry(ctx context.Context, entryID int64, workspaceID in
 		return nil, err
 	}
 	if status != http.StatusOK {
-		msg := extractAPIError(respBody)
-		if msg == "" {
-			msg = fmt.Sprintf("%d %s", status, http.StatusText(status))
-		}
-		return nil, fmt.Errorf("GetEntry failed: %s", msg)
+		return nil, newAPIError("GetEntry", path, status, respBody)
 	}
 
 	var res GetEntryResponse
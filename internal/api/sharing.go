@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 )
 
 // CreateShareableLink creates a new public link for a file entry
@@ -68,6 +69,20 @@ func (c *HTTPClient) GetShareableLink(ctx context.Context, entryID int64) (*Shar
 	return &result.Link, nil
 }
 
+// GetShareableLinkByHash looks up a public link by the hash (or custom
+// suffix) at the end of its URL, together with the entry it points to
+func (c *HTTPClient) GetShareableLinkByHash(ctx context.Context, hash string) (*ShareableLink, error) {
+	var result struct {
+		Link ShareableLink `json:"link"`
+	}
+	path := "/shareable-links/" + url.PathEscape(hash)
+	err := c.doJSON(ctx, http.MethodGet, path, nil, nil, &result, true)
+	if err != nil {
+		return nil, err
+	}
+	return &result.Link, nil
+}
+
 // ShareEntry shares a file entry with specified emails and permissions
 func (c *HTTPClient) ShareEntry(ctx context.Context, entryID int64, emails []string, permissions []string) error {
 	path := fmt.Sprintf("/file-entries/%d/share", entryID)
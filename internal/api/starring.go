@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 )
 
 // StarEntries marks the given entries as starred
@@ -65,3 +66,17 @@ func (c *HTTPClient) UnstarEntries(ctx context.Context, entryIDs []int64, worksp
 
 	return nil
 }
+
+// ListTags returns the tags in use on file entries whose name matches query
+// (all of them when query is empty). The API can list tags but only set the
+// "starred" one, through StarEntries.
+func (c *HTTPClient) ListTags(ctx context.Context, query string) ([]Tag, error) {
+	var result struct {
+		Tags []Tag `json:"tags"`
+	}
+	err := c.doJSON(ctx, http.MethodGet, "/file-entry-tags", url.Values{"query": {query}}, nil, &result, true)
+	if err != nil {
+		return nil, err
+	}
+	return result.Tags, nil
+}
@@ -20,11 +20,7 @@ func (c *HTTPClient) RestoreEntries(ctx context.Context, entryIDs []int64, works
 		return err
 	}
 	if status != http.StatusOK {
-		msg := extractAPIError(respBody)
-		if msg == "" {
-			msg = fmt.Sprintf("%d %s", status, http.StatusText(status))
-		}
-		return fmt.Errorf("RestoreEntries failed: %s", msg)
+		return newAPIError("RestoreEntries", "/file-entries/restore", status, respBody)
 	}
 	return nil
 }
@@ -43,11 +39,7 @@ func (c *HTTPClient) EmptyTrash(ctx context.Context, workspaceID int64) error {
 		return err
 	}
 	if status != http.StatusOK {
-		msg := extractAPIError(respBody)
-		if msg == "" {
-			msg = fmt.Sprintf("%d %s", status, http.StatusText(status))
-		}
-		return fmt.Errorf("EmptyTrash failed: %s", msg)
+		return newAPIError("EmptyTrash", "/file-entries/delete", status, respBody)
 	}
 	return nil
 }
@@ -66,11 +58,7 @@ func (c *HTTPClient) DeleteEntriesForever(ctx context.Context, entryIDs []int64,
 		return err
 	}
 	if status != http.StatusOK {
-		msg := extractAPIError(respBody)
-		if msg == "" {
-			msg = fmt.Sprintf("%d %s", status, http.StatusText(status))
-		}
-		return fmt.Errorf("DeleteEntriesForever failed: %s", msg)
+		return newAPIError("DeleteEntriesForever", "/file-entries/delete", status, respBody)
 	}
 	return nil
 }
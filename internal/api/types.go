@@ -1,6 +1,7 @@
 package api
 
 import (
+	"strings"
 	"time"
 )
 
@@ -113,6 +114,16 @@ type FileEntryPermissions struct {
 	Delete   bool `json:"files.delete"`
 }
 
+// RootID is the ID of the synthetic entry the cache keeps for the root
+// folder. The API has no entry for the root: its children have a nil
+// parent_id, and a nil parent ID is what requests about the root take.
+const RootID int64 = 0
+
+// NewRootEntry returns a synthetic root folder entry.
+func NewRootEntry() *FileEntry {
+	return &FileEntry{ID: RootID, Name: "/", Type: "folder"}
+}
+
 // FileEntry represents a file or folder in Drime Cloud
 type FileEntry struct {
 	ParentID    *int64               `json:"parent_id"`
@@ -152,6 +163,7 @@ type ShareableLink struct {
 	UpdatedAt          time.Time  `json:"updated_at"`
 	Perso              int        `json:"perso"`                // 1 if personal link
 	PersonnalLinkValue string     `json:"personnal_link_value"` // Custom link suffix
+	Entry              *FileEntry `json:"entry,omitempty"`      // Set when looked up by hash
 }
 
 // FileRequestPayload represents the payload for creating a file request
@@ -254,16 +266,37 @@ type SetTrackingRequest struct {
 	Tracked bool  `json:"tracked"`
 }
 
+// StarredTag is the tag the API uses to mark starred entries
+const StarredTag = "starred"
+
 // IsStarred returns true if this entry has the "starred" tag
 func (e *FileEntry) IsStarred() bool {
+	return e.HasTag(StarredTag)
+}
+
+// HasTag reports whether the entry has the tag name, compared without regard
+// to case.
+func (e *FileEntry) HasTag(name string) bool {
 	for _, tag := range e.Tags {
-		if tag.Name == "starred" {
+		if strings.EqualFold(tag.Name, name) {
 			return true
 		}
 	}
 	return false
 }
 
+// HasMetadata reports whether e carries the hash and modification time that
+// listing endpoints return. Entries from the folder tree may lack them.
+func (e *FileEntry) HasMetadata() bool {
+	return e.Hash != "" && !e.UpdatedAt.IsZero()
+}
+
+// IsRoot reports whether e is the synthetic root folder entry. Entries from
+// the API always have a real ID, so only a folder with RootID qualifies.
+func (e *FileEntry) IsRoot() bool {
+	return e != nil && e.ID == RootID && e.Type == "folder"
+}
+
 // IsInTrash returns true if this entry is in trash
 func (e *FileEntry) IsInTrash() bool {
 	return e.DeletedAt != nil
@@ -346,17 +379,6 @@ type SpaceUsage struct {
 	Available int64  `json:"available"`
 }
 
-// APIError represents a structured error from the Drime API
-type APIError struct {
-	Message    string `json:"message"`
-	Status     string `json:"status"`
-	RetryAfter int    `json:"retry_after,omitempty"` // From Retry-After header
-}
-
-func (e *APIError) Error() string {
-	return e.Message
-}
-
 // ValidateFile represents a file to be validated
 type ValidateFile struct {
 	Name         string `json:"name"`
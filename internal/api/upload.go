@@ -72,9 +72,9 @@ func detectMimeType(reader io.Reader, filename string) (mimeType string, buffere
 const (
 	ChunkSize       = 60 * 1024 * 1024 // 60MB
 	MultipartThresh = 65 * 1024 * 1024 // 65MB - use multipart above this
-	BatchSize       = 8                // Sign URLs in batches
-	S3MaxRetries    = 5                // Max retries for S3 operations
-	S3RetryDelay    = time.Second      // Base delay for S3 retries
+	BatchSize       = 8                // Sign multipart part URLs in batches
+	S3MaxRetries    = 5                // Max retries for S3 operations (see RetryPolicy)
+	S3RetryDelay    = time.Second      // Base delay for S3 retries (see RetryPolicy)
 )
 
 // SimplePresignRequest is the request body for /s3/simple/presign
@@ -157,7 +157,37 @@ type CreateS3EntryResponse struct {
 	FileEntry FileEntry `json:"fileEntry"`
 }
 
+// UploadOptions configures an upload operation
+type UploadOptions struct {
+	// Mime replaces the content type detected from the file's bytes and name
+	Mime string
+	// PartConcurrency bounds how many parts of a multipart upload are sent at
+	// once. Zero means a whole signing batch (BatchSize) at a time.
+	PartConcurrency int
+}
+
+// mimeFor returns the MIME type to send for an upload, given the detected one
+func (o *UploadOptions) mimeFor(detected string) string {
+	if o != nil && o.Mime != "" {
+		return o.Mime
+	}
+	return detected
+}
+
+// partConcurrency returns how many multipart parts may be in flight at once.
+func (o *UploadOptions) partConcurrency() int {
+	if o == nil || o.PartConcurrency <= 0 || o.PartConcurrency > BatchSize {
+		return BatchSize
+	}
+	return o.PartConcurrency
+}
+
 func (c *HTTPClient) Upload(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*FileEntry, error) {
+	return c.UploadWithOptions(ctx, reader, name, parentID, size, workspaceID, nil)
+}
+
+// UploadWithOptions uploads a file with configurable options such as an explicit MIME type
+func (c *HTTPClient) UploadWithOptions(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *UploadOptions) (*FileEntry, error) {
 	// We can't easily stat io.Reader or use File-specific logic easily.
 	// We MUST adapt.
 	// Multipart S3 Upload requires random access for parallel uploads usually (ReadAt).
@@ -191,26 +221,27 @@ func (c *HTTPClient) Upload(ctx context.Context, reader io.Reader, name string,
 		if f, ok := reader.(*os.File); ok {
 			stat, err := f.Stat()
 			if err == nil {
-				return c.uploadMultipart(ctx, f, stat, name, parentID, nil, workspaceID)
+				return c.uploadMultipart(ctx, f, stat, name, parentID, nil, workspaceID, opts)
 			}
 		}
 		// For bytes.Reader, we can use uploadMultipartFromReader
 		if br, ok := reader.(*bytes.Reader); ok {
-			return c.uploadMultipartFromReader(ctx, br, name, size, parentID, workspaceID)
+			return c.uploadMultipartFromReader(ctx, br, name, size, parentID, workspaceID, opts)
 		}
 		return nil, fmt.Errorf("multipart upload only supported for files and byte readers currently")
 	} else {
 		// Simple Upload
-		return c.uploadSimple(ctx, reader, name, size, parentID, workspaceID)
+		return c.uploadSimple(ctx, reader, name, size, parentID, workspaceID, opts)
 	}
 }
 
-func (c *HTTPClient) uploadSimple(ctx context.Context, reader io.Reader, name string, size int64, parentID *int64, workspaceID int64) (*FileEntry, error) {
+func (c *HTTPClient) uploadSimple(ctx context.Context, reader io.Reader, name string, size int64, parentID *int64, workspaceID int64, opts *UploadOptions) (*FileEntry, error) {
 	// Detect MIME type from content using magic bytes
 	mimeType, headerReader, err := detectMimeType(reader, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect mime type: %w", err)
 	}
+	mimeType = opts.mimeFor(mimeType)
 
 	// Chain header back with rest of reader
 	combinedReader := io.MultiReader(headerReader, reader)
@@ -259,7 +290,7 @@ func (c *HTTPClient) uploadSimple(ctx context.Context, reader io.Reader, name st
 
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("presign failed (%s): %s", resp.Status, extractAPIError(b))
+		return nil, newAPIError("presign", resp.Request.URL.Path, resp.StatusCode, b)
 	}
 
 	var presignRes SimplePresignResponse
@@ -270,7 +301,9 @@ func (c *HTTPClient) uploadSimple(ctx context.Context, reader io.Reader, name st
 	// 2. Upload directly to S3 using presigned URL (with retries)
 	var putResp *http.Response
 	var lastErr error
-	for attempt := 0; attempt <= S3MaxRetries; attempt++ {
+	policy := RetryPolicyFrom(ctx)
+	maxRetries, retryDelay := policy.RetriesOr(S3MaxRetries), policy.DelayOr(S3RetryDelay)
+	for attempt := 0; attempt <= maxRetries; attempt++ {
 		putReq, _ := http.NewRequestWithContext(ctx, "PUT", presignRes.URL, bytes.NewReader(content))
 		putReq.ContentLength = actualSize
 		putReq.Header.Set("Content-Type", mimeType)
@@ -291,8 +324,8 @@ func (c *HTTPClient) uploadSimple(ctx context.Context, reader io.Reader, name st
 			putResp.Body.Close()
 		}
 
-		if attempt < S3MaxRetries {
-			backoff := S3RetryDelay * time.Duration(1<<attempt)
+		if attempt < maxRetries {
+			backoff := retryDelay * time.Duration(1<<attempt)
 			jitter := time.Duration(float64(backoff) * 0.25 * (2*rand.Float64() - 1))
 			select {
 			case <-time.After(backoff + jitter):
@@ -304,7 +337,7 @@ func (c *HTTPClient) uploadSimple(ctx context.Context, reader io.Reader, name st
 	}
 
 	if lastErr != nil {
-		return nil, fmt.Errorf("S3 upload failed after %d retries: %w", S3MaxRetries, lastErr)
+		return nil, fmt.Errorf("S3 upload failed after %d retries: %w", maxRetries, lastErr)
 	}
 	if putResp != nil && putResp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("S3 upload failed with status: %s", putResp.Status)
@@ -336,7 +369,7 @@ func (c *HTTPClient) uploadSimple(ctx context.Context, reader io.Reader, name st
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("create entry failed (%s): %s", resp.Status, extractAPIError(b))
+		return nil, newAPIError("create entry", resp.Request.URL.Path, resp.StatusCode, b)
 	}
 
 	var entryRes CreateS3EntryResponse
@@ -348,12 +381,13 @@ func (c *HTTPClient) uploadSimple(ctx context.Context, reader io.Reader, name st
 }
 
 // uploadMultipartFromReader handles multipart upload for bytes.Reader
-func (c *HTTPClient) uploadMultipartFromReader(ctx context.Context, reader *bytes.Reader, name string, size int64, parentID *int64, workspaceID int64) (*FileEntry, error) {
+func (c *HTTPClient) uploadMultipartFromReader(ctx context.Context, reader *bytes.Reader, name string, size int64, parentID *int64, workspaceID int64, opts *UploadOptions) (*FileEntry, error) {
 	// Detect MIME type from content using magic bytes
 	mimeType, headerReader, err := detectMimeType(reader, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect mime type: %w", err)
 	}
+	mimeType = opts.mimeFor(mimeType)
 	// Reset reader position after detection (bytes.Reader supports this)
 	_, _ = reader.Seek(0, io.SeekStart)
 	_ = headerReader // Not needed since we can seek
@@ -393,6 +427,7 @@ func (c *HTTPClient) uploadMultipartFromReader(ctx context.Context, reader *byte
 	if err := json.NewDecoder(resp.Body).Decode(&initRes); err != nil {
 		return nil, err
 	}
+	c.recordMultipart(initRes, name, size, workspaceID)
 
 	// 2. Sign URL for single part
 	signReq := BatchSignRequest{
@@ -470,6 +505,7 @@ func (c *HTTPClient) uploadMultipartFromReader(ctx context.Context, reader *byte
 		b, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("complete multipart failed (%s): %s", resp.Status, string(b))
 	}
+	_ = c.Journal.Forget(initRes.UploadID)
 
 	// 5. Create file entry
 	s3Filename := filepath.Base(initRes.Key)
@@ -507,7 +543,7 @@ func (c *HTTPClient) uploadMultipartFromReader(ctx context.Context, reader *byte
 	return &entryRes.FileEntry, nil
 }
 
-func (c *HTTPClient) uploadMultipart(ctx context.Context, file *os.File, stat os.FileInfo, name string, parentID *int64, progress func(int64, int64), workspaceID int64) (*FileEntry, error) {
+func (c *HTTPClient) uploadMultipart(ctx context.Context, file *os.File, stat os.FileInfo, name string, parentID *int64, progress func(int64, int64), workspaceID int64, opts *UploadOptions) (*FileEntry, error) {
 	// Detect MIME type from file content using magic bytes
 	mtype, err := mimetype.DetectFile(file.Name())
 	mimeType := "application/octet-stream"
@@ -547,6 +583,7 @@ func (c *HTTPClient) uploadMultipart(ctx context.Context, file *os.File, stat os
 			mimeType = "text/x-shellscript"
 		}
 	}
+	mimeType = opts.mimeFor(mimeType)
 
 	// 1. Initialize
 	ext := filepath.Ext(name)
@@ -583,6 +620,7 @@ func (c *HTTPClient) uploadMultipart(ctx context.Context, file *os.File, stat os
 	if err := json.NewDecoder(resp.Body).Decode(&initRes); err != nil {
 		return nil, err
 	}
+	c.recordMultipart(initRes, name, stat.Size(), workspaceID)
 
 	// 2. Upload Parts
 	// Calculate parts
@@ -595,6 +633,7 @@ func (c *HTTPClient) uploadMultipart(ctx context.Context, file *os.File, stat os
 
 	var uploadedBytes int64
 	var mu sync.Mutex
+	inFlight := make(chan struct{}, opts.partConcurrency())
 
 	for i := 0; i < totalParts; i += BatchSize {
 		end := i + BatchSize
@@ -640,6 +679,8 @@ func (c *HTTPClient) uploadMultipart(ctx context.Context, file *os.File, stat os
 			wg.Add(1)
 			go func(partNum int, url string) {
 				defer wg.Done()
+				inFlight <- struct{}{}
+				defer func() { <-inFlight }()
 
 				// Read chunk
 				offset := int64(partNum-1) * ChunkSize
@@ -739,6 +780,7 @@ func (c *HTTPClient) uploadMultipart(ctx context.Context, file *os.File, stat os
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("complete failed: %s", resp.Status)
 	}
+	_ = c.Journal.Forget(initRes.UploadID)
 
 	// 4. Create Entry
 	// Extract just the filename from the S3 key (e.g., \"uploads/uuid/uuid\" -> \"uuid\")
@@ -800,12 +842,26 @@ func (c *HTTPClient) AbortMultipart(ctx context.Context, key, uploadID string) e
 
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("abort multipart failed (%s): %s", resp.Status, extractAPIError(b))
+		return newAPIError("abort multipart", resp.Request.URL.Path, resp.StatusCode, b)
 	}
 
+	_ = c.Journal.Forget(uploadID)
 	return nil
 }
 
+// recordMultipart journals a freshly created multipart upload so it can be
+// aborted later if the shell never gets to complete it.
+func (c *HTTPClient) recordMultipart(initRes CreateMultipartResponse, name string, size int64, workspaceID int64) {
+	_ = c.Journal.Record(PendingMultipart{
+		Key:         initRes.Key,
+		UploadID:    initRes.UploadID,
+		Name:        name,
+		Size:        size,
+		WorkspaceID: workspaceID,
+		StartedAt:   time.Now(),
+	})
+}
+
 // ValidateEntries checks for duplicates and quota before upload/move/copy
 func (c *HTTPClient) ValidateEntries(ctx context.Context, req ValidateRequest) (*ValidateResponse, error) {
 	body, err := json.Marshal(req)
@@ -833,7 +889,8 @@ func (c *HTTPClient) ValidateEntries(ctx context.Context, req ValidateRequest) (
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("validate failed: %s", resp.Status)
+		b, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError("validate", httpReq.URL.Path, resp.StatusCode, b)
 	}
 
 	var validateResp ValidateResponse
@@ -959,7 +1016,7 @@ func (c *HTTPClient) UploadToVault(ctx context.Context, encryptedContent []byte,
 
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("presign failed (%s): %s", resp.Status, extractAPIError(b))
+		return nil, newAPIError("presign", resp.Request.URL.Path, resp.StatusCode, b)
 	}
 
 	var presignRes SimplePresignResponse
@@ -970,7 +1027,9 @@ func (c *HTTPClient) UploadToVault(ctx context.Context, encryptedContent []byte,
 	// 2. Upload encrypted content directly to S3
 	var putResp *http.Response
 	var lastErr error
-	for attempt := 0; attempt <= S3MaxRetries; attempt++ {
+	policy := RetryPolicyFrom(ctx)
+	maxRetries, retryDelay := policy.RetriesOr(S3MaxRetries), policy.DelayOr(S3RetryDelay)
+	for attempt := 0; attempt <= maxRetries; attempt++ {
 		putReq, _ := http.NewRequestWithContext(ctx, "PUT", presignRes.URL, bytes.NewReader(encryptedContent))
 		putReq.ContentLength = size
 		putReq.Header.Set("Content-Type", mimeType)
@@ -990,8 +1049,8 @@ func (c *HTTPClient) UploadToVault(ctx context.Context, encryptedContent []byte,
 			putResp.Body.Close()
 		}
 
-		if attempt < S3MaxRetries {
-			backoff := S3RetryDelay * time.Duration(1<<attempt)
+		if attempt < maxRetries {
+			backoff := retryDelay * time.Duration(1<<attempt)
 			jitter := time.Duration(float64(backoff) * 0.25 * (2*rand.Float64() - 1))
 			select {
 			case <-time.After(backoff + jitter):
@@ -1003,7 +1062,7 @@ func (c *HTTPClient) UploadToVault(ctx context.Context, encryptedContent []byte,
 	}
 
 	if lastErr != nil {
-		return nil, fmt.Errorf("S3 upload failed after %d retries: %w", S3MaxRetries, lastErr)
+		return nil, fmt.Errorf("S3 upload failed after %d retries: %w", maxRetries, lastErr)
 	}
 	if putResp != nil && putResp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("S3 upload failed with status: %s", putResp.Status)
@@ -1038,7 +1097,7 @@ func (c *HTTPClient) UploadToVault(ctx context.Context, encryptedContent []byte,
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("create entry failed (%s): %s", resp.Status, extractAPIError(b))
+		return nil, newAPIError("create entry", resp.Request.URL.Path, resp.StatusCode, b)
 	}
 
 	var entryRes CreateS3EntryResponse
@@ -3,9 +3,11 @@ package api_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -139,3 +141,78 @@ func TestHTTPClient_Upload_PresignFailure(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Invalid file type")
 }
+
+func TestHTTPClient_AbortMultipart_ClearsJournal(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/s3/multipart/abort" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer apiServer.Close()
+
+	journal := api.NewMultipartJournal(filepath.Join(t.TempDir(), "pending-uploads.json"))
+	require.NoError(t, journal.Record(api.PendingMultipart{Key: "uploads/a", UploadID: "id-a", Name: "a.bin"}))
+	require.NoError(t, journal.Record(api.PendingMultipart{Key: "uploads/b", UploadID: "id-b", Name: "b.bin"}))
+
+	client := api.NewHTTPClient(apiServer.URL, "test-token")
+	client.Journal = journal
+
+	require.NoError(t, client.AbortMultipart(context.Background(), "uploads/a", "id-a"))
+
+	pending, err := journal.List()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "id-b", pending[0].UploadID)
+
+	// A nil journal is a no-op
+	var none *api.MultipartJournal
+	assert.NoError(t, none.Record(api.PendingMultipart{UploadID: "x"}))
+	list, err := none.List()
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestHTTPClient_UploadWithOptions_OverridesMime(t *testing.T) {
+	var presignMime, entryMime string
+
+	s3Server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc123"`)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s3Server.Close()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/s3/simple/presign":
+			var req api.SimplePresignRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			presignMime = req.Mime
+			w.Write([]byte(`{"url": "` + s3Server.URL + `/upload", "acl": "private", "key": "uploads/scan.bin"}`))
+		case "/s3/entries":
+			var req api.CreateS3EntryRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			entryMime = req.ClientMime
+			w.Write([]byte(`{"status": "success", "fileEntry": {"id": 1, "name": "scan.bin"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer apiServer.Close()
+
+	client := api.NewHTTPClient(apiServer.URL, "test-token")
+	client.BaseRetryDelay = 1 * time.Millisecond
+	content := []byte{0x00, 0x01, 0x02, 0x03}
+
+	_, err := client.Upload(context.Background(), bytes.NewReader(content), "scan.bin", nil, int64(len(content)), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "application/octet-stream", presignMime, "detected without an override")
+
+	_, err = client.UploadWithOptions(context.Background(), bytes.NewReader(content), "scan.bin", nil, int64(len(content)), 0,
+		&api.UploadOptions{Mime: "application/pdf"})
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", presignMime)
+	assert.Equal(t, "application/pdf", entryMime)
+}
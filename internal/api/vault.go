@@ -34,7 +34,7 @@ func (c *HTTPClient) GetVaultMetadata(ctx context.Context) (*VaultMeta, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("GetVaultMetadata failed: %s - %s", resp.Status, extractAPIError(body))
+		return nil, newAPIError("GetVaultMetadata", resp.Request.URL.Path, resp.StatusCode, body)
 	}
 
 	var result struct {
@@ -79,7 +79,7 @@ func (c *HTTPClient) InitializeVault(ctx context.Context, salt, check, iv string
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("InitializeVault failed: %s - %s", resp.Status, extractAPIError(respBody))
+		return nil, newAPIError("InitializeVault", resp.Request.URL.Path, resp.StatusCode, respBody)
 	}
 
 	var result struct {
@@ -111,7 +111,7 @@ func (c *HTTPClient) GetVaultFolders(ctx context.Context, userID int64) ([]FileE
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("GetVaultFolders failed: %s - %s", resp.Status, extractAPIError(body))
+		return nil, newAPIError("GetVaultFolders", resp.Request.URL.Path, resp.StatusCode, body)
 	}
 
 	var result struct {
@@ -149,7 +149,7 @@ func (c *HTTPClient) ListVaultEntries(ctx context.Context, folderHash string) ([
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("ListVaultEntries failed: %s - %s", resp.Status, extractAPIError(body))
+		return nil, newAPIError("ListVaultEntries", resp.Request.URL.Path, resp.StatusCode, body)
 	}
 
 	var result struct {
@@ -193,7 +193,7 @@ func (c *HTTPClient) MoveVaultEntries(ctx context.Context, entryIDs []int64, des
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("MoveVaultEntries failed: %s - %s", resp.Status, extractAPIError(respBody))
+		return newAPIError("MoveVaultEntries", resp.Request.URL.Path, resp.StatusCode, respBody)
 	}
 
 	return nil
@@ -228,7 +228,7 @@ func (c *HTTPClient) DeleteVaultEntries(ctx context.Context, entryIDs []int64) e
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("DeleteVaultEntries failed: %s - %s", resp.Status, extractAPIError(respBody))
+		return newAPIError("DeleteVaultEntries", resp.Request.URL.Path, resp.StatusCode, respBody)
 	}
 
 	return nil
@@ -266,7 +266,7 @@ func (c *HTTPClient) CreateVaultFolder(ctx context.Context, name string, parentI
 	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("CreateVaultFolder failed: %s - %s", resp.Status, extractAPIError(respBody))
+		return nil, newAPIError("CreateVaultFolder", resp.Request.URL.Path, resp.StatusCode, respBody)
 	}
 
 	var res CreateFolderResponse
@@ -297,7 +297,7 @@ func (c *HTTPClient) DownloadEncrypted(ctx context.Context, hash string, w io.Wr
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("DownloadEncrypted failed: %s - %s", resp.Status, extractAPIError(body))
+		return nil, newAPIError("DownloadEncrypted", resp.Request.URL.Path, resp.StatusCode, body)
 	}
 
 	var entry FileEntry
@@ -60,11 +60,7 @@ func (c *HTTPClient) Login(ctx context.Context, email, password, deviceName stri
 		return nil, fmt.Errorf("login failed: validation error")
 	}
 	if status >= 400 {
-		msg := extractAPIError(respBody)
-		if msg == "" {
-			msg = fmt.Sprintf("HTTP %d", status)
-		}
-		return nil, fmt.Errorf("login failed: %s", msg)
+		return nil, newAPIError("login", "/auth/login", status, respBody)
 	}
 
 	var result struct {
@@ -118,11 +114,7 @@ func (c *HTTPClient) CreateWorkspace(ctx context.Context, name string) (*Workspa
 		return nil, fmt.Errorf("failed to create workspace: validation error (%s)", string(respBody))
 	}
 	if status >= 400 {
-		msg := extractAPIError(respBody)
-		if msg == "" {
-			msg = fmt.Sprintf("HTTP %d", status)
-		}
-		return nil, fmt.Errorf("CreateWorkspace failed: %s", msg)
+		return nil, newAPIError("CreateWorkspace", "/workspace", status, respBody)
 	}
 
 	var result struct {
@@ -158,11 +150,7 @@ func (c *HTTPClient) UpdateWorkspace(ctx context.Context, workspaceID int64, nam
 		return nil, fmt.Errorf("failed to update workspace: validation error")
 	}
 	if status >= 400 {
-		msg := extractAPIError(respBody)
-		if msg == "" {
-			msg = fmt.Sprintf("HTTP %d", status)
-		}
-		return nil, fmt.Errorf("UpdateWorkspace failed: %s", msg)
+		return nil, newAPIError("UpdateWorkspace", path, status, respBody)
 	}
 
 	var result struct {
@@ -194,11 +182,7 @@ func (c *HTTPClient) DeleteWorkspace(ctx context.Context, workspaceID int64) err
 		return fmt.Errorf("you don't have permission to delete this workspace")
 	}
 	if status != http.StatusOK && status != http.StatusNoContent {
-		msg := extractAPIError(respBody)
-		if msg == "" {
-			msg = fmt.Sprintf("HTTP %d", status)
-		}
-		return fmt.Errorf("DeleteWorkspace failed: %s", msg)
+		return newAPIError("DeleteWorkspace", path, status, respBody)
 	}
 
 	return nil
@@ -216,11 +200,7 @@ func (c *HTTPClient) GetWorkspace(ctx context.Context, workspaceID int64) (*Work
 		return nil, fmt.Errorf("workspace not found")
 	}
 	if status >= 400 {
-		msg := extractAPIError(body)
-		if msg == "" {
-			msg = fmt.Sprintf("HTTP %d", status)
-		}
-		return nil, fmt.Errorf("GetWorkspace failed: %s", msg)
+		return nil, newAPIError("GetWorkspace", path, status, body)
 	}
 
 	var result struct {
@@ -0,0 +1,113 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ReleasesURL is where the latest release is looked up.
+var ReleasesURL = "https://api.github.com/repos/gYonder/drime-shell/releases/latest"
+
+// LatestUpdate returns the tag of the latest release when it is newer than
+// this build's Version, or "" when this build is up to date.
+func LatestUpdate(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", ReleasesURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "drime-shell/"+Version)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("release lookup: %s", resp.Status)
+	}
+
+	var rel struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return "", err
+	}
+
+	latest := strings.TrimPrefix(rel.TagName, "v")
+	current := strings.TrimPrefix(Version, "v")
+	if latest != "" && semverGreater(latest, current) {
+		return rel.TagName, nil
+	}
+	return "", nil
+}
+
+// semverGreater returns true if version a > b using semantic versioning.
+// Handles versions like "1.2.3", "1.2.3-beta", etc.
+// Returns false if either version is invalid or if a <= b.
+func semverGreater(a, b string) bool {
+	// Handle dev/unknown versions - always consider releases newer than dev
+	if b == "dev" || b == "" {
+		return a != "dev" && a != ""
+	}
+	if a == "dev" || a == "" {
+		return false
+	}
+
+	// Split into version and prerelease parts
+	aParts := strings.SplitN(a, "-", 2)
+	bParts := strings.SplitN(b, "-", 2)
+
+	// Parse major.minor.patch
+	aVer := parseVersion(aParts[0])
+	bVer := parseVersion(bParts[0])
+
+	if aVer == nil || bVer == nil {
+		return false
+	}
+
+	// Compare major.minor.patch
+	for i := 0; i < 3; i++ {
+		if aVer[i] > bVer[i] {
+			return true
+		}
+		if aVer[i] < bVer[i] {
+			return false
+		}
+	}
+
+	// Same version numbers - check prerelease
+	// A release (no prerelease) is greater than a prerelease
+	aHasPrerelease := len(aParts) > 1
+	bHasPrerelease := len(bParts) > 1
+
+	if !aHasPrerelease && bHasPrerelease {
+		return true // 1.0.0 > 1.0.0-beta
+	}
+
+	return false // Equal or b is release and a is prerelease
+}
+
+// parseVersion parses "1.2.3" into [1, 2, 3]. Returns nil on error.
+func parseVersion(s string) []int {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	result := make([]int, 3)
+	for i, p := range parts {
+		var n int
+		if _, err := fmt.Sscanf(p, "%d", &n); err != nil {
+			return nil
+		}
+		if n < 0 {
+			return nil
+		}
+		result[i] = n
+	}
+	return result
+}
@@ -50,6 +50,9 @@ func aliasCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args [
 		s.Aliases = make(map[string]string)
 	}
 	s.Aliases[name] = value
+	if _, ok := s.WorkspaceAliases()[name]; ok {
+		fmt.Fprintf(env.Stderr, "Warning: this workspace's own '%s' alias from the config takes precedence\n", name)
+	}
 
 	// Persist to config
 	if err := saveAliasesToConfig(s.Aliases); err != nil {
@@ -67,11 +70,10 @@ func unaliasCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 
 	name := args[0]
 
-	if s.Aliases == nil {
-		return fmt.Errorf("unalias: %s: not found", name)
-	}
-
 	if _, exists := s.Aliases[name]; !exists {
+		if _, ok := s.WorkspaceAliases()[name]; ok {
+			return fmt.Errorf("unalias: %s: set for this workspace in the config (workspaces section)", name)
+		}
 		return fmt.Errorf("unalias: %s: not found", name)
 	}
 
@@ -86,7 +88,8 @@ func unaliasCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 }
 
 func listAliases(s *session.Session, env *ExecutionEnv) error {
-	if len(s.Aliases) == 0 {
+	aliases := s.AllAliases()
+	if len(aliases) == 0 {
 		fmt.Fprintln(env.Stdout, "No aliases defined.")
 		fmt.Fprintln(env.Stdout, "")
 		fmt.Fprintln(env.Stdout, ui.MutedStyle.Render("Use 'alias name=value' to create an alias."))
@@ -94,14 +97,19 @@ func listAliases(s *session.Session, env *ExecutionEnv) error {
 	}
 
 	// Sort alias names
-	names := make([]string, 0, len(s.Aliases))
-	for name := range s.Aliases {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
 		names = append(names, name)
 	}
 	sort.Strings(names)
 
+	scoped := s.WorkspaceAliases()
 	for _, name := range names {
-		value := s.Aliases[name]
+		value := aliases[name]
+		if _, ok := scoped[name]; ok {
+			fmt.Fprintf(env.Stdout, "alias %s='%s' %s\n", ui.CommandStyle.Render(name), value, ui.MutedStyle.Render("(workspace)"))
+			continue
+		}
 		fmt.Fprintf(env.Stdout, "alias %s='%s'\n", ui.CommandStyle.Render(name), value)
 	}
 	return nil
@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/spf13/pflag"
+)
+
+// Mutates reports whether running the command name with args may change
+// remote state, and so ends up in the audit log. Read-only commands are
+// left out to keep it reviewable.
+func Mutates(name string, args []string) bool {
+	if name == RedirectCommand {
+		return true
+	}
+	cmd, ok := Registry[name]
+	return ok && cmd.Mutates != nil && cmd.Mutates(args)
+}
+
+// alwaysMutates is Command.Mutates for commands that may change remote
+// state whatever their arguments.
+func alwaysMutates([]string) bool { return true }
+
+// subcommandMutates returns a Command.Mutates for commands whose first
+// argument picks the subcommand, which only the given ones change state.
+// Subcommands match in any case, as ws and vault accept them.
+func subcommandMutates(subcommands ...string) func([]string) bool {
+	return func(args []string) bool {
+		return len(args) > 0 && slices.Contains(subcommands, strings.ToLower(args[0]))
+	}
+}
+
+// flagMutates returns a Command.Mutates for commands that only change
+// state when one of the given long flags is set.
+func flagMutates(flags ...string) func([]string) bool {
+	return func(args []string) bool {
+		for _, arg := range args {
+			if arg == "--" {
+				break
+			}
+			name, _, _ := strings.Cut(arg, "=")
+			if slices.Contains(flags, strings.TrimPrefix(name, "--")) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// redacted replaces secrets in logged arguments.
+const redacted = "[REDACTED]"
+
+// secretFlagWords mark flags whose values are never logged, e.g.
+// request create --password.
+var secretFlagWords = []string{"password", "passphrase", "token", "secret"}
+
+// auditRecord is one line of the audit log.
+type auditRecord struct {
+	Time        time.Time `json:"time"`
+	User        string    `json:"user"`
+	UserID      int64     `json:"user_id"`
+	WorkspaceID int64     `json:"workspace_id"`
+	Workspace   string    `json:"workspace,omitempty"`
+	Vault       bool      `json:"vault,omitempty"`
+	CWD         string    `json:"cwd"`
+	Command     string    `json:"command"`
+	Args        []string  `json:"args"`
+	Error       string    `json:"error,omitempty"`
+	DurationMS  int64     `json:"duration_ms"`
+}
+
+// AuditHook appends a JSON line for every mutating command to a local file,
+// with the time, user, workspace, arguments and outcome. Values of secret
+// flags and the session token are redacted. Write failures are reported once
+// and never fail the command.
+type AuditHook struct {
+	path   string
+	mu     sync.Mutex
+	warned bool
+}
+
+// NewAuditHook returns a hook logging to path, which is created on first use.
+func NewAuditHook(path string) *AuditHook {
+	return &AuditHook{path: path}
+}
+
+// Before implements Hook. Commands are logged once their outcome is known.
+func (h *AuditHook) Before(*session.Session, string, []string) {}
+
+// After implements Hook.
+func (h *AuditHook) After(s *session.Session, name string, args []string, err error, elapsed time.Duration) {
+	if !Mutates(name, args) {
+		return
+	}
+	rec := auditRecord{
+		Time:        time.Now().UTC(),
+		User:        s.Username,
+		UserID:      s.UserID,
+		WorkspaceID: s.WorkspaceID,
+		Workspace:   s.WorkspaceName,
+		Vault:       s.InVault,
+		CWD:         s.CWD,
+		Command:     name,
+		Args:        redactArgs(args, s.Token, commandFlags(name)),
+		DurationMS:  elapsed.Milliseconds(),
+	}
+	if err != nil {
+		rec.Error = redactToken(err.Error(), s.Token)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if werr := h.append(rec); werr != nil && !h.warned {
+		h.warned = true
+		fmt.Fprintf(os.Stderr, "warning: audit log: %v\n", werr)
+	}
+}
+
+func (h *AuditHook) append(rec auditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// commandFlags returns the flags the command name declares, or nil.
+func commandFlags(name string) *pflag.FlagSet {
+	if cmd, ok := Registry[name]; ok && cmd.Flags != nil {
+		return cmd.Flags()
+	}
+	return nil
+}
+
+// redactArgs returns a copy of args with the values of secret flags, in
+// both "--flag value" and "--flag=value" form, and the token replaced.
+// With flags, the command's own, secret flags are also found under their
+// short names: "-p value", "-pvalue" and bundles such as "-dp value".
+func redactArgs(args []string, token string, flags *pflag.FlagSet) []string {
+	out := make([]string, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			for ; i < len(args); i++ {
+				out[i] = redactToken(args[i], token)
+			}
+			break
+		}
+		if name, _, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(arg, "-") && isSecretFlag(name) {
+			out[i] = name + "=" + redacted
+			continue
+		}
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") {
+			if kept, inline, ok := secretShorthand(flags, arg); ok {
+				if inline {
+					out[i] = kept + redacted
+				} else if out[i] = kept; i+1 < len(args) {
+					i++
+					out[i] = redacted
+				}
+				continue
+			}
+		}
+		out[i] = redactToken(arg, token)
+		if strings.HasPrefix(arg, "-") && isSecretFlag(arg) && i+1 < len(args) {
+			i++
+			out[i] = redacted
+		}
+	}
+	return out
+}
+
+// secretShorthand looks through the short flags bundled in arg, as pflag
+// parses them, for a secret one. It returns arg up to and including that
+// flag, and whether its value follows within arg rather than in the next
+// argument.
+func secretShorthand(flags *pflag.FlagSet, arg string) (kept string, inline, ok bool) {
+	if flags == nil {
+		return "", false, false
+	}
+	for j := 1; j < len(arg); j++ {
+		f := flags.ShorthandLookup(arg[j : j+1])
+		if f == nil {
+			return "", false, false
+		}
+		if f.NoOptDefVal != "" {
+			continue // a bool, which takes no value
+		}
+		if !isSecretFlag(f.Name) {
+			return "", false, false // the rest of arg is this flag's value
+		}
+		kept = arg[:j+1]
+		rest := arg[j+1:]
+		if strings.HasPrefix(rest, "=") {
+			kept += "="
+			rest = rest[1:]
+		}
+		return kept, rest != "", true
+	}
+	return "", false, false
+}
+
+func isSecretFlag(flag string) bool {
+	name := strings.ToLower(strings.TrimLeft(flag, "-"))
+	for _, w := range secretFlagWords {
+		if strings.Contains(name, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactToken(s, token string) string {
+	if token == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, token, redacted)
+}
@@ -4,13 +4,17 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/config"
 	"github.com/gYonder/drime-shell/internal/session"
 	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/spf13/pflag"
 	"golang.org/x/term"
 )
 
@@ -55,6 +59,43 @@ Displays information about the currently logged-in user:
   - Current workspace (if not default)`,
 		Run: whoamiCmd,
 	})
+	Register(&Command{
+		Name:        "reconnect",
+		Description: "Re-establish the connection to Drime Cloud",
+		Usage: `reconnect [--reload]
+
+Recovers the session after a network loss (laptop sleep, VPN or Wi-Fi
+change) without restarting the shell. Pooled connections are dropped and the
+startup connectivity check is run again. The current directory, workspace and
+vault state are kept.
+
+Options:
+  -r, --reload   Also re-list the current directory from the server
+
+The shell suggests running reconnect when a command fails because the server
+could not be reached.`,
+		Run: reconnectCmd,
+	})
+	Register(&Command{
+		Name:        "ping",
+		Description: "Measure API latency and check the connection",
+		Usage: `ping [-c N] [file]
+
+Sends a few lightweight authenticated requests (the same account lookup as
+whoami) and reports the round-trip latency. Also shows the API URL in use,
+any proxy picked up from HTTP_PROXY/HTTPS_PROXY, and whether the download
+endpoint accepts Range requests, which resumable downloads rely on. Range
+support is checked on the given file, or the first file in the current
+directory.
+
+Options:
+  -c, --count N   Number of requests to send (default 4)
+
+Examples:
+  ping
+  ping -c 10 big.iso`,
+		Run: pingCmd,
+	})
 }
 
 func loginCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
@@ -196,3 +237,160 @@ func whoamiCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 
 	return nil
 }
+
+// connectionResetter is implemented by clients that pool connections and can
+// drop them, like api.HTTPClient.
+type connectionResetter interface {
+	ResetConnections()
+}
+
+func reconnectCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("reconnect", pflag.ContinueOnError)
+	reload := fs.BoolP("reload", "r", false, "re-list the current directory")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: reconnect [--reload]")
+	}
+
+	if r, ok := s.Client.(connectionResetter); ok {
+		r.ResetConnections()
+	}
+
+	user, err := ui.WithSpinner(env.Stderr, "Reconnecting...", false, func() (*api.User, error) {
+		return s.Client.Whoami(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("reconnect: %w", err)
+	}
+	s.Username = user.Email
+	s.UserID = user.ID
+
+	if *reload {
+		dir, ok := s.Cache.Get(s.CWD)
+		if ok {
+			if err := refreshListing(ctx, s, s.CWD, dir); err != nil {
+				return fmt.Errorf("reconnect: %s: %w", s.CWD, err)
+			}
+		}
+	}
+
+	fmt.Fprintf(env.Stdout, "%s Connected as %s\n",
+		ui.SuccessStyle.Render("✓"),
+		ui.PromptUserStyle.Render(user.Email))
+	return nil
+}
+
+// endpointInfo is implemented by clients that talk to the API over HTTP,
+// like api.HTTPClient.
+type endpointInfo interface {
+	Proxy() (*url.URL, error)
+	CheckResumeSupport(ctx context.Context, hash string) (bool, int64, error)
+}
+
+func pingCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("ping", pflag.ContinueOnError)
+	count := fs.IntP("count", "c", 4, "number of requests to send")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 || *count < 1 {
+		return fmt.Errorf("usage: ping [-c N] [file]")
+	}
+
+	endpoint, _ := s.Client.(endpointInfo)
+	if c, ok := s.Client.(*api.HTTPClient); ok {
+		fmt.Fprintf(env.Stdout, "API:   %s\n", c.BaseURL)
+	}
+	if endpoint != nil {
+		proxy, err := endpoint.Proxy()
+		switch {
+		case err != nil:
+			fmt.Fprintf(env.Stdout, "Proxy: invalid (%v)\n", err)
+		case proxy != nil:
+			fmt.Fprintf(env.Stdout, "Proxy: %s\n", proxy.Redacted())
+		default:
+			fmt.Fprintln(env.Stdout, "Proxy: none")
+		}
+	}
+
+	var rtts []time.Duration
+	var lastErr error
+	for i := 1; i <= *count; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		start := time.Now()
+		_, err := s.Client.Whoami(ctx)
+		rtt := time.Since(start)
+		if err != nil {
+			fmt.Fprintf(env.Stdout, "request %d: %v\n", i, err)
+			lastErr = err
+			continue
+		}
+		fmt.Fprintf(env.Stdout, "request %d: %s\n", i, formatRTT(rtt))
+		rtts = append(rtts, rtt)
+	}
+
+	fmt.Fprintf(env.Stdout, "\n%d requests, %d failed\n", *count, *count-len(rtts))
+	if len(rtts) == 0 {
+		return fmt.Errorf("ping: %w", lastErr)
+	}
+	lo, hi, sum := rtts[0], rtts[0], time.Duration(0)
+	for _, d := range rtts {
+		lo, hi, sum = min(lo, d), max(hi, d), sum+d
+	}
+	fmt.Fprintf(env.Stdout, "rtt min/avg/max = %s/%s/%s\n",
+		formatRTT(lo), formatRTT(sum/time.Duration(len(rtts))), formatRTT(hi))
+
+	if endpoint == nil || s.InVault {
+		return nil
+	}
+	file, err := pingTarget(ctx, s, fs.Args())
+	if err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	if file == nil {
+		fmt.Fprintln(env.Stdout, "Range: unknown (no file to test; pass one)")
+		return nil
+	}
+	ranges, _, err := endpoint.CheckResumeSupport(ctx, file.Hash)
+	switch {
+	case err != nil:
+		fmt.Fprintf(env.Stdout, "Range: check failed on %s (%v)\n", file.Name, err)
+	case ranges:
+		fmt.Fprintf(env.Stdout, "Range: supported (checked on %s)\n", file.Name)
+	default:
+		fmt.Fprintf(env.Stdout, "Range: not advertised (checked on %s)\n", file.Name)
+	}
+	return nil
+}
+
+// pingTarget picks the file whose download endpoint is probed for Range
+// support: the one named in args, else the first file in the current
+// directory. It returns nil when there is none.
+func pingTarget(ctx context.Context, s *session.Session, args []string) (*api.FileEntry, error) {
+	if len(args) == 1 {
+		entry, err := ResolveEntry(ctx, s, args[0])
+		if err != nil {
+			return nil, err
+		}
+		if entry.Type == "folder" {
+			return nil, fmt.Errorf("%s: Is a directory", args[0])
+		}
+		return entry, nil
+	}
+	for _, child := range s.Cache.GetChildren(s.CWD) {
+		if child.Type != "folder" && child.Hash != "" {
+			return &child, nil
+		}
+	}
+	return nil, nil
+}
+
+func formatRTT(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/spf13/pflag"
+)
+
+// checksumAlgos are the digests --checksum-algo and the <algo>sum commands
+// can compute. The server exposes no content hash to compare against, so
+// whichever is chosen, the remote copy is read back to compute it.
+var checksumAlgos = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"md5":    md5.New,
+	"crc32":  func() hash.Hash { return crc32.NewIEEE() },
+}
+
+// defaultChecksumAlgo is used when neither --checksum-algo nor checksum_algo
+// in the config picks one.
+const defaultChecksumAlgo = "sha256"
+
+func init() {
+	for _, algo := range checksumAlgoNames() {
+		name := algo + "sum"
+		Register(&Command{
+			Name:        name,
+			Description: fmt.Sprintf("Print the %s checksum of files", strings.ToUpper(algo)),
+			Usage: fmt.Sprintf(`%[1]s <file>...
+%[1]s (reads from stdin when piped)
+
+Downloads each file and prints its %[2]s checksum and path, in the format
+of the coreutils tool of the same name. Vault files are decrypted first, so
+the checksum is the one of the original file.
+
+Examples:
+  %[1]s report.pdf
+  %[1]s *.iso
+  cat notes.txt | %[1]s`, name, strings.ToUpper(algo)),
+			Run: func(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+				return checksumCmd(ctx, s, env, algo, args)
+			},
+		})
+	}
+}
+
+// checksumAlgoNames returns the names of checksumAlgos in sorted order.
+func checksumAlgoNames() []string {
+	names := make([]string, 0, len(checksumAlgos))
+	for name := range checksumAlgos {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// checksumAlgo returns the digest a command verifies with: flag when given,
+// else checksum_algo from the config, else sha256.
+func checksumAlgo(s *session.Session, flag string) (string, error) {
+	algo := strings.ToLower(flag)
+	if algo == "" {
+		algo = strings.ToLower(s.ChecksumAlgo)
+	}
+	if algo == "" {
+		return defaultChecksumAlgo, nil
+	}
+	if _, ok := checksumAlgos[algo]; ok {
+		return algo, nil
+	}
+	want := strings.Join(checksumAlgoNames(), ", ")
+	if flag == "" {
+		return "", fmt.Errorf("invalid checksum_algo in config: %s (must be one of %s)", algo, want)
+	}
+	return "", fmt.Errorf("invalid --checksum-algo value: %s (must be one of %s)", algo, want)
+}
+
+// addVerifyFlags adds --verify and --checksum-algo to fs. The returned
+// function gives the algorithm to verify with, or "" when neither flag was
+// given; --checksum-algo alone implies --verify.
+func addVerifyFlags(s *session.Session, fs *pflag.FlagSet) func() (string, error) {
+	verify := fs.Bool("verify", false, "read the copy back and compare checksums")
+	algo := fs.String("checksum-algo", "", "checksum to verify with: "+strings.Join(checksumAlgoNames(), ", "))
+	return func() (string, error) {
+		if !*verify && !fs.Changed("checksum-algo") {
+			return "", nil
+		}
+		return checksumAlgo(s, *algo)
+	}
+}
+
+// digest returns the hex checksum of everything r yields.
+func digest(algo string, r io.Reader) (string, error) {
+	h := checksumAlgos[algo]()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// localDigest returns the checksum of the local file at path.
+func localDigest(algo, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return digest(algo, f)
+}
+
+// remoteDigest downloads entry (decrypting vault files) and returns its
+// checksum.
+func remoteDigest(ctx context.Context, s *session.Session, algo string, entry *api.FileEntry) (string, error) {
+	h := checksumAlgos[algo]()
+	if err := DownloadAndDecryptToWriter(ctx, s, entry, h, nil); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyLocalCopy compares the checksum of the local file at localPath with
+// the one of entry, reporting a match on stdout.
+func verifyLocalCopy(ctx context.Context, s *session.Session, env *ExecutionEnv, algo, localPath string, entry *api.FileEntry) error {
+	local, err := localDigest(algo, localPath)
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", localPath, err)
+	}
+	return checkDigests(ctx, s, env, algo, entry.Name, local, entry)
+}
+
+// verifyRemoteCopy compares the checksums of a remote file and its copy.
+func verifyRemoteCopy(ctx context.Context, s *session.Session, env *ExecutionEnv, algo string, src, copied *api.FileEntry) error {
+	sum, err := remoteDigest(ctx, s, algo, src)
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", src.Name, err)
+	}
+	return checkDigests(ctx, s, env, algo, copied.Name, sum, copied)
+}
+
+// checkDigests reads entry back and checks its checksum is want.
+func checkDigests(ctx context.Context, s *session.Session, env *ExecutionEnv, algo, name, want string, entry *api.FileEntry) error {
+	got, err := ui.WithSpinner(env.Stderr, "Verifying "+name+"...", false, func() (string, error) {
+		return remoteDigest(ctx, s, algo, entry)
+	})
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", name, err)
+	}
+	if got != want {
+		return fmt.Errorf("verify %s: %s mismatch: expected %s, remote copy has %s", name, algo, want, got)
+	}
+	fmt.Fprintf(env.Stdout, "Verified %s (%s %s)\n", name, algo, got)
+	return nil
+}
+
+// checksumCmd prints the algo checksum of each file in args, or of stdin.
+func checksumCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, algo string, args []string) error {
+	name := algo + "sum"
+	if len(args) == 0 {
+		if isStdinTTY(env.Stdin) {
+			return fmt.Errorf("usage: %s <file>...", name)
+		}
+		sum, err := digest(algo, env.Stdin)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fmt.Fprintf(env.Stdout, "%s  -\n", sum)
+		return nil
+	}
+
+	var failed int
+	for _, path := range args {
+		entry, err := ResolveEntry(ctx, s, path)
+		if err == nil && entry.Type == "folder" {
+			err = fmt.Errorf("Is a directory")
+		}
+		var sum string
+		if err == nil {
+			sum, err = ui.WithSpinner(env.Stderr, "", false, func() (string, error) {
+				return remoteDigest(ctx, s, algo, entry)
+			})
+		}
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "%s: %s: %v\n", name, path, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(env.Stdout, "%s  %s\n", sum, path)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s: %d of %d files failed", name, failed, len(args))
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/atotto/clipboard"
+	"github.com/gYonder/drime-shell/internal/ui"
+)
+
+// copyToClipboard writes text to the system clipboard (pbcopy on macOS,
+// xclip/xsel/wl-copy on Linux) and reports the outcome on stderr.
+// A missing clipboard tool is not an error: the text was already printed.
+func copyToClipboard(env *ExecutionEnv, text string) {
+	if clipboard.Unsupported {
+		fmt.Fprintln(env.Stderr, ui.MutedStyle.Render("(clipboard unavailable: install xclip, xsel or wl-clipboard)"))
+		return
+	}
+	if err := clipboard.WriteAll(text); err != nil {
+		fmt.Fprintln(env.Stderr, ui.MutedStyle.Render(fmt.Sprintf("(could not copy to clipboard: %v)", err)))
+		return
+	}
+	fmt.Fprintln(env.Stderr, ui.MutedStyle.Render("(copied to clipboard)"))
+}
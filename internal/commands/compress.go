@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// gzipMarker is stored in the gzip header comment of files compressed by
+// `upload --compress`, so `download` knows it may restore the original.
+const gzipMarker = "drime-shell:compressed"
+
+// incompressibleMIMEs lists already-compressed formats that gain nothing from gzip.
+var incompressibleMIMEs = []string{
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/vnd.rar",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/zstd",
+	"application/x-compress",
+	"application/pdf",
+	"application/epub+zip",
+	"application/java-archive",
+	"application/vnd.openxmlformats-officedocument",
+	"application/vnd.oasis.opendocument",
+}
+
+// isCompressible reports whether gzip is likely to shrink the file, based on
+// its detected MIME type. Images, audio, video and archives are skipped.
+func isCompressible(path string) bool {
+	mtype, err := mimetype.DetectFile(path)
+	if err != nil {
+		return false
+	}
+	return isCompressibleMIME(mtype.String())
+}
+
+// isCompressibleMIME reports whether data of the given MIME type is likely
+// to compress.
+func isCompressibleMIME(mime string) bool {
+	for _, prefix := range []string{"image/", "video/", "audio/", "font/woff"} {
+		if strings.HasPrefix(mime, prefix) {
+			return false
+		}
+	}
+	for _, m := range incompressibleMIMEs {
+		if strings.HasPrefix(mime, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// gzipToTemp compresses src into a temporary file whose gzip header records
+// the original name and modification time. The caller removes the file.
+func gzipToTemp(src io.Reader, name string, modTime time.Time) (*os.File, error) {
+	tmp, err := os.CreateTemp("", "drime-gzip-*")
+	if err != nil {
+		return nil, err
+	}
+
+	zw := gzip.NewWriter(tmp)
+	zw.Name = name
+	zw.ModTime = modTime
+	zw.Comment = gzipMarker
+
+	if _, err := io.Copy(zw, src); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return tmp, nil
+}
+
+// decompressIfMarked restores a downloaded .gz file that was compressed by
+// `upload --compress`, writing the original name next to it and removing the
+// archive. Other files are left untouched, and so is the archive when clobber
+// keeps a local file already under the original name.
+func decompressIfMarked(env *ExecutionEnv, path string, clobber clobberMode) error {
+	if !strings.HasSuffix(path, ".gz") {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil // Not gzip data, keep as is
+	}
+	defer zr.Close()
+	if zr.Comment != gzipMarker {
+		return nil
+	}
+
+	name := filepath.Base(zr.Name)
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = strings.TrimSuffix(filepath.Base(path), ".gz")
+	}
+	outPath := filepath.Join(filepath.Dir(path), name)
+	if !clobber.allow(env, outPath) {
+		return nil
+	}
+
+	// Written aside and renamed, so a failure leaves an existing file intact
+	out, err := os.CreateTemp(filepath.Dir(path), "."+name+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, zr); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return fmt.Errorf("decompress %s: %w", filepath.Base(path), err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	if err := os.Chmod(out.Name(), 0644); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	if !zr.ModTime.IsZero() {
+		_ = os.Chtimes(out.Name(), zr.ModTime, zr.ModTime)
+	}
+	if err := os.Rename(out.Name(), outPath); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+
+	f.Close()
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "Decompressed: %s\n", outPath)
+	return nil
+}
+
+// decompressAll runs decompressIfMarked over the files a folder download
+// wrote, stopping at the first error.
+func decompressAll(env *ExecutionEnv, paths []string, clobber clobberMode) error {
+	for _, p := range paths {
+		if err := decompressIfMarked(env, p, clobber); err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzipRoundTrip_RestoresOriginal(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Repeat("compressible text\n", 200)
+	modTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	gz, err := gzipToTemp(strings.NewReader(content), "notes.txt", modTime)
+	require.NoError(t, err)
+	defer os.Remove(gz.Name())
+
+	data, err := os.ReadFile(gz.Name())
+	require.NoError(t, err)
+	gz.Close()
+	assert.Less(t, len(data), len(content))
+
+	downloaded := filepath.Join(dir, "notes.txt.gz")
+	require.NoError(t, os.WriteFile(downloaded, data, 0644))
+
+	var stdout bytes.Buffer
+	env := &ExecutionEnv{Stdout: &stdout, Stderr: &stdout}
+	require.NoError(t, decompressIfMarked(env, downloaded, clobberOverwrite))
+
+	restored, err := os.ReadFile(filepath.Join(dir, "notes.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(restored))
+	assert.NoFileExists(t, downloaded)
+
+	info, err := os.Stat(filepath.Join(dir, "notes.txt"))
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(modTime))
+}
+
+func TestDecompressIfMarked_LeavesForeignGzipAlone(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "other.gz")
+	require.NoError(t, os.WriteFile(path, []byte("not gzip"), 0644))
+
+	env := &ExecutionEnv{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	require.NoError(t, decompressIfMarked(env, path, clobberOverwrite))
+	assert.FileExists(t, path)
+}
+
+func TestIsCompressible(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(text, []byte("hello world\n"), 0644))
+	png := filepath.Join(dir, "a.png")
+	require.NoError(t, os.WriteFile(png, []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0644))
+
+	assert.True(t, isCompressible(text))
+	assert.False(t, isCompressible(png))
+}
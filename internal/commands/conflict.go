@@ -3,11 +3,13 @@ package commands
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/session"
 )
 
 // ConflictResolution represents the user's choice for handling a file conflict
@@ -17,13 +19,20 @@ const (
 	ResolutionOverwrite ConflictResolution = iota
 	ResolutionKeepBoth
 	ResolutionSkip
+	ResolutionMerge // folders only: upload into the existing folder
 )
 
+// canPrompt reports whether ResolveConflict can ask the user, which needs
+// stdin to be a terminal.
+var canPrompt = func() bool { return isStdinTTY(os.Stdin) }
+
 // ResolveConflict prompts the user to resolve a file conflict
 // Returns the new name (if renamed), whether to proceed, and error
 func ResolveConflict(ctx context.Context, client api.DrimeClient, workspaceID int64, parentID *int64, filename string) (string, bool, error) {
-	// If not interactive (e.g. piped input), we might want a default.
-	// For now, assume interactive.
+	// Without a terminal the prompt would wait forever (scripts, CI)
+	if !canPrompt() {
+		return "", false, fmt.Errorf("%s already exists and stdin is not a terminal to ask what to do; pass --on-duplicate or set default_on_duplicate in the config", filename)
+	}
 
 	// We use bubbletea for the prompt
 	p := tea.NewProgram(newConflictModel(filename))
@@ -59,6 +68,24 @@ func ResolveConflict(ctx context.Context, client api.DrimeClient, workspaceID in
 	}
 }
 
+// askFolderConflict asks what to do about a folder an upload would create
+// that already exists: merge into it, replace it, keep both or skip.
+func askFolderConflict(name string) (ConflictResolution, error) {
+	if !canPrompt() {
+		return 0, fmt.Errorf("%s already exists and stdin is not a terminal to ask what to do; pass --merge, --rename or --replace", name)
+	}
+	p := tea.NewProgram(newFolderConflictModel(name))
+	m, err := p.Run()
+	if err != nil {
+		return 0, err
+	}
+	model := m.(conflictModel)
+	if model.canceled {
+		return 0, fmt.Errorf("operation canceled")
+	}
+	return model.choice, nil
+}
+
 // Bubbletea model for conflict prompt
 
 type item struct {
@@ -84,8 +111,22 @@ func newConflictModel(filename string) conflictModel {
 		item{title: "Skip file", desc: "File will not be uploaded", choice: ResolutionSkip},
 	}
 
+	return newConflictList(fmt.Sprintf("Duplicate File Found: %s already exists in this location.", filename), filename, items)
+}
+
+func newFolderConflictModel(name string) conflictModel {
+	items := []list.Item{
+		item{title: "Merge into existing folder", desc: "Files already there are handled by --on-duplicate", choice: ResolutionMerge},
+		item{title: "Keep both folders", desc: "A number will be added to the folder name", choice: ResolutionKeepBoth},
+		item{title: "Replace existing folder", desc: "The existing folder is moved to the trash first", choice: ResolutionOverwrite},
+		item{title: "Skip folder", desc: "Nothing will be uploaded", choice: ResolutionSkip},
+	}
+	return newConflictList(fmt.Sprintf("Duplicate Folder Found: %s already exists in this location.", name), name, items)
+}
+
+func newConflictList(title, filename string, items []list.Item) conflictModel {
 	l := list.New(items, list.NewDefaultDelegate(), 0, 0)
-	l.Title = fmt.Sprintf("Duplicate File Found: %s already exists in this location.", filename)
+	l.Title = title
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(false)
 	l.SetShowHelp(false)
@@ -131,13 +172,6 @@ func (m conflictModel) View() string {
 	return "\n" + m.list.View()
 }
 
-// checkCollisionsAndResolve checks for duplicates and resolves them interactively.
-// It returns a map of original filename -> new filename (or empty string if skipped).
-// If overwrite is chosen, new filename is same as original.
-func checkCollisionsAndResolve(ctx context.Context, client api.DrimeClient, workspaceID int64, parentID *int64, destPath string, sources []string) (map[string]string, error) {
-	return checkCollisionsAndResolveWithPolicy(ctx, client, workspaceID, parentID, destPath, sources, "ask")
-}
-
 // DuplicatePolicy specifies how to handle duplicate files
 type DuplicatePolicy string
 
@@ -148,6 +182,29 @@ const (
 	DuplicatePolicySkip    DuplicatePolicy = "skip"
 )
 
+// duplicatePolicy returns the policy a command applies to duplicates: flag
+// when given, else default_on_duplicate from the config, else ask.
+func duplicatePolicy(s *session.Session, flag string) (string, error) {
+	policy := flag
+	if policy == "" {
+		policy = s.OnDuplicate
+	}
+	switch DuplicatePolicy(policy) {
+	case "":
+		return string(DuplicatePolicyAsk), nil
+	case DuplicatePolicyAsk, DuplicatePolicyReplace, DuplicatePolicyRename, DuplicatePolicySkip:
+		return policy, nil
+	}
+	if flag == "" {
+		return "", fmt.Errorf("invalid default_on_duplicate in config: %s (must be ask, replace, rename, or skip)", policy)
+	}
+	return "", fmt.Errorf("invalid --on-duplicate value: %s (must be ask, replace, rename, or skip)", policy)
+}
+
+// checkCollisionsAndResolveWithPolicy checks for duplicates and resolves them
+// by policy, asking the user when it is ask. It returns a map of original
+// filename -> new filename; skipped files are left out, and replaced ones
+// keep their name.
 func checkCollisionsAndResolveWithPolicy(ctx context.Context, client api.DrimeClient, workspaceID int64, parentID *int64, destPath string, sources []string, policy string) (map[string]string, error) {
 	// 1. Validate
 	var files []api.ValidateFile
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gYonder/drime-shell/internal/build"
+	"github.com/gYonder/drime-shell/internal/config"
+	"github.com/gYonder/drime-shell/internal/ui"
+)
+
+// newIssueURL is where crash reports are filed.
+const newIssueURL = "https://github.com/gYonder/drime-shell/issues/new"
+
+// PanicError is returned by Execute when a command panicked. The shell keeps
+// running with its session as it was; the details go to a crash report.
+type PanicError struct {
+	Command string
+	Value   any
+	Report  string // Crash report, without paths under the home directory
+	LogPath string // File the report was appended to; "" if saving failed
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s: internal error: %v", e.Command, e.Value)
+}
+
+// IssueURL returns a link to a new issue prefilled with the crash report.
+// Nothing is sent unless the user opens it and submits the issue.
+func (e *PanicError) IssueURL() string {
+	body := e.Report
+	if len(body) > 6000 {
+		body = body[:6000] + "\n..."
+	}
+	q := url.Values{}
+	q.Set("title", fmt.Sprintf("Crash in %s: %v", e.Command, e.Value))
+	q.Set("body", "```\n"+body+"\n```")
+	return newIssueURL + "?" + q.Encode()
+}
+
+// newPanicError builds the error for a panic recovered from command name and
+// saves its crash report. Panics carried out of a spinner keep the stack of
+// the goroutine they happened in.
+func newPanicError(name string, r any) *PanicError {
+	stack := debug.Stack()
+	if p, ok := r.(*ui.Panic); ok {
+		r, stack = p.Value, p.Stack
+	}
+	e := &PanicError{Command: name, Value: r, Report: crashReport(name, r, stack)}
+	if path, err := saveCrashReport(e.Report); err == nil {
+		e.LogPath = path
+	}
+	return e
+}
+
+// crashReport describes a panic without the command's arguments, which name
+// the user's files; paths under the home directory are shortened to ~.
+func crashReport(name string, value any, stack []byte) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "drime-shell %s (%s), %s/%s, %s\n", build.Version, build.Commit, runtime.GOOS, runtime.GOARCH, runtime.Version())
+	fmt.Fprintf(&b, "command: %s\n", name)
+	fmt.Fprintf(&b, "panic: %v\n\n", value)
+	b.Write(stack)
+	report := strings.TrimRight(b.String(), "\n")
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		report = strings.ReplaceAll(report, home, "~")
+	}
+	return report
+}
+
+// saveCrashReport appends report to crash.log in the config directory and
+// returns the file's path.
+func saveCrashReport(report string) (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "crash.log")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+	_, err = fmt.Fprintf(f, "=== %s ===\n%s\n\n", time.Now().Format(time.RFC3339), report)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,310 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	Register(&Command{
+		Name:        "dedupe",
+		Mutates:     flagMutates("delete"),
+		Description: "Find files with identical content",
+		Usage: `dedupe [options] [path]
+
+Scans path (default: the current directory) recursively for files with the
+same size and content, and lists each set of duplicates. The oldest file of
+a set is the one kept.
+
+The server does not expose content hashes, so files that share a size with
+another file are downloaded to be compared. dedupe says how much that is and
+asks before it starts, unless -y is given or it is within --max-download.
+
+Drime has no links, so duplicates can't be replaced by references; with
+--delete all but the kept file of each set are moved to the trash.
+
+Options:
+  --delete            Move the duplicates to the trash (after confirmation)
+  -n, --dry-run       With --delete, list what would be deleted and stop
+  -y, --yes           Don't ask for confirmation
+  --min-size SIZE     Ignore files smaller than SIZE (e.g. 100K, 1M)
+  --max-download SIZE
+                      Compare without asking when that means downloading at
+                      most SIZE, and fail instead when it is more
+
+Examples:
+  dedupe                          # List duplicates below the current folder
+  dedupe --min-size 1M /Photos    # Only sets worth the bother
+  dedupe --max-download 2G /Docs  # In a script, within a budget
+  dedupe --delete -n /            # What would go, workspace-wide
+  dedupe --delete /Photos         # Trash the extra copies`,
+		Run: dedupe,
+	})
+}
+
+// duplicateSet is a group of files with the same size and content hash,
+// the kept file first.
+type duplicateSet struct {
+	sum   string
+	size  int64
+	paths []string
+	ids   []int64
+}
+
+// reclaimable is the space freed by deleting all but the kept file.
+func (d duplicateSet) reclaimable() int64 {
+	return d.size * int64(len(d.paths)-1)
+}
+
+func dedupe(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("dedupe", pflag.ContinueOnError)
+	doDelete := fs.Bool("delete", false, "move duplicates to the trash")
+	dryRun := fs.BoolP("dry-run", "n", false, "list what --delete would remove")
+	yes := fs.BoolP("yes", "y", false, "don't ask for confirmation")
+	minSizeStr := fs.String("min-size", "", "ignore files smaller than this")
+	maxDownloadStr := fs.String("max-download", "", "download at most this much to compare, without asking")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: dedupe [--delete [-n]] [-y] [--min-size SIZE] [--max-download SIZE] [path]")
+	}
+	if *dryRun && !*doDelete {
+		return fmt.Errorf("dedupe: --dry-run only applies to --delete")
+	}
+	if s.InVault {
+		return fmt.Errorf("dedupe: not available in vault (files are encrypted with their own keys)")
+	}
+
+	minSize := int64(1)
+	if *minSizeStr != "" {
+		n, err := parseSize(*minSizeStr)
+		if err != nil {
+			return fmt.Errorf("dedupe: invalid --min-size: %w", err)
+		}
+		minSize = max(n, 1)
+	}
+	maxDownload := int64(-1)
+	if *maxDownloadStr != "" {
+		n, err := parseSize(*maxDownloadStr)
+		if err != nil {
+			return fmt.Errorf("dedupe: invalid --max-download: %w", err)
+		}
+		maxDownload = n
+	}
+
+	target := s.CWD
+	if fs.NArg() == 1 {
+		target = fs.Arg(0)
+	}
+	root, err := s.ResolvePathArg(target)
+	if err != nil {
+		return fmt.Errorf("dedupe: %w", err)
+	}
+	rootEntry, ok := s.Cache.Get(root)
+	if !ok {
+		return fmt.Errorf("dedupe: %s: No such file or directory", target)
+	}
+	if rootEntry.Type != "folder" {
+		return fmt.Errorf("dedupe: %s: Not a directory", target)
+	}
+
+	// Only files sharing their size with another file can be duplicates
+	bySize := make(map[int64][]string)
+	entries := make(map[string]api.FileEntry)
+	_, err = ui.WithSpinner(env.Stderr, "Scanning...", false, func() (struct{}, error) {
+		return struct{}{}, walkCachedTree(ctx, s, root, walkBudget{}, func(p string, e *api.FileEntry) {
+			if e.Type == "folder" || e.Size < minSize {
+				return
+			}
+			bySize[e.Size] = append(bySize[e.Size], p)
+			entries[p] = *e
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("dedupe: %w", err)
+	}
+
+	var candidates []string
+	var downloadBytes int64
+	for size, paths := range bySize {
+		if len(paths) > 1 {
+			candidates = append(candidates, paths...)
+			downloadBytes += size * int64(len(paths))
+		}
+	}
+	if len(candidates) == 0 {
+		fmt.Fprintln(env.Stdout, "No duplicates found")
+		return nil
+	}
+	sort.Strings(candidates)
+
+	// Both questions are answered on the same input
+	answers := bufio.NewReader(env.Stdin)
+	switch {
+	case maxDownload >= 0 && downloadBytes > maxDownload:
+		return fmt.Errorf("dedupe: comparing %d files means downloading %s, more than --max-download %s", len(candidates), ui.FormatSize(downloadBytes), ui.FormatSize(maxDownload))
+	case maxDownload >= 0 || *yes:
+		fmt.Fprintf(env.Stderr, "dedupe: downloading %d files (%s) to compare their contents\n", len(candidates), ui.FormatSize(downloadBytes))
+	default:
+		fmt.Fprintf(env.Stderr, "dedupe: Download %d files (%s) to compare their contents? [y/N] ", len(candidates), ui.FormatSize(downloadBytes))
+		response, _ := answers.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(response)) != "y" {
+			fmt.Fprintln(env.Stderr, "dedupe: cancelled")
+			return nil
+		}
+	}
+	sums := make(map[string]string)
+	_, err = ui.WithSpinner(env.Stderr, "Comparing...", false, func() (struct{}, error) {
+		for _, p := range candidates {
+			if err := ctx.Err(); err != nil {
+				return struct{}{}, err
+			}
+			e := entries[p]
+			h := sha256.New()
+			if _, err := s.Client.Download(ctx, e.Hash, h, nil); err != nil {
+				fmt.Fprintf(env.Stderr, "dedupe: %s: skipped: %v\n", p, err)
+				continue
+			}
+			sums[p] = hex.EncodeToString(h.Sum(nil))
+		}
+		return struct{}{}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("dedupe: %w", err)
+	}
+
+	sets := duplicateSets(candidates, entries, sums)
+	if len(sets) == 0 {
+		fmt.Fprintln(env.Stdout, "No duplicates found")
+		return nil
+	}
+
+	var redundant int
+	var reclaimable int64
+	for _, set := range sets {
+		fmt.Fprintf(env.Stdout, "%d copies of %s (sha256 %s):\n", len(set.paths), ui.FormatSize(set.size), set.sum[:12])
+		for i, p := range set.paths {
+			if i == 0 {
+				fmt.Fprintf(env.Stdout, "  %s  (kept)\n", p)
+			} else {
+				fmt.Fprintf(env.Stdout, "  %s\n", p)
+			}
+		}
+		redundant += len(set.paths) - 1
+		reclaimable += set.reclaimable()
+	}
+	fmt.Fprintf(env.Stdout, "%d duplicate sets, %d redundant files, %s reclaimable\n", len(sets), redundant, ui.FormatSize(reclaimable))
+
+	if !*doDelete {
+		return nil
+	}
+	return deleteDuplicates(ctx, s, env, answers, sets, redundant, reclaimable, *dryRun, *yes)
+}
+
+// duplicateSets groups paths by size and content hash, keeping groups of two
+// or more. Each set lists its oldest file first; sets are sorted by the
+// space they waste, largest first.
+func duplicateSets(paths []string, entries map[string]api.FileEntry, sums map[string]string) []duplicateSet {
+	groups := make(map[string]*duplicateSet)
+	var keys []string
+	for _, p := range paths {
+		sum, ok := sums[p]
+		if !ok {
+			continue
+		}
+		e := entries[p]
+		key := fmt.Sprintf("%d:%s", e.Size, sum)
+		set, ok := groups[key]
+		if !ok {
+			set = &duplicateSet{sum: sum, size: e.Size}
+			groups[key] = set
+			keys = append(keys, key)
+		}
+		set.paths = append(set.paths, p)
+	}
+
+	var sets []duplicateSet
+	for _, key := range keys {
+		set := groups[key]
+		if len(set.paths) < 2 {
+			continue
+		}
+		sort.SliceStable(set.paths, func(i, j int) bool {
+			a, b := entries[set.paths[i]], entries[set.paths[j]]
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+			return set.paths[i] < set.paths[j]
+		})
+		for _, p := range set.paths {
+			set.ids = append(set.ids, entries[p].ID)
+		}
+		sets = append(sets, *set)
+	}
+	sort.SliceStable(sets, func(i, j int) bool {
+		if sets[i].reclaimable() != sets[j].reclaimable() {
+			return sets[i].reclaimable() > sets[j].reclaimable()
+		}
+		return sets[i].paths[0] < sets[j].paths[0]
+	})
+	return sets
+}
+
+// deleteDuplicates moves every file but the kept one of each set to the
+// trash, in batches, asking on answers first unless yes.
+func deleteDuplicates(ctx context.Context, s *session.Session, env *ExecutionEnv, answers *bufio.Reader, sets []duplicateSet, redundant int, reclaimable int64, dryRun, yes bool) error {
+	var ids []int64
+	var paths []string
+	for _, set := range sets {
+		ids = append(ids, set.ids[1:]...)
+		paths = append(paths, set.paths[1:]...)
+	}
+
+	if dryRun {
+		for _, p := range paths {
+			fmt.Fprintf(env.Stdout, "would delete: %s\n", p)
+		}
+		return nil
+	}
+
+	if !yes {
+		fmt.Fprintf(env.Stderr, "dedupe: Move %d duplicates (%s) to trash? [y/N] ", redundant, ui.FormatSize(reclaimable))
+		response, _ := answers.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(response)) != "y" {
+			fmt.Fprintln(env.Stderr, "dedupe: cancelled")
+			return nil
+		}
+	}
+
+	done := 0
+	err := ui.WithSpinnerErr(env.Stderr, "", false, func() error {
+		for start := 0; start < len(ids); start += searchActionBatch {
+			end := min(start+searchActionBatch, len(ids))
+			if err := s.Client.DeleteEntries(ctx, ids[start:end], s.WorkspaceID); err != nil {
+				return err
+			}
+			for _, p := range paths[start:end] {
+				s.Cache.Remove(p)
+			}
+			done = end
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("dedupe: %d of %d moved to trash: %w", done, len(ids), err)
+	}
+	fmt.Fprintf(env.Stderr, "Moved %d duplicates to trash\n", done)
+	return nil
+}
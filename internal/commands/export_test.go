@@ -2,6 +2,8 @@ package commands
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
 )
@@ -10,3 +12,39 @@ import (
 func CheckCollisionsAndResolveWithPolicyForTest(ctx context.Context, client api.DrimeClient, workspaceID int64, parentID *int64, destPath string, sources []string, policy string) (map[string]string, error) {
 	return checkCollisionsAndResolveWithPolicy(ctx, client, workspaceID, parentID, destPath, sources, policy)
 }
+
+// SetRetryBaseDelayForTest shortens the download retry backoff and returns a
+// function restoring the previous value.
+func SetRetryBaseDelayForTest(d time.Duration) func() {
+	prev := retryBaseDelay
+	retryBaseDelay = d
+	return func() { retryBaseDelay = prev }
+}
+
+// ResetHooksForTest drops the registered command hooks once the test ends.
+func ResetHooksForTest() func() {
+	prev := hooks
+	return func() { hooks = prev }
+}
+
+// SetNoPromptForTest makes conflict prompts behave as without a terminal and
+// returns a function restoring the previous behavior.
+func SetNoPromptForTest() func() {
+	prev := canPrompt
+	canPrompt = func() bool { return false }
+	return func() { canPrompt = prev }
+}
+
+// SetLatestUpdateForTest replaces the release lookup used by info and
+// returns a function restoring it.
+func SetLatestUpdateForTest(f func(context.Context) (string, error)) func() {
+	prev := latestUpdate
+	latestUpdate = f
+	return func() { latestUpdate = prev }
+}
+
+// ReportTransfersForTest exposes the status lines printed on SIGINFO or
+// SIGUSR1.
+func ReportTransfersForTest(w io.Writer) {
+	reportTransfers(w)
+}
@@ -17,20 +17,23 @@ import (
 func init() {
 	Register(&Command{
 		Name:        "mv",
+		Mutates:     alwaysMutates,
 		Description: "Move or rename files",
-		Usage:       "mv [-w workspace] <source>... <dest>\\n\\nOptions:\\n  -w    Target workspace (name or ID) for moving across workspaces\\n\\nExamples:\\n  mv file.txt newname.txt    Rename a file\\n  mv file.txt /folder/       Move file to folder\\n  mv a.txt b.txt /folder/    Move multiple files\\n  mv -w 123 file.txt /       Move file to root of workspace 123\\n  mv -w MyTeam file.txt /    Move file to root of workspace 'MyTeam'",
+		Usage:       "mv [-f] [--on-duplicate <action>] [-w workspace [--preserve-acl]] <source>... <dest>\\n\\nA dest ending in / must be an existing folder, which the sources are moved\\ninto; without the slash a single source is renamed to dest if it doesn't exist.\\n\\nOptions:\\n  -f    Replace an existing destination file instead of refusing\\n  -w    Target workspace (name or ID) for moving across workspaces\\n  --preserve-acl  With -w, recreate the share links of moved public entries\\n        with the same access and expiry\\n  --on-duplicate <action>  Files already in the target folder: ask, replace,\\n        rename or skip (default: default_on_duplicate in config, else ask)\\n\\nExamples:\\n  mv file.txt newname.txt    Rename a file\\n  mv file.txt /folder/       Move file to folder\\n  mv a.txt b.txt /folder/    Move multiple files\\n  mv -f new.txt old.txt      Replace old.txt with new.txt\\n  mv -w 123 file.txt /       Move file to root of workspace 123\\n  mv -w MyTeam file.txt /    Move file to root of workspace 'MyTeam'",
 		Run:         mv,
 	})
 	Register(&Command{
 		Name:        "cp",
+		Mutates:     alwaysMutates,
 		Description: "Copy files",
-		Usage:       "cp [-r] [-w workspace] <source>... <dest>\\n\\nOptions:\\n  -r    Copy directories recursively\\n  -w    Target workspace (name or ID) for copying across workspaces\\n\\nExamples:\\n  cp file.txt copy.txt       Copy a file\\n  cp file.txt /folder/       Copy file to folder\\n  cp -r folder/ /backup/     Copy folder recursively\\n  cp -w 123 file.txt /       Copy file to root of workspace 123\\n  cp -w MyTeam file.txt /    Copy file to root of workspace 'MyTeam'",
+		Usage:       "cp [-r] [-f] [-u] [-v] [--verify] [--on-duplicate <action>] [--reflink[=WHEN]] [-w workspace [--preserve-acl]] <source>... <dest>\\n\\nCopies within the workspace, or to another workspace, are done server-side\\n(folders included) without transferring any data. Copies into, out of or\\nwithin the vault are downloaded and re-uploaded, since contents are encrypted;\\nthose show a progress bar per file and report each file copied.\\n\\nA dest ending in / must be an existing folder, which the sources are copied\\ninto; without the slash a single source is copied to the name dest if it\\ndoesn't exist.\\n\\nOptions:\\n  -r    Copy directories recursively\\n  -f    Replace an existing destination file instead of refusing\\n  -u    Copy only when the source is newer than the destination (or it is missing)\\n  -v    Print each copy and whether it ran server-side\\n  -w    Target workspace (name or ID) for copying across workspaces\\n  --preserve-acl  With -w, give copies of public entries share links with the\\n        same access and expiry (password-protected links are refused)\\n  --verify  Read each copied file back and compare its checksum with the\\n        source's (server-side copies of files only, so not with -r;\\n        downloads both)\\n  --checksum-algo <algo>  Checksum for --verify: sha256 (default, or\\n        checksum_algo in config), md5 or crc32; implies --verify\\n  --on-duplicate <action>  Files already in the target folder: ask, replace,\\n        rename or skip (default: default_on_duplicate in config, else ask)\\n  --reflink[=WHEN]  always (the default for a bare --reflink) fails instead of\\n                    downloading and re-uploading; auto falls back to it\\n\\nExamples:\\n  cp file.txt copy.txt       Copy a file\\n  cp file.txt /folder/       Copy file to folder\\n  cp -r folder/ /backup/     Copy folder recursively\\n  cp -u report.pdf /backup/  Copy only if newer than /backup/report.pdf\\n  cp -f draft.txt final.txt  Replace final.txt with a copy of draft.txt\\n  cp -w 123 file.txt /       Copy file to root of workspace 123\\n  cp -w MyTeam file.txt /    Copy file to root of workspace 'MyTeam'\\n  cp -v --reflink -r a/ b/   Copy server-side only, and say so",
 		Run:         cp,
 	})
 	Register(&Command{
 		Name:        "touch",
-		Description: "Create an empty file",
-		Usage:       "touch <file>...\n\nExamples:\n  touch file.txt           Create an empty file\n  touch a.txt b.txt        Create multiple files",
+		Mutates:     alwaysMutates,
+		Description: "Create an empty file or update its timestamp",
+		Usage:       "touch [-c] <file>...\n\nCreates empty files. Existing files keep their content and ID; their\nmodification time is updated instead.\n\nOptions:\n  -c, --no-create     Do not create files that don't exist\n\nThe time is always the server's current time: the API has no way to set\nanother one, so -t is refused.\n\nExamples:\n  touch file.txt                 Create an empty file or bump its time\n  touch a.txt b.txt              Create multiple files",
 		Run:         touch,
 	})
 }
@@ -38,22 +41,29 @@ func init() {
 func mv(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
 	// Parse flags
 	flags := pflag.NewFlagSet("mv", pflag.ContinueOnError)
-	targetWorkspaceStr := flags.StringP("workspace", "w", "", "Target workspace (name or ID)")
+	force := flags.BoolP("force", "f", false, "Replace an existing destination file")
+	onDuplicate := flags.String("on-duplicate", "", "How to handle files already in the target folder: ask, replace, rename, skip")
+	targetWorkspaceStr := flags.StringP("workspace", "w", "", "Target workspace (name, ID, or name:/id: prefixed)")
 	toVault := flags.BoolP("vault", "V", false, "Move to vault (when in workspace) or from vault to workspace (when in vault with -w)")
+	preserveACL := flags.Bool("preserve-acl", false, "Recreate the share links of public entries in the target workspace")
 	flags.SetOutput(env.Stderr)
 	if err := flags.Parse(args); err != nil {
 		return err
 	}
 	args = flags.Args()
+	policy, err := duplicatePolicy(s, *onDuplicate)
+	if err != nil {
+		return fmt.Errorf("mv: %w", err)
+	}
 
 	if len(args) < 2 {
-		return fmt.Errorf("usage: mv [-w workspace] [--vault] <source>... <dest>")
+		return fmt.Errorf("usage: mv [-f] [-w workspace] [--vault] <source>... <dest>")
 	}
 
 	// Resolve target workspace if specified
 	var targetWorkspaceID *int64
 	if *targetWorkspaceStr != "" {
-		wsID, _, err := ResolveWorkspace(ctx, s, *targetWorkspaceStr)
+		wsID, _, err := ResolveWorkspace(ctx, s, env, *targetWorkspaceStr)
 		if err != nil {
 			return fmt.Errorf("mv: %w", err)
 		}
@@ -64,6 +74,9 @@ func mv(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 	if *toVault && targetWorkspaceID != nil {
 		return fmt.Errorf("mv: cannot specify both --vault and -w")
 	}
+	if *preserveACL && (targetWorkspaceID == nil || s.InVault) {
+		return fmt.Errorf("mv: --preserve-acl only applies between workspaces (-w), since vault entries can't be shared")
+	}
 
 	if *toVault {
 		if s.InVault {
@@ -93,6 +106,12 @@ func mv(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 		return deleteVaultSources(ctx, s, sources)
 	}
 
+	// Moves within the workspace are logged for undo
+	if targetWorkspaceID == nil {
+		s.BeginUndo("mv")
+		defer s.EndUndo()
+	}
+
 	return ui.WithSpinnerErr(env.Stderr, "", false, func() error {
 		dest := args[len(args)-1]
 		sources := args[:len(args)-1]
@@ -132,6 +151,12 @@ func mv(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 			destEntry, destExists = s.Cache.Get(destResolved)
 		}
 
+		if len(sources) > 1 || session.IsDirArg(dest) {
+			if err := requireDirectoryTarget("mv", dest, destEntry, destExists); err != nil {
+				return err
+			}
+		}
+
 		// Case 1: Rename (Source is singular, Dest doesnt exist (and parent is same) OR Dest is not a folder)
 		// Rename is only possible within same workspace
 		if len(sources) == 1 && destWorkspaceID == nil {
@@ -148,6 +173,13 @@ func mv(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 			// Look up dest
 			// destEntry is already looked up above
 
+			if destExists && *force && destEntry.Type != "folder" {
+				if err := removeOverwrittenFile(ctx, s, srcEntry, destEntry, destResolved); err != nil {
+					return fmt.Errorf("mv: %w", err)
+				}
+				destExists = false
+			}
+
 			if !destExists {
 				destDir := filepath.Dir(destResolved)
 				destName := filepath.Base(destResolved)
@@ -163,6 +195,7 @@ func mv(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 					if err != nil {
 						return err
 					}
+					s.RecordMove(srcEntry.ID, srcResolved, destResolved)
 					// Update cache: remove old, add new
 					s.Cache.Remove(srcResolved)
 					if renamedEntry != nil {
@@ -178,10 +211,7 @@ func mv(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 				}
 
 				// Move to destDir, then rename if needed
-				var destDirID *int64
-				if destDirEntry.ID != 0 {
-					destDirID = &destDirEntry.ID
-				}
+				destDirID := parentIDPtr(destDirEntry)
 
 				if err := s.Client.MoveEntries(ctx, []int64{srcEntry.ID}, destDirID, s.WorkspaceID, nil); err != nil {
 					return err
@@ -190,6 +220,7 @@ func mv(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 				// Update cache for move
 				s.Cache.Remove(srcResolved)
 				newPath := filepath.Join(destDir, srcEntry.Name)
+				s.RecordMove(srcEntry.ID, srcResolved, newPath)
 
 				// Rename if the destination name differs from source name
 				if srcEntry.Name != destName {
@@ -197,6 +228,7 @@ func mv(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 					if err != nil {
 						return fmt.Errorf("mv: moved but failed to rename: %w", err)
 					}
+					s.RecordMove(srcEntry.ID, newPath, destResolved)
 					if renamedEntry != nil {
 						s.Cache.Add(renamedEntry, destResolved)
 					}
@@ -216,16 +248,12 @@ func mv(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 			return fmt.Errorf("mv: destination '%s' is not a directory", dest)
 		}
 
-		var destID *int64
-		if destEntry.ID != 0 {
-			destID = &destEntry.ID
-		}
-
-		return moveEntries(ctx, s, sources, destID, destResolved, destWorkspaceID)
+		return moveEntries(ctx, s, sources, destEntry, destResolved, destWorkspaceID, policy, *preserveACL)
 	})
 }
 
-func moveEntries(ctx context.Context, s *session.Session, sources []string, destID *int64, destPath string, destWorkspaceID *int64) error {
+func moveEntries(ctx context.Context, s *session.Session, sources []string, destEntry *api.FileEntry, destPath string, destWorkspaceID *int64, policy string, preserveACL bool) error {
+	destID := parentIDPtr(destEntry)
 	var srcPaths []string
 	var entries []*api.FileEntry
 	for _, src := range sources {
@@ -237,10 +265,26 @@ func moveEntries(ctx context.Context, s *session.Session, sources []string, dest
 		if !ok {
 			return fmt.Errorf("mv: cannot stat '%s': No such file", src)
 		}
+		// replace puts the moved file in place of an existing one, like -f
+		if policy == string(DuplicatePolicyReplace) && destWorkspaceID == nil && entry.Type != "folder" {
+			if existing, ok := existingChild(ctx, s, destEntry, destPath, entry.Name, nil); ok && existing.Type != "folder" && existing.ID != entry.ID {
+				if err := removeOverwrittenFile(ctx, s, entry, existing, filepath.Join(destPath, entry.Name)); err != nil {
+					return fmt.Errorf("mv: %w", err)
+				}
+			}
+		}
 		srcPaths = append(srcPaths, resolved)
 		entries = append(entries, entry)
 	}
 
+	var links map[int64]*api.ShareableLink
+	if preserveACL {
+		var err error
+		if links, err = publicLinks(ctx, s, entries); err != nil {
+			return fmt.Errorf("mv: --preserve-acl: %w", err)
+		}
+	}
+
 	// Check collisions and resolve
 	targetWsID := s.WorkspaceID
 	if destWorkspaceID != nil {
@@ -250,7 +294,7 @@ func moveEntries(ctx context.Context, s *session.Session, sources []string, dest
 	// We only check collisions if we are moving into a folder (destID is set)
 	// If destID is nil (root), we check against root.
 	// Note: destPath is the folder path where items will be placed.
-	resolvedMap, err := checkCollisionsAndResolve(ctx, s.Client, targetWsID, destID, destPath, sources)
+	resolvedMap, err := checkCollisionsAndResolveWithPolicy(ctx, s.Client, targetWsID, destID, destPath, sources, policy)
 	if err != nil {
 		return err
 	}
@@ -279,6 +323,9 @@ func moveEntries(ctx context.Context, s *session.Session, sources []string, dest
 			// Update entry and cache
 			s.Cache.Remove(srcPaths[i])
 			entry = renamed
+			if destWorkspaceID == nil {
+				s.RecordMove(entry.ID, srcPaths[i], filepath.Join(filepath.Dir(srcPaths[i]), newName))
+			}
 			// Update srcPath to reflect rename (though we are about to move it)
 			// We don't strictly need to update srcPaths[i] as we remove it later,
 			// but we should ensure cache consistency if move fails?
@@ -305,12 +352,22 @@ func moveEntries(ctx context.Context, s *session.Session, sources []string, dest
 		}
 	}
 
+	// Moved entries keep their IDs; a link the move dropped is recreated
+	for _, id := range finalIDs {
+		if link, ok := links[id]; ok {
+			if err := restoreLink(ctx, s, id, link); err != nil {
+				return fmt.Errorf("mv: moved, but cannot restore the share link of entry %d: %w", id, err)
+			}
+		}
+	}
+
 	// Update cache: remove from old paths, add to new paths
 	for i, srcPath := range finalSrcPaths {
 		s.Cache.Remove(srcPath)
 		if destWorkspaceID == nil && finalEntries[i] != nil {
 			newPath := filepath.Join(destPath, finalEntries[i].Name)
 			s.Cache.Add(finalEntries[i], newPath)
+			s.RecordMove(finalIDs[i], srcPath, newPath)
 		}
 	}
 
@@ -321,22 +378,41 @@ func moveEntries(ctx context.Context, s *session.Session, sources []string, dest
 func cp(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
 	flags := pflag.NewFlagSet("cp", pflag.ContinueOnError)
 	recursive := flags.BoolP("recursive", "r", false, "Copy directories recursively")
-	targetWorkspaceStr := flags.StringP("workspace", "w", "", "Target workspace (name or ID)")
+	update := flags.BoolP("update", "u", false, "Copy only when the source is newer than the destination")
+	force := flags.BoolP("force", "f", false, "Replace an existing destination file")
+	onDuplicate := flags.String("on-duplicate", "", "How to handle files already in the target folder: ask, replace, rename, skip")
+	targetWorkspaceStr := flags.StringP("workspace", "w", "", "Target workspace (name, ID, or name:/id: prefixed)")
 	toVault := flags.BoolP("vault", "V", false, "Copy to vault (when in workspace)")
+	verbose := flags.BoolP("verbose", "v", false, "Explain how each copy is made")
+	reflink := flags.String("reflink", "auto", "Require server-side copies: always or auto")
+	preserveACL := flags.Bool("preserve-acl", false, "Recreate the share links of public entries in the target workspace")
+	verifyAlgo := addVerifyFlags(s, flags)
+	flags.Lookup("reflink").NoOptDefVal = "always"
 	flags.SetOutput(env.Stderr)
 	if err := flags.Parse(args); err != nil {
 		return err
 	}
 	args = flags.Args()
+	policy, err := duplicatePolicy(s, *onDuplicate)
+	if err != nil {
+		return fmt.Errorf("cp: %w", err)
+	}
 
 	if len(args) < 2 {
-		return fmt.Errorf("usage: cp [-r] [-w workspace] [--vault] <source>... <dest>")
+		return fmt.Errorf("usage: cp [-r] [-f] [-u] [-v] [--reflink[=WHEN]] [-w workspace] [--vault] <source>... <dest>")
+	}
+	if *reflink != "auto" && *reflink != "always" {
+		return fmt.Errorf("cp: invalid --reflink value '%s' (want always or auto)", *reflink)
+	}
+	verify, err := verifyAlgo()
+	if err != nil {
+		return fmt.Errorf("cp: %w", err)
 	}
 
 	// Resolve target workspace if specified
 	var targetWorkspaceID *int64
 	if *targetWorkspaceStr != "" {
-		wsID, _, err := ResolveWorkspace(ctx, s, *targetWorkspaceStr)
+		wsID, _, err := ResolveWorkspace(ctx, s, env, *targetWorkspaceStr)
 		if err != nil {
 			return fmt.Errorf("cp: %w", err)
 		}
@@ -347,6 +423,26 @@ func cp(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 	if *toVault && targetWorkspaceID != nil {
 		return fmt.Errorf("cp: cannot specify both --vault and -w")
 	}
+	if *preserveACL && (targetWorkspaceID == nil || s.InVault) {
+		return fmt.Errorf("cp: --preserve-acl only applies between workspaces (-w), since vault entries can't be shared")
+	}
+
+	route, serverSide := copyRoute(s.InVault, *toVault, targetWorkspaceID)
+	if !serverSide && *reflink == "always" {
+		return fmt.Errorf("cp: --reflink=always: %s", route)
+	}
+	if !serverSide && verify != "" {
+		return fmt.Errorf("cp: --verify only checks server-side copies, not %s", route)
+	}
+	if *recursive && verify != "" {
+		return fmt.Errorf("cp: --verify applies to single files, not with -r")
+	}
+	if *verbose {
+		dest := args[len(args)-1]
+		for _, src := range args[:len(args)-1] {
+			fmt.Fprintf(env.Stderr, "'%s' -> '%s' (%s)\n", src, dest, route)
+		}
+	}
 
 	if *toVault {
 		if s.InVault {
@@ -366,7 +462,19 @@ func cp(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 		return copyFromVault(ctx, s, env, sources, dest, *recursive, *targetWorkspaceID)
 	}
 
-	return ui.WithSpinnerErr(env.Stderr, "", false, func() error {
+	if s.InVault {
+		return copyWithinVault(ctx, s, env, args[:len(args)-1], args[len(args)-1], *recursive, *force, *update)
+	}
+
+	// Copies within the workspace are logged for undo
+	if targetWorkspaceID == nil {
+		s.BeginUndo("cp")
+		defer s.EndUndo()
+	}
+
+	// Files copied, for --verify once the copying is done
+	var copies []copyPair
+	err = ui.WithSpinnerErr(env.Stderr, "", false, func() error {
 		dest := args[len(args)-1]
 		sources := args[:len(args)-1]
 
@@ -403,6 +511,12 @@ func cp(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 			destEntry, destExists = s.Cache.Get(destResolved)
 		}
 
+		if len(sources) > 1 || session.IsDirArg(dest) {
+			if err := requireDirectoryTarget("cp", dest, destEntry, destExists); err != nil {
+				return err
+			}
+		}
+
 		// Single source: can copy to new name or into folder
 		if len(sources) == 1 {
 			src := sources[0]
@@ -418,6 +532,13 @@ func cp(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 				return fmt.Errorf("cp: -r not specified; omitting directory '%s'", src)
 			}
 
+			if destExists && *force && destWorkspaceID == nil && destEntry.Type != "folder" {
+				if err := removeOverwrittenFile(ctx, s, srcEntry, destEntry, destResolved); err != nil {
+					return fmt.Errorf("cp: %w", err)
+				}
+				destExists = false
+			}
+
 			if !destExists {
 				// Destination doesn't exist: copy to parent folder with new name
 				// e.g., cp file.txt newfile.txt
@@ -434,16 +555,8 @@ func cp(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 					return fmt.Errorf("cp: cannot create '%s': No such directory", destDir)
 				}
 
-				// Vault: use download → encrypt → upload approach
-				if s.InVault {
-					return copyVaultFile(ctx, s, srcEntry, srcResolved, destResolved, destName)
-				}
-
-				// Copy to parent folder (use nil for root folder, ID=0 is synthetic)
-				var parentID *int64
-				if parentEntry.ID != 0 {
-					parentID = &parentEntry.ID
-				}
+				// Copy to parent folder
+				parentID := parentIDPtr(parentEntry)
 
 				var copied []api.FileEntry
 				copied, err := s.Client.CopyEntries(ctx, []int64{srcEntry.ID}, parentID, s.WorkspaceID, nil)
@@ -466,17 +579,33 @@ func cp(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 
 				// Update cache
 				s.Cache.Add(copiedEntry, destResolved)
+				s.RecordCopy(copiedEntry.ID, destResolved)
+				copies = append(copies, copyPair{srcEntry, copiedEntry})
 				return nil
 			}
 
 			// Destination exists
 			if destEntry.Type == "folder" {
 				// Copy into folder (keeps original name)
-				return copyIntoFolder(ctx, s, sources, destEntry, destResolved, *recursive, destWorkspaceID)
+				copies, err = copyIntoFolder(ctx, s, sources, destEntry, destResolved, *recursive, *update, policy, destWorkspaceID, *preserveACL)
+				return err
 			}
 
-			// Destination is a file - error (we don't support overwrite)
-			return fmt.Errorf("cp: cannot overwrite '%s'", dest)
+			// Destination is a file: only -u may replace it, and only when the source is newer
+			if *update && destWorkspaceID == nil && !s.InVault && srcEntry.Type != "folder" {
+				fillMetadata(ctx, s, srcEntry)
+				fillMetadata(ctx, s, destEntry)
+				if !isNewerThan(srcEntry, destEntry) {
+					return nil
+				}
+				copiedEntry, err := replaceFileWithCopy(ctx, s, srcEntry, destEntry, destResolved)
+				if err != nil {
+					return err
+				}
+				copies = append(copies, copyPair{srcEntry, copiedEntry})
+				return nil
+			}
+			return fmt.Errorf("cp: cannot overwrite '%s' (use -f to replace it)", dest)
 		}
 
 		// Multiple sources: destination MUST be an existing directory
@@ -487,69 +616,214 @@ func cp(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 			return fmt.Errorf("cp: target '%s' is not a directory", dest)
 		}
 
-		return copyIntoFolder(ctx, s, sources, destEntry, destResolved, *recursive, destWorkspaceID)
+		copies, err = copyIntoFolder(ctx, s, sources, destEntry, destResolved, *recursive, *update, policy, destWorkspaceID, *preserveACL)
+		return err
 	})
+	if err != nil || verify == "" {
+		return err
+	}
+	for _, c := range copies {
+		if err := verifyRemoteCopy(ctx, s, env, verify, c.src, c.copy); err != nil {
+			return fmt.Errorf("cp: %w", err)
+		}
+	}
+	return nil
 }
 
-// copyIntoFolder copies sources into a destination folder
-func copyIntoFolder(ctx context.Context, s *session.Session, sources []string, destEntry *api.FileEntry, destPath string, recursive bool, destWorkspaceID *int64) error {
-	// For vault, we use download → encrypt → upload approach for each file
-	if s.InVault && destWorkspaceID == nil {
-		for _, src := range sources {
-			resolved, err := s.ResolvePathArg(src)
-			if err != nil {
-				return fmt.Errorf("cp: %w", err)
-			}
-			entry, ok := s.Cache.Get(resolved)
-			if !ok {
-				return fmt.Errorf("cp: cannot stat '%s': No such file or directory", src)
-			}
-			if entry.Type == "folder" {
-				if !recursive {
-					return fmt.Errorf("cp: -r not specified; omitting directory '%s'", src)
-				}
-				return fmt.Errorf("cp: copying folders in vault is not supported")
-			}
-			// Copy file to destPath with same name
-			destFilePath := filepath.Join(destPath, entry.Name)
-			if err := copyVaultFile(ctx, s, entry, resolved, destFilePath, entry.Name); err != nil {
-				return err
-			}
+// copyPair is a file and its server-side copy.
+type copyPair struct {
+	src, copy *api.FileEntry
+}
+
+// copyRoute describes how cp copies between the current location and the
+// destination, and whether that happens server-side. Only copies touching
+// the vault need the data downloaded and re-uploaded.
+func copyRoute(inVault, toVault bool, destWorkspaceID *int64) (string, bool) {
+	switch {
+	case toVault:
+		return "download and re-upload: encrypting into the vault", false
+	case inVault && destWorkspaceID != nil:
+		return "download and re-upload: decrypting out of the vault", false
+	case inVault:
+		return "download and re-upload: vault copies are re-encrypted", false
+	case destWorkspaceID != nil:
+		return fmt.Sprintf("server-side copy to workspace %d", *destWorkspaceID), true
+	}
+	return "server-side copy", true
+}
+
+// requireDirectoryTarget checks that dest is an existing folder, which is
+// required when several sources (e.g. from a glob) are copied or moved, or
+// when dest ends in a slash.
+func requireDirectoryTarget(cmdName, dest string, destEntry *api.FileEntry, destExists bool) error {
+	if !destExists || destEntry == nil {
+		return fmt.Errorf("%s: target '%s': No such file or directory", cmdName, dest)
+	}
+	if destEntry.Type != "folder" {
+		return fmt.Errorf("%s: target '%s' is not a directory", cmdName, dest)
+	}
+	return nil
+}
+
+// isNewerThan reports whether src was modified after dst. Entries without a
+// timestamp can't be compared, so they count as newer and get copied.
+func isNewerThan(src, dst *api.FileEntry) bool {
+	if src.UpdatedAt.IsZero() || dst.UpdatedAt.IsZero() {
+		return true
+	}
+	return src.UpdatedAt.After(dst.UpdatedAt)
+}
+
+// replaceFileWithCopy moves dst to trash and copies src into its place under
+// the same name, returning the copy.
+func replaceFileWithCopy(ctx context.Context, s *session.Session, src, dst *api.FileEntry, dstPath string) (*api.FileEntry, error) {
+	if err := s.Client.DeleteEntries(ctx, []int64{dst.ID}, s.WorkspaceID); err != nil {
+		return nil, fmt.Errorf("cp: cannot replace '%s': %w", dstPath, err)
+	}
+	s.Cache.Remove(dstPath)
+	s.RecordTrash(dst.ID, dstPath)
+
+	copied, err := s.Client.CopyEntries(ctx, []int64{src.ID}, dst.ParentID, s.WorkspaceID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(copied) == 0 {
+		return nil, fmt.Errorf("cp: copy failed, no entry returned")
+	}
+
+	copiedEntry := &copied[0]
+	destName := filepath.Base(dstPath)
+	if copiedEntry.Name != destName {
+		copiedEntry, err = s.Client.RenameEntry(ctx, copiedEntry.ID, destName, s.WorkspaceID)
+		if err != nil {
+			return nil, fmt.Errorf("cp: copied but failed to rename: %w", err)
 		}
-		return nil
 	}
+	s.Cache.Add(copiedEntry, dstPath)
+	s.RecordCopy(copiedEntry.ID, dstPath)
+	return copiedEntry, nil
+}
 
+// removeOverwrittenFile deletes the file dst at dstPath so -f can put src in
+// its place. Folders are never replaced, and neither is src itself.
+func removeOverwrittenFile(ctx context.Context, s *session.Session, src, dst *api.FileEntry, dstPath string) error {
+	if src.ID == dst.ID {
+		return fmt.Errorf("'%s' and '%s' are the same file", src.Name, dstPath)
+	}
+	if src.Type == "folder" {
+		return fmt.Errorf("cannot overwrite non-directory '%s' with directory '%s'", dstPath, src.Name)
+	}
+
+	var err error
+	if s.InVault {
+		err = s.Client.DeleteVaultEntries(ctx, []int64{dst.ID})
+	} else {
+		err = s.Client.DeleteEntries(ctx, []int64{dst.ID}, s.WorkspaceID)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot replace '%s': %w", dstPath, err)
+	}
+	s.Cache.Remove(dstPath)
+	s.RecordTrash(dst.ID, dstPath)
+	return nil
+}
+
+// existingChild looks up name inside destPath, in the current cache or, for
+// cross-workspace copies, directly in the target workspace.
+func existingChild(ctx context.Context, s *session.Session, destEntry *api.FileEntry, destPath, name string, destWorkspaceID *int64) (*api.FileEntry, bool) {
+	childPath := filepath.Join(destPath, name)
+	if destWorkspaceID != nil {
+		entry, err := resolvePathInWorkspace(ctx, s.Client, *destWorkspaceID, childPath)
+		return entry, err == nil
+	}
+
+	if !s.Cache.HasChildren(destPath) && destEntry != nil {
+		parentID := parentIDPtr(destEntry)
+		children, err := s.Client.ListByParentIDWithOptions(ctx, parentID, api.ListOptions(s.WorkspaceID))
+		if err == nil {
+			s.Cache.AddChildren(destPath, children)
+		}
+	}
+	return s.Cache.Get(childPath)
+}
+
+// copyIntoFolder copies sources into a destination folder and returns the
+// files copied along with their sources.
+func copyIntoFolder(ctx context.Context, s *session.Session, sources []string, destEntry *api.FileEntry, destPath string, recursive, update bool, policy string, destWorkspaceID *int64, preserveACL bool) ([]copyPair, error) {
 	var ids []int64
+	var entries []*api.FileEntry
+	var kept []string
+	var replaced []int64
+	var replacedPaths []string
 	for _, src := range sources {
 		resolved, err := s.ResolvePathArg(src)
 		if err != nil {
-			return fmt.Errorf("cp: %w", err)
+			return nil, fmt.Errorf("cp: %w", err)
 		}
 		entry, ok := s.Cache.Get(resolved)
 		if !ok {
-			return fmt.Errorf("cp: cannot stat '%s': No such file or directory", src)
+			return nil, fmt.Errorf("cp: cannot stat '%s': No such file or directory", src)
 		}
 		if entry.Type == "folder" && !recursive {
-			return fmt.Errorf("cp: -r not specified; omitting directory '%s'", src)
+			return nil, fmt.Errorf("cp: -r not specified; omitting directory '%s'", src)
+		}
+		replace := policy == string(DuplicatePolicyReplace)
+		if (update || replace) && entry.Type != "folder" {
+			if existing, ok := existingChild(ctx, s, destEntry, destPath, entry.Name, destWorkspaceID); ok && existing.Type != "folder" && existing.ID != entry.ID {
+				if update {
+					fillMetadata(ctx, s, entry)
+					fillMetadata(ctx, s, existing)
+					if !isNewerThan(entry, existing) {
+						continue
+					}
+				}
+				replaced = append(replaced, existing.ID)
+				replacedPaths = append(replacedPaths, filepath.Join(destPath, existing.Name))
+			}
 		}
 		ids = append(ids, entry.ID)
+		entries = append(entries, entry)
+		kept = append(kept, src)
 	}
+	sources = kept
 
-	// Use nil for root folder (ID=0 is synthetic)
-	var destID *int64
-	if destEntry != nil && destEntry.ID != 0 {
-		destID = &destEntry.ID
+	if len(sources) == 0 {
+		return nil, nil
 	}
 
+	var links map[int64]*api.ShareableLink
+	if preserveACL {
+		var err error
+		if links, err = publicLinks(ctx, s, entries); err != nil {
+			return nil, fmt.Errorf("cp: --preserve-acl: %w", err)
+		}
+	}
+
+	destID := parentIDPtr(destEntry)
+
 	// Check collisions and resolve
 	targetWsID := s.WorkspaceID
 	if destWorkspaceID != nil {
 		targetWsID = *destWorkspaceID
 	}
 
-	resolvedMap, err := checkCollisionsAndResolve(ctx, s.Client, targetWsID, destID, destPath, sources)
+	// With -u or the replace policy, existing destination files are replaced
+	// rather than prompted for
+	if len(replaced) > 0 {
+		if err := s.Client.DeleteEntries(ctx, replaced, targetWsID); err != nil {
+			return nil, fmt.Errorf("cp: cannot replace outdated files: %w", err)
+		}
+		if destWorkspaceID == nil {
+			s.Cache.InvalidateChildren(destPath)
+			for i, id := range replaced {
+				s.RecordTrash(id, replacedPaths[i])
+			}
+		}
+	}
+
+	resolvedMap, err := checkCollisionsAndResolveWithPolicy(ctx, s.Client, targetWsID, destID, destPath, sources, policy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var finalIDs []int64
@@ -584,13 +858,37 @@ func copyIntoFolder(ctx context.Context, s *session.Session, sources []string, d
 	}
 
 	if len(finalIDs) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	var copied []api.FileEntry
 	copied, err = s.Client.CopyEntries(ctx, finalIDs, destID, s.WorkspaceID, destWorkspaceID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// Copies come back in the order of their sources
+	if len(links) > 0 && len(copied) == len(finalIDs) {
+		for i, id := range finalIDs {
+			if link, ok := links[id]; ok {
+				if err := restoreLink(ctx, s, copied[i].ID, link); err != nil {
+					return nil, fmt.Errorf("cp: copied, but cannot share '%s' like its source: %w", copied[i].Name, err)
+				}
+			}
+		}
+	}
+
+	var pairs []copyPair
+	if len(copied) == len(finalIDs) {
+		byID := make(map[int64]*api.FileEntry, len(entries))
+		for i, id := range ids {
+			byID[id] = entries[i]
+		}
+		for i, id := range finalIDs {
+			if src := byID[id]; src.Type != "folder" {
+				pairs = append(pairs, copyPair{src, &copied[i]})
+			}
+		}
 	}
 
 	// Add copied entries to cache only if same workspace
@@ -598,12 +896,13 @@ func copyIntoFolder(ctx context.Context, s *session.Session, sources []string, d
 		for i := range copied {
 			newPath := filepath.Join(destPath, copied[i].Name)
 			s.Cache.Add(&copied[i], newPath)
+			s.RecordCopy(copied[i].ID, newPath)
 		}
 		// Invalidate children of destination folder
 		s.Cache.InvalidateChildren(destPath)
 	}
 
-	return nil
+	return pairs, nil
 }
 
 func touch(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
@@ -614,8 +913,20 @@ func touch(ctx context.Context, s *session.Session, env *ExecutionEnv, args []st
 		}
 	}
 
+	fs := pflag.NewFlagSet("touch", pflag.ContinueOnError)
+	noCreate := fs.BoolP("no-create", "c", false, "do not create missing files")
+	stamp := fs.StringP("timestamp", "t", "", "not supported: the API cannot set modification times")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	if *stamp != "" {
+		return fmt.Errorf("touch: -t: modification times can't be set; the Drime API only updates an entry's name and description")
+	}
 	if len(args) < 1 {
-		return fmt.Errorf("usage: touch <file>")
+		return fmt.Errorf("usage: touch [-c] <file>")
 	}
 
 	return ui.WithSpinnerErr(env.Stderr, "", false, func() error {
@@ -625,6 +936,17 @@ func touch(ctx context.Context, s *session.Session, env *ExecutionEnv, args []st
 				return fmt.Errorf("touch: %w", err)
 			}
 
+			// Existing entry: update its timestamp without recreating it
+			if existing, ok := s.Cache.Get(resolved); ok {
+				if err := touchExisting(ctx, s, env, existing, resolved); err != nil {
+					return fmt.Errorf("touch: cannot touch '%s': %w", arg, err)
+				}
+				continue
+			}
+			if *noCreate {
+				continue
+			}
+
 			// Get parent directory
 			parentPath := filepath.Dir(resolved)
 			parentEntry, ok := s.Cache.Get(parentPath)
@@ -632,29 +954,10 @@ func touch(ctx context.Context, s *session.Session, env *ExecutionEnv, args []st
 				return fmt.Errorf("touch: cannot touch '%s': No such directory", parentPath)
 			}
 
-			// Get parent ID (nil for root)
-			var parentID *int64
-			if parentEntry.ID != 0 {
-				parentID = &parentEntry.ID
-			}
+			parentID := parentIDPtr(parentEntry)
 
 			name := filepath.Base(resolved)
 
-			// Check if file already exists (skip conflict resolution in vault - no duplicates)
-			if !s.InVault {
-				if _, ok := s.Cache.Get(resolved); ok {
-					// File exists, prompt for resolution
-					newName, proceed, err := ResolveConflict(ctx, s.Client, s.WorkspaceID, parentID, name)
-					if err != nil {
-						return err
-					}
-					if !proceed {
-						continue
-					}
-					name = newName
-				}
-			}
-
 			var entry *api.FileEntry
 			if s.InVault {
 				// Vault: encrypt empty content and upload
@@ -689,6 +992,25 @@ func touch(ctx context.Context, s *session.Session, env *ExecutionEnv, args []st
 	})
 }
 
+// touchExisting bumps the modification time of an existing entry to now in
+// place, preserving its ID and history.
+func touchExisting(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, path string) error {
+	if s.InVault {
+		fmt.Fprintf(env.Stderr, "touch: %s: timestamps cannot be changed in the vault\n", path)
+		return nil
+	}
+
+	updated, err := s.Client.TouchEntry(ctx, entry.ID, entry.Name, s.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	if updated == nil || updated.ID == 0 {
+		return nil
+	}
+	s.Cache.Add(updated, path)
+	return nil
+}
+
 // resolvePathInWorkspace resolves a path in a specific workspace without loading the entire tree.
 // It returns the file entry if found, or an error.
 func resolvePathInWorkspace(ctx context.Context, client api.DrimeClient, workspaceID int64, path string) (*api.FileEntry, error) {
@@ -696,7 +1018,9 @@ func resolvePathInWorkspace(ctx context.Context, client api.DrimeClient, workspa
 	path = filepath.Clean(path)
 	if path == "/" || path == "." {
 		// Root folder
-		return &api.FileEntry{ID: 0, Type: "folder", Name: "root", WorkspaceID: workspaceID}, nil
+		root := api.NewRootEntry()
+		root.WorkspaceID = workspaceID
+		return root, nil
 	}
 
 	parts := strings.Split(strings.Trim(path, "/"), "/")
@@ -770,16 +1094,12 @@ func copyToVault(ctx context.Context, s *session.Session, env *ExecutionEnv, sou
 	// Determine destination parent in vault
 	var destParentID *int64
 	if destEntry, ok := vaultCache.Get(destResolved); ok && destEntry.Type == "folder" {
-		if destEntry.ID != 0 {
-			destParentID = &destEntry.ID
-		}
+		destParentID = parentIDPtr(destEntry)
 	} else {
 		// Create destination folder in vault if needed
 		parentDir := filepath.Dir(destResolved)
 		if parentEntry, ok := vaultCache.Get(parentDir); ok && parentEntry.Type == "folder" {
-			if parentEntry.ID != 0 {
-				destParentID = &parentEntry.ID
-			}
+			destParentID = parentIDPtr(parentEntry)
 		}
 	}
 
@@ -854,9 +1174,7 @@ func copyFolderToVault(ctx context.Context, s *session.Session, env *ExecutionEn
 	destParentPath := filepath.Dir(destFolderPath)
 	var destParentID *int64
 	if parentEntry, ok := vaultCache.Get(destParentPath); ok && parentEntry.Type == "folder" {
-		if parentEntry.ID != 0 {
-			destParentID = &parentEntry.ID
-		}
+		destParentID = parentIDPtr(parentEntry)
 	}
 
 	folder, err := s.Client.CreateVaultFolder(ctx, srcEntry.Name, destParentID, vaultID)
@@ -866,7 +1184,7 @@ func copyFolderToVault(ctx context.Context, s *session.Session, env *ExecutionEn
 	vaultCache.Add(folder, destFolderPath)
 
 	// List children of source folder
-	children, err := s.Client.ListByParentIDWithOptions(ctx, &srcEntry.ID, api.ListOptions(srcWorkspaceID))
+	children, err := s.Client.ListByParentIDWithOptions(ctx, parentIDPtr(srcEntry), api.ListOptions(srcWorkspaceID))
 	if err != nil {
 		return fmt.Errorf("failed to list %s: %w", srcPath, err)
 	}
@@ -956,9 +1274,7 @@ func copyFileFromVault(ctx context.Context, s *session.Session, env *ExecutionEn
 	var destParentID *int64
 	destEntry, err := resolvePathInWorkspace(ctx, s.Client, destWorkspaceID, destPath)
 	if err == nil && destEntry != nil && destEntry.Type == "folder" {
-		if destEntry.ID != 0 {
-			destParentID = &destEntry.ID
-		}
+		destParentID = parentIDPtr(destEntry)
 	}
 
 	// Upload to workspace
@@ -980,9 +1296,7 @@ func copyFolderFromVault(ctx context.Context, s *session.Session, env *Execution
 	var destParentID *int64
 	destEntry, err := resolvePathInWorkspace(ctx, s.Client, destWorkspaceID, destPath)
 	if err == nil && destEntry != nil && destEntry.Type == "folder" {
-		if destEntry.ID != 0 {
-			destParentID = &destEntry.ID
-		}
+		destParentID = parentIDPtr(destEntry)
 	}
 
 	folder, err := s.Client.CreateFolder(ctx, srcEntry.Name, destParentID, destWorkspaceID)
@@ -1063,19 +1377,32 @@ func deleteVaultSources(ctx context.Context, s *session.Session, sources []strin
 	return nil
 }
 
-// reencryptAndUploadVaultFile downloads a vault file, decrypts it, re-encrypts with a fresh IV,
+// reencryptAndUploadVaultFile downloads a vault file, re-encrypts it with a fresh IV,
 // and uploads to the destination. Used by both mv (rename) and cp in vault.
-func reencryptAndUploadVaultFile(ctx context.Context, s *session.Session, srcEntry *api.FileEntry, destPath, newName string) (*api.FileEntry, error) {
-	// Download and decrypt using the shared helper
-	decrypted, err := DownloadAndDecrypt(ctx, s, srcEntry)
+// progress, if not nil, follows the download.
+func reencryptAndUploadVaultFile(ctx context.Context, s *session.Session, srcEntry *api.FileEntry, destPath, newName string, progress func(int64, int64)) (*api.FileEntry, error) {
+	if !s.VaultUnlocked {
+		return nil, fmt.Errorf("vault session error - please re-enter vault")
+	}
+	if srcEntry.IV == "" {
+		return nil, fmt.Errorf("file has no IV (not encrypted?)")
+	}
+	iv, err := crypto.DecodeBase64(srcEntry.IV)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid IV: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(int(srcEntry.Size))
+	if _, err := s.Client.DownloadEncrypted(ctx, srcEntry.Hash, &buf, progress); err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
 	}
 
-	// Encrypt with fresh IV for the new file
-	encrypted, newIV, err := s.VaultKey.Encrypt(decrypted)
+	// Vault files are a single AES-GCM message, so they can't be streamed;
+	// decrypting and encrypting in the download buffer keeps one copy in memory
+	encrypted, newIV, err := s.VaultKey.Reencrypt(buf.Bytes(), iv)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt: %w", err)
+		return nil, fmt.Errorf("failed to re-encrypt: %w", err)
 	}
 	newIVBase64 := crypto.EncodeBase64(newIV)
 
@@ -1087,7 +1414,7 @@ func reencryptAndUploadVaultFile(ctx context.Context, s *session.Session, srcEnt
 		if !ok {
 			return nil, fmt.Errorf("parent folder not found")
 		}
-		parentID = &parentEntry.ID
+		parentID = parentIDPtr(parentEntry)
 	}
 
 	// Upload with new name
@@ -1100,7 +1427,7 @@ func renameVaultFile(ctx context.Context, s *session.Session, srcEntry *api.File
 		return fmt.Errorf("mv: renaming folders in vault is not supported")
 	}
 
-	newEntry, err := reencryptAndUploadVaultFile(ctx, s, srcEntry, destPath, newName)
+	newEntry, err := reencryptAndUploadVaultFile(ctx, s, srcEntry, destPath, newName, nil)
 	if err != nil {
 		return fmt.Errorf("mv: %w", err)
 	}
@@ -1119,21 +1446,154 @@ func renameVaultFile(ctx context.Context, s *session.Session, srcEntry *api.File
 	return nil
 }
 
-// copyVaultFile copies a vault file by downloading, decrypting, re-encrypting, and uploading with new name
-func copyVaultFile(ctx context.Context, s *session.Session, srcEntry *api.FileEntry, srcPath, destPath, newName string) error {
-	if srcEntry.Type == "folder" {
-		return fmt.Errorf("cp: copying folders in vault is not supported")
+// copyWithinVault copies files and folders inside the vault. The vault has
+// no server-side copy, so every file is downloaded, re-encrypted with a
+// fresh IV and uploaded again, with a progress bar per file on stderr. Folders are
+// recreated and copied file by file; a file that fails is reported and the
+// others are still copied.
+func copyWithinVault(ctx context.Context, s *session.Session, env *ExecutionEnv, sources []string, dest string, recursive, force, update bool) error {
+	destResolved, err := s.ResolvePathArg(dest)
+	if err != nil {
+		return fmt.Errorf("cp: %w", err)
+	}
+	destEntry, destExists := s.Cache.Get(destResolved)
+	if len(sources) > 1 || session.IsDirArg(dest) {
+		if err := requireDirectoryTarget("cp", dest, destEntry, destExists); err != nil {
+			return err
+		}
+	}
+
+	vc := &vaultCopier{ctx: ctx, s: s, env: env, force: force, update: update}
+	for _, src := range sources {
+		srcResolved, err := s.ResolvePathArg(src)
+		if err != nil {
+			return fmt.Errorf("cp: %w", err)
+		}
+		srcEntry, ok := s.Cache.Get(srcResolved)
+		if !ok {
+			return fmt.Errorf("cp: cannot stat '%s': No such file or directory", src)
+		}
+		if srcEntry.Type == "folder" && !recursive {
+			return fmt.Errorf("cp: -r not specified; omitting directory '%s'", src)
+		}
+
+		target := destResolved
+		if destExists && destEntry.Type == "folder" {
+			target = filepath.Join(destResolved, srcEntry.Name)
+		} else if parent, ok := s.Cache.Get(filepath.Dir(destResolved)); !ok || parent.Type != "folder" {
+			return fmt.Errorf("cp: cannot create '%s': No such directory", filepath.Dir(destResolved))
+		}
+		if srcEntry.Type == "folder" && (target == srcResolved || strings.HasPrefix(target, srcResolved+"/")) {
+			return fmt.Errorf("cp: cannot copy a directory, '%s', into itself, '%s'", src, dest)
+		}
+
+		if srcEntry.Type == "folder" {
+			err = vc.copyFolder(srcEntry, srcResolved, target)
+		} else {
+			err = vc.copyFile(srcEntry, target)
+		}
+		if err != nil {
+			return fmt.Errorf("cp: %w", err)
+		}
 	}
 
-	newEntry, err := reencryptAndUploadVaultFile(ctx, s, srcEntry, destPath, newName)
+	if vc.failed > 0 {
+		return fmt.Errorf("cp: %d of %d files could not be copied", vc.failed, vc.failed+vc.copied)
+	}
+	return nil
+}
+
+// vaultCopier copies vault entries and counts the files copied and failed.
+type vaultCopier struct {
+	ctx           context.Context
+	s             *session.Session
+	env           *ExecutionEnv
+	force, update bool
+	copied        int
+	failed        int
+}
+
+// copyFile copies src to destPath, replacing an existing file there only
+// with -f, or with -u when src is newer.
+func (vc *vaultCopier) copyFile(src *api.FileEntry, destPath string) error {
+	s := vc.s
+	if existing, ok := s.Cache.Get(destPath); ok {
+		switch {
+		case existing.Type == "folder":
+			return fmt.Errorf("cannot overwrite directory '%s' with non-directory", destPath)
+		case vc.update && !isNewerThan(src, existing):
+			return nil
+		case !vc.force && !vc.update:
+			return fmt.Errorf("cannot overwrite '%s' (use -f to replace it)", destPath)
+		}
+		if err := removeOverwrittenFile(vc.ctx, s, src, existing, destPath); err != nil {
+			return err
+		}
+	}
+
+	name := filepath.Base(destPath)
+	var newEntry *api.FileEntry
+	err := ui.RunFileTransferTo(vc.ctx, vc.env.Stderr, "Copying "+name, name, src.Size, func(send func(int64, int64)) error {
+		var err error
+		newEntry, err = reencryptAndUploadVaultFile(vc.ctx, s, src, destPath, name, send)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("cp: %w", err)
+		return fmt.Errorf("%s: %w", src.Name, err)
 	}
 
 	// Update cache (add new entry, keep original)
 	if newEntry != nil {
 		s.Cache.Add(newEntry, destPath)
 	}
+	vc.copied++
+	fmt.Fprintf(vc.env.Stdout, "Copied: %s -> %s (re-encrypted)\n", src.Name, destPath)
+	return nil
+}
+
+// copyFolder creates destPath, unless it exists, and copies the contents of
+// src into it. Failing files are reported and skipped.
+func (vc *vaultCopier) copyFolder(src *api.FileEntry, srcPath, destPath string) error {
+	s := vc.s
+	folder, ok := s.Cache.Get(destPath)
+	if ok && folder.Type != "folder" {
+		return fmt.Errorf("cannot overwrite non-directory '%s' with directory '%s'", destPath, src.Name)
+	}
+	if !ok {
+		parent, _ := s.Cache.Get(filepath.Dir(destPath))
+		var err error
+		folder, err = s.Client.CreateVaultFolder(vc.ctx, filepath.Base(destPath), parentIDPtr(parent), s.VaultID)
+		if err != nil {
+			return fmt.Errorf("failed to create folder %s: %w", destPath, err)
+		}
+		s.Cache.Add(folder, destPath)
+	}
 
+	children, err := s.Client.ListVaultEntries(vc.ctx, src.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", srcPath, err)
+	}
+	for _, child := range children {
+		if err := vc.ctx.Err(); err != nil {
+			return err
+		}
+		childCopy := child
+		childSrc := filepath.Join(srcPath, child.Name)
+		childDest := filepath.Join(destPath, child.Name)
+		if child.Type == "folder" {
+			s.Cache.Add(&childCopy, childSrc)
+			if err := vc.copyFolder(&childCopy, childSrc, childDest); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := vc.copyFile(&childCopy, childDest); err != nil {
+			if vc.ctx.Err() != nil {
+				return err
+			}
+			vc.failed++
+			fmt.Fprintf(vc.env.Stderr, "cp: %v\n", err)
+		}
+	}
 	return nil
 }
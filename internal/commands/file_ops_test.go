@@ -0,0 +1,994 @@
+package commands_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/commands"
+	"github.com/gYonder/drime-shell/internal/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// CP -u (UPDATE-ONLY) TESTS
+// ============================================================================
+
+func TestCpUpdate_SkipsWhenDestinationIsNewer(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	now := time.Now()
+	destID := int64(200)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "report.txt", Type: "text", UpdatedAt: now.Add(-time.Hour)},
+		{ID: destID, Name: "backup", Type: "folder"},
+	})
+	s.Cache.AddChildren("/backup", []api.FileEntry{
+		{ID: 201, Name: "report.txt", Type: "text", ParentID: &destID, UpdatedAt: now},
+	})
+
+	copyCalled := false
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+		copyCalled = true
+		return nil, nil
+	}
+
+	cmd, ok := commands.Get("cp")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-u", "report.txt", "/backup"}))
+	assert.False(t, copyCalled, "up-to-date destination should not be copied over")
+}
+
+func TestCpUpdate_ReplacesOlderDestination(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	now := time.Now()
+	destID := int64(200)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "report.txt", Type: "text", UpdatedAt: now},
+		{ID: destID, Name: "backup", Type: "folder"},
+	})
+	s.Cache.AddChildren("/backup", []api.FileEntry{
+		{ID: 201, Name: "report.txt", Type: "text", ParentID: &destID, UpdatedAt: now.Add(-time.Hour)},
+	})
+
+	var deletedIDs, copiedIDs []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		deletedIDs = append(deletedIDs, entryIDs...)
+		return nil
+	}
+	mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+		copiedIDs = append(copiedIDs, entryIDs...)
+		return []api.FileEntry{{ID: 301, Name: "report.txt", Type: "text", ParentID: &destID}}, nil
+	}
+
+	cmd, ok := commands.Get("cp")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-u", "report.txt", "/backup"}))
+	assert.Equal(t, []int64{201}, deletedIDs)
+	assert.Equal(t, []int64{101}, copiedIDs)
+}
+
+func TestCpUpdate_CopiesWhenDestinationMissing(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	destID := int64(200)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "report.txt", Type: "text", UpdatedAt: time.Now()},
+		{ID: destID, Name: "backup", Type: "folder"},
+	})
+	s.Cache.AddChildren("/backup", []api.FileEntry{})
+
+	var copiedIDs []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+		copiedIDs = append(copiedIDs, entryIDs...)
+		return nil, nil
+	}
+
+	cmd, ok := commands.Get("cp")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-u", "report.txt", "/backup"}))
+	assert.Equal(t, []int64{101}, copiedIDs)
+}
+
+func TestCpMv_PreserveACLAcrossWorkspaces(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "public.pdf", Type: "file", Public: true},
+		{ID: 102, Name: "private.pdf", Type: "file"},
+		{ID: 103, Name: "locked.pdf", Type: "file", Public: true},
+	})
+	password := "hidden"
+	sourceLinks := map[int64]*api.ShareableLink{
+		101: {Hash: "pub", EntryID: 101, AllowDownload: true, ExpiresAt: &expires},
+		103: {Hash: "lock", EntryID: 103, Password: &password},
+	}
+
+	created := make(map[int64]api.ShareableLinkRequest)
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetShareableLinkFunc = func(ctx context.Context, entryID int64) (*api.ShareableLink, error) {
+		return sourceLinks[entryID], nil
+	}
+	mockClient.CreateShareableLinkFunc = func(ctx context.Context, entryID int64, req api.ShareableLinkRequest) (*api.ShareableLink, error) {
+		created[entryID] = req
+		return &api.ShareableLink{Hash: "new", EntryID: entryID}, nil
+	}
+	mockClient.ListByParentIDWithOptionsFunc = func(ctx context.Context, parentID *int64, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+		return []api.FileEntry{}, nil
+	}
+	mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+		var copies []api.FileEntry
+		for _, id := range entryIDs {
+			copies = append(copies, api.FileEntry{ID: id + 1000, Name: fmt.Sprint(id)})
+		}
+		return copies, nil
+	}
+	// The move drops the link, as the server does across workspaces
+	mockClient.MoveEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) error {
+		for _, id := range entryIDs {
+			delete(sourceLinks, id)
+		}
+		return nil
+	}
+
+	cp, _ := commands.Get("cp")
+	mv, _ := commands.Get("mv")
+
+	// Without -w there is nothing to preserve
+	err := cp.Run(context.Background(), s, env, []string{"--preserve-acl", "public.pdf", "/backup/"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only applies between workspaces")
+
+	// Only the public entry's copy is shared, with the same access and expiry
+	require.NoError(t, cp.Run(context.Background(), s, env, []string{"--preserve-acl", "-w", "default", "public.pdf", "private.pdf", "/"}))
+	require.Len(t, created, 1)
+	req := created[1101]
+	assert.True(t, req.AllowDownload)
+	assert.False(t, req.AllowEdit)
+	require.NotNil(t, req.ExpiresAt)
+	assert.Equal(t, expires.Format(time.RFC3339), *req.ExpiresAt)
+
+	// Without the flag the copy stays private
+	created = make(map[int64]api.ShareableLinkRequest)
+	require.NoError(t, cp.Run(context.Background(), s, env, []string{"-w", "default", "public.pdf", "/"}))
+	assert.Empty(t, created)
+
+	// A password can't be read back, so such a link is refused up front
+	err = cp.Run(context.Background(), s, env, []string{"--preserve-acl", "-w", "default", "locked.pdf", "/"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has a password")
+
+	// A moved entry keeps its ID and gets its link back
+	require.NoError(t, mv.Run(context.Background(), s, env, []string{"--preserve-acl", "-w", "default", "public.pdf", "/"}))
+	assert.Contains(t, created, int64(101))
+}
+
+// ============================================================================
+// TOUCH TESTS
+// ============================================================================
+
+func TestTouch_ExistingFileUpdatesTimestampInPlace(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 1, 15, 12, 30, 45, 0, time.UTC)
+	s.Cache.Add(&api.FileEntry{ID: 42, Name: "notes.txt", Type: "text", UpdatedAt: old}, "/notes.txt")
+
+	var touchedID int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		t.Fatal("existing file must not be re-uploaded")
+		return nil, nil
+	}
+	mockClient.TouchEntryFunc = func(ctx context.Context, entryID int64, name string, workspaceID int64) (*api.FileEntry, error) {
+		touchedID = entryID
+		return &api.FileEntry{ID: entryID, Name: name, Type: "text", UpdatedAt: now}, nil
+	}
+
+	cmd, ok := commands.Get("touch")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"notes.txt"}))
+
+	assert.Equal(t, int64(42), touchedID)
+	entry, ok := s.Cache.Get("/notes.txt")
+	require.True(t, ok)
+	assert.Equal(t, int64(42), entry.ID)
+	assert.True(t, entry.UpdatedAt.Equal(now))
+
+	// The API has no way to set another time, so -t says so up front
+	touchedID = 0
+	err := cmd.Run(context.Background(), s, env, []string{"-t", "202401151230.45", "notes.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can't be set")
+	assert.Zero(t, touchedID)
+
+	// An unset mock answers like the others do
+	mockClient.TouchEntryFunc = nil
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"notes.txt"}))
+}
+
+func TestTouch_NoCreateSkipsMissingFiles(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		t.Fatal("-c must not create files")
+		return nil, nil
+	}
+
+	cmd, ok := commands.Get("touch")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-c", "missing.txt"}))
+}
+
+// ============================================================================
+// MULTI-SOURCE DESTINATION CHECK TESTS
+// ============================================================================
+
+func TestMultiSource_RequiresDirectoryTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		dest    string
+		wantErr string
+	}{
+		{"into file", "backup", "target 'backup' is not a directory"},
+		{"into missing path", "nowhere", "target 'nowhere': No such file or directory"},
+		{"into folder", "dir", ""},
+	}
+
+	for _, cmdName := range []string{"mv", "cp"} {
+		for _, tt := range tests {
+			t.Run(cmdName+" "+tt.name, func(t *testing.T) {
+				s, env, _ := setupTestEnv(t)
+				s.Cache.Add(&api.FileEntry{ID: 1, Name: "a.txt", Type: "text"}, "/a.txt")
+				s.Cache.Add(&api.FileEntry{ID: 2, Name: "b.txt", Type: "text"}, "/b.txt")
+				s.Cache.Add(&api.FileEntry{ID: 3, Name: "backup", Type: "text"}, "/backup")
+				s.Cache.Add(&api.FileEntry{ID: 4, Name: "dir", Type: "folder"}, "/dir")
+
+				apiCalled := false
+				mockClient := s.Client.(*api.MockDrimeClient)
+				mockClient.MoveEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) error {
+					apiCalled = true
+					return nil
+				}
+				mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+					apiCalled = true
+					return nil, nil
+				}
+
+				cmd, ok := commands.Get(cmdName)
+				require.True(t, ok)
+				err := cmd.Run(context.Background(), s, env, []string{"a.txt", "b.txt", tt.dest})
+
+				if tt.wantErr == "" {
+					require.NoError(t, err)
+					assert.True(t, apiCalled)
+					return
+				}
+				require.Error(t, err)
+				assert.Equal(t, cmdName+": "+tt.wantErr, err.Error())
+				assert.False(t, apiCalled, "no API call expected before the target check")
+			})
+		}
+	}
+}
+
+func TestCpMv_TrailingSlashDestination(t *testing.T) {
+	tests := []struct {
+		name string
+		dest string
+		want string // "into", "rename" or the error
+	}{
+		{"folder", "dir", "into"},
+		{"folder with slash", "dir/", "into"},
+		{"missing path", "nowhere", "rename"},
+		{"missing path with slash", "nowhere/", "target 'nowhere/': No such file or directory"},
+		{"file with slash", "backup/", "target 'backup/' is not a directory"},
+	}
+
+	for _, cmdName := range []string{"mv", "cp"} {
+		for _, tt := range tests {
+			t.Run(cmdName+" "+tt.name, func(t *testing.T) {
+				s, env, _ := setupTestEnv(t)
+				s.Cache.Add(&api.FileEntry{ID: 1, Name: "a.txt", Type: "text"}, "/a.txt")
+				s.Cache.Add(&api.FileEntry{ID: 3, Name: "backup", Type: "text"}, "/backup")
+				s.Cache.Add(&api.FileEntry{ID: 4, Name: "dir", Type: "folder"}, "/dir")
+
+				var into int64
+				renamed := ""
+				mockClient := s.Client.(*api.MockDrimeClient)
+				mockClient.MoveEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) error {
+					if destinationParentID != nil {
+						into = *destinationParentID
+					}
+					return nil
+				}
+				mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+					if destinationParentID != nil {
+						into = *destinationParentID
+					}
+					return []api.FileEntry{{ID: 9, Name: "a.txt", Type: "text"}}, nil
+				}
+				mockClient.RenameEntryFunc = func(ctx context.Context, entryID int64, newName string, workspaceID int64) (*api.FileEntry, error) {
+					renamed = newName
+					return &api.FileEntry{ID: entryID, Name: newName, Type: "text"}, nil
+				}
+
+				cmd, ok := commands.Get(cmdName)
+				require.True(t, ok)
+				err := cmd.Run(context.Background(), s, env, []string{"a.txt", tt.dest})
+
+				switch tt.want {
+				case "into":
+					require.NoError(t, err)
+					assert.Equal(t, int64(4), into)
+					assert.Empty(t, renamed)
+				case "rename":
+					require.NoError(t, err)
+					assert.Equal(t, "nowhere", renamed)
+				default:
+					require.Error(t, err)
+					assert.Equal(t, cmdName+": "+tt.want, err.Error())
+					assert.Empty(t, renamed)
+				}
+			})
+		}
+	}
+}
+
+func TestCatAndEdit_BinaryFiles(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	binary := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x01\x02")
+	s.Cache.Add(&api.FileEntry{ID: 7, Name: "logo.png", Type: "image", Hash: "h7", Size: int64(len(binary))}, "/logo.png")
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		_, err := w.Write(binary)
+		return nil, err
+	}
+
+	// Output is not a terminal, so cat passes the bytes through untouched
+	cat, ok := commands.Get("cat")
+	require.True(t, ok)
+	require.NoError(t, cat.Run(context.Background(), s, env, []string{"logo.png"}))
+	assert.Equal(t, binary, stdout.Bytes())
+
+	edit, ok := commands.Get("edit")
+	require.True(t, ok)
+	err := edit.Run(context.Background(), s, env, []string{"logo.png"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "binary file")
+}
+
+func TestCp_VerboseReportsServerSideCopy(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	stderr := env.Stderr.(*bytes.Buffer)
+
+	destID := int64(200)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "photos", Type: "folder"},
+		{ID: destID, Name: "backup", Type: "folder"},
+	})
+	s.Cache.AddChildren("/backup", []api.FileEntry{})
+
+	var copiedIDs []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.ListByParentIDWithOptionsFunc = func(ctx context.Context, parentID *int64, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+		return []api.FileEntry{}, nil
+	}
+	mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+		copiedIDs = entryIDs
+		return []api.FileEntry{{ID: 301, Name: "photos", Type: "folder", ParentID: &destID}}, nil
+	}
+
+	cmd, ok := commands.Get("cp")
+	require.True(t, ok)
+
+	// The folder is copied in one server-side call, not file by file
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-v", "--reflink", "-r", "photos", "/backup"}))
+	assert.Equal(t, []int64{101}, copiedIDs)
+	assert.Contains(t, stderr.String(), "'photos' -> '/backup' (server-side copy)")
+
+	// Copies into the vault can't be server-side
+	err := cmd.Run(context.Background(), s, env, []string{"--reflink=always", "--vault", "photos", "/"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "download and re-upload")
+
+	err = cmd.Run(context.Background(), s, env, []string{"--reflink=never", "photos", "/backup"})
+	require.Error(t, err)
+}
+
+func TestCp_VerifyReadsCopiesBack(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+
+	destID := int64(200)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "a.txt", Type: "text", Hash: "a"},
+		{ID: 102, Name: "docs", Type: "folder"},
+		{ID: destID, Name: "backup", Type: "folder"},
+	})
+	s.Cache.AddChildren("/backup", []api.FileEntry{})
+
+	contents := map[string]string{"a": "alpha", "a-copy": "alpha"}
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+		copies := map[int64]api.FileEntry{
+			101: {ID: 301, Name: "a.txt", Type: "text", Hash: "a-copy", ParentID: &destID},
+			102: {ID: 302, Name: "docs", Type: "folder", ParentID: &destID},
+		}
+		var out []api.FileEntry
+		for _, id := range entryIDs {
+			out = append(out, copies[id])
+		}
+		return out, nil
+	}
+	mockClient.RenameEntryFunc = func(ctx context.Context, entryID int64, newName string, workspaceID int64) (*api.FileEntry, error) {
+		return &api.FileEntry{ID: entryID, Name: newName, Type: "text", Hash: "a-copy"}, nil
+	}
+	var downloaded []string
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		downloaded = append(downloaded, hash)
+		_, err := io.WriteString(w, contents[hash])
+		return nil, err
+	}
+
+	cmd, ok := commands.Get("cp")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--verify", "a.txt", "/backup/"}))
+	assert.Equal(t, []string{"a", "a-copy"}, downloaded)
+	assert.Contains(t, stdout.String(), "Verified a.txt (sha256 ")
+
+	// A folder's files would go unchecked, so -r is refused before copying
+	copied := false
+	mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+		copied = true
+		return nil, nil
+	}
+	err := cmd.Run(context.Background(), s, env, []string{"-r", "--verify", "docs", "/backup/"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not with -r")
+	assert.False(t, copied)
+
+	mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+		return []api.FileEntry{{ID: 301, Name: "a.txt", Type: "text", Hash: "a-copy", ParentID: &destID}}, nil
+	}
+	contents["a-copy"] = "alphA"
+	err = cmd.Run(context.Background(), s, env, []string{"--checksum-algo", "crc32", "a.txt", "/backup/copy.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "crc32 mismatch")
+
+	err = cmd.Run(context.Background(), s, env, []string{"--verify", "--vault", "a.txt", "/"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only checks server-side copies")
+}
+
+// ============================================================================
+// CP/MV -f (FORCE OVERWRITE) TESTS
+// ============================================================================
+
+func TestCpMv_RefuseExistingFileWithoutForce(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "draft.txt", Type: "text"},
+		{ID: 102, Name: "final.txt", Type: "text"},
+	})
+
+	cp, _ := commands.Get("cp")
+	err := cp.Run(context.Background(), s, env, []string{"draft.txt", "final.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot overwrite")
+
+	// Without -f, mv treats an existing file like any non-directory target
+	mv, _ := commands.Get("mv")
+	err = mv.Run(context.Background(), s, env, []string{"draft.txt", "final.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a directory")
+}
+
+func TestCpForce_ReplacesExistingFile(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "draft.txt", Type: "text"},
+		{ID: 102, Name: "final.txt", Type: "text"},
+	})
+
+	var deletedIDs []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		deletedIDs = append(deletedIDs, entryIDs...)
+		return nil
+	}
+	mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+		require.Equal(t, []int64{102}, deletedIDs, "destination must be deleted before copying")
+		return []api.FileEntry{{ID: 301, Name: "draft.txt", Type: "text"}}, nil
+	}
+	mockClient.RenameEntryFunc = func(ctx context.Context, entryID int64, newName string, workspaceID int64) (*api.FileEntry, error) {
+		return &api.FileEntry{ID: entryID, Name: newName, Type: "text"}, nil
+	}
+
+	cmd, ok := commands.Get("cp")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-f", "draft.txt", "final.txt"}))
+	assert.Equal(t, []int64{102}, deletedIDs)
+
+	entry, ok := s.Cache.Get("/final.txt")
+	require.True(t, ok)
+	assert.Equal(t, int64(301), entry.ID)
+	_, ok = s.Cache.Get("/draft.txt")
+	assert.True(t, ok, "cp must keep the source")
+}
+
+func TestMvForce_ReplacesExistingFile(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "draft.txt", Type: "text"},
+		{ID: 102, Name: "final.txt", Type: "text"},
+		{ID: 103, Name: "same.txt", Type: "text"},
+	})
+
+	var deletedIDs []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		deletedIDs = append(deletedIDs, entryIDs...)
+		return nil
+	}
+	mockClient.RenameEntryFunc = func(ctx context.Context, entryID int64, newName string, workspaceID int64) (*api.FileEntry, error) {
+		return &api.FileEntry{ID: entryID, Name: newName, Type: "text"}, nil
+	}
+
+	cmd, ok := commands.Get("mv")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-f", "draft.txt", "final.txt"}))
+	assert.Equal(t, []int64{102}, deletedIDs)
+
+	entry, ok := s.Cache.Get("/final.txt")
+	require.True(t, ok)
+	assert.Equal(t, int64(101), entry.ID)
+	_, ok = s.Cache.Get("/draft.txt")
+	assert.False(t, ok)
+
+	// A file is never deleted to make room for itself
+	err := cmd.Run(context.Background(), s, env, []string{"-f", "same.txt", "same.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "same file")
+	assert.Equal(t, []int64{102}, deletedIDs)
+}
+
+func TestCpMv_DefaultOnDuplicate(t *testing.T) {
+	defer commands.SetNoPromptForTest()()
+	s, env, _ := setupTestEnv(t)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "a.txt", Type: "text"},
+		{ID: 102, Name: "b.txt", Type: "text"},
+		{ID: 5, Name: "dest", Type: "folder"},
+	})
+	s.Cache.AddChildren("/dest", []api.FileEntry{
+		{ID: 201, Name: "a.txt", Type: "text"},
+		{ID: 202, Name: "b.txt", Type: "text"},
+	})
+
+	var deletedIDs, copiedIDs, movedIDs []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.ValidateEntriesFunc = func(ctx context.Context, req api.ValidateRequest) (*api.ValidateResponse, error) {
+		var dups []string
+		for _, f := range req.Files {
+			if _, ok := s.Cache.Get("/dest/" + f.Name); ok {
+				dups = append(dups, f.Name)
+			}
+		}
+		return &api.ValidateResponse{Duplicates: dups}, nil
+	}
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		deletedIDs = append(deletedIDs, entryIDs...)
+		return nil
+	}
+	mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+		copiedIDs = append(copiedIDs, entryIDs...)
+		return nil, nil
+	}
+	mockClient.MoveEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationID *int64, workspaceID int64, destWorkspaceID *int64) error {
+		movedIDs = append(movedIDs, entryIDs...)
+		return nil
+	}
+
+	cp, _ := commands.Get("cp")
+	mv, _ := commands.Get("mv")
+
+	// Without a policy, duplicates would need a prompt, which fails fast
+	// when stdin isn't a terminal
+	err := cp.Run(context.Background(), s, env, []string{"a.txt", "/dest"})
+	require.ErrorContains(t, err, "stdin is not a terminal")
+	assert.Empty(t, copiedIDs)
+
+	s.OnDuplicate = "skip"
+	require.NoError(t, cp.Run(context.Background(), s, env, []string{"a.txt", "/dest"}))
+	require.NoError(t, mv.Run(context.Background(), s, env, []string{"a.txt", "/dest"}))
+	assert.Empty(t, copiedIDs)
+	assert.Empty(t, movedIDs)
+
+	// An explicit flag wins over the config
+	require.NoError(t, cp.Run(context.Background(), s, env, []string{"--on-duplicate", "replace", "a.txt", "/dest"}))
+	assert.Equal(t, []int64{201}, deletedIDs)
+	assert.Equal(t, []int64{101}, copiedIDs)
+
+	s.OnDuplicate = "replace"
+	require.NoError(t, mv.Run(context.Background(), s, env, []string{"b.txt", "/dest"}))
+	assert.Equal(t, []int64{201, 202}, deletedIDs)
+	assert.Equal(t, []int64{102}, movedIDs)
+
+	s.OnDuplicate = "sometimes"
+	err = cp.Run(context.Background(), s, env, []string{"a.txt", "/dest"})
+	require.ErrorContains(t, err, "invalid default_on_duplicate")
+}
+
+func TestCpForce_ReplacesExistingFileInVault(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	salt, err := crypto.GenerateSalt()
+	require.NoError(t, err)
+	key := crypto.DeriveKey("pw", salt)
+	ciphertext, iv, err := key.Encrypt([]byte("new contents"))
+	require.NoError(t, err)
+
+	s.InVault = true
+	s.VaultID = 7
+	s.SetVaultKey(key)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "draft.txt", Type: "text", Hash: "h-draft", IV: crypto.EncodeBase64(iv)},
+		{ID: 102, Name: "final.txt", Type: "text", Hash: "h-final"},
+	})
+
+	var deletedIDs []int64
+	var uploadedName string
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DeleteVaultEntriesFunc = func(ctx context.Context, entryIDs []int64) error {
+		deletedIDs = append(deletedIDs, entryIDs...)
+		return nil
+	}
+	mockClient.DownloadEncryptedFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		require.Equal(t, "h-draft", hash)
+		_, err := w.Write(ciphertext)
+		return nil, err
+	}
+	mockClient.UploadToVaultFunc = func(ctx context.Context, encryptedContent []byte, name string, parentID *int64, vaultID int64, ivBase64 string) (*api.FileEntry, error) {
+		require.Equal(t, []int64{102}, deletedIDs, "destination must be deleted before uploading the copy")
+		uploadedName = name
+		return &api.FileEntry{ID: 301, Name: name, Type: "text", IV: ivBase64}, nil
+	}
+
+	cmd, ok := commands.Get("cp")
+	require.True(t, ok)
+	err = cmd.Run(context.Background(), s, env, []string{"draft.txt", "final.txt"})
+	require.Error(t, err, "vault copies must refuse to overwrite without -f")
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-f", "draft.txt", "final.txt"}))
+	assert.Equal(t, "final.txt", uploadedName)
+	entry, ok := s.Cache.Get("/final.txt")
+	require.True(t, ok)
+	assert.Equal(t, int64(301), entry.ID)
+}
+
+func TestCp_RecursiveCopyWithinVault(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+
+	salt, err := crypto.GenerateSalt()
+	require.NoError(t, err)
+	key := crypto.DeriveKey("pw", salt)
+	encrypt := func(text string) ([]byte, string) {
+		ct, iv, err := key.Encrypt([]byte(text))
+		require.NoError(t, err)
+		return ct, crypto.EncodeBase64(iv)
+	}
+	aCT, aIV := encrypt("alpha")
+	bCT, bIV := encrypt("bravo")
+
+	s.InVault = true
+	s.VaultID = 7
+	s.SetVaultKey(key)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 10, Name: "docs", Type: "folder", Hash: "h-docs"},
+	})
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.ListVaultEntriesFunc = func(ctx context.Context, folderHash string) ([]api.FileEntry, error) {
+		switch folderHash {
+		case "h-docs":
+			return []api.FileEntry{
+				{ID: 11, Name: "a.txt", Type: "text", Hash: "h-a", IV: aIV, Size: int64(len(aCT))},
+				{ID: 12, Name: "sub", Type: "folder", Hash: "h-sub"},
+				{ID: 13, Name: "broken.txt", Type: "text", Hash: "h-broken", IV: aIV},
+			}, nil
+		case "h-sub":
+			return []api.FileEntry{{ID: 14, Name: "b.txt", Type: "text", Hash: "h-b", IV: bIV, Size: int64(len(bCT))}}, nil
+		}
+		return nil, nil
+	}
+	mockClient.DownloadEncryptedFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		data := map[string][]byte{"h-a": aCT, "h-b": bCT, "h-broken": []byte("garbage that won't decrypt")}[hash]
+		_, err := w.Write(data)
+		return nil, err
+	}
+	nextID := int64(100)
+	folderParents := map[string]*int64{}
+	mockClient.CreateVaultFolderFunc = func(ctx context.Context, name string, parentID *int64, vaultID int64) (*api.FileEntry, error) {
+		folderParents[name] = parentID
+		nextID++
+		return &api.FileEntry{ID: nextID, Name: name, Type: "folder"}, nil
+	}
+	uploaded := map[string]string{}
+	mockClient.UploadToVaultFunc = func(ctx context.Context, encryptedContent []byte, name string, parentID *int64, vaultID int64, ivBase64 string) (*api.FileEntry, error) {
+		iv, err := crypto.DecodeBase64(ivBase64)
+		require.NoError(t, err)
+		plain, err := key.Decrypt(encryptedContent, iv)
+		require.NoError(t, err)
+		uploaded[name] = string(plain)
+		nextID++
+		return &api.FileEntry{ID: nextID, Name: name, Type: "text", IV: ivBase64}, nil
+	}
+
+	cmd, ok := commands.Get("cp")
+	require.True(t, ok)
+	err = cmd.Run(context.Background(), s, env, []string{"docs", "copy"})
+	require.Error(t, err, "folders need -r")
+
+	err = cmd.Run(context.Background(), s, env, []string{"-r", "docs", "copy"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 3 files could not be copied")
+
+	assert.Equal(t, map[string]string{"a.txt": "alpha", "b.txt": "bravo"}, uploaded)
+	assert.Nil(t, folderParents["copy"], "copy is created at the vault root")
+	require.NotNil(t, folderParents["sub"])
+	copyEntry, ok := s.Cache.Get("/copy")
+	require.True(t, ok)
+	assert.Equal(t, copyEntry.ID, *folderParents["sub"])
+	_, ok = s.Cache.Get("/copy/sub/b.txt")
+	assert.True(t, ok)
+	assert.Contains(t, stdout.String(), "Copied: a.txt -> /copy/a.txt (re-encrypted)")
+	assert.Contains(t, stdout.String(), "Copied: b.txt -> /copy/sub/b.txt (re-encrypted)")
+}
+
+// ============================================================================
+// EDIT WITH $EDITOR TESTS
+// ============================================================================
+
+func TestEdit_ExternalEditorSeveralFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell as the editor")
+	}
+	s, env, _ := setupTestEnv(t)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 1, Name: "a.txt", Type: "text", Hash: "ha"},
+		{ID: 2, Name: "b.txt", Type: "text", Hash: "hb"},
+		{ID: 3, Name: "dir", Type: "folder"},
+	})
+	contents := map[string]string{"ha": "old value\n", "hb": "nothing to change\n"}
+
+	var deleted []int64
+	uploaded := map[string]string{}
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		_, err := io.WriteString(w, contents[hash])
+		return nil, err
+	}
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		deleted = append(deleted, entryIDs...)
+		return nil
+	}
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		data, err := io.ReadAll(reader)
+		uploaded[name] = string(data)
+		return &api.FileEntry{ID: 10, Name: name, Type: "text", Size: size}, err
+	}
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "sed -i s/old/new/")
+	cmd, ok := commands.Get("edit")
+	require.True(t, ok)
+
+	// The folder fails, the other files are still edited
+	err := cmd.Run(context.Background(), s, env, []string{"-e", "a.txt", "dir", "b.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 3 files failed")
+	assert.Contains(t, env.Stderr.(*bytes.Buffer).String(), "Is a directory")
+
+	// Only the file the editor changed is uploaded again
+	assert.Equal(t, []int64{1}, deleted)
+	assert.Equal(t, map[string]string{"a.txt": "new value\n"}, uploaded)
+	entry, ok := s.Cache.Get("/a.txt")
+	require.True(t, ok)
+	assert.Equal(t, int64(10), entry.ID)
+
+	// A failing editor discards whatever it wrote
+	s.ExternalEditor = true
+	t.Setenv("EDITOR", "sed -i s/nothing/something/ \"$1\"; false")
+	err = cmd.Run(context.Background(), s, env, []string{"b.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exited with status 1")
+	assert.NotContains(t, uploaded, "b.txt")
+}
+
+// ============================================================================
+// ROOT FOLDER TESTS
+// ============================================================================
+
+func TestRootFolder_SentAsNilParent(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 6, Name: "Docs", Type: "folder"}, "/Docs")
+	s.Cache.Add(&api.FileEntry{ID: 7, Name: "b.txt", Type: "text", Hash: "b-hash"}, "/Docs/b.txt")
+	s.Cache.AddChildren("/", []api.FileEntry{{ID: 6, Name: "Docs", Type: "folder"}})
+	mockClient := s.Client.(*api.MockDrimeClient)
+
+	// Root as source: listing it asks for the entries without a parent
+	var listed []*int64
+	mockClient.ListByParentIDWithOptionsFunc = func(ctx context.Context, parentID *int64, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+		listed = append(listed, parentID)
+		return []api.FileEntry{{ID: 6, Name: "Docs", Type: "folder"}}, nil
+	}
+	find, ok := commands.Get("find")
+	require.True(t, ok)
+	require.NoError(t, find.Run(context.Background(), s, env, []string{"/"}))
+	require.NotEmpty(t, listed)
+	assert.Nil(t, listed[0], "the root has no ID on the server")
+
+	// Root as destination
+	var movedTo []*int64
+	mockClient.MoveEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) error {
+		movedTo = append(movedTo, destinationParentID)
+		return nil
+	}
+	mv, ok := commands.Get("mv")
+	require.True(t, ok)
+	require.NoError(t, mv.Run(context.Background(), s, env, []string{"/Docs/b.txt", "/"}))
+	require.Len(t, movedTo, 1)
+	assert.Nil(t, movedTo[0])
+
+	// Root as parent of a new entry, while a real folder's ID is passed on
+	var parents []*int64
+	mockClient.CreateFolderFunc = func(ctx context.Context, name string, parentID *int64, workspaceID int64) (*api.FileEntry, error) {
+		parents = append(parents, parentID)
+		return &api.FileEntry{ID: 20 + int64(len(parents)), Name: name, Type: "folder"}, nil
+	}
+	mkdir, ok := commands.Get("mkdir")
+	require.True(t, ok)
+	require.NoError(t, mkdir.Run(context.Background(), s, env, []string{"/New", "/Docs/Sub"}))
+	require.Len(t, parents, 2)
+	assert.Nil(t, parents[0])
+	require.NotNil(t, parents[1])
+	assert.Equal(t, int64(6), *parents[1])
+}
+
+func TestUndo_ReversesMvCpAndRm(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	archiveID := int64(200)
+	root := []api.FileEntry{
+		{ID: 101, Name: "report.txt", Type: "text"},
+		{ID: 102, Name: "notes.txt", Type: "text"},
+		{ID: 103, Name: "scratch.txt", Type: "text"},
+		{ID: archiveID, Name: "Archive", Type: "folder"},
+	}
+	s.Cache.AddChildren("/", root)
+	s.Cache.AddChildren("/Archive", []api.FileEntry{})
+
+	var moved [][]int64
+	var moveDests []*int64
+	var deleted, restored []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.MoveEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) error {
+		moved = append(moved, entryIDs)
+		moveDests = append(moveDests, destinationParentID)
+		return nil
+	}
+	mockClient.CopyEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) ([]api.FileEntry, error) {
+		return []api.FileEntry{{ID: entryIDs[0] + 1000, Name: "notes.txt", Type: "text", ParentID: destinationParentID}}, nil
+	}
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		deleted = append(deleted, entryIDs...)
+		return nil
+	}
+	mockClient.DeleteEntriesForeverFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		return nil
+	}
+	mockClient.RestoreEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		restored = append(restored, entryIDs...)
+		return nil
+	}
+	mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+		if parentID == nil {
+			return root, nil
+		}
+		return []api.FileEntry{}, nil
+	}
+
+	run := func(name string, args ...string) error {
+		cmd, ok := commands.Get(name)
+		require.True(t, ok)
+		return cmd.Run(context.Background(), s, env, args)
+	}
+	require.NoError(t, run("mv", "report.txt", "/Archive/"))
+	require.NoError(t, run("cp", "notes.txt", "/Archive/"))
+	require.NoError(t, run("rm", "notes.txt"))
+	// Permanent deletes can't be undone, so they are not logged
+	require.NoError(t, run("rm", "-F", "scratch.txt"))
+
+	stdout.Reset()
+	require.NoError(t, run("undo", "--list"))
+	assert.Equal(t, "  1  rm: trashed /notes.txt\n  2  cp: copied to /Archive/notes.txt\n  3  mv /report.txt -> /Archive/report.txt\n", stdout.String())
+
+	require.NoError(t, run("undo"))
+	assert.Equal(t, []int64{102}, restored)
+	_, ok := s.Cache.Get("/notes.txt")
+	assert.True(t, ok, "restored entry should be listed again")
+
+	require.NoError(t, run("undo"))
+	assert.Equal(t, []int64{102, 1102}, deleted)
+	_, ok = s.Cache.Get("/Archive/notes.txt")
+	assert.False(t, ok)
+
+	require.NoError(t, run("undo"))
+	require.Len(t, moved, 2)
+	assert.Equal(t, []int64{101}, moved[1])
+	assert.Nil(t, moveDests[1], "report.txt should go back to the root")
+	_, ok = s.Cache.Get("/report.txt")
+	assert.True(t, ok)
+	_, ok = s.Cache.Get("/Archive/report.txt")
+	assert.False(t, ok)
+
+	assert.ErrorContains(t, run("undo"), "nothing to undo")
+}
+
+func TestUndo_KeepsWhatWasNotReversed(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	archiveID := int64(200)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 101, Name: "report.txt", Type: "text"},
+		{ID: archiveID, Name: "Archive", Type: "folder"},
+	})
+	s.Cache.AddChildren("/Archive", []api.FileEntry{})
+
+	var moves int
+	renameErr := errors.New("rename refused")
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.MoveEntriesFunc = func(ctx context.Context, entryIDs []int64, destinationParentID *int64, workspaceID int64, destinationWorkspaceID *int64) error {
+		moves++
+		return nil
+	}
+	mockClient.RenameEntryFunc = func(ctx context.Context, entryID int64, newName string, workspaceID int64) (*api.FileEntry, error) {
+		if renameErr != nil && newName == "report.txt" {
+			return nil, renameErr
+		}
+		return &api.FileEntry{ID: entryID, Name: newName, Type: "text"}, nil
+	}
+
+	run := func(name string, args ...string) error {
+		cmd, ok := commands.Get(name)
+		require.True(t, ok)
+		return cmd.Run(context.Background(), s, env, args)
+	}
+	require.NoError(t, run("mv", "report.txt", "/Archive/final.txt"))
+	require.Equal(t, 1, moves)
+
+	// The move back worked, the rename didn't: only the rename is left
+	err := run("undo")
+	require.ErrorContains(t, err, "only partly undone")
+	assert.Equal(t, 2, moves)
+	stdout.Reset()
+	require.NoError(t, run("undo", "--list"))
+	assert.Equal(t, "  1  mv /report.txt -> /final.txt\n", stdout.String())
+
+	renameErr = nil
+	require.NoError(t, run("undo"))
+	assert.Equal(t, 2, moves, "the entry is not moved twice")
+	_, ok := s.Cache.Get("/report.txt")
+	assert.True(t, ok)
+	assert.ErrorContains(t, run("undo"), "nothing to undo")
+}
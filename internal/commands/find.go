@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/session"
@@ -26,6 +27,21 @@ Flags:
   -S, --starred     Only show starred files.
   --trash           Show items in trash.
   --shared          Show files shared by me.
+  --since <date>    Modified at or after date (YYYY-MM-DD, "today", ...).
+  --until <date>    Modified before date.
+  --newer-than <age>
+                    Modified within age (30m, 12h, 7d, 2w).
+  --category <list> Files of these comma-separated categories (image, video,
+                    audio, document, archive, code) or MIME types
+                    (image/png, image/*); by extension when the MIME type is
+                    unknown or generic.
+  --no-cache        Re-list the folders leading to path instead of trusting
+                    the cache (for folders created elsewhere).
+  -r, --recursive   Walk every folder below path (default: the current
+                    folder) and print full paths; works with -name, -type,
+                    --category and the date flags.
+  --deadline <d>    With -r, stop after d (e.g. 30s) with partial results.
+  --max-entries <n> With -r, stop after n entries with partial results.
 
 Examples:
   find -name "vacation"           Find files containing 'vacation'
@@ -33,9 +49,12 @@ Examples:
   find /Photos -type d            Find folders in /Photos (direct children only)
   find -S -name "important"       Find starred files containing 'important'
   find --shared                   Find all files I've shared
+  find -type f --newer-than 1d    Find files changed in the last day
+  find -r /Photos -name ".raw"    Find .raw files anywhere below /Photos
+  find -r --category document     Find documents however they are named
 
 Note: When a path is specified, only direct children of that folder are searched.
-      For recursive search, omit the path to search the entire workspace.`,
+      For recursive search, use -r or omit the path to search the entire workspace.`,
 		Run: find,
 	})
 }
@@ -46,13 +65,43 @@ func find(ctx context.Context, s *session.Session, env *ExecutionEnv, args []str
 
 	namePattern := fs.String("name", "", "File name pattern (substring match)")
 	fileType := fs.String("type", "", "File type (f=file, d=directory)")
+	category := fs.String("category", "", "Only files of these categories or MIME types")
 	starred := fs.BoolP("starred", "S", false, "Only show starred files")
 	trash := fs.Bool("trash", false, "Show items in trash")
 	shared := fs.Bool("shared", false, "Show files shared by me")
+	noCache := fs.Bool("no-cache", false, "Resolve path from the API instead of the cache")
+	recursive := fs.BoolP("recursive", "r", false, "Walk every folder below path")
+	window := addTimeWindowFlags(fs)
+	budgetFlags := addWalkBudgetFlags(fs)
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	modified, err := window.parse()
+	if err != nil {
+		return fmt.Errorf("find: %w", err)
+	}
+	budget, err := budgetFlags.parse()
+	if err != nil {
+		return fmt.Errorf("find: %w", err)
+	}
+	types, err := parseTypeFilter(*category)
+	if err != nil {
+		return fmt.Errorf("find: --category: %w", err)
+	}
+	if budget != (walkBudget{}) && !*recursive {
+		return fmt.Errorf("find: --deadline and --max-entries need -r")
+	}
+	if *recursive {
+		if *starred || *trash || *shared {
+			return fmt.Errorf("find: -r can't be combined with -S, --trash or --shared")
+		}
+		root := "."
+		if fs.NArg() > 0 {
+			root = fs.Arg(0)
+		}
+		return findRecursive(ctx, s, env, root, *namePattern, *fileType, modified, types, budget, *noCache)
+	}
 
 	// Check for path argument
 	var parentID *int64
@@ -62,6 +111,11 @@ func find(ctx context.Context, s *session.Session, env *ExecutionEnv, args []str
 		if err != nil {
 			return fmt.Errorf("find: %w", err)
 		}
+		if *noCache {
+			if err := refreshPath(ctx, s, resolvedPath); err != nil {
+				return fmt.Errorf("find: %w", err)
+			}
+		}
 		entry, ok := s.Cache.Get(resolvedPath)
 		if !ok {
 			return fmt.Errorf("find: %s: No such file or directory", searchPath)
@@ -69,7 +123,7 @@ func find(ctx context.Context, s *session.Session, env *ExecutionEnv, args []str
 		if entry.Type != "folder" {
 			return fmt.Errorf("find: %s: Not a directory", searchPath)
 		}
-		parentID = &entry.ID
+		parentID = parentIDPtr(entry)
 	}
 
 	// Build search options
@@ -111,6 +165,8 @@ func find(ctx context.Context, s *session.Session, env *ExecutionEnv, args []str
 		})
 	}
 
+	filters = append(filters, modified.filters()...)
+
 	// Encode filters if any
 	if len(filters) > 0 {
 		opts.Filters = api.EncodeFilters(filters)
@@ -118,8 +174,6 @@ func find(ctx context.Context, s *session.Session, env *ExecutionEnv, args []str
 
 	// Perform search
 	var results []api.FileEntry
-	var err error
-
 	results, err = ui.WithSpinner(env.Stdout, "", false, func() ([]api.FileEntry, error) {
 		if parentID != nil {
 			// Search within specific folder (direct children only)
@@ -132,6 +186,9 @@ func find(ctx context.Context, s *session.Session, env *ExecutionEnv, args []str
 		return fmt.Errorf("find: %w", err)
 	}
 
+	results = modified.filter(results)
+	results = types.filter(results)
+
 	// Client-side filtering for -type f (exclude folders)
 	if *fileType == "f" {
 		filtered := make([]api.FileEntry, 0, len(results))
@@ -165,3 +222,42 @@ func find(ctx context.Context, s *session.Session, env *ExecutionEnv, args []str
 
 	return nil
 }
+
+// findRecursive walks the tree below root and prints the path of every
+// entry whose name contains pattern, as it is found.
+func findRecursive(ctx context.Context, s *session.Session, env *ExecutionEnv, root, pattern, fileType string, modified timeWindow, types typeFilter, budget walkBudget, noCache bool) error {
+	resolved, err := s.ResolvePathArg(root)
+	if err != nil {
+		return fmt.Errorf("find: %w", err)
+	}
+	if noCache {
+		if err := refreshPath(ctx, s, resolved); err != nil {
+			return fmt.Errorf("find: %w", err)
+		}
+		invalidateSubtree(s, resolved)
+	}
+	entry, ok := s.Cache.Get(resolved)
+	if !ok {
+		return fmt.Errorf("find: %s: No such file or directory", root)
+	}
+	if entry.Type != "folder" {
+		return fmt.Errorf("find: %s: Not a directory", root)
+	}
+
+	pattern = strings.ToLower(pattern)
+	err = walkCachedTree(ctx, s, resolved, budget, func(p string, e *api.FileEntry) {
+		switch {
+		case fileType == "f" && e.Type == "folder",
+			fileType == "d" && e.Type != "folder",
+			!strings.Contains(strings.ToLower(e.Name), pattern),
+			modified.active() && !modified.contains(e.UpdatedAt),
+			types.active() && !types.matches(e):
+			return
+		}
+		fmt.Fprintln(env.Stdout, p)
+	})
+	if err != nil && !partialWalk(env, "find", err) {
+		return fmt.Errorf("find: %w", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"path/filepath"
@@ -14,14 +15,16 @@ import (
 func init() {
 	Register(&Command{
 		Name:        "mkdir",
+		Mutates:     alwaysMutates,
 		Description: "Create a directory",
 		Usage:       "mkdir [-p] <path>...\\n\\nOptions:\\n  -p    Create parent directories as needed\\n\\nExamples:\\n  mkdir photos          Create a directory\\n  mkdir -p a/b/c        Create nested directories",
 		Run:         mkdir,
 	})
 	Register(&Command{
 		Name:        "rm",
+		Mutates:     alwaysMutates,
 		Description: "Remove files or directories (moves to trash by default)",
-		Usage:       "rm [-rf] [--forever|-F] <path>...\n\nOptions:\n  -r, -R        Remove directories recursively\n  -f            Force removal without prompting\n  --forever, -F Permanently delete (bypass trash)\n\nBy default, rm moves files to trash. Use --forever to permanently delete.\nUse 'trash' command to view and restore trashed items.\n\nExamples:\n  rm file.txt           Move file to trash\n  rm -rf folder/        Move folder to trash\n  rm -F file.txt        Permanently delete file\n  rm *.tmp              Move matching files to trash",
+		Usage:       "rm [-rf] [--forever|-F] <path>...\n       rm [-rf] [--only-show-errors] --from-file <list>\n\nOptions:\n  -r, -R        Remove directories recursively\n  -f            Force removal without prompting\n  --forever, -F Permanently delete (bypass trash)\n  --from-file   Read paths to remove from a local file, one per line ('-' for stdin)\n  --only-show-errors  Print only the paths that failed, then the summary as the\n                last line; no trash hint\n\nBy default, rm moves files to trash. Use --forever to permanently delete.\nUse 'trash' command to view and restore trashed items.\nFolders with more than rm_confirm_entries entries (default 100) ask for\nconfirmation unless -f is given; with '--from-file -' there is no stdin left\nto answer, so such folders need -f. If the server rejects part of a batch, each\npath is re-checked; only the ones still in place are reported and kept in\nthe cache.\n\nExamples:\n  rm file.txt           Move file to trash\n  rm -rf folder/        Move folder to trash\n  rm -F file.txt        Permanently delete file\n  rm *.tmp              Move matching files to trash\n  rm --from-file old.txt  Remove every path listed in old.txt",
 		Run:         rm,
 	})
 }
@@ -115,10 +118,8 @@ func mkdirOne(ctx context.Context, s *session.Session, env *ExecutionEnv, path s
 		for _, name := range toCreate {
 			// Get parent ID
 			var parentID *int64
-			if currentPath != "/" {
-				if parentEntry, ok := s.Cache.Get(currentPath); ok {
-					parentID = &parentEntry.ID
-				}
+			if parentEntry, ok := s.Cache.Get(currentPath); ok {
+				parentID = parentIDPtr(parentEntry)
 			}
 
 			var newEntry *api.FileEntry
@@ -159,10 +160,23 @@ func rm(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 	recursive := false
 	force := false
 	forever := false // Permanently delete (bypass trash)
+	fromFile := ""
+	onlyErrors := false
 	var patterns []string
 
-	for _, arg := range args {
-		if arg == "-r" || arg == "-R" {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--from-file" {
+			if i+1 >= len(args) {
+				return fmt.Errorf("rm: --from-file requires a file argument")
+			}
+			i++
+			fromFile = args[i]
+		} else if strings.HasPrefix(arg, "--from-file=") {
+			fromFile = strings.TrimPrefix(arg, "--from-file=")
+		} else if arg == "--only-show-errors" {
+			onlyErrors = true
+		} else if arg == "-r" || arg == "-R" {
 			recursive = true
 		} else if arg == "-f" {
 			force = true
@@ -195,17 +209,24 @@ func rm(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 		}
 	}
 
+	// Entries moved to the trash are logged for undo
+	s.BeginUndo("rm")
+	defer s.EndUndo()
+
+	if fromFile != "" {
+		return rmFromFile(ctx, s, env, fromFile, recursive, force, forever, onlyErrors)
+	}
+
 	if len(patterns) < 1 {
 		return fmt.Errorf("usage: rm [-rf] <path>")
 	}
 
 	deletedCount := 0
 	movedToTrash := false
+	var ids []int64
+	var resolvedPaths []string
 
 	err := ui.WithSpinnerErr(env.Stderr, "", false, func() error {
-		var ids []int64
-		var resolvedPaths []string
-
 		for _, pattern := range patterns {
 			// Check if pattern contains glob characters
 			if strings.ContainsAny(pattern, "*?[") {
@@ -221,10 +242,7 @@ func rm(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 				if !s.Cache.HasChildren(parentDir) {
 					// Need to load children first
 					if parentEntry, ok := s.Cache.Get(parentDir); ok {
-						var parentID *int64
-						if parentEntry.ID != 0 {
-							parentID = &parentEntry.ID
-						}
+						parentID := parentIDPtr(parentEntry)
 						apiOpts := api.ListOptions(s.WorkspaceID)
 						children, err := s.Client.ListByParentIDWithOptions(ctx, parentID, apiOpts)
 						if err != nil {
@@ -277,45 +295,279 @@ func rm(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 			ids = append(ids, entry.ID)
 			resolvedPaths = append(resolvedPaths, resolved)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-		if len(ids) == 0 {
-			return nil // Nothing to delete (all were non-existent with -f)
+	if len(ids) == 0 {
+		return nil // Nothing to delete (all were non-existent with -f)
+	}
+
+	// Large folders need confirmation unless -f was given
+	if !force {
+		if ok, _ := confirmLargeFolderRemoval(ctx, s, env, bufio.NewReader(env.Stdin), resolvedPaths, false); !ok {
+			fmt.Fprintln(env.Stderr, "rm: cancelled")
+			return nil
 		}
+	}
 
-		if s.InVault {
-			// Vault always deletes permanently (no trash)
-			if err := s.Client.DeleteVaultEntries(ctx, ids); err != nil {
-				return err
-			}
-			forever = true // Mark as permanent for message display
-		} else if forever {
-			// Permanently delete (bypass trash)
-			if err := s.Client.DeleteEntriesForever(ctx, ids, s.WorkspaceID); err != nil {
-				return err
+	var failed []deleteFailure
+	err = ui.WithSpinnerErr(env.Stderr, "", false, func() error {
+		var err error
+		movedToTrash, failed, err = deleteAndPurge(ctx, s, ids, resolvedPaths, forever)
+		deletedCount = len(ids) - len(failed)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		for _, f := range failed {
+			fmt.Fprintf(env.Stderr, "rm: cannot remove '%s': %v\n", f.path, f.err)
+		}
+		return fmt.Errorf("rm: %d of %d paths could not be removed", len(failed), len(ids))
+	}
+
+	// Unix rm is silent on success, but we'll give a hint about trash
+	if movedToTrash && deletedCount == 1 && !onlyErrors {
+		fmt.Fprintln(env.Stderr, ui.MutedStyle.Render("(Moved to trash. Use 'rm -F' to delete permanently)"))
+	}
+	return nil
+}
+
+// deleteEntryIDs removes entries in a single API call: permanently in the vault
+// or with forever set, otherwise by moving them to trash.
+// Returns true when the entries were moved to trash.
+func deleteEntryIDs(ctx context.Context, s *session.Session, ids []int64, forever bool) (bool, error) {
+	if s.InVault {
+		// Vault always deletes permanently (no trash)
+		return false, s.Client.DeleteVaultEntries(ctx, ids)
+	}
+	if forever {
+		// Permanently delete (bypass trash)
+		return false, s.Client.DeleteEntriesForever(ctx, ids, s.WorkspaceID)
+	}
+	// Move to trash (default)
+	return true, s.Client.DeleteEntries(ctx, ids, s.WorkspaceID)
+}
+
+// deleteFailure is a path a batch delete did not remove.
+type deleteFailure struct {
+	path string
+	err  error
+}
+
+// deleteAndPurge deletes ids (found at paths, in the same order) in one
+// batch and drops the removed ones from the cache. When the batch fails the
+// server may still have removed part of it, so each entry is re-checked and
+// only those still in place are returned as failures. The error is only
+// non-nil when the outcome can't be established at all.
+func deleteAndPurge(ctx context.Context, s *session.Session, ids []int64, paths []string, forever bool) (bool, []deleteFailure, error) {
+	trashed, batchErr := deleteEntryIDs(ctx, s, ids, forever)
+	if batchErr == nil {
+		for i, p := range paths {
+			s.Cache.Remove(p)
+			if trashed {
+				s.RecordTrash(ids[i], p)
 			}
-		} else {
-			// Move to trash (default)
-			if err := s.Client.DeleteEntries(ctx, ids, s.WorkspaceID); err != nil {
-				return err
+		}
+		return trashed, nil, nil
+	}
+	if ctx.Err() != nil {
+		return false, nil, batchErr
+	}
+
+	var failed []deleteFailure
+	for i, id := range ids {
+		removed, err := entryRemoved(ctx, s, id, paths[i], trashed)
+		switch {
+		case err != nil:
+			failed = append(failed, deleteFailure{paths[i], fmt.Errorf("%v (and could not check its state: %v)", batchErr, err)})
+		case removed:
+			s.Cache.Remove(paths[i])
+			if trashed {
+				s.RecordTrash(id, paths[i])
 			}
-			movedToTrash = true
+		default:
+			failed = append(failed, deleteFailure{paths[i], batchErr})
 		}
+	}
+	return trashed, failed, nil
+}
 
-		// Remove from cache
-		for _, resolved := range resolvedPaths {
-			s.Cache.Remove(resolved)
+// entryRemoved asks the server whether the entry at p is gone (or, when
+// trashed is set, in the trash). The vault has no per-entry lookup, so its
+// parent folder is re-listed instead.
+func entryRemoved(ctx context.Context, s *session.Session, id int64, p string, trashed bool) (bool, error) {
+	if s.InVault {
+		folderHash := ""
+		if parent := filepath.Dir(p); parent != "/" {
+			parentEntry, ok := s.Cache.Get(parent)
+			if !ok {
+				return false, fmt.Errorf("%s: not in cache", parent)
+			}
+			folderHash = parentEntry.Hash
+		}
+		children, err := s.Client.ListVaultEntries(ctx, folderHash)
+		if err != nil {
+			return false, err
+		}
+		for _, c := range children {
+			if c.ID == id {
+				return false, nil
+			}
 		}
+		return true, nil
+	}
 
-		deletedCount = len(ids)
-		return nil
-	})
+	entry, err := s.Client.GetEntry(ctx, id, s.WorkspaceID)
+	if api.IsNotFound(err) {
+		return true, nil
+	}
 	if err != nil {
-		return err
+		return false, err
 	}
+	return entry == nil || (trashed && entry.IsInTrash()), nil
+}
 
-	// Unix rm is silent on success, but we'll give a hint about trash
-	if movedToTrash && deletedCount == 1 {
-		fmt.Fprintln(env.Stderr, ui.MutedStyle.Render("(Moved to trash. Use 'rm -F' to delete permanently)"))
+// rmFromFile removes every path listed in listPath with one batched delete.
+// Paths that cannot be resolved are reported in a summary instead of
+// aborting the whole batch.
+func rmFromFile(ctx context.Context, s *session.Session, env *ExecutionEnv, listPath string, recursive, force, forever, onlyErrors bool) error {
+	paths, err := readPathList(env, listPath)
+	if err != nil {
+		return fmt.Errorf("rm: %w", err)
+	}
+
+	var ids []int64
+	var resolvedPaths []string
+	var failures []string
+	seen := make(map[int64]bool)
+
+	for _, p := range paths {
+		entry, err := ResolveEntry(ctx, s, p)
+		if err != nil {
+			if !force {
+				failures = append(failures, err.Error())
+			}
+			continue
+		}
+		if entry.Type == "folder" && !recursive {
+			failures = append(failures, fmt.Sprintf("%s: Is a directory", p))
+			continue
+		}
+		if seen[entry.ID] {
+			continue
+		}
+		seen[entry.ID] = true
+		ids = append(ids, entry.ID)
+		resolvedPaths = append(resolvedPaths, s.ResolvePath(p))
+	}
+
+	removed := len(ids)
+	if len(ids) > 0 {
+		if !force {
+			// A list read from stdin has used up the answers too
+			ok, err := confirmLargeFolderRemoval(ctx, s, env, bufio.NewReader(env.Stdin), resolvedPaths, listPath == "-")
+			if err != nil {
+				return fmt.Errorf("rm: %w", err)
+			}
+			if !ok {
+				fmt.Fprintln(env.Stderr, "rm: cancelled")
+				return nil
+			}
+		}
+
+		var failed []deleteFailure
+		err := ui.WithSpinnerErr(env.Stderr, "", false, func() error {
+			var err error
+			_, failed, err = deleteAndPurge(ctx, s, ids, resolvedPaths, forever)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("rm: %w", err)
+		}
+		for _, f := range failed {
+			failures = append(failures, fmt.Sprintf("%s: %v", f.path, f.err))
+		}
+		removed -= len(failed)
+	}
+
+	if !onlyErrors {
+		fmt.Fprintf(env.Stderr, "Removed %d of %d paths\n", removed, len(paths))
+	}
+	for _, f := range failures {
+		fmt.Fprintf(env.Stderr, "  %s %s\n", ui.ErrorStyle.Render("✗"), f)
+	}
+	if onlyErrors {
+		// Last, so it is what's left on screen after a long list
+		fmt.Fprintf(env.Stderr, "Removed %d of %d paths\n", removed, len(paths))
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("rm: %d of %d paths failed", len(failures), len(paths))
 	}
 	return nil
 }
+
+// confirmLargeFolderRemoval asks before removing folders holding more entries
+// than the session's rm threshold (rm_confirm_entries), reading one answer
+// per folder from answers, which the whole rm call shares so that a buffered
+// read for one folder doesn't swallow the next folder's answer. Returns true
+// when removal may proceed. With stdinUsed, stdin has no answers left to
+// read, so a folder that needs confirmation is an error instead.
+func confirmLargeFolderRemoval(ctx context.Context, s *session.Session, env *ExecutionEnv, answers *bufio.Reader, paths []string, stdinUsed bool) (bool, error) {
+	threshold := s.RmConfirmThreshold()
+	for _, p := range paths {
+		entry, ok := s.Cache.Get(p)
+		if !ok || entry.Type != "folder" {
+			continue
+		}
+		count, size := subtreeStats(s.Cache, p)
+		// Folders never listed have next to nothing cached, so ask the
+		// server; the vault has no count endpoint
+		if !s.InVault && count <= threshold {
+			if n, err := s.Client.GetFolderCount(ctx, entry.ID, s.WorkspaceID); err == nil && n > count {
+				count = n
+			}
+		}
+		if count <= threshold {
+			continue
+		}
+		if entry.Size > size {
+			size = entry.Size
+		}
+
+		if stdinUsed {
+			return false, fmt.Errorf("'%s' contains %d entries (%s), which needs confirmation, but the path list came from stdin; pass -f to remove it without asking", p, count, formatSize(size))
+		}
+		fmt.Fprintf(env.Stderr, "rm: '%s' contains %d entries (%s). Remove it? [y/N] ", p, count, formatSize(size))
+		response, _ := answers.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(response)) != "y" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// subtreeStats counts the cached entries below path and sums their file sizes.
+func subtreeStats(cache *api.FileCache, path string) (int, int64) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	count := 0
+	var size int64
+	for _, p := range cache.AllPaths() {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		entry, ok := cache.Get(p)
+		if !ok {
+			continue
+		}
+		count++
+		if entry.Type != "folder" {
+			size += entry.Size
+		}
+	}
+	return count, size
+}
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	Register(&Command{
+		Name:        "locate",
+		Description: "Find paths by name using the local index",
+		Usage: `locate [options] <pattern>...
+locate --update
+
+Searches a local index of every path the shell has seen in this workspace,
+without any API calls. Paths containing any of the patterns are printed, one
+per line. The index is kept up to date as you browse and is saved in
+~/.drime-shell/index/ between sessions. Enable it with 'locate_index: true'
+in ~/.drime-shell/config.yaml.
+
+Options:
+  -i, --ignore-case   Match patterns case-insensitively
+  -c, --count         Print the number of matches instead of the paths
+  -l, --limit N       Print at most N matches
+  -u, --update        Re-list every folder of the workspace and save the index
+  -S, --statistics    Print the number of indexed paths
+
+Examples:
+  locate --update            # Index the whole workspace once
+  locate invoice             # Instant lookups afterwards
+  locate -i readme | head`,
+		Run: locate,
+	})
+}
+
+func locate(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("locate", pflag.ContinueOnError)
+	ignoreCase := fs.BoolP("ignore-case", "i", false, "match case-insensitively")
+	count := fs.BoolP("count", "c", false, "print the number of matches")
+	limit := fs.IntP("limit", "l", 0, "print at most N matches")
+	update := fs.BoolP("update", "u", false, "rebuild the index")
+	stats := fs.BoolP("statistics", "S", false, "print index statistics")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+
+	if s.InVault {
+		return fmt.Errorf("locate: not available in vault")
+	}
+	idx := s.Cache.Index()
+	if idx == nil {
+		return fmt.Errorf("locate: the name index is disabled (set 'locate_index: true' in ~/.drime-shell/config.yaml)")
+	}
+
+	if *update {
+		return updateNameIndex(ctx, s, env, idx)
+	}
+	if *stats {
+		fmt.Fprintf(env.Stdout, "%d paths indexed\n", idx.Len())
+		return nil
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: locate [options] <pattern>...")
+	}
+
+	seen := make(map[string]bool)
+	var matches []string
+	for _, pattern := range fs.Args() {
+		for _, p := range idx.Search(pattern, *ignoreCase) {
+			if !seen[p] {
+				seen[p] = true
+				matches = append(matches, p)
+			}
+		}
+	}
+	if *limit > 0 && len(matches) > *limit {
+		matches = matches[:*limit]
+	}
+
+	if *count {
+		fmt.Fprintln(env.Stdout, len(matches))
+		return nil
+	}
+	for _, p := range matches {
+		fmt.Fprintln(env.Stdout, p)
+	}
+	return nil
+}
+
+// updateNameIndex re-lists every folder of the workspace so the index (kept
+// in step by the cache) reflects the server, then saves it.
+func updateNameIndex(ctx context.Context, s *session.Session, env *ExecutionEnv, idx *api.NameIndex) error {
+	for _, p := range s.Cache.AllPaths() {
+		s.Cache.InvalidateChildren(p)
+	}
+
+	_, err := ui.WithSpinner(env.Stderr, "Indexing...", false, func() (struct{}, error) {
+		return struct{}{}, walkCachedTree(ctx, s, "/", walkBudget{}, func(string, *api.FileEntry) {})
+	})
+	if err != nil {
+		return fmt.Errorf("locate: %w", err)
+	}
+	if err := idx.Save(); err != nil {
+		return fmt.Errorf("locate: saving index: %w", err)
+	}
+	fmt.Fprintf(env.Stderr, "Indexed %d paths\n", idx.Len())
+	return nil
+}
@@ -3,9 +3,13 @@ package commands_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/commands"
@@ -242,6 +246,367 @@ func TestLs_LongFormat(t *testing.T) {
 	assert.Contains(t, output, "report.txt")
 }
 
+func TestLs_SizeFormats(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 1, Name: "small.txt", Type: "text", Size: 500},
+		{ID: 2, Name: "medium.txt", Type: "text", Size: 1536},
+		{ID: 3, Name: "large.bin", Type: "file", Size: 5<<20 + 1},
+	})
+	cmd, ok := commands.Get("ls")
+	require.True(t, ok)
+
+	sizes := func(args ...string) []string {
+		stdout.Reset()
+		require.NoError(t, cmd.Run(context.Background(), s, env, append([]string{"-l", "--color=never"}, args...)))
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		var got []string
+		for _, line := range lines[1:] {
+			// Sizes are right-aligned, so every line has them in the same columns
+			got = append(got, line[:strings.Index(lines[1], "  -")])
+		}
+		return got
+	}
+
+	assert.ElementsMatch(t, []string{" 500 B", "1.5 KB", "5.0 MB"}, sizes())
+	assert.ElementsMatch(t, []string{" 500 B", "1.5 KB", "5.0 MB"}, sizes("-h"))
+	assert.ElementsMatch(t, []string{"   1K", "   2K", "5121K"}, sizes("--block-size=K"))
+	assert.ElementsMatch(t, []string{"1", "1", "6"}, sizes("--block-size", "1M"))
+	assert.ElementsMatch(t, []string{"    500", "   1536", "5242881"}, sizes("--bytes"))
+	assert.Contains(t, stdout.String(), "total 5244917")
+
+	assert.Error(t, cmd.Run(context.Background(), s, env, []string{"-l", "--bytes", "-h"}))
+	assert.Error(t, cmd.Run(context.Background(), s, env, []string{"-l", "--block-size=lots"}))
+
+	// -h is an option of ls, so only --help asks for its usage
+	assert.False(t, commands.WantsHelp(cmd, []string{"-l", "-h"}))
+	assert.True(t, commands.WantsHelp(cmd, []string{"--help"}))
+}
+
+func TestLs_ClassifyIndicators(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+
+	docsID := int64(100)
+	s.Cache.Add(&api.FileEntry{ID: docsID, Name: "docs", Type: "folder"}, "/docs")
+	s.Cache.AddChildren("/docs", []api.FileEntry{
+		{ID: 101, Name: "notes.txt", Type: "text", ParentID: &docsID},
+		{ID: 102, Name: "sub", Type: "folder", ParentID: &docsID},
+		{ID: 103, Name: "build.sh", Type: "text", Mime: "application/x-sh", ParentID: &docsID},
+		{ID: 104, Name: "public.pdf", Type: "pdf", Public: true, ParentID: &docsID},
+	})
+	s.CWD = "/docs"
+
+	cmd, ok := commands.Get("ls")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-F", "--color=never"}))
+
+	fields := strings.Fields(stdout.String())
+	assert.ElementsMatch(t, []string{"build.sh*", "notes.txt", "public.pdf@", "sub/"}, fields)
+}
+
+func TestLs_ColorModes(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+
+	docsID := int64(100)
+	s.Cache.Add(&api.FileEntry{ID: docsID, Name: "docs", Type: "folder"}, "/docs")
+	s.Cache.AddChildren("/docs", []api.FileEntry{
+		{ID: 101, Name: "sub", Type: "folder", ParentID: &docsID},
+	})
+	s.CWD = "/docs"
+
+	cmd, ok := commands.Get("ls")
+	require.True(t, ok)
+
+	// Output is not a terminal, so auto and never print plain names
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{}))
+	assert.Equal(t, "sub\n", stdout.String())
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--color=always", "-F"}))
+	assert.Contains(t, stdout.String(), "\x1b[")
+	assert.True(t, strings.HasSuffix(stdout.String(), "/\n"), "indicator stays outside the colored name")
+
+	err := cmd.Run(context.Background(), s, env, []string{"--color=sometimes"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid color mode")
+}
+
+func TestLs_IncludeDeleted(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+
+	docsID := int64(100)
+	deletedAt := time.Now()
+	s.Cache.Add(&api.FileEntry{ID: docsID, Name: "docs", Type: "folder"}, "/docs")
+	s.Cache.AddChildren("/docs", []api.FileEntry{
+		{ID: 101, Name: "live.txt", Type: "text", ParentID: &docsID},
+	})
+	s.CWD = "/docs"
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.ListByParentIDWithOptionsFunc = func(ctx context.Context, parentID *int64, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+		if opts.DeletedOnly && parentID != nil && *parentID == docsID {
+			return []api.FileEntry{{ID: 102, Name: "gone.txt", Type: "text", ParentID: &docsID, DeletedAt: &deletedAt}}, nil
+		}
+		return []api.FileEntry{}, nil
+	}
+
+	cmd, ok := commands.Get("ls")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--color=never"}))
+	assert.Equal(t, "live.txt\n", stdout.String(), "trashed entries are hidden by default")
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--include-deleted", "--color=never", "-l"}))
+	out := stdout.String()
+	assert.Contains(t, out, "live.txt")
+	assert.Contains(t, out, "gone.txt [deleted #102]")
+	_, cached := s.Cache.Get("/docs/gone.txt")
+	assert.False(t, cached, "trashed entries must not enter the cache")
+
+	// A trashed path resolves too
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--include-deleted", "--color=never", "gone.txt"}))
+	assert.Equal(t, "gone.txt [deleted #102]\n", stdout.String())
+}
+
+func TestLs_ShowsIDsAndHashes(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+
+	docsID := int64(100)
+	s.Cache.Add(&api.FileEntry{ID: docsID, Name: "docs", Type: "folder"}, "/docs")
+	s.Cache.AddChildren("/docs", []api.FileEntry{
+		{ID: 7, Name: "a.txt", Type: "text", Hash: "aGFzaDc", ParentID: &docsID},
+		{ID: 1234, Name: "b.txt", Type: "text", ParentID: &docsID},
+	})
+	s.CWD = "/docs"
+
+	cmd, ok := commands.Get("ls")
+	require.True(t, ok)
+
+	// Short format prefixes the name, with IDs right-aligned
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-i", "--color=never"}))
+	assert.Contains(t, stdout.String(), "   7 a.txt")
+	assert.Contains(t, stdout.String(), "1234 b.txt")
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-i", "--hash", "--color=never"}))
+	assert.Contains(t, stdout.String(), "   7 aGFzaDc a.txt")
+	assert.Contains(t, stdout.String(), "1234 -       b.txt")
+
+	// Long format adds leading columns
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-l", "-i", "--hash", "--color=never"}))
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[1], "   7  aGFzaDc  "), "got %q", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "1234  -        "), "got %q", lines[2])
+}
+
+func TestLs_FitsTerminalWidth(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 1, Name: "a", Type: "text"},
+		{ID: 2, Name: "bb", Type: "text"},
+		{ID: 3, Name: "ccc", Type: "text"},
+		{ID: 4, Name: "a-rather-long-report-name-from-last-quarter.pdf", Type: "pdf"},
+	})
+
+	cmd, ok := commands.Get("ls")
+	require.True(t, ok)
+
+	// Columns are only as wide as their longest name
+	t.Setenv("COLUMNS", "80")
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--color=never"}))
+	assert.Equal(t, "a  a-rather-long-report-name-from-last-quarter.pdf  bb  ccc\n", stdout.String())
+
+	// A narrow terminal gets fewer columns
+	stdout.Reset()
+	t.Setenv("COLUMNS", "40")
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--color=never"}))
+	assert.Equal(t, 4, strings.Count(stdout.String(), "\n"), "got %q", stdout.String())
+
+	// Long format shortens names that would overflow, unless --full-names
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-l", "--color=never"}))
+	assert.NotContains(t, stdout.String(), "last-quarter.pdf")
+	assert.Contains(t, stdout.String(), "…")
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-l", "--full-names", "--color=never"}))
+	assert.Contains(t, stdout.String(), "a-rather-long-report-name-from-last-quarter.pdf")
+}
+
+func TestLs_CountShowsFolderSizes(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	docsID, emptyID, bigID := int64(10), int64(20), int64(30)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: bigID, Name: "Big", Type: "folder"},
+		{ID: docsID, Name: "Docs", Type: "folder"},
+		{ID: emptyID, Name: "Empty", Type: "folder"},
+		{ID: 3, Name: "notes.txt", Type: "text"},
+	})
+	s.Cache.AddChildren("/Docs", []api.FileEntry{
+		{ID: 11, Name: "a.txt", Type: "text", ParentID: &docsID},
+		{ID: 12, Name: "b.txt", Type: "text", ParentID: &docsID},
+		{ID: 13, Name: "Old", Type: "folder", ParentID: &docsID},
+	})
+
+	var mu sync.Mutex
+	var counted []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+		t.Errorf("folder %d listed instead of counted", *parentID)
+		return nil, nil
+	}
+	mockClient.GetFolderCountFunc = func(ctx context.Context, folderID int64, workspaceID int64) (int, error) {
+		mu.Lock()
+		counted = append(counted, folderID)
+		mu.Unlock()
+		if folderID == bigID {
+			return 1200, nil
+		}
+		return 0, nil
+	}
+
+	cmd, ok := commands.Get("ls")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-l", "--count", "--color=never"}))
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Contains(t, lines[1], "1200 items")
+	assert.Contains(t, lines[2], "3 items")
+	assert.Contains(t, lines[3], "0 items")
+	assert.NotContains(t, lines[4], "items")
+	assert.ElementsMatch(t, []int64{bigID, emptyID}, counted, "only the uncached folders should be counted")
+
+	// Counts come from the cache the second time
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-l", "--count", "--color=never"}))
+	assert.Contains(t, stdout.String(), "1200 items")
+	assert.Len(t, counted, 2)
+
+	// A failed count fails the listing
+	s.Cache.InvalidateChildren("/Big")
+	mockClient.GetFolderCountFunc = func(ctx context.Context, folderID int64, workspaceID int64) (int, error) {
+		return 0, errors.New("boom")
+	}
+	var stderr bytes.Buffer
+	env.Stderr = &stderr
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-l", "--count", "--color=never"}))
+	assert.Contains(t, stderr.String(), "ls: --count: /Big: boom")
+	assert.Empty(t, stdout.String())
+
+	err := cmd.Run(context.Background(), s, env, []string{"--count"})
+	assert.ErrorContains(t, err, "requires -l")
+}
+
+func TestLs_ModificationWindow(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 1, Name: "old.txt", Type: "text", UpdatedAt: time.Now().Add(-30 * 24 * time.Hour)},
+		{ID: 2, Name: "recent.txt", Type: "text", UpdatedAt: time.Now().Add(-time.Hour)},
+		{ID: 3, Name: "jan.txt", Type: "text", UpdatedAt: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+	})
+
+	cmd, ok := commands.Get("ls")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--newer-than", "7d", "--color=never"}))
+	assert.Equal(t, "recent.txt\n", stdout.String())
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--since", "2024-01-01", "--until", "2024-02-01", "--color=never"}))
+	assert.Equal(t, "jan.txt\n", stdout.String())
+
+	err := cmd.Run(context.Background(), s, env, []string{"--newer-than", "soon"})
+	assert.ErrorContains(t, err, "--newer-than")
+}
+
+func TestLsFind_TypeFilter(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	docsID := int64(10)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: docsID, Name: "docs", Type: "folder"},
+		{ID: 1, Name: "IMG_0001", Type: "image", Mime: "image/jpeg"},
+		{ID: 2, Name: "holiday.MOV", Type: "file"},
+		{ID: 3, Name: "main.go", Type: "text", Mime: "text/plain"},
+		{ID: 4, Name: "scan.png", Type: "image", Mime: "image/png"},
+	})
+	s.Cache.AddChildren("/docs", []api.FileEntry{
+		{ID: 11, Name: "report", Type: "pdf", Mime: "application/pdf", ParentID: &docsID},
+		{ID: 12, Name: "backup.tar.gz", Type: "file", ParentID: &docsID},
+	})
+
+	ls, _ := commands.Get("ls")
+	find, _ := commands.Get("find")
+
+	// MIME types decide, and names without a useful extension still match
+	require.NoError(t, ls.Run(context.Background(), s, env, []string{"--type", "image,video", "--color=never"}))
+	assert.Equal(t, "IMG_0001  holiday.MOV  scan.png\n", stdout.String())
+
+	// text/plain says nothing, so the extension does
+	stdout.Reset()
+	require.NoError(t, ls.Run(context.Background(), s, env, []string{"--type", "code", "--color=never"}))
+	assert.Equal(t, "main.go\n", stdout.String())
+
+	stdout.Reset()
+	require.NoError(t, ls.Run(context.Background(), s, env, []string{"--type", "image/png", "--color=never"}))
+	assert.Equal(t, "scan.png\n", stdout.String())
+
+	stdout.Reset()
+	require.NoError(t, find.Run(context.Background(), s, env, []string{"-r", "/", "--category", "document,archive"}))
+	assert.ElementsMatch(t, []string{"/docs/report", "/docs/backup.tar.gz"}, strings.Fields(stdout.String()))
+
+	err := ls.Run(context.Background(), s, env, []string{"--type", "pictures"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown type 'pictures'")
+}
+
+func TestLs_NoCacheResolvesDeepPathsFromServer(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+
+	aID, bID := int64(10), int64(20)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: aID, Name: "a", Type: "folder"},
+		{ID: 11, Name: "old.txt", Type: "text"},
+	})
+
+	// Another client removed old.txt and created a/b/new.txt
+	calls := 0
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.ListByParentIDWithOptionsFunc = func(ctx context.Context, parentID *int64, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+		calls++
+		switch {
+		case parentID == nil:
+			return []api.FileEntry{{ID: aID, Name: "a", Type: "folder"}}, nil
+		case *parentID == aID:
+			return []api.FileEntry{{ID: bID, Name: "b", Type: "folder", ParentID: &aID}}, nil
+		case *parentID == bID:
+			return []api.FileEntry{{ID: 21, Name: "new.txt", Type: "text", ParentID: &bID}}, nil
+		}
+		return nil, fmt.Errorf("unexpected parent %d", *parentID)
+	}
+
+	cmd, ok := commands.Get("ls")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"a/b"}))
+	assert.Empty(t, stdout.String(), "without --no-cache the path isn't known yet")
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--no-cache", "a/b"}))
+	assert.Equal(t, "new.txt\n", stdout.String())
+	assert.Equal(t, 3, calls, "one listing per level")
+
+	_, ok = s.Cache.Get("/old.txt")
+	assert.False(t, ok, "entries missing from a fresh listing are dropped")
+	_, ok = s.Cache.Get("/a/b/new.txt")
+	assert.True(t, ok, "the cache is updated afterwards")
+}
+
 func TestLs_NonExistentPath(t *testing.T) {
 	s, env, stdout := setupTestEnv(t)
 
@@ -402,6 +767,29 @@ func TestCat_MultipleFiles(t *testing.T) {
 	assert.Contains(t, output, "Content 2")
 }
 
+func TestCat_StdinDashAndNoPager(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 101, Name: "notes.txt", Type: "text", Hash: "hash1"}, "/notes.txt")
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		w.Write([]byte("remote\n"))
+		return nil, nil
+	}
+	env.Stdin = strings.NewReader("piped\n")
+
+	cmd, ok := commands.Get("cat")
+	require.True(t, ok)
+
+	// Output to a buffer is never paged, with or without --no-pager
+	err := cmd.Run(context.Background(), s, env, []string{"--no-pager", "-", "notes.txt"})
+	require.NoError(t, err)
+	output := stdout.String()
+	assert.Contains(t, output, "piped")
+	assert.Contains(t, output, "remote")
+	assert.Less(t, strings.Index(output, "piped"), strings.Index(output, "remote"))
+}
+
 // ============================================================================
 // RM COMMAND TESTS - Testing multi-argument support
 // ============================================================================
@@ -440,6 +828,219 @@ func TestRm_MultipleFiles(t *testing.T) {
 	assert.Contains(t, deletedIDs, int64(103))
 }
 
+func TestRm_LargeFolderRequiresConfirmation(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.RmConfirmEntries = 2
+
+	bigID := int64(100)
+	s.Cache.Add(&api.FileEntry{ID: bigID, Name: "big", Type: "folder"}, "/big")
+	for i := int64(1); i <= 3; i++ {
+		name := fmt.Sprintf("f%d.txt", i)
+		s.Cache.Add(&api.FileEntry{ID: bigID + i, Name: name, Type: "text", Size: 10, ParentID: &bigID}, "/big/"+name)
+	}
+
+	var deletedIDs []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		deletedIDs = append(deletedIDs, entryIDs...)
+		return nil
+	}
+
+	cmd, ok := commands.Get("rm")
+	require.True(t, ok)
+
+	// Declined (empty stdin) - nothing deleted
+	err := cmd.Run(context.Background(), s, env, []string{"-r", "big"})
+	require.NoError(t, err)
+	assert.Empty(t, deletedIDs)
+	assert.Contains(t, env.Stderr.(*bytes.Buffer).String(), "contains 3 entries (30 B)")
+
+	// Confirmed
+	env.Stdin = strings.NewReader("y\n")
+	err = cmd.Run(context.Background(), s, env, []string{"-r", "big"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{bigID}, deletedIDs)
+
+	// Each folder reads its own answer from the same input
+	for _, id := range []int64{bigID, 200} {
+		name := fmt.Sprintf("dir%d", id)
+		s.Cache.Add(&api.FileEntry{ID: id, Name: name, Type: "folder"}, "/"+name)
+		for i := int64(1); i <= 3; i++ {
+			file := fmt.Sprintf("f%d.txt", i)
+			s.Cache.Add(&api.FileEntry{ID: id + i, Name: file, Type: "text", Size: 10, ParentID: &id}, "/"+name+"/"+file)
+		}
+	}
+	deletedIDs = nil
+	env.Stdin = strings.NewReader("y\ny\n")
+	err = cmd.Run(context.Background(), s, env, []string{"-r", "dir100", "dir200"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int64{bigID, 200}, deletedIDs)
+}
+
+func TestRm_CountsUnlistedFoldersOnTheServer(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	// Never listed: nothing below it is cached
+	s.Cache.Add(&api.FileEntry{ID: 100, Name: "archive", Type: "folder"}, "/archive")
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetFolderCountFunc = func(ctx context.Context, folderID int64, workspaceID int64) (int, error) {
+		assert.Equal(t, int64(100), folderID)
+		return 5000, nil
+	}
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		t.Fatal("nothing should be deleted without confirmation")
+		return nil
+	}
+
+	cmd, ok := commands.Get("rm")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-r", "archive"}))
+	assert.Contains(t, env.Stderr.(*bytes.Buffer).String(), "contains 5000 entries")
+	assert.Contains(t, env.Stderr.(*bytes.Buffer).String(), "rm: cancelled")
+}
+
+func TestRm_ForceSkipsLargeFolderConfirmation(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.RmConfirmEntries = 1
+
+	bigID := int64(100)
+	s.Cache.Add(&api.FileEntry{ID: bigID, Name: "big", Type: "folder"}, "/big")
+	s.Cache.Add(&api.FileEntry{ID: 101, Name: "a", Type: "text", ParentID: &bigID}, "/big/a")
+	s.Cache.Add(&api.FileEntry{ID: 102, Name: "b", Type: "text", ParentID: &bigID}, "/big/b")
+
+	var deletedIDs []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		deletedIDs = append(deletedIDs, entryIDs...)
+		return nil
+	}
+
+	cmd, ok := commands.Get("rm")
+	require.True(t, ok)
+
+	err := cmd.Run(context.Background(), s, env, []string{"-rf", "big"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{bigID}, deletedIDs)
+}
+
+func TestRm_FromFileBatchesAndReportsFailures(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	docsID := int64(100)
+	s.Cache.Add(&api.FileEntry{ID: docsID, Name: "docs", Type: "folder"}, "/docs")
+	s.Cache.Add(&api.FileEntry{ID: 101, Name: "a.txt", Type: "text", ParentID: &docsID}, "/docs/a.txt")
+	s.Cache.Add(&api.FileEntry{ID: 102, Name: "b.txt", Type: "text", ParentID: &docsID}, "/docs/b.txt")
+
+	var calls [][]int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		calls = append(calls, entryIDs)
+		return nil
+	}
+
+	env.Stdin = strings.NewReader("/docs/a.txt\n# comment\n\n/docs/missing.txt\n/docs/b.txt\n/docs\n")
+
+	cmd, ok := commands.Get("rm")
+	require.True(t, ok)
+
+	err := cmd.Run(context.Background(), s, env, []string{"--from-file", "-"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 of 4 paths failed")
+
+	// Single batched call with the resolvable files only
+	require.Len(t, calls, 1)
+	assert.Equal(t, []int64{101, 102}, calls[0])
+
+	stderr := env.Stderr.(*bytes.Buffer).String()
+	assert.Contains(t, stderr, "Removed 2 of 4 paths")
+	assert.Contains(t, stderr, "/docs/missing.txt: No such file or directory")
+	assert.Contains(t, stderr, "/docs: Is a directory")
+
+	// --only-show-errors leaves the summary as the last line
+	env.Stderr.(*bytes.Buffer).Reset()
+	env.Stdin = strings.NewReader("/docs/missing.txt\n")
+	err = cmd.Run(context.Background(), s, env, []string{"--only-show-errors", "--from-file", "-"})
+	require.ErrorContains(t, err, "1 of 1 paths failed")
+	stderr = env.Stderr.(*bytes.Buffer).String()
+	assert.True(t, strings.HasSuffix(stderr, "Removed 0 of 1 paths\n"), stderr)
+	assert.Contains(t, stderr, "/docs/missing.txt: No such file or directory")
+}
+
+func TestRm_FromStdinRequiresForceForLargeFolders(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.RmConfirmEntries = 1
+
+	bigID := int64(100)
+	s.Cache.Add(&api.FileEntry{ID: bigID, Name: "big", Type: "folder"}, "/big")
+	s.Cache.Add(&api.FileEntry{ID: 101, Name: "a", Type: "text", ParentID: &bigID}, "/big/a")
+	s.Cache.Add(&api.FileEntry{ID: 102, Name: "b", Type: "text", ParentID: &bigID}, "/big/b")
+
+	var deletedIDs []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		deletedIDs = append(deletedIDs, entryIDs...)
+		return nil
+	}
+
+	cmd, ok := commands.Get("rm")
+	require.True(t, ok)
+
+	// The prompt has nothing left to read once stdin supplied the list
+	env.Stdin = strings.NewReader("/big\n")
+	err := cmd.Run(context.Background(), s, env, []string{"-r", "--from-file", "-"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "came from stdin; pass -f")
+	assert.Empty(t, deletedIDs)
+
+	env.Stdin = strings.NewReader("/big\n")
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-rf", "--from-file", "-"}))
+	assert.Equal(t, []int64{bigID}, deletedIDs)
+}
+
+func TestRm_PartialBatchFailurePurgesOnlyRemoved(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	docsID := int64(100)
+	s.Cache.Add(&api.FileEntry{ID: docsID, Name: "docs", Type: "folder"}, "/docs")
+	s.Cache.Add(&api.FileEntry{ID: 101, Name: "a.txt", Type: "text", ParentID: &docsID}, "/docs/a.txt")
+	s.Cache.Add(&api.FileEntry{ID: 102, Name: "b.txt", Type: "text", ParentID: &docsID}, "/docs/b.txt")
+	s.Cache.Add(&api.FileEntry{ID: 103, Name: "c.txt", Type: "text", ParentID: &docsID}, "/docs/c.txt")
+
+	// The server trashes a.txt, removes b.txt, keeps c.txt, and reports an error
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		return &api.APIError{StatusCode: 422, Message: "c.txt is locked"}
+	}
+	now := time.Now()
+	mockClient.GetEntryFunc = func(ctx context.Context, entryID int64, workspaceID int64) (*api.FileEntry, error) {
+		switch entryID {
+		case 101:
+			return &api.FileEntry{ID: 101, Name: "a.txt", DeletedAt: &now}, nil
+		case 102:
+			return nil, &api.APIError{StatusCode: 404, Message: "not found"}
+		}
+		return &api.FileEntry{ID: entryID, Name: "c.txt"}, nil
+	}
+
+	cmd, ok := commands.Get("rm")
+	require.True(t, ok)
+
+	err := cmd.Run(context.Background(), s, env, []string{"/docs/a.txt", "/docs/b.txt", "/docs/c.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 3 paths could not be removed")
+
+	_, ok = s.Cache.Get("/docs/a.txt")
+	assert.False(t, ok)
+	_, ok = s.Cache.Get("/docs/b.txt")
+	assert.False(t, ok)
+	_, ok = s.Cache.Get("/docs/c.txt")
+	assert.True(t, ok, "the entry the server kept stays cached")
+
+	stderr := env.Stderr.(*bytes.Buffer).String()
+	assert.Contains(t, stderr, "cannot remove '/docs/c.txt': c.txt is locked")
+	assert.NotContains(t, stderr, "a.txt")
+}
+
 // ============================================================================
 // CP COMMAND TESTS - Brace expansion use case
 // ============================================================================
@@ -608,3 +1209,143 @@ func TestGlobExpansionScenario_BraceExpansion(t *testing.T) {
 	assert.Contains(t, copiedIDs, int64(101))
 	assert.Contains(t, copiedIDs, int64(201))
 }
+
+// ============================================================================
+// CD COMMAND TESTS
+// ============================================================================
+
+func TestCd_SuggestsClosestDirectory(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	s.Cache.Add(&api.FileEntry{ID: 100, Name: "Documents", Type: "folder"}, "/Documents")
+	s.Cache.Add(&api.FileEntry{ID: 101, Name: "Downloads", Type: "folder"}, "/Downloads")
+	s.Cache.Add(&api.FileEntry{ID: 102, Name: "Documentz.txt", Type: "text"}, "/Documentz.txt")
+
+	cmd, ok := commands.Get("cd")
+	require.True(t, ok)
+
+	err := cmd.Run(context.Background(), s, env, []string{"/Documets"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "did you mean 'Documents'?")
+	assert.Equal(t, "/", s.CWD)
+
+	err = cmd.Run(context.Background(), s, env, []string{"Music"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "did you mean")
+}
+
+func TestCd_ClampsAboveRoot(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 100, Name: "docs", Type: "folder"}, "/docs")
+	s.CWD = "/docs"
+
+	cmd, ok := commands.Get("cd")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"../../.."}))
+	assert.Equal(t, "/", s.CWD)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"../../docs"}))
+	assert.Equal(t, "/docs", s.CWD)
+}
+
+func TestCdf_ChangesToContainingFolder(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 100, Name: "work", Type: "folder"}, "/work")
+	s.Cache.Add(&api.FileEntry{ID: 101, Name: "2023", Type: "folder"}, "/work/2023")
+	s.Cache.Add(&api.FileEntry{ID: 102, Name: "2024", Type: "folder"}, "/work/2024")
+
+	var results []api.FileEntry
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.SearchWithOptionsFunc = func(ctx context.Context, query string, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+		return results, nil
+	}
+	parent := func(id int64) *int64 { return &id }
+
+	cmd, ok := commands.Get("cdf")
+	require.True(t, ok)
+
+	// An exact name wins over names merely containing it
+	results = []api.FileEntry{
+		{ID: 200, Name: "report.pdf.bak", Type: "file", ParentID: parent(101)},
+		{ID: 201, Name: "Report.PDF", Type: "pdf", ParentID: parent(102)},
+		{ID: 202, Name: "report.pdf", Type: "folder", ParentID: parent(100)},
+	}
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"report.pdf"}))
+	assert.Equal(t, "/work/2024", s.CWD)
+
+	// Several matches are listed; without a terminal one is picked by number
+	results = []api.FileEntry{
+		{ID: 203, Name: "notes.md", Type: "text", ParentID: parent(102)},
+		{ID: 204, Name: "notes.md", Type: "text", ParentID: parent(101)},
+	}
+	err := cmd.Run(context.Background(), s, env, []string{"notes.md"})
+	require.ErrorContains(t, err, "run 'cdf notes.md <n>'")
+	assert.Contains(t, stdout.String(), "  1  /work/2023/notes.md\n  2  /work/2024/notes.md\n")
+	assert.Equal(t, "/work/2024", s.CWD)
+
+	cd, ok := commands.Get("cd")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"notes.md", "1"}))
+	assert.Equal(t, "/work/2023", s.CWD)
+	results = results[:1]
+	require.NoError(t, cd.Run(context.Background(), s, env, []string{"@notes.md"}))
+	assert.Equal(t, "/work/2024", s.CWD)
+
+	results = nil
+	require.ErrorContains(t, cmd.Run(context.Background(), s, env, []string{"missing.txt"}), "no such file")
+}
+
+func TestRealpath_ResolvesRelativePaths(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.CWD = "/docs/reports"
+
+	cmd, ok := commands.Get("realpath")
+	require.True(t, ok)
+
+	s.Cache.Add(&api.FileEntry{ID: 1, Name: "notes.txt", Type: "text"}, "/docs/notes.txt")
+	s.Cache.Add(&api.FileEntry{ID: 2, Name: "q1", Type: "folder"}, "/docs/reports/q1")
+
+	err := cmd.Run(context.Background(), s, env, []string{"../notes.txt", "./q1"})
+	require.NoError(t, err)
+	assert.Equal(t, "/docs/notes.txt\n/docs/reports/q1\n", stdout.String())
+}
+
+func TestRealpath_MissingPaths(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.CWD = "/docs"
+	s.Cache.Add(&api.FileEntry{ID: 1, Name: "notes.txt", Type: "text"}, "/docs/notes.txt")
+
+	cmd, ok := commands.Get("realpath")
+	require.True(t, ok)
+
+	err := cmd.Run(context.Background(), s, env, []string{"notes.txt", "nope/../ghost"})
+	require.Error(t, err)
+	assert.Equal(t, "/docs/notes.txt\n", stdout.String())
+	assert.Contains(t, env.Stderr.(*bytes.Buffer).String(), "ghost: No such file or directory")
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-m", "nope/../ghost"}))
+	assert.Equal(t, "/docs/ghost\n", stdout.String())
+}
+
+func TestPwd_PhysicalAsksServer(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 5, Name: "old-name", Type: "folder", Hash: "h5"}, "/old-name")
+	s.CWD = "/old-name"
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetFolderPathFunc = func(ctx context.Context, folderHash string, workspaceID int64) ([]api.FileEntry, error) {
+		return []api.FileEntry{{ID: 9, Name: "projects"}, {ID: 5, Name: "new-name"}}, nil
+	}
+
+	cmd, ok := commands.Get("pwd")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, nil))
+	assert.Equal(t, "/old-name\n", stdout.String())
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-P"}))
+	assert.Equal(t, "/projects/new-name\n", stdout.String())
+}
@@ -3,40 +3,318 @@ package commands
 import (
 	"archive/zip"
 	"bytes"
+	"container/heap"
 	"context"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/session"
 	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/spf13/pflag"
 )
 
 func init() {
 	Register(&Command{
 		Name:        "du",
 		Description: "Show usage statistics",
-		Usage:       "du\\n\\nDisplays disk usage: used space, available space, and percentage.",
+		Usage:       "du [--include-vault]\\ndu [--top N | --count] [--threshold SIZE] [--no-cache] [--deadline D] [--max-entries N] [path]\\n\\nDisplays disk usage: used space, available space, and percentage.\\nWith a path, --top or --threshold, walks the folder recursively and reports\\nits files instead.\\n\\nOptions:\\n  --include-vault      Also show space used by the vault (vault must be unlocked);\\n                       summary only, not with a path or the options below\\n  -n, --top N          Print the N largest files, largest first\\n  -c, --count          Print each folder directly below path with its size and\\n                       file count, most files first\\n  --threshold SIZE     Print files of at least SIZE as they are found (e.g. 500K, 100M, 2G)\\n  --no-cache           List folders from the server instead of the cache\\n  --deadline D         Stop walking after D (e.g. 30s) and report what was seen\\n  --max-entries N      Stop walking after N entries and report what was seen\\n\\nExamples:\\n  du --top 20 /Photos\\n  du --threshold 1G\\n  du /Backups           Total size of /Backups\\n  du --count /Backups   Find the folders full of small files\\n  du --deadline 10s /   Rough total, without waiting on a huge tree",
 		Run:         du,
 	})
+	Register(&Command{
+		Name:        "df",
+		Description: "Show free and used space",
+		Usage:       "df [--include-vault]\\n\\nDisplays used space, available space, and percentage.\\n\\nOptions:\\n  --include-vault   Also show space used by the vault (vault must be unlocked)",
+		Run:         df,
+	})
 	Register(&Command{
 		Name:        "unzip",
+		Mutates:     alwaysMutates,
 		Description: "Extract archive",
 		Usage:       "unzip <file>\\n\\nExtracts a ZIP archive on the server (server-side extraction).\\nExtracted files appear in the same directory as the archive.",
 		Run:         unzip,
 	})
+	Register(&Command{
+		Name:        "extract",
+		Mutates:     alwaysMutates,
+		Description: "Extract archive into a folder",
+		Usage:       "extract <archive> [dest]\\n\\nExtracts an archive on the server (server-side extraction) into dest.\\nDefaults to the directory containing the archive.\\nNo data is downloaded or re-uploaded.\\n\\nExamples:\\n  extract backup.zip              Extract next to the archive\\n  extract backup.zip /Restored    Extract into /Restored",
+		Run:         extract,
+	})
 	Register(&Command{
 		Name:        "zip",
+		Mutates:     alwaysMutates,
 		Description: "Create a zip archive",
-		Usage:       "zip <archive.zip> <file|folder>...\\n\\nCreates a ZIP archive from remote files/folders.\\nThe archive is uploaded to Drime Cloud.\\n\\nExamples:\\n  zip backup.zip file1.txt file2.txt\\n  zip photos.zip /Photos/vacation/\\n  zip all.zip /                      Zip entire storage",
+		Usage:       "zip [options] <archive.zip> <file|folder>...\\n\\nCreates a ZIP archive from remote files/folders.\\nThe archive is uploaded to Drime Cloud.\\n\\nFiles are downloaded and compressed several at a time. Files that don't\\nshrink (images, video, archives, ...) are stored uncompressed.\\n\\nOptions:\\n  -l, --level N     Compression level, 0 (store only) to 9 (smallest); default 6\\n                    (--compress-level is an alias)\\n  -j, --jobs N      Number of files compressed at once (default: transfer jobs)\\n\\nExamples:\\n  zip backup.zip file1.txt file2.txt\\n  zip --level 9 logs.zip /Logs/      Smallest archive\\n  zip -l 0 media.zip /Videos/        Just bundle, don't compress\\n  zip photos.zip /Photos/vacation/\\n  zip all.zip /                      Zip entire storage",
 		Run:         zipCmd,
 	})
 }
 
 func du(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("du", pflag.ContinueOnError)
+	includeVault := fs.Bool("include-vault", false, "include vault usage")
+	top := fs.IntP("top", "n", 0, "print the N largest files")
+	thresholdStr := fs.String("threshold", "", "print files of at least this size")
+	count := fs.BoolP("count", "c", false, "print the size and file count of each subfolder")
+	noCache := fs.Bool("no-cache", false, "list folders from the API instead of the cache")
+	budgetFlags := addWalkBudgetFlags(fs)
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	budget, err := budgetFlags.parse()
+	if err != nil {
+		return fmt.Errorf("du: %w", err)
+	}
+
+	// Without a report to produce, du is the account-wide summary
+	if *top == 0 && *thresholdStr == "" && !*count && fs.NArg() == 0 && !*noCache && budget == (walkBudget{}) {
+		return df(ctx, s, env, args)
+	}
+	if *includeVault {
+		return fmt.Errorf("du: --include-vault only applies to the summary, not with a path, --top, --threshold or --count")
+	}
+	if *top < 0 {
+		return fmt.Errorf("du: invalid --top value %d", *top)
+	}
+	if *top > 0 && *count {
+		return fmt.Errorf("du: --top and --count are mutually exclusive")
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: du [--top N | --count] [--threshold SIZE] [--no-cache] [--deadline D] [--max-entries N] [path]")
+	}
+
+	var threshold int64
+	if *thresholdStr != "" {
+		var err error
+		threshold, err = parseSize(*thresholdStr)
+		if err != nil {
+			return fmt.Errorf("du: invalid --threshold: %w", err)
+		}
+	}
+
+	target := "."
+	if fs.NArg() == 1 {
+		target = fs.Arg(0)
+	}
+	resolved, err := s.ResolvePathArg(target)
+	if err != nil {
+		return fmt.Errorf("du: %w", err)
+	}
+	if *noCache {
+		if err := refreshPath(ctx, s, resolved); err != nil {
+			return fmt.Errorf("du: %w", err)
+		}
+		invalidateSubtree(s, resolved)
+	}
+	root, ok := s.Cache.Get(resolved)
+	if !ok {
+		return fmt.Errorf("du: %s: No such file or directory", target)
+	}
+	if root.Type != "folder" {
+		fmt.Fprintf(env.Stdout, "%10s  %s\n", formatBytes(root.Size), resolved)
+		return nil
+	}
+
+	largest := &fileSizeHeap{}
+	var total int64
+	files := 0
+	subfolders := make(map[string]*folderUsage) // --count, by folder path
+	err = walkCachedTree(ctx, s, resolved, budget, func(p string, e *api.FileEntry) {
+		if *count {
+			if dir := topLevelFolder(resolved, p, e.Type == "folder"); dir != "" {
+				u := subfolders[dir]
+				if u == nil {
+					u = &folderUsage{path: dir}
+					subfolders[dir] = u
+				}
+				if e.Type != "folder" {
+					u.files++
+					u.size += e.Size
+				}
+			}
+		}
+		if e.Type == "folder" {
+			return
+		}
+		files++
+		total += e.Size
+		if e.Size < threshold {
+			return
+		}
+		if *top == 0 {
+			if *thresholdStr != "" {
+				fmt.Fprintf(env.Stdout, "%10s  %s\n", formatBytes(e.Size), p)
+			}
+			return
+		}
+		// Keep only the N largest files seen so far
+		heap.Push(largest, sizedPath{path: p, size: e.Size})
+		if largest.Len() > *top {
+			heap.Pop(largest)
+		}
+	})
+	if err != nil && !partialWalk(env, "du", err) {
+		return fmt.Errorf("du: %w", err)
+	}
+
+	if *top > 0 {
+		results := make([]sizedPath, largest.Len())
+		for i := len(results) - 1; i >= 0; i-- {
+			results[i] = heap.Pop(largest).(sizedPath)
+		}
+		for _, r := range results {
+			fmt.Fprintf(env.Stdout, "%10s  %s\n", formatBytes(r.size), r.path)
+		}
+	}
+	if *count {
+		usage := make([]*folderUsage, 0, len(subfolders))
+		for _, u := range subfolders {
+			usage = append(usage, u)
+		}
+		sort.Slice(usage, func(i, j int) bool {
+			if usage[i].files != usage[j].files {
+				return usage[i].files > usage[j].files
+			}
+			return usage[i].path < usage[j].path
+		})
+		for _, u := range usage {
+			fmt.Fprintf(env.Stdout, "%10s  %s (%d files)\n", formatBytes(u.size), u.path, u.files)
+		}
+	}
+	if (*top == 0 && *thresholdStr == "") || *count {
+		fmt.Fprintf(env.Stdout, "%10s  %s (%d files)\n", formatBytes(total), resolved, files)
+	}
+	return nil
+}
+
+// folderUsage is what du --count reports for a folder below the target.
+type folderUsage struct {
+	path  string
+	size  int64
+	files int
+}
+
+// topLevelFolder returns the folder directly below root that the entry at p
+// is in (or is), or "" for a file directly in root.
+func topLevelFolder(root, p string, isFolder bool) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+	first, _, nested := strings.Cut(rel, "/")
+	if !nested && !isFolder {
+		return ""
+	}
+	return path.Join(root, first)
+}
+
+// walkCachedTree visits every entry below dir breadth-first, fetching folder
+// listings that are not cached yet and adding them to the cache. A walk that
+// runs out of budget stops with a *budgetError after visiting what it could.
+func walkCachedTree(ctx context.Context, s *session.Session, dir string, budget walkBudget, visit func(p string, e *api.FileEntry)) error {
+	limit, cancel := budget.begin(ctx)
+	defer cancel()
+	ctx = limit.ctx
+
+	queue := []string{dir}
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return limit.check(err)
+		}
+		current := queue[0]
+		queue = queue[1:]
+
+		if !s.Cache.HasChildren(current) {
+			entry, ok := s.Cache.Get(current)
+			if !ok {
+				continue
+			}
+			if err := refreshListing(ctx, s, current, entry); err != nil {
+				return fmt.Errorf("%s: %w", current, limit.check(err))
+			}
+		}
+
+		for _, child := range s.Cache.GetChildren(current) {
+			if err := limit.next(); err != nil {
+				return err
+			}
+			childPath := path.Join(current, child.Name)
+			visit(childPath, &child)
+			if child.Type == "folder" {
+				queue = append(queue, childPath)
+			}
+		}
+	}
+	return nil
+}
+
+// invalidateSubtree marks the listings of every cached folder below dir as
+// stale, so walkCachedTree fetches them again.
+func invalidateSubtree(s *session.Session, dir string) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	for _, p := range s.Cache.AllPaths() {
+		if p != dir && strings.HasPrefix(p, prefix) {
+			s.Cache.InvalidateChildren(p)
+		}
+	}
+}
+
+type sizedPath struct {
+	path string
+	size int64
+}
+
+// fileSizeHeap is a min-heap on size, so the smallest of the kept files is
+// the one evicted when a larger file turns up.
+type fileSizeHeap []sizedPath
+
+func (h fileSizeHeap) Len() int { return len(h) }
+func (h fileSizeHeap) Less(i, j int) bool {
+	if h[i].size != h[j].size {
+		return h[i].size < h[j].size
+	}
+	return h[i].path > h[j].path
+}
+func (h fileSizeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *fileSizeHeap) Push(x any)   { *h = append(*h, x.(sizedPath)) }
+func (h *fileSizeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// parseSize parses a human size such as "512", "100K", "1.5M" or "2GB" into
+// bytes. Units are binary (1K = 1024 bytes), matching formatBytes.
+func parseSize(value string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(value))
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "B"), "I")
+	multiplier := int64(1)
+	if v != "" {
+		if idx := strings.IndexByte("KMGTP", v[len(v)-1]); idx >= 0 {
+			multiplier = int64(1) << (10 * (idx + 1))
+			v = v[:len(v)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+func df(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("df", pflag.ContinueOnError)
+	includeVault := fs.Bool("include-vault", false, "include vault usage")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
 	usage, err := s.Client.GetSpaceUsage(ctx, s.WorkspaceID)
 	if err != nil {
 		return err
@@ -50,6 +328,28 @@ func du(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 		percent = float64(usage.Used) / float64(usage.Available+usage.Used) * 100
 	}
 	fmt.Fprintf(env.Stdout, "Usage:     %.1f%%\n", percent)
+
+	if *includeVault {
+		if !s.IsVaultUnlocked() {
+			fmt.Fprintln(env.Stderr, "df: vault is locked, skipping vault usage (run 'vault' to unlock)")
+			return nil
+		}
+		items, err := ui.WithSpinner(env.Stderr, "", false, func() ([]vaultItem, error) {
+			return walkVault(ctx, s)
+		})
+		if err != nil {
+			return fmt.Errorf("df: vault: %w", err)
+		}
+		var vaultBytes int64
+		files := 0
+		for _, item := range items {
+			if item.Entry.Type != "folder" {
+				vaultBytes += item.Entry.Size
+				files++
+			}
+		}
+		fmt.Fprintf(env.Stdout, "vault:     %s (%d files)\n", formatBytes(vaultBytes), files)
+	}
 	return nil
 }
 
@@ -57,60 +357,86 @@ func unzip(ctx context.Context, s *session.Session, env *ExecutionEnv, args []st
 	if len(args) < 1 {
 		return fmt.Errorf("usage: unzip <file>")
 	}
+	return extractArchive(ctx, s, env, "unzip", args[0], "")
+}
 
-	path := args[0]
+func extract(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: extract <archive> [dest]")
+	}
+	dest := ""
+	if len(args) == 2 {
+		dest = args[1]
+	}
+	return extractArchive(ctx, s, env, "extract", args[0], dest)
+}
+
+// extractArchive extracts a remote archive server-side into destArg (or the
+// archive's own directory when destArg is empty) and refreshes that directory
+// in the cache so the extracted files are visible immediately.
+func extractArchive(ctx context.Context, s *session.Session, env *ExecutionEnv, cmdName, path, destArg string) error {
 	resolved, err := s.ResolvePathArg(path)
 	if err != nil {
-		return fmt.Errorf("unzip: %w", err)
+		return fmt.Errorf("%s: %w", cmdName, err)
 	}
 	entry, ok := s.Cache.Get(resolved)
 	if !ok {
-		return fmt.Errorf("unzip: %s: No such file", path)
+		return fmt.Errorf("%s: %s: No such file", cmdName, path)
 	}
 
 	if entry.Type == "folder" {
-		return fmt.Errorf("unzip: %s: Is a directory", path)
+		return fmt.Errorf("%s: %s: Is a directory", cmdName, path)
 	}
 
-	// Get parent directory info for the refresh
-	parentDir := filepath.Dir(resolved)
-	parentEntry, _ := s.Cache.Get(parentDir)
+	destDir := filepath.Dir(resolved)
+	if destArg != "" {
+		destDir, err = s.ResolvePathArg(destArg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cmdName, err)
+		}
+	}
+
+	// Root has no entry ID; anything else must be a known folder
+	var destID *int64
+	if destDir != "/" {
+		destEntry, ok := s.Cache.Get(destDir)
+		if !ok {
+			return fmt.Errorf("%s: %s: No such file or directory", cmdName, destDir)
+		}
+		if destEntry.Type != "folder" {
+			return fmt.Errorf("%s: %s: Not a directory", cmdName, destDir)
+		}
+		destID = parentIDPtr(destEntry)
+	}
 
 	err = ui.WithSpinnerErr(env.Stderr, "", false, func() error {
 		// Extract the archive
-		if err := s.Client.ExtractEntry(ctx, entry.ID, entry.ParentID, s.WorkspaceID); err != nil {
+		if err := s.Client.ExtractEntry(ctx, entry.ID, destID, s.WorkspaceID); err != nil {
 			return err
 		}
 
-		// Refresh the parent directory contents (fetch new files into cache)
-		var parentID *int64
-		if parentDir == "/" {
-			parentID = nil
-		} else if parentEntry != nil {
-			parentID = &parentEntry.ID
-		} else {
-			// Parent not found in cache (and not root), can't refresh safely
-			return nil
-		}
-
+		// Refresh the destination directory contents (fetch new files into cache)
 		apiOpts := api.ListOptions(s.WorkspaceID)
-		children, err := s.Client.ListByParentIDWithOptions(ctx, parentID, apiOpts)
+		children, err := s.Client.ListByParentIDWithOptions(ctx, destID, apiOpts)
 		if err != nil {
 			return err
 		}
 
 		// Update cache with fresh data
-		s.Cache.InvalidateChildren(parentDir)
+		s.Cache.InvalidateChildren(destDir)
 		for i := range children {
-			childPath := filepath.Join(parentDir, children[i].Name)
+			childPath := filepath.Join(destDir, children[i].Name)
 			s.Cache.Add(&children[i], childPath)
 		}
-		s.Cache.MarkChildrenLoaded(parentDir)
+		s.Cache.MarkChildrenLoaded(destDir)
 
 		return nil
 	})
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", cmdName, path, err)
+	}
 
-	return err
+	return nil
 }
 
 func formatBytes(b int64) string {
@@ -129,12 +455,29 @@ func formatBytes(b int64) string {
 // zipCmd creates a zip archive from remote files/folders.
 // Usage: zip archive.zip file1 file2 folder/
 func zipCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
-	if len(args) < 2 {
-		return fmt.Errorf("usage: zip <archive.zip> <file|folder>")
+	fs := pflag.NewFlagSet("zip", pflag.ContinueOnError)
+	level := fs.IntP("level", "l", 6, "compression level, 0 (store) to 9 (best)")
+	fs.Int("compress-level", 6, "alias for --level")
+	jobs := fs.IntP("jobs", "j", 0, "number of files compressed at once")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return fmt.Errorf("usage: zip [--level N] [-j N] <archive.zip> <file|folder>...")
+	}
+	if fs.Changed("compress-level") {
+		*level, _ = fs.GetInt("compress-level")
+	}
+	if *level < 0 || *level > 9 {
+		return fmt.Errorf("zip: invalid --level %d (must be 0-9)", *level)
+	}
+	if *jobs < 0 {
+		return fmt.Errorf("zip: invalid --jobs %d", *jobs)
 	}
 
-	archiveName := args[0]
-	sources := args[1:]
+	archiveName := fs.Arg(0)
+	sources := fs.Args()[1:]
 
 	// Ensure archive name ends with .zip
 	if !strings.HasSuffix(strings.ToLower(archiveName), ".zip") {
@@ -161,12 +504,7 @@ func zipCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 	}
 
 	// Collect all source entries and estimate total size
-	type sourceInfo struct {
-		entry    *api.FileEntry
-		resolved string
-		baseName string
-	}
-	var sourcesToZip []sourceInfo
+	var zipJobs []zipJob
 	var estimatedSize int64
 
 	for _, src := range sources {
@@ -178,19 +516,8 @@ func zipCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 		if !ok {
 			return fmt.Errorf("zip: %s: No such file or directory", src)
 		}
-
-		// Determine a good base name for the entry in the zip
-		baseName := filepath.Base(resolved)
-		if resolved == "/" || baseName == "/" || baseName == "" {
-			// Root folder - use "root" or skip the prefix entirely
-			baseName = ""
-		}
-
-		sourcesToZip = append(sourcesToZip, sourceInfo{
-			entry:    entry,
-			resolved: resolved,
-			baseName: baseName,
-		})
+		// The root folder's contents go at the top level of the archive
+		zipJobs = append(zipJobs, zipJob{entry: entry, name: zipName(resolved)})
 		estimatedSize += entry.Size
 	}
 
@@ -220,18 +547,15 @@ func zipCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 		zipWriter = zip.NewWriter(memBuf)
 	}
 
-	for _, src := range sourcesToZip {
-		if src.entry.Type == "folder" {
-			if err := addFolderToZip(ctx, s, zipWriter, src.entry, src.baseName, env, maxMemory); err != nil {
-				zipWriter.Close()
-				return fmt.Errorf("zip: error adding folder %s: %w", src.baseName, err)
-			}
-		} else {
-			if err := addFileToZip(ctx, s, zipWriter, src.entry, src.baseName, env, maxMemory); err != nil {
-				zipWriter.Close()
-				return fmt.Errorf("zip: error adding file %s: %w", src.baseName, err)
-			}
-		}
+	zipJobs, err = planZipJobs(ctx, s, zipJobs)
+	if err != nil {
+		zipWriter.Close()
+		return fmt.Errorf("zip: %w", err)
+	}
+	builder := &zipBuilder{s: s, env: env, level: *level, maxMemory: maxMemory, zw: zipWriter}
+	if err := builder.run(ctx, zipJobs, uploadWorkers(s, *jobs, len(zipJobs))); err != nil {
+		zipWriter.Close()
+		return fmt.Errorf("zip: %w", err)
 	}
 
 	if err := zipWriter.Close(); err != nil {
@@ -252,12 +576,11 @@ func zipCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 		uploadReader = memBuf
 	}
 
+	fmt.Fprintf(env.Stdout, "Compressed %d files: %s to %s (%d%% saved)\n", builder.files,
+		formatBytes(builder.rawSize), formatBytes(builder.packedSize), savedPercent(builder.rawSize, builder.packedSize))
 	fmt.Fprintf(env.Stdout, "Uploading %s (%s)...\n", filepath.Base(destResolved), formatBytes(uploadSize))
 
-	var parentID *int64
-	if destDirEntry != nil && destDirEntry.ID != 0 {
-		parentID = &destDirEntry.ID
-	}
+	parentID := parentIDPtr(destDirEntry)
 
 	var uploadedEntry *api.FileEntry
 	err = ui.WithSpinnerErr(env.Stderr, "", false, func() error {
@@ -276,196 +599,3 @@ func zipCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 	fmt.Fprintf(env.Stdout, "Created %s\n", archiveName)
 	return nil
 }
-
-// addFileToZip downloads a single file and adds it to the zip archive
-func addFileToZip(ctx context.Context, s *session.Session, zw *zip.Writer, entry *api.FileEntry, nameInZip string, env *ExecutionEnv, maxMemory int64) error {
-	fmt.Fprintf(env.Stdout, "  adding: %s\n", nameInZip)
-
-	// For large files, use temp file
-	if entry.Size > maxMemory {
-		return addLargeFileToZip(ctx, s, zw, entry, nameInZip, env)
-	}
-
-	// Download file content to memory
-	content := new(bytes.Buffer)
-	_, err := ui.WithSpinner(io.Discard, "", false, func() (*api.FileEntry, error) {
-		return s.Client.Download(ctx, entry.Hash, content, nil)
-	})
-	if err != nil {
-		return err
-	}
-
-	// Create zip entry with proper header
-	header := &zip.FileHeader{
-		Name:     nameInZip,
-		Method:   zip.Deflate,
-		Modified: entry.UpdatedAt,
-	}
-
-	w, err := zw.CreateHeader(header)
-	if err != nil {
-		return err
-	}
-
-	_, err = io.Copy(w, content)
-	return err
-}
-
-// addLargeFileToZip handles files that exceed memory threshold using temp files
-func addLargeFileToZip(ctx context.Context, s *session.Session, zw *zip.Writer, entry *api.FileEntry, nameInZip string, _ *ExecutionEnv) error {
-	// Create temp file for download
-	tempFile, err := os.CreateTemp("", "drime-download-*")
-	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
-	}
-	defer func() {
-		tempFile.Close()
-		os.Remove(tempFile.Name())
-	}()
-
-	// Download to temp file
-	_, err = ui.WithSpinner(io.Discard, "", false, func() (*api.FileEntry, error) {
-		return s.Client.Download(ctx, entry.Hash, tempFile, nil)
-	})
-	if err != nil {
-		return err
-	}
-
-	// Seek back to start
-	_, _ = tempFile.Seek(0, io.SeekStart)
-
-	// Create zip entry
-	header := &zip.FileHeader{
-		Name:     nameInZip,
-		Method:   zip.Deflate,
-		Modified: entry.UpdatedAt,
-	}
-
-	w, err := zw.CreateHeader(header)
-	if err != nil {
-		return err
-	}
-
-	_, err = io.Copy(w, tempFile)
-	return err
-}
-
-// addFolderToZip downloads a folder (which comes as ZIP from API) and re-adds its contents
-func addFolderToZip(ctx context.Context, s *session.Session, zw *zip.Writer, entry *api.FileEntry, folderName string, env *ExecutionEnv, maxMemory int64) error {
-	if folderName != "" {
-		fmt.Fprintf(env.Stdout, "  adding: %s/\n", folderName)
-	}
-
-	// Root folder (ID=0) can't be downloaded - we need to zip its children individually
-	if entry.ID == 0 {
-		return addRootFolderToZip(ctx, s, zw, folderName, env, maxMemory)
-	}
-
-	// For large folders, use temp file
-	var zipReader *zip.Reader
-
-	if entry.Size > maxMemory {
-		// Download to temp file
-		tempFile, err := os.CreateTemp("", "drime-folder-*.zip")
-		if err != nil {
-			return fmt.Errorf("failed to create temp file: %w", err)
-		}
-		defer func() {
-			tempFile.Close()
-			os.Remove(tempFile.Name())
-		}()
-
-		_, err = ui.WithSpinner(io.Discard, "", false, func() (*api.FileEntry, error) {
-			return s.Client.Download(ctx, entry.Hash, tempFile, nil)
-		})
-		if err != nil {
-			return err
-		}
-
-		// Get file size and create reader
-		size, _ := tempFile.Seek(0, io.SeekEnd)
-		_, _ = tempFile.Seek(0, io.SeekStart)
-
-		zipReader, err = zip.NewReader(tempFile, size)
-		if err != nil {
-			return fmt.Errorf("failed to read folder zip: %w", err)
-		}
-	} else {
-		// Download to memory
-		content := new(bytes.Buffer)
-		_, err := ui.WithSpinner(io.Discard, "", false, func() (*api.FileEntry, error) {
-			return s.Client.Download(ctx, entry.Hash, content, nil)
-		})
-		if err != nil {
-			return err
-		}
-
-		zipReader, err = zip.NewReader(bytes.NewReader(content.Bytes()), int64(content.Len()))
-		if err != nil {
-			return fmt.Errorf("failed to read folder zip: %w", err)
-		}
-	}
-
-	// Copy all entries from the downloaded zip, prefixing with folder name
-	for _, f := range zipReader.File {
-		// Check for ZipSlip vulnerability - reject paths with traversal
-		if strings.Contains(f.Name, "..") {
-			return fmt.Errorf("illegal file path in zip: %s", f.Name)
-		}
-
-		newName := filepath.Join(folderName, f.Name)
-		fmt.Fprintf(env.Stdout, "  adding: %s\n", newName)
-
-		header := f.FileHeader
-		header.Name = newName
-
-		w, err := zw.CreateHeader(&header)
-		if err != nil {
-			return err
-		}
-
-		if !f.FileInfo().IsDir() {
-			rc, err := f.Open()
-			if err != nil {
-				return err
-			}
-			_, err = io.Copy(w, rc)
-			rc.Close()
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
-
-// addRootFolderToZip handles the special case of zipping the root folder (ID=0)
-// which can't be downloaded directly - we zip its children instead
-func addRootFolderToZip(ctx context.Context, s *session.Session, zw *zip.Writer, prefix string, env *ExecutionEnv, maxMemory int64) error {
-	// List children of root
-	apiOpts := api.ListOptions(s.WorkspaceID)
-	children, err := s.Client.ListByParentIDWithOptions(ctx, nil, apiOpts)
-	if err != nil {
-		return fmt.Errorf("failed to list root folder: %w", err)
-	}
-
-	for _, child := range children {
-		childName := child.Name
-		if prefix != "" {
-			childName = filepath.Join(prefix, child.Name)
-		}
-
-		if child.Type == "folder" {
-			if err := addFolderToZip(ctx, s, zw, &child, childName, env, maxMemory); err != nil {
-				return err
-			}
-		} else {
-			if err := addFileToZip(ctx, s, zw, &child, childName, env, maxMemory); err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,895 @@
+package commands_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/commands"
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// EXTRACT COMMAND TESTS
+// ============================================================================
+
+func TestExtract_IntoDestinationFolder(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	destID := int64(200)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 100, Name: "backup.zip", Type: "archive"},
+		{ID: destID, Name: "Restored", Type: "folder"},
+	})
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	var gotParent *int64
+	mockClient.ExtractEntryFunc = func(ctx context.Context, entryID int64, parentID *int64, workspaceID int64) error {
+		assert.Equal(t, int64(100), entryID)
+		gotParent = parentID
+		return nil
+	}
+	mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+		if parentID != nil && *parentID == destID {
+			return []api.FileEntry{{ID: 201, Name: "notes.txt", Type: "text", ParentID: &destID}}, nil
+		}
+		return []api.FileEntry{}, nil
+	}
+
+	cmd, ok := commands.Get("extract")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"backup.zip", "Restored"}))
+
+	require.NotNil(t, gotParent)
+	assert.Equal(t, destID, *gotParent)
+	_, ok = s.Cache.Get("/Restored/notes.txt")
+	assert.True(t, ok, "extracted file should be cached")
+}
+
+func TestExtract_SurfacesUnsupportedArchiveError(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 100, Name: "data.rar", Type: "archive"},
+	})
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.ExtractEntryFunc = func(ctx context.Context, entryID int64, parentID *int64, workspaceID int64) error {
+		return errors.New("extraction failed: Unsupported archive type")
+	}
+
+	cmd, ok := commands.Get("extract")
+	require.True(t, ok)
+	err := cmd.Run(context.Background(), s, env, []string{"data.rar"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Unsupported archive type")
+}
+
+func TestExtract_DestinationMustBeFolder(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 100, Name: "backup.zip", Type: "archive"},
+		{ID: 101, Name: "file.txt", Type: "text"},
+	})
+
+	cmd, ok := commands.Get("extract")
+	require.True(t, ok)
+	err := cmd.Run(context.Background(), s, env, []string{"backup.zip", "file.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Not a directory")
+}
+
+// ============================================================================
+// SOURCE / HISTORY SCRIPT TESTS
+// ============================================================================
+
+func TestSource_RunsScriptLines(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	var ran []string
+	s.LineRunner = func(ctx context.Context, line string) error {
+		ran = append(ran, line)
+		if line == "rm missing" {
+			return errors.New("missing: No such file or directory")
+		}
+		return nil
+	}
+
+	script := filepath.Join(t.TempDir(), "batch.drime")
+	require.NoError(t, os.WriteFile(script, []byte("# setup\ncd /docs\n\nrm missing\nls -l | grep txt\n"), 0644))
+
+	cmd, ok := commands.Get("source")
+	require.True(t, ok)
+
+	err := cmd.Run(context.Background(), s, env, []string{script})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "batch.drime:4")
+	assert.Equal(t, []string{"cd /docs", "rm missing"}, ran)
+
+	ran = nil
+	err = cmd.Run(context.Background(), s, env, []string{"--keep-going", script})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 commands failed")
+	assert.Equal(t, []string{"cd /docs", "rm missing", "ls -l | grep txt"}, ran)
+}
+
+func TestSource_WithoutShellRunsPlainCommands(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.LineRunner = nil
+	s.Cache.Add(&api.FileEntry{ID: 5, Name: "docs", Type: "folder"}, "/docs")
+
+	script := filepath.Join(t.TempDir(), "batch.drime")
+	require.NoError(t, os.WriteFile(script, []byte("cd /docs\npwd\nls | grep txt\n"), 0644))
+
+	cmd, ok := commands.Get("source")
+	require.True(t, ok)
+	err := cmd.Run(context.Background(), s, env, []string{script})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "batch.drime:3")
+	assert.Contains(t, err.Error(), "need the interactive shell")
+	assert.Equal(t, "/docs", s.CWD)
+	assert.Contains(t, stdout.String(), "/docs")
+}
+
+func TestHistory_SaveAsScript(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.HistoryGetter = func() []string {
+		return []string{"cd /docs", "history", "upload a.txt", "history -s out.drime"}
+	}
+
+	out := filepath.Join(t.TempDir(), "out.drime")
+	cmd, ok := commands.Get("history")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-s", out}))
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "#"))
+	assert.Equal(t, []string{"cd /docs", "upload a.txt"}, lines[1:])
+}
+
+// ============================================================================
+// DU COMMAND TESTS
+// ============================================================================
+
+func TestDu_TopAndThreshold(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+
+	photosID := int64(10)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 1, Name: "small.txt", Type: "text", Size: 100},
+		{ID: photosID, Name: "Photos", Type: "folder"},
+	})
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+		if parentID != nil && *parentID == photosID {
+			return []api.FileEntry{
+				{ID: 11, Name: "big.jpg", Type: "image", Size: 5 << 20, ParentID: &photosID},
+				{ID: 12, Name: "mid.jpg", Type: "image", Size: 2 << 20, ParentID: &photosID},
+				{ID: 13, Name: "tiny.jpg", Type: "image", Size: 10, ParentID: &photosID},
+			}, nil
+		}
+		return []api.FileEntry{}, nil
+	}
+
+	cmd, ok := commands.Get("du")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--top", "2", "/"}))
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "/Photos/big.jpg")
+	assert.Contains(t, lines[0], "5.0 MB")
+	assert.Contains(t, lines[1], "/Photos/mid.jpg")
+	_, ok = s.Cache.Get("/Photos/tiny.jpg")
+	assert.True(t, ok, "walked folders should be cached")
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--threshold", "1M"}))
+	out := stdout.String()
+	assert.Contains(t, out, "/Photos/big.jpg")
+	assert.Contains(t, out, "/Photos/mid.jpg")
+	assert.NotContains(t, out, "tiny.jpg")
+	assert.NotContains(t, out, "small.txt")
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"/Photos"}))
+	assert.Contains(t, stdout.String(), "7.0 MB  /Photos (3 files)")
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--count", "/"}))
+	lines = strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "7.0 MB  /Photos (3 files)")
+	assert.Contains(t, lines[1], "/ (4 files)")
+
+	err := cmd.Run(context.Background(), s, env, []string{"--threshold", "lots"})
+	assert.Error(t, err)
+
+	// The vault only counts in the summary, so a walk refuses it
+	for _, args := range [][]string{{"--include-vault", "/Photos"}, {"--include-vault", "--top", "2"}, {"--count", "--include-vault", "/"}} {
+		err = cmd.Run(context.Background(), s, env, args)
+		assert.ErrorContains(t, err, "--include-vault only applies to the summary", args)
+	}
+}
+
+func TestRecursiveWalks_StopWithinBudget(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	stderr := env.Stderr.(*bytes.Buffer)
+
+	photosID, slowID := int64(10), int64(20)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 1, Name: "notes.txt", Type: "text", Size: 100},
+		{ID: photosID, Name: "Photos", Type: "folder"},
+	})
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+		switch {
+		case parentID != nil && *parentID == photosID:
+			return []api.FileEntry{
+				{ID: 11, Name: "a.jpg", Type: "image", Size: 1 << 20, ParentID: &photosID},
+				{ID: 12, Name: "b.jpg", Type: "image", Size: 1 << 20, ParentID: &photosID},
+				{ID: slowID, Name: "slow", Type: "folder", ParentID: &photosID},
+			}, nil
+		case parentID != nil && *parentID == slowID:
+			// A listing that never comes back on its own
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []api.FileEntry{}, nil
+	}
+
+	du, ok := commands.Get("du")
+	require.True(t, ok)
+	require.NoError(t, du.Run(context.Background(), s, env, []string{"--max-entries", "3", "/"}))
+	assert.Contains(t, stdout.String(), "  / (")
+	assert.Contains(t, stderr.String(), "du: partial results (limit of 3 entries reached)")
+
+	// The deadline interrupts the listing in flight
+	stdout.Reset()
+	start := time.Now()
+	find, ok := commands.Get("find")
+	require.True(t, ok)
+	require.NoError(t, find.Run(context.Background(), s, env, []string{"-r", "--deadline", "50ms", "--name", ".JPG", "/Photos"}))
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.ElementsMatch(t, []string{"/Photos/a.jpg", "/Photos/b.jpg"}, strings.Fields(stdout.String()))
+	assert.Contains(t, stderr.String(), "find: partial results (deadline of 50ms reached)")
+
+	stdout.Reset()
+	tree, ok := commands.Get("tree")
+	require.True(t, ok)
+	require.NoError(t, tree.Run(context.Background(), s, env, []string{"--max-entries", "1", "/Photos"}))
+	assert.Contains(t, stdout.String(), "a.jpg")
+	assert.NotContains(t, stdout.String(), "b.jpg")
+	assert.Contains(t, stderr.String(), "tree: partial results (limit of 1 entries reached)")
+
+	// Without a budget a cancelled walk is an error, not partial results
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Error(t, find.Run(ctx, s, env, []string{"-r", "/Photos"}))
+
+	assert.Error(t, find.Run(context.Background(), s, env, []string{"--max-entries", "3", "/"}), "budgets need -r")
+}
+
+// ============================================================================
+// LOCATE COMMAND TESTS
+// ============================================================================
+
+func TestLocate_SearchesIndexWithoutAPICalls(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.IndexDir = t.TempDir()
+	s.UserID = 7
+	require.NoError(t, s.AttachNameIndex(s.Cache, 0))
+
+	docsID := int64(10)
+	s.Cache.AddChildren("/", []api.FileEntry{{ID: docsID, Name: "Docs", Type: "folder"}})
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	listed := 0
+	mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+		listed++
+		if parentID != nil && *parentID == docsID {
+			return []api.FileEntry{{ID: 11, Name: "Invoice.pdf", Type: "pdf", ParentID: &docsID}}, nil
+		}
+		return []api.FileEntry{{ID: docsID, Name: "Docs", Type: "folder"}}, nil
+	}
+
+	cmd, ok := commands.Get("locate")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--update"}))
+	assert.Equal(t, 2, listed, "update re-lists every folder")
+	_, err := os.Stat(filepath.Join(s.IndexDir, "user-7-ws-0.json"))
+	assert.NoError(t, err, "index should be persisted")
+
+	listed = 0
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-i", "invoice"}))
+	assert.Equal(t, "/Docs/Invoice.pdf\n", stdout.String())
+	assert.Zero(t, listed, "lookups must not hit the API")
+
+	// Another account on the same machine starts from its own, empty index
+	other, otherEnv, otherOut := setupTestEnv(t)
+	other.IndexDir = s.IndexDir
+	other.UserID = 8
+	require.NoError(t, other.AttachNameIndex(other.Cache, 0))
+	_ = cmd.Run(context.Background(), other, otherEnv, []string{"-i", "invoice"})
+	assert.NotContains(t, otherOut.String(), "Invoice.pdf")
+}
+
+func TestLocate_DisabledWithoutIndex(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+
+	cmd, ok := commands.Get("locate")
+	require.True(t, ok)
+	err := cmd.Run(context.Background(), s, env, []string{"foo"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "locate_index")
+}
+
+// ============================================================================
+// RECONNECT COMMAND TESTS
+// ============================================================================
+
+func TestReconnect_RechecksAccountAndReloadsCWD(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.Cache.AddChildren("/", []api.FileEntry{{ID: 1, Name: "stale.txt", Type: "text"}})
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.WhoamiFunc = func(ctx context.Context) (*api.User, error) {
+		return &api.User{ID: 123, Email: "me@example.com"}, nil
+	}
+	mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+		return []api.FileEntry{{ID: 2, Name: "fresh.txt", Type: "text"}}, nil
+	}
+
+	cmd, ok := commands.Get("reconnect")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--reload"}))
+
+	assert.Contains(t, stdout.String(), "me@example.com")
+	assert.Equal(t, "me@example.com", s.Username)
+	_, ok = s.Cache.Get("/stale.txt")
+	assert.False(t, ok)
+	_, ok = s.Cache.Get("/fresh.txt")
+	assert.True(t, ok)
+}
+
+func TestReconnect_ReportsFailure(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.WhoamiFunc = func(ctx context.Context) (*api.User, error) {
+		return nil, errors.New("dial tcp: connection refused")
+	}
+
+	cmd, ok := commands.Get("reconnect")
+	require.True(t, ok)
+	err := cmd.Run(context.Background(), s, env, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reconnect:")
+	assert.Equal(t, "testuser", s.Username)
+}
+
+func TestPing_ReportsLatencyAndFailures(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	calls := 0
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.WhoamiFunc = func(ctx context.Context) (*api.User, error) {
+		calls++
+		if calls == 2 {
+			return nil, errors.New("dial tcp: i/o timeout")
+		}
+		return &api.User{ID: 123, Email: "me@example.com"}, nil
+	}
+
+	cmd, ok := commands.Get("ping")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-c", "3"}))
+
+	assert.Equal(t, 3, calls)
+	out := stdout.String()
+	assert.Contains(t, out, "request 2: dial tcp: i/o timeout")
+	assert.Contains(t, out, "3 requests, 1 failed")
+	assert.Contains(t, out, "rtt min/avg/max = ")
+}
+
+func TestPing_FailsWhenNoRequestSucceeds(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.WhoamiFunc = func(ctx context.Context) (*api.User, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	cmd, ok := commands.Get("ping")
+	require.True(t, ok)
+	err := cmd.Run(context.Background(), s, env, []string{"-c", "2"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+}
+
+type panickingHook struct{}
+
+func (panickingHook) Before(*session.Session, string, []string) { panic("boom") }
+func (panickingHook) After(*session.Session, string, []string, error, time.Duration) {
+	panic("boom")
+}
+
+func TestExecute_AuditHookLogsMutatingCommands(t *testing.T) {
+	defer commands.ResetHooksForTest()()
+
+	s, env, _ := setupTestEnv(t)
+	s.Token = "drm_secret_token"
+	s.Cache.Add(&api.FileEntry{ID: 100, Name: "docs", Type: "folder"}, "/docs")
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.CreateFolderFunc = func(ctx context.Context, name string, parentID *int64, workspaceID int64) (*api.FileEntry, error) {
+		return &api.FileEntry{ID: 200, Name: name, Type: "folder"}, nil
+	}
+
+	logPath := filepath.Join(t.TempDir(), "audit", "audit.log")
+	commands.AddHook(panickingHook{})
+	commands.AddHook(commands.NewAuditHook(logPath))
+
+	mkdir, _ := commands.Get("mkdir")
+	pwd, _ := commands.Get("pwd")
+	require.NoError(t, commands.Execute(context.Background(), mkdir, s, env, []string{"/docs/new"}))
+	require.NoError(t, commands.Execute(context.Background(), pwd, s, env, nil))
+	assert.Contains(t, env.Stderr.(*bytes.Buffer).String(), "command hook failed")
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1, "read-only commands are not audited")
+
+	var rec map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, "mkdir", rec["command"])
+	assert.Equal(t, "testuser", rec["user"])
+	assert.Equal(t, []any{"/docs/new"}, rec["args"])
+	assert.NotContains(t, string(data), "drm_secret_token")
+}
+
+func TestAuditHook_RedactsSecrets(t *testing.T) {
+	s, _, _ := setupTestEnv(t)
+	s.Token = "drm_secret_token"
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	hook := commands.NewAuditHook(logPath)
+
+	hook.After(s, "request", []string{"create", "inbox", "--password", "hunter2", "--password=hunter3", "--note", "drm_secret_token"}, errors.New("failed"), time.Second)
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	out := string(data)
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "hunter3")
+	assert.NotContains(t, out, "drm_secret_token")
+	assert.Contains(t, out, `"--password=[REDACTED]"`)
+	assert.Contains(t, out, `"error":"failed"`)
+}
+
+func TestAuditHook_RedactsShortSecretFlags(t *testing.T) {
+	s, _, _ := setupTestEnv(t)
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	hook := commands.NewAuditHook(logPath)
+
+	for _, args := range [][]string{
+		{"-p", "hunter2", "file.txt"},
+		{"link", "-phunter2", "file.txt"},
+		{"-dp", "hunter2", "file.txt"},
+		{"file.txt", "-p=hunter2", "-r", "view"},
+	} {
+		hook.After(s, "share", args, nil, time.Second)
+	}
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 4)
+	assert.NotContains(t, string(data), "hunter2")
+	want := [][]any{
+		{"-p", "[REDACTED]", "file.txt"},
+		{"link", "-p[REDACTED]", "file.txt"},
+		{"-dp", "[REDACTED]", "file.txt"},
+		{"file.txt", "-p=[REDACTED]", "-r", "view"},
+	}
+	for i, line := range lines {
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		assert.Equal(t, want[i], rec["args"])
+	}
+}
+
+func TestMutates_ListsEveryMutatingCommand(t *testing.T) {
+	var mutating []string
+	for name, cmd := range commands.Registry {
+		if cmd.Mutates != nil && name == cmd.Name {
+			mutating = append(mutating, name)
+		}
+	}
+	sort.Strings(mutating)
+	assert.Equal(t, []string{
+		"cp", "dedupe", "edit", "extract", "mkdir", "mv", "request", "rm", "search", "share", "star",
+		"tag", "touch", "track", "trash", "undo", "unzip", "upload", "uploads", "vault", "ws", "zip",
+	}, mutating, "a command that changes remote state must set Mutates")
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{"pwd", nil, false},
+		{"search", []string{"tmp", "--paths"}, false},
+		{"search", []string{"tmp", "--delete", "-y"}, true},
+		{"search", []string{"--move=/Archive", "invoice"}, true},
+		{"search", []string{"--", "--star"}, false},
+		{"dedupe", []string{"/"}, false},
+		{"dedupe", []string{"--delete", "/"}, true},
+		{"tag", []string{"ls", "a.txt"}, false},
+		{"tag", []string{"add", "a.txt", "work"}, true},
+		{"uploads", nil, false},
+		{"uploads", []string{"abort", "1"}, true},
+		{"ws", nil, false},
+		{"ws", []string{"members"}, false},
+		{"ws", []string{"Team"}, false},
+		{"ws", []string{"RM", "Team"}, true},
+		{"ws", []string{"invite", "a@example.com"}, true},
+		{"vault", []string{"status"}, false},
+		{"vault", []string{"info"}, false},
+		{"vault", []string{"init"}, true},
+		{"trash", nil, false},
+		{"trash", []string{"ls"}, false},
+		{"trash", []string{"restore", "#12"}, true},
+		{"trash", []string{"empty"}, true},
+		{commands.RedirectCommand, []string{">", "/out.txt"}, true},
+	} {
+		assert.Equal(t, tc.want, commands.Mutates(tc.name, tc.args), "%s %v", tc.name, tc.args)
+	}
+}
+
+func TestStat_FollowsShareLinks(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	docsID := int64(100)
+	s.Cache.Add(&api.FileEntry{ID: docsID, Name: "docs", Type: "folder"}, "/docs")
+	s.Cache.Add(&api.FileEntry{ID: 101, Name: "plan.pdf", Type: "pdf", Public: true, ParentID: &docsID}, "/docs/plan.pdf")
+
+	var lookedUp []string
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetShareableLinkByHashFunc = func(ctx context.Context, hash string) (*api.ShareableLink, error) {
+		lookedUp = append(lookedUp, hash)
+		if hash == "foreign" {
+			return &api.ShareableLink{Hash: hash, UserID: 999, AllowDownload: true,
+				Entry: &api.FileEntry{ID: 555, Name: "their.zip", Type: "archive", Size: 42}}, nil
+		}
+		return nil, &api.APIError{StatusCode: 404, Message: "not found"}
+	}
+	mockClient.GetShareableLinkFunc = func(ctx context.Context, entryID int64) (*api.ShareableLink, error) {
+		if entryID == 101 {
+			return &api.ShareableLink{Hash: "mine123", UserID: 123, EntryID: 101}, nil
+		}
+		return nil, nil
+	}
+
+	cmd, ok := commands.Get("stat")
+	require.True(t, ok)
+
+	// The server lookup fails, so the link is matched against known entries
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"https://dri.me/mine123"}))
+	out := stdout.String()
+	assert.Contains(t, out, "plan.pdf")
+	assert.Contains(t, out, "/docs/plan.pdf")
+	assert.Contains(t, out, "you")
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--follow", "foreign"}))
+	out = stdout.String()
+	assert.Contains(t, out, "their.zip")
+	assert.Contains(t, out, "not you")
+	assert.Contains(t, out, "view, download")
+	assert.Equal(t, []string{"mine123", "foreign"}, lookedUp)
+
+	err := cmd.Run(context.Background(), s, env, []string{"https://dri.me/nothing"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no share link found")
+}
+
+func TestStat_ShowPathFromIDOrHash(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	projectsID, reportsID := int64(7), int64(8)
+	entries := map[int64]*api.FileEntry{
+		reportsID: {ID: reportsID, Name: "reports", Type: "folder", Hash: "reports-hash", ParentID: &projectsID},
+		4821:      {ID: 4821, Name: "q3.pdf", Type: "file", Hash: "NDgyMXxwYWRkaW5n", ParentID: &reportsID},
+	}
+	var fetched []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetEntryFunc = func(ctx context.Context, entryID int64, workspaceID int64) (*api.FileEntry, error) {
+		fetched = append(fetched, entryID)
+		if e, ok := entries[entryID]; ok {
+			return e, nil
+		}
+		return nil, &api.APIError{StatusCode: 404, Message: "not found"}
+	}
+	mockClient.GetFolderPathFunc = func(ctx context.Context, folderHash string, workspaceID int64) ([]api.FileEntry, error) {
+		if folderHash == "reports-hash" {
+			return []api.FileEntry{
+				{ID: projectsID, Name: "projects", Type: "folder", Hash: "projects-hash"},
+				*entries[reportsID],
+			}, nil
+		}
+		return nil, &api.APIError{StatusCode: 404, Message: "not found"}
+	}
+
+	cmd, ok := commands.Get("stat")
+	require.True(t, ok)
+
+	// A file ID resolves through its parent folder's breadcrumb
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--show-path", "4821"}))
+	assert.Equal(t, "/projects/reports/q3.pdf\n", stdout.String())
+	e, ok := s.Cache.Get("/projects/reports")
+	require.True(t, ok, "ancestors are cached")
+	assert.Equal(t, reportsID, e.ID)
+
+	// Once cached, IDs and hashes are answered without the server
+	fetched = nil
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--show-path", "4821", "reports-hash"}))
+	assert.Equal(t, "/projects/reports/q3.pdf\n/projects/reports\n", stdout.String())
+	assert.Empty(t, fetched)
+
+	// A file hash the cache doesn't know is decoded to its ID and checked
+	s.Cache.Remove("/projects/reports/q3.pdf")
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--show-path", "NDgyMXxwYWRkaW5n"}))
+	assert.Equal(t, "/projects/reports/q3.pdf\n", stdout.String())
+	assert.Equal(t, []int64{4821}, fetched)
+
+	err := cmd.Run(context.Background(), s, env, []string{"--show-path", "999", "nope"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 of 2 entries not found")
+}
+
+// ============================================================================
+// DEDUPE COMMAND TESTS
+// ============================================================================
+
+func TestDedupe_ListsAndTrashesDuplicates(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	env.Stdin = strings.NewReader("n\n")
+
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	photosID := int64(10)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: 1, Name: "a.jpg", Type: "image", Hash: "ha", Size: 5, CreatedAt: old.Add(time.Hour)},
+		{ID: 2, Name: "b.txt", Type: "text", Hash: "hb", Size: 5},
+		{ID: 3, Name: "unique.bin", Type: "file", Hash: "hu", Size: 9},
+		{ID: photosID, Name: "photos", Type: "folder"},
+	})
+	s.Cache.AddChildren("/photos", []api.FileEntry{
+		{ID: 4, Name: "a-copy.jpg", Type: "image", Hash: "hc", Size: 5, ParentID: &photosID, CreatedAt: old},
+	})
+	contents := map[string]string{"ha": "hello", "hb": "world", "hc": "hello", "hu": "different"}
+
+	var downloaded, deleted []string
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		downloaded = append(downloaded, hash)
+		_, err := io.WriteString(w, contents[hash])
+		return nil, err
+	}
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		for _, id := range entryIDs {
+			deleted = append(deleted, fmt.Sprint(id))
+		}
+		return nil
+	}
+
+	cmd, ok := commands.Get("dedupe")
+	require.True(t, ok)
+
+	// Nothing is downloaded without confirmation or within --max-download
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"/"}))
+	assert.Empty(t, downloaded)
+	err := cmd.Run(context.Background(), s, env, []string{"--max-download", "10", "/"})
+	assert.ErrorContains(t, err, "more than --max-download")
+	assert.Empty(t, downloaded)
+
+	// A dry run lists the sets and what would go, without deleting
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--delete", "-n", "--max-download", "1K", "/"}))
+	out := stdout.String()
+	assert.Contains(t, out, "2 copies of")
+	assert.Contains(t, out, "/photos/a-copy.jpg  (kept)", "the oldest copy is kept")
+	assert.Contains(t, out, "would delete: /a.jpg")
+	assert.Contains(t, out, "1 duplicate sets, 1 redundant files")
+	assert.NotContains(t, downloaded, "hu", "files without a same-size twin are never downloaded")
+	assert.Empty(t, deleted)
+
+	// Asked twice: before downloading and before deleting
+	env.Stdin = strings.NewReader("y\ny\n")
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--delete", "/"}))
+	assert.Equal(t, []string{"1"}, deleted)
+	_, ok = s.Cache.Get("/a.jpg")
+	assert.False(t, ok)
+	_, ok = s.Cache.Get("/photos/a-copy.jpg")
+	assert.True(t, ok)
+}
+
+func TestZip_CompressesFilesAndStoresIncompressible(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	text := strings.Repeat("all work and no play makes jack a dull boy\n", 200)
+	jpeg := "\xff\xd8\xff\xe0 not really a jpeg"
+	s.Cache.Add(&api.FileEntry{ID: 2, Name: "notes.txt", Type: "text", Hash: "notes-hash", Size: int64(len(text))}, "/notes.txt")
+	s.Cache.Add(&api.FileEntry{ID: 3, Name: "photo.jpg", Type: "image", Mime: "image/jpeg", Hash: "photo-hash", Size: int64(len(jpeg))}, "/photo.jpg")
+	s.Cache.Add(&api.FileEntry{ID: 4, Name: "docs", Type: "folder", Hash: "docs-hash"}, "/docs")
+	folder := buildZip(t, map[string]string{"a.txt": text, "sub/b.txt": "tiny"})
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		content := map[string]string{"notes-hash": text, "photo-hash": jpeg, "docs-hash": string(folder)}[hash]
+		_, err := io.WriteString(w, content)
+		return nil, err
+	}
+	var archive []byte
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		assert.Equal(t, "out.zip", name)
+		var err error
+		archive, err = io.ReadAll(reader)
+		return &api.FileEntry{ID: 10, Name: name, Type: "archive", Size: size}, err
+	}
+
+	cmd, ok := commands.Get("zip")
+	require.True(t, ok)
+	err := cmd.Run(context.Background(), s, env, []string{"--level", "9", "-j", "3", "out.zip", "notes.txt", "photo.jpg", "docs"})
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	methods := make(map[string]uint16)
+	for _, f := range zr.File {
+		methods[f.Name] = f.Method
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err, f.Name)
+		want := map[string]string{"notes.txt": text, "photo.jpg": jpeg, "docs/a.txt": text, "docs/sub/b.txt": "tiny"}[f.Name]
+		assert.Equal(t, want, string(data), f.Name)
+	}
+	assert.Equal(t, zip.Deflate, methods["notes.txt"])
+	assert.Equal(t, zip.Deflate, methods["docs/a.txt"])
+	assert.Equal(t, zip.Store, methods["photo.jpg"], "known incompressible type")
+	assert.Equal(t, zip.Store, methods["docs/sub/b.txt"], "compression wouldn't shrink it")
+	assert.Contains(t, methods, "docs/")
+	assert.Contains(t, stdout.String(), "adding: notes.txt (deflated")
+	assert.Contains(t, stdout.String(), "adding: photo.jpg (stored 0%)")
+}
+
+func TestZip_WritesEntriesInOrder(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	files := make(map[string]string)
+	var want []string
+	for i := range 12 {
+		name := fmt.Sprintf("f%02d.txt", i)
+		files[name] = strings.Repeat(name, 10*(12-i))
+	}
+	folder := buildZip(t, files)
+	zr, err := zip.NewReader(bytes.NewReader(folder), int64(len(folder)))
+	require.NoError(t, err)
+	want = append(want, "a.txt", "docs/")
+	for _, f := range zr.File {
+		want = append(want, "docs/"+f.Name)
+	}
+	want = append(want, "z.txt")
+
+	s.Cache.Add(&api.FileEntry{ID: 2, Name: "a.txt", Type: "text", Hash: "a-hash", Size: 4000}, "/a.txt")
+	s.Cache.Add(&api.FileEntry{ID: 4, Name: "docs", Type: "folder", Hash: "docs-hash"}, "/docs")
+	s.Cache.Add(&api.FileEntry{ID: 5, Name: "z.txt", Type: "text", Hash: "z-hash", Size: 1}, "/z.txt")
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		if hash == "a-hash" {
+			// The first job finishes last
+			time.Sleep(20 * time.Millisecond)
+		}
+		content := map[string]string{"a-hash": strings.Repeat("a", 4000), "docs-hash": string(folder), "z-hash": "z"}[hash]
+		_, err := io.WriteString(w, content)
+		return nil, err
+	}
+	var archive []byte
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		var err error
+		archive, err = io.ReadAll(reader)
+		return &api.FileEntry{ID: 10, Name: name, Type: "archive", Size: size}, err
+	}
+
+	cmd, ok := commands.Get("zip")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-j", "8", "out.zip", "a.txt", "docs", "z.txt"}))
+
+	out, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	var got []string
+	for _, f := range out.File {
+		got = append(got, f.Name)
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestZip_RejectsInvalidLevel(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 2, Name: "notes.txt", Type: "text", Hash: "notes-hash"}, "/notes.txt")
+
+	cmd, ok := commands.Get("zip")
+	require.True(t, ok)
+	err := cmd.Run(context.Background(), s, env, []string{"--compress-level", "12", "out.zip", "notes.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid --level 12")
+}
+
+func TestCd_HomeDirectory(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 1, Name: "home", Type: "folder"}, "/home")
+	s.Cache.Add(&api.FileEntry{ID: 2, Name: "sub", Type: "folder"}, "/home/sub")
+	s.Cache.Add(&api.FileEntry{ID: 3, Name: "other", Type: "folder"}, "/other")
+	s.HomeDir = "/home"
+	s.CWD = "/other"
+
+	cmd, ok := commands.Get("cd")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, nil))
+	assert.Equal(t, "/home", s.CWD)
+
+	s.CWD = "/other"
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"~/sub"}))
+	assert.Equal(t, "/home/sub", s.CWD)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"$HOME"}))
+	assert.Equal(t, "/home", s.CWD)
+
+	err := cmd.Run(context.Background(), s, env, []string{"~sub"})
+	assert.ErrorContains(t, err, "No such file or directory")
+}
+
+func TestInfo_ReportsBuildAndUpdate(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.Username = "alice"
+	t.Setenv("HOME", t.TempDir())
+	defer commands.SetLatestUpdateForTest(func(context.Context) (string, error) { return "v9.9.9", nil })()
+
+	cmd, ok := commands.Get("info")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--json"}))
+
+	var info map[string]string
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &info))
+	assert.Equal(t, "v9.9.9", info["update_available"])
+	assert.Equal(t, "alice", info["user"])
+	assert.Equal(t, "default", info["workspace"])
+	assert.Equal(t, filepath.Join(os.Getenv("HOME"), ".drime-shell", "config.yaml"), info["config_file"])
+	assert.NotEmpty(t, info["go_version"])
+
+	stdout.Reset()
+	defer commands.SetLatestUpdateForTest(func(context.Context) (string, error) { return "", errors.New("offline") })()
+	require.NoError(t, cmd.Run(context.Background(), s, env, nil))
+	assert.Contains(t, stdout.String(), "could not check (offline)")
+	assert.Contains(t, stdout.String(), "alice")
+
+	assert.Error(t, cmd.Run(context.Background(), s, env, []string{"extra"}))
+}
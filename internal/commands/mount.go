@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	Register(&Command{
+		Name:        "mount",
+		Description: "Mount the drive as a local read-only filesystem (FUSE)",
+		Usage: `mount <local-dir>
+
+Exposes the current workspace as a read-only FUSE filesystem at <local-dir>.
+The command runs in the foreground; press Ctrl+C to unmount.
+
+Directory listings come from the shell's cache and are fetched on demand.
+File contents are downloaded lazily, 4 MB blocks at a time, with Range
+requests, and kept in a temporary file until the mount is closed. Only the
+blocks that are read are fetched.
+
+Requires a build with FUSE support (go build -tags fuse) on Linux or macOS,
+plus fuse/macFUSE installed on the system. Vault contents cannot be mounted.
+
+Examples:
+  mount /mnt/drime
+  mount ~/drime`,
+		Run: mountCmd,
+	})
+}
+
+func mountCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("mount", pflag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: mount <local-dir>")
+	}
+	if s.InVault {
+		return fmt.Errorf("mount: vault contents cannot be mounted")
+	}
+
+	dir, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("mount: %w", err)
+	}
+	return mountDrive(ctx, s, env, dir)
+}
@@ -0,0 +1,288 @@
+//go:build fuse && (linux || darwin)
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// mountBlockSize is the unit file content is fetched and spooled in, so a
+// small read still costs at most one request per block.
+const mountBlockSize = 4 * 1024 * 1024
+
+func mountDrive(ctx context.Context, s *session.Session, env *ExecutionEnv, dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("mount: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("mount: %s: Not a directory", dir)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "drime-mount-*")
+	if err != nil {
+		return fmt.Errorf("mount: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	root := &mountDir{s: s, path: "/", tmpDir: tmpDir}
+	server, err := fs.Mount(dir, root, &fs.Options{
+		MountOptions: fuse.MountOptions{
+			FsName: "drime",
+			Name:   "drime",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("mount: %w", err)
+	}
+
+	fmt.Fprintf(env.Stdout, "Mounted at %s (read-only). Press Ctrl+C to unmount.\n", dir)
+
+	done := make(chan struct{})
+	go func() {
+		server.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		if err := server.Unmount(); err != nil {
+			return fmt.Errorf("mount: unmount %s: %w", dir, err)
+		}
+		<-done
+	case <-done:
+	}
+
+	fmt.Fprintf(env.Stdout, "Unmounted %s\n", dir)
+	return nil
+}
+
+// mountDir is a remote folder. Listings go through the session cache.
+type mountDir struct {
+	fs.Inode
+	s      *session.Session
+	path   string
+	tmpDir string
+}
+
+var (
+	_ fs.NodeReaddirer = (*mountDir)(nil)
+	_ fs.NodeLookuper  = (*mountDir)(nil)
+	_ fs.NodeGetattrer = (*mountDir)(nil)
+)
+
+func (d *mountDir) children(ctx context.Context) ([]api.FileEntry, syscall.Errno) {
+	if d.s.Cache.HasChildren(d.path) {
+		return d.s.Cache.GetChildren(d.path), 0
+	}
+
+	entry, ok := d.s.Cache.Get(d.path)
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	parentID := parentIDPtr(entry)
+	entries, err := d.s.Client.ListByParentIDWithOptions(ctx, parentID, api.ListOptions(d.s.WorkspaceID))
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	d.s.Cache.AddChildren(d.path, entries)
+	return entries, 0
+}
+
+func (d *mountDir) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	entries, errno := d.children(ctx)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	list := make([]fuse.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		mode := uint32(fuse.S_IFREG)
+		if e.Type == "folder" {
+			mode = fuse.S_IFDIR
+		}
+		list = append(list, fuse.DirEntry{Name: e.Name, Mode: mode, Ino: uint64(e.ID)})
+	}
+	return fs.NewListDirStream(list), 0
+}
+
+func (d *mountDir) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	entries, errno := d.children(ctx)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	for i := range entries {
+		e := entries[i]
+		if e.Name != name {
+			continue
+		}
+		childPath := path.Join(d.path, name)
+		fillAttr(&e, &out.Attr)
+		if e.Type == "folder" {
+			child := &mountDir{s: d.s, path: childPath, tmpDir: d.tmpDir}
+			return d.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: uint64(e.ID)}), 0
+		}
+		child := &mountFile{s: d.s, entry: e, tmpDir: d.tmpDir}
+		return d.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFREG, Ino: uint64(e.ID)}), 0
+	}
+	return nil, syscall.ENOENT
+}
+
+func (d *mountDir) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if entry, ok := d.s.Cache.Get(d.path); ok {
+		fillAttr(entry, &out.Attr)
+	}
+	out.Mode = fuse.S_IFDIR | 0o555
+	return 0
+}
+
+// mountFile is a remote file. Its content is fetched on demand in blocks of
+// mountBlockSize with Range requests and spooled to a sparse temp file that
+// serves later reads of the same blocks.
+type mountFile struct {
+	fs.Inode
+	s      *session.Session
+	entry  api.FileEntry
+	tmpDir string
+
+	mu      sync.Mutex
+	spool   *os.File
+	fetched map[int64]bool // indexes of the blocks present in spool
+}
+
+var (
+	_ fs.NodeGetattrer = (*mountFile)(nil)
+	_ fs.NodeOpener    = (*mountFile)(nil)
+	_ fs.NodeReader    = (*mountFile)(nil)
+)
+
+func (f *mountFile) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	fillAttr(&f.entry, &out.Attr)
+	return 0
+}
+
+func (f *mountFile) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR|syscall.O_APPEND|syscall.O_TRUNC) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	return nil, fuse.FOPEN_KEEP_CACHE, 0
+}
+
+func (f *mountFile) Read(ctx context.Context, fh fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if off >= f.entry.Size {
+		return fuse.ReadResultData(nil), 0
+	}
+	end := off + int64(len(dest))
+	if end > f.entry.Size {
+		end = f.entry.Size
+	}
+
+	if err := f.fill(ctx, off, end); err != nil {
+		return nil, syscall.EIO
+	}
+
+	n, err := f.spool.ReadAt(dest[:end-off], off)
+	if err != nil && n == 0 {
+		return nil, syscall.EIO
+	}
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+// fill fetches the blocks covering [off, end) that aren't spooled yet, each
+// run of missing blocks with one Range request.
+func (f *mountFile) fill(ctx context.Context, off, end int64) error {
+	if f.spool == nil {
+		spool, err := os.CreateTemp(f.tmpDir, "entry-*")
+		if err != nil {
+			return err
+		}
+		f.spool = spool
+		f.fetched = make(map[int64]bool)
+	}
+
+	last := (end - 1) / mountBlockSize
+	for block := off / mountBlockSize; block <= last; block++ {
+		if f.fetched[block] {
+			continue
+		}
+		run := block
+		for run < last && !f.fetched[run+1] {
+			run++
+		}
+		if err := f.fetchBlocks(ctx, block, run); err != nil {
+			return err
+		}
+		block = run
+	}
+	return nil
+}
+
+// fetchBlocks downloads blocks first through last into the spool. The server
+// must answer with exactly that range: a whole file sent instead would land
+// at the wrong offset.
+func (f *mountFile) fetchBlocks(ctx context.Context, first, last int64) error {
+	start := first * mountBlockSize
+	stop := (last + 1) * mountBlockSize
+	if stop > f.entry.Size {
+		stop = f.entry.Size
+	}
+	w := &spoolWriter{f: f.spool, off: start, limit: stop}
+	opts := &api.DownloadOptions{ResumeFrom: start, Length: stop - start, RequireRange: true}
+	if _, err := f.s.Client.DownloadWithOptions(ctx, f.entry.Hash, w, nil, opts); err != nil {
+		return err
+	}
+	if w.off != stop {
+		return fmt.Errorf("short read: got %d of %d bytes", w.off-start, stop-start)
+	}
+	for block := first; block <= last; block++ {
+		f.fetched[block] = true
+	}
+	return nil
+}
+
+// spoolWriter writes a downloaded range into the spool at its offset,
+// failing if the server sends more than was asked for.
+type spoolWriter struct {
+	f     *os.File
+	off   int64
+	limit int64
+}
+
+func (w *spoolWriter) Write(p []byte) (int, error) {
+	if w.off+int64(len(p)) > w.limit {
+		return 0, errors.New("server sent more than the requested range")
+	}
+	n, err := w.f.WriteAt(p, w.off)
+	w.off += int64(n)
+	return n, err
+}
+
+func fillAttr(e *api.FileEntry, attr *fuse.Attr) {
+	attr.Ino = uint64(e.ID)
+	if e.Type == "folder" {
+		attr.Mode = fuse.S_IFDIR | 0o555
+	} else {
+		attr.Mode = fuse.S_IFREG | 0o444
+		attr.Size = uint64(e.Size)
+	}
+	mtime := e.UpdatedAt
+	if mtime.IsZero() {
+		mtime = e.CreatedAt
+	}
+	attr.SetTimes(nil, &mtime, &mtime)
+}
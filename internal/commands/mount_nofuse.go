@@ -0,0 +1,14 @@
+//go:build !fuse || !(linux || darwin)
+
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gYonder/drime-shell/internal/session"
+)
+
+func mountDrive(ctx context.Context, s *session.Session, env *ExecutionEnv, dir string) error {
+	return fmt.Errorf("mount: FUSE support is not included in this build (rebuild with -tags fuse on Linux or macOS)")
+}
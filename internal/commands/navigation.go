@@ -1,18 +1,22 @@
 package commands
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/session"
 	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/gYonder/drime-shell/internal/util"
 	"github.com/spf13/pflag"
 )
 
@@ -26,21 +30,34 @@ func init() {
 	Register(&Command{
 		Name:        "ls",
 		Description: "List directory contents",
-		Usage:       "ls [-l] [-a] [path]\n\nOptions:\n  -l    Long listing format (size, owner, date, name, starred)\n  -a    Show hidden files (starting with .)\n\nExamples:\n  ls           List current directory\n  ls -la       Long format with hidden files\n  ls /Photos   List specific directory",
+		Usage:       "ls [-l] [-a] [-F] [-i] [--hash] [--color=WHEN] [--include-deleted] [--no-cache] [--full-names]\n          [--count] [-h | --block-size=SIZE | --bytes] [--since DATE] [--until DATE] [--newer-than AGE]\n          [--type TYPES] [path]\n\nShort listings are laid out in columns sized to the terminal width (80 when\nunknown). In long format, names too long for the terminal are shortened\nwith an ellipsis unless --full-names is given. Long-format sizes are\nhuman-readable (1.5 MB) unless --block-size or --bytes is given.\n\nOptions:\n  -l                 Long listing format (size, owner, date, name, starred)\n  -h, --human-readable  Sizes in powers of 1024 with a unit (the default)\n  --block-size=SIZE  Sizes in units of SIZE, rounded up: K, M, G (shown with\n                     the unit) or a size like 1M or 4096 (shown bare)\n  --bytes            Sizes in bytes\n  -a                 Show hidden files (starting with .)\n  -F                 Append indicator: / folder, * executable, @ shared\n  -i, --inode        Show each entry's numeric ID\n  --hash             Show each entry's hash\n  --color=WHEN       Colorize names: always, never or auto (default auto)\n  --include-deleted  Also list trashed entries, marked [deleted #ID]\n  --no-cache         List from the server instead of the cache\n  --full-names       Never shorten names in long format\n  --count            With -l, show how many items each folder holds; folders\n                     not listed yet are counted by the server, several at\n                     once, and the counts kept in the cache\n  --since DATE       Only entries modified at or after DATE (YYYY-MM-DD, today, ...)\n  --until DATE       Only entries modified before DATE\n  --newer-than AGE   Only entries modified within AGE (30m, 12h, 7d, 2w)\n  --type TYPES       Only files of these comma-separated categories (image,\n                     video, audio, document, archive, code) or MIME types\n                     (image/png, image/*); the extension decides when the\n                     MIME type is unknown or generic\n\nExamples:\n  ls                        List current directory\n  ls -la                    Long format with hidden files\n  ls -F /Photos             List specific directory with indicators\n  ls --color=always | less  Keep colors when piping\n  ls -i --hash              Grab IDs and hashes for API calls\n  ls -l --newer-than 7d     What changed this week\n  ls -l --block-size=M      Sizes in whole megabytes\n  ls -l --count /Backups    Spot folders holding huge numbers of files\n  ls --type image,video     Only photos and videos\n  ls --include-deleted      Show trashed items inline (restore with 'trash restore #ID')",
 		Run:         ls,
+		OwnsShortH:  true,
 	})
 	Register(&Command{
 		Name:        "cd",
 		Description: "Change directory",
-		Usage:       "cd [path]\n\nSpecial paths:\n  ~            Home directory\n  -            Previous directory\n  ..           Parent directory\n  .            Current directory",
+		Usage:       "cd [path]\n\nWithout a path, goes to the home directory.\n\nSpecial paths:\n  ~, $HOME     Home directory (also ~/sub, $HOME/sub in any command)\n  -            Previous directory\n  ..           Parent directory\n  .            Current directory\n  @name        Folder containing the file called name (see cdf)",
 		Run:         cd,
 	})
+	Register(&Command{
+		Name:        "cdf",
+		Description: "Change to the directory containing a file",
+		Usage:       "cdf <name> [n]\n\nSearches for files called name (a case-insensitive exact match, else names\ncontaining it) and changes to the folder holding it. The workspace is\nsearched on the server; in the vault, the folders seen so far are. When\nseveral files match they are listed with a number: pick one at the prompt,\nor run cdf again with that number. 'cd @name' does the same.\n\nExamples:\n  cdf report.pdf       Go to the folder holding report.pdf\n  cdf invoice 2        Go to the second match\n  cd @notes.md         Same as cdf notes.md",
+		Run:         cdf,
+	})
 	Register(&Command{
 		Name:        "pwd",
 		Description: "Print current working directory",
-		Usage:       "pwd",
+		Usage:       "pwd [-P] [--copy]\n\nOptions:\n  -P, --physical  Ask the server for the folder's canonical path (catches\n                  renames or moves made elsewhere)\n  -c, --copy      Also copy the path to the clipboard",
 		Run:         pwd,
 	})
+	Register(&Command{
+		Name:        "realpath",
+		Description: "Print the absolute remote path",
+		Usage:       "realpath [-m] [--copy] <path>...\n\nPrints the cleaned absolute remote path. Fails for paths that do not exist\nunless -m is given.\n\nOptions:\n  -m, --canonicalize-missing  Allow paths that do not exist\n  -c, --copy                  Also copy the path(s) to the clipboard\n\nExamples:\n  realpath ../docs        Print /parent/docs\n  realpath -m new/dir     Print the path even though it does not exist yet\n  realpath -c report.pdf  Print and copy the full path",
+		Run:         realpath,
+	})
 	Register(&Command{
 		Name:        "exit",
 		Description: "Exit the shell",
@@ -54,6 +71,20 @@ func ls(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 	showAll := fs.BoolP("all", "a", false, "show hidden files")
 	longFormat := fs.BoolP("long", "l", false, "use long listing format")
 	starredOnly := fs.BoolP("starred", "S", false, "show only starred files")
+	classify := fs.BoolP("classify", "F", false, "append indicator (one of /*@) to entries")
+	color := fs.String("color", "auto", "colorize names: always, never or auto")
+	fs.Lookup("color").NoOptDefVal = "always"
+	includeDeleted := fs.Bool("include-deleted", false, "also list trashed entries")
+	showID := fs.BoolP("inode", "i", false, "show entry IDs")
+	showHash := fs.Bool("hash", false, "show entry hashes")
+	noCache := fs.Bool("no-cache", false, "list from the API instead of the cache")
+	fullNames := fs.Bool("full-names", false, "never shorten names in long format")
+	human := fs.BoolP("human-readable", "h", false, "human-readable sizes (the default)")
+	blockSize := fs.String("block-size", "", "show sizes in units of SIZE")
+	rawBytes := fs.Bool("bytes", false, "show sizes in bytes")
+	window := addTimeWindowFlags(fs)
+	typeSpec := fs.String("type", "", "only files of these categories or MIME types")
+	countChildren := fs.Bool("count", false, "with -l, show the number of items in each folder")
 
 	// Set output of flag set to env.Stderr for usage?
 	fs.SetOutput(env.Stderr)
@@ -67,16 +98,63 @@ func ls(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 		paths = []string{"."}
 	}
 
+	colorMode, err := ui.ParseColorMode(*color)
+	if err != nil {
+		return fmt.Errorf("ls: %w", err)
+	}
+	modified, err := window.parse()
+	if err != nil {
+		return fmt.Errorf("ls: %w", err)
+	}
+	types, err := parseTypeFilter(*typeSpec)
+	if err != nil {
+		return fmt.Errorf("ls: --type: %w", err)
+	}
+	if *includeDeleted && s.InVault {
+		return fmt.Errorf("ls: --include-deleted: the vault has no trash")
+	}
+	if *countChildren && !*longFormat {
+		return fmt.Errorf("ls: --count requires -l")
+	}
+	sizeFormat := formatSize
+	switch {
+	case (*human && (*blockSize != "" || *rawBytes)) || (*blockSize != "" && *rawBytes):
+		return fmt.Errorf("ls: -h, --block-size and --bytes are mutually exclusive")
+	case *rawBytes:
+		sizeFormat = func(n int64) string { return strconv.FormatInt(n, 10) }
+	case *blockSize != "":
+		if sizeFormat, err = blockSizeFormat(*blockSize); err != nil {
+			return fmt.Errorf("ls: %w", err)
+		}
+	}
+
 	opts := &listPathOptions{
-		showAll:     *showAll,
-		longFormat:  *longFormat,
-		starredOnly: *starredOnly,
+		showAll:        *showAll,
+		longFormat:     *longFormat,
+		starredOnly:    *starredOnly,
+		classify:       *classify,
+		includeDeleted: *includeDeleted,
+		showID:         *showID,
+		showHash:       *showHash,
+		fullNames:      *fullNames,
+		countChildren:  *countChildren,
+		modified:       modified,
+		types:          types,
+		width:          ui.TerminalWidth(env.Stdout),
+		styleName:      ui.NameStyler(colorMode, env.Stdout),
+		sizeFormat:     sizeFormat,
 	}
 
 	for i, path := range paths {
 		// If multiple args and this is a directory, print header?
 		// We can peek at cache.
 		resolved, err := s.ResolvePathArg(path)
+		if err == nil && *noCache {
+			if rerr := refreshPath(ctx, s, resolved); rerr != nil {
+				fmt.Fprintf(env.Stderr, "ls: %v\n", rerr)
+				continue
+			}
+		}
 		if err == nil {
 			if entry, ok := s.Cache.Get(resolved); ok && entry.Type == "folder" && len(paths) > 1 {
 				fmt.Fprintf(env.Stdout, "%s:\n", path)
@@ -104,9 +182,77 @@ func ls(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 
 // listPathOptions controls the behavior of listPathWithOpts
 type listPathOptions struct {
-	showAll     bool
-	longFormat  bool
-	starredOnly bool
+	styleName      func(name, fileType string) string // nil renders with ui.StyleName
+	showAll        bool
+	longFormat     bool
+	starredOnly    bool
+	classify       bool
+	includeDeleted bool               // merge trashed entries into listings
+	showID         bool               // -i: show FileEntry.ID
+	showHash       bool               // --hash: show FileEntry.Hash
+	fullNames      bool               // --full-names: never ellipsize long-format names
+	countChildren  bool               // --count: show the number of items in folders
+	childCounts    map[string]int     // item counts by row name, filled by loadChildCounts
+	width          int                // terminal width in columns, 0 if unknown
+	modified       timeWindow         // --since/--until/--newer-than
+	types          typeFilter         // --type
+	sizeFormat     func(int64) string // long-format sizes; nil is formatSize
+}
+
+// formatEntrySize formats a long-format size as chosen by -h, --block-size
+// or --bytes.
+func (o *listPathOptions) formatEntrySize(n int64) string {
+	if o.sizeFormat == nil {
+		return formatSize(n)
+	}
+	return o.sizeFormat(n)
+}
+
+// renderName styles an entry name and, with -F, appends its indicator
+// outside the styling so it stays uncolored as in GNU ls. Trashed entries
+// are struck through and tagged with the ID 'trash restore' accepts.
+func (o *listPathOptions) renderName(name string, e *api.FileEntry) string {
+	style := ui.StyleName
+	if o.styleName != nil {
+		style = o.styleName
+	}
+	fileType := e.Type
+	if e.IsInTrash() {
+		fileType = ui.TrashedType
+	}
+	rendered := style(name, fileType)
+	if o.classify {
+		rendered += classifyIndicator(e)
+	}
+	if e.IsInTrash() {
+		rendered += fmt.Sprintf(" [deleted #%d]", e.ID)
+	}
+	return rendered
+}
+
+// classifyIndicator returns the ls -F suffix for an entry: "@" for entries
+// shared with others or via a public link, "/" for folders, "*" for
+// executables and "" otherwise.
+func classifyIndicator(e *api.FileEntry) string {
+	switch {
+	case e.Public || len(e.Users) > 1:
+		return "@"
+	case e.Type == "folder":
+		return "/"
+	case isExecutableMime(e.Mime):
+		return "*"
+	}
+	return ""
+}
+
+func isExecutableMime(mime string) bool {
+	switch mime {
+	case "application/x-executable", "application/x-elf", "application/x-sharedlib",
+		"application/x-mach-binary", "application/x-msdownload", "application/vnd.microsoft.portable-executable",
+		"application/x-sh", "text/x-shellscript", "application/x-shellscript":
+		return true
+	}
+	return false
 }
 
 func listPathWithOpts(ctx context.Context, s *session.Session, path string, opts *listPathOptions, w io.Writer) error {
@@ -117,6 +263,9 @@ func listPathWithOpts(ctx context.Context, s *session.Session, path string, opts
 
 	// Check if path exists in cache
 	entry, ok := s.Cache.Get(resolved)
+	if !ok && opts.includeDeleted {
+		entry, ok = findDeletedEntry(ctx, s, resolved)
+	}
 	if !ok {
 		return fmt.Errorf("ls: cannot access '%s': No such file or directory", path)
 	}
@@ -126,10 +275,7 @@ func listPathWithOpts(ctx context.Context, s *session.Session, path string, opts
 	if entry.Type == "folder" {
 		// For starred-only listing, always fetch from API with the filter
 		if opts.starredOnly {
-			var parentID *int64
-			if resolved != "/" {
-				parentID = &entry.ID
-			}
+			parentID := parentIDPtr(entry)
 			apiOpts := api.ListOptions(s.WorkspaceID).WithStarredOnly()
 			children, err := ui.WithSpinner(w, "", false, func() ([]api.FileEntry, error) {
 				return s.Client.ListByParentIDWithOptions(ctx, parentID, apiOpts)
@@ -143,10 +289,7 @@ func listPathWithOpts(ctx context.Context, s *session.Session, path string, opts
 			entries = cached
 		} else {
 			// Fetch from API (with spinner for slow requests)
-			var parentID *int64
-			if resolved != "/" {
-				parentID = &entry.ID
-			}
+			parentID := parentIDPtr(entry)
 
 			var children []api.FileEntry
 			if s.InVault {
@@ -172,6 +315,13 @@ func listPathWithOpts(ctx context.Context, s *session.Session, path string, opts
 			// Update cache with fetched entries
 			s.Cache.AddChildren(resolved, children)
 		}
+		if opts.includeDeleted && !entry.IsInTrash() {
+			deleted, err := listDeletedChildren(ctx, s, resolved, entry)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, deleted...)
+		}
 	} else {
 		// Just list the file itself
 		entries = []api.FileEntry{*entry}
@@ -188,83 +338,200 @@ func listPathWithOpts(ctx context.Context, s *session.Session, path string, opts
 		entries = filtered
 	}
 
+	entries = opts.modified.filter(entries)
+	entries = opts.types.filter(entries)
+
 	// Sort by name
 	sort.Slice(entries, func(i, j int) bool {
 		return entries[i].Name < entries[j].Name
 	})
 
 	if opts.longFormat {
-		return printLong(s, resolved, entries, opts.showAll, w)
+		if opts.countChildren {
+			counts, err := loadChildCounts(ctx, s, resolved, entries, opts.showAll, w)
+			if err != nil {
+				return fmt.Errorf("ls: --count: %w", err)
+			}
+			opts.childCounts = counts
+		}
+		return printLong(s, resolved, entries, opts, w)
 	}
 
 	// Short format - only show . and .. with -a flag
-	var names []string
+	var listed []namedEntry
 	if opts.showAll {
-		names = append(names, ui.DirStyle.Render("."))
-		names = append(names, ui.DirStyle.Render(".."))
+		listed = append(listed, dotEntries(s, resolved)...)
 	}
-	for _, e := range entries {
-		names = append(names, ui.StyleName(e.Name, e.Type))
+	for i := range entries {
+		listed = append(listed, namedEntry{name: entries[i].Name, entry: &entries[i]})
+	}
+
+	var ids, hashes []string
+	for _, n := range listed {
+		ids = append(ids, entryID(n.entry))
+		hashes = append(hashes, entryHash(n.entry))
 	}
+	wID, wHash := maxVisibleLen(ids), maxVisibleLen(hashes)
 
-	printColumns(names, w)
+	names := make([]string, 0, len(listed))
+	for i, n := range listed {
+		name := opts.renderName(n.name, n.entry)
+		// Prefix identifiers like GNU ls -i, padded so names stay aligned
+		if opts.showHash {
+			name = padRightVisible(hashes[i], wHash) + " " + name
+		}
+		if opts.showID {
+			name = padLeftVisible(ids[i], wID) + " " + name
+		}
+		names = append(names, name)
+	}
+
+	printColumns(names, w, opts.width)
 	return nil
 }
 
-// printColumns prints names in columns, similar to ls (column-major order)
-func printColumns(names []string, w io.Writer) {
-	if len(names) == 0 {
-		return
+// namedEntry is an entry listed under a display name ("." and ".." differ
+// from the entry's own name).
+type namedEntry struct {
+	name  string
+	entry *api.FileEntry
+}
+
+// dotEntries returns the "." and ".." entries for dirPath, falling back to
+// bare folders when they aren't cached (e.g. the root's parent).
+func dotEntries(s *session.Session, dirPath string) []namedEntry {
+	current, ok := s.Cache.Get(dirPath)
+	if !ok {
+		current = &api.FileEntry{Type: "folder"}
 	}
+	parent := current
+	if dirPath != "/" {
+		if p, ok := s.Cache.Get(filepath.Dir(dirPath)); ok {
+			parent = p
+		}
+	}
+	return []namedEntry{{name: ".", entry: current}, {name: "..", entry: parent}}
+}
+
+// entryID formats an entry's ID for ls -i.
+func entryID(e *api.FileEntry) string {
+	return fmt.Sprintf("%d", e.ID)
+}
 
-	// Get terminal width (default to 80 if unknown)
-	termWidth := 80
+// entryHash formats an entry's hash for ls --hash, "-" when it has none.
+func entryHash(e *api.FileEntry) string {
+	if e.Hash == "" {
+		return "-"
+	}
+	return e.Hash
+}
 
-	// Find the longest visible name (excluding ANSI codes)
-	maxLen := 0
-	for _, name := range names {
-		vLen := ui.VisibleLen(name)
-		if vLen > maxLen {
-			maxLen = vLen
+func maxVisibleLen(values []string) int {
+	width := 0
+	for _, v := range values {
+		if l := ui.VisibleLen(v); l > width {
+			width = l
 		}
 	}
+	return width
+}
 
-	// Column width = longest name + 2 spaces padding
-	colWidth := maxLen + 2
-	if colWidth < 1 {
-		colWidth = 1
+// listDeletedChildren returns the trashed direct children of the folder at
+// dirPath. They are never added to the cache.
+func listDeletedChildren(ctx context.Context, s *session.Session, dirPath string, dir *api.FileEntry) ([]api.FileEntry, error) {
+	parentID := parentIDPtr(dir)
+	apiOpts := api.ListOptions(s.WorkspaceID).WithDeletedOnly()
+	children, err := s.Client.ListByParentIDWithOptions(ctx, parentID, apiOpts)
+	if err != nil {
+		return nil, err
+	}
+	deleted := children[:0]
+	for _, c := range children {
+		if c.IsInTrash() {
+			deleted = append(deleted, c)
+		}
+	}
+	return deleted, nil
+}
+
+// findDeletedEntry resolves a path that isn't cached to a trashed entry in
+// its (live) parent folder.
+func findDeletedEntry(ctx context.Context, s *session.Session, resolved string) (*api.FileEntry, bool) {
+	parentPath := filepath.Dir(resolved)
+	parent, ok := s.Cache.Get(parentPath)
+	if !ok || parent.Type != "folder" || resolved == "/" {
+		return nil, false
+	}
+	deleted, err := listDeletedChildren(ctx, s, parentPath, parent)
+	if err != nil {
+		return nil, false
+	}
+	name := filepath.Base(resolved)
+	for i := range deleted {
+		if deleted[i].Name == name {
+			return &deleted[i], true
+		}
 	}
+	return nil, false
+}
 
-	// Number of columns that fit
-	numCols := termWidth / colWidth
-	if numCols < 1 {
-		numCols = 1
+// defaultTermWidth is the layout width when the terminal size is unknown.
+const defaultTermWidth = 80
+
+// printColumns prints names in columns like GNU ls: column-major order, as
+// many columns as fit in termWidth (80 if 0), each only as wide as its
+// longest name plus two spaces.
+func printColumns(names []string, w io.Writer, termWidth int) {
+	if len(names) == 0 {
+		return
+	}
+	if termWidth <= 0 {
+		termWidth = defaultTermWidth
 	}
 
-	// Calculate number of rows needed
-	numRows := (len(names) + numCols - 1) / numCols
+	lens := make([]int, len(names))
+	for i, name := range names {
+		lens[i] = ui.VisibleLen(name)
+	}
+
+	// Try the most columns first; one column always fits
+	numRows, colWidths := len(names), []int{0}
+	for numCols := len(names); numCols > 1; numCols-- {
+		rows := (len(names) + numCols - 1) / numCols
+		if (len(names)+rows-1)/rows < numCols {
+			continue // Same row count as fewer columns, leaving one empty
+		}
+		widths := make([]int, numCols)
+		total := 0
+		for col := range widths {
+			for row := 0; row < rows; row++ {
+				if idx := col*rows + row; idx < len(names) && lens[idx] > widths[col] {
+					widths[col] = lens[idx]
+				}
+			}
+			total += widths[col]
+			if col < numCols-1 {
+				widths[col] += 2
+				total += 2
+			}
+		}
+		if total <= termWidth {
+			numRows, colWidths = rows, widths
+			break
+		}
+	}
 
-	// Print in column-major order (filling top-to-bottom, then left-to-right)
 	for row := 0; row < numRows; row++ {
-		for col := 0; col < numCols; col++ {
+		for col := range colWidths {
 			idx := col*numRows + row
 			if idx >= len(names) {
 				continue
 			}
-
-			name := names[idx]
-			padding := colWidth - ui.VisibleLen(name)
-			if padding < 0 {
-				padding = 0
-			}
-
 			// Last column or last item in row - no padding, just newline
-			isLastCol := col == numCols-1
-			isLastInRow := (col+1)*numRows+row >= len(names)
-			if isLastCol || isLastInRow {
-				fmt.Fprint(w, name)
+			if col == len(colWidths)-1 || (col+1)*numRows+row >= len(names) {
+				fmt.Fprint(w, names[idx])
 			} else {
-				fmt.Fprintf(w, "%s%s", name, strings.Repeat(" ", padding))
+				fmt.Fprint(w, padRightVisible(names[idx], colWidths[col]))
 			}
 		}
 		fmt.Fprintln(w)
@@ -272,11 +539,17 @@ func printColumns(names []string, w io.Writer) {
 }
 
 type longRow struct {
+	id    string
+	hash  string
 	size  string
+	count string
 	owner string
 	date  string
 	star  string
 	name  string
+
+	label string         // unstyled display name, kept for ellipsizing
+	entry *api.FileEntry // entry the row describes
 }
 
 func padLeftVisible(s string, width int) string {
@@ -295,8 +568,8 @@ func padRightVisible(s string, width int) string {
 	return s + strings.Repeat(" ", pad)
 }
 
-func buildLongRow(name string, e *api.FileEntry) longRow {
-	size := ui.SizeStyle.Render(formatSize(e.Size))
+func buildLongRow(name string, e *api.FileEntry, opts *listPathOptions) longRow {
+	size := ui.SizeStyle.Render(opts.formatEntrySize(e.Size))
 	owner := e.Owner()
 	if owner == "" {
 		owner = "-"
@@ -308,50 +581,203 @@ func buildLongRow(name string, e *api.FileEntry) longRow {
 	if e.IsStarred() {
 		star = "*"
 	}
-	styledName := ui.StyleName(name, e.Type)
-	return longRow{size: size, owner: owner, date: date, star: star, name: styledName}
+	count := ""
+	if n, ok := opts.childCounts[name]; ok && e.Type == "folder" {
+		count = ui.MutedStyle.Render(fmt.Sprintf("%d items", n))
+	}
+	styledName := opts.renderName(name, e)
+	return longRow{id: entryID(e), hash: entryHash(e), size: size, count: count, owner: owner, date: date, star: star, name: styledName, label: name, entry: e}
 }
 
-func printLong(s *session.Session, dirPath string, entries []api.FileEntry, showAll bool, w io.Writer) error {
+// loadChildCounts returns how many items each folder in entries holds, keyed
+// by name, plus "." and ".." with showAll. Listings already in the cache are
+// counted as they are; the other folders are counted by the server in
+// parallel behind a spinner, and the counts cached, so listing the same
+// folder again costs nothing.
+func loadChildCounts(ctx context.Context, s *session.Session, dirPath string, entries []api.FileEntry, showAll bool, w io.Writer) (map[string]int, error) {
+	folders := make(map[string]string) // row name -> folder path
+	for _, e := range entries {
+		if e.Type == "folder" && !e.IsInTrash() {
+			folders[e.Name] = path.Join(dirPath, e.Name)
+		}
+	}
+	if showAll {
+		folders["."] = dirPath
+		if dirPath != "/" {
+			folders[".."] = path.Dir(dirPath)
+		}
+	}
+
+	var missing []string
+	for _, p := range folders {
+		if _, ok := s.Cache.Count(p); !ok && !s.Cache.HasChildren(p) {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		msg := fmt.Sprintf("Counting items in %d folders...", len(missing))
+		err := ui.WithSpinnerErr(w, msg, false, func() error {
+			return countFolders(ctx, s, missing)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	counts := make(map[string]int, len(folders))
+	for name, p := range folders {
+		if s.Cache.HasChildren(p) {
+			counts[name] = len(s.Cache.GetChildren(p))
+		} else if n, ok := s.Cache.Count(p); ok {
+			counts[name] = n
+		}
+	}
+	return counts, nil
+}
+
+// countFolders caches the item counts of the folders at paths, asking the
+// server for several at once and stopping at the first error. The vault and
+// the root have no count endpoint, so those are listed instead.
+func countFolders(ctx context.Context, s *session.Session, paths []string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	var first firstError
+	var panics ui.PanicTrap
+	for range uploadWorkers(s, 0, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer panics.Catch(cancel)
+			for p := range queue {
+				if err := countFolder(ctx, s, p); err != nil {
+					first.set(fmt.Errorf("%s: %w", p, err), cancel)
+				}
+			}
+		}()
+	}
+feed:
+	for _, p := range paths {
+		select {
+		case queue <- p:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	panics.Rethrow()
+
+	if first.err != nil {
+		return first.err
+	}
+	return ctx.Err()
+}
+
+// countFolder caches the item count of the folder at p.
+func countFolder(ctx context.Context, s *session.Session, p string) error {
+	entry, ok := s.Cache.Get(p)
+	if !ok || entry.Type != "folder" {
+		return nil
+	}
+	if s.InVault || entry.IsRoot() {
+		return refreshListing(ctx, s, p, entry)
+	}
+	n, err := s.Client.GetFolderCount(ctx, entry.ID, s.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	s.Cache.SetCount(p, n)
+	return nil
+}
+
+// minNameWidth is the narrowest a long-format name is ellipsized to, however
+// little room the other columns leave.
+const minNameWidth = 16
+
+func printLong(s *session.Session, dirPath string, entries []api.FileEntry, opts *listPathOptions, w io.Writer) error {
 	// Calculate total size
 	var total int64
 	for _, e := range entries {
 		total += e.Size
 	}
-	fmt.Fprintf(w, "total %s\n", formatSize(total))
+	fmt.Fprintf(w, "total %s\n", opts.formatEntrySize(total))
 
 	rows := make([]longRow, 0, len(entries)+2)
 
 	// Show . and .. only with -a flag
-	if showAll {
+	if opts.showAll {
 		if currentEntry, ok := s.Cache.Get(dirPath); ok {
-			rows = append(rows, buildLongRow(".", currentEntry))
+			rows = append(rows, buildLongRow(".", currentEntry, opts))
 		}
 		if dirPath != "/" {
 			parentPath := filepath.Dir(dirPath)
 			if parentEntry, ok := s.Cache.Get(parentPath); ok {
-				rows = append(rows, buildLongRow("..", parentEntry))
+				rows = append(rows, buildLongRow("..", parentEntry, opts))
 			}
 		}
 	}
 
 	for _, e := range entries {
 		entry := e
-		rows = append(rows, buildLongRow(entry.Name, &entry))
+		rows = append(rows, buildLongRow(entry.Name, &entry, opts))
 	}
 
 	// Compute widths based on visible lengths (ANSI stripped)
-	wSize, wOwner, wDate, wName := 0, 0, 0, 0
+	wID, wHash, wSize, wCount, wOwner, wDate, wName := 0, 0, 0, 0, 0, 0, 0
 	for _, r := range rows {
+		if l := ui.VisibleLen(r.id); l > wID {
+			wID = l
+		}
+		if l := ui.VisibleLen(r.hash); l > wHash {
+			wHash = l
+		}
 		if l := ui.VisibleLen(r.size); l > wSize {
 			wSize = l
 		}
+		if l := ui.VisibleLen(r.count); l > wCount {
+			wCount = l
+		}
 		if l := ui.VisibleLen(r.owner); l > wOwner {
 			wOwner = l
 		}
 		if l := ui.VisibleLen(r.date); l > wDate {
 			wDate = l
 		}
+	}
+
+	// Shorten names that would overflow the terminal, keeping any suffix
+	// renderName adds (indicators, [deleted #ID]) intact.
+	if opts.width > 0 && !opts.fullNames {
+		fixed := wSize + wOwner + wDate + 3*2 + 2 + 1
+		if opts.showID {
+			fixed += wID + 2
+		}
+		if opts.showHash {
+			fixed += wHash + 2
+		}
+		if opts.countChildren {
+			fixed += wCount + 2
+		}
+		avail := opts.width - fixed
+		if avail < minNameWidth {
+			avail = minNameWidth
+		}
+		for i, r := range rows {
+			over := ui.VisibleLen(r.name) - avail
+			if over <= 0 {
+				continue
+			}
+			labelWidth := ui.VisibleLen(r.label) - over
+			if labelWidth < 1 {
+				labelWidth = 1
+			}
+			rows[i].name = opts.renderName(ui.Ellipsize(r.label, labelWidth), r.entry)
+		}
+	}
+	for _, r := range rows {
 		if l := ui.VisibleLen(r.name); l > wName {
 			wName = l
 		}
@@ -359,8 +785,18 @@ func printLong(s *session.Session, dirPath string, entries []api.FileEntry, show
 
 	// Render with fixed column start positions regardless of ANSI sequences.
 	for _, r := range rows {
-		line := padLeftVisible(r.size, wSize) + "  " +
-			padRightVisible(r.owner, wOwner) + "  " +
+		line := ""
+		if opts.showID {
+			line += padLeftVisible(r.id, wID) + "  "
+		}
+		if opts.showHash {
+			line += padRightVisible(r.hash, wHash) + "  "
+		}
+		line += padLeftVisible(r.size, wSize) + "  "
+		if opts.countChildren {
+			line += padLeftVisible(r.count, wCount) + "  "
+		}
+		line += padRightVisible(r.owner, wOwner) + "  " +
 			padRightVisible(r.date, wDate) + "  " +
 			padRightVisible(r.name, wName) + "  " +
 			r.star
@@ -384,11 +820,31 @@ func formatSize(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// blockSizeFormat returns the size formatter for ls --block-size=value. A
+// bare unit (K, M, G, ...) is printed after each size as GNU ls does; an
+// explicit size such as 1M or 4096 is not. Sizes are rounded up, so only
+// empty files show as 0.
+func blockSizeFormat(value string) (func(int64) string, error) {
+	suffix := ""
+	spec := strings.TrimSpace(value)
+	if spec != "" && (spec[0] < '0' || spec[0] > '9') {
+		suffix = strings.ToUpper(spec[:1])
+		spec = "1" + spec
+	}
+	unit, err := parseSize(spec)
+	if err != nil || unit <= 0 {
+		return nil, fmt.Errorf("invalid --block-size '%s' (e.g. K, M or 4096)", value)
+	}
+	return func(n int64) string {
+		return strconv.FormatInt((n+unit-1)/unit, 10) + suffix
+	}, nil
+}
+
 func cd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
 	var target string
 	if len(args) < 1 {
 		// cd without args goes to home directory
-		return cd(ctx, s, env, []string{s.HomeDir})
+		return cd(ctx, s, env, []string{s.Home()})
 	} else {
 		target = args[0]
 	}
@@ -409,7 +865,13 @@ func cd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 
 	// Verify it exists AND is a directory
 	entry, ok := s.Cache.Get(newPath)
+	if !ok && strings.HasPrefix(target, "@") && len(target) > 1 {
+		return cdf(ctx, s, env, []string{target[1:]})
+	}
 	if !ok {
+		if suggestion := suggestDirectory(s, newPath); suggestion != "" {
+			return fmt.Errorf("cd: %s: No such file or directory (did you mean '%s'?)", target, suggestion)
+		}
 		return fmt.Errorf("cd: %s: No such file or directory", target)
 	}
 	if entry.Type != "folder" {
@@ -425,6 +887,117 @@ func cd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []strin
 	return nil
 }
 
+func cdf(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	if len(args) < 1 || len(args) > 2 || args[0] == "" {
+		return fmt.Errorf("usage: cdf <name> [n]")
+	}
+	name := args[0]
+	pick := 0
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("cdf: invalid match number '%s'", args[1])
+		}
+		pick = n
+	}
+
+	matches, err := ui.WithSpinner(env.Stderr, "", false, func() ([]string, error) {
+		return findFilesByName(ctx, s, name)
+	})
+	if err != nil {
+		return fmt.Errorf("cdf: %w", err)
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("cdf: %s: no such file", name)
+	}
+	if pick > len(matches) {
+		return fmt.Errorf("cdf: %s: only %d matches", name, len(matches))
+	}
+
+	if pick == 0 && len(matches) > 1 {
+		for i, m := range matches {
+			fmt.Fprintf(env.Stdout, "%3d  %s\n", i+1, m)
+		}
+		if !isStdinTTY(env.Stdin) {
+			return fmt.Errorf("cdf: %d files match '%s'; run 'cdf %s <n>' to pick one", len(matches), name, name)
+		}
+		fmt.Fprintf(env.Stderr, "Go to which? [1-%d] ", len(matches))
+		response, _ := bufio.NewReader(env.Stdin).ReadString('\n')
+		n, err := strconv.Atoi(strings.TrimSpace(response))
+		if err != nil || n < 1 || n > len(matches) {
+			return fmt.Errorf("cdf: canceled")
+		}
+		pick = n
+	}
+	if pick == 0 {
+		pick = 1
+	}
+	return cd(ctx, s, env, []string{filepath.Dir(matches[pick-1])})
+}
+
+// findFilesByName returns the sorted paths of the files called name, matched
+// case-insensitively, or failing that of those whose name contains it. The
+// workspace is searched on the server, the vault in the cache; results whose
+// folder is unknown are left out.
+func findFilesByName(ctx context.Context, s *session.Session, name string) ([]string, error) {
+	var paths []string
+	if s.InVault {
+		for _, p := range s.Cache.AllPaths() {
+			if e, ok := s.Cache.Get(p); ok && e.Type != "folder" {
+				paths = append(paths, p)
+			}
+		}
+	} else {
+		entries, err := s.Client.SearchWithOptions(ctx, name, api.SearchOptions(s.WorkspaceID, name))
+		if err != nil {
+			return nil, err
+		}
+		for i := range entries {
+			if entries[i].Type == "folder" {
+				continue
+			}
+			if p, ok := searchResultPath(s, &entries[i]); ok {
+				paths = append(paths, p)
+			}
+		}
+	}
+
+	var exact, partial []string
+	needle := strings.ToLower(name)
+	for _, p := range paths {
+		base := strings.ToLower(filepath.Base(p))
+		switch {
+		case base == needle:
+			exact = append(exact, p)
+		case strings.Contains(base, needle):
+			partial = append(partial, p)
+		}
+	}
+	if len(exact) == 0 {
+		exact = partial
+	}
+	sort.Strings(exact)
+	return exact, nil
+}
+
+// suggestDirectory returns the name of the folder next to missingPath that is
+// most likely what the user meant, or "" if nothing is close. Only siblings
+// in the parent directory are compared, which the folder tree always has cached.
+func suggestDirectory(s *session.Session, missingPath string) string {
+	parentDir := filepath.Dir(missingPath)
+	if _, ok := s.Cache.Get(parentDir); !ok {
+		return ""
+	}
+
+	var names []string
+	for _, name := range s.Cache.ChildNames(parentDir) {
+		if e, ok := s.Cache.Get(filepath.Join(parentDir, name)); ok && e.Type == "folder" {
+			names = append(names, name)
+		}
+	}
+	return util.ClosestMatch(filepath.Base(missingPath), names)
+}
+
 // prefetchDirectory fetches directory contents in background.
 // depth controls how many levels deep to prefetch (0 = just this dir, 1 = this + children)
 func prefetchDirectory(s *session.Session, path string, depth int) {
@@ -461,10 +1034,7 @@ func prefetchDirectory(s *session.Session, path string, depth int) {
 		}
 		children, err = s.Client.ListVaultEntries(context.Background(), folderHash)
 	} else {
-		var parentID *int64
-		if path != "/" {
-			parentID = &entry.ID
-		}
+		parentID := parentIDPtr(entry)
 		apiOpts := api.ListOptions(s.WorkspaceID)
 		children, err = s.Client.ListByParentIDWithOptions(context.Background(), parentID, apiOpts)
 	}
@@ -492,11 +1062,99 @@ func prefetchDirectory(s *session.Session, path string, depth int) {
 }
 
 func pwd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
-	fmt.Fprintln(env.Stdout, s.VirtualCWD())
+	fs := pflag.NewFlagSet("pwd", pflag.ContinueOnError)
+	copyPath := fs.BoolP("copy", "c", false, "copy path to clipboard")
+	physical := fs.BoolP("physical", "P", false, "print the server's canonical path")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cwd := s.VirtualCWD()
+	if *physical {
+		canonical, err := canonicalFolderPath(ctx, s, cwd)
+		if err != nil {
+			return fmt.Errorf("pwd: %w", err)
+		}
+		cwd = canonical
+	}
+	fmt.Fprintln(env.Stdout, cwd)
+	if *copyPath {
+		copyToClipboard(env, cwd)
+	}
 	return nil
 }
 
+func realpath(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("realpath", pflag.ContinueOnError)
+	copyPath := fs.BoolP("copy", "c", false, "copy path to clipboard")
+	allowMissing := fs.BoolP("canonicalize-missing", "m", false, "allow paths that do not exist")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: realpath [-m] [--copy] <path>...")
+	}
+
+	var resolved []string
+	missing := 0
+	for _, arg := range fs.Args() {
+		p, err := s.ResolvePathArg(arg)
+		if err != nil {
+			return fmt.Errorf("realpath: %w", err)
+		}
+		if !*allowMissing {
+			if _, ok := s.Cache.Get(p); !ok {
+				fmt.Fprintf(env.Stderr, "realpath: %s: No such file or directory\n", arg)
+				missing++
+				continue
+			}
+		}
+		fmt.Fprintln(env.Stdout, p)
+		resolved = append(resolved, p)
+	}
+	if *copyPath && len(resolved) > 0 {
+		copyToClipboard(env, strings.Join(resolved, "\n"))
+	}
+	if missing > 0 {
+		return fmt.Errorf("realpath: %d of %d paths not found", missing, fs.NArg())
+	}
+	return nil
+}
+
+// canonicalFolderPath asks the server for the current path of the folder
+// cached at path, so renames or moves made by other clients show up. The
+// vault and the root have no server-side path lookup and are returned as is.
+func canonicalFolderPath(ctx context.Context, s *session.Session, path string) (string, error) {
+	if path == "/" || s.InVault {
+		return path, nil
+	}
+	entry, ok := s.Cache.Get(path)
+	if !ok {
+		return "", fmt.Errorf("%s: No such file or directory", path)
+	}
+	if entry.Hash == "" {
+		return path, nil
+	}
+
+	ancestors, err := s.Client.GetFolderPath(ctx, entry.Hash, s.WorkspaceID)
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(ancestors)+1)
+	for _, a := range ancestors {
+		names = append(names, a.Name)
+	}
+	// The breadcrumb normally ends with the folder itself; add it if not
+	if len(ancestors) == 0 || ancestors[len(ancestors)-1].ID != entry.ID {
+		names = append(names, entry.Name)
+	}
+	return "/" + strings.Join(names, "/"), nil
+}
+
 func exitCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	_ = s.SaveNameIndexes()
 	os.Exit(0)
 	return nil
 }
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/gYonder/drime-shell/internal/session"
 	"github.com/gYonder/drime-shell/internal/ui"
@@ -23,10 +25,93 @@ type Command struct {
 	Name        string
 	Description string
 	Usage       string // Detailed usage info shown by "help <command>"
+	OwnsShortH  bool   // -h is one of the command's options; only --help shows usage
+
+	// Mutates reports whether a run with args may change remote state, which
+	// puts it in the audit log (nil = never).
+	Mutates func(args []string) bool
+
+	// Flags declares the command's options without parsing anything, so the
+	// audit log can redact secret values given under short names, such as
+	// share -p (nil = only long names are recognised).
+	Flags func() *pflag.FlagSet
 }
 
 var Registry = make(map[string]*Command)
 
+// Hook observes command runs, e.g. to keep an audit trail. Before is called
+// ahead of a command and After once it returned, with its error. Hooks may
+// be called from several goroutines at once when commands run in a pipeline.
+type Hook interface {
+	Before(s *session.Session, name string, args []string)
+	After(s *session.Session, name string, args []string, err error, elapsed time.Duration)
+}
+
+var hooks []Hook
+
+// AddHook registers h to run around every command started through Execute.
+func AddHook(h Hook) {
+	hooks = append(hooks, h)
+}
+
+// Execute runs cmd with the registered hooks around it. A failing hook never
+// stops the command: panics are recovered and reported on env.Stderr. A
+// panicking command returns a *PanicError instead of ending the shell.
+func Execute(ctx context.Context, cmd *Command, s *session.Session, env *ExecutionEnv, args []string) error {
+	for _, h := range hooks {
+		callHook(env, func() { h.Before(s, cmd.Name, args) })
+	}
+	if s.ProgressMode != "" {
+		// Machine-readable progress on this command's stderr
+		if sink, err := ui.ProgressSinkFor(s.ProgressMode, env.Stderr); err == nil {
+			ctx = ui.WithProgressSink(ctx, sink)
+		}
+	}
+	start := time.Now()
+	err := runRecovered(ctx, cmd, s, env, args)
+	elapsed := time.Since(start)
+	for _, h := range hooks {
+		callHook(env, func() { h.After(s, cmd.Name, args, err, elapsed) })
+	}
+	return err
+}
+
+// RedirectCommand is the name hooks see for the upload behind an output
+// redirection ("> file"), which happens once the command itself returned.
+const RedirectCommand = "redirect"
+
+// NotifyHooks runs the hooks for a change made outside any command's Run,
+// such as the upload behind an output redirection, as a run of name.
+func NotifyHooks(s *session.Session, name string, args []string, err error, elapsed time.Duration) {
+	env := &ExecutionEnv{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+	for _, h := range hooks {
+		callHook(env, func() { h.Before(s, name, args) })
+	}
+	for _, h := range hooks {
+		callHook(env, func() { h.After(s, name, args, err, elapsed) })
+	}
+}
+
+// runRecovered runs cmd, turning a panic into a *PanicError so one broken
+// command doesn't take the session, and an unlocked vault, down with it.
+func runRecovered(ctx context.Context, cmd *Command, s *session.Session, env *ExecutionEnv, args []string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(cmd.Name, r)
+		}
+	}()
+	return cmd.Run(ctx, s, env, args)
+}
+
+func callHook(env *ExecutionEnv, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(env.Stderr, "warning: command hook failed: %v\n", r)
+		}
+	}()
+	fn()
+}
+
 // ReorderArgsForFlags reorders arguments so flags come before positional args.
 // This allows Unix-style interspersed flags like "cmd file.txt -f" to work
 // the same as "cmd -f file.txt".
@@ -38,8 +123,9 @@ func ReorderArgsForFlags(fs *pflag.FlagSet, args []string) []string {
 	for i < len(args) {
 		arg := args[i]
 		if arg == "--" {
-			// Everything after -- is positional
-			positional = append(positional, args[i+1:]...)
+			// Everything after -- is positional, and stays so for the
+			// parser: the -- goes in front of the positional arguments
+			positional = append(append([]string{"--"}, positional...), args[i+1:]...)
 			break
 		}
 		if strings.HasPrefix(arg, "-") && arg != "-" {
@@ -91,7 +177,7 @@ func init() {
 	Register(&Command{
 		Name:        "history",
 		Description: "Show command history",
-		Usage:       "history\\n\\nDisplays numbered list of previously executed commands.",
+		Usage:       "history [-s <file>]\n\nDisplays numbered list of previously executed commands.\n\nOptions:\n  -s, --save <file>  Write the history to a local file as a script for 'source'\n                     (history and exit commands are left out)\n\nExamples:\n  history\n  history -s session.drime",
 		Run:         history,
 	})
 }
@@ -119,6 +205,23 @@ func HasHelpFlag(args []string) bool {
 	return false
 }
 
+// WantsHelp reports whether args ask for cmd's usage rather than running
+// it. For commands that use -h themselves (ls -h) only --help does.
+func WantsHelp(cmd *Command, args []string) bool {
+	if !cmd.OwnsShortH {
+		return HasHelpFlag(args)
+	}
+	for _, arg := range args {
+		if arg == "--help" {
+			return true
+		}
+		if len(arg) > 0 && arg[0] != '-' {
+			break
+		}
+	}
+	return false
+}
+
 // PrintUsage prints usage information for a command to the given writer
 func PrintUsage(cmd *Command, w io.Writer) {
 	fmt.Fprintf(w, "%s - %s\n", ui.CommandStyle.Render(cmd.Name), cmd.Description)
@@ -188,7 +291,17 @@ func history(ctx context.Context, s *session.Session, env *ExecutionEnv, args []
 		return fmt.Errorf("history not available")
 	}
 
+	fs := pflag.NewFlagSet("history", pflag.ContinueOnError)
+	savePath := fs.StringP("save", "s", "", "write history to a script file")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
 	hist := s.HistoryGetter()
+	if *savePath != "" {
+		return saveHistoryScript(env, hist, *savePath)
+	}
 	if len(hist) == 0 {
 		fmt.Fprintln(env.Stdout, "No history.")
 		return nil
@@ -200,3 +313,25 @@ func history(ctx context.Context, s *session.Session, env *ExecutionEnv, args []
 	}
 	return nil
 }
+
+// saveHistoryScript writes hist to path as a script runnable with source,
+// leaving out history and exit commands that make no sense when replayed.
+func saveHistoryScript(env *ExecutionEnv, hist []string, path string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# drime-shell script, exported %s\n", time.Now().Format("2006-01-02 15:04"))
+	count := 0
+	for _, line := range hist {
+		name, _ := Parse(line)
+		if name == "history" || name == "exit" {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+		count++
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	fmt.Fprintf(env.Stdout, "Saved %d commands to %s\n", count, path)
+	return nil
+}
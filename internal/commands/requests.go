@@ -20,6 +20,7 @@ func init() {
 
 var requestCommand = &Command{
 	Name:        "request",
+	Mutates:     alwaysMutates,
 	Description: "Manage file requests",
 	Run: func(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
 		if len(args) == 0 {
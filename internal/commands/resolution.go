@@ -1,10 +1,14 @@
 package commands
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"path"
+	"strings"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/crypto"
@@ -25,6 +29,106 @@ func ResolveEntry(ctx context.Context, s *session.Session, arg string) (*api.Fil
 	return entry, nil
 }
 
+// parentIDPtr returns the parent ID that requests about the folder entry
+// take: nil for the root, which has no ID on the server, or a copy of the
+// entry's ID.
+func parentIDPtr(entry *api.FileEntry) *int64 {
+	if entry == nil || entry.IsRoot() {
+		return nil
+	}
+	id := entry.ID
+	return &id
+}
+
+// refreshListing lists the folder at dirPath from the API and replaces its
+// cached children with the result.
+func refreshListing(ctx context.Context, s *session.Session, dirPath string, dir *api.FileEntry) error {
+	var children []api.FileEntry
+	var err error
+	if s.InVault {
+		folderHash := ""
+		if dirPath != "/" {
+			folderHash = dir.Hash
+		}
+		children, err = s.Client.ListVaultEntries(ctx, folderHash)
+	} else {
+		parentID := parentIDPtr(dir)
+		children, err = s.Client.ListByParentIDWithOptions(ctx, parentID, api.ListOptions(s.WorkspaceID))
+	}
+	if err != nil {
+		return err
+	}
+	s.Cache.ReplaceChildren(dirPath, children)
+	return nil
+}
+
+// refreshPath re-lists every folder from the root down to resolved (itself
+// included when it is a folder), for --no-cache. Paths created, removed or
+// renamed by another client then resolve as the server sees them, however
+// deep they are.
+func refreshPath(ctx context.Context, s *session.Session, resolved string) error {
+	current := "/"
+	for {
+		entry, ok := s.Cache.Get(current)
+		if !ok || entry.Type != "folder" {
+			return nil
+		}
+		if err := refreshListing(ctx, s, current, entry); err != nil {
+			return fmt.Errorf("%s: %w", current, err)
+		}
+		if current == resolved {
+			return nil
+		}
+		rest := strings.TrimPrefix(strings.TrimPrefix(resolved, current), "/")
+		next, _, _ := strings.Cut(rest, "/")
+		current = path.Join(current, next)
+	}
+}
+
+// fillMetadata completes entry's hash and timestamps from the API if the
+// cache lacks them. The vault has no per-entry lookup, and failures leave the
+// entry unchanged; callers treat missing timestamps as unknown.
+func fillMetadata(ctx context.Context, s *session.Session, entry *api.FileEntry) {
+	if s.InVault {
+		return
+	}
+	_ = s.Cache.FillMetadata(ctx, s.Client, entry, s.WorkspaceID)
+}
+
+// readPathList reads remote paths from a local file, one per line, for the
+// --from-file batch options. "-" reads from stdin. Blank lines and lines
+// starting with '#' are ignored.
+func readPathList(env *ExecutionEnv, listPath string) ([]string, error) {
+	var r io.Reader
+	if listPath == "-" {
+		r = env.Stdin
+	} else {
+		f, err := os.Open(listPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%s: no paths listed", listPath)
+	}
+	return paths, nil
+}
+
 // DownloadAndDecrypt downloads a file, handling vault decryption automatically.
 // Returns the plaintext content as bytes.
 func DownloadAndDecrypt(ctx context.Context, s *session.Session, entry *api.FileEntry) ([]byte, error) {
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	Register(&Command{
+		Name:        "source",
+		Description: "Run shell commands from a local script file",
+		Usage: `source [--keep-going] <file>
+
+Reads a local file of shell commands and runs them one by one in the current
+session, so cd, workspace switches and aliases carry over between lines.
+Blank lines and lines starting with '#' are skipped. Execution stops at the
+first failing command unless --keep-going is given.
+Outside the interactive shell each line runs as a single command: pipes,
+redirections, command chains, quoting, globs and aliases need the shell.
+
+Options:
+  -k, --keep-going   Continue after a failing command and report failures at the end
+
+Examples:
+  history -s backup.drime    # Save this session's history as a script
+  source backup.drime        # Replay it
+  source -k nightly.drime`,
+		Run: source,
+	})
+}
+
+// maxSourceDepth bounds nested source calls so a script sourcing itself
+// fails instead of recursing forever.
+const maxSourceDepth = 16
+
+type sourceDepthKey struct{}
+
+func source(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("source", pflag.ContinueOnError)
+	keepGoing := fs.BoolP("keep-going", "k", false, "continue after a failing command")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: source [--keep-going] <file>")
+	}
+	run := s.LineRunner
+	if run == nil {
+		run = func(ctx context.Context, line string) error {
+			return runPlainLine(ctx, s, env, line)
+		}
+	}
+
+	depth, _ := ctx.Value(sourceDepthKey{}).(int)
+	if depth >= maxSourceDepth {
+		return fmt.Errorf("source: nested too deeply (max %d)", maxSourceDepth)
+	}
+	ctx = context.WithValue(ctx, sourceDepthKey{}, depth+1)
+
+	scriptPath := fs.Arg(0)
+	f, err := os.Open(scriptPath)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	defer f.Close()
+
+	failed := 0
+	lineNo := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := run(ctx, line); err != nil {
+			if !*keepGoing {
+				return fmt.Errorf("source: %s:%d: %w", scriptPath, lineNo, err)
+			}
+			fmt.Fprintf(env.Stderr, "source: %s:%d: %v\n", scriptPath, lineNo, err)
+			failed++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("source: %d commands failed", failed)
+	}
+	return nil
+}
+
+// runPlainLine runs a script line as one command with whitespace-separated
+// arguments, for source without the interactive shell to parse full lines.
+func runPlainLine(ctx context.Context, s *session.Session, env *ExecutionEnv, line string) error {
+	if strings.ContainsAny(line, "|<>;&\"'\\") {
+		return fmt.Errorf("pipes, redirections, command chains and quoting need the interactive shell")
+	}
+	name, args := Parse(line)
+	cmd, ok := Get(name)
+	if !ok {
+		return fmt.Errorf("%s: command not found", name)
+	}
+	return Execute(ctx, cmd, s, env, args)
+}
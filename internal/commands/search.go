@@ -1,8 +1,11 @@
 package commands
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -15,6 +18,7 @@ import (
 func init() {
 	Register(&Command{
 		Name:        "search",
+		Mutates:     flagMutates("delete", "star", "move"),
 		Description: "Search for files with advanced filtering",
 		Usage: `search [query] [flags]
 
@@ -26,16 +30,35 @@ Flags:
   --link             Show only files with a generated public link (Send & Track)
   --trash            Show only files in trash
   --starred          Show only starred files
+  --tag <name>       Show only entries with tag name (see tag ls)
   --after <date>     Show files created after date (YYYY-MM-DD, "today", "yesterday")
   --before <date>    Show files created before date
+  --since <date>     Show files modified at or after date
+  --until <date>     Show files modified before date
+  --newer-than <age> Show files modified within age (30m, 12h, 7d, 2w)
   --sort <field>     Sort by: name, size, created, updated (default: updated)
   --asc              Sort ascending
   --desc             Sort descending (default)
+  --include-vault    Also match vault entry names (vault must be unlocked);
+                     vault results are prefixed with "vault:"
+  --paths            Print only full paths, one per line (for pipes and xargs)
+
+Acting on results (after confirmation):
+  --delete           Move every match to the trash
+  --star             Star every match
+  --move <dest>      Move every match into folder dest
+  -y, --yes          Don't ask for confirmation
+                     (these need a query or at least one filter)
 
 Examples:
   search "project" --type image
   search --shared --type pdf
-  search --after 2023-01-01 --sort size`,
+  search --after 2023-01-01 --sort size
+  search report --newer-than 7d
+  search --tag starred --type pdf
+  search invoice --paths > invoices.txt
+  search invoice --type pdf --move /Archive/Invoices
+  search "tmp" --delete -y`,
 		Run: search,
 	})
 }
@@ -50,11 +73,19 @@ func search(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 	link := fs.Bool("link", false, "Show files with public link")
 	trash := fs.Bool("trash", false, "Show files in trash")
 	starred := fs.Bool("starred", false, "Show starred files")
+	tag := fs.String("tag", "", "Show entries with this tag")
 	after := fs.String("after", "", "Created after date")
 	before := fs.String("before", "", "Created before date")
 	sortBy := fs.String("sort", "updated", "Sort field")
 	asc := fs.Bool("asc", false, "Sort ascending")
 	desc := fs.Bool("desc", false, "Sort descending")
+	includeVault := fs.Bool("include-vault", false, "Also search vault entry names")
+	pathsOnly := fs.Bool("paths", false, "Print only full paths")
+	doDelete := fs.Bool("delete", false, "Move matches to the trash")
+	doStar := fs.Bool("star", false, "Star matches")
+	moveTo := fs.String("move", "", "Move matches into folder")
+	yes := fs.BoolP("yes", "y", false, "Don't ask for confirmation")
+	window := addTimeWindowFlags(fs)
 
 	fs.SetOutput(env.Stderr)
 	if err := fs.Parse(args); err != nil {
@@ -62,6 +93,31 @@ func search(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 	}
 
 	query := strings.Join(fs.Args(), " ")
+	modified, err := window.parse()
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+
+	action := ""
+	actions := 0
+	if *doDelete {
+		action = "delete"
+		actions++
+	}
+	if *doStar {
+		action = "star"
+		actions++
+	}
+	if *moveTo != "" {
+		action = "move"
+		actions++
+	}
+	if actions > 1 {
+		return fmt.Errorf("search: --delete, --star and --move are mutually exclusive")
+	}
+	if action != "" && (*includeVault || *trash) {
+		return fmt.Errorf("search: --%s can't be combined with --include-vault or --trash", action)
+	}
 
 	// Build filters
 	var filters []api.Filter
@@ -131,6 +187,13 @@ func search(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 		})
 	}
 
+	filters = append(filters, modified.filters()...)
+
+	// An empty search returns whatever the server lists, possibly everything
+	if action != "" && strings.TrimSpace(query) == "" && len(filters) == 0 && !*starred && *tag == "" {
+		return fmt.Errorf("search: --%s needs a query or at least one filter", action)
+	}
+
 	// Sorting
 	orderBy := "updated_at"
 	switch *sortBy {
@@ -166,6 +229,67 @@ func search(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 	if err != nil {
 		return err
 	}
+	entries = modified.filter(entries)
+	if *tag != "" {
+		// The search endpoint has no tag filter; entries carry their tags
+		tagged := entries[:0]
+		for _, e := range entries {
+			if e.HasTag(*tag) {
+				tagged = append(tagged, e)
+			}
+		}
+		entries = tagged
+	}
+
+	// Vault names are matched locally; contents are encrypted server-side
+	var vaultNames []string
+	if *includeVault {
+		if !s.IsVaultUnlocked() {
+			fmt.Fprintln(env.Stderr, "search: vault is locked, skipping vault results (run 'vault' to unlock)")
+		} else {
+			items, err := ui.WithSpinner(env.Stderr, "", false, func() ([]vaultItem, error) {
+				return walkVault(ctx, s)
+			})
+			if err != nil {
+				return fmt.Errorf("search: vault: %w", err)
+			}
+			needle := strings.ToLower(query)
+			for _, item := range items {
+				if needle != "" && !strings.Contains(strings.ToLower(item.Entry.Name), needle) {
+					continue
+				}
+				if *fileType != "" && item.Entry.Type != *fileType {
+					continue
+				}
+				if !modified.contains(item.Entry.UpdatedAt) {
+					continue
+				}
+				if *tag != "" && !item.Entry.HasTag(*tag) {
+					continue
+				}
+				entries = append(entries, item.Entry)
+				vaultNames = append(vaultNames, "vault:"+item.Path)
+			}
+		}
+	}
+	vaultStart := len(entries) - len(vaultNames)
+
+	if action != "" {
+		return actOnSearchResults(ctx, s, env, entries, action, *moveTo, *yes)
+	}
+
+	if *pathsOnly {
+		for i := range entries {
+			if i >= vaultStart {
+				fmt.Fprintln(env.Stdout, vaultNames[i-vaultStart])
+			} else if p, ok := searchResultPath(s, &entries[i]); ok {
+				fmt.Fprintln(env.Stdout, p)
+			} else {
+				fmt.Fprintf(env.Stderr, "search: %s: location unknown, skipped (#%d)\n", entries[i].Name, entries[i].ID)
+			}
+		}
+		return nil
+	}
 
 	if len(entries) == 0 {
 		fmt.Fprintln(env.Stdout, "No results found.")
@@ -203,7 +327,11 @@ func search(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 			maxDate = len(date)
 		}
 
-		rows[i] = struct{ size, owner, date, name string }{size, owner, date, e.Name}
+		name := e.Name
+		if i >= vaultStart {
+			name = vaultNames[i-vaultStart]
+		}
+		rows[i] = struct{ size, owner, date, name string }{size, owner, date, name}
 	}
 
 	// Print header
@@ -221,6 +349,124 @@ func search(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 	return nil
 }
 
+// searchActionBatch caps the IDs sent in one delete/star/move request.
+const searchActionBatch = 100
+
+// actOnSearchResults deletes, stars or moves every result with batched calls.
+// Results span folders, so they are addressed by ID rather than path.
+func actOnSearchResults(ctx context.Context, s *session.Session, env *ExecutionEnv, entries []api.FileEntry, action, dest string, yes bool) error {
+	if len(entries) == 0 {
+		fmt.Fprintf(env.Stderr, "search: no results, nothing to %s\n", action)
+		return nil
+	}
+
+	var destID *int64
+	destPath := ""
+	if action == "move" {
+		resolved, err := s.ResolvePathArg(dest)
+		if err != nil {
+			return fmt.Errorf("search: %w", err)
+		}
+		destEntry, ok := s.Cache.Get(resolved)
+		if !ok {
+			return fmt.Errorf("search: %s: No such file or directory", dest)
+		}
+		if destEntry.Type != "folder" {
+			return fmt.Errorf("search: %s: Not a directory", dest)
+		}
+		destID = parentIDPtr(destEntry)
+		destPath = resolved
+	}
+
+	ids := make([]int64, 0, len(entries))
+	var paths []string
+	for i := range entries {
+		ids = append(ids, entries[i].ID)
+		if p, ok := searchResultPath(s, &entries[i]); ok {
+			paths = append(paths, p)
+		}
+	}
+
+	if !yes {
+		verb := map[string]string{"delete": "Move to trash", "star": "Star", "move": "Move"}[action]
+		prompt := fmt.Sprintf("search: %s %d matches", verb, len(ids))
+		if action == "move" {
+			prompt += " into " + destPath
+		}
+		fmt.Fprintf(env.Stderr, "%s? [y/N] ", prompt)
+		response, _ := bufio.NewReader(env.Stdin).ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(response)) != "y" {
+			fmt.Fprintln(env.Stderr, "search: cancelled")
+			return nil
+		}
+	}
+
+	done := 0
+	err := ui.WithSpinnerErr(env.Stderr, "", false, func() error {
+		for start := 0; start < len(ids); start += searchActionBatch {
+			batch := ids[start:min(start+searchActionBatch, len(ids))]
+			var err error
+			switch action {
+			case "delete":
+				err = s.Client.DeleteEntries(ctx, batch, s.WorkspaceID)
+			case "star":
+				err = s.Client.StarEntries(ctx, batch, s.WorkspaceID)
+			case "move":
+				err = s.Client.MoveEntries(ctx, batch, destID, s.WorkspaceID, nil)
+			}
+			if err != nil {
+				return err
+			}
+			done += len(batch)
+		}
+		return nil
+	})
+
+	// Cached copies of the results are stale either way
+	switch action {
+	case "delete", "move":
+		for _, p := range paths {
+			s.Cache.Remove(p)
+		}
+		if action == "move" {
+			s.Cache.InvalidateChildren(destPath)
+		}
+	case "star":
+		for _, p := range paths {
+			s.Cache.InvalidateChildren(filepath.Dir(p))
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("search: %s: %d of %d done: %w", action, done, len(ids), err)
+	}
+	switch action {
+	case "delete":
+		fmt.Fprintf(env.Stderr, "Moved %d entries to trash\n", done)
+	case "star":
+		fmt.Fprintf(env.Stderr, "Starred %d entries\n", done)
+	case "move":
+		fmt.Fprintf(env.Stderr, "Moved %d entries to %s\n", done, destPath)
+	}
+	return nil
+}
+
+// searchResultPath returns the full path of a search result, from the cache
+// or from its parent's cached path.
+func searchResultPath(s *session.Session, e *api.FileEntry) (string, bool) {
+	if p, ok := s.Cache.PathForID(e.ID); ok {
+		return p, true
+	}
+	if e.ParentID == nil {
+		return "/" + e.Name, true
+	}
+	parent, ok := s.Cache.PathForID(*e.ParentID)
+	if !ok {
+		return "", false
+	}
+	return path.Join(parent, e.Name), true
+}
+
 func parseDate(input string) (time.Time, error) {
 	now := time.Now()
 	switch strings.ToLower(input) {
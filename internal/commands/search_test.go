@@ -1,12 +1,17 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/crypto"
 	"github.com/gYonder/drime-shell/internal/session"
 )
 
@@ -97,6 +102,22 @@ func TestSearchCommand(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "modification window",
+			args: []string{"--since", "2024-01-01", "--until", "2024-02-01"},
+			expected: func(opts *api.ListEntriesOptions) {
+				filters := decodeFilters(t, opts.Filters)
+				if len(filters) != 2 {
+					t.Fatalf("expected 2 filters, got %d", len(filters))
+				}
+				if filters[0].Key != "updated_at" || filters[0].Operator != ">" || filters[0].Value != "2024-01-01T00:00:00Z" {
+					t.Errorf("unexpected filter 0: %+v", filters[0])
+				}
+				if filters[1].Key != "updated_at" || filters[1].Operator != "<" || filters[1].Value != "2024-02-01T00:00:00Z" {
+					t.Errorf("unexpected filter 1: %+v", filters[1])
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -114,6 +135,32 @@ func TestSearchCommand(t *testing.T) {
 	}
 }
 
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"7d", 7 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"1.5d", 36 * time.Hour, true},
+		{"12h", 12 * time.Hour, true},
+		{"30m", 30 * time.Minute, true},
+		{"d", 0, false},
+		{"-1d", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.in)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("parseAge(%q) = %v; want an error", tt.in, got)
+		}
+	}
+}
+
 func decodeFilters(t *testing.T, encoded string) []api.Filter {
 	t.Helper()
 	if encoded == "" {
@@ -135,3 +182,237 @@ type mockWriter struct{}
 func (m *mockWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
+
+func TestSearchCommand_IncludeVault(t *testing.T) {
+	mockClient := &api.MockDrimeClient{
+		SearchWithOptionsFunc: func(ctx context.Context, query string, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+			return []api.FileEntry{{ID: 1, Name: "report.txt", Type: "text"}}, nil
+		},
+		GetVaultFoldersFunc: func(ctx context.Context, userID int64) ([]api.FileEntry, error) {
+			return []api.FileEntry{{ID: 10, Name: "secret", Type: "folder", Hash: "h10"}}, nil
+		},
+		ListVaultEntriesFunc: func(ctx context.Context, folderHash string) ([]api.FileEntry, error) {
+			if folderHash == "" {
+				return []api.FileEntry{{ID: 10, Name: "secret", Type: "folder", Hash: "h10"}}, nil
+			}
+			parentID := int64(10)
+			return []api.FileEntry{
+				{ID: 11, Name: "report-2024.pdf", Type: "pdf", ParentID: &parentID},
+				{ID: 12, Name: "taxes.pdf", Type: "pdf", ParentID: &parentID},
+			}, nil
+		},
+	}
+	sess := &session.Session{Client: mockClient}
+	salt, _ := crypto.GenerateSalt()
+	sess.SetVaultKey(crypto.DeriveKey("pw", salt))
+
+	var out bytes.Buffer
+	env := &ExecutionEnv{Stdout: &out, Stderr: &mockWriter{}}
+
+	cmd, _ := Get("search")
+	if err := cmd.Run(context.Background(), sess, env, []string{"report", "--include-vault"}); err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+
+	got := out.String()
+	if !strings.Contains(got, "report.txt") {
+		t.Errorf("expected workspace result, got:\n%s", got)
+	}
+	if !strings.Contains(got, "vault:/secret/report-2024.pdf") {
+		t.Errorf("expected prefixed vault result, got:\n%s", got)
+	}
+	if strings.Contains(got, "taxes.pdf") {
+		t.Errorf("unexpected non-matching vault entry, got:\n%s", got)
+	}
+}
+
+func TestSearchCommand_MoveResultsInBatches(t *testing.T) {
+	docsID := int64(5)
+	var results []api.FileEntry
+	for i := 0; i < 150; i++ {
+		results = append(results, api.FileEntry{ID: int64(100 + i), Name: fmt.Sprintf("invoice-%d.pdf", i), Type: "pdf", ParentID: &docsID})
+	}
+	var batches [][]int64
+	var movedTo *int64
+	mockClient := &api.MockDrimeClient{
+		SearchWithOptionsFunc: func(ctx context.Context, query string, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+			return results, nil
+		},
+		MoveEntriesFunc: func(ctx context.Context, entryIDs []int64, destinationID *int64, workspaceID int64, destWorkspaceID *int64) error {
+			batches = append(batches, entryIDs)
+			movedTo = destinationID
+			return nil
+		},
+	}
+	cache := api.NewFileCache()
+	cache.Add(&api.FileEntry{ID: 0, Name: "/", Type: "folder"}, "/")
+	cache.AddChildren("/", []api.FileEntry{
+		{ID: docsID, Name: "Docs", Type: "folder"},
+		{ID: 6, Name: "Archive", Type: "folder"},
+	})
+	cache.AddChildren("/Docs", results[:1])
+	sess := &session.Session{Client: mockClient, Cache: cache, CWD: "/"}
+
+	var out bytes.Buffer
+	env := &ExecutionEnv{Stdout: &out, Stderr: &mockWriter{}, Stdin: strings.NewReader("n\n")}
+	cmd, _ := Get("search")
+
+	// --paths prints full paths built from the parent folder
+	if err := cmd.Run(context.Background(), sess, env, []string{"invoice", "--paths"}); err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 150 || lines[1] != "/Docs/invoice-1.pdf" {
+		t.Fatalf("unexpected --paths output: %v", lines[:2])
+	}
+
+	// Declining the confirmation does nothing
+	if err := cmd.Run(context.Background(), sess, env, []string{"invoice", "--move", "/Archive"}); err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(batches) != 0 {
+		t.Fatalf("moved without confirmation")
+	}
+
+	env.Stdin = strings.NewReader("y\n")
+	if err := cmd.Run(context.Background(), sess, env, []string{"invoice", "--move", "/Archive"}); err != nil {
+		t.Fatalf("search --move failed: %v", err)
+	}
+	if len(batches) != 2 || len(batches[0]) != searchActionBatch || len(batches[1]) != 50 {
+		t.Fatalf("expected batches of %d and 50, got %d batches", searchActionBatch, len(batches))
+	}
+	if movedTo == nil || *movedTo != 6 {
+		t.Errorf("expected move into /Archive (6), got %v", movedTo)
+	}
+	if _, ok := cache.Get("/Docs/invoice-0.pdf"); ok {
+		t.Errorf("moved entry still cached at its old path")
+	}
+
+	if err := cmd.Run(context.Background(), sess, env, []string{"invoice", "--star", "--delete"}); err == nil {
+		t.Errorf("expected conflicting actions to fail")
+	}
+}
+
+func TestSearchCommand_ActionsNeedQueryOrFilter(t *testing.T) {
+	searched := false
+	var trashed []int64
+	mockClient := &api.MockDrimeClient{
+		SearchWithOptionsFunc: func(ctx context.Context, query string, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+			searched = true
+			return []api.FileEntry{{ID: 1, Name: "a.pdf", Type: "pdf"}}, nil
+		},
+		DeleteEntriesFunc: func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+			trashed = append(trashed, entryIDs...)
+			return nil
+		},
+	}
+	cache := api.NewFileCache()
+	cache.Add(&api.FileEntry{ID: 0, Name: "/", Type: "folder"}, "/")
+	sess := &session.Session{Client: mockClient, Cache: cache, CWD: "/"}
+	env := &ExecutionEnv{Stdout: &bytes.Buffer{}, Stderr: &mockWriter{}}
+	cmd, _ := Get("search")
+
+	for _, args := range [][]string{{"--delete", "-y"}, {"--star", "-y"}, {"--move", "/Archive", "-y"}, {"  ", "--delete", "-y"}} {
+		err := cmd.Run(context.Background(), sess, env, args)
+		if err == nil || !strings.Contains(err.Error(), "needs a query or at least one filter") {
+			t.Errorf("search %v: expected refusal, got %v", args, err)
+		}
+	}
+	if searched || len(trashed) != 0 {
+		t.Fatalf("an unfiltered search must not be acted on")
+	}
+
+	if err := cmd.Run(context.Background(), sess, env, []string{"--type", "pdf", "--delete", "-y"}); err != nil {
+		t.Fatalf("search --type pdf --delete failed: %v", err)
+	}
+	if len(trashed) != 1 {
+		t.Errorf("expected the filtered match to be trashed, got %v", trashed)
+	}
+}
+
+func TestSearchCommand_TagFilter(t *testing.T) {
+	mockClient := &api.MockDrimeClient{
+		SearchWithOptionsFunc: func(ctx context.Context, query string, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+			return []api.FileEntry{
+				{ID: 1, Name: "a.pdf", Type: "pdf", Tags: []api.Tag{{ID: 1, Name: "starred"}}},
+				{ID: 2, Name: "b.pdf", Type: "pdf"},
+			}, nil
+		},
+	}
+	sess := &session.Session{Client: mockClient}
+	var out bytes.Buffer
+	env := &ExecutionEnv{Stdout: &out, Stderr: &mockWriter{}}
+
+	cmd, _ := Get("search")
+	if err := cmd.Run(context.Background(), sess, env, []string{"--tag", "Starred"}); err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if got := out.String(); !strings.Contains(got, "a.pdf") || strings.Contains(got, "b.pdf") {
+		t.Errorf("expected only the tagged entry, got:\n%s", got)
+	}
+}
+
+func TestTagCommand(t *testing.T) {
+	var batches [][]int64
+	mockClient := &api.MockDrimeClient{
+		ListByParentIDFunc: func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+			return nil, nil
+		},
+		StarEntriesFunc: func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+			batches = append(batches, entryIDs)
+			return nil
+		},
+		GetEntryFunc: func(ctx context.Context, entryID int64, workspaceID int64) (*api.FileEntry, error) {
+			return &api.FileEntry{ID: entryID, Name: "a.txt", Tags: []api.Tag{{Name: "work"}, {Name: "starred"}}}, nil
+		},
+		ListTagsFunc: func(ctx context.Context, query string) ([]api.Tag, error) {
+			return []api.Tag{{Name: "work"}, {Name: "starred"}}, nil
+		},
+	}
+	cache := api.NewFileCache()
+	cache.Add(&api.FileEntry{ID: 0, Name: "/", Type: "folder"}, "/")
+	var files []api.FileEntry
+	var args []string
+	for i := 0; i < 120; i++ {
+		name := fmt.Sprintf("f%d.txt", i)
+		files = append(files, api.FileEntry{ID: int64(100 + i), Name: name, Type: "text"})
+		args = append(args, name)
+	}
+	cache.AddChildren("/", files)
+	sess := &session.Session{Client: mockClient, Cache: cache, CWD: "/"}
+
+	var out bytes.Buffer
+	env := &ExecutionEnv{Stdout: &out, Stderr: &mockWriter{}}
+	cmd, _ := Get("tag")
+
+	if err := cmd.Run(context.Background(), sess, env, append([]string{"add"}, append(args, "starred")...)); err != nil {
+		t.Fatalf("tag add failed: %v", err)
+	}
+	if len(batches) != 2 || len(batches[0]) != searchActionBatch || len(batches[1]) != 20 {
+		t.Fatalf("expected batches of %d and 20, got %d batches", searchActionBatch, len(batches))
+	}
+
+	// Tags other than starred can't be set through the API
+	err := cmd.Run(context.Background(), sess, env, []string{"add", "f1.txt", "starred,work"})
+	if err == nil || !strings.Contains(err.Error(), `can't set "work"`) {
+		t.Errorf("expected unsupported tag error, got %v", err)
+	}
+	if len(batches) != 2 {
+		t.Errorf("unsupported tag still changed entries")
+	}
+
+	out.Reset()
+	if err := cmd.Run(context.Background(), sess, env, []string{"ls", "f1.txt"}); err != nil {
+		t.Fatalf("tag ls failed: %v", err)
+	}
+	if got := out.String(); got != "f1.txt: starred, work\n" {
+		t.Errorf("unexpected tag ls output: %q", got)
+	}
+
+	out.Reset()
+	if err := cmd.Run(context.Background(), sess, env, []string{"ls"}); err != nil {
+		t.Fatalf("tag ls failed: %v", err)
+	}
+	if got := out.String(); got != "starred\nwork\n" {
+		t.Errorf("unexpected tag ls output: %q", got)
+	}
+}
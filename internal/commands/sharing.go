@@ -9,7 +9,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/atotto/clipboard"
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/session"
 	"github.com/gYonder/drime-shell/internal/ui"
@@ -19,6 +18,8 @@ import (
 func init() {
 	Register(&Command{
 		Name:        "share",
+		Mutates:     alwaysMutates,
+		Flags:       shareLinkFlags,
 		Description: "Manage sharing and links",
 		Usage: `share [command] <file> [options]
 
@@ -120,13 +121,20 @@ func shareInvite(ctx context.Context, s *session.Session, env *ExecutionEnv, arg
 	return nil
 }
 
-func shareLink(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+// shareLinkFlags declares the options of share link, also used by the
+// audit log to redact -p.
+func shareLinkFlags() *pflag.FlagSet {
 	flags := pflag.NewFlagSet("share link", pflag.ContinueOnError)
-	deleteLink := flags.BoolP("delete", "d", false, "Delete the shareable link")
-	password := flags.StringP("password", "p", "", "Set a password")
-	expire := flags.StringP("expire", "e", "", "Set expiration (e.g. 24h, 30m)")
-	role := flags.StringP("role", "r", "download", "Permission level: view, edit, download")
-	copyLink := flags.Bool("copy", true, "Copy link to clipboard")
+	flags.BoolP("delete", "d", false, "Delete the shareable link")
+	flags.StringP("password", "p", "", "Set a password")
+	flags.StringP("expire", "e", "", "Set expiration (e.g. 24h, 30m)")
+	flags.StringP("role", "r", "download", "Permission level: view, edit, download")
+	flags.Bool("copy", true, "Copy link to clipboard")
+	return flags
+}
+
+func shareLink(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	flags := shareLinkFlags()
 	flags.SetOutput(env.Stderr)
 
 	// Reorder args to allow flags after positional arguments (Unix-style)
@@ -135,6 +143,11 @@ func shareLink(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 	if err := flags.Parse(args); err != nil {
 		return err
 	}
+	deleteLink, _ := flags.GetBool("delete")
+	password, _ := flags.GetString("password")
+	expire, _ := flags.GetString("expire")
+	role, _ := flags.GetString("role")
+	copyLink, _ := flags.GetBool("copy")
 
 	if flags.NArg() == 0 {
 		return fmt.Errorf("usage: share [link] [options] <file>")
@@ -149,7 +162,7 @@ func shareLink(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 		return fmt.Errorf("file not found: %s", path)
 	}
 
-	if *deleteLink {
+	if deleteLink {
 		if err := s.Client.DeleteShareableLink(ctx, entry.ID); err != nil {
 			return err
 		}
@@ -159,7 +172,7 @@ func shareLink(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 
 	// Map role to permissions
 	var allowEdit, allowDownload bool
-	switch *role {
+	switch role {
 	case "view":
 		allowEdit = false
 		allowDownload = false
@@ -170,7 +183,7 @@ func shareLink(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 		allowEdit = true
 		allowDownload = true
 	default:
-		return fmt.Errorf("invalid role: %s (must be view, edit, or download)", *role)
+		return fmt.Errorf("invalid role: %s (must be view, edit, or download)", role)
 	}
 
 	// Prepare request
@@ -179,12 +192,12 @@ func shareLink(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 		AllowDownload: allowDownload,
 	}
 
-	if *password != "" {
-		req.Password = password
+	if password != "" {
+		req.Password = &password
 	}
 
-	if *expire != "" {
-		duration, err := time.ParseDuration(*expire)
+	if expire != "" {
+		duration, err := time.ParseDuration(expire)
 		if err != nil {
 			return fmt.Errorf("invalid duration: %w", err)
 		}
@@ -199,15 +212,13 @@ func shareLink(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 	})
 
 	// If no changes requested and link exists, just show it
-	noChanges := *password == "" && *expire == "" && *role == "download"
+	noChanges := password == "" && expire == "" && role == "download"
 	if noChanges && existingLink != nil && existingLink.Hash != "" {
 		url := fmt.Sprintf("https://dri.me/%s", existingLink.Hash)
 		fmt.Fprintf(env.Stdout, "Shareable link: %s\n", ui.RenderLink(url))
 		printLinkDetails(env.Stdout, existingLink)
-		if *copyLink {
-			if err := clipboard.WriteAll(url); err == nil {
-				fmt.Fprintln(env.Stdout, "(copied to clipboard)")
-			}
+		if copyLink {
+			copyToClipboard(env, url)
 		}
 		return nil
 	}
@@ -246,10 +257,8 @@ func shareLink(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 	}
 	printLinkDetails(env.Stdout, link)
 
-	if *copyLink {
-		if err := clipboard.WriteAll(url); err == nil {
-			fmt.Fprintln(env.Stdout, "(copied to clipboard)")
-		}
+	if copyLink {
+		copyToClipboard(env, url)
 	}
 
 	return nil
@@ -437,3 +446,46 @@ func shareList(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 
 	return nil
 }
+
+// publicLinks returns the share links of the public entries among entries,
+// by entry ID, so --preserve-acl can recreate them after a transfer.
+func publicLinks(ctx context.Context, s *session.Session, entries []*api.FileEntry) (map[int64]*api.ShareableLink, error) {
+	links := make(map[int64]*api.ShareableLink)
+	for _, entry := range entries {
+		if !entry.Public {
+			continue
+		}
+		link, err := s.Client.GetShareableLink(ctx, entry.ID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the share link of '%s': %w", entry.Name, err)
+		}
+		if link == nil || link.Hash == "" {
+			continue
+		}
+		// Link passwords can't be read back, so such a link would come back
+		// without one
+		if link.Password != nil {
+			return nil, fmt.Errorf("the share link of '%s' has a password, which can't be carried over; remove it or share the copy by hand", entry.Name)
+		}
+		links[entry.ID] = link
+	}
+	return links, nil
+}
+
+// restoreLink gives entryID a share link with the same access and expiry as
+// link, unless it already has one.
+func restoreLink(ctx context.Context, s *session.Session, entryID int64, link *api.ShareableLink) error {
+	if existing, err := s.Client.GetShareableLink(ctx, entryID); err == nil && existing != nil && existing.Hash != "" {
+		return nil
+	}
+	req := api.ShareableLinkRequest{
+		AllowEdit:     link.AllowEdit,
+		AllowDownload: link.AllowDownload,
+	}
+	if link.ExpiresAt != nil {
+		expTime := link.ExpiresAt.Format(time.RFC3339)
+		req.ExpiresAt = &expTime
+	}
+	_, err := s.Client.CreateShareableLink(ctx, entryID, req)
+	return err
+}
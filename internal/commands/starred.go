@@ -29,7 +29,7 @@ func invalidateAndRefreshCWD(ctx context.Context, s *session.Session, touchedPat
 	var parentID *int64
 	if s.CWD != "/" {
 		if parentEntry, ok := s.Cache.Get(s.CWD); ok {
-			parentID = &parentEntry.ID
+			parentID = parentIDPtr(parentEntry)
 		} else {
 			// Can't refresh safely; leave invalidated so next ls will refetch.
 			return nil
@@ -56,6 +56,7 @@ func invalidateAndRefreshCWD(ctx context.Context, s *session.Session, touchedPat
 func init() {
 	Register(&Command{
 		Name:        "star",
+		Mutates:     alwaysMutates,
 		Description: "Manage starred files",
 		Usage: `star [command] <file>...
 
@@ -158,7 +159,7 @@ func starredList(ctx context.Context, s *session.Session, env *ExecutionEnv, arg
 	// sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
 
 	// Use standard ls formatting
-	return printLong(s, "starred", entries, false, env.Stdout)
+	return printLong(s, "starred", entries, &listPathOptions{}, env.Stdout)
 }
 func unstarCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
 	if len(args) == 0 {
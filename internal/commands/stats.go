@@ -2,20 +2,28 @@ package commands
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"path"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/session"
 	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/spf13/pflag"
 )
 
 func init() {
 	Register(&Command{
 		Name:        "stat",
 		Description: "Display file status",
-		Usage: `stat <file>
+		Usage: `stat [--follow] <file|link>
+       stat --show-path <id|hash>...
 
 Shows detailed metadata about a file or folder:
   - File name and type
@@ -24,24 +32,48 @@ Shows detailed metadata about a file or folder:
   - Created and modified timestamps
   - MIME type (for media files)
 
+Given a share URL (https://dri.me/...), shows the entry behind it, with
+its path and the link's access settings. For links you don't own, only the
+metadata the link exposes is shown.
+
+With --show-path, each argument is an entry ID or hash, as found in API
+responses or ls -i / ls --hash output, and only the entry's absolute path is
+printed. Paths are rebuilt from the server and added to the cache.
+
+Options:
+  -L, --follow   Treat the argument as a share link even without a scheme,
+                 e.g. dri.me/abc123 or just the hash
+  --show-path    Print the full path of the entries with these IDs or hashes
+
 Examples:
   stat document.pdf       Show info about a file
-  stat Photos/            Show info about a folder`,
+  stat Photos/            Show info about a folder
+  stat https://dri.me/x1  Find which entry a share link points to
+  stat --show-path 4821   Where entry 4821 lives`,
 		Run: stat,
 	})
 
 	Register(&Command{
 		Name:        "tree",
 		Description: "List contents in a tree-like format",
-		Usage: `tree [path]
+		Usage: `tree [--no-cache] [--deadline D] [--max-entries N] [path]
 
 Displays directory structure as a tree.
 Defaults to current directory if no path specified.
+Folders are always listed from the server and the cache is updated with
+what they contain.
+
+Options:
+  --no-cache   Also re-list the folders leading to path, so one created
+               elsewhere since the cache was loaded can be found
+  --deadline D       Stop after D (e.g. 30s) and show what was listed so far
+  --max-entries N    Stop after N entries and show what was listed so far
 
 Examples:
   tree              Show tree from current directory
   tree Photos/      Show tree starting from Photos folder
   tree /            Show tree from root
+  tree --max-entries 500 /   First 500 entries of a huge tree
 
 Note: Limited to 20 levels deep to prevent excessive API calls.`,
 		Run: tree,
@@ -49,59 +81,332 @@ Note: Limited to 20 levels deep to prevent excessive API calls.`,
 }
 
 func stat(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: stat <file>")
+	fs := pflag.NewFlagSet("stat", pflag.ContinueOnError)
+	follow := fs.BoolP("follow", "L", false, "resolve a share link to its entry")
+	showPath := fs.Bool("show-path", false, "print the full path of entries given by ID or hash")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: stat [--follow] <file|link> | stat --show-path <id|hash>...")
+	}
+	if *showPath {
+		return statShowPaths(ctx, s, env, fs.Args())
+	}
+
+	path := fs.Arg(0)
+	if hash, ok := shareLinkHash(path, *follow); ok {
+		return statShareLink(ctx, s, env, path, hash)
 	}
 
-	path := args[0]
 	cached, err := ResolveEntry(ctx, s, path)
 	if err != nil {
 		return fmt.Errorf("stat: %w", err)
 	}
 
 	// Fetch fresh details from API (with spinner for slow requests)
-	entry, _ := ui.WithSpinner(env.Stdout, "", false, func() (*api.FileEntry, error) {
+	entry, err := ui.WithSpinner(env.Stdout, "", false, func() (*api.FileEntry, error) {
 		return s.Client.GetEntry(ctx, cached.ID, s.WorkspaceID)
 	})
+	if api.IsNotFound(err) {
+		// Deleted remotely - drop the stale cache entry
+		if resolved, rerr := s.ResolvePathArg(path); rerr == nil {
+			s.Cache.Remove(resolved)
+		}
+		return fmt.Errorf("stat: cannot stat '%s': No such file or directory", path)
+	}
 	if entry == nil {
-		// Could be 404 (deleted remotely), network error, etc.
-		// Silently use cached data - it's still useful
+		// Network error, etc. Silently use cached data - it's still useful
 		entry = cached
+	} else {
+		// Keep the cache in step so later commands don't need to refetch
+		s.Cache.UpdateMetadata(cached, entry)
 	}
 
+	printEntryStat(env.Stdout, entry)
+	return nil
+}
+
+func printEntryStat(w io.Writer, entry *api.FileEntry) {
 	label := ui.MutedStyle.Render
-	fmt.Fprintf(env.Stdout, "%s %s\n", label("  File:"), ui.StyleName(entry.Name, entry.Type))
-	fmt.Fprintf(env.Stdout, "%s %s\n", label("  Size:"), ui.SizeStyle.Render(fmt.Sprintf("%d", entry.Size)))
-	fmt.Fprintf(env.Stdout, "%s %s\n", label("  Type:"), ui.StyleForType(entry.Type).Render(entry.Type))
-	fmt.Fprintf(env.Stdout, "%s %s\n", label("    ID:"), ui.MutedStyle.Render(fmt.Sprintf("%d", entry.ID)))
-	fmt.Fprintf(env.Stdout, "%s %s\n", label("  Hash:"), ui.MutedStyle.Render(entry.Hash))
+	fmt.Fprintf(w, "%s %s\n", label("  File:"), ui.StyleName(entry.Name, entry.Type))
+	fmt.Fprintf(w, "%s %s\n", label("  Size:"), ui.SizeStyle.Render(fmt.Sprintf("%d", entry.Size)))
+	fmt.Fprintf(w, "%s %s\n", label("  Type:"), ui.StyleForType(entry.Type).Render(entry.Type))
+	fmt.Fprintf(w, "%s %s\n", label("    ID:"), ui.MutedStyle.Render(fmt.Sprintf("%d", entry.ID)))
+	fmt.Fprintf(w, "%s %s\n", label("  Hash:"), ui.MutedStyle.Render(entry.Hash))
 	if entry.UpdatedAt.IsZero() {
-		fmt.Fprintf(env.Stdout, "%s %s\n", label("Modify:"), ui.MutedStyle.Render("<unknown>"))
+		fmt.Fprintf(w, "%s %s\n", label("Modify:"), ui.MutedStyle.Render("<unknown>"))
 	} else {
-		fmt.Fprintf(env.Stdout, "%s %s\n", label("Modify:"), ui.DateStyle.Render(entry.UpdatedAt.String()))
+		fmt.Fprintf(w, "%s %s\n", label("Modify:"), ui.DateStyle.Render(entry.UpdatedAt.String()))
 	}
 	if entry.CreatedAt.IsZero() {
-		fmt.Fprintf(env.Stdout, "%s %s\n", label("Create:"), ui.MutedStyle.Render("<unknown>"))
+		fmt.Fprintf(w, "%s %s\n", label("Create:"), ui.MutedStyle.Render("<unknown>"))
 	} else {
-		fmt.Fprintf(env.Stdout, "%s %s\n", label("Create:"), ui.DateStyle.Render(entry.CreatedAt.String()))
+		fmt.Fprintf(w, "%s %s\n", label("Create:"), ui.DateStyle.Render(entry.CreatedAt.String()))
 	}
 	if entry.Type == "image" || entry.Type == "video" {
-		fmt.Fprintf(env.Stdout, "%s %s\n", label("  Mime:"), ui.MutedStyle.Render(entry.Mime))
+		fmt.Fprintf(w, "%s %s\n", label("  Mime:"), ui.MutedStyle.Render(entry.Mime))
 	}
+}
 
+// statShowPaths prints the absolute path of each entry given by ID or hash.
+func statShowPaths(ctx context.Context, s *session.Session, env *ExecutionEnv, refs []string) error {
+	if s.InVault {
+		return fmt.Errorf("stat: --show-path is not available in the vault")
+	}
+	failed := 0
+	for _, ref := range refs {
+		p, err := ui.WithSpinner(env.Stderr, "", false, func() (string, error) {
+			return entryPathByRef(ctx, s, ref)
+		})
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "stat: %s: %v\n", ref, err)
+			failed++
+			continue
+		}
+		fmt.Fprintln(env.Stdout, p)
+	}
+	if failed > 0 {
+		return fmt.Errorf("stat: %d of %d entries not found", failed, len(refs))
+	}
 	return nil
 }
 
+// entryPathByRef returns the absolute path of the entry with the ID or hash
+// ref, from the cache when it knows the entry and otherwise from the server,
+// caching what it finds.
+func entryPathByRef(ctx context.Context, s *session.Session, ref string) (string, error) {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return entryPathByID(ctx, s, id)
+	}
+
+	for _, p := range s.Cache.AllPaths() {
+		if entry, ok := s.Cache.Get(p); ok && entry.Hash == ref {
+			return p, nil
+		}
+	}
+	// A folder hash leads straight to the folder's path
+	if ancestors, err := s.Client.GetFolderPath(ctx, ref, s.WorkspaceID); err == nil && len(ancestors) > 0 && ancestors[len(ancestors)-1].Hash == ref {
+		return cacheAncestors(s, ancestors), nil
+	}
+	// Entry hashes encode the ID (base64 of "<id>|" and padding); the entry
+	// fetched must carry the hash, so a different scheme just finds nothing
+	if id, ok := idFromHash(ref); ok {
+		entry, err := s.Client.GetEntry(ctx, id, s.WorkspaceID)
+		if err == nil && entry != nil && entry.Hash == ref {
+			return entryPath(ctx, s, entry)
+		}
+	}
+	return "", fmt.Errorf("no entry with this hash")
+}
+
+// entryPathByID returns the absolute path of the entry with the given ID.
+func entryPathByID(ctx context.Context, s *session.Session, id int64) (string, error) {
+	if id == api.RootID {
+		return "/", nil
+	}
+	if p, ok := s.Cache.PathForID(id); ok {
+		return p, nil
+	}
+	entry, err := s.Client.GetEntry(ctx, id, s.WorkspaceID)
+	if err != nil {
+		if api.IsNotFound(err) {
+			return "", fmt.Errorf("no entry with this ID")
+		}
+		return "", err
+	}
+	if entry == nil {
+		return "", fmt.Errorf("no entry with this ID")
+	}
+	return entryPath(ctx, s, entry)
+}
+
+// entryPath rebuilds the path of entry from its parent folders and caches
+// it. Folders come with their breadcrumb; files are put under the path of
+// their parent.
+func entryPath(ctx context.Context, s *session.Session, entry *api.FileEntry) (string, error) {
+	if entry.Type == "folder" && entry.Hash != "" {
+		ancestors, err := s.Client.GetFolderPath(ctx, entry.Hash, s.WorkspaceID)
+		if err != nil {
+			return "", err
+		}
+		if len(ancestors) == 0 || ancestors[len(ancestors)-1].ID != entry.ID {
+			ancestors = append(ancestors, *entry)
+		}
+		return cacheAncestors(s, ancestors), nil
+	}
+
+	parent := "/"
+	if entry.ParentID != nil && *entry.ParentID != api.RootID {
+		var err error
+		if parent, err = entryPathByID(ctx, s, *entry.ParentID); err != nil {
+			return "", fmt.Errorf("parent folder: %w", err)
+		}
+	}
+	p := path.Join(parent, entry.Name)
+	s.Cache.Add(entry, p)
+	return p, nil
+}
+
+// cacheAncestors adds a folder breadcrumb (root first) to the cache and
+// returns the path of its last folder.
+func cacheAncestors(s *session.Session, ancestors []api.FileEntry) string {
+	p := "/"
+	for i := range ancestors {
+		p = path.Join(p, ancestors[i].Name)
+		if _, ok := s.Cache.Get(p); !ok {
+			s.Cache.Add(&ancestors[i], p)
+		}
+	}
+	return p
+}
+
+// idFromHash decodes the entry ID from an entry hash.
+func idFromHash(hash string) (int64, bool) {
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(hash, "="))
+	if err != nil {
+		return 0, false
+	}
+	idStr, _, ok := strings.Cut(string(data), "|")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	return id, err == nil && id > 0
+}
+
+// shareLinkHash extracts the link hash from a share URL such as
+// https://dri.me/<hash>. With follow set, scheme-less URLs and bare hashes
+// are accepted too; otherwise anything but an http(s) URL is a path.
+func shareLinkHash(arg string, follow bool) (string, bool) {
+	raw := arg
+	if follow && !strings.Contains(raw, "://") && strings.Contains(raw, "/") {
+		raw = "https://" + raw
+	}
+	if u, err := url.Parse(raw); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		hash := path.Base(strings.TrimRight(u.Path, "/"))
+		if hash == "" || hash == "/" || hash == "." {
+			return "", false
+		}
+		return hash, true
+	}
+	if follow && arg != "" && !strings.Contains(arg, "/") {
+		return arg, true
+	}
+	return "", false
+}
+
+// statShareLink prints the entry a share link points to. Links are looked
+// up on the server; if that fails, the links of the public entries known to
+// the cache are compared instead. Entries you own are shown in full with
+// their path, others with whatever the link exposes.
+func statShareLink(ctx context.Context, s *session.Session, env *ExecutionEnv, arg, hash string) error {
+	link, err := ui.WithSpinner(env.Stderr, "", false, func() (*api.ShareableLink, error) {
+		link, err := s.Client.GetShareableLinkByHash(ctx, hash)
+		if err == nil && link != nil && link.Entry != nil {
+			return link, nil
+		}
+		if own := findOwnShareLink(ctx, s, hash); own != nil {
+			return own, nil
+		}
+		if err == nil || api.IsNotFound(err) {
+			err = fmt.Errorf("no share link found")
+		}
+		return nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("stat: %s: %w", arg, err)
+	}
+
+	entry := link.Entry
+	owned := link.UserID == s.UserID || entry.OwnerID == s.UserID
+	if owned {
+		if fresh, err := s.Client.GetEntry(ctx, entry.ID, s.WorkspaceID); err == nil && fresh != nil {
+			entry = fresh
+		}
+	}
+
+	printEntryStat(env.Stdout, entry)
+	label := ui.MutedStyle.Render
+	if p, ok := s.Cache.PathForID(entry.ID); ok {
+		fmt.Fprintf(env.Stdout, "%s %s\n", label("  Path:"), p)
+	}
+	fmt.Fprintf(env.Stdout, "%s %s\n", label("  Link:"), fmt.Sprintf("https://dri.me/%s", link.Hash))
+	if owned {
+		fmt.Fprintf(env.Stdout, "%s %s\n", label(" Owner:"), "you")
+	} else {
+		fmt.Fprintf(env.Stdout, "%s %s\n", label(" Owner:"), fmt.Sprintf("user %d (not you; metadata is limited to what the link exposes)", link.UserID))
+	}
+	fmt.Fprintf(env.Stdout, "%s %s\n", label("Access:"), linkAccess(link))
+	if link.ExpiresAt != nil {
+		fmt.Fprintf(env.Stdout, "%s %s\n", label("Expire:"), ui.DateStyle.Render(link.ExpiresAt.String()))
+	}
+	return nil
+}
+
+// findOwnShareLink matches hash against the links of public entries in the
+// cache. It returns nil when none matches.
+func findOwnShareLink(ctx context.Context, s *session.Session, hash string) *api.ShareableLink {
+	for _, p := range s.Cache.AllPaths() {
+		entry, ok := s.Cache.Get(p)
+		if !ok || !entry.Public {
+			continue
+		}
+		link, err := s.Client.GetShareableLink(ctx, entry.ID)
+		if err != nil || link == nil {
+			continue
+		}
+		if link.Hash == hash || (link.PersonnalLinkValue != "" && link.PersonnalLinkValue == hash) {
+			link.Entry = entry
+			return link
+		}
+	}
+	return nil
+}
+
+func linkAccess(link *api.ShareableLink) string {
+	access := []string{"view"}
+	if link.AllowDownload {
+		access = append(access, "download")
+	}
+	if link.AllowEdit {
+		access = append(access, "edit")
+	}
+	desc := strings.Join(access, ", ")
+	if link.Password != nil && *link.Password != "" {
+		desc += " (password protected)"
+	}
+	return desc
+}
+
 func tree(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("tree", pflag.ContinueOnError)
+	noCache := fs.Bool("no-cache", false, "re-list the folders leading to path")
+	budgetFlags := addWalkBudgetFlags(fs)
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	budget, err := budgetFlags.parse()
+	if err != nil {
+		return fmt.Errorf("tree: %w", err)
+	}
+
 	rootPath := "."
-	if len(args) > 0 {
-		rootPath = args[0]
+	if fs.NArg() > 0 {
+		rootPath = fs.Arg(0)
 	}
 
 	resolved, err := s.ResolvePathArg(rootPath)
 	if err != nil {
 		return fmt.Errorf("tree: %w", err)
 	}
+	if *noCache {
+		if err := refreshPath(ctx, s, resolved); err != nil {
+			return fmt.Errorf("tree: %w", err)
+		}
+	}
 	rootEntry, ok := s.Cache.Get(resolved)
 	if !ok {
 		return fmt.Errorf("tree: %s: No such directory", rootPath)
@@ -113,10 +418,20 @@ func tree(ctx context.Context, s *session.Session, env *ExecutionEnv, args []str
 	}
 
 	fmt.Fprintln(env.Stdout, rootPath)
-	return walkTree(ctx, s, rootEntry, "", 0, env.Stdout)
+	limit, cancel := budget.begin(ctx)
+	defer cancel()
+	err = walkTree(s, limit, resolved, rootEntry, "", 0, env.Stdout)
+	if partialWalk(env, "tree", err) {
+		return nil
+	}
+	return err
 }
 
-func walkTree(ctx context.Context, s *session.Session, parent *api.FileEntry, prefix string, depth int, w io.Writer) error {
+// walkTree prints the tree below dirPath. Listing errors are printed in
+// place, except the one that ends the walk when its budget runs out.
+func walkTree(s *session.Session, limit *walkLimit, dirPath string, parent *api.FileEntry, prefix string, depth int, w io.Writer) error {
+	ctx := limit.ctx
+
 	// Hard limit on recursion depth to prevent infinite loops or API spam
 	if depth > 20 {
 		fmt.Fprintf(w, "%s... (max depth reached)\n", prefix)
@@ -130,11 +445,14 @@ func walkTree(ctx context.Context, s *session.Session, parent *api.FileEntry, pr
 		children, err = s.Client.ListVaultEntries(ctx, parent.Hash)
 	} else {
 		apiOpts := api.ListOptions(s.WorkspaceID)
-		children, err = s.Client.ListByParentIDWithOptions(ctx, &parent.ID, apiOpts)
+		children, err = s.Client.ListByParentIDWithOptions(ctx, parentIDPtr(parent), apiOpts)
 	}
 	if err != nil {
-		return err
+		return limit.check(err)
 	}
+	// The listing is fresh, so keep the cache in step (with its own copy,
+	// as sorting below reorders the slice)
+	s.Cache.ReplaceChildren(dirPath, append([]api.FileEntry(nil), children...))
 
 	// Sort by name
 	sort.Slice(children, func(i, j int) bool {
@@ -142,6 +460,9 @@ func walkTree(ctx context.Context, s *session.Session, parent *api.FileEntry, pr
 	})
 
 	for i, child := range children {
+		if err := limit.next(); err != nil {
+			return err
+		}
 		isLast := i == len(children)-1
 		connector := "├── "
 		if isLast {
@@ -155,7 +476,11 @@ func walkTree(ctx context.Context, s *session.Session, parent *api.FileEntry, pr
 			if isLast {
 				newPrefix = prefix + "    "
 			}
-			err := walkTree(ctx, s, &child, newPrefix, depth+1, w)
+			err := walkTree(s, limit, path.Join(dirPath, child.Name), &child, newPrefix, depth+1, w)
+			var be *budgetError
+			if errors.As(err, &be) {
+				return err
+			}
 			if err != nil {
 				// Warn but continue
 				fmt.Fprintf(w, "%s[Error: %v]\n", newPrefix, err)
@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/gYonder/drime-shell/internal/ui"
+)
+
+func init() {
+	Register(&Command{
+		Name:        "tag",
+		Mutates:     subcommandMutates("add", "rm", "remove"),
+		Description: "List and set tags on files",
+		Usage: `tag ls [path...]
+tag add <path>... <tag>[,<tag>...]
+tag rm <path>... <tag>[,<tag>...]
+
+Without paths, tag ls lists the tags in use in the account; with paths, it
+shows the tags of each.
+
+tag add and tag rm change the tags of all paths at once. The last argument
+is the tag, or several separated by commas. The Drime API lists any tag but
+can only set and clear "starred", so that is the one tag they accept.
+
+Use search --tag to find files by tag.
+
+Examples:
+  tag ls                    # Tags in use
+  tag ls report.pdf Photos  # Tags of these entries
+  tag add *.pdf starred     # Same as star *.pdf
+  tag rm old/* starred`,
+		Run: tagCmd,
+	})
+}
+
+func tagCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: tag ls [path...] | tag add|rm <path>... <tag>")
+	}
+	if s.InVault {
+		return fmt.Errorf("tag: not available in vault")
+	}
+	switch args[0] {
+	case "ls", "list":
+		return tagList(ctx, s, env, args[1:])
+	case "add", "rm", "remove":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: tag %s <path>... <tag>[,<tag>...]", args[0])
+		}
+		return tagChange(ctx, s, env, args[0] == "add", args[1:len(args)-1], args[len(args)-1])
+	default:
+		return fmt.Errorf("tag: unknown subcommand %q (use ls, add or rm)", args[0])
+	}
+}
+
+// tagList prints the tags in use, or the tags of each path.
+func tagList(ctx context.Context, s *session.Session, env *ExecutionEnv, paths []string) error {
+	if len(paths) == 0 {
+		tags, err := ui.WithSpinner(env.Stderr, "", false, func() ([]api.Tag, error) {
+			return s.Client.ListTags(ctx, "")
+		})
+		if err != nil {
+			return fmt.Errorf("tag: %w", err)
+		}
+		if len(tags) == 0 {
+			fmt.Fprintln(env.Stdout, "No tags")
+			return nil
+		}
+		for _, name := range tagNames(tags) {
+			fmt.Fprintln(env.Stdout, name)
+		}
+		return nil
+	}
+
+	var failed int
+	for _, arg := range paths {
+		resolved, err := s.ResolvePathArg(arg)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "tag: %s: %v\n", arg, err)
+			failed++
+			continue
+		}
+		entry, ok := s.Cache.Get(resolved)
+		if !ok {
+			fmt.Fprintf(env.Stderr, "tag: %s: No such file or directory\n", arg)
+			failed++
+			continue
+		}
+		// Entries from the folder tree may come without their tags
+		if fresh, err := s.Client.GetEntry(ctx, entry.ID, s.WorkspaceID); err == nil && fresh != nil {
+			entry = fresh
+		}
+		fmt.Fprintf(env.Stdout, "%s: %s\n", arg, strings.Join(tagNames(entry.Tags), ", "))
+	}
+	if failed > 0 {
+		return fmt.Errorf("tag: %d of %d paths not found", failed, len(paths))
+	}
+	return nil
+}
+
+// tagChange adds or removes tagList (comma-separated) on every path, in
+// batches.
+func tagChange(ctx context.Context, s *session.Session, env *ExecutionEnv, add bool, paths []string, tagList string) error {
+	tags := strings.Split(tagList, ",")
+	for _, tag := range tags {
+		if !strings.EqualFold(strings.TrimSpace(tag), api.StarredTag) {
+			return fmt.Errorf("tag: can't set %q: the Drime API only supports the %q tag", tag, api.StarredTag)
+		}
+	}
+
+	var ids []int64
+	var touched []string
+	for _, arg := range paths {
+		resolved, err := s.ResolvePathArg(arg)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "tag: %s: %v\n", arg, err)
+			continue
+		}
+		entry, ok := s.Cache.Get(resolved)
+		if !ok {
+			fmt.Fprintf(env.Stderr, "tag: %s: No such file or directory\n", arg)
+			continue
+		}
+		ids = append(ids, entry.ID)
+		touched = append(touched, resolved)
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("tag: no valid files")
+	}
+
+	change := s.Client.UnstarEntries
+	if add {
+		change = s.Client.StarEntries
+	}
+	done := 0
+	var refreshErr error
+	err := ui.WithSpinnerErr(env.Stderr, "", false, func() error {
+		for start := 0; start < len(ids); start += searchActionBatch {
+			end := min(start+searchActionBatch, len(ids))
+			if err := change(ctx, ids[start:end], s.WorkspaceID); err != nil {
+				return err
+			}
+			done = end
+		}
+		refreshErr = invalidateAndRefreshCWD(ctx, s, touched)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("tag: %d of %d changed: %w", done, len(ids), err)
+	}
+	if refreshErr != nil {
+		fmt.Fprintf(env.Stderr, "warning: failed to refresh current directory: %v\n", refreshErr)
+	}
+
+	if add {
+		fmt.Fprintf(env.Stdout, "Tagged %d items with %s\n", done, api.StarredTag)
+	} else {
+		fmt.Fprintf(env.Stdout, "Removed %s from %d items\n", api.StarredTag, done)
+	}
+	return nil
+}
+
+// tagNames returns the sorted names of tags.
+func tagNames(tags []api.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -133,3 +133,26 @@ func TestGrepCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestIsBinaryContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    bool
+	}{
+		{"empty", nil, false},
+		{"ascii", []byte("hello world\n"), false},
+		{"utf8", []byte("héllo wörld ✓\n"), false},
+		{"json", []byte(`{"a": 1}`), false},
+		{"nul byte", []byte("abc\x00def"), true},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), true},
+		{"gzip", []byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBinaryContent(tt.content); got != tt.want {
+				t.Errorf("isBinaryContent(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
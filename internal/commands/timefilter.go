@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/spf13/pflag"
+)
+
+// timeWindowFlags holds the --since/--until/--newer-than flags shared by ls,
+// find and search.
+type timeWindowFlags struct {
+	since     *string
+	until     *string
+	newerThan *string
+}
+
+func addTimeWindowFlags(fs *pflag.FlagSet) *timeWindowFlags {
+	return &timeWindowFlags{
+		since:     fs.String("since", "", "only entries modified at or after date"),
+		until:     fs.String("until", "", "only entries modified before date"),
+		newerThan: fs.String("newer-than", "", "only entries modified within age (e.g. 7d)"),
+	}
+}
+
+// timeWindow bounds FileEntry.UpdatedAt. Zero bounds are open.
+type timeWindow struct {
+	since time.Time
+	until time.Time
+}
+
+// parse validates the flags. Dates accept the same forms as search --after
+// (YYYY-MM-DD, RFC 3339, "today", "yesterday"); when both --since and
+// --newer-than are given the later bound wins.
+func (f *timeWindowFlags) parse() (timeWindow, error) {
+	var w timeWindow
+	var err error
+	if *f.since != "" {
+		if w.since, err = parseDate(*f.since); err != nil {
+			return w, fmt.Errorf("invalid --since date: %w", err)
+		}
+	}
+	if *f.until != "" {
+		if w.until, err = parseDate(*f.until); err != nil {
+			return w, fmt.Errorf("invalid --until date: %w", err)
+		}
+	}
+	if *f.newerThan != "" {
+		age, err := parseAge(*f.newerThan)
+		if err != nil {
+			return w, fmt.Errorf("invalid --newer-than age: %w", err)
+		}
+		if t := time.Now().Add(-age); t.After(w.since) {
+			w.since = t
+		}
+	}
+	if !w.since.IsZero() && !w.until.IsZero() && !w.since.Before(w.until) {
+		return w, fmt.Errorf("--since must be before --until")
+	}
+	return w, nil
+}
+
+func (w timeWindow) active() bool {
+	return !w.since.IsZero() || !w.until.IsZero()
+}
+
+func (w timeWindow) contains(t time.Time) bool {
+	if !w.since.IsZero() && t.Before(w.since) {
+		return false
+	}
+	if !w.until.IsZero() && !t.Before(w.until) {
+		return false
+	}
+	return true
+}
+
+// filters returns the server-side equivalent of the window on updated_at.
+func (w timeWindow) filters() []api.Filter {
+	var filters []api.Filter
+	if !w.since.IsZero() {
+		filters = append(filters, api.Filter{
+			Key:      api.FilterKeyUpdatedAt,
+			Value:    w.since.Format(time.RFC3339),
+			Operator: api.FilterOpGreater,
+		})
+	}
+	if !w.until.IsZero() {
+		filters = append(filters, api.Filter{
+			Key:      api.FilterKeyUpdatedAt,
+			Value:    w.until.Format(time.RFC3339),
+			Operator: api.FilterOpLess,
+		})
+	}
+	return filters
+}
+
+// filter keeps the entries modified inside the window. The server filters
+// too where it can; this guards against it ignoring or rounding a bound.
+func (w timeWindow) filter(entries []api.FileEntry) []api.FileEntry {
+	if !w.active() {
+		return entries
+	}
+	kept := make([]api.FileEntry, 0, len(entries))
+	for _, e := range entries {
+		if w.contains(e.UpdatedAt) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// parseAge parses an age such as 30m, 12h, 7d or 2w. Units below a day go
+// through time.ParseDuration.
+func parseAge(s string) (time.Duration, error) {
+	unit := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, d := range unit {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("'%s' is not an age like 7d", s)
+			}
+			return time.Duration(v * float64(d)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("'%s' is not an age like 7d", s)
+	}
+	return d, nil
+}
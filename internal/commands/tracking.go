@@ -13,6 +13,7 @@ import (
 func init() {
 	Register(&Command{
 		Name:        "track",
+		Mutates:     alwaysMutates,
 		Description: "Manage file tracking",
 		Usage: `track [command] <file>...
 
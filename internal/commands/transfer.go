@@ -1,16 +1,24 @@
 package commands
 
 import (
+	"archive/tar"
 	"archive/zip"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"math/rand"
+	"mime"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
@@ -23,20 +31,22 @@ import (
 func init() {
 	Register(&Command{
 		Name:        "upload",
+		Mutates:     alwaysMutates,
 		Description: "Upload a file or directory to Drime Cloud",
-		Usage:       "upload [options] <local_path> [remote_path]\n\nUploads a local file or directory to Drime Cloud.\nDirectories are uploaded recursively automatically.\nLarge files (>65MB) use multipart upload.\n\nOptions:\n  --on-duplicate <action>  How to handle duplicates: ask (default), replace, rename, skip\n\nExamples:\n  upload photo.jpg                       # Upload to current directory\n  upload photo.jpg /Photos/              # Upload to /Photos/\n  upload --on-duplicate skip ./project   # Skip existing files",
+		Usage:       "upload [options] <local_path> [remote_path]\n\nUploads a local file or directory to Drime Cloud.\nDirectories are uploaded recursively automatically.\nLarge files (>65MB) use multipart upload.\n\nOptions:\n  --on-duplicate <action>  How to handle duplicates: ask, replace, rename, skip\n                           (default: default_on_duplicate in config, else ask;\n                           ask fails when stdin is not a terminal)\n  --merge                  When a directory's folder already exists, upload into\n                           it; files already there follow --on-duplicate\n  --rename                 ... create a renamed copy such as \"project (1)\" instead\n  --replace                ... move the existing folder to the trash first\n                           (without these, --on-duplicate replace merges, rename\n                           and skip apply to the folder, and ask offers all four;\n                           -u always merges)\n  -u, --update             Upload only files newer than their remote copy\n  --force                  Skip the free-space check before uploading (only made\n                           when 8 MB or more are to be sent)\n  -p, --make-parents       Create missing folders of remote_path (a trailing /\n                           makes all of it a folder); without it they must exist\n  --compress               Gzip compressible files, a directory's included, and\n                           upload them as <name>.gz (download restores the\n                           originals automatically, in folders too)\n  --atomic                 Upload under a temporary name and rename it into place\n                           only once complete, so the final name never shows a\n                           partial file (if the rename fails, the upload is kept\n                           under the temporary name and a replaced file restored)\n  --staging                The same for directories: upload into a hidden folder\n                           and rename it into place once every file is there; on\n                           failure the hidden folder is kept and the next run\n                           resumes into it\n  --mime <type>            Content type to store instead of the detected one\n                           (e.g. application/pdf), which drives previews\n  --verify                 Read an uploaded file back and compare its checksum\n                           with the local file's; the server has no checksums\n                           of its own, so this downloads the file once more\n  --checksum-algo <algo>   Checksum for --verify: sha256 (default, or\n                           checksum_algo in config), md5 or crc32; implies --verify\n  -j, --jobs <n>           Parallel workers for directory uploads (default 6 or\n                           transfer_jobs in config, max 32; alias --max-concurrency)\n  --workers-per-file <n>   Let big files in a directory upload send up to n parts\n                           at once, taken from the --jobs budget so the total\n                           number of streams stays the same\n  --big-file-threshold <size>\n                           Size above which --workers-per-file applies\n                           (default 65M, e.g. 500M or 2G)\n  --delete-after           Once everything is uploaded, check that each file sent\n                           has a remote copy of the same size (and checksum, with\n                           --verify), then delete those local files and any folder\n                           left empty; files skipped by -u or as duplicates are\n                           kept, and nothing is deleted if any upload failed or\n                           any check does not pass\n  --dry-run                With --delete-after, list what would be deleted instead\n  --verify-after           Once uploaded, list the destination fresh from the\n                           server and check that every local file has a remote\n                           copy of the same size, reporting any that don't (off\n                           by default: it costs a listing per folder)\n  --only-show-errors       Print only the files that failed and the final summary:\n                           no progress, folder or skipped-file lines\n  --max-depth <n>          Upload only files up to n levels down a directory\n                           (1 = its direct children) and say how many were left out\n  --progress <mode>        Progress output: bar (default) or json (NDJSON on stderr,\n                           also set with DRIME_PROGRESS=json)\n  --progress-interval <d>  Minimum time between progress updates (default 100ms,\n                           0 for every update)\n  --retries <n>            Retries per file after the first try (default 9, and 5\n                           for each storage request); 0 fails fast\n  --retry-delay <d>        First wait between tries, doubled each time (default 2s,\n                           1s for storage requests)\n\nExamples:\n  upload photo.jpg                       # Upload to current directory\n  upload photo.jpg /Photos/              # Upload to /Photos/\n  upload -p notes.md /Docs/2024/q3/      # Create /Docs/2024/q3 first\n  upload --on-duplicate skip ./project   # Skip existing files\n  upload --merge ./project /Code/        # Add new files to /Code/project\n  upload -u notes.md /Docs/              # Upload only if changed locally\n  upload --atomic data.csv /Inbox/       # Watchers only ever see the complete file\n  upload --staging ./site /Public/       # Publish the folder all at once\n  upload --mime application/pdf scan.bin # Detection guessed wrong\n  upload --checksum-algo md5 disk.img /Backups/  # Check against an .md5 file\n  upload -j 8 --workers-per-file 4 ./videos  # Don't let one huge file lag behind\n  upload --delete-after ./scans /Archive/  # Move a folder to the cloud\n  upload --max-depth 1 ./project /Backup/  # Top-level files only\n  upload --verify-after ./photos /Archive/ # Make sure nothing went missing\n  upload --only-show-errors ./archive /Backup/  # Large batch, failures only\n  upload --retries 0 backup.tar /Backups/ # Fail fast in a script",
 		Run:         upload,
 	})
 	Register(&Command{
 		Name:        "download",
 		Description: "Download a file or directory from Drime Cloud",
-		Usage:       "download <remote_path> [local_path]\n\nDownloads a file or directory from Drime Cloud.\nDirectories are downloaded as zip and extracted automatically.\n\nExamples:\n  download photo.jpg            # Download to current directory\n  download /Photos/vacation ./  # Download folder to local directory",
+		Usage:       "download [-i|-n] [-o dir] <remote_path> [local_path]\n       download <remote_path> -\n       download --tar <folder> -\n       download --from-file <list> [-o dir] [local_dir]\n\nDownloads a file or directory from Drime Cloud. Without a local path (or -o),\nfiles go to download_dir from the config or $DRIME_DOWNLOAD_DIR, created if\nmissing, and otherwise to the current directory.\nDirectories are downloaded as zip and extracted automatically. Folders with\nmore files than no_zip_threshold in the config (default 200) are fetched\nfile by file instead, so an interrupted download resumes where it stopped.\nFiles are written as <name>.drime-partial and renamed once complete, so a\nfile under its final name is always whole; the partial file is what a later\nrun resumes.\nWith download_cache_mb set in the config, downloaded files are also kept in\na local cache and copied from it when fetched again unchanged; folders are\nthen fetched file by file (unless --zip) so their files use the cache too.\nWith '-' as local path, the file is written to stdout (with retries and\nresume) and progress goes to stderr, for piping into local tools. With\n--tar, a folder is written to stdout as a tar archive, built from\nper-file downloads rather than the server's zip.\nA relative local path that climbs out of the current directory (such as\n../../etc/passwd) is only written after confirmation; give an absolute\npath to skip the question.\n\nOptions:\n  --from-file <list>  Download every remote path listed in a local file, one per line ('-' for stdin)\n  --raw               Keep files uploaded with --compress as .gz instead of\n                      decompressing them (in folders too); -n and -i apply\n                      to the restored name\n  --verify            Read a downloaded file back from the server and compare\n                      checksums with the local copy (an existing file kept by\n                      -n or -i is checked too)\n  --checksum-algo <algo>\n                      Checksum for --verify: sha256 (default, or checksum_algo\n                      in config), md5 or crc32; implies --verify\n  -o, --output-dir <dir>  Download into dir, creating it (and any missing\n                      parents of local_path under it) as needed\n  -i, --interactive   Ask before overwriting an existing local file (also when\n                      extracting folders); existing files are never resumed\n  -n, --no-clobber    Never overwrite existing local files, skip them instead\n  --no-resume         Download files from the first byte, replacing a partial\n                      (or suspect complete) local file; also --resume=false\n  --resume            Require resuming a partial local file, failing if there\n                      is none\n  --partial-suffix <s>\n                      Suffix of files still being downloaded (default\n                      .drime-partial, or partial_suffix in config); a file\n                      only gets its final name once complete\n  --no-zip            Download folders file by file into the same structure,\n                      with per-file resume (alias --preserve-structure)\n  --zip               Always download folders as a single zip\n  --tar               Write a folder to stdout ('-') as a tar archive, one\n                      file after the other, without a temporary file\n  --strip-components N\n                      Drop the first N path components of a folder's files,\n                      the folder itself being the first; files with no more\n                      components are skipped\n  --progress <mode>   Progress output: bar (default) or json (NDJSON on stderr)\n  --progress-interval <d>\n                    Minimum time between progress updates (default 100ms,\n                    0 for every update)\n  --retries <n>       Retries per file after the first try (default 9, 4 in\n                      the vault); 0 fails fast\n  --retry-delay <d>   First wait between tries, doubled each time (default 2s)\n\nExamples:\n  download photo.jpg            # Download to download_dir or current directory\n  download /Photos/vacation ./  # Download folder to local directory\n  download --from-file list.txt ./out\n  download -o backups/2024 --from-file list.txt\n  download -n /Photos ./        # Only fetch photos not already here\n  download --verify backup.tar ./\n  download --no-resume big.iso ./  # The partial file is corrupt\n  download --partial-suffix .part big.iso ./  # Watchers ignore *.part\n  download --no-zip /Backups ./ # Re-run to resume after a failure\n  download --strip-components 1 /Site ./public  # Site's contents, no Site/\n  download big.tar - | tar x\n  download --tar /Site - | ssh host tar x -C /srv\n  download --retries 30 --retry-delay 5s /big.iso ./  # Flaky link",
 		Run:         download,
 	})
 	Register(&Command{
 		Name:        "edit",
-		Description: "Edit a file using the built-in editor",
-		Usage:       "edit <file>\n\nOpens the file in the built-in text editor.\n\nKeybindings (nano-like):\n  Ctrl+S    Save\n  Ctrl+Q    Quit (or Ctrl+X)\n  Ctrl+G    Toggle help\n\nExamples:\n  edit config.yaml\n  edit notes.txt",
+		Mutates:     alwaysMutates,
+		Description: "Edit files in the built-in editor or $EDITOR",
+		Usage:       "edit [-e | --builtin] <file>...\n\nOpens each file in turn in the built-in text editor, or in your own editor\n($VISUAL or $EDITOR) with -e or 'external_editor: true' in the config.\nFiles are only uploaded again when they were saved with changes.\n\nOptions:\n  -e, --external    Edit in $VISUAL/$EDITOR (default vi) via a temporary file\n  --builtin         Use the built-in editor even if external_editor is set\n\nKeybindings (nano-like):\n  Ctrl+S    Save\n  Ctrl+Q    Quit (or Ctrl+X)\n  Ctrl+G    Toggle help\n\nExamples:\n  edit config.yaml\n  edit notes.txt todo.txt    Edit both files, one after the other\n  edit -e main.go            Edit in vim, emacs, ...",
 		Run:         edit,
 	})
 }
@@ -44,21 +54,56 @@ func init() {
 func upload(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
 	// Handle vault uploads separately
 	if s.InVault {
-		return uploadToVault(ctx, s, env, args)
+		fs := pflag.NewFlagSet("upload", pflag.ContinueOnError)
+		transfer := addTransferFlags(fs)
+		fs.SetOutput(env.Stderr)
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		ctx, err := transfer.apply(ctx, env)
+		if err != nil {
+			return fmt.Errorf("upload: %w", err)
+		}
+		return uploadToVault(ctx, s, env, fs.Args())
 	}
 
 	// Parse flags
 	fs := pflag.NewFlagSet("upload", pflag.ContinueOnError)
-	onDuplicate := fs.String("on-duplicate", "ask", "how to handle duplicates: ask, replace, rename, skip")
+	transfer := addTransferFlags(fs)
+	onDuplicate := fs.String("on-duplicate", "", "how to handle duplicates: ask, replace, rename, skip")
+	update := fs.BoolP("update", "u", false, "upload only files newer than their remote copy")
+	force := fs.Bool("force", false, "skip the free-space check before uploading")
+	makeParents := fs.BoolP("make-parents", "p", false, "create missing remote folders")
+	compress := fs.Bool("compress", false, "gzip compressible files before uploading")
+	atomic := fs.Bool("atomic", false, "upload under a temporary name, then rename into place")
+	staging := fs.Bool("staging", false, "upload a directory into a hidden folder, then rename into place")
+	mimeType := fs.String("mime", "", "content type to store instead of the detected one")
+	jobs := fs.IntP("jobs", "j", 0, "parallel workers for directory uploads")
+	fs.IntVar(jobs, "max-concurrency", 0, "alias for --jobs")
+	workersPerFile := fs.Int("workers-per-file", 0, "parallel part uploads for big files in a directory")
+	bigFileThreshold := fs.String("big-file-threshold", "", "size above which --workers-per-file applies")
+	deleteAfter := fs.Bool("delete-after", false, "delete the local source once uploaded and verified")
+	dryRun := fs.Bool("dry-run", false, "with --delete-after, only list what would be deleted")
+	verifyAfter := fs.Bool("verify-after", false, "check every file arrived with the right size once uploaded")
+	onlyErrors := fs.Bool("only-show-errors", false, "print only failures and the final summary")
+	maxDepth := fs.Int("max-depth", 0, "levels of a directory to upload (1 = direct children only)")
+	merge := fs.Bool("merge", false, "upload a directory into an existing folder of the same name")
+	rename := fs.Bool("rename", false, "upload a directory as a renamed copy when its folder exists")
+	replace := fs.Bool("replace", false, "trash an existing folder of the same name before uploading")
+	verifyAlgo := addVerifyFlags(s, fs)
 	fs.SetOutput(env.Stderr)
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 	args = fs.Args()
+	ctx, err := transfer.apply(ctx, env)
+	if err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
 
 	if len(args) < 1 {
-		return fmt.Errorf("usage: upload [--on-duplicate <action>] <local_path> [remote_path]")
+		return fmt.Errorf("usage: upload [-u] [--force] [--compress] [--on-duplicate <action>] <local_path> [remote_path]")
 	}
 
 	localPath := args[0]
@@ -67,12 +112,9 @@ func upload(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 		remotePath = args[1]
 	}
 
-	// Validate --on-duplicate value
-	switch *onDuplicate {
-	case "ask", "replace", "rename", "skip":
-		// Valid
-	default:
-		return fmt.Errorf("invalid --on-duplicate value: %s (must be ask, replace, rename, or skip)", *onDuplicate)
+	policy, err := duplicatePolicy(s, *onDuplicate)
+	if err != nil {
+		return err
 	}
 
 	// Check if local path exists and what type it is
@@ -81,14 +123,494 @@ func upload(ctx context.Context, s *session.Session, env *ExecutionEnv, args []s
 		return fmt.Errorf("upload: %s: %v", localPath, err)
 	}
 
+	if *jobs < 0 {
+		return fmt.Errorf("upload: --jobs must be positive")
+	}
+	if *mimeType != "" {
+		if *mimeType, err = parseMimeType(*mimeType); err != nil {
+			return fmt.Errorf("upload: --mime: %w", err)
+		}
+		if stat.IsDir() {
+			return fmt.Errorf("upload: --mime applies to single files")
+		}
+		if *compress {
+			return fmt.Errorf("upload: --mime can't be combined with --compress")
+		}
+	}
+	if *workersPerFile < 0 {
+		return fmt.Errorf("upload: --workers-per-file must be positive")
+	}
+	var bigFileBytes int64
+	if *bigFileThreshold != "" {
+		if bigFileBytes, err = parseSize(*bigFileThreshold); err != nil {
+			return fmt.Errorf("upload: --big-file-threshold: %w", err)
+		}
+		if *workersPerFile == 0 {
+			return fmt.Errorf("upload: --big-file-threshold needs --workers-per-file")
+		}
+	}
+	if *dryRun && !*deleteAfter {
+		return fmt.Errorf("upload: --dry-run needs --delete-after")
+	}
+	if *deleteAfter && *compress {
+		return fmt.Errorf("upload: --delete-after can't be combined with --compress")
+	}
+	if *verifyAfter && *compress {
+		return fmt.Errorf("upload: --verify-after can't be combined with --compress (the remote sizes are the compressed ones)")
+	}
+	if *staging && *update {
+		return fmt.Errorf("upload: --staging can't be combined with -u (it merges into the existing folder)")
+	}
+	folderExists := ""
+	for _, mode := range []struct {
+		name string
+		set  bool
+	}{{"merge", *merge}, {"rename", *rename}, {"replace", *replace}} {
+		if !mode.set {
+			continue
+		}
+		if folderExists != "" {
+			return fmt.Errorf("upload: only one of --merge, --rename and --replace can be given")
+		}
+		folderExists = mode.name
+	}
+	if folderExists != "" {
+		if !stat.IsDir() {
+			return fmt.Errorf("upload: --%s applies to directories (use --on-duplicate for files)", folderExists)
+		}
+		if *update && folderExists != "merge" {
+			return fmt.Errorf("upload: -u always merges into an existing folder; --%s can't be combined with it", folderExists)
+		}
+		if *staging && folderExists != "rename" {
+			return fmt.Errorf("upload: --staging publishes a new folder; --%s can't be combined with it", folderExists)
+		}
+	}
+	if fs.Changed("max-depth") {
+		if *maxDepth < 1 {
+			return fmt.Errorf("upload: --max-depth must be 1 or more")
+		}
+		if *deleteAfter {
+			return fmt.Errorf("upload: --delete-after can't be combined with --max-depth (deeper files would be deleted too)")
+		}
+	}
+	verify, err := verifyAlgo()
+	if err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	if *jobs > MaxConcurrency {
+		fmt.Fprintf(env.Stderr, "upload: --jobs %d exceeds the maximum, using %d\n", *jobs, MaxConcurrency)
+	}
+
+	opts := uploadOptions{
+		policy:   policy,
+		update:   *update,
+		force:    *force,
+		compress: *compress,
+		atomic:   *atomic,
+		staging:  *staging,
+		parents:  *makeParents,
+		mime:     *mimeType,
+		jobs:     *jobs,
+		maxDepth: *maxDepth,
+		folder:   folderExists,
+		verify:   verify,
+		quiet:    *onlyErrors,
+
+		workersPerFile:   *workersPerFile,
+		bigFileThreshold: bigFileBytes,
+	}
+	if *deleteAfter || *verifyAfter {
+		opts.result = &uploadResult{}
+	}
+	if opts.quiet {
+		// No progress bars either; failures surface as errors or through
+		// the directory printer
+		ctx = ui.WithProgressSink(ctx, ui.DiscardProgress)
+	}
+
 	if stat.IsDir() {
-		return uploadDirectoryWithPolicy(ctx, s, env, localPath, remotePath, *onDuplicate)
+		if opts.atomic {
+			fmt.Fprintln(env.Stderr, "upload: --atomic applies to single files; uploading directory directly")
+		}
+		if opts.verify != "" && !*deleteAfter {
+			fmt.Fprintln(env.Stderr, "upload: --verify applies to single files; uploading directory unverified")
+		}
+		err = uploadDirectoryWithPolicy(ctx, s, env, localPath, remotePath, opts)
+	} else {
+		if opts.staging {
+			// For a single file that is what --atomic does
+			opts.atomic = true
+		}
+		err = uploadFileWithPolicy(ctx, s, env, localPath, remotePath, opts)
+	}
+	if err != nil || opts.result == nil {
+		return err
+	}
+	if *verifyAfter && opts.result.remotePath != "" {
+		if err := verifyUploadedTree(ctx, s, env, localPath, opts.result.remotePath, *maxDepth); err != nil {
+			return err
+		}
+	}
+	if !*deleteAfter {
+		return nil
+	}
+	return removeUploadedSource(ctx, s, env, localPath, opts.result, opts.verify, *dryRun)
+}
+
+// uploadResult reports where an upload ended up, for --delete-after and
+// --verify-after.
+type uploadResult struct {
+	remotePath string          // final remote path of the file or folder
+	complete   bool            // every file was uploaded or already up to date
+	sent       map[string]bool // files this command uploaded, relative to the source ("" for a single file)
+}
+
+// removeUploadedSource deletes the local files this command uploaded from
+// localPath, but only once the upload completed and each of them has a
+// remote copy of the same size under res.remotePath, as listed fresh from
+// the server, and with algo set the same checksum too. Otherwise nothing is
+// deleted. Files that were skipped, by -u or as duplicates, are kept, and so
+// are the folders holding them. With dryRun the files that would be deleted
+// are listed instead.
+func removeUploadedSource(ctx context.Context, s *session.Session, env *ExecutionEnv, localPath string, res *uploadResult, algo string, dryRun bool) error {
+	if !res.complete {
+		return fmt.Errorf("upload: --delete-after: upload incomplete, keeping %s", localPath)
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("upload: --delete-after: %w", err)
+	}
+	if len(res.sent) == 0 {
+		fmt.Fprintf(env.Stdout, "Nothing uploaded, keeping local %s\n", localPath)
+		return nil
+	}
+	sent := func(rel string) bool { return res.sent[rel] }
+	files, unverified, err := checkUploadedTree(ctx, s, localPath, res.remotePath, 0, sent)
+	if err != nil {
+		return fmt.Errorf("upload: --delete-after: verifying: %w", err)
+	}
+	// A single file was already checked with --verify as it was uploaded
+	if algo != "" && info.IsDir() {
+		for _, local := range files {
+			if err := verifyUploadedFile(ctx, s, env, algo, localPath, res.remotePath, local); err != nil {
+				unverified = append(unverified, err.Error())
+			}
+		}
+	}
+
+	if len(unverified) > 0 {
+		for _, u := range unverified {
+			fmt.Fprintf(env.Stderr, "  ✗ %s\n", u)
+		}
+		return fmt.Errorf("upload: --delete-after: %d files could not be verified, keeping %s", len(unverified), localPath)
+	}
+
+	if dryRun {
+		for _, f := range files {
+			fmt.Fprintf(env.Stdout, "would delete %s\n", f)
+		}
+		return nil
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return fmt.Errorf("upload: --delete-after: %w", err)
+		}
+	}
+	if info.IsDir() {
+		removeEmptyDirs(localPath)
+	}
+	fmt.Fprintf(env.Stdout, "Deleted %d local files from %s (verified)\n", len(files), localPath)
+	return nil
+}
+
+// verifyUploadedFile compares the algo checksum of local, a file under the
+// uploaded folder localRoot, with its remote copy under remoteRoot.
+func verifyUploadedFile(ctx context.Context, s *session.Session, env *ExecutionEnv, algo, localRoot, remoteRoot, local string) error {
+	rel, err := filepath.Rel(localRoot, local)
+	if err != nil {
+		return err
+	}
+	entry, ok := s.Cache.Get(filepath.Join(remoteRoot, rel))
+	if !ok {
+		return fmt.Errorf("%s: no remote copy", local)
+	}
+	want, err := localDigest(algo, local)
+	if err != nil {
+		return fmt.Errorf("%s: %w", local, err)
+	}
+	return checkDigests(ctx, s, env, algo, rel, want, entry)
+}
+
+// removeEmptyDirs removes the folders under root, and root itself, that
+// are left empty, deepest first.
+func removeEmptyDirs(root string) {
+	var dirs []string
+	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	for i := len(dirs) - 1; i >= 0; i-- {
+		// Fails, as intended, on folders that still hold something
+		_ = os.Remove(dirs[i])
+	}
+}
+
+// checkUploadedTree compares the local file or folder at localPath with its
+// upload at remotePath, listed fresh from the server: every local file, down
+// to maxDepth levels (0 = all) and, when only is set, for which only(rel)
+// holds, needs a remote file of the same size. It returns the local files
+// that have one and what is wrong with the others.
+func checkUploadedTree(ctx context.Context, s *session.Session, localPath, remotePath string, maxDepth int, only func(rel string) bool) (verified, problems []string, err error) {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	rels := []string{""}
+	if info.IsDir() {
+		if rels, _, err = walkLocalDirectory(localPath, maxDepth); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := refreshPath(ctx, s, filepath.Dir(remotePath)); err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		if err := refreshPath(ctx, s, remotePath); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, rel := range rels {
+		local := filepath.Join(localPath, rel)
+		remote := filepath.Join(remotePath, rel)
+		localInfo, err := os.Stat(local)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", local, err))
+			continue
+		}
+		entry, ok := s.Cache.Get(remote)
+		if localInfo.IsDir() {
+			// Walked parents first, so each folder's listing is loaded
+			// before its files are checked
+			if ok && entry.Type == "folder" {
+				if err := refreshListing(ctx, s, remote, entry); err != nil {
+					return nil, nil, err
+				}
+			}
+			continue
+		}
+		if only != nil && !only(rel) {
+			continue
+		}
+		switch {
+		case !ok || entry.Type == "folder":
+			problems = append(problems, fmt.Sprintf("%s: no remote copy at %s", local, remote))
+		case entry.Size != localInfo.Size():
+			problems = append(problems, fmt.Sprintf("%s: remote copy is %d bytes, local file %d", local, entry.Size, localInfo.Size()))
+		default:
+			verified = append(verified, local)
+		}
+	}
+	return verified, problems, nil
+}
+
+// verifyUploadedTree reports the outcome of checkUploadedTree for
+// --verify-after: a count when everything arrived, else each discrepancy.
+func verifyUploadedTree(ctx context.Context, s *session.Session, env *ExecutionEnv, localPath, remotePath string, maxDepth int) error {
+	var verified, problems []string
+	err := ui.WithSpinnerErr(env.Stderr, "Verifying upload...", false, func() error {
+		var err error
+		verified, problems, err = checkUploadedTree(ctx, s, localPath, remotePath, maxDepth, nil)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("upload: --verify-after: %w", err)
+	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintf(env.Stderr, "  ✗ %s\n", p)
+		}
+		return fmt.Errorf("upload: --verify-after: %d of %d files missing or different in %s", len(problems), len(verified)+len(problems), remotePath)
+	}
+	fmt.Fprintf(env.Stdout, "Verified %d files in %s\n", len(verified), remotePath)
+	return nil
+}
+
+// transferFlags holds the --progress/--progress-interval and
+// --retries/--retry-delay flags shared by upload and download.
+type transferFlags struct {
+	fs               *pflag.FlagSet
+	progress         *string
+	progressInterval *string
+	retries          *string
+	retryDelay       *string
+}
+
+func addTransferFlags(fs *pflag.FlagSet) *transferFlags {
+	return &transferFlags{
+		fs:               fs,
+		progress:         fs.String("progress", "", "progress output: bar or json"),
+		progressInterval: fs.String("progress-interval", "", "minimum time between progress updates (e.g. 250ms)"),
+		retries:          fs.String("retries", "", "retries per file after the first try"),
+		retryDelay:       fs.String("retry-delay", "", "first wait between tries, doubled each time"),
+	}
+}
+
+// apply returns ctx carrying the options that were given, so they only
+// apply to this command: the progress sink (JSON progress goes to
+// env.Stderr) and update interval, and the retry policy every retry loop of
+// the command follows, the S3 uploads below it included.
+func (f *transferFlags) apply(ctx context.Context, env *ExecutionEnv) (context.Context, error) {
+	if f.fs.Changed("retries") || f.fs.Changed("retry-delay") {
+		policy := api.RetryPolicy{Retries: -1}
+		if f.fs.Changed("retries") {
+			n, err := strconv.Atoi(*f.retries)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid --retries '%s' (must be 0 or more)", *f.retries)
+			}
+			policy.Retries = n
+		}
+		if f.fs.Changed("retry-delay") {
+			d, err := time.ParseDuration(*f.retryDelay)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid --retry-delay '%s' (e.g. 500ms or 5s)", *f.retryDelay)
+			}
+			policy.Delay = d
+		}
+		ctx = api.WithRetryPolicy(ctx, policy)
+	}
+	if f.fs.Changed("progress-interval") {
+		d, err := time.ParseDuration(*f.progressInterval)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid --progress-interval '%s' (e.g. 250ms, 1s or 0 for every update)", *f.progressInterval)
+		}
+		ctx = ui.WithProgressInterval(ctx, d)
+	}
+	if f.fs.Changed("progress") {
+		sink, err := ui.ProgressSinkFor(*f.progress, env.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		ctx = ui.WithProgressSink(ctx, sink)
+	}
+	return ctx, nil
+}
+
+// uploadOptions holds the upload flags that are threaded down to file and
+// directory uploads.
+type uploadOptions struct {
+	policy   string        // duplicate policy: ask, replace, rename, skip
+	update   bool          // skip files whose remote copy is at least as new
+	force    bool          // skip the free-space pre-check
+	compress bool          // gzip compressible files, adding a .gz suffix
+	atomic   bool          // upload under a temporary name and rename once complete
+	staging  bool          // directories: upload into a hidden folder, rename once complete
+	parents  bool          // create missing folders of the destination path
+	mime     string        // content type overriding detection ("" = detect)
+	jobs     int           // parallel workers for directories (0 = session default)
+	maxDepth int           // directories: levels to upload (0 = all)
+	folder   string        // directories: merge, rename or replace an existing folder ("" = by policy)
+	result   *uploadResult // filled in for --delete-after and --verify-after (nil = not needed)
+	verify   string        // checksum to verify a single file's upload with ("" = don't)
+	quiet    bool          // --only-show-errors: report failures and the final summary only
+
+	workersPerFile   int   // part uploads a big file in a directory may run at once (0 = off)
+	bigFileThreshold int64 // size above which a file is big (0 = multipart threshold)
+}
+
+// parseMimeType checks that value looks like a MIME type ("type/subtype",
+// optionally with parameters) and returns it normalized.
+func parseMimeType(value string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid MIME type '%s'", value)
+	}
+	major, minor, ok := strings.Cut(mediaType, "/")
+	if !ok || major == "" || minor == "" || strings.Contains(minor, "/") {
+		return "", fmt.Errorf("invalid MIME type '%s' (want type/subtype, e.g. application/pdf)", value)
+	}
+	return mime.FormatMediaType(mediaType, params), nil
+}
+
+// uploadWorkers returns how many workers a directory upload of fileCount
+// files runs: the requested jobs (or the configured default), clamped to
+// MaxConcurrency and never more than there are files.
+func uploadWorkers(s *session.Session, jobs int, fileCount int) int {
+	if jobs <= 0 {
+		jobs = s.TransferJobs
+	}
+	workers := ClampConcurrency(jobs)
+	if fileCount > 0 && workers > fileCount {
+		workers = fileCount
+	}
+	return workers
+}
+
+// quotaCheckMinBytes is the smallest upload checkUploadQuota checks: below
+// it, letting a doomed upload fail costs less than the two requests.
+const quotaCheckMinBytes = 8 * 1024 * 1024
+
+// checkUploadQuota fails fast when the workspace can't hold totalBytes, the
+// bytes about to be sent, so a doomed upload is rejected before any data is
+// sent. The server's validate endpoint and the reported free space are both
+// consulted; if neither can be reached the upload is allowed to proceed and
+// fail on its own. Uploads under quotaCheckMinBytes aren't checked.
+func checkUploadQuota(ctx context.Context, s *session.Session, files []api.ValidateFile, totalBytes int64) error {
+	if totalBytes < quotaCheckMinBytes {
+		return nil
 	}
-	return uploadFileWithPolicy(ctx, s, env, localPath, remotePath, *onDuplicate)
+	_, err := s.Client.ValidateEntries(ctx, api.ValidateRequest{
+		Files:       files,
+		WorkspaceID: s.WorkspaceID,
+	})
+	rejected := api.IsQuotaExceeded(err)
+
+	usage, usageErr := s.Client.GetSpaceUsage(ctx, s.WorkspaceID)
+	if usageErr == nil && usage != nil && totalBytes > usage.Available {
+		rejected = true
+	}
+
+	if !rejected {
+		return nil
+	}
+	if usageErr == nil && usage != nil {
+		return fmt.Errorf("upload: insufficient space (need %s, have %s); use --force to try anyway",
+			formatBytes(totalBytes), formatBytes(usage.Available))
+	}
+	return fmt.Errorf("upload: insufficient space (need %s); use --force to try anyway", formatBytes(totalBytes))
+}
+
+// remoteUpToDate reports whether remotePath already exists as a file modified
+// at or after localMod, allowing for the skew between the local and server
+// clocks. The parent listing is fetched if it isn't cached yet.
+func remoteUpToDate(ctx context.Context, s *session.Session, remotePath string, localMod time.Time) bool {
+	parentDir := filepath.Dir(remotePath)
+	if !s.Cache.HasChildren(parentDir) {
+		if parent, ok := s.Cache.Get(parentDir); ok && parent.Type == "folder" {
+			parentID := parentIDPtr(parent)
+			children, err := s.Client.ListByParentIDWithOptions(ctx, parentID, api.ListOptions(s.WorkspaceID))
+			if err == nil {
+				s.Cache.AddChildren(parentDir, children)
+			}
+		}
+	}
+
+	entry, ok := s.Cache.Get(remotePath)
+	if !ok || entry.Type == "folder" {
+		return false
+	}
+	fillMetadata(ctx, s, entry)
+	if entry.UpdatedAt.IsZero() {
+		return false
+	}
+	return !entry.UpdatedAt.Before(s.ServerTime(localMod))
 }
 
 // uploadFileWithPolicy uploads a single file with the specified duplicate policy
-func uploadFileWithPolicy(ctx context.Context, s *session.Session, env *ExecutionEnv, localPath, remotePath, policy string) error {
+func uploadFileWithPolicy(ctx context.Context, s *session.Session, env *ExecutionEnv, localPath, remotePath string, opts uploadOptions) error {
 	f, err := os.Open(localPath)
 	if err != nil {
 		return err
@@ -100,47 +622,79 @@ func uploadFileWithPolicy(ctx context.Context, s *session.Session, env *Executio
 		return err
 	}
 	size := stat.Size()
+	baseName := filepath.Base(localPath)
+
+	if opts.compress && isCompressible(localPath) {
+		gz, err := gzipToTemp(f, baseName, stat.ModTime())
+		if err != nil {
+			return fmt.Errorf("upload: compress %s: %w", baseName, err)
+		}
+		defer os.Remove(gz.Name())
+		defer gz.Close()
+
+		gzStat, err := gz.Stat()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(opts.info(env), "Compressed %s: %s -> %s\n", baseName, formatBytes(size), formatBytes(gzStat.Size()))
+		f = gz
+		size = gzStat.Size()
+		baseName += ".gz"
+	}
 
 	// Resolve destination
 	destResolved, err := s.ResolvePathArg(remotePath)
 	if err != nil {
 		return fmt.Errorf("upload: %w", err)
 	}
+	if err := ensureUploadFolder(ctx, s, env, remotePath, destResolved, opts); err != nil {
+		return err
+	}
 	var parentID *int64
-	destName := filepath.Base(localPath)
+	destName := baseName
 	finalPath := filepath.Join(destResolved, destName)
 
 	// Check if destination is an existing folder
 	var destFolder string
 	if entry, ok := s.Cache.Get(destResolved); ok && entry.Type == "folder" {
-		if entry.ID != 0 {
-			parentID = &entry.ID
-		}
+		parentID = parentIDPtr(entry)
 		destFolder = destResolved
 		finalPath = filepath.Join(destResolved, destName)
 	} else {
 		// Destination might be the target filename
 		parentDir := filepath.Dir(destResolved)
 		if parentEntry, ok := s.Cache.Get(parentDir); ok && parentEntry.Type == "folder" {
-			if parentEntry.ID != 0 {
-				parentID = &parentEntry.ID
-			}
+			parentID = parentIDPtr(parentEntry)
 			destFolder = parentDir
 			destName = filepath.Base(destResolved)
 			finalPath = destResolved
 		}
 	}
 
+	policy := opts.policy
+	if opts.update {
+		if remoteUpToDate(ctx, s, finalPath, stat.ModTime()) {
+			fmt.Fprintf(opts.info(env), "Skipped: %s (remote is up to date)\n", filepath.Base(localPath))
+			opts.recordResult(finalPath, true)
+			return nil
+		}
+		// Local copy is newer, so an existing remote file gets replaced
+		policy = string(DuplicatePolicyReplace)
+	}
+
 	// Check collisions with policy
-	resolvedMap, err := checkCollisionsAndResolveWithPolicy(ctx, s.Client, s.WorkspaceID, parentID, destFolder, []string{localPath}, policy)
+	// Check collisions under the name the file is stored as, which
+	// remote_path may have changed
+	resolvedMap, err := checkCollisionsAndResolveWithPolicy(ctx, s.Client, s.WorkspaceID, parentID, destFolder, []string{destName}, policy)
 	if err != nil {
 		return err
 	}
 
-	newName, ok := resolvedMap[filepath.Base(localPath)]
+	newName, ok := resolvedMap[destName]
 	if !ok {
-		// Skipped
-		fmt.Fprintf(env.Stdout, "Skipped: %s (duplicate)\n", filepath.Base(localPath))
+		// Skipped: nothing was sent, so --delete-after keeps the file
+		fmt.Fprintf(opts.info(env), "Skipped: %s (duplicate)\n", destName)
+		opts.recordResult(finalPath, true)
 		return nil
 	}
 	if newName != destName {
@@ -148,60 +702,224 @@ func uploadFileWithPolicy(ctx context.Context, s *session.Session, env *Executio
 		finalPath = filepath.Join(destFolder, destName)
 	}
 
+	if !opts.force {
+		files := []api.ValidateFile{{Name: destName, Size: size, RelativePath: destFolder}}
+		if err := checkUploadQuota(ctx, s, files, size); err != nil {
+			return err
+		}
+	}
+
+	uploadName := destName
+	if opts.atomic {
+		uploadName = atomicUploadName(destName)
+	}
+
 	var uploadedEntry *api.FileEntry
-	err = ui.RunTransfer("Uploading "+filepath.Base(localPath), size, func(send func(int64, int64)) error {
+	err = ui.RunFileTransfer(ctx, "Uploading "+filepath.Base(localPath), localPath, size, func(send func(int64, int64)) error {
 		reader := &progressReader{
 			Reader:   f,
 			Callback: func(curr int64) { send(curr, size) },
 		}
 
 		var uploadErr error
-		uploadedEntry, uploadErr = s.Client.Upload(ctx, reader, destName, parentID, size, s.WorkspaceID)
+		uploadedEntry, uploadErr = s.Client.UploadWithOptions(ctx, reader, uploadName, parentID, size, s.WorkspaceID, &api.UploadOptions{Mime: opts.mime})
 		return uploadErr
 	})
 
 	if err != nil {
+		if opts.atomic && uploadedEntry != nil {
+			_ = s.Client.DeleteEntriesForever(ctx, []int64{uploadedEntry.ID}, s.WorkspaceID)
+		}
 		return err
 	}
 
+	if opts.atomic && uploadedEntry != nil {
+		uploadedEntry, err = commitAtomicUpload(ctx, s, uploadedEntry, destName, finalPath)
+		if err != nil {
+			return fmt.Errorf("upload: %w", err)
+		}
+	}
+
 	if uploadedEntry != nil {
 		s.Cache.Add(uploadedEntry, finalPath)
+		if opts.verify != "" {
+			// What was sent, which for --compress is the gzipped file
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("upload: %w", err)
+			}
+			want, err := digest(opts.verify, f)
+			if err != nil {
+				return fmt.Errorf("upload: verify %s: %w", localPath, err)
+			}
+			if err := checkDigests(ctx, s, env, opts.verify, destName, want, uploadedEntry); err != nil {
+				return fmt.Errorf("upload: %w", err)
+			}
+		}
 	}
+	opts.recordSent("")
+	opts.recordResult(finalPath, true)
 	return nil
 }
 
-// uploadDirectoryWithPolicy uploads a directory with the specified duplicate policy
-func uploadDirectoryWithPolicy(ctx context.Context, s *session.Session, env *ExecutionEnv, localPath, remotePath, policy string) error {
-	// For now, delegate to original uploadDirectory - full policy support would require more changes
-	// to the worker pool and session tracking. The policy is applied to individual file collisions.
-	_ = policy // TODO: Pass policy through to worker pool
-	return uploadDirectory(ctx, s, env, localPath, remotePath)
+// ensureUploadFolder makes sure the folder an upload to remotePath lands in
+// exists: remotePath itself when it ends in a slash or names a folder,
+// otherwise its parent. Missing folders are only created with -p; without
+// it the upload fails instead of guessing from what may be a typo.
+func ensureUploadFolder(ctx context.Context, s *session.Session, env *ExecutionEnv, remotePath, destResolved string, opts uploadOptions) error {
+	if entry, ok := s.Cache.Get(destResolved); ok && entry.Type == "folder" {
+		return nil
+	}
+	folder := filepath.Dir(destResolved)
+	if strings.HasSuffix(remotePath, "/") {
+		folder = destResolved
+	}
+	if entry, ok := s.Cache.Get(folder); ok {
+		if entry.Type != "folder" {
+			return fmt.Errorf("upload: '%s' is not a directory", folder)
+		}
+		return nil
+	}
+	if !opts.parents {
+		return fmt.Errorf("upload: cannot upload to '%s': No such directory (use -p to create it)", folder)
+	}
+	if err := mkdirOne(ctx, s, env, folder, true); err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	return nil
+}
+
+// recordResult notes where the upload went for --delete-after and
+// --verify-after.
+func (o uploadOptions) recordResult(remotePath string, complete bool) {
+	if o.result != nil {
+		o.result.remotePath = remotePath
+		o.result.complete = complete
+	}
+}
+
+// recordSent notes the files, relative to the source, that were actually
+// uploaded, the only ones --delete-after may delete.
+func (o uploadOptions) recordSent(rels ...string) {
+	if o.result == nil {
+		return
+	}
+	if o.result.sent == nil {
+		o.result.sent = make(map[string]bool)
+	}
+	for _, rel := range rels {
+		o.result.sent[rel] = true
+	}
+}
+
+// uploadConfig builds the worker pool settings for a folder upload of
+// totalFiles files. Fresh and resumed uploads both use it, so a resume runs
+// with the same -j, per-file worker and big-file threshold options.
+func (o uploadOptions) uploadConfig(s *session.Session, totalFiles int) UploadConfig {
+	config := DefaultUploadConfig()
+	config.Concurrency = uploadWorkers(s, o.jobs, totalFiles)
+	config.WorkersPerFile = o.workersPerFile
+	config.BigFileThreshold = o.bigFileThreshold
+	return config
+}
+
+// info returns where the upload reports what it is doing, which
+// --only-show-errors silences; failures and the summary go to env directly.
+func (o uploadOptions) info(env *ExecutionEnv) io.Writer {
+	if o.quiet {
+		return io.Discard
+	}
+	return env.Stdout
+}
+
+// atomicUploadName returns the hidden temporary name an --atomic upload of
+// name is stored under until it completes.
+func atomicUploadName(name string) string {
+	return fmt.Sprintf(".%s.%d.uploading", name, time.Now().UnixNano())
+}
+
+// commitAtomicUpload renames a completed temporary upload to name, first
+// moving a file it replaces to the trash. If the rename fails the replaced
+// file is restored and the temporary entry is kept, so neither copy is lost.
+func commitAtomicUpload(ctx context.Context, s *session.Session, temp *api.FileEntry, name, finalPath string) (*api.FileEntry, error) {
+	tempPath := filepath.Join(filepath.Dir(finalPath), temp.Name)
+	folder, _ := s.Cache.Get(filepath.Dir(finalPath))
+	var replaced *api.FileEntry
+	if existing, ok := existingChild(ctx, s, folder, filepath.Dir(finalPath), name, nil); ok && existing.Type != "folder" && existing.ID != temp.ID {
+		if err := s.Client.DeleteEntries(ctx, []int64{existing.ID}, s.WorkspaceID); err != nil {
+			s.Cache.Add(temp, tempPath)
+			return nil, fmt.Errorf("cannot replace %s, upload kept as %s: %w", name, tempPath, err)
+		}
+		s.Cache.Remove(finalPath)
+		replaced = existing
+	}
+
+	renamed, err := s.Client.RenameEntry(ctx, temp.ID, name, s.WorkspaceID)
+	if err != nil {
+		if replaced != nil {
+			if restoreErr := s.Client.RestoreEntries(ctx, []int64{replaced.ID}, s.WorkspaceID); restoreErr == nil {
+				s.Cache.Add(replaced, finalPath)
+			} else {
+				err = fmt.Errorf("%w (the replaced file is in the trash: %v)", err, restoreErr)
+			}
+		}
+		s.Cache.Add(temp, tempPath)
+		return nil, fmt.Errorf("uploaded but could not rename to %s, upload kept as %s: %w", name, tempPath, err)
+	}
+	return renamed, nil
+}
+
+// newUploadTask describes the local file at itemPath (item relative to the
+// directory being uploaded) for the worker pool. With --compress, files
+// worth gzipping are sent as <name>.gz.
+func newUploadTask(itemPath, item string, info os.FileInfo, opts uploadOptions) FileUploadTask {
+	task := FileUploadTask{
+		LocalPath:    itemPath,
+		RelativePath: item,
+		Size:         info.Size(),
+	}
+	if opts.compress && isCompressible(itemPath) {
+		task.Name = filepath.Base(itemPath) + ".gz"
+		task.Compress = true
+	}
+	return task
+}
+
+// uploadDirectoryWithPolicy uploads a directory with the specified duplicate
+// policy. An existing folder of the same name is merged into, replaced or
+// renamed around (see resolveBaseFolder); with -u it is always merged into,
+// and its files older than their local copies are replaced.
+func uploadDirectoryWithPolicy(ctx context.Context, s *session.Session, env *ExecutionEnv, localPath, remotePath string, opts uploadOptions) error {
+	return uploadDirectory(ctx, s, env, localPath, remotePath, opts)
 }
 
 // uploadDirectory uploads an entire directory tree to the remote path
-func uploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv, localPath, remotePath string) error {
+func uploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv, localPath, remotePath string, opts uploadOptions) error {
 	// Check for existing session to resume
 	existingSession, _ := FindExistingSession(localPath, remotePath)
 	if existingSession != nil {
 		completed, failed, total := existingSession.Progress()
-		if completed+failed < total {
-			fmt.Fprintf(env.Stdout, "Found incomplete upload session (started %s)\n", existingSession.StartedAt.Format("2006-01-02 15:04"))
-			fmt.Fprintf(env.Stdout, "  Progress: %d/%d files completed, %d failed\n", completed, total, failed)
-			fmt.Fprintf(env.Stdout, "Resuming upload...\n\n")
-			return resumeUploadDirectory(ctx, s, env, existingSession, localPath)
+		// A staged upload stays unpublished until every file is in
+		if completed+failed < total || existingSession.FinalName != "" {
+			fmt.Fprintf(opts.info(env), "Found incomplete upload session (started %s)\n", existingSession.StartedAt.Format("2006-01-02 15:04"))
+			fmt.Fprintf(opts.info(env), "  Progress: %d/%d files completed, %d failed\n", completed, total, failed)
+			fmt.Fprintf(opts.info(env), "Resuming upload...\n\n")
+			return resumeUploadDirectory(ctx, s, env, existingSession, localPath, opts)
 		}
 		// Session is complete, clean it up
 		_ = existingSession.Delete()
 	}
 
 	// Walk local directory to get all items
-	items, err := walkLocalDirectory(localPath)
+	items, skipped, err := walkLocalDirectory(localPath, opts.maxDepth)
 	if err != nil {
 		return fmt.Errorf("failed to scan directory: %w", err)
 	}
+	if skipped > 0 {
+		fmt.Fprintf(opts.info(env), "Skipping %d files below --max-depth %d\n", skipped, opts.maxDepth)
+	}
 
 	if len(items) == 0 {
-		fmt.Fprintf(env.Stdout, "Directory is empty, nothing to upload\n")
+		fmt.Fprintf(opts.info(env), "Directory is empty, nothing to upload\n")
 		return nil
 	}
 
@@ -210,6 +928,9 @@ func uploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv,
 	if err != nil {
 		return fmt.Errorf("upload: %w", err)
 	}
+	if err := ensureUploadFolder(ctx, s, env, remotePath, destResolved, opts); err != nil {
+		return err
+	}
 	baseDirName := filepath.Base(localPath)
 
 	// Determine parent folder for the new directory
@@ -218,77 +939,98 @@ func uploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv,
 
 	if entry, ok := s.Cache.Get(destResolved); ok && entry.Type == "folder" {
 		// Destination exists and is a folder - create our folder inside it
-		if entry.ID != 0 {
-			baseParentID = &entry.ID
-		}
+		baseParentID = parentIDPtr(entry)
 		baseFolderPath = filepath.Join(destResolved, baseDirName)
 	} else {
 		// Destination doesn't exist - use it as the target folder name
 		parentDir := filepath.Dir(destResolved)
 		if parentEntry, ok := s.Cache.Get(parentDir); ok && parentEntry.Type == "folder" {
-			if parentEntry.ID != 0 {
-				baseParentID = &parentEntry.ID
-			}
+			baseParentID = parentIDPtr(parentEntry)
 		}
 		baseDirName = filepath.Base(destResolved)
 		baseFolderPath = destResolved
 	}
 
-	// Create the base folder
-	// Check collision for base folder?
-	// If base folder exists, we might want to merge or rename.
-	// For now, let's assume we just create it or use existing if CreateFolder handles it (it usually returns existing).
-	// But if we want to support "Keep Both" for the root folder of the upload, we should check.
-	// However, CreateFolder usually returns existing if it exists.
-	// Let's stick to standard behavior for folders for now, as user asked about files mostly.
-	// But if the user uploads "project" and "project" exists, maybe they want "project (1)".
-	// Let's check collision for the base folder.
+	// Separate folders and files, leaving out files -u finds up to date (-u
+	// always merges, so baseFolderPath is where their copies are)
+	var folders []string
+	var files []FileUploadTask
+	upToDate := 0
+	for _, item := range items {
+		itemPath := filepath.Join(localPath, item)
+		info, err := os.Stat(itemPath)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			folders = append(folders, item)
+		} else {
+			task := newUploadTask(itemPath, item, info, opts)
+			remote := filepath.Join(baseFolderPath, filepath.Dir(item), task.remoteName())
+			if opts.update && remoteUpToDate(ctx, s, remote, info.ModTime()) {
+				upToDate++
+				continue
+			}
+			files = append(files, task)
+		}
+	}
+	if upToDate > 0 {
+		fmt.Fprintf(opts.info(env), "Skipping %d files that are up to date\n", upToDate)
+	}
+
+	if !opts.force {
+		var quotaFiles []api.ValidateFile
+		var totalBytes int64
+		for _, task := range files {
+			quotaFiles = append(quotaFiles, api.ValidateFile{
+				Name:         filepath.Base(task.RelativePath),
+				Size:         task.Size,
+				RelativePath: filepath.Dir(task.RelativePath),
+			})
+			totalBytes += task.Size
+		}
+		if err := checkUploadQuota(ctx, s, quotaFiles, totalBytes); err != nil {
+			return err
+		}
+	}
 
-	// Check collision for base folder
-	resolvedMap, err := checkCollisionsAndResolve(ctx, s.Client, s.WorkspaceID, baseParentID, filepath.Dir(baseFolderPath), []string{filepath.Join(filepath.Dir(localPath), baseDirName)})
+	// An existing folder of the same name is merged into, replaced or kept
+	// next to a renamed copy
+	merged, newName, ok, err := resolveBaseFolder(ctx, s, env, baseParentID, filepath.Dir(baseFolderPath), baseDirName, opts)
 	if err != nil {
 		return err
 	}
-
-	newName, ok := resolvedMap[baseDirName]
 	if !ok {
-		return nil // Skipped
+		fmt.Fprintf(opts.info(env), "Skipped: %s (already exists)\n", baseFolderPath)
+		return nil
 	}
 	baseDirName = newName
 	baseFolderPath = filepath.Join(filepath.Dir(baseFolderPath), baseDirName)
 
-	fmt.Fprintf(env.Stdout, "Creating folder: %s\n", baseFolderPath)
-	baseFolder, err := s.Client.CreateFolder(ctx, baseDirName, baseParentID, s.WorkspaceID)
-	if err != nil {
-		return fmt.Errorf("failed to create folder %s: %w", baseDirName, err)
+	finalName, finalPath := "", baseFolderPath
+	if opts.staging {
+		finalName = baseDirName
+		baseDirName = atomicUploadName(baseDirName)
+		baseFolderPath = filepath.Join(filepath.Dir(baseFolderPath), baseDirName)
+	}
+
+	baseFolder := merged
+	if merged != nil {
+		fmt.Fprintf(opts.info(env), "Merging into existing folder: %s\n", baseFolderPath)
+	} else {
+		fmt.Fprintf(opts.info(env), "Creating folder: %s\n", baseFolderPath)
+		baseFolder, err = s.Client.CreateFolder(ctx, baseDirName, baseParentID, s.WorkspaceID)
+		if err != nil {
+			return fmt.Errorf("failed to create folder %s: %w", baseDirName, err)
+		}
+		s.Cache.Add(baseFolder, baseFolderPath)
 	}
-	s.Cache.Add(baseFolder, baseFolderPath)
 
 	// Track created folders: relative path -> folder ID
 	createdFolders := map[string]int64{
 		"": baseFolder.ID,
 	}
 
-	// Separate folders and files
-	var folders []string
-	var files []FileUploadTask
-	for _, item := range items {
-		itemPath := filepath.Join(localPath, item)
-		info, err := os.Stat(itemPath)
-		if err != nil {
-			continue
-		}
-		if info.IsDir() {
-			folders = append(folders, item)
-		} else {
-			files = append(files, FileUploadTask{
-				LocalPath:    itemPath,
-				RelativePath: item,
-				Size:         info.Size(),
-			})
-		}
-	}
-
 	// Create all folders first (they come sorted by depth from walkLocalDirectory)
 	for _, folder := range folders {
 		parentRelPath := filepath.Dir(folder)
@@ -302,19 +1044,54 @@ func uploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv,
 		}
 
 		folderName := filepath.Base(folder)
+		folderPath := filepath.Join(baseFolderPath, folder)
+		if merged != nil {
+			// Reuse the folders the merged tree already has
+			parentPath := filepath.Dir(folderPath)
+			parentEntry, _ := s.Cache.Get(parentPath)
+			if existing, ok := existingChild(ctx, s, parentEntry, parentPath, folderName, nil); ok && existing.Type == "folder" {
+				createdFolders[folder] = existing.ID
+				continue
+			}
+		}
 		newFolder, err := s.Client.CreateFolder(ctx, folderName, &parentID, s.WorkspaceID)
 		if err != nil {
 			fmt.Fprintf(env.Stderr, "Warning: failed to create folder %s: %v\n", folder, err)
 			continue
 		}
 		createdFolders[folder] = newFolder.ID
-		s.Cache.Add(newFolder, filepath.Join(baseFolderPath, folder))
+		s.Cache.Add(newFolder, folderPath)
+		if merged != nil {
+			// New, so there is nothing in it to collide with
+			s.Cache.MarkChildrenLoaded(folderPath)
+		}
+	}
+
+	if merged != nil {
+		// With -u only newer files are left, and they replace their copies
+		policy := opts.policy
+		if opts.update {
+			policy = string(DuplicatePolicyReplace)
+		}
+		var skipped int
+		files, skipped, err = resolveMergeCollisions(ctx, s, baseFolderPath, files, createdFolders, policy)
+		if err != nil {
+			return err
+		}
+		if skipped > 0 {
+			fmt.Fprintf(opts.info(env), "Skipping %d files already in %s\n", skipped, baseFolderPath)
+		}
 	}
 
 	// Upload files with progress
 	totalFiles := len(files)
 	if totalFiles == 0 {
-		fmt.Fprintf(env.Stdout, "No files to upload (only folders created)\n")
+		if opts.staging {
+			if err := publishStagedUpload(ctx, s, baseFolder.ID, baseFolderPath, finalName); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(opts.info(env), "No files to upload (only folders created)\n")
 		return nil
 	}
 
@@ -328,18 +1105,19 @@ func uploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv,
 	// Save folder info to session
 	if uploadSession != nil {
 		uploadSession.SetBaseFolderInfo(baseFolder.ID, baseFolderPath)
+		uploadSession.FinalName = finalName
 		for folder, id := range createdFolders {
 			uploadSession.MarkFolderCreated(folder, id)
 		}
 		_ = uploadSession.Save()
 	}
 
-	// Create upload config
-	config := DefaultUploadConfig()
+	config := opts.uploadConfig(s, totalFiles)
 
-	fmt.Fprintf(env.Stdout, "Uploading %d files (%d parallel workers)...\n", totalFiles, config.Concurrency)
+	fmt.Fprintf(opts.info(env), "Uploading %d files (%d parallel workers)...\n", totalFiles, config.Concurrency)
 
 	// Set parent IDs for all files based on their folder
+	orphans := 0
 	for i := range files {
 		parentRelPath := filepath.Dir(files[i].RelativePath)
 		if parentRelPath == "." {
@@ -350,13 +1128,15 @@ func uploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv,
 		} else {
 			// Skip files with missing parent
 			fmt.Fprintf(env.Stderr, "  ✗ %s (parent folder missing)\n", files[i].RelativePath)
+			orphans++
 		}
 	}
 
 	// Create and start worker pool
 	pool := NewWorkerPool(ctx, s.Client, s.Cache, baseFolderPath, config, uploadSession, s.WorkspaceID)
 
-	printer := NewProgressPrinter()
+	printer := NewProgressPrinter(ctx, config.Concurrency)
+	printer.onlyErrors = opts.quiet
 	pool.SetCallbacks(printer.OnProgress, printer.OnFile)
 
 	pool.Start()
@@ -372,9 +1152,22 @@ func uploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv,
 	stats := pool.Close()
 	printer.Finish()
 
+	complete := stats.Failed == 0 && orphans == 0
+	var publishErr error
+	if opts.staging {
+		if complete {
+			publishErr = publishStagedUpload(ctx, s, baseFolder.ID, baseFolderPath, finalName)
+		}
+		if !complete || publishErr != nil {
+			fmt.Fprintf(env.Stdout, "\nNothing published; the uploaded files stay in %s\n", baseFolderPath)
+		} else {
+			baseFolderPath = finalPath
+		}
+	}
+
 	// Clean up session if successful
 	if uploadSession != nil {
-		if stats.Failed == 0 {
+		if stats.Failed == 0 && publishErr == nil && (!opts.staging || complete) {
 			_ = uploadSession.Delete()
 		} else {
 			fmt.Fprintf(env.Stdout, "\nSession saved. Run the same command to resume.\n")
@@ -384,7 +1177,8 @@ func uploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv,
 	// Summary
 	if stats.Failed > 0 {
 		fmt.Fprintf(env.Stdout, "\nUploaded %d files, %d failed\n", stats.Uploaded, stats.Failed)
-		if len(stats.Errors) > 0 && len(stats.Errors) <= 10 {
+		// --only-show-errors already printed each failure as it happened
+		if len(stats.Errors) > 0 && len(stats.Errors) <= 10 && !opts.quiet {
 			fmt.Fprintf(env.Stdout, "Failed files:\n")
 			for _, e := range stats.Errors {
 				fmt.Fprintf(env.Stdout, "  - %s: %s\n", e.Path, e.Error)
@@ -393,14 +1187,164 @@ func uploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv,
 	} else {
 		fmt.Fprintf(env.Stdout, "\nUploaded %d files to %s\n", stats.Uploaded, baseFolderPath)
 	}
+	if publishErr != nil {
+		return publishErr
+	}
+	opts.recordSent(stats.Sent...)
+	opts.recordResult(baseFolderPath, complete)
 
 	return nil
 }
 
+// resolveBaseFolder decides what a directory upload does when the folder it
+// creates, name in parentPath, already exists: merge into it (returning it),
+// trash it first, or create a renamed copy (returning the new name). The
+// choice is --merge/--replace/--rename, or -u, which always merges, or else
+// the duplicate policy, whose replace keeps merging as before. ok is false
+// when the upload is skipped.
+func resolveBaseFolder(ctx context.Context, s *session.Session, env *ExecutionEnv, parentID *int64, parentPath, name string, opts uploadOptions) (*api.FileEntry, string, bool, error) {
+	parentEntry, _ := s.Cache.Get(parentPath)
+	existing, exists := existingChild(ctx, s, parentEntry, parentPath, name, nil)
+	if !exists {
+		return nil, name, true, nil
+	}
+	path := filepath.Join(parentPath, name)
+
+	mode := opts.folder
+	switch {
+	case mode != "":
+	case opts.update, opts.policy == string(DuplicatePolicyReplace):
+		mode = "merge"
+	case opts.policy == string(DuplicatePolicyAsk):
+		choice, err := askFolderConflict(name)
+		if err != nil {
+			return nil, "", false, fmt.Errorf("upload: %w", err)
+		}
+		mode = map[ConflictResolution]string{
+			ResolutionMerge:     "merge",
+			ResolutionKeepBoth:  "rename",
+			ResolutionOverwrite: "replace",
+			ResolutionSkip:      "skip",
+		}[choice]
+	default:
+		mode = opts.policy
+	}
+
+	switch mode {
+	case "merge":
+		if existing.Type != "folder" {
+			return nil, "", false, fmt.Errorf("upload: cannot merge into '%s': not a folder", path)
+		}
+		return existing, name, true, nil
+	case "replace":
+		if err := s.Client.DeleteEntries(ctx, []int64{existing.ID}, s.WorkspaceID); err != nil {
+			return nil, "", false, fmt.Errorf("upload: cannot replace '%s': %w", path, err)
+		}
+		s.Cache.Remove(path)
+		fmt.Fprintf(opts.info(env), "Moved existing %s to the trash\n", path)
+		return nil, name, true, nil
+	case "rename":
+		resp, err := s.Client.GetAvailableName(ctx, api.GetAvailableNameRequest{Name: name, ParentID: parentID, WorkspaceID: s.WorkspaceID})
+		if err != nil {
+			return nil, "", false, fmt.Errorf("upload: failed to get available name for %s: %w", name, err)
+		}
+		return nil, resp.Name, true, nil
+	}
+	return nil, "", false, nil
+}
+
+// resolveMergeCollisions applies policy to the files of an upload merged
+// into an existing folder that are already there: skipped ones are left out
+// and renamed ones get their new name, while replaced ones are uploaded and
+// the old copy then moved to the trash. It returns the files to upload and
+// how many were skipped.
+func resolveMergeCollisions(ctx context.Context, s *session.Session, baseFolderPath string, files []FileUploadTask, folders map[string]int64, policy string) ([]FileUploadTask, int, error) {
+	kept := files[:0]
+	skipped := 0
+	for _, task := range files {
+		rel := filepath.Dir(task.RelativePath)
+		if rel == "." {
+			rel = ""
+		}
+		parentID, ok := folders[rel]
+		if !ok {
+			kept = append(kept, task)
+			continue
+		}
+		parentPath := filepath.Join(baseFolderPath, rel)
+		parentEntry, _ := s.Cache.Get(parentPath)
+		name := task.remoteName()
+		existing, exists := existingChild(ctx, s, parentEntry, parentPath, name, nil)
+		if !exists {
+			kept = append(kept, task)
+			continue
+		}
+
+		switch policy {
+		case string(DuplicatePolicyReplace):
+			if existing.Type != "folder" {
+				task.Replaces = existing.ID
+			}
+		case string(DuplicatePolicySkip):
+			skipped++
+			continue
+		case string(DuplicatePolicyRename):
+			resp, err := s.Client.GetAvailableName(ctx, api.GetAvailableNameRequest{Name: name, ParentID: &parentID, WorkspaceID: s.WorkspaceID})
+			if err != nil {
+				return nil, 0, fmt.Errorf("upload: failed to get available name for %s: %w", task.RelativePath, err)
+			}
+			task.Name = resp.Name
+		default:
+			newName, proceed, err := ResolveConflict(ctx, s.Client, s.WorkspaceID, &parentID, name)
+			if err != nil {
+				return nil, 0, fmt.Errorf("upload: %w", err)
+			}
+			if !proceed {
+				skipped++
+				continue
+			}
+			if newName != name {
+				task.Name = newName
+			} else if existing.Type != "folder" {
+				task.Replaces = existing.ID
+			}
+		}
+		kept = append(kept, task)
+	}
+	return kept, skipped, nil
+}
+
+// publishStagedUpload renames the hidden folder a --staging upload went
+// into to its final name. The folder is kept when that fails, so the next
+// run can publish it.
+func publishStagedUpload(ctx context.Context, s *session.Session, stagingID int64, stagingPath, finalName string) error {
+	dir := filepath.Dir(stagingPath)
+	finalPath := filepath.Join(dir, finalName)
+	parent, _ := s.Cache.Get(dir)
+	if _, ok := existingChild(ctx, s, parent, dir, finalName, nil); ok {
+		return fmt.Errorf("upload: cannot publish %s: it was created while uploading (staged files are in %s)", finalPath, stagingPath)
+	}
+
+	renamed, err := s.Client.RenameEntry(ctx, stagingID, finalName, s.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("upload: uploaded to %s but could not rename it to %s: %w", stagingPath, finalName, err)
+	}
+	prefix := stagingPath + "/"
+	for _, p := range s.Cache.AllPaths() {
+		if p == stagingPath || strings.HasPrefix(p, prefix) {
+			s.Cache.Remove(p)
+		}
+	}
+	if renamed != nil {
+		s.Cache.Add(renamed, finalPath)
+	}
+	return nil
+}
+
 // resumeUploadDirectory resumes an interrupted directory upload
-func resumeUploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv, uploadSession *UploadSession, localPath string) error {
+func resumeUploadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv, uploadSession *UploadSession, localPath string, opts uploadOptions) error {
 	// Walk local directory to get all items
-	items, err := walkLocalDirectory(localPath)
+	items, _, err := walkLocalDirectory(localPath, opts.maxDepth)
 	if err != nil {
 		return fmt.Errorf("failed to scan directory: %w", err)
 	}
@@ -434,11 +1378,7 @@ func resumeUploadDirectory(ctx context.Context, s *session.Session, env *Executi
 			if uploadSession.IsFileCompleted(item, info.Size()) {
 				continue
 			}
-			files = append(files, FileUploadTask{
-				LocalPath:    itemPath,
-				RelativePath: item,
-				Size:         info.Size(),
-			})
+			files = append(files, newUploadTask(itemPath, item, info, opts))
 		}
 	}
 
@@ -476,18 +1416,29 @@ func resumeUploadDirectory(ctx context.Context, s *session.Session, env *Executi
 	// Upload remaining files
 	totalFiles := len(files)
 	if totalFiles == 0 {
-		fmt.Fprintf(env.Stdout, "All files already uploaded!\n")
+		if uploadSession.FinalName != "" {
+			if err := publishStagedUpload(ctx, s, uploadSession.BaseFolderID, baseFolderPath, uploadSession.FinalName); err != nil {
+				return err
+			}
+			baseFolderPath = filepath.Join(filepath.Dir(baseFolderPath), uploadSession.FinalName)
+		}
+		fmt.Fprintf(opts.info(env), "All files already uploaded!\n")
+		for rel := range uploadSession.CompletedFiles {
+			opts.recordSent(rel)
+		}
 		_ = uploadSession.Delete()
+		opts.recordResult(baseFolderPath, true)
 		return nil
 	}
 
-	config := DefaultUploadConfig()
+	config := opts.uploadConfig(s, totalFiles)
 
 	alreadyDone := len(uploadSession.CompletedFiles)
-	fmt.Fprintf(env.Stdout, "Resuming: %d files remaining (%d already done, %d parallel workers)...\n",
+	fmt.Fprintf(opts.info(env), "Resuming: %d files remaining (%d already done, %d parallel workers)...\n",
 		totalFiles, alreadyDone, config.Concurrency)
 
 	// Set parent IDs for all files
+	orphans := 0
 	for i := range files {
 		parentRelPath := filepath.Dir(files[i].RelativePath)
 		if parentRelPath == "." {
@@ -497,13 +1448,15 @@ func resumeUploadDirectory(ctx context.Context, s *session.Session, env *Executi
 			files[i].ParentID = parentID
 		} else {
 			fmt.Fprintf(env.Stderr, "  ✗ %s (parent folder missing)\n", files[i].RelativePath)
+			orphans++
 		}
 	}
 
 	// Create and start worker pool
 	pool := NewWorkerPool(ctx, s.Client, s.Cache, baseFolderPath, config, uploadSession, s.WorkspaceID)
 
-	printer := NewProgressPrinter()
+	printer := NewProgressPrinter(ctx, config.Concurrency)
+	printer.onlyErrors = opts.quiet
 	pool.SetCallbacks(printer.OnProgress, printer.OnFile)
 
 	pool.Start()
@@ -519,8 +1472,17 @@ func resumeUploadDirectory(ctx context.Context, s *session.Session, env *Executi
 	stats := pool.Close()
 	printer.Finish()
 
+	complete := stats.Failed == 0 && orphans == 0
+	if uploadSession.FinalName != "" && complete {
+		if err := publishStagedUpload(ctx, s, uploadSession.BaseFolderID, baseFolderPath, uploadSession.FinalName); err != nil {
+			_ = uploadSession.Save()
+			return err
+		}
+		baseFolderPath = filepath.Join(filepath.Dir(baseFolderPath), uploadSession.FinalName)
+	}
+
 	// Clean up session if successful
-	if stats.Failed == 0 {
+	if stats.Failed == 0 && (uploadSession.FinalName == "" || complete) {
 		_ = uploadSession.Delete()
 		fmt.Fprintf(env.Stdout, "\nUpload complete! %d files uploaded (total: %d)\n",
 			stats.Uploaded, stats.Uploaded+int64(alreadyDone))
@@ -529,11 +1491,134 @@ func resumeUploadDirectory(ctx context.Context, s *session.Session, env *Executi
 		fmt.Fprintf(env.Stdout, "\n%d files uploaded, %d failed. Run the same command to retry.\n",
 			stats.Uploaded, stats.Failed)
 	}
+	// What earlier runs of the same upload sent counts as well
+	for rel := range uploadSession.CompletedFiles {
+		opts.recordSent(rel)
+	}
+	opts.recordResult(baseFolderPath, complete)
 
 	return nil
 }
 
 func download(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("download", pflag.ContinueOnError)
+	transfer := addTransferFlags(fs)
+	fromFile := fs.String("from-file", "", "read remote paths from a local file")
+	raw := fs.Bool("raw", false, "keep files compressed by upload --compress as .gz")
+	interactive := fs.BoolP("interactive", "i", false, "ask before overwriting local files")
+	noClobber := fs.BoolP("no-clobber", "n", false, "never overwrite local files")
+	outputDir := fs.StringP("output-dir", "o", "", "download into dir, creating it as needed")
+	noZip := fs.Bool("no-zip", false, "download folders file by file")
+	fs.Bool("preserve-structure", false, "alias for --no-zip")
+	forceZip := fs.Bool("zip", false, "always download folders as a zip")
+	strip := fs.Int("strip-components", 0, "drop the first N path components of folder contents")
+	asTar := fs.Bool("tar", false, "write a folder to stdout as a tar archive")
+	verifyAlgo := addVerifyFlags(s, fs)
+	requireResume := fs.Bool("resume", false, "resume a partial local file, failing if there is none")
+	noResume := fs.Bool("no-resume", false, "download from the first byte, replacing any partial local file")
+	partialSuffix := fs.String("partial-suffix", "", "suffix of files still being downloaded (default .drime-partial)")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	args = fs.Args()
+	ctx, err := transfer.apply(ctx, env)
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	if fs.Changed("partial-suffix") {
+		if *partialSuffix == "" || strings.ContainsAny(*partialSuffix, `/\`) {
+			return fmt.Errorf("download: invalid --partial-suffix '%s' (must be non-empty, without path separators)", *partialSuffix)
+		}
+		ctx = context.WithValue(ctx, partialSuffixKey{}, *partialSuffix)
+	} else if strings.ContainsAny(s.PartialSuffix, `/\`) {
+		return fmt.Errorf("download: invalid partial_suffix in config: '%s' (must not contain path separators)", s.PartialSuffix)
+	}
+	// --resume=false reads as --no-resume
+	resume := resumeAuto
+	switch {
+	case *requireResume && *noResume:
+		return fmt.Errorf("download: --resume and --no-resume are mutually exclusive")
+	case *requireResume:
+		resume = resumeRequired
+	case *noResume || fs.Changed("resume"):
+		resume = resumeNever
+	}
+	if resume != resumeAuto {
+		switch {
+		case s.InVault:
+			return fmt.Errorf("download: --resume and --no-resume don't apply in the vault, where files are always downloaded whole")
+		case *asTar:
+			return fmt.Errorf("download: --resume and --no-resume can't be combined with --tar")
+		case resume == resumeRequired && (*interactive || *noClobber):
+			return fmt.Errorf("download: --resume can't be combined with -i or -n, which never resume")
+		}
+	}
+	if preserve, _ := fs.GetBool("preserve-structure"); preserve {
+		*noZip = true
+	}
+	if *noZip && *forceZip {
+		return fmt.Errorf("download: --no-zip and --zip are mutually exclusive")
+	}
+	if *strip < 0 {
+		return fmt.Errorf("download: invalid --strip-components %d", *strip)
+	}
+	if *strip > 0 && s.InVault {
+		return fmt.Errorf("download: --strip-components is not supported in the vault")
+	}
+	if *asTar {
+		switch {
+		case len(args) != 2 || args[1] != "-" || *fromFile != "":
+			return fmt.Errorf("usage: download --tar <folder> -")
+		case *noZip || *forceZip || *strip > 0 || *outputDir != "":
+			return fmt.Errorf("download: --tar cannot be combined with --no-zip, --zip, --strip-components or -o")
+		case s.InVault:
+			return fmt.Errorf("download: --tar is not supported in the vault")
+		}
+	}
+	verify, err := verifyAlgo()
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	if verify != "" && (*asTar || *fromFile != "") {
+		return fmt.Errorf("download: --verify applies to single files")
+	}
+	folders := folderMode{perFile: *noZip, zip: *forceZip, strip: *strip, raw: *raw}
+
+	// As with cp, the last of -i and -n wins; here -n is the safer choice
+	clobber := clobberOverwrite
+	switch {
+	case *noClobber:
+		clobber = clobberNever
+	case *interactive:
+		clobber = clobberAsk
+	}
+
+	// Without a local path or -o, files go to the configured download
+	// directory, created as needed
+	useDownloadDir := *outputDir == "" && s.DownloadDir != ""
+
+	if *fromFile != "" {
+		localPath := "."
+		if len(args) >= 1 {
+			localPath = args[0]
+		} else if useDownloadDir {
+			localPath = s.DownloadDir
+		}
+		if *outputDir != "" {
+			localPath = filepath.Join(*outputDir, localPath)
+		}
+		if !confirmLocalTarget(env, localPath) {
+			return fmt.Errorf("download: not writing outside the current directory")
+		}
+		if *outputDir != "" || (len(args) == 0 && useDownloadDir) {
+			if err := os.MkdirAll(localPath, 0755); err != nil {
+				return fmt.Errorf("download: %w", err)
+			}
+		}
+		return downloadFromFile(ctx, s, env, *fromFile, localPath, *raw, clobber, resume, folders)
+	}
+
 	if len(args) < 1 {
 		return fmt.Errorf("usage: download <remote_path> [local_path]")
 	}
@@ -542,6 +1627,18 @@ func download(ctx context.Context, s *session.Session, env *ExecutionEnv, args [
 	localPath := "." // Default to current directory
 	if len(args) >= 2 {
 		localPath = args[1]
+		useDownloadDir = false
+	} else if useDownloadDir {
+		localPath = s.DownloadDir
+	}
+	if *outputDir != "" {
+		if localPath == "-" {
+			return fmt.Errorf("download: --output-dir cannot be used with '-'")
+		}
+		localPath = filepath.Join(*outputDir, localPath)
+	}
+	if localPath != "-" && !confirmLocalTarget(env, localPath) {
+		return fmt.Errorf("download: not writing outside the current directory")
 	}
 
 	// Resolve remote path and find the entry
@@ -550,28 +1647,252 @@ func download(ctx context.Context, s *session.Session, env *ExecutionEnv, args [
 		return fmt.Errorf("download: %w", err)
 	}
 
+	// Like mkdir -p: the output directory, and any missing parents of a
+	// local path given under it, are created once the source is known
+	if *outputDir != "" {
+		dir := filepath.Dir(localPath)
+		if localPath == filepath.Clean(*outputDir) {
+			dir = localPath
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+	}
+	if useDownloadDir {
+		if err := os.MkdirAll(localPath, 0755); err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+	}
+
+	if *asTar {
+		if entry.Type != "folder" {
+			return fmt.Errorf("download: %s: --tar needs a folder", remotePath)
+		}
+		return downloadTar(ctx, s, env, entry, remotePath)
+	}
+	if verify != "" && (entry.Type == "folder" || localPath == "-") {
+		return fmt.Errorf("download: --verify applies to single files saved locally")
+	}
+	if localPath == "-" {
+		if entry.Type == "folder" {
+			return fmt.Errorf("download: %s: cannot write a folder to stdout (use --tar)", remotePath)
+		}
+		if s.InVault {
+			return fmt.Errorf("download: writing vault files to stdout is not supported (use cat)")
+		}
+		return downloadToStdout(ctx, s, env, entry)
+	}
+
 	// Handle vault downloads separately (requires decryption)
 	if s.InVault {
 		if entry.Type == "folder" {
-			return downloadVaultDirectory(ctx, s, env, entry, remotePath, localPath)
+			return downloadVaultDirectory(ctx, s, env, entry, remotePath, localPath, clobber)
+		}
+		if err := downloadVaultFile(ctx, s, env, entry, localPath, clobber); err != nil || verify == "" {
+			return err
 		}
-		return downloadVaultFile(ctx, s, env, entry, localPath)
+		return verifyDownload(ctx, s, env, verify, entry, localPath)
 	}
 
 	if entry.Type == "folder" {
-		return downloadDirectory(ctx, s, env, entry, remotePath, localPath)
+		if resume != resumeAuto {
+			return fmt.Errorf("download: %s: --resume and --no-resume apply to files", remotePath)
+		}
+		return downloadFolder(ctx, s, env, entry, remotePath, localPath, clobber, folders)
+	}
+	if err := downloadFile(ctx, s, env, entry, localPath, clobber, resume); err != nil {
+		return err
+	}
+	if verify != "" {
+		if err := verifyDownload(ctx, s, env, verify, entry, localPath); err != nil {
+			return err
+		}
+	}
+	if *raw {
+		return nil
+	}
+	return decompressIfMarked(env, downloadTarget(entry, localPath), clobber)
+}
+
+// verifyDownload checks the file entry was downloaded to (as located by
+// downloadTarget) against the remote copy. A file that -n kept is checked
+// too, which tells whether it is the same file.
+func verifyDownload(ctx context.Context, s *session.Session, env *ExecutionEnv, algo string, entry *api.FileEntry, localPath string) error {
+	if err := verifyLocalCopy(ctx, s, env, algo, downloadTarget(entry, localPath), entry); err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	return nil
+}
+
+// downloadFromFile downloads every remote path listed in listPath into
+// localDir, continuing past failures and summarizing them at the end.
+func downloadFromFile(ctx context.Context, s *session.Session, env *ExecutionEnv, listPath, localDir string, raw bool, clobber clobberMode, resume resumeMode, folders folderMode) error {
+	paths, err := readPathList(env, listPath)
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+
+	info, err := os.Stat(localDir)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("download: %s: Not a directory", localDir)
+	}
+
+	var failures []string
+	for _, p := range paths {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		entry, err := ResolveEntry(ctx, s, p)
+		if err == nil {
+			switch {
+			case s.InVault && entry.Type == "folder":
+				err = downloadVaultDirectory(ctx, s, env, entry, p, localDir, clobber)
+			case s.InVault:
+				err = downloadVaultFile(ctx, s, env, entry, localDir, clobber)
+			case entry.Type == "folder":
+				err = downloadFolder(ctx, s, env, entry, p, localDir, clobber, folders)
+			default:
+				err = downloadFile(ctx, s, env, entry, localDir, clobber, resume)
+				if err == nil && !raw {
+					err = decompressIfMarked(env, downloadTarget(entry, localDir), clobber)
+				}
+			}
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", p, err))
+		}
+	}
+
+	fmt.Fprintf(env.Stderr, "Downloaded %d of %d paths\n", len(paths)-len(failures), len(paths))
+	for _, f := range failures {
+		fmt.Fprintf(env.Stderr, "  %s %s\n", ui.ErrorStyle.Render("✗"), f)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("download: %d of %d paths failed", len(failures), len(paths))
+	}
+	return nil
+}
+
+// escapesWorkingDir reports whether the relative local path p climbs out of
+// the current directory through ".." components. Absolute paths are taken as
+// meant.
+func escapesWorkingDir(p string) bool {
+	if filepath.IsAbs(p) {
+		return false
+	}
+	clean := filepath.Clean(p)
+	return clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
+
+// confirmLocalTarget asks before a download writes to a relative path
+// outside the current directory, such as ../../etc/passwd. Without an answer
+// on env.Stdin the download is refused.
+func confirmLocalTarget(env *ExecutionEnv, localPath string) bool {
+	if !escapesWorkingDir(localPath) {
+		return true
+	}
+	fmt.Fprintf(env.Stderr, "download: '%s' is outside the current directory; write there? [y/N] ", localPath)
+	return strings.ToLower(strings.TrimSpace(readAnswer(env.Stdin))) == "y"
+}
+
+// localName checks that a remote entry name is safe to use as a single local
+// path component, so a name like ".." or "a/../../b" from the server cannot
+// place a download outside its target directory.
+func localName(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("unsafe file name %q", name)
+	}
+	return name, nil
+}
+
+// withinDir reports whether p is dir or lies below it.
+func withinDir(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// downloadTarget returns the local file a download of entry to localPath
+// writes: inside localPath when it is an existing directory, else localPath.
+func downloadTarget(entry *api.FileEntry, localPath string) string {
+	if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+		return filepath.Join(localPath, entry.Name)
+	}
+	return localPath
+}
+
+// resumeMode says what downloadFile does with a partial local file.
+type resumeMode int
+
+const (
+	resumeAuto     resumeMode = iota // Resume a partial file, else start over (default)
+	resumeNever                      // --no-resume: always start from the first byte
+	resumeRequired                   // --resume: fail unless there is a partial file
+)
+
+// clobberMode says what a download does with local files already in the way.
+type clobberMode int
+
+const (
+	clobberOverwrite clobberMode = iota // Overwrite, or resume a partial file (default)
+	clobberAsk                          // -i: prompt before overwriting
+	clobberNever                        // -n: keep the local file and skip
+)
+
+// allow reports whether the local file at path may be replaced, prompting
+// on env.Stdin in ask mode. Paths that don't exist are always allowed.
+func (m clobberMode) allow(env *ExecutionEnv, path string) bool {
+	if m == clobberOverwrite {
+		return true
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return true
+	}
+	if m == clobberNever {
+		fmt.Fprintf(env.Stderr, "download: not overwriting '%s'\n", path)
+		return false
+	}
+	fmt.Fprintf(env.Stderr, "download: overwrite '%s'? [y/N] ", path)
+	return strings.ToLower(strings.TrimSpace(readAnswer(env.Stdin))) == "y"
+}
+
+// readAnswer reads one line from r a byte at a time, so that a series of
+// prompts answered from a pipe each get their own line.
+func readAnswer(r io.Reader) string {
+	if r == nil {
+		return ""
+	}
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			break
+		}
 	}
-	return downloadFile(ctx, s, env, entry, localPath)
+	return string(line)
 }
 
 // downloadFile downloads a single file with retry and resume support
-func downloadFile(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, localPath string) error {
+func downloadFile(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, localPath string, clobber clobberMode, resume resumeMode) error {
 	// Determine final local path
 	finalPath := localPath
 	info, err := os.Stat(localPath)
 	if err == nil && info.IsDir() {
 		// localPath is an existing directory, put file inside it
-		finalPath = filepath.Join(localPath, entry.Name)
+		name, err := localName(entry.Name)
+		if err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+		finalPath = filepath.Join(localPath, name)
 	} else if os.IsNotExist(err) {
 		// Check if parent exists
 		parentDir := filepath.Dir(localPath)
@@ -584,28 +1905,143 @@ func downloadFile(ctx context.Context, s *session.Session, env *ExecutionEnv, en
 		finalPath = localPath
 	}
 
-	// Check for existing partial file to resume
-	var resumeOffset int64
-	existingInfo, err := os.Stat(finalPath)
-	if err == nil && existingInfo.Size() > 0 && existingInfo.Size() < entry.Size {
-		// Partial file exists - offer to resume
-		resumeOffset = existingInfo.Size()
-		fmt.Fprintf(env.Stdout, "Resuming download from %.1f%% (%s / %s)\n",
-			float64(resumeOffset)/float64(entry.Size)*100,
-			formatBytes(resumeOffset), formatBytes(entry.Size))
-	} else if err == nil && existingInfo.Size() == entry.Size {
-		// File already complete
+	// The file is written under a partial name until it is complete, so
+	// only finished downloads ever carry the final name
+	partial := partialPath(ctx, s, finalPath)
+
+	// With -i or -n an existing file is never resumed or trusted as complete
+	if clobber != clobberOverwrite {
+		if !clobber.allow(env, finalPath) {
+			return nil
+		}
+		for _, p := range []string{finalPath, partial} {
+			if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("download: %w", err)
+			}
+		}
+	}
+
+	// --no-resume starts over, even from a file of the right size
+	if resume == resumeNever {
+		for _, p := range []string{finalPath, partial} {
+			if info, err := os.Stat(p); err == nil && !info.IsDir() {
+				if err := os.Remove(p); err != nil {
+					return fmt.Errorf("download: %w", err)
+				}
+			}
+		}
+	}
+
+	if info, err := os.Stat(finalPath); err == nil && !info.IsDir() && info.Size() == entry.Size {
 		fmt.Fprintf(env.Stdout, "File already downloaded: %s\n", finalPath)
 		return nil
 	}
 
-	// Use retry logic for robustness
+	// Check for a partial file to resume
+	var resumeOffset int64
+	if info, err := os.Stat(partial); err == nil && info.Mode().IsRegular() {
+		switch size := info.Size(); {
+		case size > 0 && size <= entry.Size:
+			resumeOffset = size
+			fmt.Fprintf(env.Stdout, "Resuming download from %.1f%% (%s / %s)\n",
+				float64(resumeOffset)/float64(entry.Size)*100,
+				formatBytes(resumeOffset), formatBytes(entry.Size))
+		case size > entry.Size:
+			// Not a part of this file; start over
+			if err := os.Remove(partial); err != nil {
+				return fmt.Errorf("download: %w", err)
+			}
+		}
+	}
+	if resume == resumeRequired && resumeOffset == 0 {
+		return fmt.Errorf("download: %s: no partial download to resume", partial)
+	}
+
+	if resumeOffset == 0 && restoreFromCache(s, entry, partial) {
+		if err := completeDownload(entry, partial, finalPath); err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+		fmt.Fprintf(env.Stdout, "Restored from download cache: %s\n", finalPath)
+		return nil
+	}
+	err = retryResumableDownload(ctx, entry, partial, resumeOffset, func(ctx context.Context, offset int64) error {
+		return downloadFileAttemptResumable(ctx, s, entry, partial, offset)
+	})
+	if err == nil {
+		err = completeDownload(entry, partial, finalPath)
+	}
+	if err != nil {
+		return err
+	}
+	storeInCache(s, env, entry, finalPath)
+	return nil
+}
+
+// defaultPartialSuffix is appended to the names of files being downloaded
+// when neither --partial-suffix nor partial_suffix in the config sets one.
+const defaultPartialSuffix = ".drime-partial"
+
+// partialSuffixKey carries download --partial-suffix in the context.
+type partialSuffixKey struct{}
+
+// partialPath returns the name a download to finalPath is written under
+// until it is complete: finalPath with --partial-suffix, partial_suffix from
+// the config or .drime-partial appended.
+func partialPath(ctx context.Context, s *session.Session, finalPath string) string {
+	suffix, _ := ctx.Value(partialSuffixKey{}).(string)
+	if suffix == "" {
+		suffix = s.PartialSuffix
+	}
+	if suffix == "" {
+		suffix = defaultPartialSuffix
+	}
+	return finalPath + suffix
+}
+
+// completeDownload gives the partial file of entry its final name once it
+// has all of entry's bytes. A short file is kept for the next run to resume.
+func completeDownload(entry *api.FileEntry, partial, finalPath string) error {
+	info, err := os.Stat(partial)
+	if err != nil {
+		return err
+	}
+	if entry.Size > 0 && info.Size() != entry.Size {
+		return fmt.Errorf("%s: incomplete download: got %d of %d bytes (kept as %s to resume)", finalPath, info.Size(), entry.Size, partial)
+	}
+	if err := os.Rename(partial, finalPath); err != nil {
+		return fmt.Errorf("cannot rename %s to %s: %w", partial, finalPath, err)
+	}
+	return nil
+}
+
+// restoreFromCache writes the copy of entry kept in the download cache, if
+// any, to path and reports whether it did.
+func restoreFromCache(s *session.Session, entry *api.FileEntry, path string) bool {
+	ok, err := s.DownloadCache.Restore(entry, path)
+	if err != nil || !ok {
+		return false
+	}
+	_ = os.Chtimes(path, time.Now(), entry.UpdatedAt)
+	return true
+}
+
+// storeInCache keeps a copy of the downloaded entry at path in the download
+// cache. A cache that can't be written never fails the download.
+func storeInCache(s *session.Session, env *ExecutionEnv, entry *api.FileEntry, path string) {
+	if err := s.DownloadCache.Store(entry, path); err != nil {
+		fmt.Fprintf(env.Stderr, "warning: download cache: %v\n", err)
+	}
+}
+
+// retryResumableDownload runs attempt until entry is fully written to
+// finalPath, retrying with backoff. Each attempt resumes from the bytes
+// already on disk and gets its own timeout.
+func retryResumableDownload(ctx context.Context, entry *api.FileEntry, finalPath string, resumeOffset int64, attempt func(ctx context.Context, offset int64) error) error {
 	var lastErr error
-	maxAttempts := 10
-	baseDelay := 2 * time.Second
+	maxAttempts := transferAttempts(ctx, 10)
 	timeout := 40 * time.Second
 
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
+	for try := 1; try <= maxAttempts; try++ {
 		// Check current file size for resume (may have progressed in previous attempt)
 		currentOffset := resumeOffset
 		if existingInfo, err := os.Stat(finalPath); err == nil {
@@ -618,7 +2054,7 @@ func downloadFile(ctx context.Context, s *session.Session, env *ExecutionEnv, en
 
 		// Create timeout context for this attempt
 		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
-		err := downloadFileAttemptResumable(attemptCtx, s, entry, finalPath, currentOffset)
+		err := attempt(attemptCtx, currentOffset)
 		cancel()
 
 		if err == nil {
@@ -633,19 +2069,71 @@ func downloadFile(ctx context.Context, s *session.Session, env *ExecutionEnv, en
 		}
 
 		// Don't retry on the last attempt
-		if attempt < maxAttempts {
-			// Exponential backoff with jitter
-			backoff := float64(baseDelay) * math.Pow(2, float64(attempt-1))
-			jitter := rand.Float64() * 0.25 * backoff
-			sleepDuration := time.Duration(backoff + jitter)
-			if sleepDuration > 30*time.Second {
-				sleepDuration = 30 * time.Second
+		if try < maxAttempts {
+			if err := waitRetryBackoff(ctx, try); err != nil {
+				return err
 			}
+		}
+	}
+
+	return fmt.Errorf("failed after %d attempts: %w", maxAttempts, lastErr)
+}
 
-			select {
-			case <-time.After(sleepDuration):
-			case <-ctx.Done():
-				return ctx.Err()
+// downloadToStdout streams a file to env.Stdout with the same retries as
+// downloadFile, resuming each attempt after the bytes already written.
+// Progress and messages only ever go to stderr.
+func downloadToStdout(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry) error {
+	if err := streamDownload(ctx, s, env, entry, env.Stdout); err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	return nil
+}
+
+// streamDownload writes the content of entry to w, showing progress on
+// env.Stderr. Failed attempts are retried from the last byte written, so w
+// never sees a byte twice; a server that answers such a retry with the whole
+// file instead of the range fails the download, as w can't be rewound.
+func streamDownload(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, w io.Writer) error {
+	out := &progressWriter{Writer: w}
+
+	var lastErr error
+	maxAttempts := transferAttempts(ctx, 10)
+	timeout := 40 * time.Second
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var opts *api.DownloadOptions
+		if out.current > 0 {
+			if out.current >= entry.Size {
+				return nil
+			}
+			opts = &api.DownloadOptions{ResumeFrom: out.current, RequireRange: true}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := ui.RunFileTransferTo(ctx, env.Stderr, "Downloading "+entry.Name, entry.Name, entry.Size, func(send func(int64, int64)) error {
+			send(out.current, entry.Size)
+			out.Callback = func(curr int64) { send(curr, entry.Size) }
+			_, dlErr := s.Client.DownloadWithOptions(attemptCtx, entry.Hash, out, nil, opts)
+			return dlErr
+		})
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, api.ErrRangeNotHonored) {
+			return fmt.Errorf("cannot resume after the %d bytes already written: %w", out.current, err)
+		}
+		lastErr = err
+
+		// Don't retry on parent context cancellation
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if attempt < maxAttempts {
+			if err := waitRetryBackoff(ctx, attempt); err != nil {
+				return err
 			}
 		}
 	}
@@ -653,8 +2141,124 @@ func downloadFile(ctx context.Context, s *session.Session, env *ExecutionEnv, en
 	return fmt.Errorf("failed after %d attempts: %w", maxAttempts, lastErr)
 }
 
+// downloadTar writes the folder entry at remotePath to stdout as a tar
+// archive rooted at the folder's name; the root folder's contents go at the
+// top level, as they do in a zip. Files are downloaded one after the other
+// straight into the archive, so nothing is staged on disk and memory stays
+// bounded whatever the folder's size.
+func downloadTar(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, remotePath string) error {
+	resolved, err := s.ResolvePathArg(remotePath)
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	var prefix string
+	if zipName(resolved) != "" {
+		name, err := localName(entry.Name)
+		if err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+		prefix = name + "/"
+	}
+	var paths []string
+	_, err = ui.WithSpinner(env.Stderr, "Listing...", false, func() (struct{}, error) {
+		return struct{}{}, walkCachedTree(ctx, s, resolved, walkBudget{}, func(p string, e *api.FileEntry) {
+			paths = append(paths, p)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("download: failed to list directory: %w", err)
+	}
+	// Sorted paths put every folder before its contents
+	sort.Strings(paths)
+
+	tw := tar.NewWriter(env.Stdout)
+	if prefix != "" {
+		if err := tw.WriteHeader(tarHeader(entry, prefix)); err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+	}
+	var files, total int64
+	for _, p := range paths {
+		e, ok := s.Cache.Get(p)
+		if !ok {
+			continue
+		}
+		rel := prefix + strings.TrimPrefix(strings.TrimPrefix(p, resolved), "/")
+		if e.Type == "folder" {
+			rel += "/"
+		}
+		if err := tw.WriteHeader(tarHeader(e, rel)); err != nil {
+			return fmt.Errorf("download: %s: %w", p, err)
+		}
+		if e.Type == "folder" {
+			continue
+		}
+		if err := streamDownload(ctx, s, env, e, tw); err != nil {
+			return fmt.Errorf("download: %s: %w", p, err)
+		}
+		files++
+		total += e.Size
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	fmt.Fprintf(env.Stderr, "Archived %d files (%s) from %s\n", files, formatSize(total), resolved)
+	return nil
+}
+
+// tarHeader describes entry in a tar archive under name, which ends in /
+// for folders.
+func tarHeader(entry *api.FileEntry, name string) *tar.Header {
+	modTime := entry.UpdatedAt
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	if entry.Type == "folder" {
+		return &tar.Header{Typeflag: tar.TypeDir, Name: name, Mode: 0755, ModTime: modTime}
+	}
+	return &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: entry.Size, ModTime: modTime}
+}
+
+// retryBaseDelay is the first backoff interval between download attempts.
+var retryBaseDelay = 2 * time.Second
+
+// transferAttempts returns how many times to try a transfer in all: def, or
+// one more than the command's --retries.
+func transferAttempts(ctx context.Context, def int) int {
+	return api.RetryPolicyFrom(ctx).RetriesOr(def-1) + 1
+}
+
+// waitRetryBackoff sleeps for an exponential backoff with jitter (capped at
+// 30s) before the next attempt, returning early if ctx is cancelled. The
+// first interval is retryBaseDelay unless the command's --retry-delay says
+// otherwise.
+func waitRetryBackoff(ctx context.Context, attempt int) error {
+	base := api.RetryPolicyFrom(ctx).DelayOr(retryBaseDelay)
+	backoff := float64(base) * math.Pow(2, float64(attempt-1))
+	jitter := rand.Float64() * 0.25 * backoff
+	sleepDuration := time.Duration(backoff + jitter)
+	if sleepDuration > 30*time.Second {
+		sleepDuration = 30 * time.Second
+	}
+
+	select {
+	case <-time.After(sleepDuration):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // downloadFileAttemptResumable performs a single download attempt with resume support
 func downloadFileAttemptResumable(ctx context.Context, s *session.Session, entry *api.FileEntry, finalPath string, resumeFrom int64) error {
+	return ui.RunFileTransfer(ctx, "Downloading "+entry.Name, finalPath, entry.Size, func(send func(int64, int64)) error {
+		return downloadAttempt(ctx, s, entry, finalPath, resumeFrom, send)
+	})
+}
+
+// downloadAttempt downloads entry to finalPath once, appending to the
+// resumeFrom bytes already there, and reports progress to send.
+func downloadAttempt(ctx context.Context, s *session.Session, entry *api.FileEntry, finalPath string, resumeFrom int64, send func(int64, int64)) error {
 	var f *os.File
 	var err error
 
@@ -670,53 +2274,254 @@ func downloadFileAttemptResumable(ctx context.Context, s *session.Session, entry
 		f, err = os.Create(finalPath)
 	}
 
-	if err != nil {
-		return fmt.Errorf("download: cannot open %s: %w", finalPath, err)
+	if err != nil {
+		return fmt.Errorf("download: cannot open %s: %w", finalPath, err)
+	}
+	defer f.Close()
+
+	// Send initial progress if resuming
+	if resumeFrom > 0 {
+		send(resumeFrom, entry.Size)
+	}
+
+	writer := &progressWriter{
+		Writer:   f,
+		current:  resumeFrom,
+		Callback: func(curr int64) { send(curr, entry.Size) },
+	}
+
+	var fileEntry *api.FileEntry
+	if resumeFrom > 0 {
+		// Use DownloadWithOptions for resume
+		fileEntry, err = s.Client.DownloadWithOptions(ctx, entry.Hash, writer, nil, &api.DownloadOptions{
+			ResumeFrom: resumeFrom,
+		})
+	} else {
+		fileEntry, err = s.Client.Download(ctx, entry.Hash, writer, nil)
+	}
+	if err != nil {
+		// Don't remove partial file - it can be resumed
+		return err
+	}
+
+	// Set file modification time
+	if !entry.UpdatedAt.IsZero() {
+		_ = os.Chtimes(finalPath, time.Now(), entry.UpdatedAt)
+	} else if fileEntry != nil && !fileEntry.UpdatedAt.IsZero() {
+		_ = os.Chtimes(finalPath, time.Now(), fileEntry.UpdatedAt)
+	}
+
+	return nil
+}
+
+// folderMode says how download fetches workspace folders: as one zip, or
+// file by file into the mirrored local tree. With neither flag set, folders
+// with more files than the session's NoZipThreshold go file by file, and so
+// do all folders when there is a download cache, which only single files
+// go through.
+type folderMode struct {
+	perFile bool // --no-zip / --preserve-structure
+	zip     bool // --zip
+	strip   int  // --strip-components
+	raw     bool // --raw: keep files uploaded with --compress as .gz
+}
+
+// downloadFolder downloads the workspace folder entry into localPath, as a
+// zip or file by file according to mode, then restores the files in it that
+// were uploaded with --compress unless mode.raw is set.
+func downloadFolder(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, remotePath, localPath string, clobber clobberMode, mode folderMode) error {
+	written, err := fetchFolder(ctx, s, env, entry, remotePath, localPath, clobber, mode)
+	if err != nil || mode.raw {
+		return err
+	}
+	return decompressAll(env, written, clobber)
+}
+
+// fetchFolder does the downloading for downloadFolder and returns the local
+// files it wrote.
+func fetchFolder(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, remotePath, localPath string, clobber clobberMode, mode folderMode) ([]string, error) {
+	perFile := mode.perFile || s.DownloadCache.Enabled()
+	if mode.zip || (!perFile && s.NoZipThreshold <= 0) {
+		return downloadDirectory(ctx, s, env, entry, localPath, clobber, mode.strip)
+	}
+
+	resolved, err := s.ResolvePathArg(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	var files, folders []string
+	_, err = ui.WithSpinner(env.Stderr, "Listing...", false, func() (struct{}, error) {
+		return struct{}{}, walkCachedTree(ctx, s, resolved, walkBudget{}, func(p string, e *api.FileEntry) {
+			if e.Type == "folder" {
+				folders = append(folders, p)
+			} else {
+				files = append(files, p)
+			}
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("download: failed to list directory: %w", err)
+	}
+
+	if !perFile && len(files) <= s.NoZipThreshold {
+		return downloadDirectory(ctx, s, env, entry, localPath, clobber, mode.strip)
+	}
+	return downloadDirectoryFiles(ctx, s, env, entry, resolved, localPath, files, folders, clobber, mode.strip)
+}
+
+// downloadDirectoryFiles downloads the files below the folder at
+// remotePath one by one into localPath/<folder name>, recreating its
+// sub-folders. Each file is fetched with the resumable single-file
+// downloader, so a re-run after a failure resumes partial files and skips
+// complete ones instead of fetching a whole zip again. As with the zip,
+// strip components are taken off paths starting with the folder name.
+func downloadDirectoryFiles(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, remotePath, localPath string, files, folders []string, clobber clobberMode, strip int) ([]string, error) {
+	info, err := os.Stat(localPath)
+	if err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("download: %s exists and is not a directory", localPath)
+	}
+	name, err := localName(entry.Name)
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	baseDir := filepath.Join(localPath, name)
+	if strip > 0 {
+		baseDir = localPath
+	}
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, fmt.Errorf("download: cannot create directory %s: %w", baseDir, err)
+	}
+
+	// Paths relative to localPath, as they would be in the folder's zip
+	archived := func(p string) string { return name + "/" + strings.TrimPrefix(p, remotePath+"/") }
+	var targets map[string]string
+	if strip > 0 {
+		var fileNames, folderNames []string
+		for _, p := range files {
+			fileNames = append(fileNames, archived(p))
+		}
+		for _, p := range folders {
+			folderNames = append(folderNames, archived(p))
+		}
+		if targets, err = stripPaths(fileNames, folderNames, strip); err != nil {
+			return nil, fmt.Errorf("download: %w", err)
+		}
+	}
+	target := func(p string) (string, bool) {
+		if targets == nil {
+			return archived(p), true
+		}
+		rel, ok := targets[archived(p)]
+		return rel, ok
+	}
+
+	// Empty folders are part of the structure too
+	for _, p := range folders {
+		rel, ok := target(p)
+		if !ok {
+			continue
+		}
+		dir := filepath.Join(localPath, filepath.FromSlash(rel))
+		if !withinDir(baseDir, dir) {
+			return nil, fmt.Errorf("download: illegal file path: %s", p)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("download: cannot create directory %s: %w", dir, err)
+		}
+	}
+
+	var jobs []downloadJob
+	skipped := 0
+	for _, p := range files {
+		file, ok := s.Cache.Get(p)
+		if !ok {
+			continue
+		}
+		rel, ok := target(p)
+		if !ok {
+			continue
+		}
+		relPath := strings.TrimPrefix(p, remotePath+"/")
+		local := filepath.Join(localPath, filepath.FromSlash(rel))
+		if !withinDir(baseDir, local) {
+			return nil, fmt.Errorf("download: illegal file path: %s", p)
+		}
+		if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+			return nil, fmt.Errorf("download: cannot create directory %s: %w", filepath.Dir(local), err)
+		}
+		if clobber == clobberOverwrite {
+			if info, err := os.Stat(local); err == nil && info.Mode().IsRegular() && info.Size() == file.Size {
+				skipped++
+				continue
+			}
+		} else {
+			// With -i or -n an existing file is never resumed
+			if !clobber.allow(env, local) {
+				skipped++
+				continue
+			}
+			if err := os.Remove(local); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("download: %w", err)
+			}
+		}
+		jobs = append(jobs, downloadJob{entry: file, relPath: relPath, local: local})
+	}
+
+	if skipped > 0 {
+		fmt.Fprintf(env.Stdout, "Skipping %d files already downloaded\n", skipped)
+	}
+	if len(jobs) == 0 {
+		fmt.Fprintf(env.Stdout, "All %d files already downloaded\n", len(files))
+		return nil, nil
 	}
-	defer f.Close()
 
-	var fileEntry *api.FileEntry
-	err = ui.RunTransfer("Downloading "+entry.Name, entry.Size, func(send func(int64, int64)) error {
-		// Send initial progress if resuming
-		if resumeFrom > 0 {
-			send(resumeFrom, entry.Size)
+	var restored atomic.Int64
+	if err := runDownloadJobs(ctx, s, env, jobs, "", func(job downloadJob, send func(int64, int64)) error {
+		partial := partialPath(ctx, s, job.local)
+		var offset int64
+		if info, err := os.Stat(partial); err == nil {
+			offset = info.Size()
+			if offset > job.entry.Size {
+				offset = 0
+				os.Remove(partial)
+			}
 		}
-
-		writer := &progressWriter{
-			Writer:   f,
-			current:  resumeFrom,
-			Callback: func(curr int64) { send(curr, entry.Size) },
+		if offset == 0 && restoreFromCache(s, job.entry, partial) {
+			if err := completeDownload(job.entry, partial, job.local); err != nil {
+				return err
+			}
+			restored.Add(1)
+			send(job.entry.Size, job.entry.Size)
+			return nil
 		}
-
-		var dlErr error
-		if resumeFrom > 0 {
-			// Use DownloadWithOptions for resume
-			fileEntry, dlErr = s.Client.DownloadWithOptions(ctx, entry.Hash, writer, nil, &api.DownloadOptions{
-				ResumeFrom: resumeFrom,
-			})
-		} else {
-			fileEntry, dlErr = s.Client.Download(ctx, entry.Hash, writer, nil)
+		err := retryResumableDownload(ctx, job.entry, partial, offset, func(ctx context.Context, offset int64) error {
+			return downloadAttempt(ctx, s, job.entry, partial, offset, send)
+		})
+		if err == nil {
+			err = completeDownload(job.entry, partial, job.local)
+		}
+		if err == nil {
+			storeInCache(s, env, job.entry, job.local)
 		}
-		return dlErr
-	})
-
-	if err != nil {
-		// Don't remove partial file - it can be resumed
 		return err
+	}); err != nil {
+		return nil, err
 	}
-
-	// Set file modification time
-	if !entry.UpdatedAt.IsZero() {
-		_ = os.Chtimes(finalPath, time.Now(), entry.UpdatedAt)
-	} else if fileEntry != nil && !fileEntry.UpdatedAt.IsZero() {
-		_ = os.Chtimes(finalPath, time.Now(), fileEntry.UpdatedAt)
+	written := make([]string, len(jobs))
+	for i, job := range jobs {
+		written[i] = job.local
 	}
-
-	return nil
+	if n := restored.Load(); n > 0 {
+		fmt.Fprintf(env.Stdout, "\nDownloaded %d files to %s (%d from the download cache)\n", len(jobs), baseDir, n)
+		return written, nil
+	}
+	fmt.Fprintf(env.Stdout, "\nDownloaded %d files to %s\n", len(jobs), baseDir)
+	return written, nil
 }
 
-// downloadDirectory downloads a folder (API returns a zip file)
-func downloadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, _ string, localPath string) error {
+// downloadDirectory downloads a folder (API returns a zip file) and returns
+// the files it extracted
+func downloadDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, localPath string, clobber clobberMode, strip int) ([]string, error) {
 	// Determine extraction directory
 	info, err := os.Stat(localPath)
 	if err == nil && info.IsDir() {
@@ -724,42 +2529,119 @@ func downloadDirectory(ctx context.Context, s *session.Session, env *ExecutionEn
 	} else if os.IsNotExist(err) {
 		// Create the directory
 		if err := os.MkdirAll(localPath, 0755); err != nil {
-			return fmt.Errorf("download: cannot create directory %s: %w", localPath, err)
+			return nil, fmt.Errorf("download: cannot create directory %s: %w", localPath, err)
 		}
 	} else {
-		return fmt.Errorf("download: %s exists and is not a directory", localPath)
+		return nil, fmt.Errorf("download: %s exists and is not a directory", localPath)
 	}
 	extractDir := localPath
 
 	// Create temp file for zip
 	tmpFile, err := os.CreateTemp("", "drime-download-*.zip")
 	if err != nil {
-		return fmt.Errorf("download: cannot create temp file: %w", err)
+		return nil, fmt.Errorf("download: cannot create temp file: %w", err)
 	}
 	tmpPath := tmpFile.Name()
+	tmpFile.Close()
 	defer os.Remove(tmpPath)
 
 	// Download the folder as zip
 	fmt.Fprintf(env.Stdout, "Downloading %s...\n", entry.Name)
 
 	_, err = ui.WithSpinner(env.Stderr, "", false, func() (*api.FileEntry, error) {
-		_, err := s.Client.Download(ctx, entry.Hash, tmpFile, nil)
-		tmpFile.Close()
-		return nil, err
+		return nil, downloadZipWithRetry(ctx, s, entry.Hash, tmpPath)
 	})
 
 	if err != nil {
-		return fmt.Errorf("download: failed to download: %w", err)
+		return nil, fmt.Errorf("download: failed to download: %w", err)
 	}
 
 	// Extract zip
 	fmt.Fprintf(env.Stdout, "Extracting to %s...\n", extractDir)
-	if err := extractZip(tmpPath, extractDir); err != nil {
-		return fmt.Errorf("download: failed to extract: %w", err)
+	allow := func(path string) bool { return clobber.allow(env, path) }
+	written, err := extractZip(tmpPath, extractDir, strip, allow)
+	if err != nil {
+		return nil, fmt.Errorf("download: failed to extract: %w", err)
 	}
 
 	fmt.Fprintf(env.Stdout, "Downloaded %s to %s\n", entry.Name, extractDir)
-	return nil
+	return written, nil
+}
+
+// downloadZipWithRetry downloads a folder archive into zipPath, retrying with
+// backoff and resuming the partial file via Range requests. A server that
+// answers the Range request with the whole archive instead is caught before
+// anything is appended, and the download restarts from scratch. Once a
+// download finishes, the archive is opened to check it is intact; a corrupt
+// archive is discarded and fetched again from scratch.
+func downloadZipWithRetry(ctx context.Context, s *session.Session, hash string, zipPath string) error {
+	var lastErr error
+	maxAttempts := transferAttempts(ctx, 10)
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var offset int64
+		if info, err := os.Stat(zipPath); err == nil {
+			offset = info.Size()
+		}
+
+		err := downloadZipAttempt(ctx, s, hash, zipPath, offset)
+		if errors.Is(err, api.ErrRangeNotHonored) {
+			// Nothing was appended; drop the partial file and refetch it
+			// whole right away rather than stitching two streams together.
+			if terr := os.Truncate(zipPath, 0); terr != nil {
+				return terr
+			}
+			lastErr = err
+			continue
+		}
+		if err == nil {
+			r, zerr := zip.OpenReader(zipPath)
+			if zerr == nil {
+				r.Close()
+				return nil
+			}
+			// Partial or corrupt archive: start over on the next attempt
+			err = fmt.Errorf("corrupt archive: %w", zerr)
+			if terr := os.Truncate(zipPath, 0); terr != nil {
+				return terr
+			}
+		}
+
+		lastErr = err
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if attempt < maxAttempts {
+			if err := waitRetryBackoff(ctx, attempt); err != nil {
+				return err
+			}
+		}
+	}
+
+	return fmt.Errorf("failed after %d attempts: %w", maxAttempts, lastErr)
+}
+
+// downloadZipAttempt performs one archive download, appending from offset
+// when a previous attempt left a partial file behind.
+func downloadZipAttempt(ctx context.Context, s *session.Session, hash string, zipPath string, offset int64) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(zipPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if offset > 0 {
+		_, err = s.Client.DownloadWithOptions(ctx, hash, f, nil, &api.DownloadOptions{ResumeFrom: offset, RequireRange: true})
+	} else {
+		_, err = s.Client.Download(ctx, hash, f, nil)
+	}
+	return err
 }
 
 // Helper types for progress tracking
@@ -798,11 +2680,44 @@ func (pw *progressWriter) Write(p []byte) (int, error) {
 }
 
 func edit(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: edit <file>")
+	fs := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+	external := fs.BoolP("external", "e", false, "open in $EDITOR")
+	builtin := fs.Bool("builtin", false, "open in the built-in editor")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: edit [-e | --builtin] <file>...")
+	}
+	if *external && *builtin {
+		return fmt.Errorf("edit: --external and --builtin are mutually exclusive")
+	}
+	useExternal := *external || (s.ExternalEditor && !*builtin)
+
+	paths := fs.Args()
+	if len(paths) == 1 {
+		return editFile(ctx, s, env, paths[0], useExternal)
 	}
 
-	path := args[0]
+	// Edit the files one after the other; a file that can't be edited
+	// doesn't keep the user from the rest
+	failed := 0
+	for _, path := range paths {
+		if err := editFile(ctx, s, env, path, useExternal); err != nil {
+			fmt.Fprintln(env.Stderr, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("edit: %d of %d files failed", failed, len(paths))
+	}
+	return nil
+}
+
+// editFile opens one remote file in the built-in editor, or in $EDITOR when
+// external is set, and uploads it again if it was saved with changes.
+func editFile(ctx context.Context, s *session.Session, env *ExecutionEnv, path string, external bool) error {
 	resolved, err := s.ResolvePathArg(path)
 	if err != nil {
 		return fmt.Errorf("edit: %w", err)
@@ -834,83 +2749,101 @@ func edit(ctx context.Context, s *session.Session, env *ExecutionEnv, args []str
 	if err != nil {
 		return fmt.Errorf("edit: %w", err)
 	}
+	if isBinaryContent(contentBytes) {
+		return fmt.Errorf("edit: %s: binary file, editing would corrupt it (use download instead)", path)
+	}
 	content := string(contentBytes)
 
 	// Run the editor
-	result, err := ui.RunEditor(entry.Name, content)
-	if err != nil {
-		return fmt.Errorf("edit: editor error: %w", err)
+	var result ui.EditorResult
+	if external {
+		result, err = ui.RunExternalEditor(entry.Name, content)
+		if err != nil {
+			return fmt.Errorf("edit: %s: %w", path, err)
+		}
+	} else {
+		result, err = ui.RunEditor(entry.Name, content)
+		if err != nil {
+			return fmt.Errorf("edit: editor error: %w", err)
+		}
 	}
 
 	// Only save if user pressed save and content changed
 	if result.Saved && result.Content != content {
-		// Get parent ID for upload
-		parentDir := filepath.Dir(resolved)
-		var parentID *int64
-		if parentEntry, ok := s.Cache.Get(parentDir); ok && parentEntry.Type == "folder" {
-			if parentEntry.ID != 0 {
-				parentID = &parentEntry.ID
-			}
+		if err := saveEditedFile(ctx, s, env, entry, resolved, result.Content); err != nil {
+			return fmt.Errorf("edit: %w", err)
 		}
+	} else if result.Content != content && !result.Saved {
+		fmt.Fprintf(env.Stderr, "%s Changes discarded.\n", ui.WarningStyle.Render("!"))
+	}
 
-		err := ui.WithSpinnerErr(env.Stderr, "", false, func() error {
-			if s.InVault {
-				// Vault: delete old, upload encrypted new
-				if err := s.Client.DeleteVaultEntries(ctx, []int64{entry.ID}); err != nil {
-					return fmt.Errorf("failed to delete old file: %w", err)
-				}
+	return nil
+}
 
-				// Encrypt new content
-				encryptedContent, iv, err := s.VaultKey.Encrypt([]byte(result.Content))
-				if err != nil {
-					return fmt.Errorf("failed to encrypt: %w", err)
-				}
-				ivBase64 := crypto.EncodeBase64(iv)
+// saveEditedFile replaces entry at resolved with content, encrypting it
+// first in the vault.
+func saveEditedFile(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, resolved, content string) error {
+	// Get parent ID for upload
+	parentDir := filepath.Dir(resolved)
+	var parentID *int64
+	if parentEntry, ok := s.Cache.Get(parentDir); ok && parentEntry.Type == "folder" {
+		parentID = parentIDPtr(parentEntry)
+	}
 
-				newEntry, err := s.Client.UploadToVault(ctx, encryptedContent, entry.Name, parentID, s.VaultID, ivBase64)
-				if err != nil {
-					return fmt.Errorf("failed to save: %w", err)
-				}
+	return ui.WithSpinnerErr(env.Stderr, "", false, func() error {
+		if s.InVault {
+			// Vault: delete old, upload encrypted new
+			if err := s.Client.DeleteVaultEntries(ctx, []int64{entry.ID}); err != nil {
+				return fmt.Errorf("failed to delete old file: %w", err)
+			}
 
-				// Update cache
-				s.Cache.Remove(resolved)
-				if newEntry != nil {
-					s.Cache.Add(newEntry, resolved)
-				}
-			} else {
-				// Regular: delete old, upload new
-				if err := s.Client.DeleteEntries(ctx, []int64{entry.ID}, s.WorkspaceID); err != nil {
-					return fmt.Errorf("failed to delete old file: %w", err)
-				}
+			// Encrypt new content
+			encryptedContent, iv, err := s.VaultKey.Encrypt([]byte(content))
+			if err != nil {
+				return fmt.Errorf("failed to encrypt: %w", err)
+			}
+			ivBase64 := crypto.EncodeBase64(iv)
 
-				reader := bytes.NewReader([]byte(result.Content))
-				size := int64(len(result.Content))
-				newEntry, err := s.Client.Upload(ctx, reader, entry.Name, parentID, size, s.WorkspaceID)
-				if err != nil {
-					return fmt.Errorf("failed to save: %w", err)
-				}
+			newEntry, err := s.Client.UploadToVault(ctx, encryptedContent, entry.Name, parentID, s.VaultID, ivBase64)
+			if err != nil {
+				return fmt.Errorf("failed to save: %w", err)
+			}
 
-				// Update cache
-				s.Cache.Remove(resolved)
-				if newEntry != nil {
-					s.Cache.Add(newEntry, resolved)
-				}
+			// Update cache
+			s.Cache.Remove(resolved)
+			if newEntry != nil {
+				s.Cache.Add(newEntry, resolved)
 			}
 			return nil
-		})
+		}
+
+		// Regular: delete old, upload new
+		if err := s.Client.DeleteEntries(ctx, []int64{entry.ID}, s.WorkspaceID); err != nil {
+			return fmt.Errorf("failed to delete old file: %w", err)
+		}
+
+		reader := bytes.NewReader([]byte(content))
+		size := int64(len(content))
+		newEntry, err := s.Client.Upload(ctx, reader, entry.Name, parentID, size, s.WorkspaceID)
 		if err != nil {
-			return fmt.Errorf("edit: %w", err)
+			return fmt.Errorf("failed to save: %w", err)
 		}
-	} else if result.Content != content && !result.Saved {
-		fmt.Fprintf(env.Stderr, "%s Changes discarded.\n", ui.WarningStyle.Render("!"))
-	}
 
-	return nil
+		// Update cache
+		s.Cache.Remove(resolved)
+		if newEntry != nil {
+			s.Cache.Add(newEntry, resolved)
+		}
+		return nil
+	})
 }
 
 // walkLocalDirectory returns a list of all files and directories within a local directory,
-// excluding ignored files like .DS_Store.
-func walkLocalDirectory(root string) ([]string, error) {
+// excluding ignored files like .DS_Store. With maxDepth > 0 only files up to
+// that many levels down are listed, and the folders holding them; the number
+// of files left out is returned too.
+func walkLocalDirectory(root string, maxDepth int) ([]string, int, error) {
+	skipped := 0
 	var files []string
 	ignored := map[string]bool{
 		".DS_Store": true,
@@ -937,50 +2870,159 @@ func walkLocalDirectory(root string) ([]string, error) {
 		if rel == "." {
 			return nil
 		}
+		if maxDepth > 0 {
+			depth := strings.Count(rel, string(filepath.Separator)) + 1
+			if info.IsDir() && depth >= maxDepth {
+				// Nothing inside is within the limit; count what is left out
+				skipped += countLocalFiles(path, ignored)
+				return filepath.SkipDir
+			}
+		}
 		files = append(files, rel)
 		return nil
 	})
 
-	return files, err
+	return files, skipped, err
+}
+
+// countLocalFiles counts the files below dir that walkLocalDirectory would
+// list.
+func countLocalFiles(dir string, ignored map[string]bool) int {
+	n := 0
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ignored[info.Name()] {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			n++
+		}
+		return nil
+	})
+	return n
+}
+
+// stripPaths maps the slash-separated files and dirs of a folder download
+// to their paths without the first n components, like tar
+// --strip-components. Paths with n components or fewer are left out. Two
+// files ending up at the same path, or a file where a directory goes, is an
+// error, since one would overwrite the other.
+func stripPaths(files, dirs []string, n int) (map[string]string, error) {
+	targets := make(map[string]string, len(files)+len(dirs))
+	strip := func(p string) (string, bool) {
+		parts := strings.Split(strings.Trim(p, "/"), "/")
+		if len(parts) <= n {
+			return "", false
+		}
+		return strings.Join(parts[n:], "/"), true
+	}
+
+	fileAt := make(map[string]string) // stripped path -> original
+	dirAt := make(map[string]string)  // stripped directory -> a path inside it
+	addParents := func(target, original string) {
+		for dir := path.Dir(target); dir != "."; dir = path.Dir(dir) {
+			if _, ok := dirAt[dir]; !ok {
+				dirAt[dir] = original
+			}
+		}
+	}
+	for _, d := range dirs {
+		if target, ok := strip(d); ok {
+			targets[d] = target
+			dirAt[target] = d
+			addParents(target, d)
+		}
+	}
+	for _, f := range files {
+		target, ok := strip(f)
+		if !ok {
+			continue
+		}
+		if other, dup := fileAt[target]; dup {
+			return nil, fmt.Errorf("--strip-components %d: '%s' and '%s' would both be written to '%s'", n, other, f, target)
+		}
+		fileAt[target] = f
+		targets[f] = target
+		addParents(target, f)
+	}
+	for target, f := range fileAt {
+		if other, ok := dirAt[target]; ok {
+			return nil, fmt.Errorf("--strip-components %d: '%s' would be written where '%s' needs a directory", n, f, other)
+		}
+	}
+	return targets, nil
 }
 
-// extractZip extracts a zip archive to a destination directory.
-func extractZip(zipPath string, destDir string) error {
+// extractZip extracts a zip archive to a destination directory, without
+// the first strip components of each path. Files for which allow returns
+// false are left as they are. Returns the files written.
+func extractZip(zipPath string, destDir string, strip int, allow func(path string) bool) ([]string, error) {
 	r, err := zip.OpenReader(zipPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer r.Close()
 
+	var targets map[string]string
+	if strip > 0 {
+		var files, dirs []string
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() {
+				dirs = append(dirs, f.Name)
+			} else {
+				files = append(files, f.Name)
+			}
+		}
+		if targets, err = stripPaths(files, dirs, strip); err != nil {
+			return nil, err
+		}
+	}
+
+	var written []string
 	for _, f := range r.File {
-		fpath := filepath.Join(destDir, f.Name)
+		name := f.Name
+		if targets != nil {
+			var ok bool
+			if name, ok = targets[f.Name]; !ok {
+				continue
+			}
+		}
+		fpath := filepath.Join(destDir, name)
 
 		// Check for ZipSlip vulnerability
-		if !strings.HasPrefix(fpath, filepath.Clean(destDir)+string(os.PathSeparator)) {
-			return fmt.Errorf("illegal file path: %s", fpath)
+		if !withinDir(destDir, fpath) {
+			return nil, fmt.Errorf("illegal file path: %s", fpath)
 		}
 
 		if f.FileInfo().IsDir() {
 			err := os.MkdirAll(fpath, os.ModePerm)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			continue
 		}
 
 		if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
-			return err
+			return nil, err
+		}
+		if !allow(fpath) {
+			continue
 		}
 
 		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		rc, err := f.Open()
 		if err != nil {
 			outFile.Close()
-			return err
+			return nil, err
 		}
 
 		_, err = io.Copy(outFile, rc)
@@ -989,10 +3031,11 @@ func extractZip(zipPath string, destDir string) error {
 		rc.Close()
 
 		if err != nil {
-			return err
+			return nil, err
 		}
+		written = append(written, fpath)
 	}
-	return nil
+	return written, nil
 }
 
 // uploadToVault handles uploads to the encrypted vault.
@@ -1043,17 +3086,13 @@ func uploadFileToVault(ctx context.Context, s *session.Session, env *ExecutionEn
 
 	// Check if destination is an existing folder
 	if entry, ok := s.Cache.Get(destResolved); ok && entry.Type == "folder" {
-		if entry.ID != 0 {
-			parentID = &entry.ID
-		}
+		parentID = parentIDPtr(entry)
 		finalPath = filepath.Join(destResolved, destName)
 	} else {
 		// Destination might be the target filename
 		parentDir := filepath.Dir(destResolved)
 		if parentEntry, ok := s.Cache.Get(parentDir); ok && parentEntry.Type == "folder" {
-			if parentEntry.ID != 0 {
-				parentID = &parentEntry.ID
-			}
+			parentID = parentIDPtr(parentEntry)
 			destName = filepath.Base(destResolved)
 			finalPath = destResolved
 		}
@@ -1069,7 +3108,7 @@ func uploadFileToVault(ctx context.Context, s *session.Session, env *ExecutionEn
 	// Upload with progress
 	size := int64(len(encryptedContent))
 	var uploadedEntry *api.FileEntry
-	err = ui.RunTransfer("Encrypting & uploading "+filepath.Base(localPath), size, func(send func(int64, int64)) error {
+	err = ui.RunFileTransfer(ctx, "Encrypting & uploading "+filepath.Base(localPath), localPath, size, func(send func(int64, int64)) error {
 		// Progress is approximate since we upload in one shot
 		send(0, size)
 		var uploadErr error
@@ -1156,9 +3195,7 @@ func ensureVaultFolder(ctx context.Context, s *session.Session, path string) err
 
 		// Check cache
 		if entry, ok := s.Cache.Get(currentPath); ok {
-			if entry.ID != 0 {
-				currentParentID = &entry.ID
-			}
+			currentParentID = parentIDPtr(entry)
 			continue
 		}
 
@@ -1175,7 +3212,7 @@ func ensureVaultFolder(ctx context.Context, s *session.Session, path string) err
 }
 
 // downloadVaultFile downloads and decrypts a single file from the vault
-func downloadVaultFile(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, localPath string) error {
+func downloadVaultFile(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, localPath string, clobber clobberMode) error {
 	if !s.VaultUnlocked {
 		return fmt.Errorf("download: vault session error - please re-enter vault")
 	}
@@ -1188,7 +3225,11 @@ func downloadVaultFile(ctx context.Context, s *session.Session, env *ExecutionEn
 	info, err := os.Stat(localPath)
 	if err == nil && info.IsDir() {
 		// localPath is an existing directory, put file inside it
-		finalPath = filepath.Join(localPath, entry.Name)
+		name, err := localName(entry.Name)
+		if err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+		finalPath = filepath.Join(localPath, name)
 	} else if os.IsNotExist(err) {
 		// Check if parent exists
 		parentDir := filepath.Dir(localPath)
@@ -1200,49 +3241,105 @@ func downloadVaultFile(ctx context.Context, s *session.Session, env *ExecutionEn
 		// localPath will be the filename
 		finalPath = localPath
 	}
-
-	// Get the IV from the entry
-	if entry.IV == "" {
-		return fmt.Errorf("download: file has no IV (not encrypted?)")
+	if clobber == clobberOverwrite && vaultFileComplete(entry, finalPath) {
+		fmt.Fprintf(env.Stdout, "File already downloaded: %s\n", finalPath)
+		return nil
 	}
-	iv, err := crypto.DecodeBase64(entry.IV)
-	if err != nil {
-		return fmt.Errorf("download: invalid IV: %w", err)
+	if !clobber.allow(env, finalPath) {
+		return nil
 	}
 
-	// Download encrypted content to memory
-	var encryptedBuf bytes.Buffer
-	err = ui.RunTransfer("Downloading "+entry.Name, entry.Size, func(send func(int64, int64)) error {
-		_, downloadErr := s.Client.DownloadEncrypted(ctx, entry.Hash, &encryptedBuf, func(current, total int64) {
-			send(current, total)
-		})
-		return downloadErr
+	err = ui.RunFileTransfer(ctx, "Downloading "+entry.Name, entry.Name, entry.Size, func(send func(int64, int64)) error {
+		return fetchVaultFile(ctx, s, entry, finalPath, send)
 	})
 	if err != nil {
 		return fmt.Errorf("download: %w", err)
 	}
 
-	// Decrypt the content
-	encryptedContent := encryptedBuf.Bytes()
-	plaintext, err := s.VaultKey.Decrypt(encryptedContent, iv)
+	fmt.Fprintf(env.Stdout, "Downloaded: %s (decrypted)\n", finalPath)
+	return nil
+}
+
+// vaultDownloadAttempts bounds the tries for one vault file. Vault files are
+// decrypted in one piece, so every attempt starts from the first byte.
+const vaultDownloadAttempts = 5
+
+// fetchVaultFile downloads entry, retrying failed or short transfers with
+// backoff, then decrypts it to finalPath. The plaintext is written under the
+// partial name and renamed, so an interrupted run never leaves a truncated
+// file that a re-run would take as complete.
+func fetchVaultFile(ctx context.Context, s *session.Session, entry *api.FileEntry, finalPath string, progress func(int64, int64)) error {
+	if entry.IV == "" {
+		return fmt.Errorf("file has no IV (not encrypted?)")
+	}
+	iv, err := crypto.DecodeBase64(entry.IV)
 	if err != nil {
-		return fmt.Errorf("download: decryption failed: %w", err)
+		return fmt.Errorf("invalid IV: %w", err)
+	}
+
+	attempts := transferAttempts(ctx, vaultDownloadAttempts)
+	var encrypted bytes.Buffer
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		encrypted.Reset()
+		_, lastErr = s.Client.DownloadEncrypted(ctx, entry.Hash, &encrypted, progress)
+		if lastErr == nil && entry.Size > 0 && int64(encrypted.Len()) != entry.Size {
+			lastErr = fmt.Errorf("incomplete download: got %d of %d bytes", encrypted.Len(), entry.Size)
+		}
+		if lastErr == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt < attempts {
+			if err := waitRetryBackoff(ctx, attempt); err != nil {
+				return err
+			}
+		}
+	}
+	if lastErr != nil {
+		return fmt.Errorf("failed after %d attempts: %w", attempts, lastErr)
 	}
 
-	// Write to file
-	if err := os.WriteFile(finalPath, plaintext, 0644); err != nil {
-		return fmt.Errorf("download: failed to write file: %w", err)
+	plaintext, err := s.VaultKey.Decrypt(encrypted.Bytes(), iv)
+	if err != nil {
+		return fmt.Errorf("decryption failed: %w", err)
 	}
 
-	fmt.Fprintf(env.Stdout, "Downloaded: %s (decrypted)\n", finalPath)
+	tmp := partialPath(ctx, s, finalPath)
+	if err := os.WriteFile(tmp, plaintext, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp, finalPath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
 	return nil
 }
 
-// downloadVaultDirectory downloads and decrypts a directory from the vault
-func downloadVaultDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, remotePath, localPath string) error {
+// vaultFileComplete reports whether path already holds the decrypted
+// content of entry, judged by size: the server stores the ciphertext, which
+// is crypto.TagSize bytes longer than the plaintext.
+func vaultFileComplete(entry *api.FileEntry, path string) bool {
+	if entry.Size < crypto.TagSize {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Size() == entry.Size-crypto.TagSize
+}
+
+// downloadVaultDirectory downloads and decrypts a directory from the vault.
+// Files are fetched by a pool of workers, each with its own retries; files
+// already on disk from an earlier run are skipped, so re-running the same
+// command after a partial failure only fetches what is missing.
+func downloadVaultDirectory(ctx context.Context, s *session.Session, env *ExecutionEnv, entry *api.FileEntry, remotePath, localPath string, clobber clobberMode) error {
 	if !s.VaultUnlocked {
 		return fmt.Errorf("download: vault session error - please re-enter vault")
 	}
+	if s.VaultKey == nil {
+		return fmt.Errorf("download: vault key not available")
+	}
 
 	// List all files in the directory recursively (use hash)
 	files, err := listVaultFilesRecursively(ctx, s, entry.Hash, remotePath)
@@ -1254,33 +3351,130 @@ func downloadVaultDirectory(ctx context.Context, s *session.Session, env *Execut
 		return fmt.Errorf("download: directory is empty")
 	}
 
-	fmt.Fprintf(env.Stdout, "Downloading %d files from vault...\n", len(files))
-
 	// Determine base directory
-	baseDir := filepath.Join(localPath, entry.Name)
+	name, err := localName(entry.Name)
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	baseDir := filepath.Join(localPath, name)
 	if err := os.MkdirAll(baseDir, 0755); err != nil {
 		return fmt.Errorf("download: failed to create directory: %w", err)
 	}
 
-	// Download each file
-	for i, file := range files {
-		// Calculate relative path
+	// Settle every local path up front: prompts for -i can't interleave
+	// with running workers, and complete files need no worker at all
+	var jobs []downloadJob
+	skipped := 0
+	for _, file := range files {
 		relPath := strings.TrimPrefix(file.path, remotePath+"/")
 		localFilePath := filepath.Join(baseDir, relPath)
-
-		// Ensure parent directory exists
+		if !withinDir(baseDir, localFilePath) {
+			return fmt.Errorf("download: illegal file path: %s", file.path)
+		}
+		if clobber == clobberOverwrite && vaultFileComplete(file.entry, localFilePath) {
+			skipped++
+			continue
+		}
+		if !clobber.allow(env, localFilePath) {
+			skipped++
+			continue
+		}
 		if err := os.MkdirAll(filepath.Dir(localFilePath), 0755); err != nil {
 			return fmt.Errorf("download: failed to create directory: %w", err)
 		}
+		jobs = append(jobs, downloadJob{entry: file.entry, relPath: relPath, local: localFilePath})
+	}
 
-		fmt.Fprintf(env.Stdout, "[%d/%d] %s\n", i+1, len(files), relPath)
+	if skipped > 0 {
+		fmt.Fprintf(env.Stdout, "Skipping %d files already downloaded\n", skipped)
+	}
+	if len(jobs) == 0 {
+		fmt.Fprintf(env.Stdout, "All %d files already downloaded\n", len(files))
+		return nil
+	}
 
-		// Download the file
-		if err := downloadVaultFile(ctx, s, env, file.entry, localFilePath); err != nil {
-			return err
+	if err := runDownloadJobs(ctx, s, env, jobs, "from vault ", func(job downloadJob, send func(int64, int64)) error {
+		return fetchVaultFile(ctx, s, job.entry, job.local, send)
+	}); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "\nDownloaded %d files to %s (decrypted)\n", len(jobs), baseDir)
+	return nil
+}
+
+// downloadJob is one file of a folder download and where it goes.
+type downloadJob struct {
+	entry   *api.FileEntry
+	relPath string
+	local   string
+}
+
+// runDownloadJobs fetches jobs with a pool of workers, each file with its own
+// retries, showing overall progress. Failures don't stop the other files;
+// they are listed at the end and make the download fail.
+func runDownloadJobs(ctx context.Context, s *session.Session, env *ExecutionEnv, jobs []downloadJob, from string, fetch func(job downloadJob, send func(int64, int64)) error) error {
+	workers := uploadWorkers(s, 0, len(jobs))
+	fmt.Fprintf(env.Stdout, "Downloading %d files %s(%d workers)...\n", len(jobs), from, workers)
+
+	progress := &UploadProgress{StartTime: time.Now(), Total: int64(len(jobs))}
+	printer := NewProgressPrinter(ctx, workers)
+
+	var mu sync.Mutex
+	var failures []string
+	queue := make(chan downloadJob)
+	var wg sync.WaitGroup
+	// A job's panic is raised again once the workers are done; the jobs
+	// after it are only drained
+	var panics ui.PanicTrap
+	run := func(job downloadJob) {
+		defer panics.Catch(nil)
+		t := ui.StartTransfer(ui.TransferDownload, job.relPath, job.entry.Size)
+		defer t.Finish()
+		err := fetch(job, t.Update)
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+			mu.Lock()
+			failures = append(failures, fmt.Sprintf("%s: %v", job.relPath, err))
+			mu.Unlock()
+		}
+		printer.OnFile(job.relPath, err == nil, errMsg)
+		done := progress.Increment()
+		printer.OnProgress(done, progress.Total, progress.Percent(), progress.ETA())
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				if !panics.Caught() {
+					run(job)
+				}
+			}
+		}()
+	}
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
 		}
+		queue <- job
 	}
+	close(queue)
+	wg.Wait()
+	panics.Rethrow()
+	printer.Finish()
 
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if len(failures) > 0 {
+		fmt.Fprintf(env.Stdout, "\nDownloaded %d files, %d failed. Run the same command to retry.\n", len(jobs)-len(failures), len(failures))
+		sort.Strings(failures)
+		for _, f := range failures {
+			fmt.Fprintf(env.Stderr, "  %s %s\n", ui.ErrorStyle.Render("✗"), f)
+		}
+		return fmt.Errorf("download: %d of %d files failed", len(failures), len(jobs))
+	}
 	return nil
 }
 
@@ -0,0 +1,12 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package commands
+
+import (
+	"os"
+	"syscall"
+)
+
+// statusSignals ask for a transfer status line: SIGINFO is what Ctrl+T
+// sends on BSD and macOS terminals.
+var statusSignals = []os.Signal{syscall.SIGINFO, syscall.SIGUSR1}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package commands
+
+import "os"
+
+// statusSignals is empty where there is no SIGINFO or SIGUSR1; use the
+// transfers command instead.
+var statusSignals []os.Signal
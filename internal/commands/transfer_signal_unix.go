@@ -0,0 +1,12 @@
+//go:build unix && !(darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package commands
+
+import (
+	"os"
+	"syscall"
+)
+
+// statusSignals ask for a transfer status line. Linux terminals have no
+// SIGINFO key, so this is 'kill -USR1 <pid>' from another window.
+var statusSignals = []os.Signal{syscall.SIGUSR1}
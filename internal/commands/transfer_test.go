@@ -0,0 +1,1811 @@
+package commands_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/commands"
+	"github.com/gYonder/drime-shell/internal/dlcache"
+	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// UPLOAD QUOTA PRE-CHECK TESTS
+// ============================================================================
+
+func writeTempFile(t *testing.T, name string, size int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+	return path
+}
+
+func TestUpload_RejectsWhenOutOfSpace(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	localFile := writeTempFile(t, "big.bin", 16<<20)
+
+	uploadCalled := false
+	usageCalls := 0
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		usageCalls++
+		return &api.SpaceUsage{Used: 1000, Available: 1024}, nil
+	}
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		uploadCalled = true
+		return &api.FileEntry{ID: 1, Name: name}, nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+	err := cmd.Run(context.Background(), s, env, []string{localFile, "/"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "insufficient space (need 16.0 MB, have 1.0 KB)")
+	assert.False(t, uploadCalled, "no data should be sent when the quota check fails")
+
+	// Small uploads skip the check and its requests
+	usageCalls = 0
+	small := writeTempFile(t, "small.bin", 4096)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", small, "/"}))
+	assert.True(t, uploadCalled)
+	assert.Zero(t, usageCalls)
+}
+
+func TestUpload_JSONProgress(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	localFile := writeTempFile(t, "data.bin", 2048)
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		return &api.SpaceUsage{Available: 1 << 30}, nil
+	}
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		_, err := io.Copy(io.Discard, reader)
+		return &api.FileEntry{ID: 1, Name: name, Size: size}, err
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+	err := cmd.Run(context.Background(), s, env, []string{"--progress", "json", localFile, "/"})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(env.Stderr.(*bytes.Buffer).String()), "\n")
+	require.NotEmpty(t, lines)
+
+	var last ui.ProgressEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	assert.Equal(t, localFile, last.File)
+	assert.True(t, last.Done)
+	assert.Equal(t, int64(2048), last.Total)
+	assert.Equal(t, float64(100), last.Pct)
+
+	// DRIME_PROGRESS applies to every command run through Execute
+	env.Stderr.(*bytes.Buffer).Reset()
+	s.ProgressMode = "json"
+	require.NoError(t, commands.Execute(context.Background(), cmd, s, env, []string{"--on-duplicate", "replace", localFile, "/"}))
+	lines = strings.Split(strings.TrimSpace(env.Stderr.(*bytes.Buffer).String()), "\n")
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	assert.True(t, last.Done)
+}
+
+func TestUpload_JSONProgressReportsFailedFiles(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+	t.Setenv("HOME", t.TempDir())
+
+	s, env, _ := setupTestEnv(t)
+	s.Cache.MarkChildrenLoaded("/")
+	local := filepath.Join(t.TempDir(), "batch")
+	require.NoError(t, os.MkdirAll(local, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "good.txt"), []byte("good"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "bad.txt"), []byte("bad"), 0644))
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.CreateFolderFunc = func(ctx context.Context, name string, parentID *int64, workspaceID int64) (*api.FileEntry, error) {
+		return &api.FileEntry{ID: 50, Name: name, Type: "folder"}, nil
+	}
+	mockClient.UploadWithOptionsFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *api.UploadOptions) (*api.FileEntry, error) {
+		if name == "bad.txt" {
+			return nil, &api.APIError{StatusCode: 400, Message: "rejected"}
+		}
+		return &api.FileEntry{ID: 60, Name: name, Size: size}, nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--retries", "0", "--progress", "json", local, "/"}))
+
+	done := map[string]ui.ProgressEvent{}
+	for _, line := range strings.Split(strings.TrimSpace(env.Stderr.(*bytes.Buffer).String()), "\n") {
+		var ev ui.ProgressEvent
+		if json.Unmarshal([]byte(line), &ev) == nil && ev.Done {
+			done[ev.File] = ev
+		}
+	}
+	require.Contains(t, done, "bad.txt")
+	assert.NotEmpty(t, done["bad.txt"].Error)
+	assert.Less(t, done["bad.txt"].Pct, float64(100), "a failed file is not complete")
+	assert.Equal(t, float64(100), done["good.txt"].Pct)
+}
+
+func TestUpload_AtomicRenamesIntoPlace(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	localFile := writeTempFile(t, "report.csv", 512)
+	s.Cache.AddChildren("/", []api.FileEntry{{ID: 50, Name: "report.csv", Type: "text"}})
+
+	var uploadedAs string
+	var trashed []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		return &api.SpaceUsage{Available: 1 << 30}, nil
+	}
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		uploadedAs = name
+		return &api.FileEntry{ID: 60, Name: name, Size: size}, nil
+	}
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		trashed = append(trashed, entryIDs...)
+		return nil
+	}
+	mockClient.RenameEntryFunc = func(ctx context.Context, entryID int64, newName string, workspaceID int64) (*api.FileEntry, error) {
+		assert.Len(t, trashed, 1, "the old file is trashed before the rename")
+		return &api.FileEntry{ID: entryID, Name: newName, Type: "text"}, nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--atomic", "--on-duplicate", "replace", "--progress", "json", localFile, "/"}))
+
+	assert.True(t, strings.HasPrefix(uploadedAs, ".report.csv.") && strings.HasSuffix(uploadedAs, ".uploading"), "uploaded as %q", uploadedAs)
+	assert.Equal(t, []int64{50}, trashed, "the replaced file goes to the trash")
+	entry, ok := s.Cache.Get("/report.csv")
+	require.True(t, ok)
+	assert.Equal(t, int64(60), entry.ID)
+}
+
+func TestUpload_MimeOverride(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.MarkChildrenLoaded("/")
+	localFile := writeTempFile(t, "scan.bin", 256)
+
+	var gotMime string
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		return &api.SpaceUsage{Available: 1 << 30}, nil
+	}
+	mockClient.UploadWithOptionsFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *api.UploadOptions) (*api.FileEntry, error) {
+		gotMime = opts.Mime
+		return &api.FileEntry{ID: 70, Name: name, Size: size}, nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--mime", "Application/PDF", "--progress", "json", localFile, "/"}))
+	assert.Equal(t, "application/pdf", gotMime)
+
+	for _, bad := range []string{"pdf", "application/", "a/b/c", "text/plain; charset"} {
+		err := cmd.Run(context.Background(), s, env, []string{"--mime", bad, localFile, "/"})
+		assert.ErrorContains(t, err, "invalid MIME type", bad)
+	}
+}
+
+func TestUpload_DeleteAfterRemovesVerifiedSource(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.Cache.MarkChildrenLoaded("/")
+	localFile := writeTempFile(t, "report.pdf", 256)
+
+	remoteSize := int64(256)
+	var remote []api.FileEntry
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		return &api.SpaceUsage{Available: 1 << 30}, nil
+	}
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		remote = []api.FileEntry{{ID: 70, Name: name, Type: "pdf", Size: remoteSize}}
+		return &remote[0], nil
+	}
+	mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+		return remote, nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+
+	// --dry-run only lists the file
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--delete-after", "--dry-run", "--on-duplicate", "replace", "--progress", "json", localFile, "/"}))
+	assert.Contains(t, stdout.String(), "would delete "+localFile)
+	assert.FileExists(t, localFile)
+
+	// A remote copy of another size is never trusted
+	remoteSize = 100
+	err := cmd.Run(context.Background(), s, env, []string{"--delete-after", "--on-duplicate", "replace", "--progress", "json", localFile, "/"})
+	assert.ErrorContains(t, err, "could not be verified")
+	assert.FileExists(t, localFile)
+
+	// -u skips a remote copy that looks up to date, which proves nothing
+	s.Cache.Add(&api.FileEntry{ID: 71, Name: "report.pdf", Type: "pdf", Size: 256, UpdatedAt: time.Now().Add(time.Hour)}, "/report.pdf")
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--delete-after", "-u", "--progress", "json", localFile, "/"}))
+	assert.Contains(t, stdout.String(), "Nothing uploaded, keeping local "+localFile)
+	assert.FileExists(t, localFile)
+
+	// So does a skip as a duplicate, which is not a failed upload either
+	stdout.Reset()
+	mockClient.ValidateEntriesFunc = func(ctx context.Context, req api.ValidateRequest) (*api.ValidateResponse, error) {
+		return &api.ValidateResponse{Duplicates: []string{"report.pdf"}}, nil
+	}
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--delete-after", "--on-duplicate", "skip", "--progress", "json", localFile, "/"}))
+	assert.Contains(t, stdout.String(), "Nothing uploaded, keeping local "+localFile)
+	assert.FileExists(t, localFile)
+	mockClient.ValidateEntriesFunc = nil
+
+	remoteSize = 256
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--delete-after", "--on-duplicate", "replace", "--progress", "json", localFile, "/"}))
+	assert.NoFileExists(t, localFile)
+}
+
+func TestUpload_DeleteAfterKeepsFilesNotUploaded(t *testing.T) {
+	defer commands.SetNoPromptForTest()()
+	t.Setenv("HOME", t.TempDir())
+	local := filepath.Join(t.TempDir(), "project")
+	require.NoError(t, os.MkdirAll(filepath.Join(local, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "README"), []byte("readme"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "src", "main.go"), []byte("package main"), 0644))
+
+	run := func(t *testing.T, stored func(name string) string, args ...string) (string, error) {
+		s, env, stdout := setupTestEnv(t)
+		s.Cache.MarkChildrenLoaded("/")
+		s.Cache.Add(&api.FileEntry{ID: 50, Name: "project", Type: "folder"}, "/project")
+		// Same size as the local README but not the same file
+		s.Cache.Add(&api.FileEntry{ID: 51, Name: "README", Type: "text", Size: 6, Hash: "h51"}, "/project/README")
+		s.Cache.Add(&api.FileEntry{ID: 52, Name: "src", Type: "folder"}, "/project/src")
+		s.Cache.MarkChildrenLoaded("/project")
+		s.Cache.MarkChildrenLoaded("/project/src")
+
+		var mu sync.Mutex
+		children := map[int64][]api.FileEntry{
+			0:  {{ID: 50, Name: "project", Type: "folder"}},
+			50: {{ID: 51, Name: "README", Type: "text", Size: 6, Hash: "h51"}, {ID: 52, Name: "src", Type: "folder"}},
+		}
+		mockClient := s.Client.(*api.MockDrimeClient)
+		mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+			return &api.SpaceUsage{Available: 1 << 30}, nil
+		}
+		mockClient.UploadWithOptionsFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *api.UploadOptions) (*api.FileEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			entry := api.FileEntry{ID: 60, Name: name, Type: "text", Size: size, Hash: "h-" + name}
+			children[*parentID] = append(children[*parentID], entry)
+			return &entry, nil
+		}
+		mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var id int64
+			if parentID != nil {
+				id = *parentID
+			}
+			return children[id], nil
+		}
+		mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+			_, err := io.WriteString(w, stored(strings.TrimPrefix(hash, "h-")))
+			return nil, err
+		}
+
+		cmd, ok := commands.Get("upload")
+		require.True(t, ok)
+		err := cmd.Run(context.Background(), s, env, append([]string{"--progress", "json", "--merge", "--on-duplicate", "skip", "--delete-after"}, args...))
+		return stdout.String(), err
+	}
+
+	// The stored copy differs: the checksum catches what the size can't
+	_, err := run(t, func(string) string { return "package mine" }, "--verify", local, "/")
+	assert.ErrorContains(t, err, "could not be verified")
+	assert.FileExists(t, filepath.Join(local, "src", "main.go"))
+
+	out, err := run(t, func(string) string { return "package main" }, "--verify", local, "/")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Verified src/main.go")
+	assert.NoFileExists(t, filepath.Join(local, "src", "main.go"))
+	assert.NoDirExists(t, filepath.Join(local, "src"), "a folder left empty goes too")
+	assert.FileExists(t, filepath.Join(local, "README"), "a skipped file is never deleted")
+}
+
+func TestUpload_AtomicKeepsTempAndRestoresOriginalOnFailedRename(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	localFile := writeTempFile(t, "report.csv", 512)
+	s.Cache.Add(&api.FileEntry{ID: 50, Name: "report.csv", Type: "text", Size: 100}, "/report.csv")
+	s.Cache.MarkChildrenLoaded("/")
+
+	var purged, trashed, restored []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		return &api.SpaceUsage{Available: 1 << 30}, nil
+	}
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		return &api.FileEntry{ID: 60, Name: name, Size: size}, nil
+	}
+	mockClient.RenameEntryFunc = func(ctx context.Context, entryID int64, newName string, workspaceID int64) (*api.FileEntry, error) {
+		return nil, errors.New("conflict")
+	}
+	mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		trashed = append(trashed, entryIDs...)
+		return nil
+	}
+	mockClient.RestoreEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		restored = append(restored, entryIDs...)
+		return nil
+	}
+	mockClient.DeleteEntriesForeverFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+		purged = append(purged, entryIDs...)
+		return nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+	err := cmd.Run(context.Background(), s, env, []string{"--atomic", "--progress", "json", localFile, "/"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upload kept as /.report.csv.")
+	assert.Empty(t, purged, "the only copy of the new data must not be deleted")
+	assert.Equal(t, []int64{50}, trashed)
+	assert.Equal(t, []int64{50}, restored, "the replaced file must come back from the trash")
+	entry, ok := s.Cache.Get("/report.csv")
+	require.True(t, ok)
+	assert.Equal(t, int64(50), entry.ID)
+}
+
+// ============================================================================
+// FOLDER DOWNLOAD RETRY TESTS
+// ============================================================================
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestDownload_FolderRetriesCorruptArchive(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 7, Name: "docs", Type: "folder", Hash: "docs-hash"}, "/docs")
+	archive := buildZip(t, map[string]string{"docs/readme.txt": "hello"})
+
+	var fullCalls, resumeOffsets []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		fullCalls = append(fullCalls, 0)
+		if len(fullCalls) == 1 {
+			// Connection drops halfway through the first attempt
+			_, _ = w.Write(archive[:len(archive)/2])
+			return nil, errors.New("connection reset")
+		}
+		_, err := w.Write(archive)
+		return &api.FileEntry{Size: int64(len(archive))}, err
+	}
+	mockClient.DownloadWithOptionsFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64), opts *api.DownloadOptions) (*api.FileEntry, error) {
+		resumeOffsets = append(resumeOffsets, opts.ResumeFrom)
+		// Stream ends early without an error, leaving a truncated archive
+		_, err := w.Write(archive[opts.ResumeFrom : len(archive)-10])
+		return &api.FileEntry{Size: int64(len(archive))}, err
+	}
+
+	localDir := t.TempDir()
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"/docs", localDir}))
+
+	assert.Equal(t, []int64{int64(len(archive) / 2)}, resumeOffsets, "second attempt should resume the partial archive")
+	assert.Len(t, fullCalls, 2, "corrupt resumed archive should be re-downloaded from scratch")
+
+	data, err := os.ReadFile(filepath.Join(localDir, "docs", "readme.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestDownload_FolderRestartsWhenRangeIgnored(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 7, Name: "docs", Type: "folder", Hash: "docs-hash"}, "/docs")
+	archive := buildZip(t, map[string]string{"docs/readme.txt": "hello"})
+
+	fullCalls := 0
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		fullCalls++
+		if fullCalls == 1 {
+			_, _ = w.Write(archive[:len(archive)/2])
+			return nil, errors.New("connection reset")
+		}
+		_, err := w.Write(archive)
+		return &api.FileEntry{Size: int64(len(archive))}, err
+	}
+	mockClient.DownloadWithOptionsFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64), opts *api.DownloadOptions) (*api.FileEntry, error) {
+		assert.True(t, opts.RequireRange, "a resumed archive must get exactly the rest")
+		return nil, api.ErrRangeNotHonored
+	}
+
+	localDir := t.TempDir()
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"/docs", localDir}))
+	assert.Equal(t, 2, fullCalls, "an ignored Range should restart the archive from scratch")
+
+	data, err := os.ReadFile(filepath.Join(localDir, "docs", "readme.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestDownload_RetriesFlags(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 8, Name: "big.tar", Type: "file", Hash: "tar-hash", Size: 100}, "/big.tar")
+
+	calls := 0
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadWithOptionsFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64), opts *api.DownloadOptions) (*api.FileEntry, error) {
+		calls++
+		return nil, errors.New("connection reset")
+	}
+
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+
+	err := cmd.Run(context.Background(), s, env, []string{"--retries", "2", "--retry-delay=1ms", "--progress", "json", "/big.tar", "-"})
+	require.ErrorContains(t, err, "failed after 3 attempts")
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = cmd.Run(context.Background(), s, env, []string{"--retries=0", "--progress", "json", "/big.tar", "-"})
+	require.ErrorContains(t, err, "failed after 1 attempts")
+	assert.Equal(t, 1, calls)
+
+	// After --, a path that looks like the option is just a path
+	calls = 0
+	err = cmd.Run(context.Background(), s, env, []string{"--progress=json", "--", "--retries", "-"})
+	require.ErrorContains(t, err, "--retries: No such file or directory")
+	assert.Equal(t, 0, calls)
+
+	err = cmd.Run(context.Background(), s, env, []string{"--retries", "-1", "/big.tar", "-"})
+	require.ErrorContains(t, err, "invalid --retries")
+	err = cmd.Run(context.Background(), s, env, []string{"--retry-delay", "soon", "/big.tar", "-"})
+	require.ErrorContains(t, err, "invalid --retry-delay")
+}
+
+func TestDownload_ToStdoutResumesAfterFailure(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+
+	s, env, stdout := setupTestEnv(t)
+	content := []byte(strings.Repeat("0123456789", 100))
+	s.Cache.Add(&api.FileEntry{ID: 8, Name: "big.tar", Type: "file", Hash: "tar-hash", Size: int64(len(content))}, "/big.tar")
+
+	var offsets []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadWithOptionsFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64), opts *api.DownloadOptions) (*api.FileEntry, error) {
+		if opts == nil {
+			// Connection drops partway through the first attempt
+			_, _ = w.Write(content[:300])
+			return nil, errors.New("connection reset")
+		}
+		offsets = append(offsets, opts.ResumeFrom)
+		assert.True(t, opts.RequireRange, "a resumed stream must get exactly the rest")
+		_, err := w.Write(content[opts.ResumeFrom:])
+		return &api.FileEntry{Size: int64(len(content))}, err
+	}
+
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"big.tar", "-"}))
+
+	assert.Equal(t, []int64{300}, offsets)
+	assert.Equal(t, content, stdout.Bytes(), "stdout should carry only the file content")
+
+	// A server that sends the whole file again can't be resumed from, and
+	// retrying won't change that
+	stdout.Reset()
+	offsets = nil
+	mockClient.DownloadWithOptionsFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64), opts *api.DownloadOptions) (*api.FileEntry, error) {
+		if opts == nil {
+			_, _ = w.Write(content[:300])
+			return nil, errors.New("connection reset")
+		}
+		offsets = append(offsets, opts.ResumeFrom)
+		return nil, api.ErrRangeNotHonored
+	}
+	err := cmd.Run(context.Background(), s, env, []string{"big.tar", "-"})
+	require.ErrorIs(t, err, api.ErrRangeNotHonored)
+	assert.Equal(t, []int64{300}, offsets, "no retries once the range is refused")
+	assert.Equal(t, content[:300], stdout.Bytes())
+
+	err = cmd.Run(context.Background(), s, env, []string{"/", "-"})
+	assert.Error(t, err, "folders cannot be written to stdout")
+}
+
+func TestDownload_TarToStdout(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+
+	s, env, stdout := setupTestEnv(t)
+	siteID, assetsID := int64(10), int64(11)
+	contents := map[string]string{"index-hash": "<h1>hi</h1>", "logo-hash": strings.Repeat("png", 100)}
+	s.Cache.AddChildren("/", []api.FileEntry{{ID: siteID, Name: "site", Type: "folder"}})
+	s.Cache.AddChildren("/site", []api.FileEntry{
+		{ID: 20, Name: "index.html", Type: "text", Hash: "index-hash", Size: int64(len(contents["index-hash"])), ParentID: &siteID},
+		{ID: assetsID, Name: "assets", Type: "folder", ParentID: &siteID},
+		{ID: 12, Name: "empty", Type: "folder", ParentID: &siteID},
+	})
+	s.Cache.AddChildren("/site/assets", []api.FileEntry{
+		{ID: 21, Name: "logo.png", Type: "image", Hash: "logo-hash", Size: int64(len(contents["logo-hash"])), ParentID: &assetsID},
+	})
+	s.Cache.AddChildren("/site/empty", []api.FileEntry{})
+
+	failed := false
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadWithOptionsFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64), opts *api.DownloadOptions) (*api.FileEntry, error) {
+		content := contents[hash]
+		if hash == "logo-hash" && !failed {
+			// The connection drops partway; the archive must not see a byte twice
+			failed = true
+			_, _ = io.WriteString(w, content[:50])
+			return nil, errors.New("connection reset")
+		}
+		if opts != nil {
+			content = content[opts.ResumeFrom:]
+		}
+		_, err := io.WriteString(w, content)
+		return nil, err
+	}
+
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--tar", "site", "-"}))
+
+	got := make(map[string]string)
+	tr := tar.NewReader(stdout)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		got[hdr.Name] = string(data)
+	}
+	assert.Equal(t, map[string]string{
+		"site/":                "",
+		"site/assets/":         "",
+		"site/assets/logo.png": contents["logo-hash"],
+		"site/empty/":          "",
+		"site/index.html":      contents["index-hash"],
+	}, got)
+
+	err := cmd.Run(context.Background(), s, env, []string{"--tar", "site", "out.tar"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "usage: download --tar <folder> -")
+}
+
+func TestDownload_TarRootFolder(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	siteID := int64(10)
+	s.Cache.AddChildren("/", []api.FileEntry{
+		{ID: siteID, Name: "site", Type: "folder"},
+		{ID: 20, Name: "notes.txt", Type: "text", Hash: "notes-hash", Size: 5},
+	})
+	s.Cache.AddChildren("/site", []api.FileEntry{
+		{ID: 21, Name: "index.html", Type: "text", Hash: "index-hash", Size: 2, ParentID: &siteID},
+	})
+	contents := map[string]string{"notes-hash": "notes", "index-hash": "hi"}
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadWithOptionsFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64), opts *api.DownloadOptions) (*api.FileEntry, error) {
+		_, err := io.WriteString(w, contents[hash])
+		return nil, err
+	}
+
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--tar", "/", "-"}))
+
+	got := make(map[string]string)
+	tr := tar.NewReader(stdout)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		got[hdr.Name] = string(data)
+	}
+	assert.Equal(t, map[string]string{
+		"notes.txt":       "notes",
+		"site/":           "",
+		"site/index.html": "hi",
+	}, got, "the root's contents go at the top level")
+}
+
+// ============================================================================
+// UNFINISHED MULTIPART UPLOAD TESTS
+// ============================================================================
+
+func TestUploadsAbort_AbortsJournaledUploads(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.UploadJournal = api.NewMultipartJournal(filepath.Join(t.TempDir(), "pending-uploads.json"))
+	require.NoError(t, s.UploadJournal.Record(api.PendingMultipart{Key: "uploads/a", UploadID: "2~alpha", Name: "a.bin", Size: 10}))
+	require.NoError(t, s.UploadJournal.Record(api.PendingMultipart{Key: "uploads/b", UploadID: "2~beta", Name: "b.bin", Size: 20}))
+
+	var aborted []string
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.AbortMultipartFunc = func(ctx context.Context, key, uploadID string) error {
+		aborted = append(aborted, uploadID)
+		return nil
+	}
+
+	cmd, ok := commands.Get("uploads")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, nil))
+	assert.Contains(t, stdout.String(), "2~alpha")
+	assert.Contains(t, stdout.String(), "b.bin")
+
+	// Prefixes must be unambiguous
+	err := cmd.Run(context.Background(), s, env, []string{"abort", "2~"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matches 2 uploads")
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"abort", "2~al"}))
+	assert.Equal(t, []string{"2~alpha"}, aborted)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"abort", "--all"}))
+	assert.Equal(t, []string{"2~alpha", "2~beta"}, aborted)
+
+	pending, err := s.UploadJournal.List()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestClampConcurrency(t *testing.T) {
+	assert.Equal(t, 6, commands.ClampConcurrency(0))
+	assert.Equal(t, 6, commands.ClampConcurrency(-3))
+	assert.Equal(t, 2, commands.ClampConcurrency(2))
+	assert.Equal(t, commands.MaxConcurrency, commands.ClampConcurrency(1000))
+}
+
+func TestWorkerPool_BigFilesShareTheStreamBudget(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	streams := map[string]int{}
+
+	mockClient := &api.MockDrimeClient{
+		UploadWithOptionsFunc: func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *api.UploadOptions) (*api.FileEntry, error) {
+			require.NotNil(t, opts)
+			mu.Lock()
+			streams[name] = opts.PartConcurrency
+			inFlight += opts.PartConcurrency
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			inFlight -= opts.PartConcurrency
+			mu.Unlock()
+			return &api.FileEntry{Name: name}, nil
+		},
+	}
+
+	config := commands.UploadConfig{Concurrency: 3, RetryAttempts: 1, WorkersPerFile: 3, BigFileThreshold: 1000}
+	pool := commands.NewWorkerPool(context.Background(), mockClient, nil, "/", config, nil, 0)
+	pool.Start()
+	pool.Submit(commands.FileUploadTask{LocalPath: writeTempFile(t, "big.bin", 2000), RelativePath: "big.bin", Size: 2000})
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		pool.Submit(commands.FileUploadTask{LocalPath: writeTempFile(t, name, 10), RelativePath: name, Size: 10})
+	}
+	stats := pool.Close()
+
+	assert.Equal(t, int64(5), stats.Uploaded)
+	assert.Equal(t, 3, streams["big.bin"])
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		assert.Equal(t, 1, streams[name], name)
+	}
+	assert.LessOrEqual(t, maxInFlight, 3, "streams never exceed --jobs")
+}
+
+func TestDownload_NoClobberAndInteractive(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 7, Name: "docs", Type: "folder", Hash: "docs-hash"}, "/docs")
+	s.Cache.Add(&api.FileEntry{ID: 8, Name: "notes.txt", Type: "text", Hash: "notes-hash", Size: 6}, "/notes.txt")
+	archive := buildZip(t, map[string]string{"docs/a.txt": "remote a", "docs/b.txt": "remote b", "docs/c.txt": "remote c"})
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		if hash == "notes-hash" {
+			_, err := w.Write([]byte("remote"))
+			return &api.FileEntry{Size: 6}, err
+		}
+		_, err := w.Write(archive)
+		return &api.FileEntry{Size: int64(len(archive))}, err
+	}
+
+	localDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(localDir, "docs"), 0755))
+	for _, name := range []string{"a.txt", "b.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(localDir, "docs", name), []byte("local"), 0644))
+	}
+	// Same size as the remote file, so it would otherwise count as downloaded
+	notesPath := filepath.Join(localDir, "notes.txt")
+	require.NoError(t, os.WriteFile(notesPath, []byte("mine!!"), 0644))
+
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+
+	// -n keeps every existing file and still extracts the new one
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-n", "/docs", localDir}))
+	for name, want := range map[string]string{"a.txt": "local", "b.txt": "local", "c.txt": "remote c"} {
+		data, err := os.ReadFile(filepath.Join(localDir, "docs", name))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(data), name)
+	}
+
+	// -i asks once per existing file; the zip's order is not fixed, so make
+	// every file local again before answering
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(localDir, "docs", name), []byte("local"), 0644))
+	}
+	env.Stdin = strings.NewReader("y\nn\ny\n")
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-i", "/docs", localDir}))
+	got := map[string]string{}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		data, err := os.ReadFile(filepath.Join(localDir, "docs", name))
+		require.NoError(t, err)
+		got[name] = string(data)
+	}
+	kept := 0
+	for _, content := range got {
+		if content == "local" {
+			kept++
+		}
+	}
+	assert.Equal(t, 1, kept, "exactly one file was declined: %v", got)
+
+	env.Stdin = strings.NewReader("y\n")
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-i", "--progress", "json", "/notes.txt", localDir}))
+	data, err := os.ReadFile(notesPath)
+	require.NoError(t, err)
+	assert.Equal(t, "remote", string(data), "an accepted overwrite replaces a same-size file")
+}
+
+func TestDownload_ConfirmsTargetsOutsideWorkingDir(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 8, Name: "notes.txt", Type: "text", Hash: "notes-hash", Size: 6}, "/notes.txt")
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		_, err := w.Write([]byte("remote"))
+		return &api.FileEntry{Size: 6}, err
+	}
+
+	root := t.TempDir()
+	work := filepath.Join(root, "a", "b")
+	require.NoError(t, os.MkdirAll(work, 0755))
+	t.Chdir(work)
+
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+
+	// No answer: refused, nothing written
+	err := cmd.Run(context.Background(), s, env, []string{"--progress", "json", "/notes.txt", "../../escaped.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outside the current directory")
+	assert.NoFileExists(t, filepath.Join(root, "escaped.txt"))
+
+	env.Stdin = strings.NewReader("y\n")
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "/notes.txt", "../../escaped.txt"}))
+	assert.FileExists(t, filepath.Join(root, "escaped.txt"))
+
+	// Paths that stay inside need no confirmation, even through ".."
+	env.Stdin = strings.NewReader("")
+	require.NoError(t, os.MkdirAll(filepath.Join(work, "sub"), 0755))
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "/notes.txt", "sub/../inside.txt"}))
+	assert.FileExists(t, filepath.Join(work, "inside.txt"))
+}
+
+func TestDownload_RejectsUnsafeRemoteNames(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 8, Name: "../evil.txt", Type: "text", Hash: "evil-hash", Size: 4}, "/evil")
+	s.Cache.Add(&api.FileEntry{ID: 9, Name: "docs", Type: "folder", Hash: "docs-hash"}, "/docs")
+	archive := buildZip(t, map[string]string{"docs/ok.txt": "fine", "../../zipslip.txt": "evil"})
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		if hash == "docs-hash" {
+			_, err := w.Write(archive)
+			return &api.FileEntry{Size: int64(len(archive))}, err
+		}
+		_, err := w.Write([]byte("evil"))
+		return &api.FileEntry{Size: 4}, err
+	}
+
+	root := t.TempDir()
+	localDir := filepath.Join(root, "out")
+	require.NoError(t, os.MkdirAll(localDir, 0755))
+
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+
+	err := cmd.Run(context.Background(), s, env, []string{"--progress", "json", "/evil", localDir})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsafe file name")
+	assert.NoFileExists(t, filepath.Join(root, "evil.txt"))
+
+	err = cmd.Run(context.Background(), s, env, []string{"/docs", localDir})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "illegal file path")
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(root), "zipslip.txt"))
+}
+
+func TestDownload_FolderIntoWorkingDir(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 7, Name: "docs", Type: "folder", Hash: "docs-hash"}, "/docs")
+	archive := buildZip(t, map[string]string{"docs/readme.txt": "hello"})
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		_, err := w.Write(archive)
+		return &api.FileEntry{Size: int64(len(archive))}, err
+	}
+
+	work := t.TempDir()
+	t.Chdir(work)
+
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"/docs"}))
+
+	data, err := os.ReadFile(filepath.Join(work, "docs", "readme.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestDownload_OutputDirCreatesTree(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 8, Name: "notes.txt", Type: "text", Hash: "notes-hash", Size: 6}, "/notes.txt")
+	s.Cache.Add(&api.FileEntry{ID: 9, Name: "todo.txt", Type: "text", Hash: "todo-hash", Size: 6}, "/todo.txt")
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		_, err := w.Write([]byte("remote"))
+		return &api.FileEntry{Size: 6}, err
+	}
+
+	out := filepath.Join(t.TempDir(), "backups", "2024")
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "-o", out, "/notes.txt"}))
+	assert.FileExists(t, filepath.Join(out, "notes.txt"))
+
+	// A local path is taken relative to the output dir, missing parents included
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "-o", out, "/notes.txt", "q1/renamed.txt"}))
+	assert.FileExists(t, filepath.Join(out, "q1", "renamed.txt"))
+
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+	require.NoError(t, os.WriteFile(listFile, []byte("/notes.txt\n/todo.txt\n"), 0644))
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "--from-file", listFile, "--output-dir", out, "bulk"}))
+	assert.FileExists(t, filepath.Join(out, "bulk", "notes.txt"))
+	assert.FileExists(t, filepath.Join(out, "bulk", "todo.txt"))
+
+	err := cmd.Run(context.Background(), s, env, []string{"-o", out, "/notes.txt", "-"})
+	require.Error(t, err)
+}
+
+func TestDownload_DefaultsToDownloadDir(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 8, Name: "notes.txt", Type: "text", Hash: "notes-hash", Size: 6}, "/notes.txt")
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		_, err := w.Write([]byte("remote"))
+		return &api.FileEntry{Size: 6}, err
+	}
+	s.DownloadDir = filepath.Join(t.TempDir(), "Downloads", "drime")
+
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "/notes.txt"}))
+	assert.FileExists(t, filepath.Join(s.DownloadDir, "notes.txt"), "the missing directory is created")
+
+	// An explicit local path or -o wins
+	explicit := t.TempDir()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "/notes.txt", explicit}))
+	assert.FileExists(t, filepath.Join(explicit, "notes.txt"))
+	out := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "-o", out, "/notes.txt"}))
+	assert.FileExists(t, filepath.Join(out, "notes.txt"))
+}
+
+func TestUpload_ProgressIntervalThrottlesUpdates(t *testing.T) {
+	localFile := writeTempFile(t, "data.bin", 200)
+
+	run := func(interval string) int {
+		s, env, _ := setupTestEnv(t)
+		mockClient := s.Client.(*api.MockDrimeClient)
+		mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+			return &api.SpaceUsage{Available: 1 << 30}, nil
+		}
+		mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+			// Small reads, as a slow connection would make them
+			buf := make([]byte, 1)
+			for {
+				if _, err := reader.Read(buf); err == io.EOF {
+					break
+				} else if err != nil {
+					return nil, err
+				}
+			}
+			return &api.FileEntry{ID: 1, Name: name, Size: size}, nil
+		}
+
+		cmd, ok := commands.Get("upload")
+		require.True(t, ok)
+		require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "--progress-interval", interval, "--on-duplicate", "replace", localFile, "/"}))
+		return strings.Count(env.Stderr.(*bytes.Buffer).String(), "\n")
+	}
+
+	// Every whole percent is reported without throttling
+	assert.Greater(t, run("0"), 100)
+	// Only the first update, the completing one and the final event remain
+	assert.LessOrEqual(t, run("1h"), 3)
+
+	s, env, _ := setupTestEnv(t)
+	cmd, _ := commands.Get("upload")
+	err := cmd.Run(context.Background(), s, env, []string{"--progress-interval", "soon", localFile, "/"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid --progress-interval")
+}
+
+func TestDownload_NoZipResumesFileByFile(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 10, Name: "site", Type: "folder", Hash: "site-hash"}, "/site")
+	children := map[int64][]api.FileEntry{
+		10: {
+			{ID: 11, Name: "index.html", Type: "text", Hash: "index-hash", Size: 10},
+			{ID: 12, Name: "assets", Type: "folder"},
+			{ID: 13, Name: "empty", Type: "folder"},
+		},
+		12: {{ID: 14, Name: "logo.png", Type: "image", Hash: "logo-hash", Size: 8}},
+	}
+	contents := map[string]string{"index-hash": "<html/>ok!", "logo-hash": "PNGDATA!"}
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+		if parentID == nil {
+			return nil, nil
+		}
+		return children[*parentID], nil
+	}
+	var mu sync.Mutex
+	var ranges []string
+	fetch := func(hash string, w io.Writer, from int64) (*api.FileEntry, error) {
+		mu.Lock()
+		ranges = append(ranges, fmt.Sprintf("%s@%d", hash, from))
+		mu.Unlock()
+		data, ok := contents[hash]
+		if !ok {
+			return nil, fmt.Errorf("unexpected download of %s", hash)
+		}
+		_, err := io.WriteString(w, data[from:])
+		return &api.FileEntry{Size: int64(len(data) - int(from))}, err
+	}
+	mockClient.DownloadWithOptionsFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64), opts *api.DownloadOptions) (*api.FileEntry, error) {
+		return fetch(hash, w, opts.ResumeFrom)
+	}
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		return fetch(hash, w, 0)
+	}
+
+	out := t.TempDir()
+	// A previous run left index.html half done, under its partial name
+	require.NoError(t, os.MkdirAll(filepath.Join(out, "site"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(out, "site", "index.html.drime-partial"), []byte("<html"), 0644))
+
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "--preserve-structure", "/site", out}))
+
+	data, err := os.ReadFile(filepath.Join(out, "site", "index.html"))
+	require.NoError(t, err)
+	assert.Equal(t, "<html/>ok!", string(data))
+	assert.NoFileExists(t, filepath.Join(out, "site", "index.html.drime-partial"))
+	data, err = os.ReadFile(filepath.Join(out, "site", "assets", "logo.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "PNGDATA!", string(data))
+	assert.DirExists(t, filepath.Join(out, "site", "empty"))
+	assert.ElementsMatch(t, []string{"index-hash@5", "logo-hash@0"}, ranges)
+
+	// Everything is on disk now, so a second run has nothing to fetch
+	ranges = nil
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "--no-zip", "/site", out}))
+	assert.Empty(t, ranges)
+
+	err = cmd.Run(context.Background(), s, env, []string{"--no-zip", "--zip", "/site", out})
+	require.Error(t, err)
+}
+
+func TestDownload_StripComponents(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 7, Name: "docs", Type: "folder", Hash: "docs-hash"}, "/docs")
+	s.Cache.Add(&api.FileEntry{ID: 8, Name: "clash", Type: "folder", Hash: "clash-hash"}, "/clash")
+	archives := map[string][]byte{
+		"docs-hash":  buildZip(t, map[string]string{"docs/readme.txt": "hello", "docs/img/a.png": "png"}),
+		"clash-hash": buildZip(t, map[string]string{"clash/a/x.txt": "1", "clash/b/x.txt": "2"}),
+	}
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		_, err := w.Write(archives[hash])
+		return &api.FileEntry{Size: int64(len(archives[hash]))}, err
+	}
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+
+	one := t.TempDir()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--strip-components", "1", "/docs", one}))
+	assert.FileExists(t, filepath.Join(one, "readme.txt"))
+	assert.FileExists(t, filepath.Join(one, "img", "a.png"))
+	assert.NoDirExists(t, filepath.Join(one, "docs"))
+
+	// Files with too few components are skipped
+	two := t.TempDir()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--strip-components", "2", "/docs", two}))
+	assert.FileExists(t, filepath.Join(two, "a.png"))
+	assert.NoFileExists(t, filepath.Join(two, "readme.txt"))
+
+	clash := t.TempDir()
+	err := cmd.Run(context.Background(), s, env, []string{"--strip-components", "2", "/clash", clash})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "would both be written to 'x.txt'")
+	assert.NoFileExists(t, filepath.Join(clash, "x.txt"), "nothing is extracted when paths collide")
+
+	// File by file, the folder name counts as the first component too
+	mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+		if parentID != nil && *parentID == 7 {
+			return []api.FileEntry{{ID: 9, Name: "notes.txt", Type: "text", Hash: "notes-hash", Size: 5}}, nil
+		}
+		return nil, nil
+	}
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		_, err := w.Write([]byte("notes"))
+		return &api.FileEntry{Size: 5}, err
+	}
+	perFile := t.TempDir()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "--no-zip", "--strip-components", "1", "/docs", perFile}))
+	assert.FileExists(t, filepath.Join(perFile, "notes.txt"))
+
+	require.Error(t, cmd.Run(context.Background(), s, env, []string{"--strip-components", "-1", "/docs", one}))
+}
+
+func TestUpload_CompressAppliesToDirectoryFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	s, env, _ := setupTestEnv(t)
+	s.Cache.MarkChildrenLoaded("/")
+	local := filepath.Join(t.TempDir(), "logs")
+	require.NoError(t, os.MkdirAll(local, 0755))
+	text := strings.Repeat("GET /index.html 200\n", 100)
+	require.NoError(t, os.WriteFile(filepath.Join(local, "access.log"), []byte(text), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "a.png"), []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0644))
+
+	var mu sync.Mutex
+	uploaded := map[string][]byte{}
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.CreateFolderFunc = func(ctx context.Context, name string, parentID *int64, workspaceID int64) (*api.FileEntry, error) {
+		return &api.FileEntry{ID: 50, Name: name, Type: "folder"}, nil
+	}
+	mockClient.UploadWithOptionsFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *api.UploadOptions) (*api.FileEntry, error) {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		uploaded[name] = data
+		return &api.FileEntry{ID: int64(60 + len(uploaded)), Name: name, Size: size}, nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--compress", "--progress", "json", local, "/"}))
+
+	require.Contains(t, uploaded, "access.log.gz")
+	assert.Contains(t, uploaded, "a.png", "images are sent as they are")
+	zr, err := gzip.NewReader(bytes.NewReader(uploaded["access.log.gz"]))
+	require.NoError(t, err)
+	restored, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, text, string(restored))
+	_, ok = s.Cache.Get("/logs/access.log.gz")
+	assert.True(t, ok)
+}
+
+func TestDownload_DecompressesFolderFilesHonouringNoClobber(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 7, Name: "logs", Type: "folder", Hash: "logs-hash"}, "/logs")
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Name = "access.log"
+	zw.Comment = "drime-shell:compressed"
+	_, err := zw.Write([]byte("from the cloud"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	archive := buildZip(t, map[string]string{"logs/access.log.gz": gz.String()})
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		_, err := w.Write(archive)
+		return &api.FileEntry{Size: int64(len(archive))}, err
+	}
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+
+	out := t.TempDir()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"/logs", out}))
+	data, err := os.ReadFile(filepath.Join(out, "logs", "access.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "from the cloud", string(data))
+	assert.NoFileExists(t, filepath.Join(out, "logs", "access.log.gz"))
+
+	// -n keeps a local file under the restored name, and the archive with it
+	require.NoError(t, os.WriteFile(filepath.Join(out, "logs", "access.log"), []byte("local edits"), 0644))
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-n", "/logs", out}))
+	data, err = os.ReadFile(filepath.Join(out, "logs", "access.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "local edits", string(data))
+	assert.FileExists(t, filepath.Join(out, "logs", "access.log.gz"))
+
+	// --raw leaves folder files compressed
+	raw := t.TempDir()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--raw", "/logs", raw}))
+	assert.FileExists(t, filepath.Join(raw, "logs", "access.log.gz"))
+	assert.NoFileExists(t, filepath.Join(raw, "logs", "access.log"))
+}
+
+func TestUpload_StagingPublishesOnlyCompleteDirectory(t *testing.T) {
+	defer commands.SetRetryBaseDelayForTest(time.Millisecond)()
+	t.Setenv("HOME", t.TempDir())
+
+	s, env, stdout := setupTestEnv(t)
+	s.Cache.MarkChildrenLoaded("/")
+	local := filepath.Join(t.TempDir(), "site")
+	require.NoError(t, os.MkdirAll(filepath.Join(local, "css"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "index.html"), []byte("<html/>"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "css", "main.css"), []byte("body{}"), 0644))
+
+	var mu sync.Mutex
+	nextID := int64(100)
+	var folders []string
+	// The first run is interrupted while uploading main.css
+	ctx, interrupt := context.WithCancel(context.Background())
+	defer interrupt()
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		return &api.SpaceUsage{Available: 1 << 30}, nil
+	}
+	mockClient.CreateFolderFunc = func(ctx context.Context, name string, parentID *int64, workspaceID int64) (*api.FileEntry, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		nextID++
+		folders = append(folders, name)
+		return &api.FileEntry{ID: nextID, Name: name, Type: "folder"}, nil
+	}
+	mockClient.UploadWithOptionsFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *api.UploadOptions) (*api.FileEntry, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if name == "main.css" && ctx.Err() == nil {
+			interrupt()
+			return nil, ctx.Err()
+		}
+		nextID++
+		return &api.FileEntry{ID: nextID, Name: name, Size: size}, nil
+	}
+	var renamed []string
+	mockClient.RenameEntryFunc = func(ctx context.Context, entryID int64, newName string, workspaceID int64) (*api.FileEntry, error) {
+		renamed = append(renamed, fmt.Sprintf("%d:%s", entryID, newName))
+		return &api.FileEntry{ID: entryID, Name: newName, Type: "folder"}, nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(ctx, s, env, []string{"--staging", "--progress", "json", local, "/"}))
+
+	// A missing file keeps the folder hidden
+	require.NotEmpty(t, folders)
+	staged := folders[0]
+	assert.True(t, strings.HasPrefix(staged, ".site.") && strings.HasSuffix(staged, ".uploading"), "staged as %q", staged)
+	assert.Empty(t, renamed)
+	_, ok = s.Cache.Get("/site")
+	assert.False(t, ok)
+	assert.Contains(t, stdout.String(), "Nothing published")
+
+	// The next run resumes into the same folder and publishes it
+	createdBefore := len(folders)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--staging", "--progress", "json", local, "/"}))
+	assert.Len(t, folders, createdBefore, "no new folders on resume")
+	assert.Equal(t, []string{"101:site"}, renamed)
+	entry, ok := s.Cache.Get("/site")
+	require.True(t, ok)
+	assert.Equal(t, int64(101), entry.ID)
+	_, ok = s.Cache.Get("/" + staged)
+	assert.False(t, ok)
+
+	err := cmd.Run(context.Background(), s, env, []string{"--staging", "-u", local, "/"})
+	assert.Error(t, err)
+}
+
+func TestUpload_MakeParentsCreatesDestinationFolders(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.MarkChildrenLoaded("/")
+	s.Cache.Add(&api.FileEntry{ID: 10, Name: "a", Type: "folder"}, "/a")
+	s.Cache.MarkChildrenLoaded("/a")
+	localFile := writeTempFile(t, "notes.txt", 64)
+
+	var created []string
+	nextID := int64(20)
+	var uploadedTo *int64
+	var uploadedAs string
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		return &api.SpaceUsage{Available: 1 << 30}, nil
+	}
+	mockClient.CreateFolderFunc = func(ctx context.Context, name string, parentID *int64, workspaceID int64) (*api.FileEntry, error) {
+		created = append(created, fmt.Sprintf("%s@%d", name, *parentID))
+		nextID++
+		return &api.FileEntry{ID: nextID, Name: name, Type: "folder", ParentID: parentID}, nil
+	}
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		uploadedTo, uploadedAs = parentID, name
+		return &api.FileEntry{ID: 99, Name: name, Size: size}, nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+
+	// Strict by default: a typo doesn't create folders
+	err := cmd.Run(context.Background(), s, env, []string{"--progress", "json", localFile, "/a/b/c/file.txt"})
+	require.ErrorContains(t, err, "No such directory")
+	assert.Empty(t, created)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-p", "--progress", "json", localFile, "/a/b/c/file.txt"}))
+	assert.Equal(t, []string{"b@10", "c@21"}, created)
+	require.NotNil(t, uploadedTo)
+	assert.Equal(t, int64(22), *uploadedTo)
+	assert.Equal(t, "file.txt", uploadedAs)
+
+	// Existing folders are reused; a trailing slash makes the whole path a folder
+	created = nil
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-p", "--progress", "json", localFile, "/a/b/c/d/"}))
+	assert.Equal(t, []string{"d@22"}, created)
+	assert.Equal(t, int64(23), *uploadedTo)
+	assert.Equal(t, "notes.txt", uploadedAs)
+}
+
+func TestUpload_UpdateAllowsForClockSkew(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	localFile := writeTempFile(t, "notes.txt", 64)
+	localMod := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(localFile, localMod, localMod))
+	// Stamped by a server whose clock runs 5 minutes ahead, right before the
+	// local edit
+	s.Cache.MarkChildrenLoaded("/")
+	s.Cache.Add(&api.FileEntry{ID: 5, Name: "notes.txt", Type: "text", UpdatedAt: localMod.Add(2 * time.Minute)}, "/notes.txt")
+
+	uploads := 0
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		return &api.SpaceUsage{Available: 1 << 30}, nil
+	}
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		uploads++
+		return &api.FileEntry{ID: 6, Name: name, Size: size}, nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+
+	// Taken at face value the remote copy looks newer
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-u", "--on-duplicate", "replace", "--progress", "json", localFile, "/"}))
+	assert.Equal(t, 0, uploads)
+
+	s.ClockSkew = 5 * time.Minute
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"-u", "--on-duplicate", "replace", "--progress", "json", localFile, "/"}))
+	assert.Equal(t, 1, uploads)
+}
+
+// ============================================================================
+// TRANSFERS COMMAND TESTS
+// ============================================================================
+
+func TestTransfers_ListsOwnAndPublishedTransfers(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	s.TransfersDir = t.TempDir()
+
+	tr := ui.StartTransfer(ui.TransferUpload, "local/report.pdf", 4096)
+	tr.Update(1024, 4096)
+	defer tr.Finish()
+
+	other, err := json.Marshal([]ui.TransferStatus{
+		{ID: 1, File: "movie.mkv", Direction: ui.TransferDownload, Done: 50, Total: 100, Speed: 10},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(s.TransfersDir, "999999.json"), other, 0600))
+
+	stale := filepath.Join(s.TransfersDir, "999998.json")
+	require.NoError(t, os.WriteFile(stale, []byte(`[{"id":1,"file":"gone.txt","direction":"upload"}]`), 0600))
+	old := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(stale, old, old))
+
+	cmd, ok := commands.Get("transfers")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--once"}))
+
+	out := stdout.String()
+	assert.Contains(t, out, "local/report.pdf")
+	assert.Contains(t, out, "25%")
+	assert.Contains(t, out, "movie.mkv")
+	assert.Contains(t, out, "999999")
+	assert.Contains(t, out, "50%")
+	assert.Contains(t, out, "5s", "ETA of the published download")
+	assert.NotContains(t, out, "gone.txt", "snapshots of exited shells are ignored")
+
+	tr.Finish()
+	s.TransfersDir = ""
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--once"}))
+	assert.Contains(t, stdout.String(), "No active transfers.")
+}
+
+func TestDownload_RestoresUnchangedFilesFromCache(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 8, Name: "notes.txt", Type: "text", Hash: "notes-hash", Size: 6, UpdatedAt: time.Unix(1700000000, 0)}, "/notes.txt")
+	s.DownloadCache = dlcache.New(filepath.Join(t.TempDir(), "cache"), 1<<20)
+	calls := 0
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		calls++
+		_, err := w.Write([]byte("remote"))
+		return &api.FileEntry{Size: 6}, err
+	}
+
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+	first, second := t.TempDir(), t.TempDir()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "/notes.txt", first}))
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "/notes.txt", second}))
+	assert.Equal(t, 1, calls, "the second download comes from the cache")
+	data, err := os.ReadFile(filepath.Join(second, "notes.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "remote", string(data))
+
+	// Folders small enough for a zip go file by file so the cache applies
+	docsID := int64(9)
+	s.Cache.Add(&api.FileEntry{ID: docsID, Name: "docs", Type: "folder", Hash: "docs-hash"}, "/docs")
+	s.Cache.AddChildren("/docs", []api.FileEntry{
+		{ID: 10, Name: "notes.txt", Type: "text", Hash: "notes-hash", Size: 6, UpdatedAt: time.Unix(1700000000, 0), ParentID: &docsID},
+	})
+	s.NoZipThreshold = 200
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--progress", "json", "/docs", first}))
+	assert.Equal(t, 1, calls, "the folder's unchanged file comes from the cache")
+	data, err = os.ReadFile(filepath.Join(first, "docs", "notes.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "remote", string(data))
+}
+
+func TestUpload_MaxDepthSkipsDeeperFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	s, env, stdout := setupTestEnv(t)
+	s.Cache.MarkChildrenLoaded("/")
+	local := filepath.Join(t.TempDir(), "project")
+	require.NoError(t, os.MkdirAll(filepath.Join(local, "src", "deep"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "README"), []byte("readme"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "src", "main.go"), []byte("package main"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "src", "deep", "a.go"), []byte("package deep"), 0644))
+
+	var mu sync.Mutex
+	nextID := int64(100)
+	var folders, files []string
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		return &api.SpaceUsage{Available: 1 << 30}, nil
+	}
+	mockClient.CreateFolderFunc = func(ctx context.Context, name string, parentID *int64, workspaceID int64) (*api.FileEntry, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		nextID++
+		folders = append(folders, name)
+		return &api.FileEntry{ID: nextID, Name: name, Type: "folder"}, nil
+	}
+	mockClient.UploadWithOptionsFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *api.UploadOptions) (*api.FileEntry, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		nextID++
+		files = append(files, name)
+		return &api.FileEntry{ID: nextID, Name: name, Size: size}, nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--max-depth", "1", "--progress", "json", local, "/"}))
+	assert.Equal(t, []string{"project"}, folders)
+	assert.Equal(t, []string{"README"}, files)
+	assert.Contains(t, stdout.String(), "Skipping 2 files below --max-depth 1")
+
+	err := cmd.Run(context.Background(), s, env, []string{"--max-depth", "0", local, "/"})
+	require.ErrorContains(t, err, "--max-depth must be 1 or more")
+	err = cmd.Run(context.Background(), s, env, []string{"--max-depth", "2", "--delete-after", local, "/"})
+	require.ErrorContains(t, err, "can't be combined with --max-depth")
+}
+
+func TestUpload_VerifyAfterReportsMissingFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	local := filepath.Join(t.TempDir(), "photos")
+	require.NoError(t, os.MkdirAll(filepath.Join(local, "2024"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "a.jpg"), []byte("aaaa"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "2024", "b.jpg"), []byte("bbbbbb"), 0644))
+
+	run := func(t *testing.T, lose string) (string, string, error) {
+		s, env, stdout := setupTestEnv(t)
+		s.Cache.MarkChildrenLoaded("/")
+
+		var mu sync.Mutex
+		nextID := int64(100)
+		server := map[int64][]api.FileEntry{} // children by parent ID, 0 = root
+		add := func(e api.FileEntry, parentID *int64) *api.FileEntry {
+			mu.Lock()
+			defer mu.Unlock()
+			nextID++
+			e.ID = nextID
+			var parent int64
+			if parentID != nil {
+				parent = *parentID
+			}
+			if e.Name != lose {
+				server[parent] = append(server[parent], e)
+			}
+			return &e
+		}
+		mockClient := s.Client.(*api.MockDrimeClient)
+		mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+			return &api.SpaceUsage{Available: 1 << 30}, nil
+		}
+		mockClient.CreateFolderFunc = func(ctx context.Context, name string, parentID *int64, workspaceID int64) (*api.FileEntry, error) {
+			return add(api.FileEntry{Name: name, Type: "folder"}, parentID), nil
+		}
+		mockClient.UploadWithOptionsFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *api.UploadOptions) (*api.FileEntry, error) {
+			return add(api.FileEntry{Name: name, Type: "image", Size: size}, parentID), nil
+		}
+		mockClient.ListByParentIDFunc = func(ctx context.Context, parentID *int64) ([]api.FileEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var parent int64
+			if parentID != nil {
+				parent = *parentID
+			}
+			return append([]api.FileEntry(nil), server[parent]...), nil
+		}
+
+		cmd, ok := commands.Get("upload")
+		require.True(t, ok)
+		err := cmd.Run(context.Background(), s, env, []string{"--verify-after", "--progress", "json", local, "/"})
+		return stdout.String(), env.Stderr.(*bytes.Buffer).String(), err
+	}
+
+	out, _, err := run(t, "")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Verified 2 files in /photos")
+
+	// The server acknowledged b.jpg but never listed it
+	out, errOut, err := run(t, "b.jpg")
+	require.ErrorContains(t, err, "1 of 2 files missing or different in /photos")
+	assert.Contains(t, errOut, "no remote copy at /photos/2024/b.jpg")
+	assert.NotContains(t, out, "Verified")
+}
+
+func TestUpload_OnlyShowErrorsPrintsSummary(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	s, env, stdout := setupTestEnv(t)
+	s.Cache.MarkChildrenLoaded("/")
+	local := filepath.Join(t.TempDir(), "batch")
+	require.NoError(t, os.MkdirAll(local, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "good.txt"), []byte("good"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "bad.txt"), []byte("bad"), 0644))
+
+	var mu sync.Mutex
+	nextID := int64(100)
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		return &api.SpaceUsage{Available: 1 << 30}, nil
+	}
+	mockClient.CreateFolderFunc = func(ctx context.Context, name string, parentID *int64, workspaceID int64) (*api.FileEntry, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		nextID++
+		return &api.FileEntry{ID: nextID, Name: name, Type: "folder"}, nil
+	}
+	mockClient.UploadWithOptionsFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *api.UploadOptions) (*api.FileEntry, error) {
+		if name == "bad.txt" {
+			return nil, errors.New("rejected")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		nextID++
+		return &api.FileEntry{ID: nextID, Name: name, Size: size}, nil
+	}
+
+	cmd, ok := commands.Get("upload")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"--only-show-errors", "--retries", "0", local, "/"}))
+	out := stdout.String()
+	assert.Contains(t, out, "Uploaded 1 files, 1 failed")
+	assert.NotContains(t, out, "Creating folder")
+	assert.NotContains(t, out, "Uploading 2 files")
+	assert.NotContains(t, out, "Failed files:")
+}
+
+func TestUpload_ExistingBaseFolder(t *testing.T) {
+	defer commands.SetNoPromptForTest()()
+	t.Setenv("HOME", t.TempDir())
+	local := filepath.Join(t.TempDir(), "project")
+	require.NoError(t, os.MkdirAll(filepath.Join(local, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "README"), []byte("readme"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "new.txt"), []byte("new"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "src", "main.go"), []byte("package main"), 0644))
+
+	type upload struct {
+		name   string
+		parent int64
+	}
+	run := func(t *testing.T, args ...string) (folders []string, uploads []upload, trashed []int64, out string, err error) {
+		s, env, stdout := setupTestEnv(t)
+		s.Cache.MarkChildrenLoaded("/")
+		s.Cache.Add(&api.FileEntry{ID: 50, Name: "project", Type: "folder"}, "/project")
+		s.Cache.Add(&api.FileEntry{ID: 51, Name: "README", Type: "text", Size: 6}, "/project/README")
+		s.Cache.Add(&api.FileEntry{ID: 52, Name: "src", Type: "folder"}, "/project/src")
+		s.Cache.MarkChildrenLoaded("/project")
+		s.Cache.MarkChildrenLoaded("/project/src")
+
+		var mu sync.Mutex
+		nextID := int64(100)
+		mockClient := s.Client.(*api.MockDrimeClient)
+		mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+			return &api.SpaceUsage{Available: 1 << 30}, nil
+		}
+		mockClient.CreateFolderFunc = func(ctx context.Context, name string, parentID *int64, workspaceID int64) (*api.FileEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			nextID++
+			folders = append(folders, name)
+			return &api.FileEntry{ID: nextID, Name: name, Type: "folder"}, nil
+		}
+		mockClient.UploadWithOptionsFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *api.UploadOptions) (*api.FileEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			nextID++
+			uploads = append(uploads, upload{name, *parentID})
+			return &api.FileEntry{ID: nextID, Name: name, Size: size}, nil
+		}
+		mockClient.DeleteEntriesFunc = func(ctx context.Context, entryIDs []int64, workspaceID int64) error {
+			mu.Lock()
+			defer mu.Unlock()
+			trashed = append(trashed, entryIDs...)
+			return nil
+		}
+
+		cmd, ok := commands.Get("upload")
+		require.True(t, ok)
+		err = cmd.Run(context.Background(), s, env, append([]string{"--progress", "json"}, args...))
+		sort.Slice(uploads, func(i, j int) bool { return uploads[i].name < uploads[j].name })
+		return folders, uploads, trashed, stdout.String(), err
+	}
+
+	t.Run("merge skips files already there", func(t *testing.T) {
+		folders, uploads, _, out, err := run(t, "--merge", "--on-duplicate", "skip", local, "/")
+		require.NoError(t, err)
+		assert.Empty(t, folders, "existing folders are reused")
+		assert.Equal(t, []upload{{"main.go", 52}, {"new.txt", 50}}, uploads)
+		assert.Contains(t, out, "Skipping 1 files already in /project")
+	})
+	t.Run("merge renames files already there", func(t *testing.T) {
+		_, uploads, _, _, err := run(t, "--merge", "--on-duplicate", "rename", local, "/")
+		require.NoError(t, err)
+		assert.Equal(t, []upload{{"README (1)", 50}, {"main.go", 52}, {"new.txt", 50}}, uploads)
+	})
+	t.Run("update merges and replaces older files", func(t *testing.T) {
+		folders, uploads, trashed, _, err := run(t, "-u", local, "/")
+		require.NoError(t, err)
+		assert.Empty(t, folders, "existing folders are reused")
+		assert.Equal(t, []upload{{"README", 50}, {"main.go", 52}, {"new.txt", 50}}, uploads)
+		assert.Equal(t, []int64{51}, trashed, "the old README goes once the new one is in")
+	})
+	t.Run("rename", func(t *testing.T) {
+		folders, uploads, _, _, err := run(t, "--rename", local, "/")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"project (1)", "src"}, folders)
+		assert.Len(t, uploads, 3)
+	})
+	t.Run("replace", func(t *testing.T) {
+		folders, uploads, trashed, _, err := run(t, "--replace", local, "/")
+		require.NoError(t, err)
+		assert.Equal(t, []int64{50}, trashed)
+		assert.Equal(t, []string{"project", "src"}, folders)
+		assert.Len(t, uploads, 3)
+	})
+	t.Run("ask without a terminal", func(t *testing.T) {
+		_, uploads, _, _, err := run(t, local, "/")
+		require.ErrorContains(t, err, "pass --merge, --rename or --replace")
+		assert.Empty(t, uploads)
+	})
+	t.Run("invalid combinations", func(t *testing.T) {
+		_, _, _, _, err := run(t, "--merge", "--replace", local, "/")
+		require.ErrorContains(t, err, "only one of")
+		_, _, _, _, err = run(t, "--merge", filepath.Join(local, "README"), "/")
+		require.ErrorContains(t, err, "applies to directories")
+	})
+}
+
+func TestTransfers_VerifyComparesChecksums(t *testing.T) {
+	s, env, stdout := setupTestEnv(t)
+	localFile := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(localFile, []byte("hello"), 0644))
+
+	stored := map[string][]byte{}
+	corrupt := false
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetSpaceUsageFunc = func(ctx context.Context, workspaceID int64) (*api.SpaceUsage, error) {
+		return &api.SpaceUsage{Available: 1 << 30}, nil
+	}
+	mockClient.UploadFunc = func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64) (*api.FileEntry, error) {
+		data, err := io.ReadAll(reader)
+		stored[name] = data
+		return &api.FileEntry{ID: 70, Name: name, Hash: name, Size: size, Type: "text"}, err
+	}
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		data := stored[hash]
+		if corrupt {
+			data = []byte("hellO")
+		}
+		_, err := w.Write(data)
+		return &api.FileEntry{Size: int64(len(data))}, err
+	}
+
+	upload, _ := commands.Get("upload")
+	require.NoError(t, upload.Run(context.Background(), s, env, []string{"--checksum-algo", "md5", "--progress", "json", localFile, "/"}))
+	assert.Contains(t, stdout.String(), "Verified notes.txt (md5 5d41402abc4b2a76b9719d911017c592)")
+
+	download, _ := commands.Get("download")
+	require.NoError(t, download.Run(context.Background(), s, env, []string{"--verify", "--progress", "json", "/notes.txt", t.TempDir()}))
+	assert.Contains(t, stdout.String(), "(sha256 2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824)")
+
+	sum, _ := commands.Get("crc32sum")
+	stdout.Reset()
+	require.NoError(t, sum.Run(context.Background(), s, env, []string{"/notes.txt"}))
+	assert.Equal(t, "3610a686  /notes.txt\n", stdout.String())
+
+	corrupt = true
+	err := upload.Run(context.Background(), s, env, []string{"--verify", "--on-duplicate", "replace", "--progress", "json", localFile, "/"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sha256 mismatch")
+
+	s.ChecksumAlgo = "sha1"
+	err = download.Run(context.Background(), s, env, []string{"--verify", "/notes.txt", t.TempDir()})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid checksum_algo in config")
+}
+
+func TestDownload_ResumeAndNoResume(t *testing.T) {
+	s, env, _ := setupTestEnv(t)
+	s.Cache.Add(&api.FileEntry{ID: 8, Name: "data.bin", Type: "file", Hash: "data-hash", Size: 5}, "/data.bin")
+	var offsets []int64
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.DownloadWithOptionsFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64), opts *api.DownloadOptions) (*api.FileEntry, error) {
+		var from int64
+		if opts != nil {
+			from = opts.ResumeFrom
+		}
+		offsets = append(offsets, from)
+		_, err := io.WriteString(w, "hello"[from:])
+		return &api.FileEntry{Size: 5}, err
+	}
+	mockClient.DownloadFunc = func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+		return mockClient.DownloadWithOptionsFunc(ctx, hash, w, progress, nil)
+	}
+
+	dir := t.TempDir()
+	local := filepath.Join(dir, "data.bin")
+	cmd, ok := commands.Get("download")
+	require.True(t, ok)
+	run := func(args ...string) error {
+		return cmd.Run(context.Background(), s, env, append([]string{"--progress", "json"}, args...))
+	}
+
+	err := run("--resume", "/data.bin", dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no partial download to resume")
+	assert.Empty(t, offsets)
+
+	// Only the partial file is resumed, and renamed once complete
+	require.NoError(t, os.WriteFile(local+".drime-partial", []byte("hel"), 0644))
+	require.NoError(t, run("--resume", "/data.bin", dir))
+	assert.NoFileExists(t, local+".drime-partial")
+	require.NoError(t, os.Remove(local))
+	require.NoError(t, os.WriteFile(local+".drime-partial", []byte("xyz"), 0644))
+	require.NoError(t, run("--no-resume", "/data.bin", dir))
+	assert.Equal(t, []int64{3, 0}, offsets)
+	data, err := os.ReadFile(local)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.NoFileExists(t, local+".drime-partial")
+
+	// A custom suffix is where the partial file is looked for
+	require.NoError(t, os.Remove(local))
+	require.NoError(t, os.WriteFile(local+".part", []byte("hell"), 0644))
+	require.NoError(t, run("--partial-suffix", ".part", "--resume", "/data.bin", dir))
+	assert.Equal(t, []int64{3, 0, 4}, offsets)
+	assert.NoFileExists(t, local+".part")
+	assert.Error(t, run("--partial-suffix", "", "/data.bin", dir))
+
+	// A complete-looking but corrupt file is fetched again too
+	require.NoError(t, os.WriteFile(local, []byte("HELLO"), 0644))
+	require.NoError(t, run("--resume=false", "/data.bin", dir))
+	data, err = os.ReadFile(local)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	assert.Error(t, run("--resume", "--no-resume", "/data.bin", dir))
+	assert.Error(t, run("--resume", "-n", "/data.bin", dir))
+}
+
+func TestReportTransfers_PrintsStatusLines(t *testing.T) {
+	var out bytes.Buffer
+	commands.ReportTransfersForTest(&out)
+	assert.Equal(t, "drime: no transfers in flight\n", out.String())
+
+	tr := ui.StartTransfer(ui.TransferUpload, "disk.img", 400<<20)
+	defer tr.Finish()
+	tr.Update(100<<20, 0)
+	out.Reset()
+	commands.ReportTransfersForTest(&out)
+	assert.Contains(t, out.String(), "upload disk.img: 100.0 MB of 400.0 MB (25%)")
+}
@@ -0,0 +1,297 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	Register(&Command{
+		Name:        "transfers",
+		Description: "Show active uploads and downloads",
+		Usage: `transfers [options]
+
+Lists every upload and download in flight with its progress, current speed
+and time left, and refreshes in place until Ctrl+C. When the output is not a
+terminal the list is printed once.
+
+The shell runs one command at a time, so transfers is mostly useful in a
+second window: each running shell publishes its transfers under
+~/.drime-shell/transfers, and transfers shows those of all of them, with the
+process they belong to.
+
+For a quick look without leaving the command, press Ctrl+T (BSD and macOS)
+or send the shell SIGUSR1 ('kill -USR1 <pid>', e.g. on Linux): it prints
+one status line per transfer, the way dd does.
+
+Options:
+  -1, --once              Print the list once and exit
+  -n, --interval DURATION Time between refreshes (default 1s)
+
+Examples:
+  transfers               # Watch transfers in other shells
+  transfers --once        # One snapshot`,
+		Run: transfersCmd,
+	})
+}
+
+// transferPublishInterval is how often PublishTransfers writes the snapshot;
+// snapshots older than transferStaleAfter belong to shells that have gone.
+const (
+	transferPublishInterval = time.Second
+	transferStaleAfter      = 5 * time.Second
+)
+
+// transferRow is a transfer as listed, with the shell running it.
+type transferRow struct {
+	ui.TransferStatus
+	pid int
+}
+
+func transfersCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("transfers", pflag.ContinueOnError)
+	once := fs.BoolP("once", "1", false, "print the list once")
+	interval := fs.DurationP("interval", "n", time.Second, "time between refreshes")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: transfers [--once] [-n interval]")
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("transfers: invalid --interval %s", *interval)
+	}
+
+	if *once || !ui.IsTerminal(env.Stdout) {
+		printTransfers(env, collectTransfers(s.TransfersDir))
+		return nil
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	lines := 0
+	for {
+		if lines > 0 {
+			// Back over the previous list and clear it
+			fmt.Fprintf(env.Stdout, "\033[%dA\033[J", lines)
+		}
+		lines = printTransfers(env, collectTransfers(s.TransfersDir))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectTransfers returns the transfers of this shell and those published
+// by other shells in dir.
+func collectTransfers(dir string) []transferRow {
+	self := os.Getpid()
+	var rows []transferRow
+	for _, t := range ui.ActiveTransfers() {
+		rows = append(rows, transferRow{TransferStatus: t, pid: self})
+	}
+	if dir == "" {
+		return rows
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, path := range files {
+		pid, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil || pid == self {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) > transferStaleAfter {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var list []ui.TransferStatus
+		if json.Unmarshal(data, &list) != nil {
+			continue
+		}
+		for _, t := range list {
+			rows = append(rows, transferRow{TransferStatus: t, pid: pid})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].pid != rows[j].pid {
+			return rows[i].pid < rows[j].pid
+		}
+		return rows[i].ID < rows[j].ID
+	})
+	return rows
+}
+
+// printTransfers writes the transfer table and returns how many lines it
+// took.
+func printTransfers(env *ExecutionEnv, rows []transferRow) int {
+	if len(rows) == 0 {
+		fmt.Fprintln(env.Stdout, "No active transfers.")
+		return 1
+	}
+
+	cells := make([][]string, 0, len(rows)+1)
+	cells = append(cells, []string{"PID", "DIR", "DONE", "TOTAL", "%", "SPEED", "ETA", "FILE"})
+	for _, r := range rows {
+		dir := "down"
+		if r.Direction == ui.TransferUpload {
+			dir = "up"
+		}
+		pct, speed, eta := "-", "-", "-"
+		if r.Total > 0 {
+			pct = fmt.Sprintf("%d%%", min(r.Done*100/r.Total, 100))
+		}
+		if r.Speed > 0 {
+			speed = ui.FormatSize(int64(r.Speed)) + "/s"
+		}
+		if d, ok := r.ETA(); ok {
+			eta = formatTransferETA(d)
+		}
+		cells = append(cells, []string{
+			strconv.Itoa(r.pid), dir, ui.FormatSize(r.Done), ui.FormatSize(r.Total), pct, speed, eta, r.File,
+		})
+	}
+
+	widths := make([]int, len(cells[0]))
+	for _, row := range cells {
+		for i, c := range row {
+			widths[i] = max(widths[i], len(c))
+		}
+	}
+	for _, row := range cells {
+		var b strings.Builder
+		for i, c := range row {
+			switch {
+			case i == len(row)-1:
+				b.WriteString(c)
+			case i >= 2 && i <= 6:
+				// Numbers are right-aligned
+				fmt.Fprintf(&b, "%*s  ", widths[i], c)
+			default:
+				fmt.Fprintf(&b, "%-*s  ", widths[i], c)
+			}
+		}
+		fmt.Fprintln(env.Stdout, b.String())
+	}
+	return len(cells)
+}
+
+// formatTransferETA renders a time left as 42s, 3m05s or 2h10m.
+func formatTransferETA(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
+// ReportTransfersOnSignal prints a status line for each transfer in flight
+// to w whenever the process gets SIGINFO (Ctrl+T on BSD and macOS) or
+// SIGUSR1, like dd does, until ctx is done. It returns at once where
+// neither signal exists.
+func ReportTransfersOnSignal(ctx context.Context, w io.Writer) {
+	if len(statusSignals) == 0 {
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, statusSignals...)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			reportTransfers(w)
+		}
+	}
+}
+
+// reportTransfers writes one line per active transfer of this shell, such
+// as "upload disk.img: 120 MB of 400 MB (30%), 8.0 MB/s, 35s left".
+func reportTransfers(w io.Writer) {
+	active := ui.ActiveTransfers()
+	if len(active) == 0 {
+		fmt.Fprintln(w, "drime: no transfers in flight")
+		return
+	}
+	for _, t := range active {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s %s: %s", t.Direction, t.File, ui.FormatSize(t.Done))
+		if t.Total > 0 {
+			fmt.Fprintf(&b, " of %s (%d%%)", ui.FormatSize(t.Total), min(t.Done*100/t.Total, 100))
+		}
+		if t.Speed > 0 {
+			fmt.Fprintf(&b, ", %s/s", ui.FormatSize(int64(t.Speed)))
+		}
+		if d, ok := t.ETA(); ok {
+			fmt.Fprintf(&b, ", %s left", formatTransferETA(d))
+		}
+		fmt.Fprintln(w, b.String())
+	}
+}
+
+// PublishTransfers writes this shell's active transfers to dir/<pid>.json
+// every second until ctx is done, so transfers in another shell can list
+// them. The file is removed whenever nothing is in flight.
+func PublishTransfers(ctx context.Context, dir string) {
+	path := filepath.Join(dir, fmt.Sprintf("%d.json", os.Getpid()))
+	defer os.Remove(path)
+
+	ticker := time.NewTicker(transferPublishInterval)
+	defer ticker.Stop()
+	published := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		active := ui.ActiveTransfers()
+		if len(active) == 0 {
+			if published {
+				os.Remove(path)
+				published = false
+			}
+			continue
+		}
+		data, err := json.Marshal(active)
+		if err != nil {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			continue
+		}
+		// Write then rename so readers never see half a file
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0600); err != nil {
+			continue
+		}
+		if err := os.Rename(tmp, path); err == nil {
+			published = true
+		}
+	}
+}
@@ -17,6 +17,7 @@ import (
 func init() {
 	Register(&Command{
 		Name:        "trash",
+		Mutates:     subcommandMutates("restore", "empty"),
 		Description: "View and manage trash",
 		Usage: `trash [command]
 
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/gYonder/drime-shell/internal/api"
+)
+
+// typeFilter is ls --type and find --category: files of any of the listed
+// categories (image, video, ...) or MIME types (image/png, image/*).
+// Folders never match.
+type typeFilter struct {
+	categories []string
+	mimes      []string
+}
+
+// parseTypeFilter parses a comma-separated list of categories and MIME
+// patterns. An empty spec gives an inactive filter.
+func parseTypeFilter(spec string) (typeFilter, error) {
+	var f typeFilter
+	if spec == "" {
+		return f, nil
+	}
+	for _, item := range strings.Split(strings.ToLower(spec), ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+			continue
+		case strings.Contains(item, "/"):
+			if _, err := path.Match(item, ""); err != nil {
+				return f, fmt.Errorf("invalid MIME pattern '%s'", item)
+			}
+			f.mimes = append(f.mimes, item)
+		case slices.Contains(api.Categories, item):
+			f.categories = append(f.categories, item)
+		default:
+			return f, fmt.Errorf("unknown type '%s' (want %s, or a MIME type like image/png)", item, strings.Join(api.Categories, ", "))
+		}
+	}
+	return f, nil
+}
+
+func (f typeFilter) active() bool {
+	return len(f.categories) > 0 || len(f.mimes) > 0
+}
+
+func (f typeFilter) matches(e *api.FileEntry) bool {
+	if e.Type == api.FileTypeFolder {
+		return false
+	}
+	if len(f.categories) > 0 && slices.Contains(f.categories, api.EntryCategory(e)) {
+		return true
+	}
+	mime, _, _ := strings.Cut(strings.ToLower(e.Mime), ";")
+	mime = strings.TrimSpace(mime)
+	for _, pattern := range f.mimes {
+		if ok, _ := path.Match(pattern, mime); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filter keeps the entries the filter matches.
+func (f typeFilter) filter(entries []api.FileEntry) []api.FileEntry {
+	if !f.active() {
+		return entries
+	}
+	kept := make([]api.FileEntry, 0, len(entries))
+	for i := range entries {
+		if f.matches(&entries[i]) {
+			kept = append(kept, entries[i])
+		}
+	}
+	return kept
+}
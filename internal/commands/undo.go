@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	Register(&Command{
+		Name:        "undo",
+		Mutates:     alwaysMutates,
+		Description: "Reverse the last mv, cp or rm",
+		Usage: `undo [-l]
+
+Reverses the most recent mv, cp or rm of this session: moved and renamed
+entries go back where they were, copies are deleted (to the trash) and
+removed entries are restored from the trash. Run it again to go further
+back. An undo that fails partway keeps what it did not reverse, so running
+it again retries the rest. Only reversible changes are logged, so rm -F,
+vault operations and moves or copies to other workspaces can't be undone.
+
+Options:
+  -l, --list  Show what undo would reverse, most recent first
+
+Examples:
+  undo             Put back what the last mv, cp or rm changed
+  undo --list      See what can still be undone`,
+		Run: undo,
+	})
+}
+
+func undo(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("undo", pflag.ContinueOnError)
+	list := fs.BoolP("list", "l", false, "show what undo would reverse")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: undo [-l]")
+	}
+
+	if *list {
+		log := s.UndoLog()
+		if len(log) == 0 {
+			fmt.Fprintln(env.Stdout, "Nothing to undo")
+			return nil
+		}
+		for i := len(log) - 1; i >= 0; i-- {
+			fmt.Fprintf(env.Stdout, "%3d  %s\n", len(log)-i, describeUndo(log[i]))
+		}
+		return nil
+	}
+
+	op, ok := s.PopUndo()
+	if !ok {
+		return fmt.Errorf("undo: nothing to undo")
+	}
+	if s.InVault || op.WorkspaceID != s.WorkspaceID {
+		s.PushUndo(op)
+		return fmt.Errorf("undo: the last %s ran in another workspace (ID %d); switch back to it first", op.Command, op.WorkspaceID)
+	}
+
+	rest := op
+	err := ui.WithSpinnerErr(env.Stderr, "", false, func() error {
+		return reverseOperation(ctx, s, &rest)
+	})
+	if err != nil {
+		// What was not reversed stays on the log for the next undo
+		if len(rest.Moved)+len(rest.Copied)+len(rest.Trashed) > 0 {
+			s.PushUndo(rest)
+		}
+		return fmt.Errorf("undo: %s was only partly undone: %w", op.Command, err)
+	}
+	fmt.Fprintf(env.Stdout, "Undid %s\n", describeUndo(op))
+	return nil
+}
+
+// describeUndo summarizes what op changed, e.g. "mv /a.txt -> /docs/a.txt"
+// or "rm: 3 entries trashed".
+func describeUndo(op session.UndoOperation) string {
+	var parts []string
+	moves := collapseMoves(op.Moved)
+	switch len(moves) {
+	case 0:
+	case 1:
+		parts = append(parts, fmt.Sprintf("%s -> %s", moves[0].From, moves[0].To))
+	default:
+		parts = append(parts, fmt.Sprintf("%d entries moved", len(moves)))
+	}
+	switch len(op.Copied) {
+	case 0:
+	case 1:
+		parts = append(parts, "copied to "+op.Copied[0].Path)
+	default:
+		parts = append(parts, fmt.Sprintf("%d copies", len(op.Copied)))
+	}
+	switch len(op.Trashed) {
+	case 0:
+	case 1:
+		parts = append(parts, "trashed "+op.Trashed[0].Path)
+	default:
+		parts = append(parts, fmt.Sprintf("%d entries trashed", len(op.Trashed)))
+	}
+	if len(parts) == 1 && len(moves) == 1 {
+		return op.Command + " " + parts[0]
+	}
+	return op.Command + ": " + strings.Join(parts, ", ")
+}
+
+// collapseMoves merges the moves of each entry into one, from where it was
+// first to where it ended up, in the order the entries were first moved.
+func collapseMoves(moved []session.UndoMove) []session.UndoMove {
+	var out []session.UndoMove
+	index := make(map[int64]int)
+	for _, m := range moved {
+		if i, ok := index[m.ID]; ok {
+			out[i].To = m.To
+			continue
+		}
+		index[m.ID] = len(out)
+		out = append(out, m)
+	}
+	kept := out[:0]
+	for _, m := range out {
+		if m.From != m.To {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// reverseOperation undoes op: its copies are deleted, moved entries are
+// moved back and renamed to their old names, and trashed entries are
+// restored. Copies go first so that nothing they hold up gets in the way.
+// On error, op is left holding only what was not reversed, moves starting
+// from where the entries are now.
+func reverseOperation(ctx context.Context, s *session.Session, op *session.UndoOperation) error {
+	if len(op.Copied) > 0 {
+		if err := s.Client.DeleteEntries(ctx, undoEntryIDs(op.Copied), s.WorkspaceID); err != nil {
+			return fmt.Errorf("cannot delete copies: %w", err)
+		}
+		for _, c := range op.Copied {
+			s.Cache.Remove(c.Path)
+		}
+		op.Copied = nil
+	}
+
+	moves := collapseMoves(op.Moved)
+	op.Moved = moves
+	defer func() { op.Moved = collapseMoves(moves) }()
+	byDir := make(map[string][]int) // original folder -> moves whose entries go back there
+	for i, m := range moves {
+		if from := path.Dir(m.From); from != path.Dir(m.To) {
+			byDir[from] = append(byDir[from], i)
+		}
+	}
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		dirEntry, ok := s.Cache.Get(dir)
+		if !ok || dirEntry.Type != "folder" {
+			return fmt.Errorf("cannot move back to '%s': No such directory", dir)
+		}
+		ids := make([]int64, len(byDir[dir]))
+		for j, i := range byDir[dir] {
+			ids[j] = moves[i].ID
+		}
+		if err := s.Client.MoveEntries(ctx, ids, parentIDPtr(dirEntry), s.WorkspaceID, nil); err != nil {
+			return fmt.Errorf("cannot move back to '%s': %w", dir, err)
+		}
+		for _, i := range byDir[dir] {
+			moved := path.Join(dir, path.Base(moves[i].To))
+			if entry, ok := s.Cache.Get(moves[i].To); ok {
+				s.Cache.Remove(moves[i].To)
+				s.Cache.Add(entry, moved)
+			}
+			moves[i].To = moved
+		}
+	}
+	for i, m := range moves {
+		if name := path.Base(m.From); name != path.Base(m.To) {
+			renamed, err := s.Client.RenameEntry(ctx, m.ID, name, s.WorkspaceID)
+			if err != nil {
+				return fmt.Errorf("cannot rename '%s' back to '%s': %w", m.To, name, err)
+			}
+			s.Cache.Remove(m.To)
+			if renamed != nil {
+				s.Cache.Add(renamed, m.From)
+			}
+		}
+		moves[i].To = m.From
+	}
+
+	if len(op.Trashed) == 0 {
+		return nil
+	}
+	if err := s.Client.RestoreEntries(ctx, undoEntryIDs(op.Trashed), s.WorkspaceID); err != nil {
+		return fmt.Errorf("cannot restore from trash: %w", err)
+	}
+	trashed := op.Trashed
+	op.Trashed = nil
+	// Re-list the folders restored into so the entries are found again
+	relisted := make(map[string]bool)
+	for _, t := range trashed {
+		dir := path.Dir(t.Path)
+		if relisted[dir] {
+			continue
+		}
+		relisted[dir] = true
+		if entry, ok := s.Cache.Get(dir); ok && entry.Type == "folder" {
+			if err := refreshListing(ctx, s, dir, entry); err != nil {
+				s.Cache.InvalidateChildren(dir)
+			}
+		}
+	}
+	return nil
+}
+
+// undoEntryIDs returns the IDs of entries.
+func undoEntryIDs(entries []session.UndoEntry) []int64 {
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
@@ -20,6 +20,7 @@ type UploadSession struct {
 	LocalPath      string            `json:"local_path"`
 	RemotePath     string            `json:"remote_path"`
 	BaseFolderPath string            `json:"base_folder_path"`
+	FinalName      string            `json:"final_name,omitempty"` // --staging: name to publish the base folder under
 	filePath       string            `json:"-"`
 	StartedAt      time.Time         `json:"started_at"`
 	UpdatedAt      time.Time         `json:"updated_at"`
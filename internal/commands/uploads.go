@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	Register(&Command{
+		Name:        "uploads",
+		Mutates:     subcommandMutates("abort", "cancel"),
+		Description: "List or abort unfinished multipart uploads",
+		Usage: `uploads [command]
+
+Failed or interrupted uploads can leave multipart uploads on the server that
+still count against your quota. The shell records every multipart upload it
+starts, so they can be cleaned up later, even after a crash.
+
+Commands:
+  uploads                     List unfinished multipart uploads
+  uploads abort <id>...       Abort the given uploads (ID prefixes accepted)
+  uploads abort --all         Abort all unfinished uploads
+
+Examples:
+  uploads
+  uploads abort 2~kX9
+  uploads abort --all`,
+		Run: uploads,
+	})
+}
+
+func uploads(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "ls", "list":
+			return listPendingUploads(s, env)
+		case "abort", "cancel":
+			return abortPendingUploads(ctx, s, env, args[1:])
+		default:
+			return fmt.Errorf("uploads: unknown command '%s'", args[0])
+		}
+	}
+	return listPendingUploads(s, env)
+}
+
+func listPendingUploads(s *session.Session, env *ExecutionEnv) error {
+	pending, err := s.UploadJournal.List()
+	if err != nil {
+		return fmt.Errorf("uploads: %w", err)
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(env.Stdout, "No unfinished uploads.")
+		return nil
+	}
+
+	for _, p := range pending {
+		fmt.Fprintf(env.Stdout, "%s  %8s  %s  %s\n",
+			p.UploadID,
+			ui.FormatSize(p.Size),
+			p.StartedAt.Local().Format("2006-01-02 15:04"),
+			p.Name)
+	}
+	return nil
+}
+
+func abortPendingUploads(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("uploads abort", pflag.ContinueOnError)
+	all := fs.BoolP("all", "a", false, "Abort all unfinished uploads")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !*all && fs.NArg() == 0 {
+		return fmt.Errorf("usage: uploads abort <id>... | --all")
+	}
+
+	pending, err := s.UploadJournal.List()
+	if err != nil {
+		return fmt.Errorf("uploads: %w", err)
+	}
+
+	var targets []api.PendingMultipart
+	if *all {
+		targets = pending
+	} else {
+		for _, id := range fs.Args() {
+			match, err := matchPendingUpload(pending, id)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, match)
+		}
+	}
+
+	if len(targets) == 0 {
+		fmt.Fprintln(env.Stdout, "No unfinished uploads.")
+		return nil
+	}
+
+	failed := 0
+	for _, p := range targets {
+		// A 404 means the upload is already gone on the server; just drop the record
+		if err := s.Client.AbortMultipart(ctx, p.Key, p.UploadID); err != nil && !api.IsNotFound(err) {
+			fmt.Fprintf(env.Stderr, "uploads: failed to abort %s: %v\n", p.Name, err)
+			failed++
+			continue
+		}
+		_ = s.UploadJournal.Forget(p.UploadID)
+		fmt.Fprintf(env.Stdout, "Aborted upload of %s\n", p.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("uploads: %d of %d aborts failed", failed, len(targets))
+	}
+	return nil
+}
+
+// matchPendingUpload finds the pending upload whose ID equals or starts with id.
+func matchPendingUpload(pending []api.PendingMultipart, id string) (api.PendingMultipart, error) {
+	var matches []api.PendingMultipart
+	for _, p := range pending {
+		if p.UploadID == id {
+			return p, nil
+		}
+		if strings.HasPrefix(p.UploadID, id) {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return api.PendingMultipart{}, fmt.Errorf("uploads: no unfinished upload '%s'", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return api.PendingMultipart{}, fmt.Errorf("uploads: '%s' matches %d uploads", id, len(matches))
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/crypto"
@@ -55,6 +57,7 @@ func readPassword(env *ExecutionEnv) (string, error) {
 func init() {
 	Register(&Command{
 		Name:        "vault",
+		Mutates:     subcommandMutates("init", "create"),
 		Description: "Enter encrypted vault or initialize a new vault",
 		Usage: `vault [command]
 
@@ -63,7 +66,12 @@ Enter vault:
   vault exit          Return to previous workspace
 
 First-time setup:
-  vault init          Initialize a new vault with a password
+  vault init          Create a vault: asks for a password twice and unlocks
+                      the new vault (the password cannot be recovered)
+
+State:
+  vault status        Whether a vault exists and is unlocked, and its usage
+  vault info          Vault metadata: ID, creation time, file count
 
 Cross-transfer (when in vault):
   cp file.txt /path -w <name|id>   Copy from vault to workspace (decrypts)
@@ -92,6 +100,10 @@ func vaultCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args [
 		return exitVault(ctx, s, env)
 	case "init", "create":
 		return initVault(ctx, s, env)
+	case "status":
+		return vaultStatus(ctx, s, env)
+	case "info":
+		return vaultInfo(ctx, s, env)
 	default:
 		return fmt.Errorf("unknown vault command: %s (use 'help vault' for usage)", args[0])
 	}
@@ -249,7 +261,9 @@ func unlockVaultWithPrompt(ctx context.Context, s *session.Session, env *Executi
 	return nil
 }
 
-// initVault initializes a new vault with a password.
+// initVault sets up a new vault: it asks for a password twice, derives the
+// key and check value from it, creates the vault on the server and leaves it
+// unlocked for the session.
 func initVault(ctx context.Context, s *session.Session, env *ExecutionEnv) error {
 	// Check if vault already exists
 	vaultMeta, err := ui.WithSpinner(env.Stdout, "", false, func() (*api.VaultMeta, error) {
@@ -263,6 +277,11 @@ func initVault(ctx context.Context, s *session.Session, env *ExecutionEnv) error
 		return fmt.Errorf("vault already exists - use 'vault' to switch to it")
 	}
 
+	// The key never leaves this machine, so the server can't help with a
+	// forgotten password
+	fmt.Fprintln(env.Stdout, ui.WarningStyle.Render("The vault password cannot be recovered or reset. Files are encrypted with it before"))
+	fmt.Fprintln(env.Stdout, ui.WarningStyle.Render("they leave this machine; if you forget it, they are lost for good."))
+
 	// Prompt for password
 	fmt.Fprint(env.Stdout, "Create vault password: ")
 	password1, err := readPassword(env)
@@ -334,8 +353,179 @@ func initVault(ctx context.Context, s *session.Session, env *ExecutionEnv) error
 	s.VaultID = newVault.ID
 	s.SetVaultKey(vaultKey)
 	s.VaultSalt = salt
+	s.VaultCheck = []byte(newVault.Check)
+	s.VaultCheckIV = []byte(newVault.IV)
 
 	fmt.Fprintln(env.Stdout, ui.SuccessStyle.Render("Vault created successfully"))
 	fmt.Fprintln(env.Stdout, "Use 'vault' to switch to your new vault.")
 	return nil
 }
+
+// vaultStatus prints whether a vault exists, whether it is unlocked and,
+// when it is, how much it holds.
+func vaultStatus(ctx context.Context, s *session.Session, env *ExecutionEnv) error {
+	vaultMeta, err := ui.WithSpinner(env.Stderr, "", false, func() (*api.VaultMeta, error) {
+		return s.Client.GetVaultMetadata(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check vault: %w", err)
+	}
+	if vaultMeta == nil {
+		fmt.Fprintln(env.Stdout, "Vault:     none (run 'vault init' to create one)")
+		return nil
+	}
+
+	fmt.Fprintf(env.Stdout, "Vault:     exists (ID %d)\n", vaultMeta.ID)
+	switch {
+	case s.InVault:
+		fmt.Fprintln(env.Stdout, "State:     unlocked, current context")
+	case s.IsVaultUnlocked():
+		fmt.Fprintln(env.Stdout, "State:     unlocked")
+	default:
+		fmt.Fprintln(env.Stdout, "State:     locked")
+	}
+	// There is no idle timeout: the key is kept until the shell exits
+	if s.IsVaultUnlocked() {
+		fmt.Fprintln(env.Stdout, "Auto-lock: none, stays unlocked until the shell exits")
+	}
+
+	if !s.IsVaultUnlocked() {
+		fmt.Fprintln(env.Stdout, "Usage:     unknown until unlocked (run 'vault')")
+		return nil
+	}
+	usage, err := vaultUsage(ctx, s, env)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	fmt.Fprintf(env.Stdout, "Usage:     %s\n", usage)
+	return nil
+}
+
+// vaultInfo prints the vault's metadata and, when it is unlocked, what it
+// holds.
+func vaultInfo(ctx context.Context, s *session.Session, env *ExecutionEnv) error {
+	vaultMeta, err := ui.WithSpinner(env.Stderr, "", false, func() (*api.VaultMeta, error) {
+		return s.Client.GetVaultMetadata(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check vault: %w", err)
+	}
+	if vaultMeta == nil {
+		return fmt.Errorf("no vault found - run 'vault init' to create one")
+	}
+
+	fmt.Fprintf(env.Stdout, "ID:         %d\n", vaultMeta.ID)
+	fmt.Fprintf(env.Stdout, "Created:    %s\n", formatVaultTime(vaultMeta.CreatedAt))
+	fmt.Fprintf(env.Stdout, "Updated:    %s\n", formatVaultTime(vaultMeta.UpdatedAt))
+	fmt.Fprintln(env.Stdout, "Encryption: AES-256-GCM, key derived from the password with PBKDF2")
+	if !s.IsVaultUnlocked() {
+		fmt.Fprintln(env.Stdout, "Contents:   unknown until unlocked (run 'vault')")
+		return nil
+	}
+	usage, err := vaultUsage(ctx, s, env)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	fmt.Fprintf(env.Stdout, "Contents:   %s\n", usage)
+	return nil
+}
+
+// vaultUsage walks the unlocked vault and describes its file and folder
+// counts and total size.
+func vaultUsage(ctx context.Context, s *session.Session, env *ExecutionEnv) (string, error) {
+	items, err := ui.WithSpinner(env.Stderr, "Counting...", false, func() ([]vaultItem, error) {
+		return walkVault(ctx, s)
+	})
+	if err != nil {
+		return "", err
+	}
+	var files, folders int
+	var size int64
+	for _, item := range items {
+		if item.Entry.Type == "folder" {
+			folders++
+			continue
+		}
+		files++
+		size += item.Entry.Size
+	}
+	return fmt.Sprintf("%d files, %d folders, %s", files, folders, ui.FormatSize(size)), nil
+}
+
+// formatVaultTime renders a timestamp from the vault API in local time, or
+// as sent when it doesn't parse.
+func formatVaultTime(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// vaultItem is a vault entry found while walking the vault tree.
+type vaultItem struct {
+	Path  string
+	Entry api.FileEntry
+}
+
+// loadVaultCache returns the session's vault cache, loading the vault folder
+// tree if it isn't cached yet. The vault must already be unlocked.
+func loadVaultCache(ctx context.Context, s *session.Session) (*api.FileCache, error) {
+	if !s.IsVaultUnlocked() {
+		return nil, fmt.Errorf("vault is locked")
+	}
+	if s.VaultCache != nil {
+		return s.VaultCache, nil
+	}
+
+	cache := api.NewFileCache()
+	if err := cache.LoadVaultFolderTree(ctx, s.Client, s.UserID, s.Username); err != nil {
+		return nil, fmt.Errorf("failed to load vault folders: %w", err)
+	}
+	s.VaultCache = cache
+	return cache, nil
+}
+
+// walkVault lists every folder of the vault and returns all entries below the
+// root. Listings are cached, so repeated walks only hit the API once.
+func walkVault(ctx context.Context, s *session.Session) ([]vaultItem, error) {
+	cache, err := loadVaultCache(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []vaultItem
+	queue := []string{"/"}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		if !cache.HasChildren(dir) {
+			folderHash := ""
+			if dir != "/" {
+				entry, ok := cache.Get(dir)
+				if !ok {
+					continue
+				}
+				folderHash = entry.Hash
+			}
+			children, err := s.Client.ListVaultEntries(ctx, folderHash)
+			if err != nil {
+				return nil, err
+			}
+			cache.AddChildren(dir, children)
+		}
+
+		for _, child := range cache.GetChildren(dir) {
+			childPath := path.Join(dir, child.Name)
+			items = append(items, vaultItem{Path: childPath, Entry: child})
+			if child.Type == "folder" {
+				queue = append(queue, childPath)
+			}
+		}
+	}
+	return items, nil
+}
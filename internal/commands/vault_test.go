@@ -3,12 +3,19 @@ package commands
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/crypto"
 	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/gYonder/drime-shell/internal/ui"
 )
 
 // TestVaultCommandInit tests the vault init command
@@ -75,6 +82,12 @@ func TestVaultCommandInit(t *testing.T) {
 	if sess.VaultKey == nil {
 		t.Error("expected VaultKey to be set after init")
 	}
+	if !strings.Contains(stdout.String(), "cannot be recovered") {
+		t.Error("expected a warning that the password can't be recovered")
+	}
+	if string(sess.VaultCheck) != capturedCheck || string(sess.VaultCheckIV) != capturedIV {
+		t.Error("expected the check value and IV to be kept in the session")
+	}
 }
 
 // TestVaultCommandEnterExit tests entering vault and exiting back to previous workspace
@@ -288,3 +301,152 @@ func TestVaultSessionSwitch(t *testing.T) {
 		t.Errorf("expected CWD '/Documents', got %q", sess.CWD)
 	}
 }
+
+// TestDownloadVaultDirectory_RetriesAndResumes checks that a failing vault
+// file is retried, that a file failing for good is reported without stopping
+// the others, and that a re-run only fetches what is missing.
+func TestDownloadVaultDirectory_RetriesAndResumes(t *testing.T) {
+	prevDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = prevDelay }()
+	ctx := ui.WithProgressSink(context.Background(), ui.NewJSONProgressSink(io.Discard))
+
+	salt, _ := crypto.GenerateSalt()
+	key := crypto.DeriveKey("pw", salt)
+	plain := map[string]string{"a.txt": "alpha", "b.txt": "bravo", "c.txt": "charlie"}
+	encrypted := map[string][]byte{}
+	var entries []api.FileEntry
+	for i, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		ct, iv, err := key.Encrypt([]byte(plain[name]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := "h-" + name
+		encrypted[hash] = ct
+		entries = append(entries, api.FileEntry{ID: int64(i + 1), Name: name, Type: "text", Hash: hash, Size: int64(len(ct)), IV: crypto.EncodeBase64(iv)})
+	}
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	brokenC := true
+	mockClient := &api.MockDrimeClient{
+		ListVaultEntriesFunc: func(ctx context.Context, folderHash string) ([]api.FileEntry, error) {
+			return entries, nil
+		},
+		DownloadEncryptedFunc: func(ctx context.Context, hash string, w io.Writer, progress func(int64, int64)) (*api.FileEntry, error) {
+			mu.Lock()
+			calls[hash]++
+			n := calls[hash]
+			broken := brokenC
+			mu.Unlock()
+			switch {
+			case hash == "h-b.txt" && n == 1:
+				// Connection drops halfway through the first attempt
+				w.Write(encrypted[hash][:3])
+				return nil, nil
+			case hash == "h-c.txt" && broken:
+				return nil, errors.New("connection reset")
+			}
+			_, err := w.Write(encrypted[hash])
+			return nil, err
+		},
+	}
+	sess := &session.Session{Client: mockClient, Cache: api.NewFileCache(), TransferJobs: 2}
+	sess.SetVaultKey(key)
+
+	localDir := t.TempDir()
+	env := &ExecutionEnv{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	folder := &api.FileEntry{ID: 10, Name: "secret", Type: "folder", Hash: "h-secret"}
+
+	err := downloadVaultDirectory(ctx, sess, env, folder, "/secret", localDir, clobberOverwrite)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 files failed") {
+		t.Fatalf("expected one failure, got %v", err)
+	}
+	if !strings.Contains(env.Stderr.(*bytes.Buffer).String(), "c.txt") {
+		t.Errorf("failed file not reported: %q", env.Stderr.(*bytes.Buffer).String())
+	}
+	if calls["h-b.txt"] != 2 {
+		t.Errorf("expected the short download of b.txt to be retried once, got %d calls", calls["h-b.txt"])
+	}
+	if calls["h-c.txt"] != vaultDownloadAttempts {
+		t.Errorf("expected %d attempts for c.txt, got %d", vaultDownloadAttempts, calls["h-c.txt"])
+	}
+	if _, err := os.Stat(filepath.Join(localDir, "secret", "c.txt")); !os.IsNotExist(err) {
+		t.Errorf("failed file should not exist, stat err = %v", err)
+	}
+
+	brokenC = false
+	calls = map[string]int{}
+	if err := downloadVaultDirectory(ctx, sess, env, folder, "/secret", localDir, clobberOverwrite); err != nil {
+		t.Fatalf("re-run failed: %v", err)
+	}
+	if calls["h-a.txt"] != 0 || calls["h-b.txt"] != 0 || calls["h-c.txt"] != 1 {
+		t.Errorf("re-run should only fetch c.txt, got %v", calls)
+	}
+	for name, want := range plain {
+		data, err := os.ReadFile(filepath.Join(localDir, "secret", name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+}
+
+func TestVaultStatusAndInfo(t *testing.T) {
+	mockClient := &api.MockDrimeClient{}
+	sess := session.NewSession(mockClient, api.NewFileCache())
+	stdout := &bytes.Buffer{}
+	env := &ExecutionEnv{Stdin: strings.NewReader(""), Stdout: stdout, Stderr: &bytes.Buffer{}}
+	cmd, _ := Get("vault")
+
+	// No vault yet
+	if err := cmd.Run(context.Background(), sess, env, []string{"status"}); err != nil {
+		t.Fatalf("vault status failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "none") {
+		t.Errorf("expected no vault, got %q", stdout.String())
+	}
+	if err := cmd.Run(context.Background(), sess, env, []string{"info"}); err == nil {
+		t.Error("expected vault info to fail without a vault")
+	}
+
+	mockClient.GetVaultMetadataFunc = func(ctx context.Context) (*api.VaultMeta, error) {
+		return &api.VaultMeta{ID: 7, CreatedAt: "2024-03-01T10:00:00.000000Z"}, nil
+	}
+	stdout.Reset()
+	if err := cmd.Run(context.Background(), sess, env, []string{"status"}); err != nil {
+		t.Fatalf("vault status failed: %v", err)
+	}
+	for _, want := range []string{"exists (ID 7)", "locked", "unknown until unlocked"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("expected %q in status, got %q", want, stdout.String())
+		}
+	}
+
+	// Unlocked, the contents are counted
+	key := crypto.DeriveKey("testpassword123", []byte("0123456789abcdef"))
+	sess.SetVaultKey(key)
+	mockClient.GetVaultFoldersFunc = func(ctx context.Context, userID int64) ([]api.FileEntry, error) {
+		return nil, nil
+	}
+	mockClient.ListVaultEntriesFunc = func(ctx context.Context, folderHash string) ([]api.FileEntry, error) {
+		switch folderHash {
+		case "":
+			return []api.FileEntry{
+				{ID: 1, Name: "tax.pdf", Type: "pdf", Size: 1024},
+				{ID: 2, Name: "keys", Type: "folder", Hash: "keys-hash"},
+			}, nil
+		case "keys-hash":
+			return []api.FileEntry{{ID: 3, Name: "id_ed25519", Type: "text", Size: 1024}}, nil
+		}
+		return nil, nil
+	}
+	stdout.Reset()
+	if err := cmd.Run(context.Background(), sess, env, []string{"info"}); err != nil {
+		t.Fatalf("vault info failed: %v", err)
+	}
+	for _, want := range []string{"ID:         7", "Created:    2024-03-01", "2 files, 1 folders, 2.0 KB"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("expected %q in info, got %q", want, stdout.String())
+		}
+	}
+}
@@ -2,12 +2,22 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"runtime"
+	"time"
 
+	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/build"
+	"github.com/gYonder/drime-shell/internal/config"
 	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/spf13/pflag"
 )
 
+// latestUpdate looks up a newer release; tests replace it to stay offline.
+var latestUpdate = build.LatestUpdate
+
 func init() {
 	Register(&Command{
 		Name:        "version",
@@ -15,6 +25,18 @@ func init() {
 		Usage:       "version",
 		Run:         versionCmd,
 	})
+	Register(&Command{
+		Name:        "info",
+		Description: "Show build, runtime and connection details",
+		Usage: `info [--json]
+
+Shows the build version, commit and date, the Go version and OS/arch, where
+the config file lives, the API URL, the signed-in user and active workspace,
+and whether a newer release is available.
+
+  --json  print the details as a JSON object`,
+		Run: infoCmd,
+	})
 }
 
 func versionCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
@@ -23,3 +45,91 @@ func versionCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args
 	fmt.Fprintf(env.Stdout, "Date:   %s\n", build.Date)
 	return nil
 }
+
+// shellInfo is what info reports. Update is the newer release's tag, empty
+// when up to date; UpdateError says why the check failed.
+type shellInfo struct {
+	Version     string `json:"version"`
+	Commit      string `json:"commit"`
+	Date        string `json:"date"`
+	GoVersion   string `json:"go_version"`
+	Platform    string `json:"platform"`
+	ConfigFile  string `json:"config_file"`
+	APIURL      string `json:"api_url,omitempty"`
+	User        string `json:"user,omitempty"`
+	Workspace   string `json:"workspace"`
+	Update      string `json:"update_available,omitempty"`
+	UpdateError string `json:"update_error,omitempty"`
+}
+
+func infoCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("info", pflag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the details as a JSON object")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: info [--json]")
+	}
+
+	info := shellInfo{
+		Version:   build.Version,
+		Commit:    build.Commit,
+		Date:      build.Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		User:      s.Username,
+		Workspace: s.ContextName(),
+	}
+	if info.Workspace == "" {
+		info.Workspace = "default"
+	}
+	if path, err := config.ConfigPath(); err == nil {
+		info.ConfigFile = path
+	}
+	if c, ok := s.Client.(*api.HTTPClient); ok {
+		info.APIURL = c.BaseURL
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	latest, err := latestUpdate(checkCtx)
+	cancel()
+	if err != nil {
+		info.UpdateError = err.Error()
+	} else {
+		info.Update = latest
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(env.Stdout, string(data))
+		return err
+	}
+
+	label := ui.MutedStyle.Render
+	fmt.Fprintf(env.Stdout, "%s %s\n", label("  Version:"), ui.HeaderStyle.Render(info.Version))
+	fmt.Fprintf(env.Stdout, "%s %s\n", label("   Commit:"), info.Commit)
+	fmt.Fprintf(env.Stdout, "%s %s\n", label("    Built:"), ui.DateStyle.Render(info.Date))
+	fmt.Fprintf(env.Stdout, "%s %s (%s)\n", label("       Go:"), info.GoVersion, info.Platform)
+	fmt.Fprintf(env.Stdout, "%s %s\n", label("   Config:"), info.ConfigFile)
+	if info.APIURL != "" {
+		fmt.Fprintf(env.Stdout, "%s %s\n", label("      API:"), ui.LinkStyle.Render(info.APIURL))
+	}
+	if info.User != "" {
+		fmt.Fprintf(env.Stdout, "%s %s\n", label("     User:"), ui.OwnerStyle.Render(info.User))
+	}
+	fmt.Fprintf(env.Stdout, "%s %s\n", label("Workspace:"), ui.WorkspaceStyle.Render(info.Workspace))
+	switch {
+	case info.UpdateError != "":
+		fmt.Fprintf(env.Stdout, "%s %s\n", label("   Update:"), ui.WarningStyle.Render("could not check ("+info.UpdateError+")"))
+	case info.Update != "":
+		fmt.Fprintf(env.Stdout, "%s %s (run %s)\n", label("   Update:"), ui.SuccessStyle.Render(info.Update+" available"), ui.CommandStyle.Render("update"))
+	default:
+		fmt.Fprintf(env.Stdout, "%s %s\n", label("   Update:"), "up to date")
+	}
+	return nil
+}
@@ -1,53 +1,144 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"slices"
+	"strings"
 
 	"github.com/gYonder/drime-shell/internal/session"
 	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/spf13/pflag"
 )
 
 func init() {
 	Register(&Command{
 		Name:        "cat",
 		Description: "Concatenate and print files to standard output",
-		Usage:       "cat <file>...\n\nDisplays the contents of remote files with syntax highlighting.\n\nExamples:\n  cat readme.txt\n  cat file1.txt file2.txt",
+		Usage:       "cat [--force] [--no-pager] <file>...\n\nDisplays the contents of remote files with syntax highlighting.\nBinary files are not printed to a terminal unless --force is given;\nwhen piped or redirected they are written unchanged. A file named '-'\nis read from standard input.\n\nOn a terminal, output taller than the screen is shown through $PAGER,\nor a built-in pager when it is unset (arrows/j/k, space/b, g/G, q to\nquit). Piped or redirected output is never paged.\n\nOptions:\n  -f, --force      Print binary files to the terminal anyway\n      --no-pager   Print straight to the terminal without paging\n\nExamples:\n  cat readme.txt\n  cat file1.txt file2.txt\n  cat --no-pager notes.md\n  cat image.png > image.png",
 		Run:         cat,
 	})
 }
 
+// binarySniffLen is how much of a file isBinaryContent inspects.
+const binarySniffLen = 8192
+
+// isBinaryContent reports whether content looks like binary data rather
+// than text: it contains a NUL byte, or its detected MIME type does not
+// descend from text/plain.
+func isBinaryContent(content []byte) bool {
+	if len(content) == 0 {
+		return false
+	}
+	sample := content
+	if len(sample) > binarySniffLen {
+		sample = sample[:binarySniffLen]
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	for mtype := mimetype.Detect(sample); mtype != nil; mtype = mtype.Parent() {
+		if mtype.Is("text/plain") {
+			return false
+		}
+	}
+	return true
+}
+
 func cat(ctx context.Context, s *session.Session, env *ExecutionEnv, args []string) error {
+	fs := pflag.NewFlagSet("cat", pflag.ContinueOnError)
+	force := fs.BoolP("force", "f", false, "print binary files to the terminal")
+	noPager := fs.Bool("no-pager", false, "never page output")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(ReorderArgsForFlags(fs, args)); err != nil {
+		return err
+	}
+	args = fs.Args()
+
 	if len(args) < 1 {
 		return fmt.Errorf("usage: cat <file>")
 	}
 
+	// Page only what would land on the terminal anyway. Reading '-' means
+	// stdin is already spoken for, so the pager could not take keystrokes.
+	paging := !*noPager && ui.IsTerminal(env.Stdout) && !slices.Contains(args, "-")
+	var paged bytes.Buffer
+	out := env.Stdout
+	if paging {
+		out = &paged
+	}
+
 	for _, path := range args {
-		entry, err := ResolveEntry(ctx, s, path)
-		if err != nil {
-			return fmt.Errorf("cat: %w", err)
-		}
+		name := path
+		var content []byte
+		if path == "-" {
+			if env.Stdin == nil {
+				return fmt.Errorf("cat: -: no standard input")
+			}
+			data, err := io.ReadAll(env.Stdin)
+			if err != nil {
+				return fmt.Errorf("cat: -: %w", err)
+			}
+			content = data
+		} else {
+			entry, err := ResolveEntry(ctx, s, path)
+			if err != nil {
+				return fmt.Errorf("cat: %w", err)
+			}
 
-		if entry.Type == "folder" {
-			fmt.Fprintf(env.Stderr, "cat: %s: Is a directory\n", path)
-			continue
+			if entry.Type == "folder" {
+				fmt.Fprintf(env.Stderr, "cat: %s: Is a directory\n", path)
+				continue
+			}
+
+			// Download content (with vault decryption if needed)
+			content, err = ui.WithSpinner(env.Stderr, "", false, func() ([]byte, error) {
+				return DownloadAndDecrypt(ctx, s, entry)
+			})
+			if err != nil {
+				return fmt.Errorf("cat: %s: %w", path, err)
+			}
+			name = entry.Name
 		}
 
-		// Download content (with vault decryption if needed)
-		content, err := ui.WithSpinner(env.Stderr, "", false, func() ([]byte, error) {
-			return DownloadAndDecrypt(ctx, s, entry)
-		})
-		if err != nil {
-			return fmt.Errorf("cat: %s: %w", path, err)
+		// Binary data is written untouched; on a terminal only when forced,
+		// since control bytes can leave it in a scrambled state
+		if isBinaryContent(content) {
+			if ui.IsTerminal(env.Stdout) && !*force {
+				fmt.Fprintf(env.Stderr, "cat: %s: binary file not shown (use --force to print it, or download it)\n", path)
+				continue
+			}
+			// A pager would mangle it just the same; flush and stop paging
+			if paging {
+				if _, err := env.Stdout.Write(paged.Bytes()); err != nil {
+					return err
+				}
+				paged.Reset()
+				paging = false
+				out = env.Stdout
+			}
+			if _, err := out.Write(content); err != nil {
+				return err
+			}
+			continue
 		}
 
 		// Apply syntax highlighting and output
-		highlighted := ui.Highlight(string(content), entry.Name)
-		fmt.Fprint(env.Stdout, highlighted)
+		highlighted := ui.Highlight(string(content), name)
+		fmt.Fprint(out, highlighted)
 
 		// Ensure trailing newline
 		if len(highlighted) > 0 && highlighted[len(highlighted)-1] != '\n' {
-			fmt.Fprintln(env.Stdout)
+			fmt.Fprintln(out)
+		}
+	}
+
+	if paging && paged.Len() > 0 {
+		if err := ui.Page(env.Stdout, strings.Join(args, " "), paged.String()); err != nil {
+			return fmt.Errorf("cat: %w", err)
 		}
 	}
 	return nil
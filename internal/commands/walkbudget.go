@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// walkBudgetFlags holds the --deadline/--max-entries flags shared by the
+// recursive commands (du, find -r, tree).
+type walkBudgetFlags struct {
+	deadline   *time.Duration
+	maxEntries *int
+}
+
+func addWalkBudgetFlags(fs *pflag.FlagSet) *walkBudgetFlags {
+	return &walkBudgetFlags{
+		deadline:   fs.Duration("deadline", 0, "stop walking after this long (e.g. 30s)"),
+		maxEntries: fs.Int("max-entries", 0, "stop walking after this many entries"),
+	}
+}
+
+// walkBudget bounds a recursive walk. Zero fields are unbounded.
+type walkBudget struct {
+	deadline   time.Duration
+	maxEntries int
+}
+
+func (f *walkBudgetFlags) parse() (walkBudget, error) {
+	if *f.deadline < 0 {
+		return walkBudget{}, fmt.Errorf("invalid --deadline %s", *f.deadline)
+	}
+	if *f.maxEntries < 0 {
+		return walkBudget{}, fmt.Errorf("invalid --max-entries %d", *f.maxEntries)
+	}
+	return walkBudget{deadline: *f.deadline, maxEntries: *f.maxEntries}, nil
+}
+
+// budgetError reports that a walk stopped early because its budget ran out.
+// What was visited until then is still valid.
+type budgetError struct {
+	reason string
+}
+
+func (e *budgetError) Error() string {
+	return "partial results (" + e.reason + ")"
+}
+
+// walkLimit tracks one walk against its budget.
+type walkLimit struct {
+	ctx     context.Context // ctx with the deadline applied
+	parent  context.Context
+	budget  walkBudget
+	visited int
+}
+
+// begin starts tracking a walk. The returned context must be used for the
+// walk's API calls so the deadline interrupts them.
+func (b walkBudget) begin(ctx context.Context) (*walkLimit, context.CancelFunc) {
+	limit := &walkLimit{ctx: ctx, parent: ctx, budget: b}
+	if b.deadline <= 0 {
+		return limit, func() {}
+	}
+	var cancel context.CancelFunc
+	limit.ctx, cancel = context.WithTimeout(ctx, b.deadline)
+	return limit, cancel
+}
+
+// next counts one visited entry. It fails once the walk is over budget.
+func (l *walkLimit) next() error {
+	if err := l.check(l.ctx.Err()); err != nil {
+		return err
+	}
+	if l.budget.maxEntries > 0 && l.visited >= l.budget.maxEntries {
+		return &budgetError{reason: fmt.Sprintf("limit of %d entries reached", l.budget.maxEntries)}
+	}
+	l.visited++
+	return nil
+}
+
+// check turns err into a budgetError when it comes from the walk's own
+// deadline rather than from the caller giving up (Ctrl+C).
+func (l *walkLimit) check(err error) error {
+	if err == nil || l.parent.Err() != nil {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) && l.ctx.Err() != nil {
+		return &budgetError{reason: fmt.Sprintf("deadline of %s reached", l.budget.deadline)}
+	}
+	return err
+}
+
+// partialWalk prints the notice for a walk cut short by its budget and
+// reports whether err was one; other errors are left to the caller.
+func partialWalk(env *ExecutionEnv, name string, err error) bool {
+	var be *budgetError
+	if !errors.As(err, &be) {
+		return false
+	}
+	fmt.Fprintf(env.Stderr, "%s: %v\n", name, be)
+	return true
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/ui"
 )
 
 // UploadConfig holds configuration for directory uploads
@@ -22,6 +24,28 @@ type UploadConfig struct {
 	RetryDelay    time.Duration // Base delay between retries (default: 2s)
 	APIDelay      time.Duration // Delay between API calls to avoid rate limiting (default: 100ms)
 	Timeout       time.Duration // Timeout per upload attempt (default: 40s)
+
+	// WorkersPerFile lets files above BigFileThreshold upload up to this many
+	// parts at once, drawn from Concurrency so the total stays bounded
+	// (default: 0, off)
+	WorkersPerFile   int
+	BigFileThreshold int64 // Size above which a file counts as big (default: multipart threshold)
+}
+
+// MaxConcurrency caps the number of parallel transfer workers. Beyond this the
+// API starts rate limiting and extra workers only add retries.
+const MaxConcurrency = 32
+
+// ClampConcurrency bounds a requested worker count to [1, MaxConcurrency],
+// mapping non-positive values to the default of 6.
+func ClampConcurrency(n int) int {
+	if n <= 0 {
+		return DefaultUploadConfig().Concurrency
+	}
+	if n > MaxConcurrency {
+		return MaxConcurrency
+	}
+	return n
 }
 
 // DefaultUploadConfig returns sensible defaults
@@ -38,6 +62,7 @@ func DefaultUploadConfig() UploadConfig {
 // UploadStats tracks upload statistics
 type UploadStats struct {
 	Errors   []UploadError
+	Sent     []string // RelativePath of each file uploaded
 	Uploaded int64
 	Skipped  int64
 	Failed   int64
@@ -50,8 +75,11 @@ type UploadError struct {
 	Error string
 }
 
-func (s *UploadStats) AddUploaded() {
+func (s *UploadStats) AddUploaded(path string) {
 	atomic.AddInt64(&s.Uploaded, 1)
+	s.mu.Lock()
+	s.Sent = append(s.Sent, path)
+	s.mu.Unlock()
 }
 
 func (s *UploadStats) AddSkipped() {
@@ -71,6 +99,17 @@ type FileUploadTask struct {
 	RelativePath string // Path relative to upload root
 	ParentID     int64  // Remote parent folder ID
 	Size         int64  // File size
+	Name         string // Remote name when it differs from the local one
+	Replaces     int64  // Remote file moved to the trash once this one is uploaded (0 = none)
+	Compress     bool   // Gzip before sending; Name carries the .gz suffix
+}
+
+// remoteName is the name the file is uploaded under.
+func (t FileUploadTask) remoteName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return filepath.Base(t.LocalPath)
 }
 
 // UploadProgress tracks overall progress
@@ -85,10 +124,11 @@ func (p *UploadProgress) Increment() int64 {
 }
 
 func (p *UploadProgress) Percent() int {
-	if p.Total == 0 {
+	total := atomic.LoadInt64(&p.Total)
+	if total == 0 {
 		return 100
 	}
-	return int(float64(p.Completed) / float64(p.Total) * 100)
+	return int(float64(atomic.LoadInt64(&p.Completed)) / float64(total) * 100)
 }
 
 func (p *UploadProgress) ETA() string {
@@ -98,7 +138,7 @@ func (p *UploadProgress) ETA() string {
 	}
 	elapsed := time.Since(p.StartTime)
 	itemsPerSecond := float64(completed) / elapsed.Seconds()
-	remaining := p.Total - completed
+	remaining := atomic.LoadInt64(&p.Total) - completed
 	if itemsPerSecond <= 0 {
 		return "calculating..."
 	}
@@ -124,6 +164,10 @@ type WorkerPool struct {
 	workspaceID int64  // Workspace ID for uploads
 	wg          sync.WaitGroup
 	config      UploadConfig
+
+	slots  chan struct{} // One per upload stream in flight, Concurrency in all
+	bigMu  sync.Mutex    // Held while a big file gathers its slots
+	panics ui.PanicTrap  // First panic of a worker, raised again by Close
 }
 
 // NewWorkerPool creates a new upload worker pool
@@ -145,9 +189,16 @@ func NewWorkerPool(
 	if config.RetryDelay <= 0 {
 		config.RetryDelay = 2 * time.Second
 	}
+	// The command's --retries/--retry-delay win
+	policy := api.RetryPolicyFrom(ctx)
+	config.RetryAttempts = policy.RetriesOr(config.RetryAttempts-1) + 1
+	config.RetryDelay = policy.DelayOr(config.RetryDelay)
 	if config.Timeout <= 0 {
 		config.Timeout = 40 * time.Second
 	}
+	if config.BigFileThreshold <= 0 {
+		config.BigFileThreshold = api.MultipartThresh
+	}
 
 	return &WorkerPool{
 		ctx:         ctx,
@@ -160,6 +211,7 @@ func NewWorkerPool(
 		basePath:    basePath,
 		session:     session,
 		workspaceID: workspaceID,
+		slots:       make(chan struct{}, config.Concurrency),
 	}
 }
 
@@ -186,10 +238,12 @@ func (wp *WorkerPool) Submit(task FileUploadTask) {
 	wp.tasks <- task
 }
 
-// Close signals no more tasks and waits for completion
+// Close signals no more tasks and waits for completion. If a worker
+// panicked, Close panics with it on the caller's goroutine.
 func (wp *WorkerPool) Close() *UploadStats {
 	close(wp.tasks)
 	wp.wg.Wait()
+	wp.panics.Rethrow()
 	return wp.stats
 }
 
@@ -203,55 +257,107 @@ func (wp *WorkerPool) worker(_ int) {
 			return
 		default:
 		}
+		// After a panic the remaining tasks are only drained, so Submit
+		// never blocks
+		if !wp.panics.Caught() {
+			wp.process(task)
+		}
+	}
+}
 
-		err := wp.uploadWithRetry(task)
+// process uploads task and records the outcome. A panic is kept for Close.
+func (wp *WorkerPool) process(task FileUploadTask) {
+	defer wp.panics.Catch(nil)
+	streams := wp.acquire(task)
+	err := func() error {
+		defer wp.release(streams) // even if the upload panics
+		return wp.uploadWithRetry(task, streams)
+	}()
+
+	completed := wp.progress.Increment()
+	if wp.onProgress != nil {
+		wp.onProgress(completed, atomic.LoadInt64(&wp.progress.Total), wp.progress.Percent(), wp.progress.ETA())
+	}
 
-		completed := wp.progress.Increment()
-		if wp.onProgress != nil {
-			wp.onProgress(completed, wp.progress.Total, wp.progress.Percent(), wp.progress.ETA())
+	if err != nil {
+		wp.stats.AddFailed(task.RelativePath, err.Error())
+		if wp.onFile != nil {
+			wp.onFile(task.RelativePath, false, err.Error())
 		}
-
-		if err != nil {
-			wp.stats.AddFailed(task.RelativePath, err.Error())
-			if wp.onFile != nil {
-				wp.onFile(task.RelativePath, false, err.Error())
-			}
-			// Update session state
-			if wp.session != nil {
-				wp.session.MarkFileFailed(task.RelativePath, err.Error())
-				_ = wp.session.Save() // Best effort save
-			}
-		} else {
-			wp.stats.AddUploaded()
-			if wp.onFile != nil {
-				wp.onFile(task.RelativePath, true, "")
-			}
-			// Update session state
-			if wp.session != nil {
-				wp.session.MarkFileCompleted(task.RelativePath, task.Size)
-				_ = wp.session.Save() // Best effort save
-			}
+		// Update session state
+		if wp.session != nil {
+			wp.session.MarkFileFailed(task.RelativePath, err.Error())
+			_ = wp.session.Save() // Best effort save
 		}
-
-		// API delay to avoid rate limiting
-		if wp.config.APIDelay > 0 {
-			time.Sleep(wp.config.APIDelay)
+	} else {
+		wp.stats.AddUploaded(task.RelativePath)
+		if wp.onFile != nil {
+			wp.onFile(task.RelativePath, true, "")
+		}
+		// Update session state
+		if wp.session != nil {
+			wp.session.MarkFileCompleted(task.RelativePath, task.Size)
+			_ = wp.session.Save() // Best effort save
 		}
 	}
+
+	// API delay to avoid rate limiting
+	if wp.config.APIDelay > 0 {
+		time.Sleep(wp.config.APIDelay)
+	}
+}
+
+// streamsFor returns how many upload streams task may use: up to
+// WorkersPerFile for big files, one for the rest.
+func (wp *WorkerPool) streamsFor(task FileUploadTask) int {
+	n := wp.config.WorkersPerFile
+	if n <= 1 || task.Size <= wp.config.BigFileThreshold {
+		return 1
+	}
+	if n > wp.config.Concurrency {
+		n = wp.config.Concurrency
+	}
+	return n
+}
+
+// acquire reserves the streams task may use, waiting for other uploads to
+// free them. Big files gather theirs one at a time under bigMu, so two big
+// files can never each hold part of what the other is waiting for.
+func (wp *WorkerPool) acquire(task FileUploadTask) int {
+	n := wp.streamsFor(task)
+	if n == 1 {
+		wp.slots <- struct{}{}
+		return 1
+	}
+	wp.bigMu.Lock()
+	defer wp.bigMu.Unlock()
+	for i := 0; i < n; i++ {
+		wp.slots <- struct{}{}
+	}
+	return n
+}
+
+func (wp *WorkerPool) release(n int) {
+	for i := 0; i < n; i++ {
+		<-wp.slots
+	}
 }
 
 // uploadWithRetry attempts to upload a file with retries
-func (wp *WorkerPool) uploadWithRetry(task FileUploadTask) error {
+func (wp *WorkerPool) uploadWithRetry(task FileUploadTask, streams int) error {
 	var lastErr error
 
+	t := ui.StartTransfer(ui.TransferUpload, task.RelativePath, task.Size)
+	defer t.Finish()
+
 	for attempt := 1; attempt <= wp.config.RetryAttempts; attempt++ {
 		// Create timeout context for this attempt
 		attemptCtx, cancel := context.WithTimeout(wp.ctx, wp.config.Timeout)
-		err := wp.uploadFile(attemptCtx, task)
+		entry, err := wp.uploadFile(attemptCtx, task, streams, t)
 		cancel()
 
 		if err == nil {
-			return nil
+			return wp.trashReplaced(task, entry)
 		}
 
 		lastErr = err
@@ -284,46 +390,108 @@ func (wp *WorkerPool) uploadWithRetry(task FileUploadTask) error {
 	return fmt.Errorf("failed after %d attempts: %w", wp.config.RetryAttempts, lastErr)
 }
 
-// uploadFile performs the actual upload
-func (wp *WorkerPool) uploadFile(ctx context.Context, task FileUploadTask) error {
+// trashReplaced moves the remote file task replaces to the trash, now that
+// entry, its new copy, is uploaded. It is not retried with the upload, which
+// would leave a second copy behind.
+func (wp *WorkerPool) trashReplaced(task FileUploadTask, entry *api.FileEntry) error {
+	if task.Replaces == 0 || (entry != nil && entry.ID == task.Replaces) {
+		return nil
+	}
+	if err := wp.client.DeleteEntries(wp.ctx, []int64{task.Replaces}, wp.workspaceID); err != nil {
+		return fmt.Errorf("uploaded, but could not move the old copy to the trash: %w", err)
+	}
+	return nil
+}
+
+// uploadFile performs the actual upload, sending up to streams parts of a
+// multipart upload at once when WorkersPerFile is set. Progress goes to t;
+// multipart uploads need the *os.File itself, so theirs is only known once
+// they are done.
+func (wp *WorkerPool) uploadFile(ctx context.Context, task FileUploadTask, streams int, t *ui.Transfer) (*api.FileEntry, error) {
 	f, err := os.Open(task.LocalPath)
 	if err != nil {
-		return fmt.Errorf("open file: %w", err)
+		return nil, fmt.Errorf("open file: %w", err)
 	}
 	defer f.Close()
 
+	src, size := f, task.Size
+	if task.Compress {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		gz, err := gzipToTemp(f, filepath.Base(task.LocalPath), info.ModTime())
+		if err != nil {
+			return nil, fmt.Errorf("compress: %w", err)
+		}
+		defer os.Remove(gz.Name())
+		defer gz.Close()
+		if info, err = gz.Stat(); err != nil {
+			return nil, err
+		}
+		src, size = gz, info.Size()
+	}
+
+	var reader io.Reader = src
+	if size <= api.MultipartThresh {
+		reader = &progressReader{
+			Reader:   src,
+			Callback: func(curr int64) { t.Update(curr, size) },
+		}
+	}
+
 	parentID := &task.ParentID
 
-	entry, err := wp.client.Upload(ctx, f, filepath.Base(task.LocalPath), parentID, task.Size, wp.workspaceID)
+	var opts *api.UploadOptions
+	if wp.config.WorkersPerFile > 0 {
+		opts = &api.UploadOptions{PartConcurrency: streams}
+	}
+	name := task.remoteName()
+	entry, err := wp.client.UploadWithOptions(ctx, reader, name, parentID, size, wp.workspaceID, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	t.Update(size, size)
 
 	// Update cache
 	if entry != nil && wp.cache != nil {
-		remotePath := filepath.Join(wp.basePath, task.RelativePath)
+		remotePath := filepath.Join(wp.basePath, filepath.Dir(task.RelativePath), name)
 		wp.cache.Add(entry, remotePath)
 	}
 
-	return nil
+	return entry, nil
 }
 
 // ProgressPrinter provides simple console progress output
 type ProgressPrinter struct {
-	lastLine string
-	mu       sync.Mutex
+	lastLine   string
+	workers    int
+	onlyErrors bool            // print failed files only, with no progress line
+	sink       ui.ProgressSink // reports progress in place of the printed lines (nil = print)
+	mu         sync.Mutex
 }
 
-func NewProgressPrinter() *ProgressPrinter {
-	return &ProgressPrinter{}
+// NewProgressPrinter returns a printer that reports workers as the number of
+// parallel uploads in flight, or reports to the progress sink of ctx when it
+// has one.
+func NewProgressPrinter(ctx context.Context, workers int) *ProgressPrinter {
+	return &ProgressPrinter{workers: workers, sink: ui.ProgressSinkFrom(ctx)}
 }
 
 func (pp *ProgressPrinter) OnProgress(completed, total int64, percent int, eta string) {
 	pp.mu.Lock()
 	defer pp.mu.Unlock()
 
+	if pp.onlyErrors {
+		return
+	}
+	if pp.sink != nil {
+		ui.ReportBatchProgress(pp.sink, "", completed, total)
+		return
+	}
+
 	// Clear previous line and print progress
-	line := fmt.Sprintf("\r  Progress: %d/%d (%d%%) - ETA: %s", completed, total, percent, eta)
+	line := fmt.Sprintf("\r  Progress: %d/%d (%d%%) - %d workers - ETA: %s", completed, total, percent, pp.workers, eta)
 	// Pad with spaces to clear any previous longer text
 	if len(line) < len(pp.lastLine) {
 		line += strings.Repeat(" ", len(pp.lastLine)-len(line))
@@ -336,6 +504,11 @@ func (pp *ProgressPrinter) OnFile(relativePath string, success bool, errMsg stri
 	pp.mu.Lock()
 	defer pp.mu.Unlock()
 
+	if pp.sink != nil && !pp.onlyErrors {
+		ui.ReportFileDone(pp.sink, relativePath, errMsg)
+		return
+	}
+
 	// Print file result on new line
 	if !success {
 		fmt.Printf("\r  ✗ %s: %s\n", relativePath, errMsg)
@@ -344,5 +517,8 @@ func (pp *ProgressPrinter) OnFile(relativePath string, success bool, errMsg stri
 }
 
 func (pp *ProgressPrinter) Finish() {
+	if pp.onlyErrors || pp.sink != nil {
+		return
+	}
 	fmt.Println() // New line after progress
 }
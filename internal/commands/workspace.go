@@ -3,6 +3,8 @@ package commands
 import (
 	"bufio"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,11 +12,13 @@ import (
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/session"
 	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/spf13/pflag"
 )
 
 func init() {
 	Register(&Command{
 		Name:        "ws",
+		Mutates:     subcommandMutates("new", "create", "rename", "rm", "delete", "invite", "kick", "remove", "role", "transfer", "leave"),
 		Description: "List or manage workspaces",
 		Usage: `ws [command] [args]
 
@@ -26,6 +30,12 @@ Switch to a workspace:
   ws <id>              Switch to workspace by ID
   ws 0                 Switch to default workspace
   ws default           Switch to default workspace
+  ws id:<id>           Switch by ID even if a workspace is named like a number
+  ws name:<name>       Switch by name only
+  ws <name> --refresh  Switch and reload the folder tree from the server (-r)
+  ws refresh           Reload the current workspace's folder tree
+
+Recently visited workspaces keep their cache, so switching back is instant.
 
 Create/manage workspaces:
   ws new <name>        Create a new workspace
@@ -35,9 +45,11 @@ Create/manage workspaces:
 Member Management:
   ws members           List members and pending invites
   ws roles             List available roles
+                       (both take --json or --csv for scripts and spreadsheets)
   ws invite <email> [role] Invite a user (default role: Member)
   ws kick <email>      Remove a member or cancel an invite
   ws role <email> <role> Change a member's role
+  ws transfer <email>  Make a member the owner; you become an Admin
   ws leave             Leave the current workspace`,
 		Run: wsCmd,
 	})
@@ -63,7 +75,7 @@ func wsCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []st
 	case "rm", "delete":
 		targetID := s.WorkspaceID
 		if len(args) > 1 {
-			wsID, _, err := ResolveWorkspace(ctx, s, args[1])
+			wsID, _, err := ResolveWorkspace(ctx, s, env, args[1])
 			if err != nil {
 				return fmt.Errorf("ws rm: %w", err)
 			}
@@ -71,9 +83,17 @@ func wsCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []st
 		}
 		return deleteWorkspace(ctx, s, env, targetID)
 	case "members":
-		return listWorkspaceMembers(ctx, s, env)
+		format, err := parseListFormat("ws members", env, args[1:])
+		if err != nil {
+			return err
+		}
+		return listWorkspaceMembers(ctx, s, env, format)
 	case "roles":
-		return listWorkspaceRoles(ctx, s, env)
+		format, err := parseListFormat("ws roles", env, args[1:])
+		if err != nil {
+			return err
+		}
+		return listWorkspaceRoles(ctx, s, env, format)
 	case "invite":
 		if len(args) < 2 {
 			return fmt.Errorf("usage: ws invite <email> [role]")
@@ -93,64 +113,118 @@ func wsCmd(ctx context.Context, s *session.Session, env *ExecutionEnv, args []st
 			return fmt.Errorf("usage: ws role <email> <role>")
 		}
 		return changeMemberRole(ctx, s, env, args[1], args[2])
+	case "transfer":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: ws transfer <email>")
+		}
+		return transferOwnership(ctx, s, env, args[1])
 	case "leave":
 		return leaveWorkspace(ctx, s, env)
+	case "refresh":
+		if s.InVault {
+			return fmt.Errorf("ws refresh: not available in vault")
+		}
+		return reloadWorkspace(ctx, s, env, s.WorkspaceID, s.WorkspaceName)
 	default:
-		return switchWorkspace(ctx, s, env, args[0])
+		fs := pflag.NewFlagSet("ws", pflag.ContinueOnError)
+		refresh := fs.BoolP("refresh", "r", false, "reload the folder tree from the server")
+		fs.SetOutput(env.Stderr)
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: ws <name|id> [--refresh]")
+		}
+		return switchWorkspaceWithOptions(ctx, s, env, fs.Arg(0), *refresh)
 	}
 }
 
 // ResolveWorkspace resolves a workspace identifier (name or ID) to workspace ID and name.
-// Accepts: numeric ID, workspace name (case-insensitive), "default" or "0" for personal workspace.
-// Returns: (workspaceID, workspaceName, error)
-func ResolveWorkspace(ctx context.Context, s *session.Session, target string) (int64, string, error) {
+// Handles "default" and "0" as the default workspace (ID 0).
+// The prefixes "id:" and "name:" force how target is interpreted. A bare
+// number that is also the name of a workspace selects that workspace by name,
+// with a warning on env.Stderr.
+// Refreshes the workspace list from the server if target is not found in the cache.
+func ResolveWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv, target string) (int64, string, error) {
+	if idStr, ok := strings.CutPrefix(target, "id:"); ok {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid workspace ID '%s'", idStr)
+		}
+		return resolveWorkspaceByID(ctx, s, id)
+	}
+	if name, ok := strings.CutPrefix(target, "name:"); ok {
+		ws, found := findWorkspace(ctx, s, func(ws api.Workspace) bool {
+			return strings.EqualFold(ws.Name, name)
+		})
+		if !found {
+			return 0, "", fmt.Errorf("workspace '%s' not found", name)
+		}
+		return ws.ID, ws.Name, nil
+	}
+
 	// Handle default workspace
 	if strings.EqualFold(target, "default") || target == "0" {
 		return 0, "default", nil
 	}
 
-	// Try to parse as numeric ID first
+	byName := func(ws api.Workspace) bool {
+		return strings.EqualFold(ws.Name, target)
+	}
+
 	if id, err := strconv.ParseInt(target, 10, 64); err == nil {
-		// Find name in cache
-		for _, ws := range s.Workspaces {
-			if ws.ID == id {
-				return id, ws.Name, nil
-			}
-		}
-		// Not in cache, try to refresh
-		workspaces, err := s.Client.GetWorkspaces(ctx)
-		if err == nil {
-			s.Workspaces = workspaces
-			for _, ws := range workspaces {
-				if ws.ID == id {
-					return id, ws.Name, nil
-				}
+		// A workspace may literally be named "2024"; the name wins over the ID
+		if ws, found := findWorkspace(ctx, s, byName); found {
+			if ws.ID != id {
+				fmt.Fprintf(env.Stderr, "warning: '%s' is a workspace name, using workspace '%s' (ID %d); use id:%d to select by ID\n", target, ws.Name, ws.ID, id)
 			}
+			return ws.ID, ws.Name, nil
 		}
-		// Still not found - use ID anyway (API will validate)
+		return resolveWorkspaceByID(ctx, s, id)
+	}
+
+	if ws, found := findWorkspace(ctx, s, byName); found {
+		return ws.ID, ws.Name, nil
+	}
+	return 0, "", fmt.Errorf("workspace '%s' not found", target)
+}
+
+// resolveWorkspaceByID looks up the name of workspace id. Unknown IDs are
+// returned without a name and left for the API to validate.
+func resolveWorkspaceByID(ctx context.Context, s *session.Session, id int64) (int64, string, error) {
+	if id == 0 {
+		return 0, "default", nil
+	}
+	ws, found := findWorkspace(ctx, s, func(ws api.Workspace) bool {
+		return ws.ID == id
+	})
+	if !found {
 		return id, "", nil
 	}
+	return id, ws.Name, nil
+}
 
-	// Try to find by name (case-insensitive)
-	targetLower := strings.ToLower(target)
+// findWorkspace returns the first workspace matching match, checking the
+// cached list first and refreshing it from the server on a miss.
+func findWorkspace(ctx context.Context, s *session.Session, match func(api.Workspace) bool) (api.Workspace, bool) {
 	for _, ws := range s.Workspaces {
-		if strings.ToLower(ws.Name) == targetLower {
-			return ws.ID, ws.Name, nil
+		if match(ws) {
+			return ws, true
 		}
 	}
 
 	// Not in cache, try to refresh
 	workspaces, err := s.Client.GetWorkspaces(ctx)
-	if err == nil {
-		s.Workspaces = workspaces
-		for _, ws := range workspaces {
-			if strings.ToLower(ws.Name) == targetLower {
-				return ws.ID, ws.Name, nil
-			}
+	if err != nil {
+		return api.Workspace{}, false
+	}
+	s.Workspaces = workspaces
+	for _, ws := range workspaces {
+		if match(ws) {
+			return ws, true
 		}
 	}
-
-	return 0, "", fmt.Errorf("workspace '%s' not found", target)
+	return api.Workspace{}, false
 }
 
 func listWorkspaces(ctx context.Context, s *session.Session, env *ExecutionEnv) error {
@@ -193,16 +267,23 @@ func listWorkspaces(ctx context.Context, s *session.Session, env *ExecutionEnv)
 }
 
 func switchWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv, target string) error {
+	return switchWorkspaceWithOptions(ctx, s, env, target, false)
+}
+
+func switchWorkspaceWithOptions(ctx context.Context, s *session.Session, env *ExecutionEnv, target string, refresh bool) error {
 	wasInVault := s.InVault
 
 	// Resolve workspace by name or ID
-	targetWsID, targetWsName, err := ResolveWorkspace(ctx, s, target)
+	targetWsID, targetWsName, err := ResolveWorkspace(ctx, s, env, target)
 	if err != nil {
 		return err
 	}
 
 	// Skip if already on this workspace (and not switching from vault)
 	if targetWsID == s.WorkspaceID && !wasInVault {
+		if refresh {
+			return reloadWorkspace(ctx, s, env, targetWsID, targetWsName)
+		}
 		if targetWsID == 0 {
 			fmt.Fprintln(env.Stdout, "Already on default workspace")
 		} else {
@@ -211,32 +292,51 @@ func switchWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv,
 		return nil
 	}
 
-	// Switch workspace: clear cache and reload folder tree
-	err = ui.WithSpinnerErr(env.Stderr, "", false, func() error {
-		newCache := api.NewFileCache()
-		if err := newCache.LoadFolderTree(ctx, s.Client, s.UserID, s.Username, targetWsID); err != nil {
-			return fmt.Errorf("failed to load folder tree: %w", err)
-		}
+	// Keep the outgoing workspace's cache around for a quick switch back.
+	// From the vault, the workspace cache lives in the saved state.
+	if wasInVault {
+		s.RetainWorkspaceCache(s.SavedWorkspaceID, s.SavedCache)
+	} else {
+		s.RetainWorkspaceCache(s.WorkspaceID, s.Cache)
+	}
 
-		// Prefetch root directory
-		entries, err := s.Client.ListByParentIDWithOptions(ctx, nil, api.ListOptions(targetWsID))
-		if err == nil {
-			newCache.AddChildren("/", entries)
-		}
+	if refresh {
+		s.ForgetWorkspaceCache(targetWsID)
+	}
 
-		// Swap in new cache + state only after successful load
-		s.Cache = newCache
+	if cache, ok := s.RetainedWorkspaceCache(targetWsID); ok {
+		// Mark as most recently used
+		s.RetainWorkspaceCache(targetWsID, cache)
+		s.Cache = cache
 		s.WorkspaceID = targetWsID
 		s.WorkspaceName = targetWsName
 		s.CWD = "/"
 		s.PreviousDir = ""
-		s.InVault = false // Ensure we're out of vault mode
+		s.InVault = false
+	} else {
+		// Switch workspace: load a fresh folder tree
+		err = ui.WithSpinnerErr(env.Stderr, "", false, func() error {
+			newCache, err := loadWorkspaceCache(ctx, s, targetWsID)
+			if err != nil {
+				return err
+			}
 
-		return nil
-	})
-	if err != nil {
-		return err
+			// Swap in new cache + state only after successful load
+			s.Cache = newCache
+			s.WorkspaceID = targetWsID
+			s.WorkspaceName = targetWsName
+			s.CWD = "/"
+			s.PreviousDir = ""
+			s.InVault = false // Ensure we're out of vault mode
+			s.RetainWorkspaceCache(targetWsID, newCache)
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
 	}
+	s.ApplyWorkspaceProfile()
 
 	// Display switch message with stats
 	if targetWsID == 0 {
@@ -252,10 +352,55 @@ func switchWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv,
 				ui.MutedStyle.Render(fmt.Sprintf("  %d files, %s", stats.Files, formatSize(stats.Size))))
 		}
 	}
+	if !s.EnterHome() {
+		fmt.Fprintf(env.Stderr, "ws: home %s not found in this workspace, staying at /\n", s.Home())
+	}
 
 	return nil
 }
 
+// loadWorkspaceCache builds a new cache for a workspace with its folder tree
+// and root listing loaded.
+func loadWorkspaceCache(ctx context.Context, s *session.Session, workspaceID int64) (*api.FileCache, error) {
+	newCache := api.NewFileCache()
+	_ = s.AttachNameIndex(newCache, workspaceID)
+	if err := newCache.LoadFolderTree(ctx, s.Client, s.UserID, s.Username, workspaceID); err != nil {
+		return nil, fmt.Errorf("failed to load folder tree: %w", err)
+	}
+
+	// Prefetch root directory
+	entries, err := s.Client.ListByParentIDWithOptions(ctx, nil, api.ListOptions(workspaceID))
+	if err == nil {
+		newCache.AddChildren("/", entries)
+	}
+	return newCache, nil
+}
+
+// reloadWorkspace replaces the current workspace's cache with a fresh copy,
+// keeping the working directory when it still exists.
+func reloadWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv, workspaceID int64, name string) error {
+	newCache, err := ui.WithSpinner(env.Stderr, "", false, func() (*api.FileCache, error) {
+		return loadWorkspaceCache(ctx, s, workspaceID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.Cache = newCache
+	s.RetainWorkspaceCache(workspaceID, newCache)
+	if entry, ok := newCache.Get(s.CWD); !ok || entry.Type != "folder" {
+		s.CWD = "/"
+		s.PreviousDir = ""
+	}
+
+	if workspaceID == 0 {
+		fmt.Fprintln(env.Stdout, "Refreshed default workspace")
+	} else {
+		fmt.Fprintf(env.Stdout, "Refreshed workspace '%s'\n", ui.WorkspaceStyle.Render(name))
+	}
+	return nil
+}
+
 func createWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv, name string) error {
 	if name == "" {
 		return fmt.Errorf("workspace name is required")
@@ -375,6 +520,7 @@ func deleteWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv,
 			}
 		}
 		s.Workspaces = newWorkspaces
+		s.ForgetWorkspaceCache(workspaceID)
 
 		// If we deleted the current workspace, switch to default
 		if s.WorkspaceID == workspaceID {
@@ -383,9 +529,17 @@ func deleteWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv,
 			s.CWD = "/"
 			s.PreviousDir = ""
 
-			// Reload cache for default workspace
-			s.Cache = api.NewFileCache()
-			_ = s.Cache.LoadFolderTree(ctx, s.Client, s.UserID, s.Username, 0)
+			// Reuse the default workspace cache if we still have it
+			if cache, ok := s.RetainedWorkspaceCache(0); ok {
+				s.Cache = cache
+			} else {
+				s.Cache = api.NewFileCache()
+				_ = s.AttachNameIndex(s.Cache, 0)
+				_ = s.Cache.LoadFolderTree(ctx, s.Client, s.UserID, s.Username, 0)
+				s.RetainWorkspaceCache(0, s.Cache)
+			}
+			s.ApplyWorkspaceProfile()
+			s.EnterHome()
 		}
 
 		return nil
@@ -401,7 +555,75 @@ func deleteWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv,
 	return nil
 }
 
-func listWorkspaceMembers(ctx context.Context, s *session.Session, env *ExecutionEnv) error {
+// listFormat selects how ws members and ws roles print their records.
+type listFormat int
+
+const (
+	formatTable listFormat = iota
+	formatJSON
+	formatCSV
+)
+
+func parseListFormat(name string, env *ExecutionEnv, args []string) (listFormat, error) {
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print records as a JSON array")
+	asCSV := fs.Bool("csv", false, "print records as CSV with a header row")
+	fs.SetOutput(env.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return formatTable, err
+	}
+	if fs.NArg() > 0 {
+		return formatTable, fmt.Errorf("usage: %s [--json | --csv]", name)
+	}
+	switch {
+	case *asJSON && *asCSV:
+		return formatTable, fmt.Errorf("%s: --json and --csv are mutually exclusive", name)
+	case *asJSON:
+		return formatJSON, nil
+	case *asCSV:
+		return formatCSV, nil
+	}
+	return formatTable, nil
+}
+
+// writeRecords prints records as JSON, or as CSV with header as the first
+// row, taking each CSV row from row.
+func writeRecords[T any](env *ExecutionEnv, format listFormat, records []T, header []string, row func(T) []string) error {
+	if format == formatJSON {
+		if records == nil {
+			records = []T{}
+		}
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(env.Stdout, string(data))
+		return err
+	}
+	w := csv.NewWriter(env.Stdout)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := w.Write(row(r)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// memberRecord is one line of ws members: a member, or a pending invite
+// (status "pending", no name).
+type memberRecord struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Role    string `json:"role"`
+	Status  string `json:"status"`
+	IsOwner bool   `json:"is_owner"`
+}
+
+func listWorkspaceMembers(ctx context.Context, s *session.Session, env *ExecutionEnv, format listFormat) error {
 	if s.WorkspaceID == 0 {
 		return fmt.Errorf("cannot list members of personal workspace")
 	}
@@ -411,15 +633,7 @@ func listWorkspaceMembers(ctx context.Context, s *session.Session, env *Executio
 		return err
 	}
 
-	t := ui.NewTable(env.Stdout)
-	t.SetHeaders(
-		ui.HeaderStyle.Render("NAME"),
-		ui.HeaderStyle.Render("EMAIL"),
-		ui.HeaderStyle.Render("ROLE"),
-		ui.HeaderStyle.Render("STATUS"),
-	)
-
-	// List members
+	var records []memberRecord
 	for _, m := range ws.Members {
 		name := m.DisplayName
 		if name == "" {
@@ -428,28 +642,51 @@ func listWorkspaceMembers(ctx context.Context, s *session.Session, env *Executio
 		if strings.TrimSpace(name) == "" {
 			name = m.Email
 		}
-		role := m.RoleName
-		if m.IsOwner {
-			role += " (Owner)"
-		}
-		t.AddRow(name, m.Email, role, "Active")
+		records = append(records, memberRecord{Name: name, Email: m.Email, Role: m.RoleName, Status: "active", IsOwner: m.IsOwner})
 	}
-
-	// List invites
 	for _, i := range ws.Invites {
-		t.AddRow("-", i.Email, i.RoleName, "Pending")
+		records = append(records, memberRecord{Email: i.Email, Role: i.RoleName, Status: "pending"})
+	}
+
+	if format != formatTable {
+		return writeRecords(env, format, records, []string{"name", "email", "role", "status", "is_owner"}, func(r memberRecord) []string {
+			return []string{r.Name, r.Email, r.Role, r.Status, strconv.FormatBool(r.IsOwner)}
+		})
 	}
 
+	t := ui.NewTable(env.Stdout)
+	t.SetHeaders(
+		ui.HeaderStyle.Render("NAME"),
+		ui.HeaderStyle.Render("EMAIL"),
+		ui.HeaderStyle.Render("ROLE"),
+		ui.HeaderStyle.Render("STATUS"),
+	)
+	for _, r := range records {
+		name, role, status := r.Name, r.Role, "Active"
+		if r.IsOwner {
+			role += " (Owner)"
+		}
+		if r.Status == "pending" {
+			name, status = "-", "Pending"
+		}
+		t.AddRow(name, r.Email, role, status)
+	}
 	t.Render()
 	return nil
 }
 
-func listWorkspaceRoles(ctx context.Context, s *session.Session, env *ExecutionEnv) error {
+func listWorkspaceRoles(ctx context.Context, s *session.Session, env *ExecutionEnv, format listFormat) error {
 	roles, err := s.Client.GetWorkspaceRoles(ctx)
 	if err != nil {
 		return err
 	}
 
+	if format != formatTable {
+		return writeRecords(env, format, roles, []string{"id", "name", "description"}, func(r api.WorkspaceRole) []string {
+			return []string{strconv.Itoa(r.ID), r.Name, r.Description}
+		})
+	}
+
 	t := ui.NewTable(env.Stdout)
 	t.SetHeaders(
 		ui.HeaderStyle.Render("ID"),
@@ -572,6 +809,97 @@ func changeMemberRole(ctx context.Context, s *session.Session, env *ExecutionEnv
 	return fmt.Errorf("member or invite not found: %s", target)
 }
 
+// transferOwnership makes the member target the owner of the current
+// workspace and demotes the caller to Admin. The server has no dedicated
+// endpoint for this; ownership goes with the owner role, so both changes are
+// role changes.
+func transferOwnership(ctx context.Context, s *session.Session, env *ExecutionEnv, target string) error {
+	if s.WorkspaceID == 0 {
+		return fmt.Errorf("cannot transfer the personal workspace")
+	}
+
+	ws, err := s.Client.GetWorkspace(ctx, s.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	var self, member *api.WorkspaceMember
+	for i, m := range ws.Members {
+		if m.MemberID == s.UserID {
+			self = &ws.Members[i]
+		}
+		if strings.EqualFold(m.Email, target) || fmt.Sprintf("%d", m.MemberID) == target {
+			member = &ws.Members[i]
+		}
+	}
+	if self == nil || !self.IsOwner {
+		return fmt.Errorf("only the owner can transfer the workspace")
+	}
+	if member == nil {
+		for _, i := range ws.Invites {
+			if strings.EqualFold(i.Email, target) {
+				return fmt.Errorf("%s has not accepted the invite yet", i.Email)
+			}
+		}
+		return fmt.Errorf("member not found: %s", target)
+	}
+	if member.MemberID == self.MemberID {
+		return fmt.Errorf("you already own this workspace")
+	}
+
+	roles, err := s.Client.GetWorkspaceRoles(ctx)
+	if err != nil {
+		return err
+	}
+	ownerRole := -1
+	for _, r := range roles {
+		if strings.Contains(strings.ToLower(r.Name), "owner") {
+			ownerRole = r.ID
+			break
+		}
+	}
+	if ownerRole < 0 {
+		return fmt.Errorf("the server offers no owner role; transfer ownership in the web app")
+	}
+	adminRole, err := resolveRoleID(ctx, s, "Admin")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.Stdout, "Make %s the owner of workspace '%s'? You will become an Admin. [y/N] ", member.Email, ws.Name)
+	reader := bufio.NewReader(env.Stdin)
+	response, _ := reader.ReadString('\n')
+	if strings.ToLower(strings.TrimSpace(response)) != "y" {
+		fmt.Fprintln(env.Stdout, "Cancelled")
+		return nil
+	}
+
+	if err := s.Client.ChangeMemberRole(ctx, s.WorkspaceID, member.MemberID, ownerRole, false); err != nil {
+		return err
+	}
+
+	// The server may demote the previous owner by itself
+	ws, err = s.Client.GetWorkspace(ctx, s.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	for _, m := range ws.Members {
+		if m.MemberID == s.UserID && m.IsOwner {
+			if err := s.Client.ChangeMemberRole(ctx, s.WorkspaceID, m.MemberID, adminRole, false); err != nil {
+				return fmt.Errorf("%s is now an owner, but demoting you failed: %w", member.Email, err)
+			}
+		}
+	}
+
+	for i := range s.Workspaces {
+		if s.Workspaces[i].ID == s.WorkspaceID {
+			s.Workspaces[i].OwnerID = member.MemberID
+		}
+	}
+	fmt.Fprintf(env.Stdout, "%s now owns workspace '%s'\n", member.Email, ws.Name)
+	return nil
+}
+
 func leaveWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv) error {
 	if s.WorkspaceID == 0 {
 		return fmt.Errorf("cannot leave personal workspace")
@@ -588,7 +916,7 @@ func leaveWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv)
 		if m.MemberID == s.UserID {
 			memberID = m.MemberID
 			if m.IsOwner {
-				return fmt.Errorf("owner cannot leave workspace (delete it or transfer ownership via web app)")
+				return fmt.Errorf("owner cannot leave workspace (delete it or hand it over with 'ws transfer')")
 			}
 			break
 		}
@@ -611,7 +939,12 @@ func leaveWorkspace(ctx context.Context, s *session.Session, env *ExecutionEnv)
 	}
 
 	fmt.Fprintln(env.Stdout, "Left workspace")
-	return switchWorkspace(ctx, s, env, "0")
+	left := s.WorkspaceID
+	if err := switchWorkspace(ctx, s, env, "0"); err != nil {
+		return err
+	}
+	s.ForgetWorkspaceCache(left)
+	return nil
 }
 
 func resolveRoleID(ctx context.Context, s *session.Session, roleName string) (int, error) {
@@ -3,6 +3,7 @@ package commands_test
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -183,6 +184,32 @@ func TestWsSwitch_ByID(t *testing.T) {
 	assert.Contains(t, stdout.String(), "Team Project")
 }
 
+func TestWsSwitch_RefreshFlagAnywhere(t *testing.T) {
+	s, env, _, _ := setupWorkspaceTestEnv(t)
+
+	reloads := 0
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetUserFoldersFunc = func(ctx context.Context, userID int64, workspaceID int64) ([]api.FileEntry, error) {
+		reloads++
+		return []api.FileEntry{}, nil
+	}
+	mockClient.GetWorkspaceStatsFunc = func(ctx context.Context, workspaceID int64) (*api.WorkspaceStats, error) {
+		return &api.WorkspaceStats{}, nil
+	}
+	mockClient.ListByParentIDWithOptionsFunc = func(ctx context.Context, parentID *int64, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+		return []api.FileEntry{}, nil
+	}
+
+	cmd, ok := commands.Get("ws")
+	require.True(t, ok)
+	for _, args := range [][]string{{"1", "--refresh"}, {"--refresh", "1"}, {"-r", "Team Project"}} {
+		require.NoError(t, cmd.Run(context.Background(), s, env, args), args)
+	}
+	assert.Equal(t, 3, reloads)
+
+	require.ErrorContains(t, cmd.Run(context.Background(), s, env, []string{"--refresh"}), "usage: ws")
+}
+
 func TestWsSwitch_ToDefault(t *testing.T) {
 	s, env, stdout, _ := setupWorkspaceTestEnv(t)
 
@@ -203,3 +230,173 @@ func TestWsSwitch_ToDefault(t *testing.T) {
 	assert.Equal(t, int64(0), s.WorkspaceID)
 	assert.Contains(t, stdout.String(), "default workspace")
 }
+
+func TestWsSwitch_AppliesWorkspaceProfile(t *testing.T) {
+	s, env, _, stderr := setupWorkspaceTestEnv(t)
+
+	projectsID := int64(10)
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetUserFoldersFunc = func(ctx context.Context, userID int64, workspaceID int64) ([]api.FileEntry, error) {
+		return []api.FileEntry{
+			{ID: projectsID, Name: "Projects", Type: "folder"},
+			{ID: 11, Name: "app", Type: "folder", ParentID: &projectsID},
+		}, nil
+	}
+	mockClient.ListByParentIDWithOptionsFunc = func(ctx context.Context, parentID *int64, opts *api.ListEntriesOptions) ([]api.FileEntry, error) {
+		return []api.FileEntry{}, nil
+	}
+	mockClient.GetWorkspaceStatsFunc = func(ctx context.Context, workspaceID int64) (*api.WorkspaceStats, error) {
+		return &api.WorkspaceStats{}, nil
+	}
+	s.WorkspaceProfiles = map[string]session.WorkspaceProfile{
+		"Personal": {Home: "Projects/app", Aliases: map[string]string{"ll": "ls -l", "b": "ls build"}},
+		"default":  {Home: "/gone"},
+	}
+
+	cmd, ok := commands.Get("ws")
+	require.True(t, ok)
+
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"Personal"}))
+	assert.Equal(t, "/Projects/app", s.CWD)
+	assert.Equal(t, "/Projects/app", s.Home())
+	aliases := s.AllAliases()
+	assert.Equal(t, "ls -l", aliases["ll"])
+	assert.Equal(t, "ls build", aliases["b"])
+	assert.Equal(t, "ws", aliases["workspace"])
+	assert.Equal(t, "ls -la", s.Aliases["ll"], "global aliases are left as they are")
+
+	// A home that doesn't exist leaves the shell at the root
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"default"}))
+	assert.Equal(t, "/", s.CWD)
+	assert.Contains(t, stderr.String(), "home /gone not found")
+	assert.NotContains(t, s.AllAliases(), "b")
+
+	// Workspaces without a profile fall back to the global home
+	s.HomeDir = "/Projects"
+	delete(s.WorkspaceProfiles, "default")
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"Personal"}))
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"default"}))
+	assert.Equal(t, "/Projects", s.CWD)
+}
+
+func TestResolveWorkspace_NumericName(t *testing.T) {
+	s, env, _, stderr := setupWorkspaceTestEnv(t)
+
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetWorkspacesFunc = func(ctx context.Context) ([]api.Workspace, error) {
+		return []api.Workspace{
+			{ID: 1, Name: "Team Project"},
+			{ID: 2, Name: "Personal"},
+			{ID: 7, Name: "2"},
+		}, nil
+	}
+
+	// A bare number that names a workspace selects it by name, with a warning
+	id, name, err := commands.ResolveWorkspace(context.Background(), s, env, "2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), id)
+	assert.Equal(t, "2", name)
+	assert.Contains(t, stderr.String(), "id:2")
+
+	// id: and name: prefixes are explicit and never warn
+	stderr.Reset()
+	id, name, err = commands.ResolveWorkspace(context.Background(), s, env, "id:2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+	assert.Equal(t, "Personal", name)
+
+	id, _, err = commands.ResolveWorkspace(context.Background(), s, env, "name:2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), id)
+	assert.Empty(t, stderr.String())
+
+	// Numbers without a matching name are still IDs
+	id, name, err = commands.ResolveWorkspace(context.Background(), s, env, "1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+	assert.Equal(t, "Team Project", name)
+
+	_, _, err = commands.ResolveWorkspace(context.Background(), s, env, "name:1")
+	assert.Error(t, err)
+	_, _, err = commands.ResolveWorkspace(context.Background(), s, env, "id:abc")
+	assert.Error(t, err)
+}
+
+func TestWsTransfer_PromotesMemberAndDemotesOwner(t *testing.T) {
+	s, env, stdout, _ := setupWorkspaceTestEnv(t)
+	env.Stdin = strings.NewReader("y\n")
+
+	members := []api.WorkspaceMember{
+		{MemberID: 123, Email: "me@example.com", RoleName: "Workspace Owner", RoleID: 1, IsOwner: true},
+		{MemberID: 200, Email: "bob@example.com", RoleName: "Workspace Member", RoleID: 3},
+	}
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetWorkspaceFunc = func(ctx context.Context, workspaceID int64) (*api.Workspace, error) {
+		return &api.Workspace{ID: workspaceID, Name: "Team Project", Members: append([]api.WorkspaceMember(nil), members...)}, nil
+	}
+	mockClient.GetWorkspaceRolesFunc = func(ctx context.Context) ([]api.WorkspaceRole, error) {
+		return []api.WorkspaceRole{{ID: 1, Name: "Workspace Owner"}, {ID: 2, Name: "Workspace Admin"}, {ID: 3, Name: "Workspace Member"}}, nil
+	}
+	var changes []string
+	mockClient.ChangeMemberRoleFunc = func(ctx context.Context, workspaceID int64, memberID interface{}, roleID int, isInvite bool) error {
+		changes = append(changes, fmt.Sprintf("%v:%d", memberID, roleID))
+		// The server keeps the old owner, so the caller has to step down
+		if memberID == int64(200) {
+			members[1].IsOwner = true
+		}
+		return nil
+	}
+
+	cmd, ok := commands.Get("ws")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"transfer", "bob@example.com"}))
+	assert.Equal(t, []string{"200:1", "123:2"}, changes)
+	assert.Contains(t, stdout.String(), "bob@example.com now owns workspace 'Team Project'")
+	assert.Equal(t, int64(200), s.Workspaces[0].OwnerID)
+
+	// Declining changes nothing
+	changes = nil
+	env.Stdin = strings.NewReader("n\n")
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"transfer", "bob@example.com"}))
+	assert.Empty(t, changes)
+
+	err := cmd.Run(context.Background(), s, env, []string{"transfer", "nobody@example.com"})
+	assert.Error(t, err)
+}
+
+func TestWsMembers_JSONAndCSV(t *testing.T) {
+	s, env, stdout, _ := setupWorkspaceTestEnv(t)
+	mockClient := s.Client.(*api.MockDrimeClient)
+	mockClient.GetWorkspaceFunc = func(ctx context.Context, workspaceID int64) (*api.Workspace, error) {
+		return &api.Workspace{
+			ID: workspaceID,
+			Members: []api.WorkspaceMember{
+				{MemberID: 123, Email: "me@example.com", DisplayName: "Me, Myself", RoleName: "Workspace Owner", IsOwner: true},
+			},
+			Invites: []api.WorkspaceInvite{{ID: 5, Email: "new@example.com", RoleName: "Workspace Member"}},
+		}, nil
+	}
+
+	cmd, ok := commands.Get("ws")
+	require.True(t, ok)
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"members", "--json"}))
+	assert.JSONEq(t, `[
+		{"name": "Me, Myself", "email": "me@example.com", "role": "Workspace Owner", "status": "active", "is_owner": true},
+		{"name": "", "email": "new@example.com", "role": "Workspace Member", "status": "pending", "is_owner": false}
+	]`, stdout.String())
+
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"members", "--csv"}))
+	assert.Equal(t, "name,email,role,status,is_owner\n"+
+		"\"Me, Myself\",me@example.com,Workspace Owner,active,true\n"+
+		",new@example.com,Workspace Member,pending,false\n", stdout.String())
+
+	mockClient.GetWorkspaceRolesFunc = func(ctx context.Context) ([]api.WorkspaceRole, error) {
+		return []api.WorkspaceRole{{ID: 2, Name: "Workspace Admin", Description: "Manage members"}}, nil
+	}
+	stdout.Reset()
+	require.NoError(t, cmd.Run(context.Background(), s, env, []string{"roles", "--csv"}))
+	assert.Equal(t, "id,name,description\n2,Workspace Admin,Manage members\n", stdout.String())
+
+	assert.Error(t, cmd.Run(context.Background(), s, env, []string{"members", "--json", "--csv"}))
+}
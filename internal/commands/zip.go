@@ -0,0 +1,582 @@
+package commands
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/gYonder/drime-shell/internal/ui"
+)
+
+// zipJob is a remote file or folder to add to an archive under name.
+type zipJob struct {
+	entry *api.FileEntry
+	name  string
+}
+
+// zipBuilder adds remote files to a zip archive. Files are downloaded and
+// compressed by several workers at once, including the files of a folder;
+// only writing the compressed data to the archive is serialized, in the
+// order of the jobs and of each folder's files.
+type zipBuilder struct {
+	s         *session.Session
+	env       *ExecutionEnv
+	level     int   // flate level 0-9; 0 stores everything
+	maxMemory int64 // data held in memory by all spools at once; the rest goes to temp files
+	slots     chan struct{}
+	memory    *zipMemory
+	order     zipOrder
+
+	mu         sync.Mutex // guards zw, the counters and progress output
+	zw         *zip.Writer
+	files      int
+	rawSize    int64
+	packedSize int64
+}
+
+// zipMemory is what is left of the memory the spools of a zipBuilder may
+// use together.
+type zipMemory struct {
+	mu   sync.Mutex
+	left int64
+}
+
+// take reserves n bytes, reporting false when they don't fit.
+func (m *zipMemory) take(n int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n > m.left {
+		return false
+	}
+	m.left -= n
+	return true
+}
+
+func (m *zipMemory) give(n int64) {
+	m.mu.Lock()
+	m.left += n
+	m.mu.Unlock()
+}
+
+// zipOrder hands out turns to write to the archive: entry item of job job
+// goes once every entry of the earlier jobs, and the earlier entries of
+// its own job, are written.
+type zipOrder struct {
+	mu      sync.Mutex
+	job     int
+	item    int
+	changed chan struct{} // closed and replaced on every turn
+}
+
+// wait blocks until it is the turn of entry item of job.
+func (o *zipOrder) wait(ctx context.Context, job, item int) error {
+	for {
+		o.mu.Lock()
+		if o.changed == nil {
+			o.changed = make(chan struct{})
+		}
+		if o.job == job && o.item == item {
+			o.mu.Unlock()
+			return nil
+		}
+		changed := o.changed
+		o.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// next passes the turn to the following entry, or with jobDone to the
+// first entry of the following job.
+func (o *zipOrder) next(jobDone bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if jobDone {
+		o.job++
+		o.item = 0
+	} else {
+		o.item++
+	}
+	if o.changed != nil {
+		close(o.changed)
+	}
+	o.changed = make(chan struct{})
+}
+
+// planZipJobs expands the root folder, which can't be downloaded as a zip,
+// into its children. Other folders stay single jobs.
+func planZipJobs(ctx context.Context, s *session.Session, jobs []zipJob) ([]zipJob, error) {
+	var planned []zipJob
+	for _, job := range jobs {
+		if !job.entry.IsRoot() {
+			planned = append(planned, job)
+			continue
+		}
+		children, err := s.Client.ListByParentIDWithOptions(ctx, nil, api.ListOptions(s.WorkspaceID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list root folder: %w", err)
+		}
+		for i := range children {
+			planned = append(planned, zipJob{entry: &children[i], name: path.Join(job.name, children[i].Name)})
+		}
+	}
+	return planned, nil
+}
+
+// run adds jobs to the archive with up to workers files being compressed
+// at once, and stops at the first error. A panic in any of its goroutines
+// stops it too, and is raised again on the caller's.
+func (b *zipBuilder) run(ctx context.Context, jobs []zipJob, workers int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	b.slots = make(chan struct{}, workers)
+	b.memory = &zipMemory{left: b.maxMemory}
+
+	type numberedJob struct {
+		zipJob
+		seq int
+	}
+	queue := make(chan numberedJob)
+	var wg sync.WaitGroup
+	var first firstError
+	var panics ui.PanicTrap
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer panics.Catch(cancel)
+			for job := range queue {
+				if err := b.addJob(ctx, job.seq, job.zipJob); err != nil {
+					first.set(fmt.Errorf("error adding %s: %w", job.name, err), cancel)
+				}
+			}
+		}()
+	}
+
+feed:
+	for i, job := range jobs {
+		select {
+		case queue <- numberedJob{job, i}:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	panics.Rethrow()
+
+	if first.err != nil {
+		return first.err
+	}
+	return ctx.Err()
+}
+
+// acquire takes a compression slot; release gives it back.
+func (b *zipBuilder) acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *zipBuilder) release() { <-b.slots }
+
+// addJob adds job, the seq-th of the archive, writing its entries in turn.
+func (b *zipBuilder) addJob(ctx context.Context, seq int, job zipJob) error {
+	src, err := b.newSpool(job.entry.Size)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if _, err := b.s.Client.Download(ctx, job.entry.Hash, src, nil); err != nil {
+		return err
+	}
+
+	if job.entry.Type != "folder" {
+		packed, err := b.packLimited(ctx, job.name, job.entry.UpdatedAt, job.entry.Mime, src.Len(), func() (io.ReadCloser, error) {
+			return io.NopCloser(src.Reader()), nil
+		})
+		src.Close()
+		if err != nil {
+			return err
+		}
+		defer packed.data.Close()
+		if err := b.order.wait(ctx, seq, 0); err != nil {
+			return err
+		}
+		if err := b.write(packed); err != nil {
+			return err
+		}
+		b.order.next(true)
+		return nil
+	}
+
+	// Folders come from the API as a zip; re-add its entries under the
+	// folder's name, compressing its files in parallel
+	zr, err := zip.NewReader(src.ReaderAt(), src.Len())
+	if err != nil {
+		return fmt.Errorf("failed to read folder zip: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	var first firstError
+	var panics ui.PanicTrap
+	item := 0
+	// addDir writes a directory entry once its turn comes, in line with
+	// the files before it
+	addDir := func(name string, modified time.Time) {
+		turn := item
+		item++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer panics.Catch(cancel)
+			err := b.order.wait(ctx, seq, turn)
+			if err == nil {
+				err = b.addDir(name, modified)
+			}
+			if err != nil {
+				first.set(err, cancel)
+				return
+			}
+			b.order.next(false)
+		}()
+	}
+	if job.name != "" {
+		addDir(job.name, job.entry.UpdatedAt)
+	}
+	for _, f := range zr.File {
+		// Check for ZipSlip vulnerability - reject paths with traversal
+		if strings.Contains(f.Name, "..") {
+			first.set(fmt.Errorf("illegal file path in zip: %s", f.Name), cancel)
+			break
+		}
+		name := path.Join(job.name, f.Name)
+		if f.FileInfo().IsDir() {
+			addDir(name, f.Modified)
+			continue
+		}
+		if err := b.acquire(ctx); err != nil {
+			first.set(err, cancel)
+			break
+		}
+		turn := item
+		item++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer panics.Catch(cancel)
+			packed, err := b.pack(name, f.Modified, "", int64(f.UncompressedSize64), f.Open)
+			b.release()
+			if err == nil {
+				defer packed.data.Close()
+				err = b.order.wait(ctx, seq, turn)
+			}
+			if err == nil {
+				err = b.write(packed)
+			}
+			if err != nil {
+				first.set(err, cancel)
+				return
+			}
+			b.order.next(false)
+		}()
+	}
+	wg.Wait()
+	panics.Rethrow()
+	if first.err != nil {
+		return first.err
+	}
+	// Hands the turn on once the last entry was written
+	if err := b.order.wait(ctx, seq, item); err != nil {
+		return err
+	}
+	b.order.next(true)
+	return nil
+}
+
+// packLimited packs a file while holding a compression slot.
+func (b *zipBuilder) packLimited(ctx context.Context, name string, modified time.Time, mime string, size int64, open func() (io.ReadCloser, error)) (*packedFile, error) {
+	if err := b.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer b.release()
+	return b.pack(name, modified, mime, size, open)
+}
+
+// firstError keeps the first of the errors reported by concurrent workers
+// and cancels their context.
+type firstError struct {
+	once sync.Once
+	err  error
+}
+
+func (f *firstError) set(err error, cancel context.CancelFunc) {
+	f.once.Do(func() {
+		f.err = err
+		cancel()
+	})
+}
+
+// addDir writes a directory entry.
+func (b *zipBuilder) addDir(name string, modified time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, err := b.zw.CreateHeader(&zip.FileHeader{Name: name + "/", Method: zip.Store, Modified: modified})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b.env.Stdout, "  adding: %s/ (stored 0%%)\n", name)
+	return nil
+}
+
+// packedFile is a file compressed, or stored, for the archive and waiting
+// for its turn to be written.
+type packedFile struct {
+	header zip.FileHeader
+	data   *zipSpool
+}
+
+// pack compresses the data returned by open. Data that compression doesn't
+// shrink, and types that are known not to compress (mime, when known), are
+// stored instead. open may be called twice. The caller closes the result's
+// data.
+func (b *zipBuilder) pack(name string, modified time.Time, mime string, size int64, open func() (io.ReadCloser, error)) (*packedFile, error) {
+	method := zip.Deflate
+	if b.level == 0 || (mime != "" && !isCompressibleMIME(mime)) {
+		method = zip.Store
+	}
+
+	// Deflate adds a few bytes per block to data it can't shrink
+	out, err := b.newSpool(size + size/1024 + 64)
+	if err != nil {
+		return nil, err
+	}
+
+	crc := crc32.NewIEEE()
+	var raw int64
+	err = func() error {
+		if method == zip.Deflate {
+			fw, err := flate.NewWriter(out, b.level)
+			if err != nil {
+				return err
+			}
+			if raw, err = copyFrom(fw, crc, open); err != nil {
+				return err
+			}
+			if err := fw.Close(); err != nil {
+				return err
+			}
+			if out.Len() >= raw {
+				// Incompressible after all
+				method = zip.Store
+				if err := out.Reset(); err != nil {
+					return err
+				}
+				crc.Reset()
+			}
+		}
+		if method == zip.Store {
+			if raw, err = copyFrom(out, crc, open); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		out.Close()
+		return nil, err
+	}
+
+	return &packedFile{
+		header: zip.FileHeader{
+			Name:               name,
+			Method:             method,
+			Modified:           modified,
+			CRC32:              crc.Sum32(),
+			CompressedSize64:   uint64(out.Len()),
+			UncompressedSize64: uint64(raw),
+		},
+		data: out,
+	}, nil
+}
+
+// write adds a packed file to the archive.
+func (b *zipBuilder) write(f *packedFile) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w, err := b.zw.CreateRaw(&f.header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, f.data.Reader()); err != nil {
+		return err
+	}
+
+	raw, packed := int64(f.header.UncompressedSize64), f.data.Len()
+	b.files++
+	b.rawSize += raw
+	b.packedSize += packed
+	if f.header.Method == zip.Store {
+		fmt.Fprintf(b.env.Stdout, "  adding: %s (stored 0%%)\n", f.header.Name)
+	} else {
+		fmt.Fprintf(b.env.Stdout, "  adding: %s (deflated %d%%)\n", f.header.Name, savedPercent(raw, packed))
+	}
+	return nil
+}
+
+// copyFrom copies the data returned by open to w, feeding it to crc too.
+func copyFrom(w io.Writer, crc io.Writer, open func() (io.ReadCloser, error)) (int64, error) {
+	r, err := open()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	return io.Copy(w, io.TeeReader(r, crc))
+}
+
+// savedPercent is how much smaller packed is than raw, in percent.
+func savedPercent(raw, packed int64) int64 {
+	if raw <= 0 || packed >= raw {
+		return 0
+	}
+	return (raw - packed) * 100 / raw
+}
+
+// zipSpool holds data in memory, within the memory reserved for it, or in
+// a temp file. Data outgrowing its reservation moves to a temp file.
+type zipSpool struct {
+	buf      *bytes.Buffer
+	file     *os.File
+	size     int64
+	memory   *zipMemory
+	reserved int64 // bytes of memory held for buf
+}
+
+// newSpool returns a spool for about size bytes, in memory when that fits
+// in what the builder's spools may still use.
+func (b *zipBuilder) newSpool(size int64) (*zipSpool, error) {
+	if b.memory.take(size) {
+		return &zipSpool{buf: new(bytes.Buffer), memory: b.memory, reserved: size}, nil
+	}
+	f, err := os.CreateTemp("", "drime-zip-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	return &zipSpool{file: f}, nil
+}
+
+func (sp *zipSpool) Write(p []byte) (int, error) {
+	if sp.file == nil && sp.size+int64(len(p)) > sp.reserved {
+		if err := sp.spill(); err != nil {
+			return 0, err
+		}
+	}
+	var n int
+	var err error
+	if sp.file != nil {
+		n, err = sp.file.Write(p)
+	} else {
+		n, err = sp.buf.Write(p)
+	}
+	sp.size += int64(n)
+	return n, err
+}
+
+// spill moves the data to a temp file, giving back its memory.
+func (sp *zipSpool) spill() error {
+	f, err := os.CreateTemp("", "drime-zip-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if _, err := f.Write(sp.buf.Bytes()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	sp.file = f
+	sp.release()
+	return nil
+}
+
+// release gives back the memory held for buf.
+func (sp *zipSpool) release() {
+	if sp.memory != nil {
+		sp.memory.give(sp.reserved)
+	}
+	sp.buf = nil
+	sp.reserved = 0
+}
+
+// Len is the number of bytes written.
+func (sp *zipSpool) Len() int64 { return sp.size }
+
+// Reader reads the data from the start. Each call returns a new reader.
+func (sp *zipSpool) Reader() io.Reader {
+	return io.NewSectionReader(sp.ReaderAt(), 0, sp.size)
+}
+
+func (sp *zipSpool) ReaderAt() io.ReaderAt {
+	if sp.file != nil {
+		return sp.file
+	}
+	return bytes.NewReader(sp.buf.Bytes())
+}
+
+// Reset discards the data written so far.
+func (sp *zipSpool) Reset() error {
+	sp.size = 0
+	if sp.file == nil {
+		sp.buf.Reset()
+		return nil
+	}
+	if err := sp.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := sp.file.Seek(0, io.SeekStart)
+	return err
+}
+
+// Close removes the temp file, if any, and gives back the memory held.
+// Closing twice is harmless.
+func (sp *zipSpool) Close() error {
+	if sp.file == nil {
+		if sp.buf != nil {
+			sp.release()
+		}
+		return nil
+	}
+	f := sp.file
+	sp.file = nil
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// zipName is the name a source gets in the archive: its base name, or
+// nothing for the root folder, whose contents go at the top level.
+func zipName(resolved string) string {
+	name := filepath.Base(resolved)
+	if resolved == "/" || name == "/" || name == "" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZipSpool_SharesMemoryAndSpillsToDisk(t *testing.T) {
+	b := &zipBuilder{memory: &zipMemory{left: 100}}
+
+	first, err := b.newSpool(60)
+	require.NoError(t, err)
+	defer first.Close()
+	assert.Nil(t, first.file, "fits in memory")
+
+	second, err := b.newSpool(60)
+	require.NoError(t, err)
+	defer second.Close()
+	assert.NotNil(t, second.file, "more than what is left goes to disk")
+
+	// Outgrowing the reservation moves the data to disk and frees it
+	data := strings.Repeat("x", 80)
+	_, err = io.WriteString(first, data)
+	require.NoError(t, err)
+	assert.NotNil(t, first.file)
+	assert.Equal(t, int64(100), b.memory.left)
+	var got bytes.Buffer
+	_, err = io.Copy(&got, first.Reader())
+	require.NoError(t, err)
+	assert.Equal(t, data, got.String())
+
+	third, err := b.newSpool(40)
+	require.NoError(t, err)
+	assert.Nil(t, third.file)
+	require.NoError(t, third.Close())
+	require.NoError(t, third.Close())
+	assert.Equal(t, int64(100), b.memory.left, "closing gives the memory back once")
+}
@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -11,20 +15,58 @@ import (
 type Config struct {
 	Aliases           map[string]string `yaml:"aliases,omitempty"`
 	Theme             string            `yaml:"theme"`
+	Colors            map[string]string `yaml:"colors,omitempty"`
 	Token             string            `yaml:"token"`
+	TokenCommand      string            `yaml:"token_command,omitempty"`
 	APIURL            string            `yaml:"api_url"`
 	HistorySize       int               `yaml:"history_size"`
 	MaxMemoryBufferMB int               `yaml:"max_memory_buffer_mb"`
+	RmConfirmEntries  int               `yaml:"rm_confirm_entries"`
+	TransferJobs      int               `yaml:"transfer_jobs"`
+	LocateIndex       bool              `yaml:"locate_index"`
+	AuditLog          string            `yaml:"audit_log,omitempty"`
+	ExternalEditor    bool              `yaml:"external_editor"`
+	NoZipThreshold    int               `yaml:"no_zip_threshold"`
+	DownloadDir       string            `yaml:"download_dir,omitempty"`
+	OnDuplicate       string            `yaml:"default_on_duplicate,omitempty"`
+	DownloadCacheMB   int               `yaml:"download_cache_mb,omitempty"`
+	ChecksumAlgo      string            `yaml:"checksum_algo,omitempty"`
+	PartialSuffix     string            `yaml:"partial_suffix,omitempty"`
+	Home              string            `yaml:"home,omitempty"`
+
+	// Workspaces holds per-workspace settings, keyed by workspace name or
+	// ID, with "default" for the default workspace.
+	Workspaces map[string]WorkspaceConfig `yaml:"workspaces,omitempty"`
+
+	// commandToken is the token obtained from TokenCommand, kept so Save
+	// doesn't write it back to the file in plaintext.
+	commandToken string
+}
+
+// WorkspaceConfig is the settings of one workspace. Aliases are merged over
+// the global ones and Home replaces the global home while it is active.
+type WorkspaceConfig struct {
+	Home    string            `yaml:"home,omitempty"`
+	Aliases map[string]string `yaml:"aliases,omitempty"`
 }
 
 const DefaultMaxMemoryBufferMB = 100 // 100MB
 
+const DefaultRmConfirmEntries = 100 // Folders with more entries need confirmation
+
+const DefaultTransferJobs = 6 // Parallel workers for directory uploads
+
+const DefaultNoZipThreshold = 200 // Folders with more files download file by file
+
 func Default() *Config {
 	return &Config{
 		Theme:             "auto",
 		APIURL:            "https://app.drime.cloud/api/v1",
 		HistorySize:       1000,
 		MaxMemoryBufferMB: DefaultMaxMemoryBufferMB,
+		RmConfirmEntries:  DefaultRmConfirmEntries,
+		TransferJobs:      DefaultTransferJobs,
+		NoZipThreshold:    DefaultNoZipThreshold,
 		Aliases:           make(map[string]string),
 	}
 }
@@ -75,9 +117,89 @@ func Load() (*Config, error) {
 		cfg.Token = token
 	}
 
+	// 3. Ask the credential helper, like git's credential.helper
+	if cfg.Token == "" && cfg.TokenCommand != "" {
+		token, err := runTokenCommand(cfg.TokenCommand)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Token = token
+		cfg.commandToken = token
+	}
+
 	return cfg, nil
 }
 
+// AuditLogPath returns the file mutating commands are logged to, with a
+// leading ~ expanded, or "" when auditing is off.
+func (c *Config) AuditLogPath() string {
+	return expandHome(c.AuditLog)
+}
+
+// DownloadDirPath returns where download puts files when no local path is
+// given, with a leading ~ expanded, or "" for the current directory.
+// $DRIME_DOWNLOAD_DIR overrides download_dir.
+func (c *Config) DownloadDirPath() string {
+	if dir := os.Getenv("DRIME_DOWNLOAD_DIR"); dir != "" {
+		return expandHome(dir)
+	}
+	return expandHome(c.DownloadDir)
+}
+
+// ValidateAPIURL checks that raw is an absolute https URL usable as the API
+// base, such as https://drime.example.com/api/v1, and returns it without a
+// trailing slash.
+func ValidateAPIURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid API URL %q: %w", raw, err)
+	}
+	if u.Scheme != "https" {
+		return "", fmt.Errorf("invalid API URL %q: must use https", raw)
+	}
+	if u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid API URL %q: expected https://host[/path]", raw)
+	}
+	return strings.TrimRight(raw, "/"), nil
+}
+
+// expandHome replaces a leading ~ in path with the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// runTokenCommand runs command through the system shell and returns the first
+// line of its output as the token.
+func runTokenCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	// Password managers may prompt for a passphrase
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token_command failed: %w", err)
+	}
+
+	token, _, _ := strings.Cut(string(out), "\n")
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("token_command printed no token")
+	}
+	return token, nil
+}
+
 // Save writes the config to ~/.drime-shell/config.yaml
 func Save(cfg *Config) error {
 	dir, err := ConfigDir()
@@ -102,9 +224,15 @@ func Save(cfg *Config) error {
 	}
 	defer f.Close()
 
+	// A token read from token_command stays in the secret store
+	out := *cfg
+	if out.commandToken != "" && out.Token == out.commandToken {
+		out.Token = ""
+	}
+
 	encoder := yaml.NewEncoder(f)
 	encoder.SetIndent(2)
-	if err := encoder.Encode(cfg); err != nil {
+	if err := encoder.Encode(&out); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
 
@@ -2,10 +2,13 @@ package config_test
 
 import (
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/gYonder/drime-shell/internal/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoad_EnvVar(t *testing.T) {
@@ -22,3 +25,111 @@ func TestConfigPath(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, path, ".drime-shell/config.yaml")
 }
+
+func TestLoad_TokenCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell command")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("DRIME_TOKEN", "")
+	dir := filepath.Join(home, ".drime-shell")
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"),
+		[]byte("token_command: \"printf 'secret-token\\\\nignored'\"\n"), 0600))
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "secret-token", cfg.Token)
+
+	// Saving the config must not write the helper's token in plaintext
+	require.NoError(t, config.Save(cfg))
+	data, err := os.ReadFile(filepath.Join(dir, "config.yaml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "token: secret-token")
+	assert.Contains(t, string(data), "token_command")
+}
+
+func TestLoad_Workspaces(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".drime-shell")
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(`home: /Inbox
+workspaces:
+  Client Site:
+    home: /site
+    aliases:
+      deploy: upload ./dist /site
+  default:
+    home: /mine
+`), 0600))
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "/Inbox", cfg.Home)
+	assert.Equal(t, config.WorkspaceConfig{Home: "/site", Aliases: map[string]string{"deploy": "upload ./dist /site"}}, cfg.Workspaces["Client Site"])
+	assert.Equal(t, "/mine", cfg.Workspaces["default"].Home)
+}
+
+func TestLoad_TokenCommandFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell command")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("DRIME_TOKEN", "")
+	dir := filepath.Join(home, ".drime-shell")
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("token_command: \"exit 3\"\n"), 0600))
+
+	_, err := config.Load()
+	assert.ErrorContains(t, err, "token_command")
+}
+
+func TestConfig_AuditLogPath(t *testing.T) {
+	cfg := config.Default()
+	assert.Empty(t, cfg.AuditLogPath())
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	cfg.AuditLog = "~/.drime-shell/audit.log"
+	assert.Equal(t, filepath.Join(home, ".drime-shell", "audit.log"), cfg.AuditLogPath())
+
+	cfg.AuditLog = "/var/log/drime.log"
+	assert.Equal(t, "/var/log/drime.log", cfg.AuditLogPath())
+}
+
+func TestConfig_DownloadDirPath(t *testing.T) {
+	t.Setenv("DRIME_DOWNLOAD_DIR", "")
+	cfg := config.Default()
+	assert.Empty(t, cfg.DownloadDirPath())
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	cfg.DownloadDir = "~/Downloads/drime"
+	assert.Equal(t, filepath.Join(home, "Downloads", "drime"), cfg.DownloadDirPath())
+	cfg.DownloadDir = "~"
+	assert.Equal(t, home, cfg.DownloadDirPath())
+
+	t.Setenv("DRIME_DOWNLOAD_DIR", "/tmp/incoming")
+	assert.Equal(t, "/tmp/incoming", cfg.DownloadDirPath())
+}
+
+func TestValidateAPIURL(t *testing.T) {
+	got, err := config.ValidateAPIURL("https://drime.example.com/api/v1/")
+	require.NoError(t, err)
+	assert.Equal(t, "https://drime.example.com/api/v1", got)
+
+	for _, bad := range []string{
+		"http://drime.example.com/api/v1",
+		"drime.example.com/api/v1",
+		"https:///api/v1",
+		"https://user:pw@drime.example.com/api/v1",
+		"https://drime.example.com/api/v1?x=1",
+		"://",
+	} {
+		_, err := config.ValidateAPIURL(bad)
+		assert.Error(t, err, bad)
+	}
+}
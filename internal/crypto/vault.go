@@ -21,6 +21,9 @@ const (
 	KeySize = 32
 	// IVSize is the size of GCM initialization vectors (12 bytes per NIST).
 	IVSize = 12
+	// TagSize is the size of the GCM authentication tag Encrypt appends, so
+	// a ciphertext is always TagSize bytes longer than its plaintext.
+	TagSize = 16
 	// SaltSize is the size of PBKDF2 salt in bytes.
 	SaltSize = 16
 	// PBKDF2Iterations matches the Drime web app (250,000 iterations).
@@ -136,6 +139,42 @@ func (vk *VaultKey) Decrypt(ciphertext []byte, iv []byte) ([]byte, error) {
 	return plaintext, nil
 }
 
+// Reencrypt decrypts ciphertext and encrypts the plaintext again with a
+// fresh IV. Both steps reuse ciphertext's memory, so a large file is held
+// only once; ciphertext is overwritten and must not be used afterwards.
+func (vk *VaultKey) Reencrypt(ciphertext []byte, iv []byte) (newCiphertext []byte, newIV []byte, err error) {
+	if vk.IsZeroed() {
+		return nil, nil, ErrKeyZeroed
+	}
+
+	if len(iv) != IVSize {
+		return nil, nil, fmt.Errorf("invalid IV size: expected %d, got %d", IVSize, len(iv))
+	}
+
+	block, err := aes.NewCipher(vk.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create GCM: %w", err)
+	}
+
+	plaintext, err := gcm.Open(ciphertext[:0], iv, ciphertext, nil)
+	if err != nil {
+		return nil, nil, ErrDecryptionFailed
+	}
+
+	newIV = make([]byte, IVSize)
+	if _, err := io.ReadFull(rand.Reader, newIV); err != nil {
+		return nil, nil, fmt.Errorf("generate IV: %w", err)
+	}
+
+	// The tag fits in the space the old one used
+	return gcm.Seal(plaintext[:0], newIV, plaintext, nil), newIV, nil
+}
+
 // GenerateSalt creates a random salt for PBKDF2 key derivation.
 func GenerateSalt() ([]byte, error) {
 	salt := make([]byte, SaltSize)
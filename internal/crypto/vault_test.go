@@ -90,6 +90,42 @@ func TestEncryptDecrypt(t *testing.T) {
 	}
 }
 
+func TestReencrypt(t *testing.T) {
+	key := DeriveKey("password", []byte("0123456789abcdef"))
+	defer key.Zero()
+
+	plaintext := []byte("Hello again, Vault!")
+	ciphertext, iv, err := key.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	newCiphertext, newIV, err := key.Reencrypt(ciphertext, iv)
+	if err != nil {
+		t.Fatalf("Reencrypt failed: %v", err)
+	}
+	if bytes.Equal(newIV, iv) {
+		t.Error("re-encryption should use a fresh IV")
+	}
+	if &newCiphertext[0] != &ciphertext[0] {
+		t.Error("re-encryption should reuse the ciphertext's memory")
+	}
+
+	decrypted, err := key.Decrypt(newCiphertext, newIV)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("expected %q, got %q", plaintext, decrypted)
+	}
+
+	// Tampered data is rejected like Decrypt does
+	newCiphertext[0] ^= 0xff
+	if _, _, err := key.Reencrypt(newCiphertext, newIV); err != ErrDecryptionFailed {
+		t.Errorf("expected ErrDecryptionFailed, got %v", err)
+	}
+}
+
 func TestEncryptProducesUniqueIVs(t *testing.T) {
 	key := DeriveKey("password", []byte("0123456789abcdef"))
 	defer key.Zero()
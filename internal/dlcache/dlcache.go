@@ -0,0 +1,229 @@
+// Package dlcache keeps local copies of downloaded files.
+package dlcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gYonder/drime-shell/internal/api"
+)
+
+// Cache keeps copies of downloaded files on disk so downloading an
+// unchanged file again is a local copy. Files are keyed by the entry's hash,
+// size and modification time, and their SHA-256 is part of the file name so
+// a copy is verified before it is used. The least recently used files are
+// evicted once the cache outgrows its limit. A nil cache is valid and caches
+// nothing.
+//
+// Each key has its own lock, so different files are hashed and copied in
+// parallel; only eviction looks at the whole cache.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex // guards keys
+	keys    map[string]*keyLock
+	evictMu sync.Mutex
+}
+
+// keyLock serializes the use of one key; users counts the goroutines
+// holding or waiting for it, so unused locks can be dropped.
+type keyLock struct {
+	sync.Mutex
+	users int
+}
+
+// New returns a cache in dir holding at most maxBytes.
+func New(dir string, maxBytes int64) *Cache {
+	return &Cache{dir: dir, maxBytes: maxBytes, keys: make(map[string]*keyLock)}
+}
+
+// Enabled reports whether the cache keeps anything.
+func (c *Cache) Enabled() bool {
+	return c != nil && c.maxBytes > 0
+}
+
+// lock takes the lock of key and returns the function releasing it.
+func (c *Cache) lock(key string) func() {
+	c.mu.Lock()
+	l, ok := c.keys[key]
+	if !ok {
+		l = &keyLock{}
+		c.keys[key] = l
+	}
+	l.users++
+	c.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		c.mu.Lock()
+		if l.users--; l.users == 0 {
+			delete(c.keys, key)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// cacheKey names the cached copies of entry, or returns "" when entry can't
+// be told apart from a later version of itself.
+func cacheKey(entry *api.FileEntry) string {
+	if entry.Hash == "" || entry.UpdatedAt.IsZero() {
+		return ""
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s:%d:%d", entry.Hash, entry.Size, entry.UpdatedAt.UnixNano()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Restore copies the cached content of entry to dst and reports whether it
+// did. A copy that fails verification is dropped from the cache.
+func (c *Cache) Restore(entry *api.FileEntry, dst string) (bool, error) {
+	if !c.Enabled() {
+		return false, nil
+	}
+	key := cacheKey(entry)
+	if key == "" {
+		return false, nil
+	}
+	defer c.lock(key)()
+
+	matches, _ := filepath.Glob(filepath.Join(c.dir, key+"-*"))
+	for _, path := range matches {
+		sum, size, err := hashFile(path)
+		if err != nil || sum != strings.TrimPrefix(filepath.Base(path), key+"-") || size != entry.Size {
+			// Damaged or changed on disk
+			os.Remove(path)
+			continue
+		}
+		if err := copyFile(path, dst); err != nil {
+			os.Remove(dst)
+			return false, err
+		}
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+		return true, nil
+	}
+	return false, nil
+}
+
+// Store adds the downloaded content of entry, read from src, to the cache
+// and evicts the least recently used files beyond the size limit.
+func (c *Cache) Store(entry *api.FileEntry, src string) error {
+	if !c.Enabled() || entry.Size > c.maxBytes {
+		return nil
+	}
+	key := cacheKey(entry)
+	if key == "" {
+		return nil
+	}
+	if err := c.store(key, entry, src); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+func (c *Cache) store(key string, entry *api.FileEntry, src string) error {
+	defer c.lock(key)()
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	sum, size, err := hashFile(src)
+	if err != nil {
+		return err
+	}
+	if size != entry.Size {
+		return fmt.Errorf("cache: %s is %d bytes, expected %d", src, size, entry.Size)
+	}
+	tmp := filepath.Join(c.dir, key+tmpSuffix)
+	if err := copyFile(src, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(c.dir, key+"-"+sum)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// tmpSuffix marks copies still being written, which eviction leaves alone.
+const tmpSuffix = ".tmp"
+
+// evict removes the least recently used files until the cache fits its
+// limit. A copy being restored meanwhile stays readable, as the open file
+// outlives its removal.
+func (c *Cache) evict() error {
+	c.evictMu.Lock()
+	defer c.evictMu.Unlock()
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	type cached struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var files []cached
+	var total int64
+	for _, de := range dirEntries {
+		info, err := de.Info()
+		if err != nil || !info.Mode().IsRegular() || strings.HasSuffix(de.Name(), tmpSuffix) {
+			continue
+		}
+		files = append(files, cached{filepath.Join(c.dir, de.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].used.Before(files[j].used) })
+	for _, f := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err == nil {
+			total -= f.size
+		}
+	}
+	return nil
+}
+
+// hashFile returns the hex SHA-256 and size of the file at path.
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// copyFile copies src to dst. Copying file to file lets the kernel clone the
+// data (copy_file_range) where the file system supports it.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := out.ReadFrom(in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
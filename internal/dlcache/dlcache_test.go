@@ -0,0 +1,109 @@
+package dlcache_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/dlcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_StoreAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	cache := dlcache.New(filepath.Join(dir, "cache"), 1<<20)
+	entry := &api.FileEntry{Hash: "h1", Size: 5, UpdatedAt: time.Unix(1700000000, 0)}
+
+	src := filepath.Join(dir, "src")
+	require.NoError(t, os.WriteFile(src, []byte("hello"), 0644))
+	require.NoError(t, cache.Store(entry, src))
+
+	dst := filepath.Join(dir, "dst")
+	ok, err := cache.Restore(entry, dst)
+	require.NoError(t, err)
+	require.True(t, ok)
+	data, _ := os.ReadFile(dst)
+	assert.Equal(t, "hello", string(data))
+
+	// A newer version of the file is a miss
+	changed := *entry
+	changed.UpdatedAt = entry.UpdatedAt.Add(time.Second)
+	ok, err = cache.Restore(&changed, filepath.Join(dir, "other"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// A damaged copy is dropped instead of used
+	files, _ := filepath.Glob(filepath.Join(dir, "cache", "*"))
+	require.Len(t, files, 1)
+	require.NoError(t, os.WriteFile(files[0], []byte("hellx"), 0644))
+	ok, err = cache.Restore(entry, filepath.Join(dir, "bad"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoFileExists(t, files[0])
+
+	// A nil cache does nothing
+	var none *dlcache.Cache
+	ok, err = none.Restore(entry, dst)
+	assert.False(t, ok)
+	assert.NoError(t, err)
+	assert.NoError(t, none.Store(entry, src))
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	cache := dlcache.New(filepath.Join(dir, "cache"), 10)
+	src := filepath.Join(dir, "src")
+	require.NoError(t, os.WriteFile(src, []byte("12345"), 0644))
+
+	entries := make([]*api.FileEntry, 3)
+	for i := range entries {
+		entries[i] = &api.FileEntry{Hash: string(rune('a' + i)), Size: 5, UpdatedAt: time.Unix(1700000000, 0)}
+	}
+	require.NoError(t, cache.Store(entries[0], src))
+	require.NoError(t, cache.Store(entries[1], src))
+	// Make the first one the oldest, then use it so the second is
+	past := time.Now().Add(-time.Hour)
+	files, _ := filepath.Glob(filepath.Join(dir, "cache", "*"))
+	for _, f := range files {
+		require.NoError(t, os.Chtimes(f, past, past))
+	}
+	ok, err := cache.Restore(entries[0], filepath.Join(dir, "dst"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, cache.Store(entries[2], src))
+	for i, want := range []bool{true, false, true} {
+		ok, err := cache.Restore(entries[i], filepath.Join(dir, "dst"))
+		require.NoError(t, err)
+		assert.Equal(t, want, ok, "entry %d", i)
+	}
+}
+
+func TestCache_ParallelUse(t *testing.T) {
+	dir := t.TempDir()
+	cache := dlcache.New(filepath.Join(dir, "cache"), 1<<20)
+	src := filepath.Join(dir, "src")
+	require.NoError(t, os.WriteFile(src, []byte("hello"), 0644))
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Two goroutines per key, four keys
+			entry := &api.FileEntry{Hash: fmt.Sprint(i % 4), Size: 5, UpdatedAt: time.Unix(1700000000, 0)}
+			assert.NoError(t, cache.Store(entry, src))
+			ok, err := cache.Restore(entry, filepath.Join(dir, fmt.Sprint("dst", i)))
+			assert.NoError(t, err)
+			assert.True(t, ok)
+		}()
+	}
+	wg.Wait()
+	files, _ := filepath.Glob(filepath.Join(dir, "cache", "*"))
+	assert.Len(t, files, 4)
+}
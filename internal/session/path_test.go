@@ -30,6 +30,18 @@ func TestSession_ResolvePath(t *testing.T) {
 		{"~", "/"},
 		{"~/docs", "/docs"},
 		{"-", "/users"},
+		{"../../..", "/"},
+		{"../../../etc", "/etc"},
+		{"/../..", "/"},
+		{"/../../etc/passwd", "/etc/passwd"},
+		{"docs/../../../../x", "/x"},
+		{"~/../..", "/"},
+		{"$HOME", "/"},
+		{"$HOME/docs", "/docs"},
+		{"~name", "/users/mikael/~name"},
+		{"~name/docs", "/users/mikael/~name/docs"},
+		{"$HOMEDIR", "/users/mikael/$HOMEDIR"},
+		{"docs/~", "/users/mikael/docs/~"},
 	}
 
 	for _, tt := range tests {
@@ -39,3 +51,124 @@ func TestSession_ResolvePath(t *testing.T) {
 		})
 	}
 }
+
+func TestSession_RetainWorkspaceCache(t *testing.T) {
+	s := &session.Session{}
+
+	caches := make([]*api.FileCache, session.MaxRetainedWorkspaceCaches+1)
+	for i := range caches {
+		caches[i] = api.NewFileCache()
+		s.RetainWorkspaceCache(int64(i), caches[i])
+	}
+
+	// Oldest cache is evicted once the bound is exceeded
+	_, ok := s.RetainedWorkspaceCache(0)
+	assert.False(t, ok)
+
+	got, ok := s.RetainedWorkspaceCache(int64(len(caches) - 1))
+	assert.True(t, ok)
+	assert.Same(t, caches[len(caches)-1], got)
+
+	// Re-retaining marks a cache as most recently used
+	s.RetainWorkspaceCache(1, caches[1])
+	s.RetainWorkspaceCache(99, api.NewFileCache())
+	_, ok = s.RetainedWorkspaceCache(1)
+	assert.True(t, ok)
+	_, ok = s.RetainedWorkspaceCache(2)
+	assert.False(t, ok)
+
+	s.ForgetWorkspaceCache(1)
+	_, ok = s.RetainedWorkspaceCache(1)
+	assert.False(t, ok)
+}
+
+func TestSession_ResolvePath_NeverAboveRoot(t *testing.T) {
+	// Without a CWD or home directory, relative paths still resolve from "/"
+	s := &session.Session{Cache: api.NewFileCache()}
+
+	assert.Equal(t, "/", s.ResolvePath("../.."))
+	assert.Equal(t, "/docs", s.ResolvePath("../docs"))
+	assert.Equal(t, "/docs", s.ResolvePath("~/../docs"))
+}
+
+func TestSession_ResolvePath_HomeDir(t *testing.T) {
+	s := &session.Session{CWD: "/work", HomeDir: "/users/mikael", Cache: api.NewFileCache()}
+
+	assert.Equal(t, "/users/mikael", s.ResolvePath("~"))
+	assert.Equal(t, "/users/mikael", s.ResolvePath("~/"))
+	assert.Equal(t, "/users/mikael/sub", s.ResolvePath("~/sub"))
+	assert.Equal(t, "/users/mikael/sub", s.ResolvePath("$HOME/sub"))
+	assert.Equal(t, "/users", s.ResolvePath("~/.."))
+	assert.Equal(t, "/work/~mikael", s.ResolvePath("~mikael"))
+
+	// Without a home directory, ~ is the root
+	s.HomeDir = ""
+	assert.Equal(t, "/", s.ResolvePath("~"))
+	assert.Equal(t, "/sub", s.ResolvePath("~/sub"))
+}
+
+func TestSession_WorkspaceProfile(t *testing.T) {
+	s := session.NewSession(nil, api.NewFileCache())
+	s.HomeDir = "/global"
+	s.WorkspaceID, s.WorkspaceName = 7, "Client"
+	s.WorkspaceProfiles = map[string]session.WorkspaceProfile{
+		"7":       {Home: "work/", Aliases: map[string]string{"la": "ls -A"}},
+		"default": {Home: "/mine"},
+	}
+
+	// Matched by ID when the name has no entry
+	s.ApplyWorkspaceProfile()
+	assert.Equal(t, "/work", s.Home())
+	assert.Equal(t, "ls -A", s.AllAliases()["la"])
+	assert.Equal(t, "ls -a", s.Aliases["la"])
+
+	s.InVault = true
+	assert.Equal(t, "/", s.Home())
+	s.InVault = false
+
+	s.WorkspaceID, s.WorkspaceName = 0, ""
+	s.ApplyWorkspaceProfile()
+	assert.Equal(t, "/mine", s.Home())
+	assert.Equal(t, "ls -a", s.AllAliases()["la"])
+
+	s.WorkspaceProfiles = nil
+	s.ApplyWorkspaceProfile()
+	assert.Equal(t, "/global", s.Home())
+}
+
+func TestSession_UndoLog(t *testing.T) {
+	s := &session.Session{WorkspaceID: 7}
+
+	// Commands that change nothing, or record outside BeginUndo, log nothing
+	s.RecordTrash(1, "/a.txt")
+	s.BeginUndo("rm")
+	s.EndUndo()
+	assert.Empty(t, s.UndoLog())
+
+	s.BeginUndo("mv")
+	s.RecordMove(2, "/b.txt", "/docs/b.txt")
+	s.EndUndo()
+	log := s.UndoLog()
+	assert.Len(t, log, 1)
+	assert.Equal(t, "mv", log[0].Command)
+	assert.Equal(t, int64(7), log[0].WorkspaceID)
+	assert.Equal(t, []session.UndoMove{{ID: 2, From: "/b.txt", To: "/docs/b.txt"}}, log[0].Moved)
+
+	// The vault has no trash, so nothing there is undoable
+	s.InVault = true
+	s.BeginUndo("rm")
+	s.RecordTrash(3, "/c.txt")
+	s.EndUndo()
+	assert.Len(t, s.UndoLog(), 1)
+	s.InVault = false
+
+	for i := 0; i < session.MaxUndoOperations+5; i++ {
+		s.BeginUndo("cp")
+		s.RecordCopy(int64(100+i), "/copy")
+		s.EndUndo()
+	}
+	assert.Len(t, s.UndoLog(), session.MaxUndoOperations)
+	op, ok := s.PopUndo()
+	assert.True(t, ok)
+	assert.Equal(t, int64(100+session.MaxUndoOperations+4), op.Copied[0].ID)
+}
@@ -1,28 +1,59 @@
 package session
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/config"
 	"github.com/gYonder/drime-shell/internal/crypto"
+	"github.com/gYonder/drime-shell/internal/dlcache"
 )
 
+// LineRunnerFunc parses and executes a shell command line, including pipes,
+// redirection and aliases.
+type LineRunnerFunc func(ctx context.Context, line string) error
+
 type Session struct {
 	Client            api.DrimeClient
 	Cache             *api.FileCache
 	HistoryGetter     func() []string
+	LineRunner        LineRunnerFunc    // Runs a full command line (set by the REPL)
 	Aliases           map[string]string // User-defined command aliases
 	CWD               string
-	HomeDir           string
+	HomeDir           string // Home outside any workspace profile ("" = "/")
 	PreviousDir       string
 	Username          string
 	Token             string
 	UserID            int64
-	WorkspaceID       int64           // Current workspace (0 = default)
-	WorkspaceName     string          // Name of current workspace (empty = default)
-	Workspaces        []api.Workspace // Cached list of available workspaces
-	MaxMemoryBufferMB int             // Max MB for in-memory operations before using temp files
+	WorkspaceID       int64                 // Current workspace (0 = default)
+	WorkspaceName     string                // Name of current workspace (empty = default)
+	Workspaces        []api.Workspace       // Cached list of available workspaces
+	MaxMemoryBufferMB int                   // Max MB for in-memory operations before using temp files
+	RmConfirmEntries  int                   // Folder entry count above which rm asks for confirmation
+	TransferJobs      int                   // Default parallel workers for directory uploads (0 = built-in default)
+	UploadJournal     *api.MultipartJournal // Multipart uploads started but not yet finished (may be nil)
+	IndexDir          string                // Where locate name indexes are persisted ("" disables them)
+	ExternalEditor    bool                  // edit opens $EDITOR instead of the built-in editor
+	NoZipThreshold    int                   // Folders with more files download file by file (0 = always zip)
+	TransfersDir      string                // Where running shells publish their active transfers ("" keeps them private)
+	DownloadDir       string                // Where download puts files when no local path is given ("" = current directory)
+	ClockSkew         time.Duration         // Server clock minus local clock, measured at startup
+	OnDuplicate       string                // Duplicate policy when no --on-duplicate is given ("" = ask)
+	DownloadCache     *dlcache.Cache        // Copies of downloaded files (nil = no cache)
+	ChecksumAlgo      string                // Digest --verify uses when no --checksum-algo is given ("" = sha256)
+	PartialSuffix     string                // Appended to files being downloaded when no --partial-suffix is given ("" = .drime-partial)
+	ProgressMode      string                // Transfer progress when no --progress is given: "json" or "" for bars (DRIME_PROGRESS)
+
+	// Per-workspace settings from the config, keyed by workspace name or
+	// ID ("default" for the default workspace), and those of the current one
+	WorkspaceProfiles map[string]WorkspaceProfile
+	workspaceProfile  WorkspaceProfile
 
 	// Vault state
 	InVault       bool             // True when vault is the active context
@@ -32,12 +63,34 @@ type Session struct {
 	VaultSalt     []byte           // Salt for key derivation (cached from API)
 	VaultCheckIV  []byte           // IV for check value decryption
 	VaultCheck    []byte           // Encrypted check value for password verification
+	VaultCache    *api.FileCache   // Vault folder cache, kept while the vault is unlocked
 
 	// Saved workspace state (for returning from vault)
 	SavedWorkspaceID   int64
 	SavedWorkspaceName string
 	SavedCWD           string
 	SavedCache         *api.FileCache
+
+	// Reversible operations for undo, oldest first, and the one the running
+	// command is recording
+	undoLog     []UndoOperation
+	pendingUndo *UndoOperation
+
+	// Retained caches of recently visited workspaces, keyed by workspace ID.
+	// cacheOrder tracks recency (least recent first) for eviction.
+	workspaceCaches map[int64]*api.FileCache
+	cacheOrder      []int64
+}
+
+// MaxRetainedWorkspaceCaches bounds how many workspace caches are kept in
+// memory for fast switching. The least recently used cache is evicted first.
+const MaxRetainedWorkspaceCaches = 4
+
+// WorkspaceProfile is what the config sets for one workspace: the home cd
+// goes to, and aliases that take precedence over the global ones there.
+type WorkspaceProfile struct {
+	Home    string
+	Aliases map[string]string
 }
 
 type ViewMode string
@@ -54,6 +107,21 @@ func (s *Session) MaxMemoryBytes() int64 {
 	return int64(s.MaxMemoryBufferMB) * 1024 * 1024
 }
 
+// ServerTime converts a local clock reading, such as a local file's mtime,
+// to the server's clock so it can be compared with UpdatedAt.
+func (s *Session) ServerTime(local time.Time) time.Time {
+	return local.Add(s.ClockSkew)
+}
+
+// RmConfirmThreshold returns the number of entries in a folder subtree above
+// which rm asks for confirmation.
+func (s *Session) RmConfirmThreshold() int {
+	if s.RmConfirmEntries <= 0 {
+		return config.DefaultRmConfirmEntries
+	}
+	return s.RmConfirmEntries
+}
+
 func NewSession(client api.DrimeClient, cache *api.FileCache) *Session {
 	s := &Session{
 		CWD:     "/",
@@ -93,28 +161,109 @@ func (s *Session) ResolvePath(path string) string {
 		return s.PreviousDir
 	}
 
-	if path == "~" {
-		return s.HomeDir
-	}
-	if strings.HasPrefix(path, "~/") {
-		return filepath.Join(s.HomeDir, path[2:])
-	}
-
 	var absolute string
-	if filepath.IsAbs(path) {
+	if home, ok := s.expandHome(path); ok {
+		absolute = home
+	} else if filepath.IsAbs(path) {
 		absolute = path
 	} else {
 		absolute = filepath.Join(s.CWD, path)
 	}
 
-	return filepath.Clean(absolute)
+	// Rooting before cleaning clamps ".." at "/", as in a Unix shell, so a
+	// remote path never resolves above the root even from an unset CWD
+	return filepath.Clean("/" + absolute)
 }
 
-// ResolvePathArg resolves a user-supplied path argument.
+// Home returns the home directory: the current workspace's, else HomeDir,
+// else "/". The vault's home is always its root.
+func (s *Session) Home() string {
+	switch {
+	case s.InVault:
+		return "/"
+	case s.workspaceProfile.Home != "":
+		return s.workspaceProfile.Home
+	case s.HomeDir != "":
+		return s.HomeDir
+	}
+	return "/"
+}
+
+// ApplyWorkspaceProfile switches to the profile of the current workspace,
+// or to none when it has no entry in WorkspaceProfiles.
+func (s *Session) ApplyWorkspaceProfile() {
+	key := "default"
+	if s.WorkspaceID != 0 {
+		key = s.WorkspaceName
+	}
+	p, ok := s.WorkspaceProfiles[key]
+	if !ok && s.WorkspaceID != 0 {
+		p = s.WorkspaceProfiles[strconv.FormatInt(s.WorkspaceID, 10)]
+	}
+	if p.Home != "" {
+		p.Home = filepath.Clean("/" + p.Home)
+	}
+	s.workspaceProfile = p
+}
+
+// WorkspaceAliases returns the aliases only the current workspace has.
+func (s *Session) WorkspaceAliases() map[string]string {
+	return s.workspaceProfile.Aliases
+}
+
+// AllAliases returns the aliases in effect: the global ones, overridden by
+// those of the current workspace.
+func (s *Session) AllAliases() map[string]string {
+	if len(s.workspaceProfile.Aliases) == 0 {
+		return s.Aliases
+	}
+	all := make(map[string]string, len(s.Aliases)+len(s.workspaceProfile.Aliases))
+	for k, v := range s.Aliases {
+		all[k] = v
+	}
+	for k, v := range s.workspaceProfile.Aliases {
+		all[k] = v
+	}
+	return all
+}
+
+// EnterHome moves to the home directory, as on switching workspaces. It
+// returns false, staying at "/", when the home is not a known folder.
+func (s *Session) EnterHome() bool {
+	s.CWD = "/"
+	home := s.Home()
+	if entry, ok := s.Cache.Get(home); !ok || entry.Type != "folder" {
+		return home == "/"
+	}
+	s.CWD = home
+	return true
+}
+
+// expandHome replaces a leading "~" or "$HOME" with the home directory. Only
+// a whole first component is expanded, so "~name" and "$HOMEDIR" stay
+// literal names.
+func (s *Session) expandHome(path string) (string, bool) {
+	for _, prefix := range []string{"~", "$HOME"} {
+		if rest, ok := strings.CutPrefix(path, prefix); ok && (rest == "" || rest[0] == '/') {
+			return s.Home() + "/" + rest, true
+		}
+	}
+	return path, false
+}
+
+// ResolvePathArg resolves a user-supplied path argument. Like ResolvePath it
+// drops a trailing slash; use IsDirArg to tell "b/" from "b".
 func (s *Session) ResolvePathArg(path string) (string, error) {
 	return s.ResolvePath(path), nil
 }
 
+// IsDirArg reports whether a path argument ends in a slash, which asks for
+// an existing directory: "mv a b/" moves a into b and fails when b is not a
+// directory, while "mv a b" may rename a to b.
+func IsDirArg(path string) bool {
+	return strings.HasSuffix(path, "/")
+}
+
 // ContextName returns a display name for the current context (workspace or vault).
 // Used in the shell prompt. Returns empty string for default workspace.
 func (s *Session) ContextName() string {
@@ -139,6 +288,7 @@ func (s *Session) ClearVaultKey() {
 		s.VaultKey = nil
 	}
 	s.VaultUnlocked = false
+	s.VaultCache = nil
 }
 
 // SetVaultKey sets the vault encryption key.
@@ -149,6 +299,82 @@ func (s *Session) SetVaultKey(key *crypto.VaultKey) {
 	s.VaultUnlocked = true
 }
 
+// RetainWorkspaceCache keeps cache for workspaceID so a later switch back can
+// reuse it instead of reloading the folder tree.
+func (s *Session) RetainWorkspaceCache(workspaceID int64, cache *api.FileCache) {
+	if cache == nil {
+		return
+	}
+	if s.workspaceCaches == nil {
+		s.workspaceCaches = make(map[int64]*api.FileCache)
+	}
+	s.removeCacheOrder(workspaceID)
+	s.workspaceCaches[workspaceID] = cache
+	s.cacheOrder = append(s.cacheOrder, workspaceID)
+
+	for len(s.cacheOrder) > MaxRetainedWorkspaceCaches {
+		oldest := s.cacheOrder[0]
+		s.cacheOrder = s.cacheOrder[1:]
+		_ = s.workspaceCaches[oldest].Index().Save()
+		delete(s.workspaceCaches, oldest)
+	}
+}
+
+// AttachNameIndex loads the persisted name index of workspaceID and attaches
+// it to cache. It does nothing when indexes are disabled. Indexes are kept
+// per user as well as per workspace, so another account logged in on the
+// same machine never sees this one's paths.
+func (s *Session) AttachNameIndex(cache *api.FileCache, workspaceID int64) error {
+	if s.IndexDir == "" || cache == nil {
+		return nil
+	}
+	name := fmt.Sprintf("user-%d-ws-%d.json", s.UserID, workspaceID)
+	idx, err := api.LoadNameIndex(filepath.Join(s.IndexDir, name))
+	if err != nil {
+		return err
+	}
+	cache.SetIndex(idx)
+	return nil
+}
+
+// SaveNameIndexes persists the name indexes of the active and retained
+// workspace caches.
+func (s *Session) SaveNameIndexes() error {
+	var errs []error
+	caches := []*api.FileCache{s.Cache, s.SavedCache}
+	for _, cache := range s.workspaceCaches {
+		caches = append(caches, cache)
+	}
+	for _, cache := range caches {
+		if cache != nil {
+			errs = append(errs, cache.Index().Save())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RetainedWorkspaceCache returns the retained cache for workspaceID, if any.
+func (s *Session) RetainedWorkspaceCache(workspaceID int64) (*api.FileCache, bool) {
+	cache, ok := s.workspaceCaches[workspaceID]
+	return cache, ok
+}
+
+// ForgetWorkspaceCache drops the retained cache for workspaceID, forcing the
+// next switch to that workspace to reload from the server.
+func (s *Session) ForgetWorkspaceCache(workspaceID int64) {
+	delete(s.workspaceCaches, workspaceID)
+	s.removeCacheOrder(workspaceID)
+}
+
+func (s *Session) removeCacheOrder(workspaceID int64) {
+	for i, id := range s.cacheOrder {
+		if id == workspaceID {
+			s.cacheOrder = append(s.cacheOrder[:i], s.cacheOrder[i+1:]...)
+			return
+		}
+	}
+}
+
 // SaveWorkspaceState saves the current workspace state before switching to vault.
 func (s *Session) SaveWorkspaceState() {
 	s.SavedWorkspaceID = s.WorkspaceID
@@ -179,6 +405,7 @@ func (s *Session) SwitchToVault(vaultID int64, vaultCache *api.FileCache) {
 	s.InVault = true
 	s.VaultID = vaultID
 	s.Cache = vaultCache
+	s.VaultCache = vaultCache
 	s.CWD = "/"
 	s.PreviousDir = ""
 }
@@ -0,0 +1,99 @@
+package session
+
+// MaxUndoOperations bounds the undo log; the oldest operations are dropped
+// first.
+const MaxUndoOperations = 50
+
+// UndoOperation is a mutating command recorded with what it takes to
+// reverse it. Only reversible changes are recorded: entries moved or
+// renamed, copies created and entries sent to the trash.
+type UndoOperation struct {
+	Command     string // Command that made the changes (mv, cp, rm)
+	WorkspaceID int64  // Workspace the command ran in
+	Moved       []UndoMove
+	Copied      []UndoEntry // Deleted again on undo
+	Trashed     []UndoEntry // Restored from the trash on undo
+}
+
+// UndoMove is an entry moved or renamed from one path to another.
+type UndoMove struct {
+	ID   int64
+	From string
+	To   string
+}
+
+// UndoEntry is an entry and the path it had when it was recorded.
+type UndoEntry struct {
+	ID   int64
+	Path string
+}
+
+// BeginUndo starts recording what the command does, until EndUndo. Nothing
+// is recorded in the vault, which deletes permanently and has no trash to
+// restore from.
+func (s *Session) BeginUndo(command string) {
+	s.pendingUndo = nil
+	if !s.InVault {
+		s.pendingUndo = &UndoOperation{Command: command, WorkspaceID: s.WorkspaceID}
+	}
+}
+
+// EndUndo adds the operation recorded since BeginUndo to the undo log, if
+// it changed anything. A command that failed half-way is logged too, so
+// what it did get to can still be undone.
+func (s *Session) EndUndo() {
+	op := s.pendingUndo
+	s.pendingUndo = nil
+	if op == nil || len(op.Moved)+len(op.Copied)+len(op.Trashed) == 0 {
+		return
+	}
+	s.undoLog = append(s.undoLog, *op)
+	if len(s.undoLog) > MaxUndoOperations {
+		s.undoLog = s.undoLog[len(s.undoLog)-MaxUndoOperations:]
+	}
+}
+
+// RecordMove notes that the entry id was moved or renamed from one path to
+// another. Like RecordCopy and RecordTrash, it does nothing outside
+// BeginUndo and EndUndo.
+func (s *Session) RecordMove(id int64, from, to string) {
+	if s.pendingUndo != nil {
+		s.pendingUndo.Moved = append(s.pendingUndo.Moved, UndoMove{ID: id, From: from, To: to})
+	}
+}
+
+// RecordCopy notes that the entry id was created at path as a copy.
+func (s *Session) RecordCopy(id int64, path string) {
+	if s.pendingUndo != nil {
+		s.pendingUndo.Copied = append(s.pendingUndo.Copied, UndoEntry{ID: id, Path: path})
+	}
+}
+
+// RecordTrash notes that the entry id at path was moved to the trash.
+func (s *Session) RecordTrash(id int64, path string) {
+	if s.pendingUndo != nil {
+		s.pendingUndo.Trashed = append(s.pendingUndo.Trashed, UndoEntry{ID: id, Path: path})
+	}
+}
+
+// UndoLog returns the recorded operations, oldest first.
+func (s *Session) UndoLog() []UndoOperation {
+	return append([]UndoOperation(nil), s.undoLog...)
+}
+
+// PopUndo removes the most recent operation from the undo log and returns
+// it.
+func (s *Session) PopUndo() (UndoOperation, bool) {
+	if len(s.undoLog) == 0 {
+		return UndoOperation{}, false
+	}
+	op := s.undoLog[len(s.undoLog)-1]
+	s.undoLog = s.undoLog[:len(s.undoLog)-1]
+	return op, true
+}
+
+// PushUndo puts op back on the undo log, for an undo that failed: op is
+// then whatever the undo did not get to reverse.
+func (s *Session) PushUndo(op UndoOperation) {
+	s.undoLog = append(s.undoLog, op)
+}
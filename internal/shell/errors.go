@@ -0,0 +1,75 @@
+package shell
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/commands"
+)
+
+// CommandError is an error returned by a command, tagged with the command's
+// name so it can be reported along with it.
+type CommandError struct {
+	Command string
+	Err     error
+}
+
+func (e *CommandError) Error() string { return e.Err.Error() }
+
+func (e *CommandError) Unwrap() error { return e.Err }
+
+// jsonError is the object written for an error in JSON mode.
+type jsonError struct {
+	Error    string `json:"error"`
+	Code     string `json:"code"`
+	Command  string `json:"command,omitempty"`
+	Status   int    `json:"status,omitempty"` // HTTP status of an API error
+	Hint     string `json:"hint,omitempty"`
+	CrashLog string `json:"crash_log,omitempty"` // Report of a command that panicked
+}
+
+// ReportError writes err the way the shell shows a failed command line: as
+// "drime: ..." text, or with asJSON as one JSON object per line for
+// programs driving the shell. A command that panicked also gets where its
+// crash report went and how to file it.
+func ReportError(w io.Writer, err error, asJSON bool) {
+	var panicErr *commands.PanicError
+	crashed := errors.As(err, &panicErr)
+	if asJSON {
+		out := jsonError{Error: err.Error(), Code: api.ErrorCode(err), Hint: api.ErrorHint(err)}
+		if crashed {
+			out.Code = "panic"
+			out.CrashLog = panicErr.LogPath
+		}
+		var cmdErr *CommandError
+		if errors.As(err, &cmdErr) {
+			out.Command = cmdErr.Command
+		}
+		var apiErr *api.APIError
+		if errors.As(err, &apiErr) {
+			out.Status = apiErr.StatusCode
+		}
+		data, _ := json.Marshal(out)
+		fmt.Fprintf(w, "%s\n", data)
+		return
+	}
+
+	if errors.Is(err, api.ErrTokenExpired) {
+		fmt.Fprintln(w, "drime: Session expired. Please run 'login' to re-authenticate.")
+	} else if hint := api.ErrorHint(err); hint != "" && !strings.Contains(strings.ToLower(err.Error()), hint) {
+		fmt.Fprintf(w, "drime: %v (%s)\n", err, hint)
+	} else {
+		fmt.Fprintf(w, "drime: %v\n", err)
+	}
+	if crashed {
+		fmt.Fprintln(w, "drime: the shell recovered; your session, vault included, is as it was.")
+		if panicErr.LogPath != "" {
+			fmt.Fprintf(w, "drime: the crash report is in %s\n", panicErr.LogPath)
+		}
+		fmt.Fprintf(w, "drime: to report it, open this link (only the report is sent, and only if you submit):\n  %s\n", panicErr.IssueURL())
+	}
+}
@@ -0,0 +1,137 @@
+package shell_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gYonder/drime-shell/internal/api"
+	"github.com/gYonder/drime-shell/internal/commands"
+	"github.com/gYonder/drime-shell/internal/session"
+	"github.com/gYonder/drime-shell/internal/shell"
+	"github.com/gYonder/drime-shell/internal/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportError_JSON(t *testing.T) {
+	commands.Register(&commands.Command{
+		Name: "mock-missing",
+		Run: func(ctx context.Context, s *session.Session, env *commands.ExecutionEnv, args []string) error {
+			return &api.APIError{StatusCode: 404, Message: "Entry not found", Op: "GetEntry"}
+		},
+	})
+
+	s := session.NewSession(&api.MockDrimeClient{}, api.NewFileCache())
+	err := shell.RunLine(context.Background(), s, "mock-missing x")
+	require.Error(t, err)
+
+	var buf bytes.Buffer
+	shell.ReportError(&buf, err, true)
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got), buf.String())
+	assert.Equal(t, "GetEntry failed: Entry not found", got["error"])
+	assert.Equal(t, "not_found", got["code"])
+	assert.Equal(t, "mock-missing", got["command"])
+	assert.Equal(t, float64(404), got["status"])
+
+	buf.Reset()
+	err = shell.RunLine(context.Background(), s, "no-such-command")
+	shell.ReportError(&buf, err, true)
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "command not found: no-such-command", got["error"])
+	assert.Equal(t, "no-such-command", got["command"])
+
+	// Text mode is unchanged
+	buf.Reset()
+	shell.ReportError(&buf, errors.New("boom"), false)
+	assert.Equal(t, "drime: boom\n", buf.String())
+}
+
+func TestRunLine_RecoversFromPanickingCommands(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	commands.Register(&commands.Command{
+		Name: "mock-panic",
+		Run: func(ctx context.Context, s *session.Session, env *commands.ExecutionEnv, args []string) error {
+			var entry *api.FileEntry
+			_ = entry.Name
+			return nil
+		},
+	})
+	commands.Register(&commands.Command{
+		Name: "mock-spinner-panic",
+		Run: func(ctx context.Context, s *session.Session, env *commands.ExecutionEnv, args []string) error {
+			return ui.WithSpinnerErr(env.Stderr, "", false, func() error {
+				panic("lost in " + home + "/secret")
+			})
+		},
+	})
+
+	commands.Register(&commands.Command{
+		Name: "mock-pool-panic",
+		Run: func(ctx context.Context, s *session.Session, env *commands.ExecutionEnv, args []string) error {
+			local := filepath.Join(t.TempDir(), "a.txt")
+			if err := os.WriteFile(local, []byte("a"), 0o644); err != nil {
+				return err
+			}
+			client := &api.MockDrimeClient{
+				UploadWithOptionsFunc: func(ctx context.Context, reader io.Reader, name string, parentID *int64, size int64, workspaceID int64, opts *api.UploadOptions) (*api.FileEntry, error) {
+					panic("upload worker")
+				},
+			}
+			pool := commands.NewWorkerPool(ctx, client, nil, "/", commands.UploadConfig{Concurrency: 2}, nil, 0)
+			pool.Start()
+			for range 4 {
+				pool.Submit(commands.FileUploadTask{LocalPath: local, RelativePath: "a.txt", Size: 1})
+			}
+			pool.Close()
+			return nil
+		},
+	})
+
+	s := session.NewSession(&api.MockDrimeClient{}, api.NewFileCache())
+	for _, line := range []string{"mock-panic", "mock-spinner-panic", "mock-panic | cat", "mock-pool-panic"} {
+		err := shell.RunLine(context.Background(), s, line)
+		var panicErr *commands.PanicError
+		require.ErrorAs(t, err, &panicErr, line)
+		assert.Contains(t, panicErr.Report, "command: mock-")
+		assert.NotContains(t, panicErr.Report, home, "home paths are left out of the report")
+	}
+
+	err := shell.RunLine(context.Background(), s, "mock-pool-panic")
+	var poolErr *commands.PanicError
+	require.ErrorAs(t, err, &poolErr)
+	assert.Contains(t, poolErr.Report, "panic: upload worker")
+	assert.Contains(t, poolErr.Report, "worker_pool.go", "the stack is the worker's")
+
+	err = shell.RunLine(context.Background(), s, "mock-spinner-panic")
+	var panicErr *commands.PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Contains(t, panicErr.Report, "panic: lost in ~/secret")
+	assert.Contains(t, panicErr.Report, "errors_test.go", "the stack is where the panic happened")
+
+	data, readErr := os.ReadFile(filepath.Join(home, ".drime-shell", "crash.log"))
+	require.NoError(t, readErr)
+	assert.Equal(t, 6, strings.Count(string(data), "=== "))
+
+	var buf bytes.Buffer
+	shell.ReportError(&buf, err, false)
+	out := buf.String()
+	assert.Contains(t, out, "drime: mock-spinner-panic: internal error: lost in")
+	assert.Contains(t, out, "crash.log")
+	assert.Contains(t, out, "https://github.com/gYonder/drime-shell/issues/new?")
+
+	buf.Reset()
+	shell.ReportError(&buf, err, true)
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "panic", got["code"])
+	assert.Equal(t, panicErr.LogPath, got["crash_log"])
+}
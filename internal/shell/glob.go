@@ -37,7 +37,7 @@ func ExpandGlobs(ctx context.Context, s *session.Session, w io.Writer, args []st
 		if !s.Cache.HasChildren(parentDir) {
 			if parentEntry, ok := s.Cache.Get(parentDir); ok {
 				var parentID *int64
-				if parentEntry.ID != 0 {
+				if !parentEntry.IsRoot() {
 					parentID = &parentEntry.ID
 				}
 
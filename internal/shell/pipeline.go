@@ -250,13 +250,16 @@ func (p *Pipeline) Execute(ctx context.Context, sess *session.Session) error {
 	for i, seg := range p.Segments {
 		cmd, ok := commands.Get(seg.CommandName)
 		if !ok {
-			return fmt.Errorf("command not found: %s", seg.CommandName)
+			return &CommandError{Command: seg.CommandName, Err: fmt.Errorf("command not found: %s", seg.CommandName)}
 		}
 		cmds[i] = cmd
 	}
 
 	if len(p.Segments) == 1 {
-		return p.executeSingle(ctx, sess, cmds[0], p.Segments[0])
+		if err := p.executeSingle(ctx, sess, cmds[0], p.Segments[0]); err != nil {
+			return &CommandError{Command: cmds[0].Name, Err: err}
+		}
+		return nil
 	}
 	return p.executePipeline(ctx, sess, cmds)
 }
@@ -276,13 +279,13 @@ func (p *Pipeline) executeSingle(ctx context.Context, sess *session.Session, cmd
 	}
 
 	// Check for -h/--help flag
-	if commands.HasHelpFlag(expandedArgs) {
+	if commands.WantsHelp(cmd, expandedArgs) {
 		commands.PrintUsage(cmd, env.Stdout)
 		closeAll(closers)
 		return nil
 	}
 
-	runErr := cmd.Run(ctx, sess, env, expandedArgs)
+	runErr := commands.Execute(ctx, cmd, sess, env, expandedArgs)
 
 	// Close all redirects - this is where uploads happen!
 	closeErr := closeAllWithError(closers)
@@ -351,18 +354,18 @@ func (p *Pipeline) executePipeline(ctx context.Context, sess *session.Session, c
 			}
 
 			// Check for -h/--help flag
-			if commands.HasHelpFlag(expandedArgs) {
+			if commands.WantsHelp(cmds[idx], expandedArgs) {
 				commands.PrintUsage(cmds[idx], envs[idx].Stdout)
 				return
 			}
-			errors[idx] = cmds[idx].Run(ctx, sess, envs[idx], expandedArgs)
+			errors[idx] = commands.Execute(ctx, cmds[idx], sess, envs[idx], expandedArgs)
 		}(i)
 	}
 	wg.Wait()
 
 	for i, err := range errors {
 		if err != nil {
-			return fmt.Errorf("%s: %v", p.Segments[i].CommandName, err)
+			return &CommandError{Command: p.Segments[i].CommandName, Err: fmt.Errorf("%s: %w", p.Segments[i].CommandName, err)}
 		}
 	}
 	return nil
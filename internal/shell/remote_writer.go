@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/commands"
@@ -118,8 +119,8 @@ func (w *RemoteFileWriter) Close() error {
 	var parentID *int64
 
 	if parentEntry, ok := w.sess.Cache.Get(parentDir); ok && parentEntry.Type == "folder" {
-		// Use nil for root folder (ID=0 is synthetic)
-		if parentEntry.ID != 0 {
+		// The root folder is sent as a nil parent
+		if !parentEntry.IsRoot() {
 			parentID = &parentEntry.ID
 		}
 	} else if parentDir != "/" && parentDir != "." {
@@ -141,8 +142,14 @@ func (w *RemoteFileWriter) Close() error {
 		}
 	}
 
-	// Upload with spinner for slow operations
-	return ui.WithSpinnerErr(os.Stderr, "", false, func() error {
+	// Upload with spinner for slow operations. Hooks such as the audit log
+	// see it as a change of its own, the command having already returned
+	op := ">"
+	if w.append {
+		op = ">>"
+	}
+	start := time.Now()
+	err = ui.WithSpinnerErr(os.Stderr, "", false, func() error {
 		var newEntry *api.FileEntry
 		var uploadErr error
 
@@ -179,4 +186,6 @@ func (w *RemoteFileWriter) Close() error {
 		}
 		return nil
 	})
+	commands.NotifyHooks(w.sess, commands.RedirectCommand, []string{op, w.remotePath}, err, time.Since(start))
+	return err
 }
@@ -2,14 +2,12 @@ package shell
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 
 	"github.com/chzyer/readline"
-	"github.com/gYonder/drime-shell/internal/api"
 	"github.com/gYonder/drime-shell/internal/config"
 	"github.com/gYonder/drime-shell/internal/session"
 	"github.com/gYonder/drime-shell/internal/ui"
@@ -20,6 +18,7 @@ type Shell struct {
 	Session        *session.Session
 	RL             *readline.Instance
 	sessionHistory []string // Commands from current session (for !!, !-n)
+	JSONErrors     bool     // Report errors as JSON objects on stderr
 }
 
 // New creates a new Shell with the given session.
@@ -47,6 +46,10 @@ func New(s *session.Session) (*Shell, error) {
 
 	// Set history getter on session so commands can access it
 	s.HistoryGetter = shell.GetHistory
+	// Let commands such as source run full command lines
+	s.LineRunner = func(ctx context.Context, line string) error {
+		return RunLine(ctx, s, line)
+	}
 
 	return shell, nil
 }
@@ -54,10 +57,11 @@ func New(s *session.Session) (*Shell, error) {
 // buildPrompt creates the shell prompt string
 func (sh *Shell) buildPrompt() string {
 	displayPath := sh.Session.VirtualCWD()
-	if displayPath == sh.Session.HomeDir {
+	home := sh.Session.Home()
+	if displayPath == home {
 		displayPath = "~"
-	} else if strings.HasPrefix(displayPath, sh.Session.HomeDir+"/") {
-		displayPath = "~" + displayPath[len(sh.Session.HomeDir):]
+	} else if strings.HasPrefix(displayPath, home+"/") {
+		displayPath = "~" + displayPath[len(home):]
 	}
 
 	contextName := sh.Session.ContextName()
@@ -87,7 +91,7 @@ func (sh *Shell) Run() {
 		if strings.HasPrefix(line, "!") && len(line) > 1 {
 			expanded, err := sh.expandHistory(line)
 			if err != nil {
-				fmt.Printf("drime: %v\n", err)
+				sh.reportError(err)
 				continue
 			}
 			line = expanded
@@ -95,7 +99,7 @@ func (sh *Shell) Run() {
 		}
 
 		// Handle alias expansion
-		if expanded, wasAlias := ExpandAlias(line, sh.Session.Aliases); wasAlias {
+		if expanded, wasAlias := ExpandAlias(line, sh.Session.AllAliases()); wasAlias {
 			line = expanded
 		}
 
@@ -105,22 +109,40 @@ func (sh *Shell) Run() {
 		// Parse the command line into a command chain
 		chain, err := ParseCommandChain(line)
 		if err != nil {
-			fmt.Printf("drime: %v\n", err)
+			sh.reportError(err)
 			continue
 		}
 
 		// Execute the command chain
 		if err := chain.Execute(ctx, sh.Session); err != nil {
-			// Check if token expired - prompt for re-authentication
-			if errors.Is(err, api.ErrTokenExpired) {
-				fmt.Println("drime: Session expired. Please run 'login' to re-authenticate.")
-			} else {
-				fmt.Printf("drime: %v\n", err)
-			}
+			sh.reportError(err)
 		}
 	}
 }
 
+// reportError shows an error from a command line: as text on stdout, or as
+// JSON on stderr in JSON mode.
+func (sh *Shell) reportError(err error) {
+	if sh.JSONErrors {
+		ReportError(os.Stderr, err, true)
+		return
+	}
+	ReportError(os.Stdout, err, false)
+}
+
+// RunLine expands aliases in line, then parses and executes it as a command
+// chain against the session. History expansion is left to the REPL.
+func RunLine(ctx context.Context, s *session.Session, line string) error {
+	if expanded, wasAlias := ExpandAlias(line, s.AllAliases()); wasAlias {
+		line = expanded
+	}
+	chain, err := ParseCommandChain(line)
+	if err != nil {
+		return err
+	}
+	return chain.Execute(ctx, s)
+}
+
 // expandHistory handles !n and !! syntax for history expansion
 func (sh *Shell) expandHistory(line string) (string, error) {
 	// For !! and !-n, use session history (current session only)
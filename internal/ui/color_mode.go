@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"golang.org/x/term"
+)
+
+// ColorMode controls when output is colorized, following GNU ls --color.
+type ColorMode string
+
+const (
+	ColorAuto   ColorMode = "auto"   // Color only when writing to a terminal
+	ColorAlways ColorMode = "always" // Color even when piped or redirected
+	ColorNever  ColorMode = "never"  // Never color
+)
+
+// ParseColorMode parses a --color value. GNU aliases (yes/force, no/none,
+// tty/if-tty) are accepted; an empty value means always, as with a bare --color.
+func ParseColorMode(value string) (ColorMode, error) {
+	switch value {
+	case "", "always", "yes", "force":
+		return ColorAlways, nil
+	case "never", "no", "none":
+		return ColorNever, nil
+	case "auto", "tty", "if-tty":
+		return ColorAuto, nil
+	}
+	return "", fmt.Errorf("invalid color mode '%s' (want always, never or auto)", value)
+}
+
+// NameStyler returns a function that renders file names for w according to
+// mode. In auto mode names are only styled when w is a terminal.
+func NameStyler(mode ColorMode, w io.Writer) func(name, fileType string) string {
+	switch mode {
+	case ColorNever:
+		return plainName
+	case ColorAlways:
+		profile := lipgloss.ColorProfile()
+		if profile == termenv.Ascii {
+			profile = termenv.ANSI256
+		}
+		r := lipgloss.NewRenderer(w)
+		r.SetColorProfile(profile)
+		return func(name, fileType string) string {
+			return StyleForType(fileType).Renderer(r).Render(name)
+		}
+	default:
+		if !IsTerminal(w) {
+			return plainName
+		}
+		return StyleName
+	}
+}
+
+func plainName(name, _ string) string {
+	return name
+}
+
+// IsTerminal reports whether w is a terminal (as opposed to a pipe, file or
+// buffer).
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// TerminalWidth returns the width in columns of the terminal w writes to, or
+// 0 when it is unknown (pipes, files, buffers). A positive $COLUMNS wins, as
+// with GNU ls.
+func TerminalWidth(w io.Writer) int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	f, ok := w.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return 0
+	}
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil || width <= 0 {
+		return 0
+	}
+	return width
+}
@@ -1,7 +1,12 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/help"
@@ -222,3 +227,64 @@ func RunEditor(filename, content string) (EditorResult, error) {
 
 	return m.Result(), nil
 }
+
+// ExternalEditor returns the command line of the user's editor: $VISUAL,
+// then $EDITOR, then vi (notepad on Windows).
+func ExternalEditor() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(name)); editor != "" {
+			return editor
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// RunExternalEditor opens content in the user's editor (see ExternalEditor)
+// through a temporary file named filename, so editors can pick a syntax
+// from its extension. The result counts as saved when the file was changed.
+// An editor exiting with an error status discards the changes.
+func RunExternalEditor(filename, content string) (EditorResult, error) {
+	dir, err := os.MkdirTemp("", "drime-edit-")
+	if err != nil {
+		return EditorResult{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, filepath.Base(filename))
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return EditorResult{}, err
+	}
+
+	// The editor may carry its own arguments (e.g. "code --wait"), so let
+	// the shell split it and pass the file as a separate argument
+	editor := ExternalEditor()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", editor, path)
+	} else {
+		cmd = exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return EditorResult{}, fmt.Errorf("%s exited with status %d, changes not saved", editor, exitErr.ExitCode())
+		}
+		return EditorResult{}, fmt.Errorf("%s: %w", editor, err)
+	}
+
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return EditorResult{}, err
+	}
+	return EditorResult{
+		Content:  string(edited),
+		Filename: filename,
+		Saved:    string(edited) != content,
+	}, nil
+}
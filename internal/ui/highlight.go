@@ -11,15 +11,17 @@ import (
 	"github.com/alecthomas/chroma/v2/formatters"
 	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/alecthomas/chroma/v2/styles"
-	"github.com/charmbracelet/lipgloss"
 )
 
-// SyntaxTheme returns the appropriate chroma style based on terminal background
+// SyntaxTheme returns the chroma style matching the active theme
 func SyntaxTheme() string {
-	if lipgloss.HasDarkBackground() {
-		return "dracula"
+	switch activeTheme {
+	case ThemeLight:
+		return "github"
+	case ThemeMono:
+		return "bw"
 	}
-	return "github"
+	return "dracula"
 }
 
 // Highlight returns syntax-highlighted content based on filename extension.
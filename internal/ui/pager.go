@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
+)
+
+// TerminalHeight returns the height in rows of the terminal w writes to, or
+// 0 when it is unknown. A positive $LINES wins.
+func TerminalHeight(w io.Writer) int {
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		return n
+	}
+	f, ok := w.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return 0
+	}
+	_, height, err := term.GetSize(int(f.Fd()))
+	if err != nil || height <= 0 {
+		return 0
+	}
+	return height
+}
+
+// Page shows content one screen at a time. $PAGER is used when set,
+// otherwise the built-in pager. Content that fits on the screen of w is
+// written to it directly.
+func Page(w io.Writer, title, content string) error {
+	height := TerminalHeight(w)
+	if height == 0 || strings.Count(content, "\n") < height {
+		_, err := io.WriteString(w, content)
+		return err
+	}
+
+	if pager := strings.TrimSpace(os.Getenv("PAGER")); pager != "" {
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", pager)
+		} else {
+			cmd = exec.Command("sh", "-c", pager)
+		}
+		cmd.Stdin = strings.NewReader(content)
+		cmd.Stdout = w
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("pager %q: %w", pager, err)
+		}
+		return nil
+	}
+
+	p := tea.NewProgram(newPagerModel(title, content), tea.WithAltScreen(), tea.WithOutput(w))
+	_, err := p.Run()
+	return err
+}
+
+// pagerKeyMap defines the keybindings for the built-in pager (less-like)
+type pagerKeyMap struct {
+	Quit   key.Binding
+	Top    key.Binding
+	Bottom key.Binding
+}
+
+type pagerModel struct {
+	viewport    viewport.Model
+	keymap      pagerKeyMap
+	statusStyle lipgloss.Style
+	title       string
+	content     string
+	ready       bool
+}
+
+func newPagerModel(title, content string) pagerModel {
+	return pagerModel{
+		keymap: pagerKeyMap{
+			Quit:   key.NewBinding(key.WithKeys("q", "Q", "esc", "ctrl+c")),
+			Top:    key.NewBinding(key.WithKeys("g", "home")),
+			Bottom: key.NewBinding(key.WithKeys("G", "end")),
+		},
+		statusStyle: lipgloss.NewStyle().Background(lipgloss.Color("62")).Foreground(lipgloss.Color("230")).Padding(0, 1),
+		title:       title,
+		content:     strings.TrimSuffix(content, "\n"),
+	}
+}
+
+// Init implements tea.Model
+func (m pagerModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model
+func (m pagerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// Reserve the last line for the status bar
+		if !m.ready {
+			m.viewport = viewport.New(msg.Width, msg.Height-1)
+			m.viewport.SetContent(m.content)
+			m.ready = true
+		} else {
+			m.viewport.Width = msg.Width
+			m.viewport.Height = msg.Height - 1
+		}
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keymap.Quit):
+			return m, tea.Quit
+		case key.Matches(msg, m.keymap.Top):
+			m.viewport.GotoTop()
+			return m, nil
+		case key.Matches(msg, m.keymap.Bottom):
+			m.viewport.GotoBottom()
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// View implements tea.Model
+func (m pagerModel) View() string {
+	if !m.ready {
+		return ""
+	}
+	status := fmt.Sprintf("%s  %3.f%%  (q to quit)", m.title, m.viewport.ScrollPercent()*100)
+	return m.viewport.View() + "\n" + m.statusStyle.Render(status)
+}
@@ -1,8 +1,12 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
@@ -90,24 +94,151 @@ const (
 	maxWidth = 80
 )
 
+// DefaultProgressInterval is how often a transfer's progress is redrawn or
+// reported unless WithProgressInterval says otherwise.
+const DefaultProgressInterval = 100 * time.Millisecond
+
+type progressIntervalKey struct{}
+
+// WithProgressInterval returns a context whose transfers update their
+// progress at most once per d. Zero reports every update.
+func WithProgressInterval(ctx context.Context, d time.Duration) context.Context {
+	if d < 0 {
+		d = 0
+	}
+	return context.WithValue(ctx, progressIntervalKey{}, d)
+}
+
+// ProgressIntervalFrom returns the minimum time between progress updates of
+// the transfers run under ctx: DefaultProgressInterval unless
+// WithProgressInterval set another.
+func ProgressIntervalFrom(ctx context.Context) time.Duration {
+	if d, ok := ctx.Value(progressIntervalKey{}).(time.Duration); ok {
+		return d
+	}
+	return DefaultProgressInterval
+}
+
+// throttleProgress wraps send so it is called at most once per progress
+// interval of ctx. Byte counts are cumulative, so skipped updates are simply
+// folded into the next one; the first update and the one completing the
+// transfer always go through.
+func throttleProgress(ctx context.Context, send func(curr, total int64)) func(curr, total int64) {
+	interval := ProgressIntervalFrom(ctx)
+	if interval <= 0 {
+		return send
+	}
+
+	var mu sync.Mutex
+	var last time.Time
+	return func(curr, total int64) {
+		mu.Lock()
+		now := time.Now()
+		complete := total > 0 && curr >= total
+		if !complete && !last.IsZero() && now.Sub(last) < interval {
+			mu.Unlock()
+			return
+		}
+		last = now
+		mu.Unlock()
+		send(curr, total)
+	}
+}
+
 // Helper to run
-func RunTransfer(taskName string, size int64, action func(send func(curr, total int64)) error) error {
+func RunTransfer(ctx context.Context, taskName string, size int64, action func(send func(curr, total int64)) error) error {
+	return RunFileTransfer(ctx, taskName, taskName, size, action)
+}
+
+// RunFileTransfer is RunTransfer for a named file; the file name is what
+// a progress sink and the transfers command report. Progress goes to the
+// sink of ctx, if it has one (see WithProgressSink).
+func RunFileTransfer(ctx context.Context, taskName, file string, size int64, action func(send func(curr, total int64)) error) error {
+	action = trackTransfer(taskName, file, size, action)
+	if sink := ProgressSinkFrom(ctx); sink != nil {
+		return runTransferWithSink(ctx, sink, file, size, action)
+	}
+
 	m := NewProgressModel(taskName, size, nil)
 	p := tea.NewProgram(m)
 
-	// Start task in goroutine
+	// Start task in goroutine; a panic ends the bar and is raised again here
+	var trap PanicTrap
 	go func() {
-		err := action(func(curr, total int64) {
+		var err error
+		defer func() { p.Send(finishedMsg{err: err}) }()
+		defer trap.Catch(nil)
+		err = action(throttleProgress(ctx, func(curr, total int64) {
 			// Calculate percentage 0.0 to 1.0
 			var ratio float64
 			if total > 0 {
 				ratio = float64(curr) / float64(total)
 			}
 			p.Send(progressMsg(ratio))
-		})
-		p.Send(finishedMsg{err: err})
+		}))
 	}()
 
 	_, err := p.Run()
+	trap.Rethrow()
+	return err
+}
+
+// RunFileTransferTo is RunFileTransfer with the progress bar drawn on out,
+// for transfers whose data goes to stdout. Nothing is drawn when out is not
+// a terminal. Unlike RunFileTransfer, the action's error is returned.
+func RunFileTransferTo(ctx context.Context, out io.Writer, taskName, file string, size int64, action func(send func(curr, total int64)) error) error {
+	action = trackTransfer(taskName, file, size, action)
+	if sink := ProgressSinkFrom(ctx); sink != nil {
+		return runTransferWithSink(ctx, sink, file, size, action)
+	}
+	if !IsTerminal(out) {
+		return action(func(int64, int64) {})
+	}
+
+	m := NewProgressModel(taskName, size, nil)
+	p := tea.NewProgram(m, tea.WithOutput(out), tea.WithInput(nil))
+
+	var actionErr error
+	var trap PanicTrap
+	go func() {
+		defer func() { p.Send(finishedMsg{err: actionErr}) }()
+		defer trap.Catch(nil)
+		actionErr = action(throttleProgress(ctx, func(curr, total int64) {
+			var ratio float64
+			if total > 0 {
+				ratio = float64(curr) / float64(total)
+			}
+			p.Send(progressMsg(ratio))
+		}))
+	}()
+
+	_, err := p.Run()
+	trap.Rethrow()
+	if err != nil {
+		return err
+	}
+	return actionErr
+}
+
+// runTransferWithSink runs action in the foreground, reporting to sink
+// instead of drawing a progress bar.
+func runTransferWithSink(ctx context.Context, sink ProgressSink, file string, size int64, action func(send func(curr, total int64)) error) error {
+	var last int64
+	report := throttleProgress(ctx, func(curr, total int64) {
+		sink.Progress(ProgressEvent{File: file, Bytes: curr, Total: total, Pct: percentOf(curr, total)})
+	})
+	err := action(func(curr, total int64) {
+		last = curr
+		report(curr, total)
+	})
+
+	ev := ProgressEvent{File: file, Bytes: last, Total: size, Pct: percentOf(last, size), Done: true}
+	if err != nil {
+		ev.Error = err.Error()
+	} else {
+		ev.Bytes = size
+		ev.Pct = 100
+	}
+	sink.Progress(ev)
 	return err
 }
@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ProgressEvent is a single transfer progress update.
+type ProgressEvent struct {
+	File       string  `json:"file"`
+	Bytes      int64   `json:"bytes"`
+	Total      int64   `json:"total"`
+	Pct        float64 `json:"pct"`
+	FilesDone  int64   `json:"files_done,omitempty"`  // Batch transfers only
+	FilesTotal int64   `json:"files_total,omitempty"` // Batch transfers only
+	Done       bool    `json:"done,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// ProgressSink receives transfer progress in place of the interactive
+// progress bars, e.g. for programs driving the shell.
+type ProgressSink interface {
+	Progress(ev ProgressEvent)
+}
+
+type progressSinkKey struct{}
+
+// WithProgressSink returns a context whose transfers report to sink instead
+// of drawing progress bars. A nil sink keeps the interactive bars.
+func WithProgressSink(ctx context.Context, sink ProgressSink) context.Context {
+	return context.WithValue(ctx, progressSinkKey{}, sink)
+}
+
+// ProgressSinkFrom returns the sink transfers run under ctx report to, or nil
+// for interactive output.
+func ProgressSinkFrom(ctx context.Context) ProgressSink {
+	sink, _ := ctx.Value(progressSinkKey{}).(ProgressSink)
+	return sink
+}
+
+// ProgressSinkFor returns the sink for a --progress mode: "json" emits
+// newline-delimited JSON to w, "bar" (or "") uses the interactive bars.
+func ProgressSinkFor(mode string, w io.Writer) (ProgressSink, error) {
+	switch mode {
+	case "", "bar":
+		return nil, nil
+	case "json":
+		return NewJSONProgressSink(w), nil
+	default:
+		return nil, fmt.Errorf("invalid progress mode: %s (must be bar or json)", mode)
+	}
+}
+
+// DiscardProgress is a sink that drops every event, for output modes that
+// show no progress at all.
+var DiscardProgress ProgressSink = discardSink{}
+
+type discardSink struct{}
+
+func (discardSink) Progress(ProgressEvent) {}
+
+// JSONProgressSink writes one JSON object per line. Byte updates for a file
+// are emitted at most once per whole percent to keep the stream small.
+type JSONProgressSink struct {
+	w       io.Writer
+	mu      sync.Mutex
+	lastPct map[string]int
+}
+
+func NewJSONProgressSink(w io.Writer) *JSONProgressSink {
+	return &JSONProgressSink{w: w, lastPct: make(map[string]int)}
+}
+
+func (s *JSONProgressSink) Progress(ev ProgressEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !ev.Done {
+		pct := int(ev.Pct)
+		if last, ok := s.lastPct[ev.File]; ok && last == pct {
+			return
+		}
+		s.lastPct[ev.File] = pct
+	} else {
+		delete(s.lastPct, ev.File)
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	s.w.Write(append(data, '\n'))
+}
+
+// percentOf returns curr/total as a percentage, rounded to two decimals.
+func percentOf(curr, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	pct := float64(curr) / float64(total) * 100
+	return float64(int64(pct*100+0.5)) / 100
+}
+
+// ReportBatchProgress reports file-count progress of a batch transfer.
+func ReportBatchProgress(sink ProgressSink, name string, done, total int64) {
+	sink.Progress(ProgressEvent{
+		File:       name,
+		Pct:        percentOf(done, total),
+		FilesDone:  done,
+		FilesTotal: total,
+	})
+}
+
+// ReportFileDone reports that one file of a batch finished: complete, or
+// failed with errMsg, in which case it is not reported as 100%.
+func ReportFileDone(sink ProgressSink, name string, errMsg string) {
+	ev := ProgressEvent{File: name, Done: true, Error: errMsg}
+	if errMsg == "" {
+		ev.Pct = 100
+	}
+	sink.Progress(ev)
+}
@@ -4,29 +4,93 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"runtime/debug"
+	"sync"
 	"time"
 )
 
 // Spinner frames for a simple dots animation
 var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 
+// Panic is what a spinner re-panics with when its action panicked, so the
+// panic reaches the caller's goroutine with the stack where it happened.
+type Panic struct {
+	Value any
+	Stack []byte
+}
+
+// PanicTrap carries the first panic of goroutines started for a caller back
+// to it: each goroutine defers Catch, and the caller calls Rethrow once they
+// are done, as WithSpinner does for its action.
+type PanicTrap struct {
+	mu     sync.Mutex
+	caught *Panic
+}
+
+// Catch recovers a panic of the goroutine it is deferred in, keeping the
+// first one, and calls stop (if not nil) so the others can give up early.
+func (t *PanicTrap) Catch(stop func()) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	p, ok := r.(*Panic)
+	if !ok {
+		p = &Panic{Value: r, Stack: debug.Stack()}
+	}
+	t.mu.Lock()
+	if t.caught == nil {
+		t.caught = p
+	}
+	t.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// Caught reports whether a goroutine has panicked.
+func (t *PanicTrap) Caught() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.caught != nil
+}
+
+// Rethrow panics on the caller's goroutine with the panic caught, if any.
+func (t *PanicTrap) Rethrow() {
+	t.mu.Lock()
+	p := t.caught
+	t.mu.Unlock()
+	if p != nil {
+		panic(p)
+	}
+}
+
 // WithSpinner runs an action while displaying a spinner. Returns the result of the action.
 // The spinner appears on a new line. If immediate is false, it waits 100ms before showing.
 func WithSpinner[T any](w io.Writer, message string, immediate bool, action func() (T, error)) (T, error) {
 	done := make(chan struct{})
 	var result T
 	var err error
+	var panicked *Panic
 
 	// Run action in goroutine
 	go func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				panicked = &Panic{Value: r, Stack: debug.Stack()}
+			}
+		}()
 		result, err = action()
-		close(done)
 	}()
 
 	// Wait a bit before showing spinner (avoid flicker for fast operations)
 	if !immediate {
 		select {
 		case <-done:
+			if panicked != nil {
+				panic(panicked)
+			}
 			return result, err
 		case <-time.After(100 * time.Millisecond):
 			// Action is taking a while, show spinner
@@ -49,6 +113,9 @@ func WithSpinner[T any](w io.Writer, message string, immediate bool, action func
 		case <-done:
 			// Clear spinner line
 			fmt.Fprintf(w, "\r\033[K")
+			if panicked != nil {
+				panic(panicked)
+			}
 			return result, err
 		case <-ticker.C:
 			frame = (frame + 1) % len(spinnerFrames)
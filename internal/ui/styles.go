@@ -56,6 +56,7 @@ func SetDarkTheme() {
 		Text: mocha.Text, Subtext: mocha.Subtext1, Overlay: mocha.Overlay1, Surface: mocha.Surface1,
 		Base: mocha.Base,
 	}
+	activeTheme = ThemeDark
 	refreshStyles()
 }
 
@@ -67,6 +68,15 @@ func SetLightTheme() {
 		Text: latte.Text, Subtext: latte.Subtext1, Overlay: latte.Overlay1, Surface: latte.Surface1,
 		Base: latte.Base,
 	}
+	activeTheme = ThemeLight
+	refreshStyles()
+}
+
+// SetMonoTheme drops all colors, for terminals where neither palette reads
+// well. Bold, underline and strikethrough still mark the styles apart.
+func SetMonoTheme() {
+	currentTheme = ThemePalette{}
+	activeTheme = ThemeMono
 	refreshStyles()
 }
 
@@ -94,6 +104,7 @@ var (
 	HeaderStyle     lipgloss.Style
 	StarStyle       lipgloss.Style // For starred files indicator
 	TrashStyle      lipgloss.Style // For trash indicator
+	DeletedStyle    lipgloss.Style // For trashed entries listed inline
 	WorkspaceStyle  lipgloss.Style // For workspace name in prompt
 	LinkStyle       lipgloss.Style // For URLs
 )
@@ -165,13 +176,22 @@ func refreshStyles() {
 	// Trash indicator (red)
 	TrashStyle = lipgloss.NewStyle().Foreground(currentTheme.Red)
 
+	// Trashed entries shown by ls --include-deleted (red, struck through)
+	DeletedStyle = lipgloss.NewStyle().Foreground(currentTheme.Red).Strikethrough(true)
+
 	// Workspace name in prompt (magenta)
 	WorkspaceStyle = lipgloss.NewStyle().Foreground(currentTheme.Magenta)
 
 	// Links
 	LinkStyle = lipgloss.NewStyle().Foreground(currentTheme.Blue).Underline(true)
+
+	applyStyleOverrides()
 }
 
+// TrashedType is the pseudo file type used to style entries that are in the
+// trash, whatever their real type.
+const TrashedType = "trashed"
+
 // StyleForType returns the appropriate style for a file type
 func StyleForType(fileType string) lipgloss.Style {
 	switch fileType {
@@ -187,6 +207,8 @@ func StyleForType(fileType string) lipgloss.Style {
 		return DocStyle
 	case "archive":
 		return ArchiveStyle
+	case TrashedType:
+		return DeletedStyle
 	default:
 		return FileStyle
 	}
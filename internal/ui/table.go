@@ -119,3 +119,9 @@ func StripANSI(s string) string {
 func VisibleLen(s string) int {
 	return runewidth.StringWidth(StripANSI(s))
 }
+
+// Ellipsize shortens the plain string s to at most width columns, ending it
+// with "…" when anything was cut.
+func Ellipsize(s string, width int) string {
+	return runewidth.Truncate(s, width, "…")
+}
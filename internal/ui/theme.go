@@ -1,6 +1,14 @@
 package ui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 // Theme represents the user interface color theme
 type Theme string
@@ -9,6 +17,7 @@ const (
 	ThemeAuto  Theme = "auto"
 	ThemeDark  Theme = "dark"
 	ThemeLight Theme = "light"
+	ThemeMono  Theme = "mono" // No colors, only bold/underline
 )
 
 // DetectTheme returns the detected terminal theme (Dark or Light)
@@ -18,3 +27,136 @@ func DetectTheme() Theme {
 	}
 	return ThemeLight
 }
+
+// activeTheme is the preset the styles were last built from.
+var activeTheme = ThemeDark
+
+// styleOverrides holds the user's colors by style name; refreshStyles
+// applies them on top of the preset.
+var styleOverrides map[string]lipgloss.Color
+
+// ApplyTheme switches to the preset name ("" or auto picks dark or light
+// from the terminal background) and then colors the styles named in colors,
+// e.g. {"dir": "#5f87ff", "warning": "208"}. Style names are those of the
+// *Style variables without the suffix, in any case ("DirStyle" works too).
+// Colors are hex (#rgb or #rrggbb) or ANSI numbers (0-255).
+func ApplyTheme(name Theme, colors map[string]string) error {
+	overrides := make(map[string]lipgloss.Color, len(colors))
+	for key, value := range colors {
+		style := normalizeStyleName(key)
+		if _, ok := styleRefs()[style]; !ok {
+			return fmt.Errorf("unknown style '%s' in colors (known: %s)", key, strings.Join(StyleNames(), ", "))
+		}
+		color, err := parseColor(value)
+		if err != nil {
+			return fmt.Errorf("colors: %s: %w", key, err)
+		}
+		overrides[style] = color
+	}
+
+	switch Theme(strings.ToLower(string(name))) {
+	case "", ThemeAuto:
+		name = DetectTheme()
+	case ThemeDark, ThemeLight, ThemeMono:
+		name = Theme(strings.ToLower(string(name)))
+	default:
+		return fmt.Errorf("unknown theme '%s' (want auto, dark, light or mono)", name)
+	}
+
+	styleOverrides = overrides
+	switch name {
+	case ThemeDark:
+		SetDarkTheme()
+	case ThemeLight:
+		SetLightTheme()
+	case ThemeMono:
+		SetMonoTheme()
+	}
+	return nil
+}
+
+// StyleNames lists the style names ApplyTheme accepts, sorted.
+func StyleNames() []string {
+	refs := styleRefs()
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// styleRefs maps the configurable style names to their variables.
+func styleRefs() map[string]*lipgloss.Style {
+	return map[string]*lipgloss.Style{
+		"dir":         &DirStyle,
+		"file":        &FileStyle,
+		"exec":        &ExecStyle,
+		"image":       &ImageStyle,
+		"archive":     &ArchiveStyle,
+		"video":       &VideoStyle,
+		"audio":       &AudioStyle,
+		"doc":         &DocStyle,
+		"perm":        &PermStyle,
+		"size":        &SizeStyle,
+		"owner":       &OwnerStyle,
+		"date":        &DateStyle,
+		"muted":       &MutedStyle,
+		"error":       &ErrorStyle,
+		"warning":     &WarningStyle,
+		"success":     &SuccessStyle,
+		"prompt_user": &PromptUserStyle,
+		"prompt_path": &PromptPathStyle,
+		"command":     &CommandStyle,
+		"header":      &HeaderStyle,
+		"star":        &StarStyle,
+		"trash":       &TrashStyle,
+		"deleted":     &DeletedStyle,
+		"workspace":   &WorkspaceStyle,
+		"link":        &LinkStyle,
+	}
+}
+
+// applyStyleOverrides recolors the styles the user configured.
+func applyStyleOverrides() {
+	refs := styleRefs()
+	for name, color := range styleOverrides {
+		if style, ok := refs[name]; ok {
+			*style = style.Foreground(color)
+		}
+	}
+}
+
+// normalizeStyleName turns "DirStyle", "promptUser" or "prompt-user" into
+// the names used by styleRefs.
+func normalizeStyleName(name string) string {
+	name = strings.TrimSuffix(name, "Style")
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '-' || r == ' ':
+			b.WriteByte('_')
+		case r >= 'A' && r <= 'Z':
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r - 'A' + 'a')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_style")
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func parseColor(value string) (lipgloss.Color, error) {
+	value = strings.TrimSpace(value)
+	if hexColor.MatchString(value) {
+		return lipgloss.Color(value), nil
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 255 {
+		return lipgloss.Color(value), nil
+	}
+	return "", fmt.Errorf("invalid color '%s' (want #rrggbb or an ANSI number 0-255)", value)
+}
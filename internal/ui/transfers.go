@@ -0,0 +1,155 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Directions of a transfer
+const (
+	TransferUpload   = "upload"
+	TransferDownload = "download"
+)
+
+// speedWindow is how much time a speed sample spans
+const speedWindow = time.Second
+
+// TransferStatus is a snapshot of one transfer in flight
+type TransferStatus struct {
+	ID        int64     `json:"id"`
+	File      string    `json:"file"`
+	Direction string    `json:"direction"`
+	Done      int64     `json:"done"`
+	Total     int64     `json:"total"`
+	Speed     float64   `json:"speed"` // bytes per second, 0 while unknown
+	Started   time.Time `json:"started"`
+}
+
+// ETA estimates the time left at the current speed. ok is false when there
+// is no estimate.
+func (t TransferStatus) ETA() (eta time.Duration, ok bool) {
+	if t.Speed <= 0 || t.Total <= 0 || t.Done > t.Total {
+		return 0, false
+	}
+	return time.Duration(float64(t.Total-t.Done) / t.Speed * float64(time.Second)), true
+}
+
+// Transfer is a transfer registered with StartTransfer. Its methods are safe
+// for concurrent use.
+type Transfer struct {
+	mu         sync.Mutex
+	status     TransferStatus
+	sampleAt   time.Time
+	sampleDone int64
+}
+
+var transfers = struct {
+	sync.Mutex
+	next   int64
+	active map[int64]*Transfer
+}{active: make(map[int64]*Transfer)}
+
+// StartTransfer registers a transfer so it shows up in ActiveTransfers until
+// Finish is called.
+func StartTransfer(direction, file string, total int64) *Transfer {
+	transfers.Lock()
+	defer transfers.Unlock()
+	transfers.next++
+	t := &Transfer{status: TransferStatus{
+		ID:        transfers.next,
+		File:      file,
+		Direction: direction,
+		Total:     total,
+		Started:   time.Now(),
+	}}
+	transfers.active[t.status.ID] = t
+	return t
+}
+
+// Update records that done of total bytes have been transferred. A total of
+// 0 or less keeps the one the transfer started with.
+func (t *Transfer) Update(done, total int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if total > 0 {
+		t.status.Total = total
+	}
+	t.status.Done = done
+	if t.sampleAt.IsZero() || done < t.sampleDone {
+		// First update, or a retry starting over: (re)start sampling here
+		// so a resumed offset doesn't count as speed
+		t.sampleAt, t.sampleDone = now, done
+		return
+	}
+	if elapsed := now.Sub(t.sampleAt); elapsed >= speedWindow {
+		speed := float64(done-t.sampleDone) / elapsed.Seconds()
+		if t.status.Speed > 0 {
+			speed = (t.status.Speed + speed) / 2
+		}
+		t.status.Speed = speed
+		t.sampleAt, t.sampleDone = now, done
+	}
+}
+
+// Finish removes the transfer from the registry.
+func (t *Transfer) Finish() {
+	transfers.Lock()
+	delete(transfers.active, t.status.ID)
+	transfers.Unlock()
+}
+
+// Status returns a snapshot of the transfer. Until a full speed sample has
+// been taken, the speed is the average since the first update.
+func (t *Transfer) Status() TransferStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.status
+	if st.Speed == 0 && !t.sampleAt.IsZero() {
+		if elapsed := time.Since(t.sampleAt).Seconds(); elapsed > 0 {
+			st.Speed = float64(st.Done-t.sampleDone) / elapsed
+		}
+	}
+	return st
+}
+
+// ActiveTransfers returns the transfers in flight in this process, oldest
+// first.
+func ActiveTransfers() []TransferStatus {
+	transfers.Lock()
+	list := make([]*Transfer, 0, len(transfers.active))
+	for _, t := range transfers.active {
+		list = append(list, t)
+	}
+	transfers.Unlock()
+
+	out := make([]TransferStatus, 0, len(list))
+	for _, t := range list {
+		out = append(out, t.Status())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// transferDirection tells uploads from downloads by the progress bar's task
+// name ("Uploading x", "Encrypting & uploading x", "Downloading x").
+func transferDirection(taskName string) string {
+	if strings.Contains(strings.ToLower(taskName), "upload") {
+		return TransferUpload
+	}
+	return TransferDownload
+}
+
+// trackTransfer wraps a transfer action so it is registered while it runs.
+func trackTransfer(taskName, file string, size int64, action func(send func(curr, total int64)) error) func(send func(curr, total int64)) error {
+	return func(send func(curr, total int64)) error {
+		t := StartTransfer(transferDirection(taskName), file, size)
+		defer t.Finish()
+		return action(func(curr, total int64) {
+			t.Update(curr, total)
+			send(curr, total)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package util
+
+import "strings"
+
+// Levenshtein returns the edit distance between a and b, counting
+// insertions, deletions and substitutions of runes.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 {
+		return len(rb)
+	}
+	if len(rb) == 0 {
+		return len(ra)
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// ClosestMatch returns the candidate closest to target (case-insensitive),
+// or "" if none is close enough to be a plausible typo.
+func ClosestMatch(target string, candidates []string) string {
+	target = strings.ToLower(target)
+	// Allow roughly one edit per three characters, but at least two
+	maxDist := max(2, len([]rune(target))/3)
+
+	best, bestDist := "", maxDist+1
+	for _, c := range candidates {
+		d := Levenshtein(target, strings.ToLower(c))
+		if d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
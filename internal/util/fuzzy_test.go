@@ -0,0 +1,37 @@
+package util
+
+import "testing"
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"Documents", "Documents", 0},
+		{"Documets", "Documents", 1},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+	}
+	for _, tt := range tests {
+		if got := Levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosestMatch(t *testing.T) {
+	candidates := []string{"Documents", "Downloads", "Photos"}
+
+	if got := ClosestMatch("Documets", candidates); got != "Documents" {
+		t.Errorf("ClosestMatch(Documets) = %q, want Documents", got)
+	}
+	if got := ClosestMatch("photos", candidates); got != "Photos" {
+		t.Errorf("ClosestMatch(photos) = %q, want Photos", got)
+	}
+	if got := ClosestMatch("Music", candidates); got != "" {
+		t.Errorf("ClosestMatch(Music) = %q, want no suggestion", got)
+	}
+}